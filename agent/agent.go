@@ -8,10 +8,16 @@
 //   agent_session.go   - session management, task scheduling, LLM summary
 //   agent_compact.go   - context overflow/compaction/token estimation
 //   agent_api.go       - callAPI, callAPIWithDepth, simpleResponse
+//   agent_provider.go  - llm.Provider resolution and message conversion
+//   agent_router.go    - per-purpose model routing (chat, summarize, split, ...)
+//   agent_usage.go     - per-call token usage and cost accounting
+//   agent_budget.go    - spend and turn budgets per session, channel and cron job
 
 package agent
 
 import (
+	"cmp"
+	"context"
 	"encoding/gob"
 	"encoding/json"
 	"fmt"
@@ -25,6 +31,7 @@ import (
 	"time"
 
 	"github.com/gliderlab/cogate/memory"
+	"github.com/gliderlab/cogate/pkg/commands"
 	"github.com/gliderlab/cogate/pkg/config"
 	"github.com/gliderlab/cogate/pkg/kv"
 	"github.com/gliderlab/cogate/pkg/llm"
@@ -49,15 +56,43 @@ type Agent struct {
 	store       *storage.Storage
 	memoryStore *memory.VectorMemoryStore
 	registry    *tools.Registry
+	commands    *commands.Registry // Slash commands (built-ins, skills, plugins)
 	systemTools []llm.Tool
 	pulse       *PulseHandler
 	compactMu   sync.Mutex // Mutex for compaction (replaces channel)
+	graphMu     sync.Mutex // One knowledge-graph extraction at a time
 	kv          *kv.KV     // Fast KV cache (BadgerDB)
 
+	// LLM provider (resolved from cfg.Provider/Groups, or injected)
+	providerMu        sync.Mutex
+	provider          llm.Provider // injected provider, overrides config
+	cachedProvider    llm.Provider
+	cachedProviderKey string
+	routeProviders    map[string]llm.Provider // providers built for routed config groups
+
 	// Rate limiting (protected by rateLimitMu)
 	rateLimitMu       sync.Mutex
 	lastAnthropicCall time.Time // Track last Anthropic API call for rate limit
 
+	// Budget enforcement (protected by budgetMu)
+	budgetMu     sync.Mutex
+	turns        map[string]*turnState // In-flight turn per session key
+	budgetWarned map[string]string     // Soft-limit warnings already fired, key -> UTC day or turn
+
+	// Cancellable in-flight turns (protected by runMu)
+	runMu sync.Mutex
+	runs  map[string][]*sessionRun // Turns per session key, oldest first
+
+	// Tool calls waiting for human approval (protected by approvalMu)
+	approvalMu       sync.Mutex
+	approvals        map[string]*ApprovalRequest        // Pending requests by ID
+	approvalWatchers map[chan *ApprovalRequest]struct{} // Subscribers (gRPC WatchApprovals)
+
+	// Relevance-based tool selection (cfg.ToolSelection)
+	embedder         memory.EmbeddingProvider // Injected; defaults to the memory store's
+	toolSelectorOnce sync.Once
+	toolSelector     *toolSelector
+
 	// Tool enhancement features
 	toolLoopDetector *ToolLoopDetector // Tool loop detection
 	thinkingConfig   ThinkingConfig    // Thinking mode config
@@ -122,6 +157,8 @@ type Message struct {
 	ToolCalls            []ToolCall   `json:"tool_calls,omitempty"`
 	ToolCallID           string       `json:"tool_call_id,omitempty"`
 	ToolExecutionResults []ToolResult `json:"tool_results,omitempty"`
+	// Parts carries image/audio content (e.g. browser screenshots) alongside Content
+	Parts []llm.ContentPart `json:"parts,omitempty"`
 }
 
 type ToolCall struct {
@@ -202,12 +239,13 @@ func toolsEqual(a, b []llm.Tool) bool {
 }
 
 type ChatRequest struct {
-	Model       string          `json:"model"`
-	Messages    []Message       `json:"messages"`
-	Temperature float64         `json:"temperature,omitempty"`
-	MaxTokens   int             `json:"max_tokens,omitempty"`
-	Tools       []llm.Tool      `json:"tools,omitempty"`
-	Stream      bool            `json:"stream,omitempty"`
+	Model          string              `json:"model"`
+	Messages       []Message           `json:"messages"`
+	Temperature    float64             `json:"temperature,omitempty"`
+	MaxTokens      int                 `json:"max_tokens,omitempty"`
+	Tools          []llm.Tool          `json:"tools,omitempty"`
+	Stream         bool                `json:"stream,omitempty"`
+	ResponseFormat *llm.ResponseFormat `json:"response_format,omitempty"` // JSON reply constraint; see ChatStructured
 }
 
 type ChatResponse struct {
@@ -245,6 +283,7 @@ func New(cfg Config) *Agent {
 		store:             cfg.Storage,
 		memoryStore:       cfg.MemoryStore,
 		registry:          cfg.Registry,
+		commands:          commands.NewRegistry(commands.Default),
 		timeProvider:      &defaultTimeProvider{},
 		idGenerator:       &defaultIDGenerator{},
 		logger:            &defaultLogger{},
@@ -316,7 +355,16 @@ func New(cfg Config) *Agent {
 
 	// Initialize pulse/heartbeat system
 	if cfg.PulseEnabled && cfg.Storage != nil {
-		a.pulse = NewPulseHandler(cfg.Storage, cfg.PulseConfig)
+		pulseCfg := cfg.PulseConfig
+		if mins := a.cfg.MemoryConsolidation.IntervalMinutes; mins > 0 && a.memoryStore != nil {
+			c := *DefaultPulseConfig()
+			if pulseCfg != nil {
+				c = *pulseCfg
+			}
+			c.MemoryConsolidateMins = mins
+			pulseCfg = &c
+		}
+		a.pulse = NewPulseHandler(cfg.Storage, pulseCfg)
 		a.pulse.SetLLMCallback(func(input string) (string, error) {
 			return a.Chat([]Message{{Role: "user", Content: input}}), nil
 		})
@@ -336,18 +384,28 @@ func New(cfg Config) *Agent {
 			log.Printf("[Pulse] Broadcast requested (priority=%d channel=%s): %s", priority, channel, tools.Truncate(message, 200))
 			return nil
 		})
+		a.pulse.SetConsolidateCallback(func() {
+			if _, err := a.ConsolidateMemories(context.Background(), "pulse", false); err != nil {
+				log.Printf("[Pulse] Memory consolidation failed: %v", err)
+			}
+		})
 		a.pulse.Start()
 		log.Printf("[Agent] Pulse/Heartbeat system started")
 	}
+	if days := a.cfg.MemoryConsolidation.HalfLifeDays; days > 0 && a.memoryStore != nil {
+		a.memoryStore.SetDecayHalfLife(time.Duration(days * float64(24*time.Hour)))
+	}
 
 	// Load OCG skills
+	a.registerBuiltinCommands()
 	a.loadSkills()
 
 	// Initialize tool enhancement features
 	a.toolLoopDetector = NewToolLoopDetector(DefaultToolLoopDetectionConfig)
 	if a.cfg.ThinkingMode != "" {
 		a.thinkingConfig = ThinkingConfig{
-			Mode: ParseThinkingMode(a.cfg.ThinkingMode),
+			Mode:      ParseThinkingMode(a.cfg.ThinkingMode),
+			MaxTokens: cmp.Or(a.cfg.ThinkingBudget, DefaultThinkingConfig.MaxTokens),
 		}
 	} else {
 		a.thinkingConfig = DefaultThinkingConfig
@@ -384,6 +442,11 @@ func (a *Agent) loadSkills() {
 		a.registry.Register(tool)
 		log.Printf("[Agent] Registered skill tool: %s", tool.Name())
 	}
+	for _, c := range adapter.GenerateCommands() {
+		if err := a.commands.Register(c); err != nil {
+			log.Printf("[WARN] skill command: %v", err)
+		}
+	}
 
 	log.Printf("[Agent] Loaded %d skill tools", len(skillTools))
 }
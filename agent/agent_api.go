@@ -1,127 +1,94 @@
-// agent_api.go - LLM API call layer: non-streaming HTTP requests and simple response mode
+// agent_api.go - LLM API call layer: non-streaming provider requests and simple response mode
 package agent
 
 import (
-	"bytes"
+	"cmp"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"strings"
 	"time"
 )
 
-func (a *Agent) callAPI(messages []Message) string {
-	return a.callAPIWithDepth(messages, 0)
+func (a *Agent) callAPI(ctx context.Context, sessionKey string, messages []Message) string {
+	return a.callAPIWithDepth(ctx, sessionKey, messages, 0)
 }
 
-func (a *Agent) callAPIWithDepth(messages []Message, depth int) string {
-	a.mu.RLock()
-	apiKey := a.cfg.APIKey
-	baseURL := a.cfg.BaseURL
-	model := a.cfg.Model
-	temperature := a.cfg.Temperature
-	maxTokens := a.cfg.MaxTokens
-
-	// Apply configuration groups if provider is set
-	if a.cfg.Provider != "" && a.cfg.Groups != nil {
-		if group, ok := a.cfg.Groups[a.cfg.Provider]; ok {
-			if group.APIKey != "" {
-				apiKey = group.APIKey
-			}
-			if group.BaseURL != "" {
-				baseURL = group.BaseURL
-			}
-			if group.Model != "" {
-				model = group.Model
-			}
-		}
-	}
-	a.mu.RUnlock()
-
-	reqBody := ChatRequest{
-		Model:       model,
-		Messages:    messages,
-		Temperature: temperature,
-		MaxTokens:   maxTokens,
-	}
-	if len(a.systemTools) == 0 {
-		a.refreshToolSpecs()
+func (a *Agent) callAPIWithDepth(ctx context.Context, sessionKey string, messages []Message, depth int) string {
+	if stop := a.budgetStop(sessionKey); stop != "" {
+		return stop
 	}
-
-	reqBody.Tools = a.systemTools
-
-	body, _ := json.Marshal(reqBody)
-	url := baseURL + "/chat/completions"
+	provider, pcfg := a.routedProvider(ctx, sessionKey, PurposeChat, messages)
+	req := a.newChatRequest(ctx, sessionKey, pcfg.Model, messages, false)
+	turn := structuredFrom(ctx)
+	turn.apply(provider, req)
 
 	// For tool result processing (depth > 0), use shorter timeout
-	ctx := context.Background()
+	callCtx := ctx
 	if depth > 0 {
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(context.Background(), 30*time.Second)
+		callCtx, cancel = context.WithTimeout(ctx, 30*time.Second)
 		defer cancel()
 		log.Printf("[FAST] depth=%d: using 30s timeout", depth)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
-	if err != nil {
-		return fmt.Sprintf("request build error: %v", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-
-	resp, err := a.client.Do(req)
-	if err != nil {
-		return fmt.Sprintf("API error: %v", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	respBody, err := io.ReadAll(resp.Body)
+	chatResp, err := provider.Chat(callCtx, req)
 	if err != nil {
-		return fmt.Sprintf("read error: %v", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Sprintf("API error (%d): %s", resp.StatusCode, string(respBody))
+		if ctx.Err() != nil {
+			return stoppedReply(ctx)
+		}
+		return formatProviderError(err)
 	}
 
 	a.updateAnthropicRateLimit()
 
-	var chatResp ChatResponse
-	if err := json.Unmarshal(respBody, &chatResp); err != nil {
-		return fmt.Sprintf("parse error: %v", err)
+	if len(chatResp.Choices) == 0 {
+		a.recordUsage(sessionKey, PurposeChat, provider.Type(), cmp.Or(chatResp.Model, pcfg.Model), &chatResp.Usage, messages, "")
+		return "no response"
+	}
+	assistantMsg := fromLLMMessage(chatResp.Choices[0].Message)
+	a.recordUsage(sessionKey, PurposeChat, provider.Type(), cmp.Or(chatResp.Model, pcfg.Model), &chatResp.Usage, messages, assistantMsg.Content)
+	if assistantMsg.Role == "" {
+		assistantMsg.Role = "assistant"
 	}
 
 	// handle tool call chain if returned (standard format)
-	if len(chatResp.Choices) > 0 && len(chatResp.Choices[0].Message.ToolCalls) > 0 {
+	if len(assistantMsg.ToolCalls) > 0 {
 		validCalls := make([]ToolCall, 0)
-		for _, tc := range chatResp.Choices[0].Message.ToolCalls {
+		for _, tc := range assistantMsg.ToolCalls {
 			if tc.Function.Name != "" && tc.Function.Arguments != "" {
 				validCalls = append(validCalls, tc)
 			}
 		}
 		if len(validCalls) > 0 {
-			assistantMsg := chatResp.Choices[0].Message
-			return a.handleToolCalls(messages, validCalls, &assistantMsg, depth, nil)
+			return a.handleToolCalls(ctx, sessionKey, messages, validCalls, &assistantMsg, depth, nil)
 		}
 	}
 
-	// handle custom tool call format (MiniMax, etc.)
-	if len(chatResp.Choices) > 0 {
-		content := chatResp.Choices[0].Message.Content
-
-		toolCalls := parseCustomToolCalls(content)
-		if len(toolCalls) > 0 {
-			assistantMsg := Message{Role: "assistant", Content: content, ToolCalls: toolCalls}
-			return a.handleToolCalls(messages, toolCalls, &assistantMsg, depth, nil)
+	// handle tool calls written into the text (local models, MiniMax, etc.);
+	// a structured reply is JSON meant for the caller, never a call
+	content := assistantMsg.Content
+	if parser := a.textToolCalls(pcfg); parser != nil && turn == nil {
+		if toolCalls, rest := parser.parse(content); len(toolCalls) > 0 {
+			customMsg := Message{Role: "assistant", Content: rest, ToolCalls: toolCalls}
+			return a.handleToolCalls(ctx, sessionKey, messages, toolCalls, &customMsg, depth, nil)
 		}
+	}
 
+	// A structured reply is the JSON alone; an invalid one goes back to the model
+	if turn != nil {
+		text, retry, err := turn.accept(content)
+		if err == nil {
+			return text
+		}
+		if retry {
+			retryMessages := append(messages[:len(messages):len(messages)], Message{Role: "assistant", Content: content}, structuredRetry(err))
+			return a.callAPIWithDepth(ctx, sessionKey, retryMessages, depth)
+		}
 		return content
 	}
 
-	return "no response"
+	return NewThinkingProcessor(a.thinkingConfig).WithThinking(chatResp.Choices[0].Message.Thinking(), content)
 }
 
 func (a *Agent) simpleResponse(messages []Message) string {
@@ -0,0 +1,202 @@
+// agent_approval.go - Human-in-the-loop approval of tool calls the policy marks "ask"
+package agent
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gliderlab/cogate/pkg/hooks"
+	"github.com/gliderlab/cogate/tools"
+)
+
+// defaultApprovalTimeout stays below the gateway's 60s agent RPC timeout so a
+// turn waiting for an answer ends on its own terms
+const defaultApprovalTimeout = 50 * time.Second
+
+// ApprovalRequest is a tool call paused until a human approves or denies it
+type ApprovalRequest struct {
+	ID         string `json:"id"`
+	SessionKey string `json:"sessionKey"`
+	Tool       string `json:"tool"`
+	Arguments  string `json:"arguments"`
+	Reason     string `json:"reason"`    // The ask rule that matched
+	ExpiresAt  int64  `json:"expiresAt"` // Unix seconds
+
+	decision chan bool
+}
+
+// Approval decisions as logged and reported in approval:decision events
+const (
+	approvalApproved  = "approved"
+	approvalDenied    = "denied"
+	approvalTimedOut  = "timeout"
+	approvalCancelled = "cancelled"
+)
+
+type approvalNotifierCtx struct{}
+
+// withApprovalNotifier makes approval requests raised under ctx also go out as
+// [TOOL_EVENT] chunks of the streaming callback (WebSocket clients)
+func withApprovalNotifier(ctx context.Context, callback func(string)) context.Context {
+	if callback == nil {
+		return ctx
+	}
+	var mu sync.Mutex
+	notify := func(req *ApprovalRequest) {
+		data, _ := json.Marshal(req)
+		mu.Lock()
+		defer mu.Unlock()
+		callback(`[TOOL_EVENT]{"type":"approval_request","approval":` + string(data) + `}`)
+	}
+	return context.WithValue(ctx, approvalNotifierCtx{}, notify)
+}
+
+// approvalTimeout returns how long a tool call waits for an answer
+func (a *Agent) approvalTimeout() time.Duration {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.cfg.Approvals.TimeoutSeconds > 0 {
+		return time.Duration(a.cfg.Approvals.TimeoutSeconds) * time.Second
+	}
+	return defaultApprovalTimeout
+}
+
+// awaitApproval pauses a tool call until it is approved, denied, times out or
+// its turn is cancelled. It returns the decision.
+func (a *Agent) awaitApproval(ctx context.Context, call ToolCall, reason string) string {
+	timeout := a.approvalTimeout()
+	req := &ApprovalRequest{
+		ID:         "approval-" + a.idGenerator.New(),
+		SessionKey: cmp.Or(tools.SessionKeyFrom(ctx), "default"),
+		Tool:       call.Function.Name,
+		Arguments:  call.Function.Arguments,
+		Reason:     reason,
+		ExpiresAt:  time.Now().Add(timeout).Unix(),
+		decision:   make(chan bool, 1),
+	}
+
+	a.approvalMu.Lock()
+	if a.approvals == nil {
+		a.approvals = make(map[string]*ApprovalRequest)
+	}
+	a.approvals[req.ID] = req
+	for ch := range a.approvalWatchers {
+		select {
+		case ch <- req:
+		default:
+			log.Printf("[APPROVAL] watcher full, dropped request %s", req.ID)
+		}
+	}
+	a.approvalMu.Unlock()
+	log.Printf("[APPROVAL] id=%s session=%s tool=%s reason=%q waiting %v", req.ID, req.SessionKey, req.Tool, reason, timeout)
+
+	if notify, ok := ctx.Value(approvalNotifierCtx{}).(func(*ApprovalRequest)); ok {
+		notify(req)
+	}
+
+	decision := approvalTimedOut
+	timer := time.NewTimer(timeout)
+	select {
+	case approved := <-req.decision:
+		decision = approvalDenied
+		if approved {
+			decision = approvalApproved
+		}
+	case <-timer.C:
+	case <-ctx.Done():
+		decision = approvalCancelled
+	}
+	timer.Stop()
+
+	a.approvalMu.Lock()
+	delete(a.approvals, req.ID)
+	a.approvalMu.Unlock()
+
+	log.Printf("[APPROVAL] id=%s session=%s tool=%s args=%s decision=%s", req.ID, req.SessionKey, req.Tool, req.Arguments, decision)
+	a.fireApprovalEvent(req, decision)
+	return decision
+}
+
+// ResolveApproval answers a pending request. The answer must come from the
+// session that raised it.
+func (a *Agent) ResolveApproval(sessionKey, id string, approve bool) error {
+	a.approvalMu.Lock()
+	defer a.approvalMu.Unlock()
+	req, ok := a.approvals[id]
+	if !ok || req.SessionKey != cmp.Or(sessionKey, "default") {
+		return fmt.Errorf("no pending approval %q", id)
+	}
+	delete(a.approvals, id)
+	req.decision <- approve
+	return nil
+}
+
+// WatchApprovals returns a channel of approval requests, starting with the
+// ones already pending. Call the returned func to stop watching.
+func (a *Agent) WatchApprovals() (<-chan *ApprovalRequest, func()) {
+	ch := make(chan *ApprovalRequest, 32)
+	a.approvalMu.Lock()
+	if a.approvalWatchers == nil {
+		a.approvalWatchers = make(map[chan *ApprovalRequest]struct{})
+	}
+	a.approvalWatchers[ch] = struct{}{}
+	for _, req := range a.approvals {
+		select {
+		case ch <- req:
+		default:
+		}
+	}
+	a.approvalMu.Unlock()
+
+	return ch, func() {
+		a.approvalMu.Lock()
+		delete(a.approvalWatchers, ch)
+		a.approvalMu.Unlock()
+	}
+}
+
+// approvalError is the error result of a call that did not get approval
+func approvalError(tool, decision string) error {
+	switch decision {
+	case approvalDenied:
+		return fmt.Errorf("%s was denied by the user", tool)
+	case approvalCancelled:
+		return fmt.Errorf("%s was cancelled while waiting for approval", tool)
+	default:
+		return fmt.Errorf("approval for %s timed out", tool)
+	}
+}
+
+// deniedReply ends a turn in which a call was refused approval; empty if none was
+func deniedReply(results []ToolResult) string {
+	for _, r := range results {
+		if m, ok := r.Result.(map[string]interface{}); ok && m["approval"] != nil && m["approval"] != approvalApproved {
+			return fmt.Sprintf("Stopped: %v.", m["error"])
+		}
+	}
+	return ""
+}
+
+// fireApprovalEvent queues an approval:decision hook event for the pulse system to dispatch
+func (a *Agent) fireApprovalEvent(req *ApprovalRequest, decision string) {
+	if a.store == nil {
+		return
+	}
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"id":         req.ID,
+		"sessionKey": req.SessionKey,
+		"tool":       req.Tool,
+		"arguments":  req.Arguments,
+		"reason":     req.Reason,
+		"decision":   decision,
+	})
+	msg := fmt.Sprintf("%s %s", req.Tool, decision)
+	if _, err := a.store.AddHookEvent("hook:"+string(hooks.EventTypeApprovalDecision), req.SessionKey, msg, string(metadata)); err != nil {
+		log.Printf("[WARN] failed to queue approval event: %v", err)
+	}
+}
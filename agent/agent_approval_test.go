@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gliderlab/cogate/pkg/config"
+	"github.com/gliderlab/cogate/pkg/llm/providers/mock"
+	"github.com/gliderlab/cogate/storage"
+	"github.com/gliderlab/cogate/tools"
+)
+
+// dangerTool counts how often it actually ran
+type dangerTool struct{ runs atomic.Int32 }
+
+func (d *dangerTool) Name() string        { return "danger" }
+func (d *dangerTool) Description() string { return "Does something risky" }
+func (d *dangerTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (d *dangerTool) Execute(args map[string]interface{}) (interface{}, error) {
+	d.runs.Add(1)
+	return "done", nil
+}
+
+func newApprovalAgent(t *testing.T, timeoutSeconds int) (*Agent, *mock.Provider, *dangerTool, *storage.Storage) {
+	t.Helper()
+	script, err := mock.Parse([]byte(`
+responses:
+  - tool_calls: [{id: call_1, name: danger, arguments: {target: prod}}]
+  - content: all done
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := storage.New(t.TempDir() + "/approval.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	tool := &dangerTool{}
+	reg := tools.NewRegistry()
+	reg.Register(tool)
+	policy := tools.DefaultToolsPolicy()
+	policy.Ask = []string{"danger"}
+	reg.SetPolicy(policy)
+
+	p := mock.NewFromScript(script)
+	a := NewAgentDI().WithConfig(config.AgentConfig{
+		Model: "m", APIKey: "k", ContextTokens: 8192,
+		Approvals: config.ApprovalConfig{TimeoutSeconds: timeoutSeconds},
+	}).WithRegistry(reg).WithStorage(store).Build()
+	a.WithProvider(p)
+	return a, p, tool, store
+}
+
+// answerNext waits for the next approval request and answers it as the user would
+func answerNext(t *testing.T, a *Agent, requests <-chan *ApprovalRequest, command string) *ApprovalRequest {
+	t.Helper()
+	select {
+	case req := <-requests:
+		if got := a.ChatWithSession(req.SessionKey, []Message{{Role: "user", Content: command + " " + req.ID}}); got == "" {
+			t.Errorf("%s reply empty", command)
+		}
+		return req
+	case <-time.After(5 * time.Second):
+		t.Fatal("no approval request raised")
+		return nil
+	}
+}
+
+func TestApprovalApproveResumesToolLoop(t *testing.T) {
+	a, p, tool, store := newApprovalAgent(t, 0)
+	requests, stop := a.WatchApprovals()
+	defer stop()
+
+	reply := make(chan string, 1)
+	go func() {
+		reply <- a.chatInternal(context.Background(), "telegram_1", []Message{{Role: "user", Content: "deploy"}})
+	}()
+	req := answerNext(t, a, requests, "/approve")
+	if req.Tool != "danger" || req.SessionKey != "telegram_1" || !strings.Contains(req.Arguments, "prod") {
+		t.Errorf("request = %+v", req)
+	}
+
+	if got := <-reply; got != "all done" {
+		t.Errorf("reply = %q", got)
+	}
+	if tool.runs.Load() != 1 || p.Calls() != 2 {
+		t.Errorf("tool runs = %d, LLM calls = %d", tool.runs.Load(), p.Calls())
+	}
+	events := hookEvents(t, store, "hook:approval:decision")
+	if len(events) != 1 || !strings.Contains(events[0].Content, "approved") {
+		t.Errorf("approval events = %v", events)
+	}
+}
+
+func TestApprovalDenyAbortsToolLoop(t *testing.T) {
+	a, p, tool, _ := newApprovalAgent(t, 0)
+	requests, stop := a.WatchApprovals()
+	defer stop()
+
+	reply := make(chan string, 1)
+	go func() {
+		reply <- a.chatInternal(context.Background(), "telegram_1", []Message{{Role: "user", Content: "deploy"}})
+	}()
+	answerNext(t, a, requests, "/deny")
+
+	if got := <-reply; got != "Stopped: danger was denied by the user." {
+		t.Errorf("reply = %q", got)
+	}
+	if tool.runs.Load() != 0 || p.Calls() != 1 {
+		t.Errorf("tool runs = %d, LLM calls = %d", tool.runs.Load(), p.Calls())
+	}
+}
+
+func TestApprovalTimeoutAndWrongSession(t *testing.T) {
+	a, _, tool, store := newApprovalAgent(t, 1)
+	requests, stop := a.WatchApprovals()
+	defer stop()
+
+	reply := make(chan string, 1)
+	go func() {
+		reply <- a.chatInternal(context.Background(), "telegram_1", []Message{{Role: "user", Content: "deploy"}})
+	}()
+	req := <-requests
+	// Another chat can't answer for this one
+	if got := a.ChatWithSession("telegram_2", []Message{{Role: "user", Content: "/approve " + req.ID}}); !strings.HasPrefix(got, "No pending approval") {
+		t.Errorf("foreign /approve reply = %q", got)
+	}
+
+	if got := <-reply; got != "Stopped: approval for danger timed out." {
+		t.Errorf("reply = %q", got)
+	}
+	if tool.runs.Load() != 0 {
+		t.Errorf("tool ran %d times without approval", tool.runs.Load())
+	}
+	events := hookEvents(t, store, "hook:approval:decision")
+	if len(events) != 1 || !strings.Contains(events[0].Content, "timeout") {
+		t.Errorf("approval events = %v", events)
+	}
+}
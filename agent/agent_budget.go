@@ -0,0 +1,237 @@
+// agent_budget.go - Spend and turn budgets per session, channel and cron job
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gliderlab/cogate/pkg/config"
+	"github.com/gliderlab/cogate/pkg/hooks"
+)
+
+// turnState tracks the spend of a session's in-flight turn
+type turnState struct {
+	started time.Time
+	cost    float64
+}
+
+// budgetScope is one configured budget that applies to a session
+type budgetScope struct {
+	kind     string // "session", "channel" or "cron"
+	key      string // Session key, channel name or cron job ID
+	budget   config.Budget
+	usageBy  string // storage.UsageFor scope counting this budget's tokens
+	usageKey string
+}
+
+// budgetViolation describes a limit that was reached
+type budgetViolation struct {
+	scope budgetScope
+	limit string // tokensPerDay, toolDepth or costPerTurn
+	max   float64
+	used  float64
+	hard  bool
+}
+
+func (v budgetViolation) message() string {
+	var msg string
+	switch v.limit {
+	case "tokensPerDay":
+		msg = fmt.Sprintf("%s %s used %.0f of %.0f tokens today", v.scope.kind, v.scope.key, v.used, v.max)
+	case "toolDepth":
+		msg = fmt.Sprintf("this turn ran %.0f tool-call rounds, the %s %s limit per turn", v.used, v.scope.kind, v.scope.key)
+	default:
+		msg = fmt.Sprintf("this turn cost $%.4f, the %s %s limit per turn is $%.4f", v.used, v.scope.kind, v.scope.key, v.max)
+	}
+	if !v.hard {
+		return "Budget warning: " + msg + "."
+	}
+	if v.limit == "tokensPerDay" {
+		return "Budget exceeded: " + msg + ". Stopping here; the limit resets at 00:00 UTC."
+	}
+	return "Budget exceeded: " + msg + ". Stopping here."
+}
+
+func lookupBudget(budgets map[string]config.Budget, key string) (config.Budget, bool) {
+	if b, ok := budgets[key]; ok {
+		return b, true
+	}
+	b, ok := budgets["*"]
+	return b, ok
+}
+
+// budgetScopes returns every budget that applies to the session
+func (a *Agent) budgetScopes(sessionKey string) []budgetScope {
+	a.mu.RLock()
+	cfg := a.cfg.Budgets
+	a.mu.RUnlock()
+
+	var scopes []budgetScope
+	if b, ok := lookupBudget(cfg.Sessions, sessionKey); ok {
+		scopes = append(scopes, budgetScope{kind: "session", key: sessionKey, budget: b, usageBy: "session", usageKey: sessionKey})
+	}
+	if ch := usageChannel(sessionKey); ch != "" {
+		if b, ok := lookupBudget(cfg.Channels, ch); ok {
+			scopes = append(scopes, budgetScope{kind: "channel", key: ch, budget: b, usageBy: "channel", usageKey: ch})
+		}
+	}
+	if jobID, ok := strings.CutPrefix(sessionKey, "cron:"); ok {
+		if b, ok := lookupBudget(cfg.Cron, jobID); ok {
+			scopes = append(scopes, budgetScope{kind: "cron", key: jobID, budget: b, usageBy: "session", usageKey: sessionKey})
+		}
+	}
+	return scopes
+}
+
+// beginTurn starts tracking the cost of a new turn; call the returned func when it ends
+func (a *Agent) beginTurn(sessionKey string) func() {
+	turn := &turnState{started: time.Now()}
+	a.budgetMu.Lock()
+	if a.turns == nil {
+		a.turns = make(map[string]*turnState)
+	}
+	a.turns[sessionKey] = turn
+	a.budgetMu.Unlock()
+
+	return func() {
+		a.budgetMu.Lock()
+		if a.turns[sessionKey] == turn {
+			delete(a.turns, sessionKey)
+		}
+		a.budgetMu.Unlock()
+	}
+}
+
+// addTurnCost adds the cost of an LLM call to the session's in-flight turn
+func (a *Agent) addTurnCost(sessionKey string, cost float64) {
+	a.budgetMu.Lock()
+	if turn := a.turns[sessionKey]; turn != nil {
+		turn.cost += cost
+	}
+	a.budgetMu.Unlock()
+}
+
+// budgetStop checks the daily token and per-turn cost limits before an LLM
+// call. It fires warnings for soft limits and returns the message to answer
+// with when a hard limit is reached, or "" to continue.
+func (a *Agent) budgetStop(sessionKey string) string {
+	scopes := a.budgetScopes(sessionKey)
+	if len(scopes) == 0 {
+		return ""
+	}
+
+	a.budgetMu.Lock()
+	var turnCost float64
+	var turnStarted time.Time
+	if turn := a.turns[sessionKey]; turn != nil {
+		turnCost, turnStarted = turn.cost, turn.started
+	}
+	a.budgetMu.Unlock()
+
+	now := time.Now().UTC()
+	day := now.Truncate(24 * time.Hour)
+	for _, s := range scopes {
+		if a.store != nil && (s.budget.Hard.TokensPerDay > 0 || s.budget.Soft.TokensPerDay > 0) {
+			total, err := a.store.UsageFor(s.usageBy, s.usageKey, day)
+			if err != nil {
+				log.Printf("[WARN] budget usage lookup failed: %v", err)
+			} else {
+				used := float64(total.PromptTokens + total.CompletionTokens)
+				if v, ok := checkLimit(s, "tokensPerDay", float64(s.budget.Soft.TokensPerDay), float64(s.budget.Hard.TokensPerDay), used); ok {
+					if msg := a.budgetViolated(sessionKey, v, day.Format("2006-01-02")); msg != "" {
+						return msg
+					}
+				}
+			}
+		}
+		if v, ok := checkLimit(s, "costPerTurn", s.budget.Soft.CostPerTurn, s.budget.Hard.CostPerTurn, turnCost); ok {
+			if msg := a.budgetViolated(sessionKey, v, turnStarted.Format(time.RFC3339Nano)); msg != "" {
+				return msg
+			}
+		}
+	}
+	return ""
+}
+
+// toolDepthStop checks the tool-call depth limit before a turn runs another
+// round of tool calls, given the number of rounds it already ran
+func (a *Agent) toolDepthStop(sessionKey string, rounds int) string {
+	for _, s := range a.budgetScopes(sessionKey) {
+		v, ok := checkLimit(s, "toolDepth", float64(s.budget.Soft.ToolDepth), float64(s.budget.Hard.ToolDepth), float64(rounds))
+		if !ok {
+			continue
+		}
+		if msg := a.budgetViolated(sessionKey, v, a.turnPeriod(sessionKey)); msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+// turnPeriod identifies the session's in-flight turn for once-per-turn warnings
+func (a *Agent) turnPeriod(sessionKey string) string {
+	a.budgetMu.Lock()
+	defer a.budgetMu.Unlock()
+	if turn := a.turns[sessionKey]; turn != nil {
+		return turn.started.Format(time.RFC3339Nano)
+	}
+	return ""
+}
+
+// checkLimit compares used against the hard limit, then the soft one
+func checkLimit(s budgetScope, limit string, soft, hard, used float64) (budgetViolation, bool) {
+	if hard > 0 && used >= hard {
+		return budgetViolation{scope: s, limit: limit, max: hard, used: used, hard: true}, true
+	}
+	if soft > 0 && used >= soft {
+		return budgetViolation{scope: s, limit: limit, max: soft, used: used}, true
+	}
+	return budgetViolation{}, false
+}
+
+// budgetViolated logs the violation and fires its hook event. Hard limits
+// return the stop message; soft limits warn once per period and return "".
+func (a *Agent) budgetViolated(sessionKey string, v budgetViolation, period string) string {
+	if !v.hard {
+		key := fmt.Sprintf("%s|%s|%s|%s", sessionKey, v.scope.kind, v.scope.key, v.limit)
+		a.budgetMu.Lock()
+		if a.budgetWarned == nil {
+			a.budgetWarned = make(map[string]string)
+		}
+		seen := a.budgetWarned[key] == period
+		a.budgetWarned[key] = period
+		a.budgetMu.Unlock()
+		if seen {
+			return ""
+		}
+		log.Printf("[BUDGET] soft limit: session=%s %s=%s %s %.4g/%.4g", sessionKey, v.scope.kind, v.scope.key, v.limit, v.used, v.max)
+		a.fireBudgetEvent(hooks.EventTypeBudgetWarning, sessionKey, v)
+		return ""
+	}
+
+	log.Printf("[BUDGET] hard limit: session=%s %s=%s %s %.4g/%.4g", sessionKey, v.scope.kind, v.scope.key, v.limit, v.used, v.max)
+	a.fireBudgetEvent(hooks.EventTypeBudgetExceeded, sessionKey, v)
+	return v.message()
+}
+
+// fireBudgetEvent queues a budget hook event for the pulse system to dispatch
+func (a *Agent) fireBudgetEvent(eventType hooks.EventType, sessionKey string, v budgetViolation) {
+	if a.store == nil {
+		return
+	}
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"sessionKey": sessionKey,
+		"scope":      v.scope.kind,
+		"key":        v.scope.key,
+		"limit":      v.limit,
+		"max":        v.max,
+		"used":       v.used,
+		"hard":       v.hard,
+	})
+	if _, err := a.store.AddHookEvent("hook:"+string(eventType), sessionKey, v.message(), string(metadata)); err != nil {
+		log.Printf("[WARN] failed to queue budget event: %v", err)
+	}
+}
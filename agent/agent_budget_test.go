@@ -0,0 +1,122 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gliderlab/cogate/pkg/config"
+	"github.com/gliderlab/cogate/pkg/llm"
+	"github.com/gliderlab/cogate/pkg/llm/providers/mock"
+	"github.com/gliderlab/cogate/storage"
+)
+
+func newBudgetAgent(t *testing.T, fixture string, budgets config.BudgetConfig) (*Agent, *mock.Provider, *storage.Storage) {
+	t.Helper()
+	script, err := mock.Parse([]byte(fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := storage.New(t.TempDir() + "/budget.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	p := mock.NewFromScript(script)
+	a := NewAgentDI().WithConfig(config.AgentConfig{
+		Model: "priced", APIKey: "k", ContextTokens: 8192,
+		Prices:  map[string]llm.ModelPrice{"priced": {Input: 10, Output: 10}},
+		Budgets: budgets,
+	}).WithStorage(store).Build()
+	a.WithProvider(p)
+	return a, p, store
+}
+
+func hookEvents(t *testing.T, store *storage.Storage, eventType string) []storage.Event {
+	t.Helper()
+	events, err := store.GetHookEvents(eventType, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestBudgetTokensPerDay(t *testing.T) {
+	a, p, store := newBudgetAgent(t, "loop: true\nresponses:\n  - content: ok\n", config.BudgetConfig{
+		Channels: map[string]config.Budget{
+			"telegram": {Soft: config.BudgetLimits{TokensPerDay: 500}, Hard: config.BudgetLimits{TokensPerDay: 1000}},
+		},
+	})
+	if _, err := store.AddUsage(storage.UsageRecord{SessionKey: "telegram_1", Channel: "telegram", PromptTokens: 600}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Over the soft limit: the turn runs and a single warning is queued
+	for i := 0; i < 2; i++ {
+		if got := a.chatInternal(context.Background(), "telegram_2", []Message{{Role: "user", Content: "hi"}}); got != "ok" {
+			t.Fatalf("turn %d: %q", i, got)
+		}
+	}
+	if n := len(hookEvents(t, store, "hook:budget:warning")); n != 1 {
+		t.Errorf("expected 1 warning event, got %d", n)
+	}
+
+	if _, err := store.AddUsage(storage.UsageRecord{SessionKey: "telegram_1", Channel: "telegram", PromptTokens: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	calls := p.Calls()
+	got := a.chatInternal(context.Background(), "telegram_2", []Message{{Role: "user", Content: "hi"}})
+	if !strings.HasPrefix(got, "Budget exceeded: channel telegram") || p.Calls() != calls {
+		t.Fatalf("expected stop without an LLM call, got %q (calls %d -> %d)", got, calls, p.Calls())
+	}
+	events := hookEvents(t, store, "hook:budget:exceeded")
+	if len(events) != 1 || events[0].HookName != "telegram_2" || !strings.Contains(events[0].Metadata, `"limit":"tokensPerDay"`) {
+		t.Fatalf("exceeded events = %+v", events)
+	}
+
+	// Other channels are not affected
+	if got := a.chatInternal(context.Background(), "discord_1", []Message{{Role: "user", Content: "hi"}}); got != "ok" {
+		t.Errorf("discord turn: %q", got)
+	}
+}
+
+func TestBudgetToolDepth(t *testing.T) {
+	a, p, store := newBudgetAgent(t, `
+loop: true
+responses:
+  - tool_calls: [{name: no_such_tool, arguments: {}}]
+`, config.BudgetConfig{Cron: map[string]config.Budget{"*": {Hard: config.BudgetLimits{ToolDepth: 1}}}})
+
+	got := a.chatInternal(context.Background(), "cron:nightly", []Message{{Role: "user", Content: "loop forever"}})
+	if !strings.Contains(got, "1 tool-call rounds, the cron nightly limit") {
+		t.Fatalf("unexpected reply: %q", got)
+	}
+	if p.Calls() != 2 {
+		t.Errorf("expected 2 LLM calls before the stop, got %d", p.Calls())
+	}
+	if len(hookEvents(t, store, "hook:budget:exceeded")) != 1 {
+		t.Error("expected an exceeded event")
+	}
+}
+
+func TestBudgetCostPerTurn(t *testing.T) {
+	a, p, _ := newBudgetAgent(t, `
+loop: true
+responses:
+  - tool_calls: [{name: no_such_tool, arguments: {}}]
+    usage: {prompt_tokens: 2000, completion_tokens: 0}
+`, config.BudgetConfig{Sessions: map[string]config.Budget{"*": {Hard: config.BudgetLimits{CostPerTurn: 0.01}}}})
+
+	// Each call costs $0.02, so the turn stops before the second call
+	got := a.chatInternal(context.Background(), "default", []Message{{Role: "user", Content: "hi"}})
+	if !strings.HasPrefix(got, "Budget exceeded: this turn cost $0.0200") || p.Calls() != 1 {
+		t.Fatalf("got %q after %d calls", got, p.Calls())
+	}
+
+	// The next turn starts from zero
+	a.chatInternal(context.Background(), "default", []Message{{Role: "user", Content: "again"}})
+	if p.Calls() != 2 {
+		t.Errorf("expected a new turn to call the LLM, calls = %d", p.Calls())
+	}
+}
@@ -0,0 +1,121 @@
+// agent_cancel.go - Cancelling a session's in-flight turns (/stop, client disconnects, cron timeouts)
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/gliderlab/cogate/pkg/commands"
+	"github.com/gliderlab/cogate/pkg/hooks"
+	"github.com/gliderlab/cogate/tools"
+)
+
+// sessionRun is the context the tool calls of one turn run under
+type sessionRun struct {
+	sessionKey string
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+type runKey struct{}
+
+// startRun registers a turn of the session that is cancelled when parent is
+// done or the session is stopped. Call the returned func when the turn ends.
+func (a *Agent) startRun(parent context.Context, sessionKey string) (context.Context, func()) {
+	p, ok := profileFrom(parent)
+	if !ok {
+		p, _ = a.lookupProfile("", sessionKey)
+		parent = withProfile(parent, p)
+	}
+	parent = tools.WithMemoryScope(parent, memoryScope(p, sessionKey))
+	parent = tools.WithMemoryAdmin(parent, a.commandLevel(sessionKey) >= commands.LevelAdmin)
+	ctx, cancel := context.WithCancel(tools.WithSessionKey(parent, sessionKey))
+	run := &sessionRun{sessionKey: sessionKey, cancel: cancel}
+	ctx = context.WithValue(ctx, runKey{}, run)
+	run.ctx = ctx
+	a.runMu.Lock()
+	if a.runs == nil {
+		a.runs = make(map[string][]*sessionRun)
+	}
+	a.runs[sessionKey] = append(a.runs[sessionKey], run)
+	a.runMu.Unlock()
+
+	return ctx, func() {
+		cancel()
+		a.runMu.Lock()
+		defer a.runMu.Unlock()
+		runs := a.runs[sessionKey]
+		for i, r := range runs {
+			if r == run {
+				runs = append(runs[:i], runs[i+1:]...)
+				break
+			}
+		}
+		if len(runs) == 0 {
+			delete(a.runs, sessionKey)
+		} else {
+			a.runs[sessionKey] = runs
+		}
+	}
+}
+
+// joinRun returns the run of a turn: ctx itself when the caller (the gRPC
+// service, ChatStructured) already started one for the session, otherwise a
+// new run under ctx. Call the returned func when the turn ends.
+func (a *Agent) joinRun(ctx context.Context, sessionKey string) (context.Context, func()) {
+	if run, ok := ctx.Value(runKey{}).(*sessionRun); ok && run.sessionKey == sessionKey {
+		return ctx, func() {}
+	}
+	return a.startRun(ctx, sessionKey)
+}
+
+// StopSession cancels every in-flight turn of the session: running tools are
+// interrupted and the turn ends without another model call. It returns the
+// number of turns cancelled.
+func (a *Agent) StopSession(sessionKey string) int {
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+	n := 0
+	for _, r := range a.runs[sessionKey] {
+		if r.ctx.Err() == nil {
+			r.cancel()
+			n++
+		}
+	}
+	if n > 0 {
+		log.Printf("[STOP] session=%s cancelled %d turn(s)", sessionKey, n)
+	}
+	return n
+}
+
+// controlIfRequested runs the immediate commands (/stop, /approve, /deny),
+// which have to bypass the normal command path so they work while another
+// turn of the session is running
+func (a *Agent) controlIfRequested(sessionKey string, messages []Message) (string, bool) {
+	if len(messages) == 0 {
+		return "", false
+	}
+	return a.dispatchCommand(context.Background(), sessionKey, strings.TrimSpace(messages[len(messages)-1].Content), true)
+}
+
+// fireStopEvent queues a command:stop hook event for the pulse system to dispatch
+func (a *Agent) fireStopEvent(sessionKey string) {
+	if a.store == nil {
+		return
+	}
+	metadata, _ := json.Marshal(map[string]interface{}{"sessionKey": sessionKey})
+	if _, err := a.store.AddHookEvent("hook:"+string(hooks.EventTypeCommandStop), sessionKey, "/stop", string(metadata)); err != nil {
+		log.Printf("[WARN] failed to queue stop event: %v", err)
+	}
+}
+
+// stoppedReply is the reply of a turn whose context ended while tools ran
+func stoppedReply(ctx context.Context) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "Stopped: the turn timed out."
+	}
+	return "Stopped."
+}
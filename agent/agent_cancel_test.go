@@ -0,0 +1,194 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gliderlab/cogate/pkg/config"
+	"github.com/gliderlab/cogate/pkg/llm/providers/mock"
+	"github.com/gliderlab/cogate/rpcproto"
+	"github.com/gliderlab/cogate/storage"
+	"github.com/gliderlab/cogate/tools"
+)
+
+// waitTool blocks until its context is done
+type waitTool struct{ started chan struct{} }
+
+func (w *waitTool) Name() string        { return "wait" }
+func (w *waitTool) Description() string { return "Waits until cancelled" }
+func (w *waitTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (w *waitTool) Execute(args map[string]interface{}) (interface{}, error) {
+	return w.ExecuteContext(context.Background(), args)
+}
+func (w *waitTool) ExecuteContext(ctx context.Context, _ map[string]interface{}) (interface{}, error) {
+	w.started <- struct{}{}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func newCancelAgent(t *testing.T) (*Agent, *mock.Provider, *waitTool, *storage.Storage) {
+	t.Helper()
+	script, err := mock.Parse([]byte("loop: true\nresponses:\n  - tool_calls: [{name: wait, arguments: {}}]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := storage.New(t.TempDir() + "/cancel.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	tool := &waitTool{started: make(chan struct{}, 1)}
+	reg := tools.NewRegistry()
+	reg.Register(tool)
+	p := mock.NewFromScript(script)
+	a := NewAgentDI().WithConfig(config.AgentConfig{Model: "m", APIKey: "k", ContextTokens: 8192}).
+		WithRegistry(reg).WithStorage(store).Build()
+	a.WithProvider(p)
+	return a, p, tool, store
+}
+
+func TestStopCommandCancelsRunningTool(t *testing.T) {
+	a, p, tool, store := newCancelAgent(t)
+
+	reply := make(chan string, 1)
+	go func() {
+		reply <- a.chatInternal(context.Background(), "telegram_1", []Message{{Role: "user", Content: "wait for it"}})
+	}()
+	select {
+	case <-tool.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("tool never started")
+	}
+
+	if got := a.ChatWithSession("telegram_1", []Message{{Role: "user", Content: "/stop"}}); got != "Stopped." {
+		t.Errorf("/stop reply = %q", got)
+	}
+	select {
+	case got := <-reply:
+		if got != "Stopped." {
+			t.Errorf("stopped turn reply = %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("turn kept running after /stop")
+	}
+	if p.Calls() != 1 {
+		t.Errorf("expected no LLM call after the stop, got %d calls", p.Calls())
+	}
+	if len(hookEvents(t, store, "hook:command:stop")) != 1 {
+		t.Error("expected a command:stop event")
+	}
+
+	// The session takes new turns afterwards
+	go a.chatInternal(context.Background(), "telegram_1", []Message{{Role: "user", Content: "again"}})
+	select {
+	case <-tool.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("tool of the next turn never started")
+	}
+	if n := a.StopSession("telegram_1"); n != 1 {
+		t.Errorf("StopSession cancelled %d turns, want 1", n)
+	}
+}
+
+func TestGRPCChatDeadlineStopsTools(t *testing.T) {
+	a, _, _, _ := newCancelAgent(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	reply, err := NewGRPCService(a).Chat(ctx, &rpcproto.ChatArgs{
+		SessionKey: "cron:nightly",
+		Isolated:   true,
+		Messages:   []*rpcproto.Message{{Role: "user", Content: "run"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Content != "Stopped: the turn timed out." {
+		t.Errorf("reply = %q", reply.Content)
+	}
+}
+
+func TestRunContextStopsModelCall(t *testing.T) {
+	script, err := mock.Parse([]byte("loop: true\nresponses:\n  - content: late\n    delay: 10s\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAgentDI().WithConfig(config.AgentConfig{Model: "m", APIKey: "k", ContextTokens: 8192}).Build()
+	a.WithProvider(mock.NewFromScript(script))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	reply, err := NewGRPCService(a).Chat(ctx, &rpcproto.ChatArgs{
+		SessionKey: "cron:nightly",
+		Isolated:   true,
+		Messages:   []*rpcproto.Message{{Role: "user", Content: "run"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Content != "Stopped: the turn timed out." || time.Since(start) > 5*time.Second {
+		t.Errorf("reply = %q after %v", reply.Content, time.Since(start))
+	}
+
+	stopped, stop := context.WithCancel(context.Background())
+	stop()
+	var streamed string
+	a.ChatStreamWithSessionContext(stopped, "telegram_1", []Message{{Role: "user", Content: "run"}}, func(s string) { streamed += s })
+	if streamed != "Stopped." {
+		t.Errorf("stream = %q", streamed)
+	}
+}
+
+func TestStopCancelsSplit(t *testing.T) {
+	script, err := mock.Parse([]byte(`
+responses:
+  - content: '{"subtasks": ["pack", "travel"]}'
+  - content: packed
+    delay: 10s
+  - content: travelled
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := storage.New(t.TempDir() + "/split.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	p := mock.NewFromScript(script)
+	a := NewAgentDI().WithConfig(config.AgentConfig{Model: "m", APIKey: "k", ContextTokens: 8192}).
+		WithRegistry(tools.NewRegistry()).WithStorage(store).Build()
+	a.WithProvider(p)
+
+	reply := make(chan string, 1)
+	go func() {
+		reply <- a.chatInternal(context.Background(), "telegram_1", []Message{{Role: "user", Content: "/split plan a trip"}})
+	}()
+	for deadline := time.Now().Add(5 * time.Second); p.Calls() < 2; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("first subtask never started")
+		}
+	}
+
+	a.ChatWithSession("telegram_1", []Message{{Role: "user", Content: "/stop"}})
+	select {
+	case got := <-reply:
+		if got != "Stopped." {
+			t.Errorf("stopped split reply = %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("split kept running after /stop")
+	}
+	if p.Calls() != 2 {
+		t.Errorf("expected no subtask after the stop, got %d calls", p.Calls())
+	}
+	// The split's calls are the session's
+	if usage, _ := store.GetUsage("telegram_1", 10); len(usage) == 0 || usage[len(usage)-1].Purpose != string(PurposeSplit) {
+		t.Errorf("session usage = %+v", usage)
+	}
+}
@@ -2,7 +2,9 @@
 package agent
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
@@ -12,6 +14,7 @@ import (
 
 	"github.com/gliderlab/cogate/pkg/llm"
 	googleprovider "github.com/gliderlab/cogate/pkg/llm/providers/google"
+	openaiprovider "github.com/gliderlab/cogate/pkg/llm/providers/openai"
 )
 
 func realtimeDirective(last string) string {
@@ -41,6 +44,10 @@ func (a *Agent) shouldUseRealtime(sessionKey string, messages []Message) bool {
 	}
 
 	last := strings.TrimSpace(messages[len(messages)-1].Content)
+	if strings.HasPrefix(last, "[realtime-fallback]") {
+		// Already failed over from this session's live backend
+		return false
+	}
 	switch realtimeDirective(last) {
 	case "force_http":
 		return false
@@ -63,6 +70,23 @@ func (a *Agent) shouldUseRealtime(sessionKey string, messages []Message) bool {
 	return false
 }
 
+// useOpenAIRealtime reports whether live sessions speak the OpenAI Realtime
+// protocol to the configured endpoint rather than the Gemini Live API: always
+// for "*realtime*" models (gpt-4o-realtime-preview, gpt-realtime), and for
+// OpenAI-compatible configs that have a key but no Gemini key.
+func useOpenAIRealtime(cfg llm.Config) bool {
+	model := strings.ToLower(cfg.Model)
+	switch {
+	case strings.Contains(model, "realtime"):
+		return true
+	case strings.Contains(model, "gemini"):
+		return false
+	case cfg.Type != "" && cfg.Type != llm.ProviderOpenAI && cfg.Type != llm.ProviderCustom:
+		return false
+	}
+	return cfg.APIKey != "" && os.Getenv("GEMINI_API_KEY") == "" && os.Getenv("GOOGLE_API_KEY") == ""
+}
+
 func (a *Agent) realtimeModel() string {
 	m := strings.TrimSpace(a.cfg.Model)
 	if strings.Contains(strings.ToLower(m), "gemini") {
@@ -71,36 +95,66 @@ func (a *Agent) realtimeModel() string {
 	return "models/gemini-2.5-flash-native-audio-preview-12-2025"
 }
 
-func (a *Agent) getRealtimeProvider(sessionKey string) (llm.RealtimeProvider, error) {
+func (a *Agent) getRealtimeProvider(ctx context.Context, sessionKey string) (llm.RealtimeProvider, error) {
 	if p, ok := a.getCachedRealtime(sessionKey); ok {
 		return p, nil
 	}
 
-	// Priority: GEMINI_API_KEY -> GOOGLE_API_KEY -> config API key
-	apiKey := strings.TrimSpace(os.Getenv("GEMINI_API_KEY"))
-	if apiKey == "" {
-		apiKey = strings.TrimSpace(os.Getenv("GOOGLE_API_KEY"))
-	}
-	if apiKey == "" {
-		apiKey = strings.TrimSpace(a.cfg.APIKey)
-	}
-	if apiKey == "" {
-		return nil, fmt.Errorf("google live API key not configured (set GEMINI_API_KEY or GOOGLE_API_KEY env var)")
-	}
-	shortKey := "****"
-	if len(apiKey) > 4 {
-		shortKey = apiKey[:4] + "****"
+	if len(a.systemTools) == 0 {
+		a.refreshToolSpecs()
 	}
-	log.Printf("[realtime] Using API key: %s", shortKey)
 
-	cfg := llm.RealtimeConfig{
-		Model:                    a.realtimeModel(),
-		APIKey:                   apiKey,
-		Voice:                    "Kore",
-		InputAudioTranscription:  true,
-		OutputAudioTranscription: true,
+	var (
+		provider llm.Provider
+		cfg      llm.RealtimeConfig
+	)
+	if pcfg := a.providerConfig(); useOpenAIRealtime(pcfg) {
+		if pcfg.APIKey == "" {
+			pcfg.APIKey = strings.TrimSpace(os.Getenv("OPENAI_API_KEY"))
+		}
+		if pcfg.APIKey == "" {
+			return nil, fmt.Errorf("openai realtime API key not configured")
+		}
+		log.Printf("[realtime] Using OpenAI realtime endpoint: %s", cmp.Or(pcfg.BaseURL, "api.openai.com"))
+
+		cfg = llm.RealtimeConfig{
+			APIKey:                   pcfg.APIKey,
+			BaseURL:                  pcfg.BaseURL,
+			InputAudioTranscription:  true,
+			OutputAudioTranscription: true,
+			Tools:                    a.profileFor(ctx, sessionKey).filterTools(a.systemTools),
+		}
+		pcfg.Type = llm.ProviderOpenAI
+		provider = openaiprovider.New(pcfg)
+	} else {
+		// Priority: GEMINI_API_KEY -> GOOGLE_API_KEY -> config API key
+		apiKey := strings.TrimSpace(os.Getenv("GEMINI_API_KEY"))
+		if apiKey == "" {
+			apiKey = strings.TrimSpace(os.Getenv("GOOGLE_API_KEY"))
+		}
+		if apiKey == "" {
+			apiKey = strings.TrimSpace(a.cfg.APIKey)
+		}
+		if apiKey == "" {
+			return nil, fmt.Errorf("google live API key not configured (set GEMINI_API_KEY or GOOGLE_API_KEY env var)")
+		}
+		shortKey := "****"
+		if len(apiKey) > 4 {
+			shortKey = apiKey[:4] + "****"
+		}
+		log.Printf("[realtime] Using API key: %s", shortKey)
+
+		cfg = llm.RealtimeConfig{
+			Model:                    a.realtimeModel(),
+			APIKey:                   apiKey,
+			Voice:                    "Kore",
+			InputAudioTranscription:  true,
+			OutputAudioTranscription: true,
+			Tools:                    a.profileFor(ctx, sessionKey).filterTools(a.systemTools),
+		}
+		provider = googleprovider.New(llm.Config{Type: llm.ProviderGoogle, APIKey: apiKey, Model: cfg.Model})
 	}
-	provider := googleprovider.New(llm.Config{Type: llm.ProviderGoogle, APIKey: apiKey, Model: cfg.Model})
+
 	rt, err := provider.Realtime(context.Background(), cfg)
 	if err != nil {
 		return nil, err
@@ -113,13 +167,15 @@ func (a *Agent) getRealtimeProvider(sessionKey string) (llm.RealtimeProvider, er
 	return rt, nil
 }
 
-func (a *Agent) chatWithRealtimeSession(sessionKey string, messages []Message) string {
+func (a *Agent) chatWithRealtimeSession(ctx context.Context, sessionKey string, messages []Message) string {
 	if len(messages) == 0 {
 		return ""
 	}
 
 	unlock := a.getRealtimeSessionLock(sessionKey)
 	defer unlock()
+	ctx, endRun := a.joinRun(ctx, sessionKey)
+	defer endRun()
 
 	lastMsg := strings.TrimSpace(messages[len(messages)-1].Content)
 	audioFile := ""
@@ -139,10 +195,10 @@ func (a *Agent) chatWithRealtimeSession(sessionKey string, messages []Message) s
 		return "(live) empty input"
 	}
 
-	rt, err := a.getRealtimeProvider(sessionKey)
+	rt, err := a.getRealtimeProvider(ctx, sessionKey)
 	if err != nil {
 		log.Printf("[realtime] init failed: %v, falling back to http", err)
-		return a.fallbackToHTTP(sessionKey, messages, err.Error())
+		return a.fallbackToHTTP(ctx, sessionKey, messages, err.Error())
 	}
 
 	if a.store != nil {
@@ -156,10 +212,11 @@ func (a *Agent) chatWithRealtimeSession(sessionKey string, messages []Message) s
 	}
 
 	var (
-		mu         sync.Mutex
-		textBuf    strings.Builder
-		lastUpdate time.Time
-		errReason  string
+		mu           sync.Mutex
+		textBuf      strings.Builder
+		lastUpdate   time.Time
+		errReason    string
+		pendingTools int
 	)
 	rt.OnText(func(text string) {
 		if text == "" {
@@ -177,27 +234,49 @@ func (a *Agent) chatWithRealtimeSession(sessionKey string, messages []Message) s
 		}
 		mu.Unlock()
 	})
+	rt.OnToolCall(func(tc llm.ToolCall) {
+		if tc.Function == nil {
+			return
+		}
+		mu.Lock()
+		pendingTools++
+		lastUpdate = time.Now()
+		mu.Unlock()
+
+		// Run off the receive loop; the model continues once the result is sent
+		go func() {
+			result := a.executeRealtimeToolCall(ctx, tc)
+			err := rt.SendToolResponse(context.Background(), llm.ToolResponse{ID: tc.ID, Name: tc.Function.Name, Result: result})
+			mu.Lock()
+			pendingTools--
+			lastUpdate = time.Now()
+			if err != nil && errReason == "" {
+				errReason = err.Error()
+			}
+			mu.Unlock()
+		}()
+	})
 
 	if audioFile != "" {
 		pcmData, err := os.ReadFile(audioFile)
 		_ = os.Remove(audioFile)
 		if err != nil {
 			log.Printf("[realtime] read audio failed: %v, falling back to http", err)
-			return a.fallbackToHTTP(sessionKey, messages, err.Error())
+			return a.fallbackToHTTP(ctx, sessionKey, messages, err.Error())
 		}
 		if err := rt.SendAudio(context.Background(), pcmData); err != nil {
 			log.Printf("[realtime] send audio failed: %v, falling back to http", err)
-			return a.fallbackToHTTP(sessionKey, messages, err.Error())
+			return a.fallbackToHTTP(ctx, sessionKey, messages, err.Error())
 		}
 		if err := rt.EndAudio(context.Background()); err != nil {
 			log.Printf("[realtime] end audio failed: %v, falling back to http", err)
-			return a.fallbackToHTTP(sessionKey, messages, err.Error())
+			return a.fallbackToHTTP(ctx, sessionKey, messages, err.Error())
 		}
 	}
 	if lastMsg != "" {
 		if err := rt.SendText(context.Background(), lastMsg); err != nil {
 			log.Printf("[realtime] send text failed: %v, falling back to http", err)
-			return a.fallbackToHTTP(sessionKey, messages, err.Error())
+			return a.fallbackToHTTP(ctx, sessionKey, messages, err.Error())
 		}
 	}
 	a.touchRealtimeInMemory(sessionKey)
@@ -209,12 +288,13 @@ func (a *Agent) chatWithRealtimeSession(sessionKey string, messages []Message) s
 		content := strings.TrimSpace(textBuf.String())
 		lu := lastUpdate
 		errMsg := errReason
+		tools := pendingTools
 		mu.Unlock()
 		if errMsg != "" {
 			log.Printf("[realtime] error during session: %s, falling back to http", errMsg)
-			return a.fallbackToHTTP(sessionKey, messages, errMsg)
+			return a.fallbackToHTTP(ctx, sessionKey, messages, errMsg)
 		}
-		if content != "" && !lu.IsZero() && time.Since(lu) > 800*time.Millisecond {
+		if content != "" && tools == 0 && !lu.IsZero() && time.Since(lu) > 800*time.Millisecond {
 			if a.store != nil {
 				a.storeMessage(sessionKey, "assistant", content)
 				_ = a.store.TouchRealtimeSession(sessionKey)
@@ -231,7 +311,7 @@ func (a *Agent) chatWithRealtimeSession(sessionKey string, messages []Message) s
 	if content == "" {
 		if errMsg != "" {
 			log.Printf("[realtime] session error: %s, falling back to http", errMsg)
-			return a.fallbackToHTTP(sessionKey, messages, errMsg)
+			return a.fallbackToHTTP(ctx, sessionKey, messages, errMsg)
 		}
 		content = "(live) no response received"
 	}
@@ -243,6 +323,28 @@ func (a *Agent) chatWithRealtimeSession(sessionKey string, messages []Message) s
 	return content
 }
 
+// executeRealtimeToolCall runs a tool requested by a live session and returns
+// its result. Backends that only send parsed arguments get them re-encoded.
+func (a *Agent) executeRealtimeToolCall(ctx context.Context, tc llm.ToolCall) any {
+	call := ToolCall{ID: tc.ID, Type: "function"}
+	call.Function.Name = tc.Function.Name
+	call.Function.Arguments = tc.Function.Arguments
+	if call.Function.Arguments == "" {
+		call.Function.Arguments = "{}"
+		if tc.Function.Parameters != nil {
+			if data, err := json.Marshal(tc.Function.Parameters); err == nil {
+				call.Function.Arguments = string(data)
+			}
+		}
+	}
+	log.Printf("[realtime] tool call: %s", call.Function.Name)
+	results := a.executeToolCalls(ctx, []ToolCall{call})
+	if len(results) == 0 {
+		return nil
+	}
+	return results[0].Result
+}
+
 // SendAudioChunk sends audio data to an active live session (for streaming voice input)
 func (a *Agent) SendAudioChunk(sessionKey string, pcmData []byte) error {
 	a.realtimeMu.Lock()
@@ -268,7 +370,7 @@ func (a *Agent) EndAudioStream(sessionKey string) error {
 	return rt.EndAudio(context.Background())
 }
 
-func (a *Agent) fallbackToHTTP(sessionKey string, messages []Message, reason string) string {
+func (a *Agent) fallbackToHTTP(ctx context.Context, sessionKey string, messages []Message, reason string) string {
 	log.Printf("[realtime->http] falling back: %s", reason)
 	cleaned := make([]Message, len(messages))
 	copy(cleaned, messages)
@@ -284,10 +386,19 @@ func (a *Agent) fallbackToHTTP(sessionKey string, messages []Message, reason str
 	if len(cleaned) > 0 {
 		cleaned[len(cleaned)-1].Content = "[realtime-fallback] " + cleaned[len(cleaned)-1].Content
 	}
-	return a.ChatWithSession(sessionKey, cleaned)
+	return a.ChatWithSessionContext(ctx, sessionKey, cleaned)
 }
 
 func (a *Agent) ChatWithSession(sessionKey string, messages []Message) string {
+	return a.ChatWithSessionContext(context.Background(), sessionKey, messages)
+}
+
+// ChatWithSessionContext is ChatWithSession for a turn bound to ctx: provider
+// calls and tools stop when it is done
+func (a *Agent) ChatWithSessionContext(ctx context.Context, sessionKey string, messages []Message) string {
+	if reply, ok := a.controlIfRequested(sessionKey, messages); ok {
+		return reply
+	}
 	if len(messages) > 0 {
 		idx := len(messages) - 1
 		last := strings.TrimSpace(messages[idx].Content)
@@ -299,7 +410,7 @@ func (a *Agent) ChatWithSession(sessionKey string, messages []Message) string {
 	}
 
 	if a.shouldUseRealtime(sessionKey, messages) {
-		return a.chatWithRealtimeSession(sessionKey, messages)
+		return a.chatWithRealtimeSession(ctx, sessionKey, messages)
 	}
 
 	isNewSession := false
@@ -324,21 +435,28 @@ func (a *Agent) ChatWithSession(sessionKey string, messages []Message) string {
 
 	// Inject BOOT.md for new sessions
 	if isNewSession {
-		if bootMsg := a.loadBootMD(); bootMsg != nil {
+		if bootMsg := a.bootMessage(ctx, sessionKey); bootMsg != nil {
 			messages = append([]Message{*bootMsg}, messages...)
 			log.Printf("[BOOT] BOOT.md injected as system message")
 		}
 	}
 
-	return a.chatInternal(sessionKey, messages)
+	return a.chatInternal(ctx, sessionKey, messages)
 }
 
 func (a *Agent) Chat(messages []Message) string {
-	return a.chatInternal("default", messages)
+	return a.chatInternal(context.Background(), "default", messages)
 }
 
 // chatInternal is the core chat logic with explicit sessionKey to avoid double-storing.
-func (a *Agent) chatInternal(sessionKey string, messages []Message) string {
+func (a *Agent) chatInternal(ctx context.Context, sessionKey string, messages []Message) string {
+	if reply, ok := a.controlIfRequested(sessionKey, messages); ok {
+		return reply
+	}
+	ctx, endRun := a.joinRun(ctx, sessionKey)
+	defer endRun()
+	defer a.beginTurn(sessionKey)()
+
 	lastMsg := ""
 	for i := len(messages) - 1; i >= 0; i-- {
 		if messages[i].Role == "user" {
@@ -361,16 +479,17 @@ func (a *Agent) chatInternal(sessionKey string, messages []Message) string {
 	// NOTE: realtime/live mode check was removed here to prevent double-checking
 	// and fallback loops. It is correctly handled in ChatWithSession.
 
-	if out, ok := a.runCommandIfRequested(lastMsg); ok {
+	if out, ok := a.runCommandIfRequested(ctx, sessionKey, lastMsg); ok {
 		return finalize(out)
 	}
+	messages = a.expandPromptCommand(sessionKey, messages)
 
 	// Shared preprocessing: auto memory capture + flush + compaction
-	a.preprocessChat(sessionKey, lastMsg, messages)
+	a.preprocessChat(ctx, sessionKey, lastMsg, messages)
 
 	// Handle tool calls
 	if len(messages) > 0 && len(messages[len(messages)-1].ToolCalls) > 0 {
-		return finalize(a.handleToolCalls(messages, messages[len(messages)-1].ToolCalls, nil, 0, nil))
+		return finalize(a.handleToolCalls(ctx, sessionKey, messages, messages[len(messages)-1].ToolCalls, nil, 0, nil))
 	}
 
 	// Detect edit intent
@@ -385,7 +504,7 @@ func (a *Agent) chatInternal(sessionKey string, messages []Message) string {
 	if len(messages) > 0 && a.memoryStore != nil {
 		lastUserMsg := messages[len(messages)-1].Content
 		if isRecallRequest(lastUserMsg) {
-			if memories := a.recallRelevantMemories(lastUserMsg); memories != "" {
+			if memories := a.recallRelevantMemories(ctx, sessionKey, lastUserMsg); memories != "" {
 				log.Printf("recall command injected %d memories", strings.Count(memories, "- ["))
 				injected := Message{Role: "system", Content: memories}
 				messages = append([]Message{injected}, messages...)
@@ -396,7 +515,7 @@ func (a *Agent) chatInternal(sessionKey string, messages []Message) string {
 	// Auto recall: inject relevant memories as a system message before sending to model
 	if a.cfg.AutoRecall && a.memoryStore != nil && len(messages) > 0 {
 		lastUserMsg := messages[len(messages)-1].Content
-		if memories := a.recallRelevantMemories(lastUserMsg); memories != "" {
+		if memories := a.recallRelevantMemories(ctx, sessionKey, lastUserMsg); memories != "" {
 			log.Printf("auto-recall injected %d memories", strings.Count(memories, "- ["))
 			injected := Message{Role: "system", Content: memories}
 			messages = append([]Message{injected}, messages...)
@@ -404,17 +523,17 @@ func (a *Agent) chatInternal(sessionKey string, messages []Message) string {
 	}
 
 	// Inject core System Prompt
-	sysPrompt := Message{Role: "system", Content: a.GetSystemPrompt()}
+	sysPrompt := Message{Role: "system", Content: a.systemPrompt(ctx, sessionKey)}
 	messages = append([]Message{sysPrompt}, messages...)
 
 	// overflow handling
 	if a.store != nil {
-		messages = a.handleContextOverflow(sessionKey, messages)
+		messages = a.handleContextOverflow(ctx, sessionKey, messages)
 	}
 
-	if a.cfg.APIKey == "" {
+	if !a.hasLLM() {
 		return finalize(a.simpleResponse(messages))
 	}
 
-	return finalize(a.callAPI(messages))
+	return finalize(a.callAPI(ctx, sessionKey, messages))
 }
@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/gliderlab/cogate/pkg/config"
+	"github.com/gliderlab/cogate/pkg/llm"
+	"github.com/gliderlab/cogate/tools"
+)
+
+type clockTool struct{}
+
+func (clockTool) Name() string        { return "clock" }
+func (clockTool) Description() string { return "Current time in a zone" }
+func (clockTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": map[string]interface{}{"zone": map[string]interface{}{"type": "string"}}}
+}
+func (clockTool) Execute(args map[string]interface{}) (interface{}, error) {
+	return "12:00 " + args["zone"].(string), nil
+}
+
+// TestLiveRoutesToOpenAIRealtime drives a /live turn through a local
+// OpenAI Realtime stand-in, including one tool call.
+func TestLiveRoutesToOpenAIRealtime(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		session map[string]any
+		outputs []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/realtime" || r.Header.Get("Authorization") != "Bearer k" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.CloseNow()
+		ctx := context.Background()
+		send := func(ev map[string]any) {
+			data, _ := json.Marshal(ev)
+			_ = conn.Write(ctx, websocket.MessageText, data)
+		}
+		for {
+			_, data, err := conn.Read(ctx)
+			if err != nil {
+				return
+			}
+			var ev map[string]any
+			_ = json.Unmarshal(data, &ev)
+			mu.Lock()
+			switch ev["type"] {
+			case "session.update":
+				session = ev["session"].(map[string]any)
+			case "conversation.item.create":
+				if item := ev["item"].(map[string]any); item["type"] == "function_call_output" {
+					outputs = append(outputs, item["output"].(string))
+				}
+			}
+			n := len(outputs)
+			mu.Unlock()
+
+			if ev["type"] != "response.create" {
+				continue
+			}
+			if n == 0 {
+				send(map[string]any{"type": "response.function_call_arguments.done", "call_id": "call_1", "name": "clock", "arguments": `{"zone":"UTC"}`})
+				send(map[string]any{"type": "response.done", "response": map[string]any{"status": "completed"}})
+				continue
+			}
+			send(map[string]any{"type": "response.audio_transcript.delta", "delta": "It is noon "})
+			send(map[string]any{"type": "response.audio_transcript.delta", "delta": "in UTC."})
+			send(map[string]any{"type": "response.done", "response": map[string]any{"status": "completed"}})
+		}
+	}))
+	defer srv.Close()
+
+	reg := tools.NewRegistry()
+	reg.Register(clockTool{})
+	a := NewAgentDI().WithConfig(config.AgentConfig{
+		Model: "gpt-4o-realtime-preview", APIKey: "k", BaseURL: srv.URL + "/v1", ContextTokens: 8192,
+	}).WithRegistry(reg).Build()
+	defer a.closeIdleRealtimeSessions(time.Nanosecond)
+
+	got := a.ChatWithSession("live:test", []Message{{Role: "user", Content: "/live what time is it?"}})
+	if got != "It is noon in UTC." {
+		t.Fatalf("reply = %q", got)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var toolNames []string
+	for _, tool := range session["tools"].([]any) {
+		toolNames = append(toolNames, tool.(map[string]any)["name"].(string))
+	}
+	if !strings.Contains(strings.Join(toolNames, ","), "clock") {
+		t.Errorf("session tools = %v", toolNames)
+	}
+	if len(outputs) != 1 || !strings.Contains(outputs[0], "12:00 UTC") {
+		t.Errorf("tool outputs = %v", outputs)
+	}
+}
+
+func TestUseOpenAIRealtime(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "")
+	t.Setenv("GOOGLE_API_KEY", "")
+	cases := []struct {
+		cfg  llm.Config
+		want bool
+	}{
+		{llm.Config{Model: "gpt-4o-realtime-preview"}, true},
+		{llm.Config{Type: llm.ProviderCustom, Model: "gpt-realtime", APIKey: "k"}, true},
+		{llm.Config{Model: "gpt-4o", APIKey: "k"}, true},
+		{llm.Config{Model: "gemini-2.5-flash", APIKey: "k"}, false},
+		{llm.Config{Type: llm.ProviderGoogle, Model: "x", APIKey: "k"}, false},
+		{llm.Config{Type: llm.ProviderAnthropic, Model: "claude", APIKey: "k"}, false},
+		{llm.Config{Model: "gpt-4o"}, false},
+	}
+	for _, c := range cases {
+		if got := useOpenAIRealtime(c.cfg); got != c.want {
+			t.Errorf("useOpenAIRealtime(%+v) = %v, want %v", c.cfg, got, c.want)
+		}
+	}
+
+	t.Setenv("GEMINI_API_KEY", "g")
+	if useOpenAIRealtime(llm.Config{Model: "gpt-4o", APIKey: "k"}) {
+		t.Error("a Gemini key should keep non-realtime models on the Gemini Live API")
+	}
+}
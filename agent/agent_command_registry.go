@@ -0,0 +1,262 @@
+// agent_command_registry.go - built-in slash commands, dispatch, permissions and /help
+package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gliderlab/cogate/pkg/commands"
+)
+
+// Commands returns the agent's slash-command registry; skills and embedders
+// register their own commands with it
+func (a *Agent) Commands() *commands.Registry {
+	return a.commands
+}
+
+// registerBuiltinCommands fills the registry with the agent's own commands
+func (a *Agent) registerBuiltinCommands() {
+	sessionArg := commands.Arg{Name: "session", Help: "Session key"}
+	builtins := []commands.Command{
+		{Name: "help", Help: "List commands, or show one command", Args: []commands.Arg{{Name: "command", Rest: true}},
+			Handler: func(_ context.Context, inv *commands.Invocation) (string, error) {
+				return a.commandHelp(inv.Arg("command"), inv.Level), nil
+			}},
+		{Name: "stop", Help: "Stop the running turn", Immediate: true,
+			Handler: func(_ context.Context, inv *commands.Invocation) (string, error) {
+				a.StopSession(inv.SessionKey)
+				a.fireStopEvent(inv.SessionKey)
+				return "Stopped.", nil
+			}},
+		{Name: "approve", Help: "Approve a pending tool call", Immediate: true,
+			Args:    []commands.Arg{{Name: "approval id", Required: true}},
+			Handler: a.resolveApprovalCommand(true)},
+		{Name: "deny", Help: "Deny a pending tool call", Immediate: true,
+			Args:    []commands.Arg{{Name: "approval id", Required: true}},
+			Handler: a.resolveApprovalCommand(false)},
+		{Name: "compact", Help: "Summarize older messages to free context", Args: []commands.Arg{{Name: "instructions", Rest: true}},
+			Handler: func(_ context.Context, inv *commands.Invocation) (string, error) {
+				return a.runCompact(inv.Arg("instructions")), nil
+			}},
+		{Name: "new", Help: "Start a new session", ExtraArgs: true,
+			Handler: func(context.Context, *commands.Invocation) (string, error) { return a.runNewSession(), nil }},
+		{Name: "reset", Help: "Reset the current session", ExtraArgs: true,
+			Handler: func(context.Context, *commands.Invocation) (string, error) { return a.runResetSession(), nil }},
+		{Name: "fork", Help: "Fork the session at a message (default: the latest) into a new session",
+			Args: []commands.Arg{{Name: "message-id", Int: true}, {Name: "new-session-key"}},
+			Handler: func(_ context.Context, inv *commands.Invocation) (string, error) {
+				var args []string
+				for _, name := range []string{"message-id", "new-session-key"} {
+					if v := inv.Arg(name); v != "" {
+						args = append(args, v)
+					}
+				}
+				return a.runForkSession(inv.SessionKey, args), nil
+			}},
+		{Name: "split", Help: "Split a task into subtasks and run them, e.g. /split summarize today's meeting notes",
+			Args: []commands.Arg{{Name: "task", Required: true, Rest: true}},
+			Handler: func(ctx context.Context, inv *commands.Invocation) (string, error) {
+				return a.executeSplitTask(ctx, inv.SessionKey, inv.Arg("task")), nil
+			}},
+		{Name: "task list", Help: "List recent split tasks", Args: []commands.Arg{{Name: "limit", Int: true}},
+			Handler: func(_ context.Context, inv *commands.Invocation) (string, error) {
+				limit := inv.Int("limit", 10)
+				if limit <= 0 || limit > 100 {
+					limit = 10
+				}
+				return a.runTaskList("default", limit), nil
+			}},
+		{Name: "task detail", Help: "Show the subtasks of a task",
+			Args: []commands.Arg{{Name: "task-id", Required: true}, {Name: "page", Int: true}, {Name: "pageSize", Int: true}},
+			Handler: func(_ context.Context, inv *commands.Invocation) (string, error) {
+				page := max(inv.Int("page", 1), 1)
+				pageSize := inv.Int("pageSize", 20)
+				if pageSize <= 0 || pageSize > 200 {
+					pageSize = 20
+				}
+				return a.runTaskDetail(inv.Arg("task-id"), page, pageSize), nil
+			}},
+		{Name: "task summary", Help: "Summarize the results of a task", Args: []commands.Arg{{Name: "task-id", Required: true}},
+			Handler: func(_ context.Context, inv *commands.Invocation) (string, error) {
+				return a.runTaskSummary(inv.Arg("task-id")), nil
+			}},
+		{Name: "memory consolidate", Help: "Merge near-duplicate memories and archive decayed ones", Level: commands.LevelAdmin,
+			Args: []commands.Arg{{Name: "dry-run", Help: "\"dry-run\" to only report"}},
+			Handler: func(ctx context.Context, inv *commands.Invocation) (string, error) {
+				mode := inv.Arg("dry-run")
+				if mode != "" && mode != "dry-run" {
+					return "Usage: /memory consolidate [dry-run]", nil
+				}
+				report, err := a.ConsolidateMemories(ctx, inv.SessionKey, mode != "")
+				if err != nil {
+					return "", err
+				}
+				return report.String(), nil
+			}},
+		{Name: "debug archive", Help: "Show the archived messages of a session", Level: commands.LevelAdmin,
+			Args: []commands.Arg{sessionArg},
+			Handler: func(_ context.Context, inv *commands.Invocation) (string, error) {
+				return a.runArchiveDebug(cmp.Or(inv.Arg("session"), "default")), nil
+			}},
+		{Name: "debug live", Help: "Show the state of live (realtime) sessions", Level: commands.LevelAdmin,
+			Args: []commands.Arg{sessionArg},
+			Handler: func(_ context.Context, inv *commands.Invocation) (string, error) {
+				return a.runLiveDebug(inv.Arg("session")), nil
+			}},
+
+		// Directives: chat routing strips them and picks the backend
+		{Name: "text", Help: "Answer over HTTP even in a live session", Args: []commands.Arg{{Name: "message", Required: true, Rest: true}}},
+		{Name: "http", Help: "Same as /text", Args: []commands.Arg{{Name: "message", Required: true, Rest: true}}},
+		{Name: "live", Help: "Answer through the realtime backend", Args: []commands.Arg{{Name: "message", Required: true, Rest: true}}},
+		{Name: "voice", Help: "Same as /live", Args: []commands.Arg{{Name: "message", Required: true, Rest: true}}},
+	}
+	for _, c := range builtins {
+		if err := a.commands.Register(c); err != nil {
+			log.Printf("[WARN] %v", err)
+		}
+	}
+}
+
+// resolveApprovalCommand handles /approve and /deny
+func (a *Agent) resolveApprovalCommand(approve bool) func(context.Context, *commands.Invocation) (string, error) {
+	return func(_ context.Context, inv *commands.Invocation) (string, error) {
+		id := inv.Arg("approval id")
+		if err := a.ResolveApproval(inv.SessionKey, id, approve); err != nil {
+			return "No pending approval " + id + " in this session.", nil
+		}
+		if approve {
+			return "Approved.", nil
+		}
+		return "Denied.", nil
+	}
+}
+
+// commandLevel returns the permission level of a session: admin when it
+// matches commands.admins (exact or "prefix*"), or for everyone while the
+// list is empty
+func (a *Agent) commandLevel(sessionKey string) commands.Level {
+	a.mu.RLock()
+	admins := a.cfg.Commands.Admins
+	a.mu.RUnlock()
+	if len(admins) == 0 {
+		return commands.LevelAdmin
+	}
+	for _, admin := range admins {
+		if prefix, ok := strings.CutSuffix(admin, "*"); (ok && strings.HasPrefix(sessionKey, prefix)) || admin == sessionKey {
+			return commands.LevelAdmin
+		}
+	}
+	return commands.LevelUser
+}
+
+// dispatchCommand runs the slash command msg invokes. Immediate commands
+// (/stop, /approve) run only when immediate is set, since the session's turn
+// may still be running; all others only when it isn't, under the turn's ctx.
+// Directives, prompt commands and unknown commands are left to the chat path.
+func (a *Agent) dispatchCommand(ctx context.Context, sessionKey, msg string, immediate bool) (string, bool) {
+	c, rest, ok := a.commands.Match(msg)
+	if !ok {
+		if immediate {
+			return "", false
+		}
+		return a.groupUsage(sessionKey, msg)
+	}
+	if c.Immediate != immediate || (c.Handler == nil && c.Prompt == nil) {
+		return "", false
+	}
+	inv, reply := a.newInvocation(sessionKey, c, rest)
+	if inv == nil {
+		return reply, true
+	}
+	if c.Handler == nil {
+		return "", false
+	}
+	out, err := c.Handler(ctx, inv)
+	if err != nil {
+		return fmt.Sprintf("/%s failed: %v", c.Name, err), true
+	}
+	return out, true
+}
+
+// newInvocation checks the caller's permission and parses the arguments of
+// a command; on failure it returns nil and the reply explaining why
+func (a *Agent) newInvocation(sessionKey string, c *commands.Command, rest string) (*commands.Invocation, string) {
+	level := a.commandLevel(sessionKey)
+	if c.Level > level {
+		return nil, fmt.Sprintf("/%s requires %s permission.", c.Name, c.Level)
+	}
+	args, err := c.Parse(rest)
+	if err != nil {
+		return nil, fmt.Sprintf("Usage: %s\n%s", c.Usage(), c.Help)
+	}
+	return &commands.Invocation{Command: c, SessionKey: sessionKey, Level: level, Args: args}, ""
+}
+
+// groupUsage answers a bare or mistyped subcommand ("/task", "/task foo")
+// with the usage of the group's commands
+func (a *Agent) groupUsage(sessionKey, msg string) (string, bool) {
+	if !strings.HasPrefix(msg, "/") {
+		return "", false
+	}
+	fields := strings.Fields(msg[1:])
+	if len(fields) == 0 {
+		return "", false
+	}
+	name, _, _ := strings.Cut(strings.ToLower(fields[0]), "@")
+	level := a.commandLevel(sessionKey)
+	var lines []string
+	for _, c := range a.commands.Group(name) {
+		if c.Level <= level {
+			lines = append(lines, c.Usage())
+		}
+	}
+	if len(lines) == 0 {
+		return "", false
+	}
+	return "Usage:\n" + strings.Join(lines, "\n"), true
+}
+
+// commandHelp renders /help: every command the caller may run, or the usage
+// of one command or group
+func (a *Agent) commandHelp(name string, level commands.Level) string {
+	if name == "" {
+		return a.commands.Help(level)
+	}
+	name = strings.ToLower(strings.TrimPrefix(name, "/"))
+	if c, ok := a.commands.Get(name); ok && c.Level <= level {
+		return fmt.Sprintf("%s\n%s", c.Usage(), c.Help)
+	}
+	var lines []string
+	for _, c := range a.commands.Group(name) {
+		if c.Level <= level {
+			lines = append(lines, c.Usage()+" - "+c.Help)
+		}
+	}
+	if len(lines) == 0 {
+		return "Unknown command: /" + name
+	}
+	return strings.Join(lines, "\n")
+}
+
+// expandPromptCommand rewrites a prompt command in the last message (a
+// skill's "/weather berlin") into the request the model answers
+func (a *Agent) expandPromptCommand(sessionKey string, messages []Message) []Message {
+	if len(messages) == 0 {
+		return messages
+	}
+	last := messages[len(messages)-1]
+	c, rest, ok := a.commands.Match(last.Content)
+	if !ok || c.Prompt == nil {
+		return messages
+	}
+	inv, _ := a.newInvocation(sessionKey, c, rest)
+	if inv == nil {
+		return messages
+	}
+	last.Content = c.Prompt(inv)
+	expanded := append([]Message(nil), messages[:len(messages)-1]...)
+	return append(expanded, last)
+}
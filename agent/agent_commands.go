@@ -5,45 +5,24 @@ import (
 	"context"
 	"fmt"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gliderlab/cogate/tools"
 )
 
-// runCommandIfRequested executes explicit user command requests via process tool.
-func (a *Agent) runCommandIfRequested(msg string) (string, bool) {
+// runCommandIfRequested runs the slash command msg invokes, or an explicit
+// run/exec request via the process tool.
+func (a *Agent) runCommandIfRequested(ctx context.Context, sessionKey, msg string) (string, bool) {
 	msg = strings.TrimSpace(msg)
-	if msg == "" || a.registry == nil {
+	if msg == "" {
 		return "", false
 	}
-
-	// Handle /compact command
-	if strings.HasPrefix(msg, "/compact") || strings.HasPrefix(msg, "/compact ") {
-		instructions := strings.TrimPrefix(msg, "/compact")
-		instructions = strings.TrimSpace(instructions)
-		return a.runCompact(instructions), true
-	}
-
-	// Handle /new command (start new session)
-	if msg == "/new" || msg == "/new " {
-		return a.runNewSession(), true
+	if reply, ok := a.dispatchCommand(ctx, sessionKey, msg, false); ok {
+		return reply, true
 	}
-
-	// Handle /reset command (reset current session)
-	if msg == "/reset" || msg == "/reset " {
-		return a.runResetSession(), true
-	}
-
-	// Handle /split command (explicit task splitting)
-	if strings.HasPrefix(msg, "/split ") || msg == "/split" {
-		taskMsg := strings.TrimPrefix(msg, "/split ")
-		if taskMsg == "/split" || taskMsg == "" {
-			return "Usage: /split <task>\nExample: /split summarize today's meeting notes", true
-		}
-		// Execute task split explicitly
-		return a.executeSplitTask(taskMsg), true
+	if a.registry == nil {
+		return "", false
 	}
 
 	// Resolve task marker(s) quickly: [task_done:task-...]
@@ -69,69 +48,6 @@ func (a *Agent) runCommandIfRequested(msg string) (string, bool) {
 		}
 	}
 
-	// Handle /debug archive
-	if strings.HasPrefix(msg, "/debug archive") {
-		parts := strings.Fields(msg)
-		session := "default"
-		if len(parts) >= 3 {
-			session = parts[2]
-		}
-		return a.runArchiveDebug(session), true
-	}
-
-	// Handle /debug live
-	if strings.HasPrefix(msg, "/debug live") {
-		parts := strings.Fields(msg)
-		session := ""
-		if len(parts) >= 3 {
-			session = parts[2]
-		}
-		return a.runLiveDebug(session), true
-	}
-
-	// Handle /task commands
-	if strings.HasPrefix(msg, "/task") {
-		parts := strings.Fields(msg)
-		if len(parts) == 1 {
-			return "Usage:\n/task list [limit]\n/task detail <task-id> [page] [pageSize]\n/task summary <task-id>", true
-		}
-		sub := strings.ToLower(parts[1])
-		switch sub {
-		case "list":
-			limit := 10
-			if len(parts) >= 3 {
-				if n, err := strconv.Atoi(parts[2]); err == nil && n > 0 && n <= 100 {
-					limit = n
-				}
-			}
-			return a.runTaskList("default", limit), true
-		case "detail":
-			if len(parts) < 3 {
-				return "Usage: /task detail <task-id> [page] [pageSize]", true
-			}
-			page := 1
-			pageSize := 20
-			if len(parts) >= 4 {
-				if n, err := strconv.Atoi(parts[3]); err == nil && n > 0 {
-					page = n
-				}
-			}
-			if len(parts) >= 5 {
-				if n, err := strconv.Atoi(parts[4]); err == nil && n > 0 && n <= 200 {
-					pageSize = n
-				}
-			}
-			return a.runTaskDetail(parts[2], page, pageSize), true
-		case "summary":
-			if len(parts) < 3 {
-				return "Usage: /task summary <task-id>", true
-			}
-			return a.runTaskSummary(parts[2]), true
-		default:
-			return "Unknown /task subcommand. Use: list | detail | summary", true
-		}
-	}
-
 	// Match explicit run/execute patterns
 	reCmd := regexp.MustCompile(`^(run|exec)\s+(.+)$`)
 	cmd := ""
@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gliderlab/cogate/pkg/commands"
+	"github.com/gliderlab/cogate/pkg/config"
+	"github.com/gliderlab/cogate/pkg/llm/providers/mock"
+	"github.com/gliderlab/cogate/rpcproto"
+	"github.com/gliderlab/cogate/tools"
+)
+
+func newCommandAgent(t *testing.T, admins ...string) (*Agent, *mock.Provider) {
+	t.Helper()
+	s, err := mock.Parse([]byte("loop: true\nresponses:\n  - content: ok\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := mock.NewFromScript(s)
+	a := NewAgentDI().WithConfig(config.AgentConfig{
+		Model: "m", APIKey: "k", ContextTokens: 8192,
+		Commands: config.CommandsConfig{Admins: admins},
+	}).WithRegistry(tools.NewRegistry()).Build()
+	a.WithProvider(p)
+	return a, p
+}
+
+func TestHelpListsCommandsByPermission(t *testing.T) {
+	a, p := newCommandAgent(t, "cli_*")
+
+	help := a.ChatWithSession("telegram_1", []Message{{Role: "user", Content: "/help"}})
+	for _, want := range []string{"/stop - Stop the running turn", "/task detail <task-id> [page] [pageSize]", "/split <task...>"} {
+		if !strings.Contains(help, want) {
+			t.Errorf("/help misses %q:\n%s", want, help)
+		}
+	}
+	if strings.Contains(help, "/debug") {
+		t.Error("/help lists admin commands to a user")
+	}
+	if !strings.Contains(a.ChatWithSession("cli_1", []Message{{Role: "user", Content: "/help"}}), "/debug archive [session]") {
+		t.Error("/help hides admin commands from an admin")
+	}
+
+	if got := a.ChatWithSession("telegram_1", []Message{{Role: "user", Content: "/debug archive"}}); got != "/debug archive requires admin permission." {
+		t.Errorf("/debug archive reply = %q", got)
+	}
+	if got := a.ChatWithSession("telegram_1", []Message{{Role: "user", Content: "/task"}}); !strings.HasPrefix(got, "Usage:\n/task detail") {
+		t.Errorf("/task reply = %q", got)
+	}
+	if got := a.ChatWithSession("telegram_1", []Message{{Role: "user", Content: "/split"}}); !strings.HasPrefix(got, "Usage: /split <task...>") {
+		t.Errorf("/split reply = %q", got)
+	}
+	if p.Calls() != 0 {
+		t.Errorf("commands reached the model %d times", p.Calls())
+	}
+
+	reply, err := NewGRPCService(a).Commands(context.Background(), &rpcproto.CommandsArgs{SessionKey: "telegram_1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range reply.Commands {
+		if c.Level != "user" {
+			t.Errorf("Commands lists /%s (%s) to a user", c.Name, c.Level)
+		}
+	}
+}
+
+func TestRegisteredCommands(t *testing.T) {
+	a, p := newCommandAgent(t)
+	err := a.Commands().Register(commands.Command{
+		Name: "echo", Help: "Echo the text", Source: "plugin",
+		Args: []commands.Arg{{Name: "text", Required: true, Rest: true}},
+		Handler: func(_ context.Context, inv *commands.Invocation) (string, error) {
+			return inv.SessionKey + ": " + inv.Arg("text"), nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.Commands().Register(commands.Command{
+		Name: "weather", Source: "skill",
+		Args: []commands.Arg{{Name: "request", Rest: true}},
+		Prompt: func(inv *commands.Invocation) string {
+			return "Use the weather skill for: " + inv.Arg("request")
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := a.ChatWithSession("s", []Message{{Role: "user", Content: "/echo hi there"}}); got != "s: hi there" {
+		t.Errorf("/echo reply = %q", got)
+	}
+	if got := a.ChatWithSession("s", []Message{{Role: "user", Content: "/weather berlin"}}); got != "ok" {
+		t.Errorf("/weather reply = %q", got)
+	}
+	msgs := p.Requests()[0].Messages
+	if last := msgs[len(msgs)-1]; last.Content != "Use the weather skill for: berlin" {
+		t.Errorf("prompt sent to the model = %q", last.Content)
+	}
+}
@@ -2,6 +2,7 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
@@ -286,7 +287,7 @@ func (a *Agent) updateAnthropicRateLimit() {
 }
 
 // handleContextOverflow estimates context tokens and applies pruning/compaction if needed
-func (a *Agent) handleContextOverflow(sessionKey string, messages []Message) []Message {
+func (a *Agent) handleContextOverflow(ctx context.Context, sessionKey string, messages []Message) []Message {
 	if a.store == nil {
 		return messages
 	}
@@ -353,7 +354,7 @@ func (a *Agent) handleContextOverflow(sessionKey string, messages []Message) []M
 				messages = convertStoredMessages(reloaded)
 				if a.cfg.AutoRecall && a.memoryStore != nil && len(messages) > 0 {
 					lastUserMsg := messages[len(messages)-1].Content
-					if memories := a.recallRelevantMemories(lastUserMsg); memories != "" {
+					if memories := a.recallRelevantMemories(ctx, sessionKey, lastUserMsg); memories != "" {
 						injected := Message{Role: "system", Content: memories}
 						messages = append([]Message{injected}, messages...)
 					}
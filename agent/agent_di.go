@@ -9,6 +9,7 @@ import (
 
 	"github.com/gliderlab/cogate/memory"
 	"github.com/gliderlab/cogate/pkg/config"
+	"github.com/gliderlab/cogate/pkg/llm"
 	"github.com/gliderlab/cogate/storage"
 	"github.com/gliderlab/cogate/tools"
 )
@@ -18,9 +19,11 @@ type AgentDI struct {
 	cfg          config.AgentConfig
 	storage      *storage.Storage
 	memoryStore  *memory.VectorMemoryStore
+	embedder     memory.EmbeddingProvider
 	registry     *tools.Registry
 	pulseConfig  *PulseConfig
 	client       *http.Client
+	provider     llm.Provider
 	timeProvider TimeProvider
 	idGenerator  IDGenerator
 	logger       Logger
@@ -51,6 +54,12 @@ func (d *AgentDI) WithMemoryStore(store *memory.VectorMemoryStore) *AgentDI {
 	return d
 }
 
+// WithEmbedder sets the embedding provider used to rank tools (defaults to the memory store's)
+func (d *AgentDI) WithEmbedder(embedder memory.EmbeddingProvider) *AgentDI {
+	d.embedder = embedder
+	return d
+}
+
 // WithRegistry sets the tool registry
 func (d *AgentDI) WithRegistry(registry *tools.Registry) *AgentDI {
 	d.registry = registry
@@ -69,6 +78,12 @@ func (d *AgentDI) WithHTTPClient(client *http.Client) *AgentDI {
 	return d
 }
 
+// WithProvider sets the LLM provider used for chat (overrides Provider/Groups)
+func (d *AgentDI) WithProvider(p llm.Provider) *AgentDI {
+	d.provider = p
+	return d
+}
+
 // WithTimeout sets the HTTP timeout
 func (d *AgentDI) WithTimeout(timeout time.Duration) *AgentDI {
 	d.cfg.HTTPTimeout = timeout
@@ -133,6 +148,12 @@ func (d *AgentDI) Build() *Agent {
 	if d.client != nil {
 		agent.client = d.client
 	}
+	if d.provider != nil {
+		agent.provider = d.provider
+	}
+	if d.embedder != nil {
+		agent.embedder = d.embedder
+	}
 	if d.timeProvider != nil {
 		agent.timeProvider = d.timeProvider
 	}
@@ -0,0 +1,177 @@
+// agent_graph.go - Knowledge-graph extraction from turns and memories, and graph-expanded recall
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gliderlab/cogate/memory"
+	"github.com/gliderlab/cogate/pkg/llm"
+)
+
+const graphExtractSystem = `You extract a knowledge graph from text. List the entities (people, projects, organizations, places, technologies, concepts) and the relations between them that the text states. Use short lowercase names, give the other names the text uses for an entity as aliases, and name relations with short verbs such as works_on or depends_on. Weight is your confidence from 0 to 1. Do not guess: return empty lists when the text states no facts.`
+
+// graphExtractFormat is the structured reply extractGraph asks for; it
+// unmarshals into memory.Extraction
+var graphExtractFormat = &llm.ResponseFormat{
+	Type: llm.ResponseFormatJSONSchema,
+	JSONSchema: &llm.JSONSchemaSpec{
+		Name: "knowledge_graph",
+		Schema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"entities": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"name":        map[string]interface{}{"type": "string"},
+							"type":        map[string]interface{}{"type": "string"},
+							"description": map[string]interface{}{"type": "string"},
+							"aliases":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+						},
+						"required": []string{"name", "type"},
+					},
+				},
+				"relations": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"source":   map[string]interface{}{"type": "string"},
+							"target":   map[string]interface{}{"type": "string"},
+							"relation": map[string]interface{}{"type": "string"},
+							"weight":   map[string]interface{}{"type": "number"},
+						},
+						"required": []string{"source", "target", "relation"},
+					},
+				},
+			},
+			"required": []string{"entities", "relations"},
+		},
+	},
+}
+
+// extractGraph runs the graph-extract model over texts and ingests what it
+// finds under ownership o
+func (a *Agent) extractGraph(ctx context.Context, sessionKey string, o memory.Ownership, texts []string) error {
+	var sb strings.Builder
+	for i, t := range texts {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, t)
+	}
+	val, err := a.completeStructured(ctx, sessionKey, PurposeGraphExtract, graphExtractSystem, "Text:\n"+sb.String(), graphExtractFormat, 1000, 0)
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	var x memory.Extraction
+	if err := json.Unmarshal(raw, &x); err != nil {
+		return err
+	}
+	entities, relations, err := a.memoryStore.Graph.Ingest(o, x)
+	if entities > 0 || relations > 0 {
+		log.Printf("[GRAPH] extracted %d entities, %d relations (owner %q)", entities, relations, o.Owner)
+	}
+	return err
+}
+
+// ExtractGraph feeds the knowledge graph from a user turn and a batch of
+// memories not extracted yet. The turn is scoped like the session's own
+// memories; memories keep their ownership. It returns at once while another
+// extraction runs. Memories whose extraction fails stay pending
+func (a *Agent) ExtractGraph(ctx context.Context, sessionKey, turn string) {
+	if a.memoryStore == nil || a.memoryStore.Graph == nil || !a.graphMu.TryLock() {
+		return
+	}
+	defer a.graphMu.Unlock()
+
+	batch := a.cfg.KnowledgeGraph.BatchSize
+	if batch <= 0 {
+		batch = 5
+	}
+	pending, err := a.memoryStore.GraphPending(batch)
+	if err != nil {
+		log.Printf("[WARN] graph pending memories: %v", err)
+	}
+
+	type group struct {
+		owner memory.Ownership
+		texts []string
+		ids   []string
+	}
+	var groups []*group
+	byOwner := make(map[string]*group)
+	add := func(o memory.Ownership, text, id string) {
+		key := o.Namespace + "\x00" + o.Owner
+		g := byOwner[key]
+		if g == nil {
+			g = &group{owner: memory.Ownership{Namespace: o.Namespace, Owner: o.Owner}}
+			byOwner[key] = g
+			groups = append(groups, g)
+		}
+		g.texts = append(g.texts, text)
+		if id != "" {
+			g.ids = append(g.ids, id)
+		}
+	}
+
+	// Short turns rarely state facts; a turn auto-capture stored is pending anyway
+	turn = strings.TrimSpace(turn)
+	if len(strings.Fields(turn)) >= 4 {
+		captured := false
+		for _, m := range pending {
+			captured = captured || m.Text == turn
+		}
+		if !captured {
+			add(memoryScope(a.profileFor(ctx, sessionKey), sessionKey).Ownership(), turn, "")
+		}
+	}
+	for _, m := range pending {
+		add(memory.Ownership{Namespace: m.Namespace, Owner: m.Owner}, m.Text, m.ID)
+	}
+
+	for _, g := range groups {
+		if err := a.extractGraph(ctx, sessionKey, g.owner, g.texts); err != nil {
+			log.Printf("[WARN] graph extraction failed: %v", err)
+			continue
+		}
+		if err := a.memoryStore.MarkGraphExtracted(g.ids...); err != nil {
+			log.Printf("[WARN] mark graph extracted: %v", err)
+		}
+	}
+}
+
+// graphFacts expands recall with the relations around the entities the
+// prompt and the recalled memories mention, when knowledgeGraph.recallHops
+// is set
+func (a *Agent) graphFacts(sc memory.Scope, prompt string, results []memory.MemoryResult) []string {
+	hops := a.cfg.KnowledgeGraph.RecallHops
+	if hops <= 0 || a.memoryStore == nil || a.memoryStore.Graph == nil {
+		return nil
+	}
+	limit := a.cfg.KnowledgeGraph.RecallFacts
+	if limit <= 0 {
+		limit = 10
+	}
+
+	text := prompt
+	for _, r := range results {
+		text += "\n" + r.Entry.Text
+	}
+	names, err := a.memoryStore.Graph.EntitiesIn(&sc, text, 10)
+	if err != nil || len(names) == 0 {
+		return nil
+	}
+	sub, err := a.memoryStore.Graph.Neighborhood(&sc, names, hops, limit)
+	if err != nil {
+		log.Printf("[WARN] graph recall failed: %v", err)
+		return nil
+	}
+	return sub.Facts()
+}
@@ -2,6 +2,7 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"sort"
@@ -9,11 +10,20 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gliderlab/cogate/memory"
+	"github.com/gliderlab/cogate/pkg/llm"
 	"github.com/gliderlab/cogate/tools"
 )
 
+// memoryScope is what a session's turns remember and recall: memories owned
+// by the session in the profile's namespace, plus global and shared ones.
+// For channel sessions the session is the chat, so a DM is one user.
+func memoryScope(p *agentProfile, sessionKey string) memory.Scope {
+	return memory.UserScope(p.memoryNamespace(), sessionKey)
+}
+
 // recallRelevantMemories automatically retrieves memories related to the prompt
-func (a *Agent) recallRelevantMemories(prompt string) string {
+func (a *Agent) recallRelevantMemories(ctx context.Context, sessionKey, prompt string) string {
 	if a.memoryStore == nil {
 		return ""
 	}
@@ -26,8 +36,9 @@ func (a *Agent) recallRelevantMemories(prompt string) string {
 		minScore = 0.3
 	}
 
-	results, err := a.memoryStore.Search(prompt, limit*2, minScore)
-	if err != nil || len(results) == 0 {
+	sc := memoryScope(a.profileFor(ctx, sessionKey), sessionKey)
+	results, err := a.memoryStore.SearchScope(sc, prompt, limit*2, minScore)
+	if err != nil {
 		return ""
 	}
 
@@ -45,11 +56,65 @@ func (a *Agent) recallRelevantMemories(prompt string) string {
 		wj := rj.Score * (1 + float32(rj.Entry.Importance)) * (1 + catBoost[strings.ToLower(rj.Entry.Category)])
 		return wi > wj
 	})
+	// Optional LLM re-rank, only when a recall-rerank route is configured
+	if len(results) > 1 && a.hasRoute(PurposeRecallRerank) {
+		results = a.rerankMemories(ctx, sessionKey, prompt, results)
+	}
 	if len(results) > limit {
 		results = results[:limit]
 	}
 
-	return tools.FormatMemoriesForContext(results)
+	// Hybrid recall: graph facts around the entities in play
+	return tools.FormatMemoriesForContext(results) + tools.FormatGraphFactsForContext(a.graphFacts(sc, prompt, results))
+}
+
+// rerankMemories asks the recall-rerank model to order candidates by relevance.
+// Candidates it leaves out are dropped; on any failure the input is returned as is.
+func (a *Agent) rerankMemories(ctx context.Context, sessionKey, prompt string, results []memory.MemoryResult) []memory.MemoryResult {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Query: %s\n\nMemories:\n", prompt)
+	for i, r := range results {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, r.Entry.Text)
+	}
+	sb.WriteString("\nList the numbers of the memories relevant to the query, most relevant first, e.g. {\"order\": [3,1]}.")
+
+	val, err := a.completeStructured(ctx, sessionKey, PurposeRecallRerank, "You rank memories by relevance.", sb.String(), rerankFormat, 200, 0)
+	if err != nil {
+		log.Printf("[WARN] memory re-rank failed: %v", err)
+		return results
+	}
+	order, _ := val.(map[string]interface{})["order"].([]interface{})
+
+	ranked := make([]memory.MemoryResult, 0, len(order))
+	seen := make(map[int]bool, len(order))
+	for _, v := range order {
+		f, _ := v.(float64)
+		n := int(f)
+		if n < 1 || n > len(results) || seen[n] {
+			continue
+		}
+		seen[n] = true
+		ranked = append(ranked, results[n-1])
+	}
+	if len(ranked) == 0 {
+		return results
+	}
+	return ranked
+}
+
+// rerankFormat is the structured reply rerankMemories asks for
+var rerankFormat = &llm.ResponseFormat{
+	Type: llm.ResponseFormatJSONSchema,
+	JSONSchema: &llm.JSONSchemaSpec{
+		Name: "ranking",
+		Schema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"order": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}},
+			},
+			"required": []string{"order"},
+		},
+	},
 }
 
 func isRecallRequest(msg string) bool {
@@ -61,7 +126,7 @@ func isRecallRequest(msg string) bool {
 
 // maybeFlushMemory soft-triggers long memory flush (SQLite storage)
 // Rules: trigger every 200 messages with a minimum interval of 10 minutes
-func (a *Agent) maybeFlushMemory(lastMsg string) {
+func (a *Agent) maybeFlushMemory(ctx context.Context, sessionKey, lastMsg string) {
 	if a.store == nil || a.memoryStore == nil {
 		return
 	}
@@ -90,7 +155,8 @@ func (a *Agent) maybeFlushMemory(lastMsg string) {
 
 	if lastMsg != "" && tools.ShouldCapture(lastMsg) {
 		category := tools.DetectCategory(lastMsg)
-		_, _ = a.memoryStore.StoreWithSource(lastMsg, category, 0.5, "flush")
+		owner := memoryScope(a.profileFor(ctx, sessionKey), sessionKey).Ownership()
+		_, _ = a.memoryStore.StoreOwned(owner, lastMsg, category, 0.5, "flush")
 	}
 
 	_ = a.store.SetConfig("memory", "lastFlushAt", fmt.Sprintf("%d", time.Now().Unix()))
@@ -98,11 +164,47 @@ func (a *Agent) maybeFlushMemory(lastMsg string) {
 	log.Printf("[Memory] Flush triggered at msgCount=%d", msgCount)
 }
 
+// ConsolidateMemories merges near-duplicate memories and archives decayed
+// ones with the memoryConsolidation settings. Rewrites of merged memories go
+// to the consolidate route and are charged to sessionKey.
+func (a *Agent) ConsolidateMemories(ctx context.Context, sessionKey string, dryRun bool) (*memory.ConsolidationReport, error) {
+	if a.memoryStore == nil {
+		return nil, fmt.Errorf("memory store not initialized")
+	}
+	a.mu.RLock()
+	cc := a.cfg.MemoryConsolidation
+	a.mu.RUnlock()
+
+	opts := memory.ConsolidateOptions{
+		Threshold:    float32(cc.Threshold),
+		ArchiveBelow: float32(cc.ArchiveBelow),
+		DryRun:       dryRun,
+	}
+	if cc.Rewrite {
+		opts.Merge = func(texts []string) (string, error) {
+			return a.rewriteMergedMemory(ctx, sessionKey, texts)
+		}
+	}
+	return a.memoryStore.Consolidate(opts)
+}
+
+// rewriteMergedMemory asks the consolidate model to fold near-duplicate
+// memories into one
+func (a *Agent) rewriteMergedMemory(ctx context.Context, sessionKey string, texts []string) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("These memories say nearly the same thing:\n\n")
+	for i, t := range texts {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, t)
+	}
+	sb.WriteString("\nWrite them as one memory that keeps every distinct fact. Reply with only the memory.")
+	return a.completeText(ctx, sessionKey, PurposeConsolidate, "You consolidate memories.", sb.String(), 300, 0)
+}
+
 // preprocessChat performs common pre-chat operations shared by chatInternal and chatStreamInternal:
 // 1. Auto memory capture for important messages
 // 2. Soft-trigger memory flush
 // 3. Async compaction check
-func (a *Agent) preprocessChat(sessionKey, lastMsg string, messages []Message) {
+func (a *Agent) preprocessChat(ctx context.Context, sessionKey, lastMsg string, messages []Message) {
 	if a.store == nil || lastMsg == "" {
 		return
 	}
@@ -110,17 +212,23 @@ func (a *Agent) preprocessChat(sessionKey, lastMsg string, messages []Message) {
 	// Auto memory capture
 	if a.memoryStore != nil && tools.ShouldCapture(lastMsg) {
 		category := tools.DetectCategory(lastMsg)
-		results, _ := a.memoryStore.Search(lastMsg, 1, 0.95)
+		owner := memoryScope(a.profileFor(ctx, sessionKey), sessionKey).Ownership()
+		results, _ := a.memoryStore.SearchScope(memory.Scope{Namespace: owner.Namespace, Owner: owner.Owner}, lastMsg, 1, 0.95)
 		if len(results) == 0 {
-			_, err := a.memoryStore.StoreWithSource(lastMsg, category, 0.6, "auto")
+			_, err := a.memoryStore.StoreOwned(owner, lastMsg, category, 0.6, "auto")
 			if err != nil {
 				log.Printf("[WARN] auto memory write failed")
 			}
 		}
 	}
 
+	// Knowledge-graph extraction runs in the background
+	if a.cfg.KnowledgeGraph.Extract && a.memoryStore != nil {
+		go a.ExtractGraph(context.WithoutCancel(ctx), sessionKey, lastMsg)
+	}
+
 	// Soft-trigger memory flush
-	a.maybeFlushMemory(lastMsg)
+	a.maybeFlushMemory(ctx, sessionKey, lastMsg)
 
 	// Async compaction check
 	go func() {
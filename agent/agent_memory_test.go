@@ -0,0 +1,185 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gliderlab/cogate/memory"
+	"github.com/gliderlab/cogate/pkg/config"
+	"github.com/gliderlab/cogate/pkg/llm/providers/mock"
+	"github.com/gliderlab/cogate/rpcproto"
+	"github.com/gliderlab/cogate/tools"
+)
+
+func TestMemoryScopedBySession(t *testing.T) {
+	store, err := memory.NewVectorMemoryStore(filepath.Join(t.TempDir(), "mem.db"), memory.Config{EmbeddingDim: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	a := NewAgentDI().WithConfig(config.AgentConfig{Model: "m", APIKey: "k", ContextTokens: 8192}).
+		WithRegistry(tools.NewRegistry()).WithMemoryStore(store).Build()
+	svc := NewGRPCService(a)
+	ctx := context.Background()
+
+	for _, args := range []*rpcproto.MemoryStoreArgs{
+		{Text: "coffee order: alice takes oat milk", SessionKey: "telegram_alice"},
+		{Text: "coffee order: bob takes it black", SessionKey: "telegram_bob", ShareWith: []string{"telegram_alice"}},
+		{Text: "coffee order: carol takes espresso", SessionKey: "telegram_carol"},
+		{Text: "coffee order: the office machine is on floor 2", SessionKey: "telegram_alice", Global: true},
+	} {
+		if _, err := svc.MemoryStore(ctx, args); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	search := func(args *rpcproto.MemorySearchArgs) string {
+		t.Helper()
+		args.Query, args.Limit = "coffee", 10
+		reply, err := svc.MemorySearch(ctx, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var res tools.MemorySearchResult
+		if err := json.Unmarshal([]byte(reply.Result), &res); err != nil {
+			t.Fatal(err)
+		}
+		var owners []string
+		for _, item := range res.Items {
+			owners = append(owners, item["owner"].(string))
+		}
+		sort.Strings(owners)
+		return strings.Join(owners, ",")
+	}
+
+	cases := []struct {
+		args *rpcproto.MemorySearchArgs
+		want string
+	}{
+		{&rpcproto.MemorySearchArgs{}, "global,telegram_alice,telegram_bob,telegram_carol"},
+		{&rpcproto.MemorySearchArgs{SessionKey: "telegram_alice"}, "global,telegram_alice,telegram_bob"},
+		{&rpcproto.MemorySearchArgs{SessionKey: "telegram_alice", OwnOnly: true}, "telegram_alice"},
+		{&rpcproto.MemorySearchArgs{SessionKey: "telegram_carol"}, "global,telegram_carol"},
+	}
+	for _, tc := range cases {
+		if got := search(tc.args); got != tc.want {
+			t.Errorf("search as %q (own=%v) = %s, want %s", tc.args.SessionKey, tc.args.OwnOnly, got, tc.want)
+		}
+	}
+
+	recalled := a.recallRelevantMemories(context.Background(), "telegram_carol", "coffee")
+	if strings.Contains(recalled, "alice") || strings.Contains(recalled, "bob") || !strings.Contains(recalled, "espresso") {
+		t.Errorf("carol recalled:\n%s", recalled)
+	}
+
+	results, err := store.SearchScope(memory.Scope{Owner: "telegram_carol"}, "coffee", 10, 0)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("carol's own memories = %v, %v", results, err)
+	}
+	path := results[0].Entry.ID
+	if _, err := svc.MemoryGet(ctx, &rpcproto.MemoryGetArgs{Path: path, SessionKey: "telegram_carol"}); err != nil {
+		t.Errorf("owner cannot get its memory: %v", err)
+	}
+	if _, err := svc.MemoryGet(ctx, &rpcproto.MemoryGetArgs{Path: path, SessionKey: "telegram_alice"}); err == nil {
+		t.Error("another session got carol's memory")
+	}
+}
+
+func TestMemoryConsolidateCommand(t *testing.T) {
+	store, err := memory.NewVectorMemoryStore(filepath.Join(t.TempDir(), "mem.db"), memory.Config{EmbeddingDim: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	s, err := mock.Parse([]byte("loop: true\nresponses:\n  - content: Alice takes oat milk, no sugar\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := mock.NewFromScript(s)
+	a := NewAgentDI().WithConfig(config.AgentConfig{
+		Model: "m", APIKey: "k", ContextTokens: 8192,
+		MemoryConsolidation: config.MemoryConsolidationConfig{Rewrite: true},
+	}).WithRegistry(tools.NewRegistry()).WithMemoryStore(store).Build()
+	a.WithProvider(p)
+
+	kept, _ := store.Store("Alice takes oat milk", "preference", 0.8)
+	dup, _ := store.Store("alice takes  oat milk", "preference", 0.5)
+	store.Store("The printer is on floor 2", "fact", 0.5)
+
+	got := a.ChatWithSession("cli", []Message{{Role: "user", Content: "/memory consolidate dry-run"}})
+	if !strings.HasPrefix(got, "Dry run: scanned 3 memories, merged 1 into 1, archived 0") {
+		t.Errorf("dry run reply = %q", got)
+	}
+	if n, _ := store.Count(); n != 3 {
+		t.Errorf("dry run left %d memories", n)
+	}
+
+	got = a.ChatWithSession("cli", []Message{{Role: "user", Content: "/memory consolidate"}})
+	if !strings.Contains(got, "Alice takes oat milk, no sugar") {
+		t.Errorf("consolidate reply = %q", got)
+	}
+	entry, err := store.Get(kept)
+	if err != nil || entry.Text != "Alice takes oat milk, no sugar" {
+		t.Errorf("kept memory = %+v, %v", entry, err)
+	}
+	if from, _ := store.MergedFrom(kept); len(from) != 1 || from[0].ID != dup {
+		t.Errorf("MergedFrom = %+v", from)
+	}
+	reqs := p.Requests()
+	if len(reqs) == 0 {
+		t.Fatal("merged memories were not rewritten")
+	}
+	msgs := reqs[len(reqs)-1].Messages
+	if prompt := msgs[len(msgs)-1].Content; !strings.Contains(prompt, "1. Alice takes oat milk\n2. alice takes  oat milk") {
+		t.Errorf("rewrite prompt = %q", prompt)
+	}
+}
+
+func TestGraphExtractionAndRecall(t *testing.T) {
+	store, err := memory.NewVectorMemoryStore(filepath.Join(t.TempDir(), "mem.db"), memory.Config{EmbeddingDim: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	s, err := mock.Parse([]byte(`loop: true
+responses:
+  - content: '{"entities": [{"name": "bob", "type": "person", "aliases": ["Robert"]}, {"name": "project atlas", "type": "project", "aliases": ["atlas"]}],
+      "relations": [{"source": "bob", "target": "atlas", "relation": "works_on", "weight": 0.9}, {"source": "atlas", "target": "postgres", "relation": "depends_on"}]}'
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := mock.NewFromScript(s)
+	a := NewAgentDI().WithConfig(config.AgentConfig{
+		Model: "m", APIKey: "k", ContextTokens: 8192,
+		KnowledgeGraph: config.KnowledgeGraphConfig{Extract: true, RecallHops: 2},
+	}).WithRegistry(tools.NewRegistry()).WithMemoryStore(store).Build()
+	a.WithProvider(p)
+
+	store.StoreOwned(memory.Ownership{Owner: "telegram_alice"}, "Bob works on Project Atlas", "fact", 0.6, "auto")
+	a.ExtractGraph(context.Background(), "telegram_alice", "Atlas depends on Postgres since last week")
+
+	// The turn and the pending memory share an owner, so one call covers both
+	if p.Calls() != 1 {
+		t.Fatalf("extraction calls = %d", p.Calls())
+	}
+	msgs := p.Requests()[0].Messages
+	if prompt := msgs[len(msgs)-1].Content; !strings.Contains(prompt, "1. Atlas depends on Postgres since last week\n2. Bob works on Project Atlas") {
+		t.Errorf("extraction prompt = %q", prompt)
+	}
+	if pending, _ := store.GraphPending(10); len(pending) != 0 {
+		t.Errorf("memories still pending: %+v", pending)
+	}
+
+	recalled := a.recallRelevantMemories(context.Background(), "telegram_alice", "what is robert up to?")
+	if !strings.Contains(recalled, "<related-facts>") || !strings.Contains(recalled, "- project atlas -[depends_on]-> postgres") {
+		t.Errorf("alice recalled:\n%s", recalled)
+	}
+	if recalled := a.recallRelevantMemories(context.Background(), "telegram_carol", "what is robert up to?"); recalled != "" {
+		t.Errorf("carol recalled alice's facts:\n%s", recalled)
+	}
+}
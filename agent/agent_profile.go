@@ -0,0 +1,185 @@
+// agent_profile.go - Named agent profiles: per-agent prompt, model, tools, skills and memory namespace
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/gliderlab/cogate/pkg/config"
+	"github.com/gliderlab/cogate/pkg/llm"
+	"github.com/gliderlab/cogate/pkg/skills"
+	"github.com/gliderlab/cogate/tools"
+)
+
+// defaultAgentID names the top-level persona when no profile of that name is
+// configured; webhooks and generated session keys use it
+const defaultAgentID = "main"
+
+// agentProfile is the profile a turn runs as; nil is the top-level persona
+type agentProfile struct {
+	ID string
+	config.AgentProfile
+}
+
+type profileKey struct{}
+
+// lookupProfile resolves the profile for an agent ID. Without one the ID is
+// taken from an "agent:<id>:..." session key. Unknown IDs are an error when
+// given explicitly; "main" and unprofiled session keys are the top-level persona.
+func (a *Agent) lookupProfile(agentID, sessionKey string) (*agentProfile, error) {
+	a.mu.RLock()
+	profiles := a.cfg.Profiles
+	a.mu.RUnlock()
+
+	explicit := agentID != ""
+	if !explicit {
+		agentID = sessionAgentID(sessionKey)
+	}
+	if p, ok := profiles[agentID]; ok {
+		return &agentProfile{ID: agentID, AgentProfile: p}, nil
+	}
+	if explicit && agentID != defaultAgentID {
+		return nil, fmt.Errorf("unknown agent: %s", agentID)
+	}
+	return nil, nil
+}
+
+// defaultSessionKey is the session of requests that name an agent but no
+// session: each profile gets its own ("agent:<id>:default") so its turns,
+// memories and usage don't mix with the top-level persona's "default"
+func defaultSessionKey(agentID string) string {
+	if agentID == "" || agentID == defaultAgentID {
+		return "default"
+	}
+	return "agent:" + agentID + ":default"
+}
+
+// sessionAgentID returns the agent ID of an "agent:<id>:..." session key, or ""
+func sessionAgentID(sessionKey string) string {
+	rest, ok := strings.CutPrefix(sessionKey, "agent:")
+	if !ok {
+		return ""
+	}
+	id, _, _ := strings.Cut(rest, ":")
+	return id
+}
+
+// withProfile runs the turns under ctx as profile p
+func withProfile(ctx context.Context, p *agentProfile) context.Context {
+	return context.WithValue(ctx, profileKey{}, p)
+}
+
+// withAgent runs the turns under ctx as the profile named agentID (see
+// lookupProfile); it fails for an unknown agent
+func (a *Agent) withAgent(ctx context.Context, agentID, sessionKey string) (context.Context, error) {
+	p, err := a.lookupProfile(agentID, sessionKey)
+	if err != nil {
+		return ctx, err
+	}
+	return withProfile(ctx, p), nil
+}
+
+// profileFrom returns the profile of ctx and whether one was resolved
+func profileFrom(ctx context.Context) (*agentProfile, bool) {
+	p, ok := ctx.Value(profileKey{}).(*agentProfile)
+	return p, ok
+}
+
+// profileFor returns the profile the turn of ctx runs as, or the one the
+// session key names for calls outside a turn
+func (a *Agent) profileFor(ctx context.Context, sessionKey string) *agentProfile {
+	if p, ok := profileFrom(ctx); ok {
+		return p
+	}
+	p, _ := a.lookupProfile("", sessionKey)
+	return p
+}
+
+func (p *agentProfile) memoryNamespace() string {
+	if p == nil {
+		return ""
+	}
+	return p.MemoryNamespace
+}
+
+// allowsTool reports whether the profile may see and call a tool. Skill tools
+// are governed by Skills when it is set, every other tool by Tools.
+func (p *agentProfile) allowsTool(name string) bool {
+	if p == nil {
+		return true
+	}
+	if skill := skills.ExtractSkillName(name); skill != "" && p.Skills != nil {
+		return slices.Contains(p.Skills, skill)
+	}
+	return p.toolsPolicy().Permits(name)
+}
+
+// toolsPolicy returns the profile's tool policy; nil for the top-level persona
+func (p *agentProfile) toolsPolicy() *tools.ToolsPolicy {
+	if p == nil {
+		return nil
+	}
+	return &tools.ToolsPolicy{
+		Allow:       p.Tools.Allow,
+		Deny:        p.Tools.Deny,
+		Ask:         p.Tools.Ask,
+		AskPatterns: p.Tools.AskPatterns,
+	}
+}
+
+// needsApproval reports whether a call waits for approval under the top-level
+// ask policy or the profile's own, and the rule that matched
+func (a *Agent) needsApproval(p *agentProfile, name string, args map[string]interface{}) (bool, string) {
+	if ask, reason := a.registry.NeedsApproval(name, args); ask {
+		return true, reason
+	}
+	return p.toolsPolicy().NeedsApproval(name, args)
+}
+
+// filterTools returns the tools the profile may use, in order
+func (p *agentProfile) filterTools(all []llm.Tool) []llm.Tool {
+	if p == nil {
+		return all
+	}
+	kept := make([]llm.Tool, 0, len(all))
+	for _, t := range all {
+		if p.allowsTool(t.Function.Name) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// systemPrompt returns the system prompt of the session's agent profile, or
+// the built-in one
+func (a *Agent) systemPrompt(ctx context.Context, sessionKey string) string {
+	if p := a.profileFor(ctx, sessionKey); p != nil && p.SystemPrompt != "" {
+		return p.SystemPrompt
+	}
+	return a.GetSystemPrompt()
+}
+
+// bootMessage returns the BOOT.md of the session's agent profile, or the
+// workspace BOOT.md
+func (a *Agent) bootMessage(ctx context.Context, sessionKey string) *Message {
+	p := a.profileFor(ctx, sessionKey)
+	if p == nil || p.BootFile == "" {
+		return a.loadBootMD()
+	}
+	path := p.BootFile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(config.DefaultWorkspaceDir(), path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || len(content) == 0 {
+		log.Printf("[WARN] agent %s: cannot read BOOT.md %s: %v", p.ID, path, err)
+		return nil
+	}
+	log.Printf("[BOOT] Loaded BOOT.md of agent %s (%d bytes)", p.ID, len(content))
+	return &Message{Role: "system", Content: string(content)}
+}
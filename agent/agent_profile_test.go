@@ -0,0 +1,201 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gliderlab/cogate/pkg/config"
+	"github.com/gliderlab/cogate/pkg/llm/providers/mock"
+	"github.com/gliderlab/cogate/rpcproto"
+	"github.com/gliderlab/cogate/storage"
+	"github.com/gliderlab/cogate/tools"
+)
+
+// namedTool answers every call with its own name
+type namedTool struct{ name string }
+
+func (n namedTool) Name() string        { return n.name }
+func (n namedTool) Description() string { return "Returns its name" }
+func (n namedTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (n namedTool) Execute(map[string]interface{}) (interface{}, error) { return n.name, nil }
+
+func newProfileAgent(t *testing.T, script string) (*Agent, *mock.Provider) {
+	t.Helper()
+	s, err := mock.Parse([]byte(script))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := storage.New(t.TempDir() + "/profile.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	reg := tools.NewRegistry()
+	reg.Register(namedTool{"read"})
+	reg.Register(namedTool{"exec"})
+	p := mock.NewFromScript(s)
+	a := NewAgentDI().WithConfig(config.AgentConfig{
+		Model: "m", APIKey: "k", ContextTokens: 8192,
+		Profiles: map[string]config.AgentProfile{
+			"support": {
+				SystemPrompt: "You are the support desk.",
+				Model:        "support-model",
+				Tools:        config.ProfileToolsConfig{Deny: []string{"exec"}},
+			},
+		},
+	}).WithRegistry(reg).WithStorage(store).Build()
+	a.WithProvider(p)
+	return a, p
+}
+
+func TestGRPCChatRunsAsProfile(t *testing.T) {
+	a, p := newProfileAgent(t, "responses:\n  - content: hello\n")
+	reply, err := NewGRPCService(a).Chat(context.Background(), &rpcproto.ChatArgs{
+		AgentId:    "support",
+		SessionKey: "s1",
+		Messages:   []*rpcproto.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Content != "hello" {
+		t.Errorf("reply = %q", reply.Content)
+	}
+	req := p.Requests()[0]
+	if req.Model != "support-model" {
+		t.Errorf("model = %q", req.Model)
+	}
+	if sys := req.Messages[0]; sys.Role != "system" || sys.Content != "You are the support desk." {
+		t.Errorf("system prompt = %+v", sys)
+	}
+	for _, tool := range req.Tools {
+		if tool.Function.Name == "exec" {
+			t.Error("denied tool offered to the model")
+		}
+	}
+	if len(req.Tools) == 0 {
+		t.Error("allowed tools missing")
+	}
+}
+
+func TestGRPCChatUnknownAgent(t *testing.T) {
+	a, p := newProfileAgent(t, "responses:\n  - content: hello\n")
+	_, err := NewGRPCService(a).Chat(context.Background(), &rpcproto.ChatArgs{
+		AgentId:  "nobody",
+		Messages: []*rpcproto.Message{{Role: "user", Content: "hi"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "unknown agent: nobody") {
+		t.Errorf("err = %v", err)
+	}
+	if p.Calls() != 0 {
+		t.Errorf("LLM called %d times for an unknown agent", p.Calls())
+	}
+}
+
+func TestSessionKeySelectsProfile(t *testing.T) {
+	a, p := newProfileAgent(t, `
+responses:
+  - tool_calls: [{name: exec, arguments: {}}]
+  - content: done
+`)
+	reply := a.ChatWithSession("agent:support:s2", []Message{{Role: "user", Content: "check the server"}})
+	if reply != "done" {
+		t.Errorf("reply = %q", reply)
+	}
+	reqs := p.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("LLM calls = %d", len(reqs))
+	}
+	if reqs[0].Model != "support-model" {
+		t.Errorf("model = %q", reqs[0].Model)
+	}
+	msgs := reqs[1].Messages
+	if last := msgs[len(msgs)-1]; last.Role != "tool" || !strings.Contains(last.Content, "tool not allowed for agent support: exec") {
+		t.Errorf("tool result = %+v", last)
+	}
+}
+
+func TestAgentWithoutSessionGetsItsOwn(t *testing.T) {
+	a, _ := newProfileAgent(t, "loop: true\nresponses:\n  - content: hello\n")
+	if _, err := NewGRPCService(a).Chat(context.Background(), &rpcproto.ChatArgs{
+		AgentId:  "support",
+		Messages: []*rpcproto.Message{{Role: "user", Content: "hi"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if msgs, _ := a.store.GetMessages("agent:support:default", 10); len(msgs) != 2 {
+		t.Errorf("agent session has %d messages", len(msgs))
+	}
+	if msgs, _ := a.store.GetMessages("default", 10); len(msgs) != 0 {
+		t.Errorf("default session has %d messages", len(msgs))
+	}
+}
+
+func TestTurnProfileComesFromItsContext(t *testing.T) {
+	a, p := newProfileAgent(t, "loop: true\nresponses:\n  - content: hello\n")
+
+	// A support turn of the session is still running
+	ctx, _ := a.withAgent(context.Background(), "support", "s1")
+	_, end := a.startRun(ctx, "s1")
+	defer end()
+
+	a.chatInternal(context.Background(), "s1", []Message{{Role: "user", Content: "hi"}})
+	if req := p.Requests()[0]; req.Model == "support-model" || req.Messages[0].Content == "You are the support desk." {
+		t.Errorf("turn ran as the other turn's profile: model %q, system %q", req.Model, req.Messages[0].Content)
+	}
+}
+
+func TestProfilesHaveTheirOwnAskRules(t *testing.T) {
+	s, err := mock.Parse([]byte(`
+responses:
+  - tool_calls: [{id: call_1, name: exec, arguments: {}}]
+  - tool_calls: [{id: call_2, name: exec, arguments: {}}]
+  - content: done
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := storage.New(t.TempDir() + "/profile.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	reg := tools.NewRegistry()
+	reg.Register(namedTool{"read"})
+	reg.Register(namedTool{"exec"})
+	a := NewAgentDI().WithConfig(config.AgentConfig{
+		Model: "m", APIKey: "k", ContextTokens: 8192,
+		Profiles: map[string]config.AgentProfile{
+			"ops": {Tools: config.ProfileToolsConfig{Ask: []string{"exec"}}},
+			"dev": {Tools: config.ProfileToolsConfig{Ask: []string{"read"}}},
+		},
+	}).WithRegistry(reg).WithStorage(store).Build()
+	a.WithProvider(mock.NewFromScript(s))
+	requests, stop := a.WatchApprovals()
+	defer stop()
+
+	reply := make(chan string, 1)
+	go func() {
+		reply <- a.ChatWithSession("agent:ops:s1", []Message{{Role: "user", Content: "restart it"}})
+	}()
+	req := answerNext(t, a, requests, "/deny")
+	if req.Tool != "exec" || req.Reason != "exec" || req.SessionKey != "agent:ops:s1" {
+		t.Errorf("request = %+v", req)
+	}
+	if got := <-reply; got != "Stopped: exec was denied by the user." {
+		t.Errorf("ops reply = %q", got)
+	}
+
+	// dev only asks before read, so its exec runs straight away
+	if got := a.ChatWithSession("agent:dev:s1", []Message{{Role: "user", Content: "restart it"}}); got != "done" {
+		t.Errorf("dev reply = %q", got)
+	}
+	select {
+	case req := <-requests:
+		t.Errorf("dev raised an approval: %+v", req)
+	default:
+	}
+}
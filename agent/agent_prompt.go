@@ -14,7 +14,8 @@ func (a *Agent) GetSystemPrompt() string {
 	// Memory & Knowledge Graph instruction
 	sb.WriteString("### Memory & Knowledge\n")
 	sb.WriteString("1. **Vector Memory**: Use `memory_search` to find past context, and `memory_store` to save important new information.\n")
-	sb.WriteString("2. **Knowledge Graph**: When the user mentions specific entities (people, projects, preferences, technologies) or their relationships, proactively use the `memory_graph` tool to extract and save them. For example, if the user says 'I prefer Python for scripts', use `memory_graph(action=\"add_relation\", source=\"User\", target=\"Python\", relation=\"prefers_for_scripts\")`.\n")
+	sb.WriteString("2. **Knowledge Graph**: When the user mentions specific entities (people, projects, preferences, technologies) or their relationships, proactively use the `memory_graph` tool to extract and save them. For example, if the user says 'I prefer Python for scripts', use `memory_graph(action=\"add_relation\", source=\"User\", target=\"Python\", relation=\"prefers_for_scripts\")`. For questions that connect several facts, use its `neighborhood` and `path` actions.\n")
+	sb.WriteString("3. **Documents**: `memory_ingest` reads files (Markdown, text, code, HTML, PDF) into memory. Excerpts from them are labelled with their file and line range, such as `[docs/setup.md:12-40]`; cite that location when an answer relies on one.\n")
 
 	return sb.String()
 }
@@ -0,0 +1,376 @@
+// agent_provider.go - LLM provider resolution and agent <-> llm message conversion
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gliderlab/cogate/pkg/llm"
+	"github.com/gliderlab/cogate/pkg/llm/factory"
+	"github.com/gliderlab/cogate/pkg/llm/providers/bedrock"
+	"github.com/gliderlab/cogate/pkg/llmhealth"
+)
+
+// providerConfig resolves the provider settings for the active configuration group.
+// Group values override the top-level APIKey/BaseURL/Model; the provider type comes
+// from the group's Type, then from the Provider name itself (e.g. "anthropic").
+func (a *Agent) providerConfig() llm.Config {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	cfg := llm.Config{
+		APIKey:  a.cfg.APIKey,
+		BaseURL: a.cfg.BaseURL,
+		Model:   a.cfg.Model,
+		Timeout: int(a.cfg.HTTPTimeout / time.Second),
+	}
+	providerType := a.cfg.Provider
+	if a.cfg.Provider != "" && a.cfg.Groups != nil {
+		if group, ok := a.cfg.Groups[a.cfg.Provider]; ok {
+			if group.APIKey != "" {
+				cfg.APIKey = group.APIKey
+			}
+			if group.BaseURL != "" {
+				cfg.BaseURL = group.BaseURL
+			}
+			if group.Model != "" {
+				cfg.Model = group.Model
+			}
+			if group.Type != "" {
+				providerType = group.Type
+			}
+		}
+	}
+	cfg.Type = llm.ProviderType(strings.ToLower(providerType))
+	return cfg
+}
+
+// fallbackConfigs resolves the configured fallback groups, in order. Names
+// that are not configured groups are skipped.
+func (a *Agent) fallbackConfigs() ([]string, []llm.Config) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	var names []string
+	var cfgs []llm.Config
+	for _, name := range a.cfg.Fallbacks {
+		if name == a.cfg.Provider {
+			continue
+		}
+		if cfg, ok := a.groupConfigLocked(name); ok {
+			names = append(names, name)
+			cfgs = append(cfgs, cfg)
+		}
+	}
+	return names, cfgs
+}
+
+// groupConfigLocked returns the provider config of a named group on its own,
+// without top-level defaults. Caller must hold a.mu.
+func (a *Agent) groupConfigLocked(name string) (llm.Config, bool) {
+	group, ok := a.cfg.Groups[name]
+	if !ok {
+		return llm.Config{}, false
+	}
+	providerType := group.Type
+	if providerType == "" {
+		providerType = name
+	}
+	return llm.Config{
+		Type:    llm.ProviderType(strings.ToLower(providerType)),
+		APIKey:  group.APIKey,
+		BaseURL: group.BaseURL,
+		Model:   group.Model,
+		Timeout: int(a.cfg.HTTPTimeout / time.Second),
+	}, true
+}
+
+// chatProvider returns the llm.Provider used for conversation turns together with
+// the resolved config. An injected provider (WithProvider) always wins; otherwise
+// the provider is built from config and cached until the config changes. When
+// Fallbacks are configured the provider is a failover chain over those groups.
+func (a *Agent) chatProvider() (llm.Provider, llm.Config) {
+	cfg := a.providerConfig()
+	fallbackNames, fallbacks := a.fallbackConfigs()
+
+	a.providerMu.Lock()
+	defer a.providerMu.Unlock()
+
+	if a.provider != nil {
+		return a.provider, cfg
+	}
+
+	key := providerCacheKey(cfg)
+	for _, fb := range fallbacks {
+		key += "||" + providerCacheKey(fb)
+	}
+	if a.cachedProvider != nil && a.cachedProviderKey == key {
+		return a.cachedProvider, cfg
+	}
+
+	p := newProviderOrOpenAI(cfg)
+	log.Printf("[Agent] LLM provider: %s (model: %s)", p.Name(), cfg.Model)
+
+	a.mu.RLock()
+	primaryName := a.cfg.Provider
+	if skipped := len(a.cfg.Fallbacks) - len(fallbacks); skipped > 0 {
+		log.Printf("[WARN] %d fallback(s) in %v are not configured groups, skipping", skipped, a.cfg.Fallbacks)
+	}
+	a.mu.RUnlock()
+
+	if len(fallbacks) > 0 {
+		if primaryName == "" {
+			primaryName = string(cfg.Type)
+		}
+		entries := []llmhealth.ChainEntry{{Name: primaryName, Provider: p, Model: cfg.Model}}
+		for i, fb := range fallbacks {
+			entries = append(entries, llmhealth.ChainEntry{Name: fallbackNames[i], Provider: newProviderOrOpenAI(fb), Model: fb.Model})
+		}
+		p = llmhealth.NewFailoverChain(llmhealth.LoadConfigFromEnv(), entries...).OnFailover(a.recordFailover)
+		log.Printf("[Agent] LLM failover chain: %s -> %s", primaryName, strings.Join(fallbackNames, " -> "))
+	}
+
+	a.cachedProvider = p
+	a.cachedProviderKey = key
+	return p, cfg
+}
+
+func providerCacheKey(cfg llm.Config) string {
+	return fmt.Sprintf("%s|%s|%s|%s|%d", cfg.Type, cfg.BaseURL, cfg.APIKey, cfg.Model, cfg.Timeout)
+}
+
+// newProviderOrOpenAI builds a provider from config; unknown types (e.g. a group
+// name without Type) are assumed to be OpenAI-compatible APIs
+func newProviderOrOpenAI(cfg llm.Config) llm.Provider {
+	p, err := factory.NewProvider(cfg)
+	if err != nil {
+		log.Printf("[WARN] %v, falling back to OpenAI-compatible provider", err)
+		cfg.Type = llm.ProviderOpenAI
+		p, _ = factory.NewProvider(cfg)
+	}
+	return p
+}
+
+// recordFailover persists a failover event raised by the provider chain
+func (a *Agent) recordFailover(event llmhealth.FailoverEvent) {
+	if a.store == nil {
+		return
+	}
+	if _, err := a.store.AddFailoverEvent(string(event.FromProvider), string(event.ToProvider), event.Reason, event.Manual, event.Time); err != nil {
+		log.Printf("[WARN] failed to record failover event: %v", err)
+	}
+}
+
+// hasLLM reports whether an LLM backend is configured: an injected provider,
+// or a primary or fallback group that can make requests (see providerReady);
+// without one the agent answers with simpleResponse.
+func (a *Agent) hasLLM() bool {
+	a.providerMu.Lock()
+	injected := a.provider != nil
+	a.providerMu.Unlock()
+	if injected || providerReady(a.providerConfig()) {
+		return true
+	}
+	_, fallbacks := a.fallbackConfigs()
+	for _, fb := range fallbacks {
+		if providerReady(fb) {
+			return true
+		}
+	}
+	return false
+}
+
+// providerReady reports whether a provider config has what its type needs to
+// make requests. Local servers (Ollama, custom, and OpenAI-compatible
+// endpoints at another base URL) and the mock need no key; Bedrock resolves
+// AWS credentials from the key, the environment or the shared credentials
+// file; hosted APIs need a key.
+func providerReady(cfg llm.Config) bool {
+	switch cfg.Type {
+	case llm.ProviderMock, llm.ProviderOllama, llm.ProviderCustom:
+		return true
+	case llm.ProviderBedrock:
+		return bedrock.HasCredentials(cfg.APIKey)
+	case llm.ProviderAnthropic, llm.ProviderGoogle, llm.ProviderMiniMax, llm.ProviderOpenRouter,
+		llm.ProviderMoonshot, llm.ProviderGLM, llm.ProviderQianfan, llm.ProviderVercel, llm.ProviderZAi:
+		return cfg.APIKey != ""
+	}
+	// OpenAI and unknown types, which are built as OpenAI-compatible
+	return cfg.APIKey != "" || (cfg.BaseURL != "" && !strings.Contains(cfg.BaseURL, "api.openai.com"))
+}
+
+// WithProvider injects a chat provider, bypassing config-based resolution
+func (a *Agent) WithProvider(p llm.Provider) *Agent {
+	a.providerMu.Lock()
+	a.provider = p
+	a.providerMu.Unlock()
+	return a
+}
+
+// newChatRequest builds a provider request for the given messages, including
+// the system tools the session's agent profile may use
+func (a *Agent) newChatRequest(ctx context.Context, sessionKey, model string, messages []Message, stream bool) *llm.ChatRequest {
+	a.mu.RLock()
+	temperature := a.cfg.Temperature
+	maxTokens := a.cfg.MaxTokens
+	a.mu.RUnlock()
+
+	if len(a.systemTools) == 0 {
+		a.refreshToolSpecs()
+	}
+
+	return &llm.ChatRequest{
+		Model:       model,
+		Messages:    toLLMMessages(messages),
+		Temperature: temperature,
+		MaxTokens:   maxTokens,
+		Stream:      stream,
+		Tools:       a.selectTools(messages, a.profileFor(ctx, sessionKey).filterTools(a.systemTools)),
+		Thinking:    a.thinkingConfig.requestOptions(),
+	}
+}
+
+// formatProviderError renders a provider error the way callers show it to users
+func formatProviderError(err error) string {
+	var apiErr *llm.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Error()
+	}
+	return fmt.Sprintf("API error: %v", err)
+}
+
+// toLLMMessages converts agent messages into provider messages
+func toLLMMessages(msgs []Message) []llm.Message {
+	out := make([]llm.Message, 0, len(msgs))
+	for _, m := range msgs {
+		out = append(out, llm.Message{
+			Role:       m.Role,
+			Content:    m.Content,
+			Parts:      m.Parts,
+			ToolCalls:  toLLMToolCalls(m.ToolCalls),
+			ToolCallID: m.ToolCallID,
+		})
+	}
+	return out
+}
+
+func toLLMToolCalls(calls []ToolCall) []llm.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]llm.ToolCall, 0, len(calls))
+	for _, tc := range calls {
+		typ := tc.Type
+		if typ == "" {
+			typ = "function"
+		}
+		out = append(out, llm.ToolCall{
+			ID:   tc.ID,
+			Type: typ,
+			Function: &llm.ToolFunction{
+				Name:      tc.Function.Name,
+				Arguments: tc.Function.Arguments,
+			},
+		})
+	}
+	return out
+}
+
+// fromLLMMessage converts a provider response message into an agent message
+func fromLLMMessage(m llm.Message) Message {
+	msg := Message{
+		Role:       m.Role,
+		Content:    m.Content,
+		ToolCallID: m.ToolCallID,
+	}
+	for _, p := range m.Parts {
+		if p.Type == llm.PartImage || p.Type == llm.PartAudio || p.Type == llm.PartThinking {
+			msg.Parts = append(msg.Parts, p)
+		}
+	}
+	for _, tc := range m.AllToolCalls() {
+		var call ToolCall
+		call.ID = tc.ID
+		call.Type = tc.Type
+		if tc.Function != nil {
+			call.Function.Name = tc.Function.Name
+			call.Function.Arguments = tc.Function.Arguments
+		}
+		msg.ToolCalls = append(msg.ToolCalls, call)
+	}
+	return msg
+}
+
+// mergeToolCallDeltas folds streamed tool call fragments into complete calls.
+// Fragments are matched by index; a fragment carrying a new ID at an occupied
+// index starts a new call (some backends omit the index).
+func mergeToolCallDeltas(calls []ToolCall, deltas []llm.ToolCall) []ToolCall {
+	for _, d := range deltas {
+		idx := d.Index
+		if d.ID != "" && idx < len(calls) && calls[idx].ID != "" && calls[idx].ID != d.ID {
+			idx = len(calls)
+		}
+		for idx >= len(calls) {
+			calls = append(calls, ToolCall{Type: "function"})
+		}
+		c := &calls[idx]
+		if d.ID != "" {
+			c.ID = d.ID
+		}
+		if d.Type != "" {
+			c.Type = d.Type
+		}
+		if d.Function != nil {
+			if c.Function.Name == "" {
+				c.Function.Name = d.Function.Name
+			}
+			c.Function.Arguments += d.Function.Arguments
+		}
+	}
+	return calls
+}
+
+// toolResultImages collects images produced by tools (currently browser
+// screenshots, which are saved to disk and returned by path). Tool messages
+// can only carry text on OpenAI-compatible APIs, so callers attach these to
+// a follow-up user message for vision-capable models.
+func toolResultImages(results []ToolResult) []llm.ContentPart {
+	var parts []llm.ContentPart
+	for _, tr := range results {
+		m, ok := tr.Result.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		path, _ := m["screenshot"].(string)
+		if path == "" {
+			continue
+		}
+		mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+		if !strings.HasPrefix(mimeType, "image/") {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("[WARN] cannot attach screenshot %s: %v", path, err)
+			continue
+		}
+		parts = append(parts, llm.ImagePart(mimeType, data))
+	}
+	return parts
+}
+
+// appendToolImages appends a user message carrying tool-produced images, if any
+func appendToolImages(messages []Message, results []ToolResult) []Message {
+	parts := toolResultImages(results)
+	if len(parts) == 0 {
+		return messages
+	}
+	return append(messages, Message{Role: "user", Content: "[Images returned by the tools above]", Parts: parts})
+}
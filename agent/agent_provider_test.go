@@ -0,0 +1,235 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gliderlab/cogate/pkg/config"
+	"github.com/gliderlab/cogate/pkg/llm"
+	"github.com/gliderlab/cogate/pkg/llm/providers/mock"
+	"github.com/gliderlab/cogate/storage"
+)
+
+func TestProviderConfigFromGroup(t *testing.T) {
+	a := NewAgentDI().WithConfig(config.AgentConfig{
+		Model:         "base-model",
+		APIKey:        "base-key",
+		ContextTokens: 8192,
+		Provider:      "claude",
+		Groups: map[string]config.ConfigGroup{
+			"claude": {Type: "anthropic", APIKey: "group-key", Model: "claude-3-5-sonnet"},
+		},
+	}).Build()
+
+	p, cfg := a.chatProvider()
+	if p.Type() != llm.ProviderAnthropic {
+		t.Errorf("expected anthropic provider, got %s", p.Type())
+	}
+	if cfg.APIKey != "group-key" || cfg.Model != "claude-3-5-sonnet" {
+		t.Errorf("group overrides not applied: %+v", cfg)
+	}
+
+	// Unknown provider names fall back to the OpenAI-compatible provider
+	b := NewAgentDI().WithConfig(config.AgentConfig{Model: "m", APIKey: "k", ContextTokens: 8192, Provider: "main"}).Build()
+	if p, _ := b.chatProvider(); p.Type() != llm.ProviderOpenAI {
+		t.Errorf("expected openai fallback, got %s", p.Type())
+	}
+}
+
+func TestHasLLMWithoutAPIKey(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/nonexistent")
+	for _, tc := range []struct {
+		cfg  config.AgentConfig
+		want bool
+	}{
+		{config.AgentConfig{Provider: "ollama"}, true},
+		{config.AgentConfig{BaseURL: "http://127.0.0.1:8080/v1"}, true},
+		{config.AgentConfig{}, false},
+		{config.AgentConfig{Provider: "anthropic"}, false},
+		{config.AgentConfig{Provider: "bedrock"}, false},
+		{config.AgentConfig{Provider: "openai", Fallbacks: []string{"local"},
+			Groups: map[string]config.ConfigGroup{"local": {Type: "ollama"}}}, true},
+	} {
+		tc.cfg.Model, tc.cfg.ContextTokens = "m", 8192
+		if got := NewAgentDI().WithConfig(tc.cfg).Build().hasLLM(); got != tc.want {
+			t.Errorf("hasLLM(%+v) = %v", tc.cfg, got)
+		}
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIA")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	if !NewAgentDI().WithConfig(config.AgentConfig{Model: "m", ContextTokens: 8192, Provider: "bedrock"}).Build().hasLLM() {
+		t.Error("bedrock with credentials from the environment has no LLM")
+	}
+}
+
+func TestCallAPIToolChainThroughProvider(t *testing.T) {
+	var mu sync.Mutex
+	var requests []llm.ChatRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		requests = append(requests, req)
+		n := len(requests)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if n == 1 {
+			fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"no_such_tool","arguments":"{\"x\":1}"}}]}}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"all done"}}]}`)
+	}))
+	defer srv.Close()
+
+	a := NewAgentDI().WithConfig(config.AgentConfig{
+		Model: "test-model", APIKey: "k", BaseURL: srv.URL, ContextTokens: 8192,
+	}).Build()
+
+	got := a.callAPI(context.Background(), "default", []Message{{Role: "user", Content: "hi"}})
+	if got != "all done" {
+		t.Fatalf("unexpected reply: %q", got)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 provider calls, got %d", len(requests))
+	}
+	msgs := requests[1].Messages
+	last := msgs[len(msgs)-1]
+	if last.Role != "tool" || last.ToolCallID != "call_1" {
+		t.Errorf("expected tool result for call_1, got %+v", last)
+	}
+	assistant := msgs[len(msgs)-2]
+	if len(assistant.ToolCalls) != 1 || assistant.ToolCalls[0].Function.Arguments != `{"x":1}` {
+		t.Errorf("assistant tool call not forwarded: %+v", assistant)
+	}
+}
+
+func TestChatStreamThroughProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	a := NewAgentDI().WithConfig(config.AgentConfig{
+		Model: "test-model", APIKey: "k", BaseURL: srv.URL, ContextTokens: 8192,
+	}).Build()
+
+	var sb strings.Builder
+	a.ChatStream([]Message{{Role: "user", Content: "say hello"}}, func(s string) { sb.WriteString(s) })
+	if sb.String() != "Hello" {
+		t.Errorf("expected streamed 'Hello', got %q", sb.String())
+	}
+}
+
+func TestThinkingSurfacedFromProvider(t *testing.T) {
+	script, err := mock.Parse([]byte(`
+loop: true
+responses:
+  - thinking: look it up
+    tool_calls: [{id: call_1, name: no_such_tool, arguments: {}}]
+  - thinking: got it
+    chunks: ["do", "ne"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := mock.NewFromScript(script)
+	a := NewAgentDI().WithConfig(config.AgentConfig{
+		Model: "test-model", APIKey: "k", ContextTokens: 8192, ThinkingMode: "stream", ThinkingBudget: 2048,
+	}).Build()
+	a.WithProvider(provider)
+
+	var sb strings.Builder
+	a.ChatStream([]Message{{Role: "user", Content: "think"}}, func(s string) {
+		if !strings.HasPrefix(s, "[TOOL_EVENT]") {
+			sb.WriteString(s)
+		}
+	})
+	if got := sb.String(); got != "<think>look it up</think>\n\n<think>got it</think>\n\ndone" {
+		t.Errorf("unexpected stream: %q", got)
+	}
+
+	requests := provider.Requests()
+	if len(requests) != 2 || requests[0].Thinking == nil || requests[0].Thinking.BudgetTokens != 2048 {
+		t.Fatalf("thinking not requested: %+v", requests)
+	}
+	// The signed thinking block goes back with the assistant's tool call
+	msgs := requests[1].Messages
+	assistant := msgs[len(msgs)-2]
+	if len(assistant.Parts) != 1 || assistant.Parts[0].Signature == "" || len(assistant.ToolCalls) != 1 {
+		t.Errorf("thinking not returned with tool call: %+v", assistant)
+	}
+
+	if got := a.callAPI(context.Background(), "default", []Message{{Role: "user", Content: "think"}}); got != "<think>got it</think>\n\ndone" {
+		t.Errorf("unexpected reply: %q", got)
+	}
+}
+
+func TestMergeToolCallDeltas(t *testing.T) {
+	var calls []ToolCall
+	calls = mergeToolCallDeltas(calls, []llm.ToolCall{{Index: 0, ID: "a", Function: &llm.ToolFunction{Name: "read", Arguments: `{"pa`}}})
+	calls = mergeToolCallDeltas(calls, []llm.ToolCall{{Index: 0, Function: &llm.ToolFunction{Arguments: `th":"x"}`}}})
+	calls = mergeToolCallDeltas(calls, []llm.ToolCall{{Index: 0, ID: "b", Function: &llm.ToolFunction{Name: "exec", Arguments: `{}`}}})
+
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(calls))
+	}
+	if calls[0].Function.Arguments != `{"path":"x"}` {
+		t.Errorf("arguments not merged: %s", calls[0].Function.Arguments)
+	}
+	if calls[1].ID != "b" || calls[1].Function.Name != "exec" {
+		t.Errorf("second call not split out: %+v", calls[1])
+	}
+}
+
+func TestFallbackChainRecordsFailover(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"backup here"}}]}`)
+	}))
+	defer up.Close()
+
+	store, err := storage.New(t.TempDir() + "/failover.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	a := NewAgentDI().WithConfig(config.AgentConfig{
+		ContextTokens: 8192,
+		Provider:      "main",
+		Groups: map[string]config.ConfigGroup{
+			"main":   {Type: "openai", APIKey: "k1", BaseURL: down.URL, Model: "m1"},
+			"backup": {Type: "openai", APIKey: "k2", BaseURL: up.URL, Model: "m2"},
+		},
+		Fallbacks: []string{"backup", "missing"},
+	}).WithStorage(store).Build()
+
+	if got := a.callAPI(context.Background(), "default", []Message{{Role: "user", Content: "hi"}}); got != "backup here" {
+		t.Fatalf("unexpected reply: %q", got)
+	}
+
+	events, err := store.GetFailoverEvents(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].FromProvider != "main" || events[0].ToProvider != "backup" {
+		t.Errorf("failover not recorded: %+v", events)
+	}
+}
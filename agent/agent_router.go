@@ -0,0 +1,171 @@
+// agent_router.go - Task-aware model routing for the main turn and auxiliary LLM calls
+package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/gliderlab/cogate/pkg/config"
+	"github.com/gliderlab/cogate/pkg/llm"
+)
+
+// CallPurpose identifies why the agent calls the LLM; AgentConfig.Routes is keyed by it
+type CallPurpose string
+
+const (
+	PurposeChat         CallPurpose = "chat"          // Main conversation turn
+	PurposeSummarize    CallPurpose = "summarize"     // Compaction summaries
+	PurposeSplit        CallPurpose = "split"         // /split task decomposition
+	PurposeRecallRerank CallPurpose = "recall-rerank" // Re-ranking recalled memories
+	PurposeTitle        CallPurpose = "title"         // Session titles (reserved; no caller yet)
+	PurposeConsolidate  CallPurpose = "consolidate"   // Rewriting merged memories
+	PurposeGraphExtract CallPurpose = "graph-extract" // Knowledge-graph extraction
+)
+
+// hasRoute reports whether any routing rule is configured for purpose
+func (a *Agent) hasRoute(purpose CallPurpose) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.cfg.Routes[string(purpose)]) > 0
+}
+
+// matchRoute returns the first rule matching the prompt size and tool result presence
+func matchRoute(rules []config.ModelRoute, promptTokens int, toolResults bool) (config.ModelRoute, bool) {
+	for _, r := range rules {
+		if r.MinPromptTokens > 0 && promptTokens < r.MinPromptTokens {
+			continue
+		}
+		if r.MaxPromptTokens > 0 && promptTokens > r.MaxPromptTokens {
+			continue
+		}
+		if r.ToolResults != nil && *r.ToolResults != toolResults {
+			continue
+		}
+		return r, true
+	}
+	return config.ModelRoute{}, false
+}
+
+func hasToolResults(messages []Message) bool {
+	for _, m := range messages {
+		if m.Role == "tool" || len(m.ToolExecutionResults) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// routedProvider resolves the provider and config for a call of the given
+// purpose. Without a matching route this is chatProvider(); a route without
+// Provider only swaps the model. The chat turns of an agent profile with its
+// own provider or model are routed there instead of by the chat routes.
+func (a *Agent) routedProvider(ctx context.Context, sessionKey string, purpose CallPurpose, messages []Message) (llm.Provider, llm.Config) {
+	var route config.ModelRoute
+	if p := a.profileFor(ctx, sessionKey); purpose == PurposeChat && p != nil && (p.Provider != "" || p.Model != "") {
+		route = config.ModelRoute{Provider: p.Provider, Model: p.Model}
+	} else {
+		a.mu.RLock()
+		rules := a.cfg.Routes[string(purpose)]
+		a.mu.RUnlock()
+		if len(rules) == 0 {
+			return a.chatProvider()
+		}
+		var ok bool
+		if route, ok = matchRoute(rules, estimateTokens(messages), hasToolResults(messages)); !ok {
+			return a.chatProvider()
+		}
+	}
+	if route.Provider == "" {
+		p, cfg := a.chatProvider()
+		if route.Model != "" {
+			cfg.Model = route.Model
+		}
+		return p, cfg
+	}
+
+	a.mu.RLock()
+	cfg, found := a.groupConfigLocked(route.Provider)
+	a.mu.RUnlock()
+	if !found {
+		log.Printf("[WARN] route %s: unknown provider group %q, using default provider", purpose, route.Provider)
+		return a.chatProvider()
+	}
+	if route.Model != "" {
+		cfg.Model = route.Model
+	}
+
+	key := providerCacheKey(cfg)
+	a.providerMu.Lock()
+	defer a.providerMu.Unlock()
+	if p, ok := a.routeProviders[key]; ok {
+		return p, cfg
+	}
+	if a.routeProviders == nil {
+		a.routeProviders = make(map[string]llm.Provider)
+	}
+	p := newProviderOrOpenAI(cfg)
+	log.Printf("[Agent] LLM route %s: %s (model: %s)", purpose, route.Provider, cfg.Model)
+	a.routeProviders[key] = p
+	return p, cfg
+}
+
+// completeText runs a single tool-free completion routed by purpose and returns its text
+func (a *Agent) completeText(ctx context.Context, sessionKey string, purpose CallPurpose, system, prompt string, maxTokens int, temperature float64) (string, error) {
+	return a.complete(ctx, sessionKey, purpose, completionMessages(system, prompt), nil, maxTokens, temperature)
+}
+
+// completeStructured is completeText for a reply that must match format. A
+// reply that fails validation is sent back with the errors, up to
+// maxStructuredRetries times; it returns the parsed value.
+func (a *Agent) completeStructured(ctx context.Context, sessionKey string, purpose CallPurpose, system, prompt string, format *llm.ResponseFormat, maxTokens int, temperature float64) (interface{}, error) {
+	messages := completionMessages(system, prompt)
+	for attempt := 0; ; attempt++ {
+		text, err := a.complete(ctx, sessionKey, purpose, messages, format, maxTokens, temperature)
+		if err != nil {
+			return nil, err
+		}
+		val, _, err := parseStructured(format, text)
+		if err == nil {
+			return val, nil
+		}
+		if attempt == maxStructuredRetries {
+			return nil, err
+		}
+		messages = append(messages, Message{Role: "assistant", Content: text}, structuredRetry(err))
+	}
+}
+
+func completionMessages(system, prompt string) []Message {
+	var messages []Message
+	if system != "" {
+		messages = append(messages, Message{Role: "system", Content: system})
+	}
+	return append(messages, Message{Role: "user", Content: prompt})
+}
+
+func (a *Agent) complete(ctx context.Context, sessionKey string, purpose CallPurpose, messages []Message, format *llm.ResponseFormat, maxTokens int, temperature float64) (string, error) {
+	provider, cfg := a.routedProvider(ctx, sessionKey, purpose, messages)
+	req := &llm.ChatRequest{
+		Model:       cfg.Model,
+		Messages:    toLLMMessages(messages),
+		Temperature: temperature,
+		MaxTokens:   maxTokens,
+	}
+	applyResponseFormat(provider, req, format)
+	resp, err := provider.Chat(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	a.updateAnthropicRateLimit()
+
+	if len(resp.Choices) == 0 {
+		a.recordUsage(sessionKey, purpose, provider.Type(), cmp.Or(resp.Model, cfg.Model), &resp.Usage, messages, "")
+		return "", fmt.Errorf("empty response from LLM")
+	}
+	text := resp.Choices[0].Message.Text()
+	a.recordUsage(sessionKey, purpose, provider.Type(), cmp.Or(resp.Model, cfg.Model), &resp.Usage, messages, text)
+	return text, nil
+}
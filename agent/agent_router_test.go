@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gliderlab/cogate/pkg/config"
+	"github.com/gliderlab/cogate/pkg/llm"
+)
+
+func TestMatchRoute(t *testing.T) {
+	yes := true
+	rules := []config.ModelRoute{
+		{Model: "big", MinPromptTokens: 1000},
+		{Model: "tools", ToolResults: &yes},
+		{Model: "small"},
+	}
+
+	cases := []struct {
+		tokens int
+		tools  bool
+		want   string
+	}{
+		{5000, false, "big"},
+		{10, true, "tools"},
+		{10, false, "small"},
+	}
+	for _, c := range cases {
+		r, ok := matchRoute(rules, c.tokens, c.tools)
+		if !ok || r.Model != c.want {
+			t.Errorf("tokens=%d tools=%v: got %q, want %q", c.tokens, c.tools, r.Model, c.want)
+		}
+	}
+	if _, ok := matchRoute([]config.ModelRoute{{MaxPromptTokens: 5}}, 10, false); ok {
+		t.Error("MaxPromptTokens should exclude larger prompts")
+	}
+}
+
+// modelRecorder is an OpenAI-compatible stub that records requested models
+func modelRecorder(t *testing.T, reply string) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var models []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		models = append(models, req.Model)
+		mu.Unlock()
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%q}}]}`, reply)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), models...)
+	}
+}
+
+func TestRoutedAuxiliaryCalls(t *testing.T) {
+	main, mainModels := modelRecorder(t, "main reply")
+	local, localModels := modelRecorder(t, `{"subtasks": ["a", "b"]}`)
+
+	a := NewAgentDI().WithConfig(config.AgentConfig{
+		Model: "expensive", APIKey: "k", BaseURL: main.URL, ContextTokens: 8192,
+		Groups: map[string]config.ConfigGroup{
+			"local": {Type: "openai", APIKey: "x", BaseURL: local.URL, Model: "cheap"},
+		},
+		Routes: map[string][]config.ModelRoute{
+			"split":     {{Provider: "local"}},
+			"summarize": {{Provider: "local", Model: "cheap-summarizer"}},
+			"chat":      {{Model: "expensive-long", MinPromptTokens: 1000}},
+		},
+	}).Build()
+
+	subtasks, err := a.SplitTask(context.Background(), "default", "plan a trip")
+	if err != nil || len(subtasks) != 2 {
+		t.Fatalf("split failed: %v %v", subtasks, err)
+	}
+	if _, err := a.callLLMForSummary("default", "summarize this"); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.callAPI(context.Background(), "default", []Message{{Role: "user", Content: "hi"}}); got != "main reply" {
+		t.Fatalf("unexpected chat reply: %q", got)
+	}
+
+	if got := localModels(); len(got) != 2 || got[0] != "cheap" || got[1] != "cheap-summarizer" {
+		t.Errorf("auxiliary calls not routed to local group: %v", got)
+	}
+	if got := mainModels(); len(got) != 1 || got[0] != "expensive" {
+		t.Errorf("short chat turn should use the default model: %v", got)
+	}
+}
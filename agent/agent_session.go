@@ -2,13 +2,11 @@
 package agent
 
 import (
-	"bytes"
+	"cmp"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -68,6 +66,45 @@ func (a *Agent) runNewSession() string {
 	return fmt.Sprintf("New session created: %s", newKey)
 }
 
+// ForkSession branches a session at messageID (0 for its latest message) into
+// newKey, or into a generated key when newKey is empty. The parent is left as is.
+func (a *Agent) ForkSession(sourceKey, newKey string, messageID int64) (storage.SessionMeta, error) {
+	if a.store == nil {
+		return storage.SessionMeta{}, fmt.Errorf("storage not available")
+	}
+	sourceKey = cmp.Or(sourceKey, "default")
+	if newKey == "" {
+		newKey = fmt.Sprintf("%s-fork-%d", sourceKey, time.Now().UnixNano())
+	}
+	meta, err := a.store.ForkSession(sourceKey, newKey, messageID)
+	if err != nil {
+		return storage.SessionMeta{}, err
+	}
+	log.Printf("[Session] forked %s at message %d into %s", sourceKey, meta.ForkMessageID, newKey)
+	return meta, nil
+}
+
+// runForkSession handles /fork [message-id] [new-session-key]
+func (a *Agent) runForkSession(sessionKey string, args []string) string {
+	var messageID int64
+	newKey := ""
+	if len(args) > 0 {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || id < 0 {
+			return "Usage: /fork [message-id] [new-session-key]\nForks the session at the given message (default: the latest) into a new session."
+		}
+		messageID = id
+	}
+	if len(args) > 1 {
+		newKey = args[1]
+	}
+	meta, err := a.ForkSession(sessionKey, newKey, messageID)
+	if err != nil {
+		return fmt.Sprintf("Fork failed: %v", err)
+	}
+	return fmt.Sprintf("Forked %s at message %d into session: %s", meta.ParentSessionKey, meta.ForkMessageID, meta.SessionKey)
+}
+
 // runResetSession resets the current session
 func (a *Agent) runResetSession() string {
 	if a.store == nil {
@@ -92,15 +129,16 @@ func (a *Agent) runResetSession() string {
 	return "Session reset"
 }
 
-// executeSplitTask explicitly splits and executes a task
-func (a *Agent) executeSplitTask(task string) string {
+// executeSplitTask explicitly splits and executes a task as calls of
+// sessionKey, stopping when ctx is done
+func (a *Agent) executeSplitTask(ctx context.Context, sessionKey, task string) string {
 	if a.store == nil {
 		return "Storage not available"
 	}
 
 	log.Printf("[TaskSplit] Splitting task: %s", task[:min(50, len(task))])
 
-	subtasks, err := a.SplitTask(task)
+	subtasks, err := a.SplitTask(ctx, sessionKey, task)
 	if err != nil {
 		log.Printf("[TaskSplit] Failed to split task: %v", err)
 		return fmt.Sprintf("Task split failed: %v", err)
@@ -116,7 +154,10 @@ func (a *Agent) executeSplitTask(task string) string {
 		return fmt.Sprintf("Create task failed: %v", err)
 	}
 
-	_, err = a.ExecuteSubtasks(taskID, "default")
+	_, err = a.ExecuteSubtasks(ctx, taskID, sessionKey)
+	if ctx.Err() != nil {
+		return stoppedReply(ctx)
+	}
 	if err != nil {
 		log.Printf("[TaskSplit] Failed to execute subtasks: %v", err)
 		return fmt.Sprintf("Execute task failed: %v", err)
@@ -333,7 +374,7 @@ func (a *Agent) buildSummaryWithInstructionsLLM(messages []storage.Message, inst
 		fmt.Fprintf(&sb, "%s: %s\n", m.Role, content)
 	}
 
-	summary, err := a.callLLMForSummary(sb.String())
+	summary, err := a.callLLMForSummary(messages[0].SessionKey, sb.String())
 	if err != nil {
 		log.Printf("[WARN] LLM summary failed: %v, using fallback", err)
 		return buildSummary(messages)
@@ -342,60 +383,10 @@ func (a *Agent) buildSummaryWithInstructionsLLM(messages []storage.Message, inst
 	return summary
 }
 
-// callLLMForSummary makes a non-streaming LLM call to generate a summary
-func (a *Agent) callLLMForSummary(prompt string) (string, error) {
-	reqBody := ChatRequest{
-		Model:       a.cfg.Model,
-		Messages:    []Message{{Role: "user", Content: prompt}},
-		Temperature: 0.3,
-		MaxTokens:   2048,
-		Stream:      false,
-	}
-
-	body, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	url := a.cfg.BaseURL + "/chat/completions"
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewReader(body))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+a.cfg.APIKey)
-
-	resp, err := a.client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("API request failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API error (%d): %s", resp.StatusCode, string(respBody))
-	}
-
-	a.updateAnthropicRateLimit()
-
-	var response struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("empty response from LLM")
-	}
-
-	return response.Choices[0].Message.Content, nil
+// callLLMForSummary makes a non-streaming LLM call to generate a summary.
+// Compaction runs in the background, apart from the turn that triggered it.
+func (a *Agent) callLLMForSummary(sessionKey, prompt string) (string, error) {
+	return a.completeText(context.Background(), sessionKey, PurposeSummarize, "", prompt, 2048, 0.3)
 }
 
 // === Test Helper Functions (exported for testing) ===
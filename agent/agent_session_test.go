@@ -1,6 +1,7 @@
 package agent_test
 
 import (
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -8,6 +9,7 @@ import (
 	"github.com/gliderlab/cogate/agent"
 	"github.com/gliderlab/cogate/pkg/config"
 	"github.com/gliderlab/cogate/storage"
+	"github.com/gliderlab/cogate/tools"
 )
 
 // TestAgentSession_TaskOperations tests task storage and retrieval
@@ -146,3 +148,44 @@ func TestAgentSession_TimeFormatting(t *testing.T) {
 
 	t.Logf("Unix milli %d -> %s (parsed: %v)", ms, formatted, parsed)
 }
+
+// TestAgentSession_Fork tests /fork and ForkSession
+func TestAgentSession_Fork(t *testing.T) {
+	store, err := storage.New(t.TempDir() + "/fork.db")
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	a := agent.NewAgentDI().
+		WithConfig(config.AgentConfig{Model: "test-model"}).
+		WithRegistry(tools.NewRegistry()).
+		WithStorage(store).
+		Build()
+
+	for _, c := range []string{"plan A?", "plan A it is", "what about B?"} {
+		_ = store.AddMessage("main", "user", c)
+	}
+	msgs, _ := store.GetMessages("main", 10)
+
+	reply := a.ChatWithSession("main", []agent.Message{{Role: "user", Content: "/fork " + strconv.FormatInt(msgs[1].ID, 10) + " main-b"}})
+	if !strings.Contains(reply, "into session: main-b") {
+		t.Fatalf("/fork reply = %q", reply)
+	}
+	forked, _ := store.GetMessages("main-b", 10)
+	if len(forked) != 2 || forked[1].Content != "plan A it is" {
+		t.Errorf("fork messages = %+v", forked)
+	}
+	// The command and its reply stay in the parent
+	if parent, _ := store.GetMessages("main", 10); len(parent) != 5 {
+		t.Errorf("parent has %d messages", len(parent))
+	}
+
+	meta, err := a.ForkSession("main-b", "", 0)
+	if err != nil || meta.ParentSessionKey != "main-b" || !strings.HasPrefix(meta.SessionKey, "main-b-fork-") {
+		t.Errorf("ForkSession = %+v, %v", meta, err)
+	}
+	if reply := a.ChatWithSession("main", []agent.Message{{Role: "user", Content: "/fork x"}}); !strings.HasPrefix(reply, "Usage: /fork") {
+		t.Errorf("bad /fork reply = %q", reply)
+	}
+}
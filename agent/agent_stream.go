@@ -1,52 +1,49 @@
-// agent_stream.go - ChatStream: SSE streaming with tool call support
+// agent_stream.go - ChatStream: provider streaming with tool call support
 // FIX-5: commands in ChatStream now accept sessionKey for proper session routing
 package agent
 
 import (
-	"bufio"
-	"bytes"
+	"cmp"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"strings"
+
+	"github.com/gliderlab/cogate/pkg/llm"
 )
 
 // ChatStream sends chat messages and streams the response via callback.
 // sessionKey defaults to "default" for backward compat; use ChatStreamWithSession for named sessions.
 func (a *Agent) ChatStream(messages []Message, callback func(string)) {
-	a.chatStreamInternal("default", messages, callback)
+	a.chatStreamInternal(context.Background(), "default", messages, callback, 0)
 }
 
 // ChatStreamWithSession streams with an explicit session key (fixes hard-coded "default").
 func (a *Agent) ChatStreamWithSession(sessionKey string, messages []Message, callback func(string)) {
-	a.chatStreamInternal(sessionKey, messages, callback)
+	a.chatStreamInternal(context.Background(), sessionKey, messages, callback, 0)
 }
 
-func (a *Agent) chatStreamInternal(sessionKey string, messages []Message, callback func(string)) {
-	a.mu.RLock()
-	apiKey := a.cfg.APIKey
-	baseURL := a.cfg.BaseURL
-	model := a.cfg.Model
-	temperature := a.cfg.Temperature
-	maxTokens := a.cfg.MaxTokens
+// ChatStreamWithSessionContext is ChatStreamWithSession for a turn bound to
+// ctx: the stream and tools stop when it is done
+func (a *Agent) ChatStreamWithSessionContext(ctx context.Context, sessionKey string, messages []Message, callback func(string)) {
+	a.chatStreamInternal(ctx, sessionKey, messages, callback, 0)
+}
 
-	// Apply configuration groups if provider is set
-	if a.cfg.Provider != "" && a.cfg.Groups != nil {
-		if group, ok := a.cfg.Groups[a.cfg.Provider]; ok {
-			if group.APIKey != "" {
-				apiKey = group.APIKey
-			}
-			if group.BaseURL != "" {
-				baseURL = group.BaseURL
-			}
-			if group.Model != "" {
-				model = group.Model
-			}
+// chatStreamInternal streams one model call; depth counts the tool-call rounds
+// already run in this turn
+func (a *Agent) chatStreamInternal(ctx context.Context, sessionKey string, messages []Message, callback func(string), depth int) {
+	if depth == 0 {
+		if reply, ok := a.controlIfRequested(sessionKey, messages); ok {
+			callback(reply)
+			return
 		}
+		var endRun func()
+		ctx, endRun = a.joinRun(ctx, sessionKey)
+		defer endRun()
+		defer a.beginTurn(sessionKey)()
 	}
-	a.mu.RUnlock()
+	provider, pcfg := a.routedProvider(ctx, sessionKey, PurposeChat, messages)
 
 	lastMsg := ""
 	for i := len(messages) - 1; i >= 0; i-- {
@@ -57,7 +54,7 @@ func (a *Agent) chatStreamInternal(sessionKey string, messages []Message, callba
 	}
 
 	// FIX-5: use sessionKey instead of hard-coded "default"
-	if out, ok := a.runCommandIfRequested(lastMsg); ok {
+	if out, ok := a.runCommandIfRequested(ctx, sessionKey, lastMsg); ok {
 		callback(out)
 		if a.store != nil && lastMsg != "" {
 			a.storeMessage(sessionKey, "user", lastMsg)
@@ -66,19 +63,11 @@ func (a *Agent) chatStreamInternal(sessionKey string, messages []Message, callba
 		return
 	}
 
-	// Handle /split command (explicit task splitting)
-	if strings.HasPrefix(lastMsg, "/split ") || lastMsg == "/split" {
-		taskMsg := strings.TrimPrefix(lastMsg, "/split ")
-		if taskMsg == "/split" || taskMsg == "" {
-			callback("Usage: /split <task>\nExample: /split summarize today's meeting notes")
-		} else {
-			result := a.executeSplitTask(taskMsg)
-			callback(result)
-		}
-		return
+	if depth == 0 {
+		messages = a.expandPromptCommand(sessionKey, messages)
 	}
 
-	if apiKey == "" {
+	if !a.hasLLM() {
 		response := a.simpleResponse(messages)
 		callback(response)
 		if a.store != nil && lastMsg != "" {
@@ -90,124 +79,94 @@ func (a *Agent) chatStreamInternal(sessionKey string, messages []Message, callba
 
 	if a.store != nil && lastMsg != "" {
 		// Shared preprocessing: auto memory capture + flush + compaction
-		a.preprocessChat(sessionKey, lastMsg, messages)
+		a.preprocessChat(ctx, sessionKey, lastMsg, messages)
 	}
 
 	// Overflow handling
 	if a.store != nil {
 		// FIX-5: pass sessionKey instead of "default"
-		messages = a.handleContextOverflow(sessionKey, messages)
-	}
-
-	// Use streaming HTTP request
-	reqBody := ChatRequest{
-		Model:       model,
-		Messages:    messages,
-		Temperature: temperature,
-		MaxTokens:   maxTokens,
-		Stream:      true,
-	}
-	if len(a.systemTools) == 0 {
-		a.refreshToolSpecs()
-	}
-	reqBody.Tools = a.systemTools
-
-	body, _ := json.Marshal(reqBody)
-	url := baseURL + "/chat/completions"
-
-	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
-	if err != nil {
-		callback(fmt.Sprintf("request build error: %v", err))
-		return
+		messages = a.handleContextOverflow(ctx, sessionKey, messages)
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Accept", "text/event-stream")
 
-	resp, err := a.client.Do(req)
-	if err != nil {
-		callback(fmt.Sprintf("API error: %v", err))
+	if stop := a.budgetStop(sessionKey); stop != "" {
+		callback(stop)
+		if a.store != nil && lastMsg != "" {
+			a.storeMessage(sessionKey, "user", lastMsg)
+			a.storeMessage(sessionKey, "assistant", stop)
+		}
 		return
 	}
-	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != 200 {
-		respBody, _ := io.ReadAll(resp.Body)
-		callback(fmt.Sprintf("API error (%d): %s", resp.StatusCode, string(respBody)))
-		return
+	// An agent profile's own persona applies to streamed turns too
+	if p := a.profileFor(ctx, sessionKey); depth == 0 && p != nil && p.SystemPrompt != "" {
+		messages = append([]Message{{Role: "system", Content: p.SystemPrompt}}, messages...)
 	}
 
-	// Update rate limiter for Anthropic
-	a.updateAnthropicRateLimit()
+	// Stream from the provider, merging tool call fragments as they arrive
+	req := a.newChatRequest(ctx, sessionKey, pcfg.Model, messages, true)
 
-	// Read SSE stream and check for tool calls
-	reader := bufio.NewReader(resp.Body)
 	var contentBuilder strings.Builder
 	var toolCalls []ToolCall
-	tcArgBuilders := make(map[int]*strings.Builder) // index -> arguments builder
-
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			break
+	thinking := newThinkingStream(a.thinkingConfig, callback)
+	textCalls := a.textToolCalls(pcfg)
+	var usage *llm.Usage
+	model := ""
+	err := provider.ChatStream(ctx, req, func(chunk *llm.StreamChunk) {
+		if chunk == nil {
+			return
 		}
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "data: ") {
-			data := strings.TrimPrefix(line, "data: ")
-			if data == "[DONE]" {
-				break
+		if chunk.Model != "" {
+			model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			return
+		}
+		delta := chunk.Choices[0].Delta
+		thinking.add(delta)
+		if len(delta.ToolCalls) > 0 {
+			toolCalls = mergeToolCallDeltas(toolCalls, delta.ToolCalls)
+		}
+		if delta.Content != "" {
+			thinking.end()
+			text := delta.Content
+			if textCalls != nil {
+				text = textCalls.feed(text)
 			}
-			var chunk map[string]interface{}
-			if err := json.Unmarshal([]byte(data), &chunk); err == nil {
-				if choices, ok := chunk["choices"].([]interface{}); ok && len(choices) > 0 {
-					if choice, ok := choices[0].(map[string]interface{}); ok {
-						if delta, ok := choice["delta"].(map[string]interface{}); ok {
-							// Check for tool calls (incremental merge by index)
-							if tcRaw, ok := delta["tool_calls"].([]interface{}); ok && len(tcRaw) > 0 {
-								for _, tcItem := range tcRaw {
-									tcMap, ok := tcItem.(map[string]interface{})
-									if !ok {
-										continue
-									}
-									idxFloat, _ := tcMap["index"].(float64)
-									idx := int(idxFloat)
-
-									if idx >= len(toolCalls) {
-										tcData, _ := json.Marshal(tcMap)
-										var tcCall ToolCall
-										_ = json.Unmarshal(tcData, &tcCall)
-										toolCalls = append(toolCalls, tcCall)
-										tcArgBuilders[idx] = &strings.Builder{}
-										tcArgBuilders[idx].WriteString(tcCall.Function.Arguments)
-									} else {
-										if fnMap, ok := tcMap["function"].(map[string]interface{}); ok {
-											if argChunk, ok := fnMap["arguments"].(string); ok {
-												if b, exists := tcArgBuilders[idx]; exists {
-													b.WriteString(argChunk)
-												}
-											}
-										}
-									}
-								}
-							}
-							// Send content
-							if content, ok := delta["content"].(string); ok {
-								contentBuilder.WriteString(content)
-								callback(content)
-							}
-						}
-					}
-				}
+			if text != "" {
+				contentBuilder.WriteString(text)
+				callback(text)
 			}
 		}
+	})
+	thinking.end()
+	if textCalls != nil {
+		calls, rest := textCalls.close()
+		if rest != "" {
+			contentBuilder.WriteString(rest)
+			callback(rest)
+		}
+		if len(toolCalls) == 0 {
+			toolCalls = calls
+		}
 	}
-
-	// Finalize tool call arguments from builders
-	for idx, b := range tcArgBuilders {
-		if idx < len(toolCalls) {
-			toolCalls[idx].Function.Arguments = b.String()
+	if err != nil {
+		if ctx.Err() != nil {
+			callback(stoppedReply(ctx))
+			return
+		}
+		if contentBuilder.Len() == 0 && len(toolCalls) == 0 {
+			callback(formatProviderError(err))
+			return
 		}
+		log.Printf("[WARN] stream interrupted: %v", err)
 	}
+	a.recordUsage(sessionKey, PurposeChat, provider.Type(), cmp.Or(model, pcfg.Model), usage, messages, contentBuilder.String())
+
+	// Update rate limiter for Anthropic
+	a.updateAnthropicRateLimit()
 
 	// Handle tool calls if any
 	if len(toolCalls) > 0 && a.registry != nil {
@@ -227,7 +186,7 @@ func (a *Agent) chatStreamInternal(sessionKey string, messages []Message, callba
 		}
 		if len(validCalls) == 0 {
 			// Fallback to non-streaming flow if tool calls are invalid
-			reply := a.Chat(messages)
+			reply := a.chatInternal(ctx, "default", messages)
 			if reply != "" {
 				callback(reply)
 			}
@@ -235,6 +194,15 @@ func (a *Agent) chatStreamInternal(sessionKey string, messages []Message, callba
 		}
 		toolCalls = validCalls
 
+		if stop := a.toolDepthStop(sessionKey, depth); stop != "" {
+			callback(stop)
+			if a.store != nil && lastMsg != "" {
+				a.storeMessage(sessionKey, "user", lastMsg)
+				a.storeMessage(sessionKey, "assistant", strings.TrimSpace(contentBuilder.String()+"\n\n"+stop))
+			}
+			return
+		}
+
 		// Send tool execution start event
 		log.Printf("[TOOL] Sending tool_start event, toolCalls=%d", len(toolCalls))
 		callback(`[TOOL_EVENT]{"type":"tool_start","tools":[` +
@@ -247,7 +215,8 @@ func (a *Agent) chatStreamInternal(sessionKey string, messages []Message, callba
 			}(), ",") + `]}`)
 
 		// Execute tool calls
-		results := a.executeToolCalls(toolCalls)
+		ctx := withApprovalNotifier(ctx, callback)
+		results := a.executeToolCalls(ctx, toolCalls)
 
 		// FIX-4: Send tool result event with actual success/failure
 		for i, tr := range results {
@@ -263,10 +232,23 @@ func (a *Agent) chatStreamInternal(sessionKey string, messages []Message, callba
 				toolCalls[i].ID, !hasError, string(resultBytes)))
 		}
 
+		stop := deniedReply(results)
+		if ctx.Err() != nil {
+			stop = stoppedReply(ctx)
+		}
+		if stop != "" {
+			callback(stop)
+			if a.store != nil && lastMsg != "" {
+				a.storeMessage(sessionKey, "user", lastMsg)
+				a.storeMessage(sessionKey, "assistant", strings.TrimSpace(contentBuilder.String()+"\n\n"+stop))
+			}
+			return
+		}
+
 		// Build tool result messages
 		newMessages := make([]Message, 0, len(messages)+len(results)+1)
 		newMessages = append(newMessages, messages...)
-		newMessages = append(newMessages, Message{Role: "assistant", Content: contentBuilder.String(), ToolCalls: toolCalls})
+		newMessages = append(newMessages, Message{Role: "assistant", Content: contentBuilder.String(), ToolCalls: toolCalls, Parts: thinking.parts})
 
 		for i, tr := range results {
 			resultBytes, _ := json.Marshal(tr.Result)
@@ -277,9 +259,10 @@ func (a *Agent) chatStreamInternal(sessionKey string, messages []Message, callba
 			}
 			newMessages = append(newMessages, toolMsg)
 		}
+		newMessages = appendToolImages(newMessages, results)
 
 		// Recurse with sessionKey
-		a.ChatStreamWithSession(sessionKey, newMessages, callback)
+		a.chatStreamInternal(ctx, sessionKey, newMessages, callback, depth+1)
 		return
 	}
 
@@ -1,8 +1,10 @@
-// agent_tools.go - tool execution, loop detection, and custom tool call parsing
+// agent_tools.go - tool execution, loop detection, and edit intent detection
 package agent
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"regexp"
@@ -11,9 +13,7 @@ import (
 	"github.com/gliderlab/cogate/tools"
 )
 
-func (a *Agent) executeToolCalls(toolCalls []ToolCall) []ToolResult {
-	results := make([]ToolResult, 0, len(toolCalls))
-
+func (a *Agent) executeToolCalls(ctx context.Context, toolCalls []ToolCall) []ToolResult {
 	// Tool loop detection - check before executing
 	if a.toolLoopDetector != nil {
 		// 先探测一次是否处于循环泥潭
@@ -42,71 +42,104 @@ func (a *Agent) executeToolCalls(toolCalls []ToolCall) []ToolResult {
 		}
 	}
 
-	for _, call := range toolCalls {
-		var result interface{}
-		var err error
+	results := runToolCalls(toolCalls, a.maxParallelTools(), a.toolConcurrency, func(call ToolCall) ToolResult {
+		return a.executeToolCall(ctx, call)
+	})
 
-		if a.registry != nil {
-			result, err = a.registry.CallTool(call.Function.Name, parseArgs(call.Function.Arguments))
-		} else {
-			err = fmt.Errorf("tool registry not initialized")
-		}
+	// Apply tool result truncation
+	results = TruncateToolResults(results, DefaultToolResultTruncationConfig)
 
-		if err != nil {
-			if call.Function.Name == "exec" && err.Error() == "shell features are disabled; use a simple command or enable OCG_EXEC_ALLOW_SHELL" {
-				result = err.Error()
-			} else {
-				result = map[string]interface{}{
-					"error":   err.Error(),
-					"tool":    call.Function.Name,
-					"success": false,
-				}
+	return results
+}
+
+// executeToolCall runs a single tool call through the registry under ctx
+func (a *Agent) executeToolCall(ctx context.Context, call ToolCall) ToolResult {
+	var result interface{}
+	var err error
+	decision := ""
+
+	if a.registry != nil {
+		var args map[string]interface{}
+		if p, _ := profileFrom(ctx); !p.allowsTool(call.Function.Name) {
+			err = fmt.Errorf("tool not allowed for agent %s: %s", p.ID, call.Function.Name)
+		} else if args, err = a.registry.ValidateCall(call.Function.Name, call.Function.Arguments); err != nil {
+			log.Printf("[TOOL] %v", err)
+		} else if ask, reason := a.needsApproval(p, call.Function.Name, args); ask {
+			decision = a.awaitApproval(ctx, call, reason)
+			if decision != approvalApproved {
+				err = approvalError(call.Function.Name, decision)
 			}
+		}
+		if err == nil {
+			result, err = a.registry.CallToolContext(ctx, call.Function.Name, args)
+		}
+	} else {
+		err = fmt.Errorf("tool registry not initialized")
+	}
+
+	if err != nil {
+		if call.Function.Name == "exec" && err.Error() == "shell features are disabled; use a simple command or enable OCG_EXEC_ALLOW_SHELL" {
+			result = err.Error()
 		} else {
-			// Simplify exec output to plain text
-			if call.Function.Name == "exec" {
-				switch v := result.(type) {
-				case tools.ExecResult:
-					out := strings.TrimSpace(v.Stdout)
-					if out == "" {
-						out = strings.TrimSpace(v.Stderr)
-					}
-					if out == "" {
-						out = "OK"
-					}
-					result = out
-				case *tools.ExecResult:
-					out := strings.TrimSpace(v.Stdout)
-					if out == "" {
-						out = strings.TrimSpace(v.Stderr)
-					}
-					if out == "" {
-						out = "OK"
-					}
-					result = out
-				}
-			}
-			result = map[string]interface{}{
-				"result":  result,
+			errResult := map[string]interface{}{
+				"error":   err.Error(),
 				"tool":    call.Function.Name,
-				"success": true,
+				"success": false,
+			}
+			if decision != "" && decision != approvalApproved {
+				errResult["approval"] = decision
+			}
+			var verr *tools.ValidationError
+			if errors.As(err, &verr) {
+				// Structured so the model can fix the arguments and retry
+				errResult["validation"] = verr.Errors
+				errResult["hint"] = "Fix the listed arguments and call the tool again."
 			}
+			result = errResult
+		}
+	} else {
+		// Simplify exec output to plain text
+		if call.Function.Name == "exec" {
+			switch v := result.(type) {
+			case tools.ExecResult:
+				out := strings.TrimSpace(v.Stdout)
+				if out == "" {
+					out = strings.TrimSpace(v.Stderr)
+				}
+				if out == "" {
+					out = "OK"
+				}
+				result = out
+			case *tools.ExecResult:
+				out := strings.TrimSpace(v.Stdout)
+				if out == "" {
+					out = strings.TrimSpace(v.Stderr)
+				}
+				if out == "" {
+					out = "OK"
+				}
+				result = out
+			}
+		}
+		result = map[string]interface{}{
+			"result":  result,
+			"tool":    call.Function.Name,
+			"success": true,
 		}
-
-		results = append(results, ToolResult{
-			ID:     call.ID,
-			Type:   "function",
-			Result: result,
-		})
 	}
 
-	// Apply tool result truncation
-	results = TruncateToolResults(results, DefaultToolResultTruncationConfig)
-
-	return results
+	return ToolResult{
+		ID:     call.ID,
+		Type:   "function",
+		Result: result,
+	}
 }
 
-func (a *Agent) handleToolCalls(messages []Message, toolCalls []ToolCall, assistantMsg *Message, depth int, callback func(string)) string {
+func (a *Agent) handleToolCalls(ctx context.Context, sessionKey string, messages []Message, toolCalls []ToolCall, assistantMsg *Message, depth int, callback func(string)) string {
+	if stop := a.toolDepthStop(sessionKey, depth); stop != "" {
+		return stop
+	}
+
 	// Send tool execution start event
 	if callback != nil && len(toolCalls) > 0 {
 		callback(`[TOOL_EVENT]{"type":"tool_start","tools":[`)
@@ -119,7 +152,8 @@ func (a *Agent) handleToolCalls(messages []Message, toolCalls []ToolCall, assist
 		callback(`]}`)
 	}
 
-	results := a.executeToolCalls(toolCalls)
+	ctx = withApprovalNotifier(ctx, callback)
+	results := a.executeToolCalls(ctx, toolCalls)
 
 	// Send tool result events - FIX: check actual success/failure
 	if callback != nil && len(results) > 0 {
@@ -137,12 +171,19 @@ func (a *Agent) handleToolCalls(messages []Message, toolCalls []ToolCall, assist
 		}
 	}
 
+	if ctx.Err() != nil {
+		return stoppedReply(ctx)
+	}
+	if stop := deniedReply(results); stop != "" {
+		return stop
+	}
+
 	resp := ToolResponse{
 		ToolResults: results,
 	}
 	respBytes, _ := json.Marshal(resp)
 
-	if a.cfg.APIKey == "" {
+	if !a.hasLLM() {
 		return string(respBytes)
 	}
 	if depth >= 2 {
@@ -169,8 +210,9 @@ func (a *Agent) handleToolCalls(messages []Message, toolCalls []ToolCall, assist
 		}
 		newMessages = append(newMessages, toolMsg)
 	}
+	newMessages = appendToolImages(newMessages, results)
 
-	return a.callAPIWithDepth(newMessages, depth+1)
+	return a.callAPIWithDepth(ctx, sessionKey, newMessages, depth+1)
 }
 
 func summarizeToolResults(results []ToolResult) string {
@@ -206,216 +248,15 @@ func parseArgs(argsJSON string) map[string]interface{} {
 	return args
 }
 
-// parseCustomToolCalls parses custom tool call format from MiniMax and similar models
-func parseCustomToolCalls(content string) []ToolCall {
-	var toolCalls []ToolCall
-
-	// Pre-validation: check if content looks like it has tool calls
-	hasToolIndicator := strings.Contains(content, "tool_calls") ||
-		strings.Contains(content, "invoke name=") ||
-		strings.Contains(content, "function_call") ||
-		strings.Contains(content, "=\"")
-
-	if !hasToolIndicator && !strings.Contains(content, "[{") && !strings.Contains(content, "{\"") {
-		return nil
-	}
-
-	// First, try JSON format (more robust and standard)
-	jsonMatches := []string{}
-	for _, match := range reJSONBlock.FindAllStringSubmatch(content, -1) {
-		if len(match) > 1 && len(match[1]) > 10 {
-			if !strings.Contains(match[1], "name") && !strings.Contains(match[1], "function") {
-				continue
-			}
-			var parsed interface{}
-			if err := json.Unmarshal([]byte(match[1]), &parsed); err == nil {
-				jsonMatches = append(jsonMatches, match[1])
-			}
-		}
-	}
-
-	for _, jsonStr := range jsonMatches {
-		var calls []map[string]interface{}
-		if err := json.Unmarshal([]byte(jsonStr), &calls); err != nil {
-			log.Printf("[WARN] Failed to parse tool calls JSON: %v", err)
-			continue
-		}
-		for _, call := range calls {
-			name, _ := call["name"].(string)
-			if name == "" {
-				name, _ = call["function"].(map[string]interface{})["name"].(string)
-			}
-			if name == "" {
-				continue
-			}
-
-			argsMap := make(map[string]interface{})
-			if args, ok := call["arguments"].(map[string]interface{}); ok {
-				argsMap = args
-			} else if args, ok := call["function"].(map[string]interface{})["arguments"].(map[string]interface{}); ok {
-				argsMap = args
-			} else if argsStr, ok := call["arguments"].(string); ok {
-				var argsParsed map[string]interface{}
-				if err := json.Unmarshal([]byte(argsStr), &argsParsed); err != nil {
-					log.Printf("[WARN] Failed to parse arguments JSON: %v", err)
-					argsMap["raw"] = argsStr
-				} else {
-					argsMap = argsParsed
-				}
-			}
-
-			argsJSON, _ := json.Marshal(argsMap)
-			toolCalls = append(toolCalls, ToolCall{
-				ID:   fmt.Sprintf("call_%d", len(toolCalls)),
-				Type: "function",
-				Function: struct {
-					Name      string `json:"name"`
-					Arguments string `json:"arguments"`
-				}{
-					Name:      mapToolName(name),
-					Arguments: string(argsJSON),
-				},
-			})
-		}
-		if len(toolCalls) > 0 {
-			log.Printf("[DEBUG] parseCustomToolCalls: parsed %d JSON tool calls", len(toolCalls))
-			return toolCalls
-		}
-	}
-
-	// Fall back to XML-like format
-	// Validate content size to prevent DoS
-	if len(content) > 50000 {
-		log.Printf("[WARN] parseCustomToolCalls: content too large, truncating")
-		content = content[:50000]
-	}
-
-	matches1 := reXMLTool1.FindAllStringSubmatch(content, -1)
-	matches2 := reXMLTool2.FindAllStringSubmatch(content, -1)
-	matches := append(matches1, matches2...)
-
-	log.Printf("[DEBUG] parseCustomToolCalls: content length=%d, XML matches found=%d", len(content), len(matches))
-
-	for _, m := range matches {
-		if len(m) < 3 {
-			continue
-		}
-		toolName := m[1]
-		if len(toolName) > 100 || toolName == "" {
-			continue
-		}
-		paramsStr := m[2]
-
-		args := make(map[string]interface{})
-		paramMatches := reXMLParam.FindAllStringSubmatch(paramsStr, -1)
-		for _, pm := range paramMatches {
-			if len(pm) >= 3 {
-				key := pm[1]
-				value := strings.TrimSpace(pm[2])
-				if len(key) <= 100 && len(value) <= 10000 {
-					args[key] = value
-				}
-			}
-		}
-
-		actualToolName := mapToolName(toolName)
-		argsJSON, _ := json.Marshal(args)
-
-		toolCalls = append(toolCalls, ToolCall{
-			ID:   fmt.Sprintf("call_%d", len(toolCalls)),
-			Type: "function",
-			Function: struct {
-				Name      string `json:"name"`
-				Arguments string `json:"arguments"`
-			}{
-				Name:      actualToolName,
-				Arguments: string(argsJSON),
-			},
-		})
-	}
-
-	if len(toolCalls) == 0 {
-		toolCalls = parseSimpleToolCalls(content)
-	}
-
-	return toolCalls
-}
-
-// parseSimpleToolCalls is a fallback parser for basic tool call extraction
-func parseSimpleToolCalls(content string) []ToolCall {
-	var toolCalls []ToolCall
-
-	matches := reSimple.FindAllStringSubmatch(content, -1) //nolint:govet
-
-	for _, m := range matches {
-		if len(m) < 2 {
-			continue
-		}
-		toolName := strings.TrimSpace(m[1])
-		if toolName == "" || len(toolName) > 100 {
-			continue
-		}
-
-		if !knownToolsSet[toolName] {
-			continue
-		}
-
-		toolCalls = append(toolCalls, ToolCall{
-			ID:   fmt.Sprintf("call_simple_%d", len(toolCalls)),
-			Type: "function",
-			Function: struct {
-				Name      string `json:"name"`
-				Arguments string `json:"arguments"`
-			}{
-				Name:      toolName,
-				Arguments: "{}",
-			},
-		})
-	}
-
-	if len(toolCalls) > 0 {
-		log.Printf("[DEBUG] parseSimpleToolCalls: extracted %d tool calls (fallback)", len(toolCalls))
-	}
-
-	return toolCalls
-}
-
-// mapToolName maps model-specific tool names to actual tool names
-func mapToolName(modelToolName string) string {
-	switch modelToolName {
-	case "read_file":
-		return "read"
-	case "write_file":
-		return "write"
-	case "execute_command", "exec_cmd":
-		return "exec"
-	case "cat":
-		return "read"
-	default:
-		return modelToolName
-	}
-}
-
 type ToolResponse struct {
 	ToolResults []ToolResult `json:"tool_results"`
 }
 
 var (
-	knownToolsSet = map[string]bool{
-		"read": true, "write": true, "edit": true, "exec": true,
-		"process": true, "browser": true, "message": true, "cron": true,
-		"memory_search": true, "memory_get": true, "sessions_send": true,
-		"subagents": true, "image": true, "tts": true, "web_fetch": true,
-	}
-	reEdit1     = regexp.MustCompile(`(?i)Edit\s+([^:]+):\s*replace\s+(.+)\s+with\s+(.+)`)
-	reEdit2     = regexp.MustCompile(`(?i)Edit\s+([^:]+):\s*change\s+(.+)\s+to\s+(.+)`)
-	reEdit3     = regexp.MustCompile(`(?i)Replace\s+(.+)\s+with\s+(.+)\s+in\s+(.+)`)
-	reEdit4     = regexp.MustCompile(`(?i)replace\s+(.+)\s+with\s+(.+)\s+in\s+([^ ]+)`)
-	reJSONBlock = regexp.MustCompile(`(?s)(\[.*?\]|\{.*?\})`)
-	reSimple    = regexp.MustCompile(`(?i)(?:tool_calls?|function_call|invoke)[:\s]+["']?([a-zA-Z_][a-zA-Z0-9_]*)["']?`)
-	reXMLTool1  = regexp.MustCompile(`(?i)<minimax:tool_call>\s*<invoke\s+name="([^"]+)"[^>]*>(.*?)</invoke>\s*</minimax:tool_call>`)
-	reXMLTool2  = regexp.MustCompile(`(?i)<minimax:tool_call>\s*<invoke\s+name="([^"]+)"[^>]*>(.*?)</invoke>\s*`)
-	reXMLParam  = regexp.MustCompile(`<parameter\s+name="([^"]+)">([^<]*)</parameter>`)
+	reEdit1 = regexp.MustCompile(`(?i)Edit\s+([^:]+):\s*replace\s+(.+)\s+with\s+(.+)`)
+	reEdit2 = regexp.MustCompile(`(?i)Edit\s+([^:]+):\s*change\s+(.+)\s+to\s+(.+)`)
+	reEdit3 = regexp.MustCompile(`(?i)Replace\s+(.+)\s+with\s+(.+)\s+in\s+(.+)`)
+	reEdit4 = regexp.MustCompile(`(?i)replace\s+(.+)\s+with\s+(.+)\s+in\s+([^ ]+)`)
 )
 
 // detectEditIntent detects natural language edit requests
@@ -0,0 +1,35 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gliderlab/cogate/pkg/config"
+	"github.com/gliderlab/cogate/tools"
+)
+
+func TestExecuteToolCallValidation(t *testing.T) {
+	reg := tools.NewRegistry()
+	reg.Register(clockTool{})
+	reg.Register(&dangerTool{})
+	a := NewAgentDI().WithConfig(config.AgentConfig{Model: "m", APIKey: "k", ContextTokens: 8192}).WithRegistry(reg).Build()
+
+	// A number where a string is expected is coerced and the call runs
+	var call ToolCall
+	call.ID, call.Function.Name, call.Function.Arguments = "c1", "clock", `{"zone":7}`
+	res := a.executeToolCall(context.Background(), call).Result.(map[string]interface{})
+	if res["result"] != "12:00 7" {
+		t.Errorf("coerced result = %v", res)
+	}
+
+	// Malformed arguments come back as a structured error without running the tool
+	call.ID, call.Function.Name, call.Function.Arguments = "c2", "danger", `{"target":`
+	res = a.executeToolCall(context.Background(), call).Result.(map[string]interface{})
+	errs, ok := res["validation"].([]tools.ArgError)
+	if res["success"] != false || !ok || len(errs) != 1 || errs[0].Problem != "invalid_json" || res["hint"] == nil {
+		t.Errorf("validation result = %v", res)
+	}
+	if st := reg.ValidationStats(); st["clock"].Coerced != 1 || st["danger"].Failed != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+}
@@ -0,0 +1,61 @@
+// agent_usage.go - Per-call token usage and cost accounting
+package agent
+
+import (
+	"log"
+	"strings"
+
+	"github.com/gliderlab/cogate/pkg/llm"
+	"github.com/gliderlab/cogate/storage"
+)
+
+// usageChannel derives the channel from a session key: "telegram_123" and
+// "telegram:123" both belong to "telegram", "cron:<job>" to "cron".
+func usageChannel(sessionKey string) string {
+	if i := strings.IndexAny(sessionKey, ":_"); i > 0 {
+		return sessionKey[:i]
+	}
+	return sessionKey
+}
+
+// priceTable returns the default price table with AgentConfig.Prices applied
+func (a *Agent) priceTable() llm.PriceTable {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return llm.DefaultPriceTable().Merge(a.cfg.Prices)
+}
+
+// recordUsage stores the token usage of one LLM call served by model. When
+// the provider reported none (some streaming APIs), tokens are estimated from
+// the request and reply and the record is flagged as estimated.
+func (a *Agent) recordUsage(sessionKey string, purpose CallPurpose, provider llm.ProviderType, model string, usage *llm.Usage, messages []Message, reply string) {
+	if a.store == nil {
+		return
+	}
+
+	var u llm.Usage
+	estimated := usage == nil || usage.IsZero()
+	if estimated {
+		u.PromptTokens = estimateTokens(messages)
+		u.CompletionTokens = estimateTokensForString(reply)
+	} else {
+		u = *usage
+	}
+
+	cost, _ := a.priceTable().Cost(provider, model, u)
+	a.addTurnCost(sessionKey, cost)
+	if _, err := a.store.AddUsage(storage.UsageRecord{
+		SessionKey:       sessionKey,
+		Channel:          usageChannel(sessionKey),
+		Provider:         string(provider),
+		Model:            model,
+		Purpose:          string(purpose),
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		CachedTokens:     u.CachedTokens,
+		Cost:             cost,
+		Estimated:        estimated,
+	}); err != nil {
+		log.Printf("[WARN] failed to record usage: %v", err)
+	}
+}
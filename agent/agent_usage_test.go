@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gliderlab/cogate/pkg/config"
+	"github.com/gliderlab/cogate/pkg/llm"
+	"github.com/gliderlab/cogate/pkg/llm/providers/mock"
+	"github.com/gliderlab/cogate/storage"
+)
+
+func TestUsageChannel(t *testing.T) {
+	for key, want := range map[string]string{
+		"telegram_123": "telegram",
+		"cron:job-1":   "cron",
+		"hook:ingress": "hook",
+		"default":      "default",
+		"":             "",
+	} {
+		if got := usageChannel(key); got != want {
+			t.Errorf("usageChannel(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestUsageRecordedPerCall(t *testing.T) {
+	script, err := mock.Parse([]byte(`
+model: gpt-4o-2024-08-06
+responses:
+  - content: first
+    usage: {prompt_tokens: 1000, completion_tokens: 100}
+  - chunks: ["stre", "amed"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := storage.New(t.TempDir() + "/usage.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	a := NewAgentDI().WithConfig(config.AgentConfig{
+		Model: "gpt-4o", APIKey: "k", ContextTokens: 8192,
+		Prices: map[string]llm.ModelPrice{"gpt-4o": {Input: 10, Output: 100}},
+	}).WithStorage(store).Build()
+	a.WithProvider(mock.NewFromScript(script))
+
+	if got := a.callAPI(context.Background(), "telegram_42", []Message{{Role: "user", Content: "hi"}}); got != "first" {
+		t.Fatalf("unexpected reply: %q", got)
+	}
+	a.ChatStreamWithSession("cron:nightly", []Message{{Role: "user", Content: "go"}}, func(string) {})
+
+	records, err := store.GetUsage("", 10)
+	if err != nil || len(records) != 2 {
+		t.Fatalf("usage records = %+v, %v", records, err)
+	}
+	stream, chat := records[0], records[1]
+	if chat.SessionKey != "telegram_42" || chat.Channel != "telegram" || chat.Purpose != "chat" ||
+		chat.Model != "gpt-4o" || chat.Provider != "mock" || chat.PromptTokens != 1000 || chat.Estimated {
+		t.Errorf("chat record = %+v", chat)
+	}
+	// Configured prices override the defaults: 1000*10/1M + 100*100/1M
+	if chat.Cost < 0.0199 || chat.Cost > 0.0201 {
+		t.Errorf("chat cost = %v", chat.Cost)
+	}
+	if stream.SessionKey != "cron:nightly" || stream.Channel != "cron" || stream.CompletionTokens == 0 {
+		t.Errorf("stream record = %+v", stream)
+	}
+}
@@ -1,14 +1,20 @@
 package agent
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"time"
 
+	"github.com/gliderlab/cogate/pkg/commands"
+	"github.com/gliderlab/cogate/pkg/llm"
 	"github.com/gliderlab/cogate/rpcproto"
+	"github.com/gliderlab/cogate/storage"
 	"github.com/gliderlab/cogate/tools"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // GRPCService implements the generated gRPC server interface.
@@ -29,7 +35,7 @@ func (s *GRPCService) Chat(ctx context.Context, args *rpcproto.ChatArgs) (*rpcpr
 
 		msgs := make([]Message, len(args.Messages))
 		for i, m := range args.Messages {
-			msgs[i] = Message{Role: m.Role, Content: m.Content}
+			msgs[i] = Message{Role: m.Role, Content: m.Content, Parts: fromRPCParts(m.Parts)}
 			if len(m.ToolCalls) > 0 {
 				msgs[i].ToolCalls = make([]ToolCall, len(m.ToolCalls))
 				for j, c := range m.ToolCalls {
@@ -54,12 +60,49 @@ func (s *GRPCService) Chat(ctx context.Context, args *rpcproto.ChatArgs) (*rpcpr
 			}
 		}
 
-		reply := s.agent.Chat(msgs)
-		return &rpcproto.ChatReply{Content: reply}, nil
+		format, err := ParseResponseFormat([]byte(args.ResponseFormat))
+		if err != nil {
+			// A bad request, not a failed turn: the gateway answers 400
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		var turn *structuredTurn
+		if format.Structured() {
+			ctx, turn = withResponseFormat(ctx, format)
+		}
+
+		sessionKey := cmp.Or(args.SessionKey, defaultSessionKey(args.AgentId))
+		ctx, err = s.agent.withAgent(ctx, args.AgentId, sessionKey)
+		if err != nil {
+			return nil, err
+		}
+
+		// Bind the turn to the request so a disconnect or deadline stops its tools
+		ctx, endRun := s.agent.startRun(ctx, sessionKey)
+		defer endRun()
+
+		out := &rpcproto.ChatReply{Content: s.chatSession(ctx, sessionKey, args, msgs)}
+		if turn != nil {
+			if obj, err := turn.result(); err != nil {
+				out.FormatError = err.Error()
+			} else if data, err := json.Marshal(obj); err == nil {
+				out.Object = string(data)
+			}
+		}
+		return out, nil
 	})
 }
 
-func (s *GRPCService) Stats(ctx context.Context, _ *rpcproto.StatsArgs) (*rpcproto.StatsReply, error) {
+// chatSession runs a Chat request in sessionKey: without stored history when
+// the request names no session (the client sends the conversation) or for
+// isolated callers, with it otherwise
+func (s *GRPCService) chatSession(ctx context.Context, sessionKey string, args *rpcproto.ChatArgs, msgs []Message) string {
+	if args.SessionKey == "" || args.Isolated {
+		return s.agent.chatInternal(ctx, sessionKey, msgs)
+	}
+	return s.agent.ChatWithSessionContext(ctx, sessionKey, msgs)
+}
+
+func (s *GRPCService) Stats(ctx context.Context, args *rpcproto.StatsArgs) (*rpcproto.StatsReply, error) {
 	return wrapGRPCStats(func() (*rpcproto.StatsReply, error) {
 		if s.agent == nil || s.agent.Store() == nil {
 			return nil, fmt.Errorf("storage not initialized")
@@ -73,7 +116,40 @@ func (s *GRPCService) Stats(ctx context.Context, _ *rpcproto.StatsArgs) (*rpcpro
 		for k, v := range stats {
 			stats32[k] = int32(v)
 		}
-		return &rpcproto.StatsReply{Stats: stats32}, nil
+		// Tool argument validation counters, e.g. tool_args.exec.failed
+		if s.agent.registry != nil {
+			for tool, st := range s.agent.registry.ValidationStats() {
+				prefix := "tool_args." + tool + "."
+				stats32[prefix+"calls"] = int32(st.Calls)
+				stats32[prefix+"coerced"] = int32(st.Coerced)
+				stats32[prefix+"failed"] = int32(st.Failed)
+				for problem, n := range st.Problems {
+					stats32[prefix+problem] = int32(n)
+				}
+			}
+		}
+		reply := &rpcproto.StatsReply{Stats: stats32}
+		if args != nil && args.UsageGroupBy != "" {
+			var since time.Time
+			if args.UsageSince > 0 {
+				since = time.Unix(args.UsageSince, 0)
+			}
+			totals, err := s.agent.Store().UsageSummary(args.UsageGroupBy, since)
+			if err != nil {
+				return nil, err
+			}
+			for _, t := range totals {
+				reply.Usage = append(reply.Usage, &rpcproto.UsageTotal{
+					Key:              t.Key,
+					Calls:            int64(t.Calls),
+					PromptTokens:     int64(t.PromptTokens),
+					CompletionTokens: int64(t.CompletionTokens),
+					CachedTokens:     int64(t.CachedTokens),
+					Cost:             t.Cost,
+				})
+			}
+		}
+		return reply, nil
 	})
 }
 
@@ -85,18 +161,41 @@ func (s *GRPCService) Sessions(ctx context.Context, args *rpcproto.SessionsArgs)
 	if err != nil {
 		return nil, err
 	}
+	branches, err := s.agent.Store().GetSessionBranches()
+	if err != nil {
+		return nil, err
+	}
 	reply := &rpcproto.SessionsReply{Count: int32(len(sessions))}
 	for _, ses := range sessions {
-		reply.Sessions = append(reply.Sessions, &rpcproto.SessionInfo{
-			SessionKey:      ses.SessionKey,
-			TotalTokens:     int32(ses.TotalTokens),
-			CompactionCount: int32(ses.CompactionCount),
-			UpdatedAt:       ses.UpdatedAt.Format(time.RFC3339),
-		})
+		info := sessionInfo(ses)
+		info.Branches = branches[ses.SessionKey]
+		reply.Sessions = append(reply.Sessions, info)
 	}
 	return reply, nil
 }
 
+func (s *GRPCService) ForkSession(ctx context.Context, args *rpcproto.ForkSessionArgs) (*rpcproto.SessionInfo, error) {
+	if s.agent == nil {
+		return nil, fmt.Errorf("agent not initialized")
+	}
+	meta, err := s.agent.ForkSession(args.SessionKey, args.NewSessionKey, args.MessageId)
+	if err != nil {
+		return nil, err
+	}
+	return sessionInfo(meta), nil
+}
+
+func sessionInfo(meta storage.SessionMeta) *rpcproto.SessionInfo {
+	return &rpcproto.SessionInfo{
+		SessionKey:       meta.SessionKey,
+		TotalTokens:      int32(meta.TotalTokens),
+		CompactionCount:  int32(meta.CompactionCount),
+		UpdatedAt:        meta.UpdatedAt.Format(time.RFC3339),
+		ParentSessionKey: meta.ParentSessionKey,
+		ForkMessageId:    meta.ForkMessageID,
+	}
+}
+
 func (s *GRPCService) ChatStream(args *rpcproto.ChatArgs, stream rpcproto.Agent_ChatStreamServer) error {
 	if s.agent == nil {
 		return fmt.Errorf("agent not initialized")
@@ -104,7 +203,7 @@ func (s *GRPCService) ChatStream(args *rpcproto.ChatArgs, stream rpcproto.Agent_
 
 	msgs := make([]Message, len(args.Messages))
 	for i, m := range args.Messages {
-		msgs[i] = Message{Role: m.Role, Content: m.Content}
+		msgs[i] = Message{Role: m.Role, Content: m.Content, Parts: fromRPCParts(m.Parts)}
 		if len(m.ToolCalls) > 0 {
 			msgs[i].ToolCalls = make([]ToolCall, len(m.ToolCalls))
 			for j, c := range m.ToolCalls {
@@ -130,7 +229,14 @@ func (s *GRPCService) ChatStream(args *rpcproto.ChatArgs, stream rpcproto.Agent_
 	}
 
 	// Use streaming callback
-	s.agent.ChatStream(msgs, func(chunk string) {
+	sessionKey := cmp.Or(args.SessionKey, defaultSessionKey(args.AgentId))
+	ctx, err := s.agent.withAgent(stream.Context(), args.AgentId, sessionKey)
+	if err != nil {
+		return err
+	}
+	ctx, endRun := s.agent.startRun(ctx, sessionKey)
+	defer endRun()
+	s.agent.ChatStreamWithSessionContext(ctx, sessionKey, msgs, func(chunk string) {
 		if err := stream.Send(&rpcproto.ChatStreamReply{Content: chunk, Done: false}); err != nil {
 			log.Printf("[GRPC] stream send error: %v", err)
 		}
@@ -143,17 +249,103 @@ func (s *GRPCService) ChatStream(args *rpcproto.ChatArgs, stream rpcproto.Agent_
 	return nil
 }
 
+// WatchApprovals streams tool calls waiting for human approval until the client goes away
+func (s *GRPCService) WatchApprovals(_ *rpcproto.ApprovalsArgs, stream rpcproto.Agent_WatchApprovalsServer) error {
+	if s.agent == nil {
+		return fmt.Errorf("agent not initialized")
+	}
+	requests, stop := s.agent.WatchApprovals()
+	defer stop()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case req := <-requests:
+			if err := stream.Send(&rpcproto.ApprovalRequest{
+				Id:         req.ID,
+				SessionKey: req.SessionKey,
+				Tool:       req.Tool,
+				Arguments:  req.Arguments,
+				Reason:     req.Reason,
+				ExpiresAt:  req.ExpiresAt,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Commands lists the slash commands the session may run
+func (s *GRPCService) Commands(ctx context.Context, args *rpcproto.CommandsArgs) (*rpcproto.CommandsReply, error) {
+	if s.agent == nil {
+		return nil, fmt.Errorf("agent not initialized")
+	}
+	level := s.agent.commandLevel(args.SessionKey)
+	reply := &rpcproto.CommandsReply{}
+	for _, c := range s.agent.Commands().Commands() {
+		if c.Level > level {
+			continue
+		}
+		reply.Commands = append(reply.Commands, &rpcproto.CommandInfo{
+			Name:   c.Name,
+			Usage:  c.Usage(),
+			Help:   c.Help,
+			Level:  c.Level.String(),
+			Source: c.Source,
+		})
+	}
+	return reply, nil
+}
+
+// fromRPCParts converts the image and audio attachments of a request message;
+// other part types are dropped
+func fromRPCParts(parts []*rpcproto.ContentPart) []llm.ContentPart {
+	var out []llm.ContentPart
+	for _, p := range parts {
+		switch t := llm.ContentPartType(p.Type); t {
+		case llm.PartImage, llm.PartAudio:
+			out = append(out, llm.ContentPart{Type: t, MimeType: p.MimeType, Data: p.Data, URL: p.Url})
+		}
+	}
+	return out
+}
+
+// memoryContext confines memory calls made for a session to its scope
+// (see memoryScope) with the session's admin rights. Calls without a session
+// key come from the operator (the CLI or the gateway's UI token) and see
+// every memory; the gateway fills in the key for session-bound tokens
+func (s *GRPCService) memoryContext(ctx context.Context, sessionKey, agentID string) (context.Context, error) {
+	if sessionKey == "" {
+		return ctx, nil
+	}
+	p, err := s.agent.lookupProfile(agentID, sessionKey)
+	if err != nil {
+		return ctx, err
+	}
+	ctx = tools.WithMemoryScope(ctx, memoryScope(p, sessionKey))
+	return tools.WithMemoryAdmin(ctx, s.agent.commandLevel(sessionKey) >= commands.LevelAdmin), nil
+}
+
 func (s *GRPCService) MemorySearch(ctx context.Context, args *rpcproto.MemorySearchArgs) (*rpcproto.ToolResultReply, error) {
 	return wrapGRPCMem(func() (*rpcproto.ToolResultReply, error) {
 		if s.agent == nil || s.agent.MemoryStore() == nil {
 			return nil, fmt.Errorf("memory store not initialized")
 		}
+		ctx, err := s.memoryContext(ctx, args.SessionKey, args.AgentId)
+		if err != nil {
+			return nil, err
+		}
+		scope := "all"
+		if args.OwnOnly {
+			scope = "own"
+		}
 		tool := tools.NewMemoryTool(s.agent.MemoryStore())
-		result, err := tool.Execute(map[string]interface{}{
+		result, err := tool.ExecuteContext(ctx, map[string]interface{}{
 			"query":    args.Query,
 			"category": args.Category,
 			"limit":    int(args.Limit),
 			"minScore": float64(args.MinScore),
+			"scope":    scope,
 		})
 		if err != nil {
 			return nil, err
@@ -168,8 +360,12 @@ func (s *GRPCService) MemoryGet(ctx context.Context, args *rpcproto.MemoryGetArg
 		if s.agent == nil || s.agent.MemoryStore() == nil {
 			return nil, fmt.Errorf("memory store not initialized")
 		}
+		ctx, err := s.memoryContext(ctx, args.SessionKey, args.AgentId)
+		if err != nil {
+			return nil, err
+		}
 		tool := tools.NewMemoryGetTool(s.agent.MemoryStore())
-		result, err := tool.Execute(map[string]interface{}{"path": args.Path})
+		result, err := tool.ExecuteContext(ctx, map[string]interface{}{"path": args.Path})
 		if err != nil {
 			return nil, err
 		}
@@ -183,11 +379,43 @@ func (s *GRPCService) MemoryStore(ctx context.Context, args *rpcproto.MemoryStor
 		if s.agent == nil || s.agent.MemoryStore() == nil {
 			return nil, fmt.Errorf("memory store not initialized")
 		}
+		ctx, err := s.memoryContext(ctx, args.SessionKey, args.AgentId)
+		if err != nil {
+			return nil, err
+		}
 		tool := tools.NewMemoryStoreTool(s.agent.MemoryStore())
-		result, err := tool.Execute(map[string]interface{}{
+		result, err := tool.ExecuteContext(ctx, map[string]interface{}{
 			"text":       args.Text,
 			"category":   args.Category,
 			"importance": float64(args.Importance),
+			"global":     args.Global,
+			"shareWith":  args.ShareWith,
+		})
+		if err != nil {
+			return nil, err
+		}
+		jsonBytes, _ := json.Marshal(result)
+		return &rpcproto.ToolResultReply{Result: string(jsonBytes)}, nil
+	})
+}
+
+// MemoryIngest ingests documents for the operator's CLI. Paths are not
+// confined to the tools' allowed directories; the documents belong to the
+// session when one is given, otherwise they are global
+func (s *GRPCService) MemoryIngest(ctx context.Context, args *rpcproto.MemoryIngestArgs) (*rpcproto.ToolResultReply, error) {
+	return wrapGRPCMem(func() (*rpcproto.ToolResultReply, error) {
+		if s.agent == nil || s.agent.MemoryStore() == nil {
+			return nil, fmt.Errorf("memory store not initialized")
+		}
+		ctx, err := s.memoryContext(ctx, args.SessionKey, args.AgentId)
+		if err != nil {
+			return nil, err
+		}
+		tool := &tools.MemoryIngestTool{Store: s.agent.MemoryStore(), AnyPath: true}
+		result, err := tool.ExecuteContext(ctx, map[string]interface{}{
+			"paths":  args.Paths,
+			"global": args.Global,
+			"force":  args.Force,
 		})
 		if err != nil {
 			return nil, err
@@ -26,6 +26,8 @@ type PulseConfig struct {
 	// Session reset
 	SessionResetEnabled bool          // Enable session reset check
 	SessionResetMins   int           // Check interval in minutes (default 60)
+	// Memory consolidation
+	MemoryConsolidateMins int // Run memory consolidation this often (0 = never)
 }
 
 // DefaultPulseConfig returns default configuration
@@ -64,14 +66,18 @@ type PulseHandler struct {
 	// Processing state
 	isProcessing bool
 	currentEvent *storage.Event
+	// Memory consolidation state
+	consolidating    bool
+	lastConsolidated time.Time
 
 	// Hooks registry for handling hook events
 	hooksRegistry *hooks.HookRegistry
 
 	// Callbacks
-	onEvent      func(*PulseEvent)
-	onBroadcast  func(string, int, string) error // (message, priority, channel)
-	onLLMProcess func(string) (string, error)
+	onEvent       func(*PulseEvent)
+	onBroadcast   func(string, int, string) error // (message, priority, channel)
+	onLLMProcess  func(string) (string, error)
+	onConsolidate func()
 }
 
 // NewPulseHandler creates a new pulse handler
@@ -101,6 +107,15 @@ func (p *PulseHandler) SetLLMCallback(cb func(string) (string, error)) {
 	p.onLLMProcess = cb
 }
 
+// SetConsolidateCallback sets the callback that consolidates memories; the
+// first run comes one MemoryConsolidateMins interval later
+func (p *PulseHandler) SetConsolidateCallback(cb func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onConsolidate = cb
+	p.lastConsolidated = time.Now()
+}
+
 // SetEventCallback sets the callback for event processing
 func (p *PulseHandler) SetEventCallback(cb func(*PulseEvent)) {
 	p.mu.Lock()
@@ -212,6 +227,9 @@ func (p *PulseHandler) tick() {
 			if p.config.SessionResetEnabled {
 				p.CheckSessionReset()
 			}
+			if p.config.MemoryConsolidateMins > 0 {
+				p.checkConsolidation()
+			}
 		}
 		return
 	}
@@ -292,6 +310,33 @@ func (p *PulseHandler) CheckSessionReset() {
 	}
 }
 
+// checkConsolidation starts memory consolidation in the background once
+// MemoryConsolidateMins have passed since the last run began
+func (p *PulseHandler) checkConsolidation() {
+	p.mu.Lock()
+	cb := p.onConsolidate
+	interval := time.Duration(p.config.MemoryConsolidateMins) * time.Minute
+	if cb == nil || p.consolidating || time.Since(p.lastConsolidated) < interval {
+		p.mu.Unlock()
+		return
+	}
+	p.consolidating = true
+	p.lastConsolidated = time.Now()
+	p.wg.Add(1)
+	p.mu.Unlock()
+
+	go func() {
+		defer p.wg.Done()
+		defer func() {
+			p.mu.Lock()
+			p.consolidating = false
+			p.mu.Unlock()
+		}()
+		log.Printf("[Pulse] Consolidating memories...")
+		cb()
+	}()
+}
+
 // shouldProcessEvent determines if an event should be processed now
 func (p *PulseHandler) shouldProcessEvent(event *storage.Event) bool {
 	p.mu.RLock()
@@ -47,6 +47,7 @@ func (s *RPCService) Chat(args *rpcproto.ChatArgs, reply *rpcproto.ChatReply) er
 			msgs[i] = Message{
 				Role:    m.Role,
 				Content: m.Content,
+				Parts:   fromRPCParts(m.Parts),
 			}
 			if len(m.ToolCalls) > 0 {
 				msgs[i].ToolCalls = make([]ToolCall, len(m.ToolCalls))
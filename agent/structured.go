@@ -0,0 +1,193 @@
+// structured.go - Structured output: final replies constrained to JSON matching a schema
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/gliderlab/cogate/pkg/llm"
+	"github.com/gliderlab/cogate/tools"
+)
+
+// maxStructuredRetries is how often a reply that fails validation is sent
+// back to the model with the errors
+const maxStructuredRetries = 2
+
+// ParseResponseFormat decodes an OpenAI-style response_format. Empty input and
+// the "text" type mean no constraint and return nil.
+func ParseResponseFormat(data []byte) (*llm.ResponseFormat, error) {
+	if s := strings.TrimSpace(string(data)); s == "" || s == "null" {
+		return nil, nil
+	}
+	var f llm.ResponseFormat
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid response_format: %w", err)
+	}
+	switch f.Type {
+	case "", llm.ResponseFormatText:
+		return nil, nil
+	case llm.ResponseFormatJSONObject:
+		return &f, nil
+	case llm.ResponseFormatJSONSchema:
+		if f.JSONSchema == nil || f.JSONSchema.Schema == nil {
+			return nil, errors.New("invalid response_format: json_schema.schema is required")
+		}
+		if f.JSONSchema.Name == "" {
+			f.JSONSchema.Name = "response"
+		}
+		return &f, nil
+	default:
+		return nil, fmt.Errorf("invalid response_format: unknown type %q", f.Type)
+	}
+}
+
+// structuredTurn carries the response format of a turn and collects its parsed reply
+type structuredTurn struct {
+	format *llm.ResponseFormat
+
+	mu       sync.Mutex
+	retries  int
+	checked  bool
+	object   interface{}
+	checkErr error
+}
+
+type structuredKey struct{}
+
+// withResponseFormat makes the final replies of turns run under ctx match format
+func withResponseFormat(ctx context.Context, format *llm.ResponseFormat) (context.Context, *structuredTurn) {
+	turn := &structuredTurn{format: format}
+	return context.WithValue(ctx, structuredKey{}, turn), turn
+}
+
+// structuredFrom returns the structured turn of ctx, or nil
+func structuredFrom(ctx context.Context) *structuredTurn {
+	turn, _ := ctx.Value(structuredKey{}).(*structuredTurn)
+	return turn
+}
+
+// apply prepares a request for the format: natively where the provider
+// supports it, otherwise by instruction (the reply is validated either way)
+func (t *structuredTurn) apply(provider llm.Provider, req *llm.ChatRequest) {
+	if t == nil {
+		return
+	}
+	applyResponseFormat(provider, req, t.format)
+}
+
+// accept validates a final reply. It returns the JSON text of the reply, or
+// whether the model should be asked again with the error.
+func (t *structuredTurn) accept(content string) (string, bool, error) {
+	val, text, err := parseStructured(t.format, content)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.checked, t.object, t.checkErr = true, val, err
+	if err == nil {
+		return text, false, nil
+	}
+	t.retries++
+	return content, t.retries <= maxStructuredRetries, err
+}
+
+// result returns the parsed reply of the turn, or why there is none
+func (t *structuredTurn) result() (interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.checked {
+		return nil, errors.New("the turn ended without a reply to check")
+	}
+	return t.object, t.checkErr
+}
+
+// ChatStructured runs an isolated turn whose final reply must be JSON matching
+// format. It returns the reply text and the parsed value; the error reports a
+// reply that still failed validation after the retries.
+func (a *Agent) ChatStructured(ctx context.Context, sessionKey string, messages []Message, format *llm.ResponseFormat) (string, interface{}, error) {
+	ctx, turn := withResponseFormat(ctx, format)
+	ctx, endRun := a.startRun(ctx, sessionKey)
+	defer endRun()
+	reply := a.chatInternal(ctx, sessionKey, messages)
+	obj, err := turn.result()
+	return reply, obj, err
+}
+
+// applyResponseFormat sets the native response format when the provider has
+// one and always states the format in the system prompt: some APIs require
+// the prompt to mention JSON, and the rest only have the prompt to go by.
+func applyResponseFormat(provider llm.Provider, req *llm.ChatRequest, format *llm.ResponseFormat) {
+	if !format.Structured() {
+		return
+	}
+	native := llm.HasCapability(provider, llm.CapabilityStructuredOutput)
+	if native {
+		req.ResponseFormat = format
+	}
+	instruction := "Reply with only a JSON object, without code fences or other text."
+	if format.Type == llm.ResponseFormatJSONSchema && !native {
+		schema, _ := json.Marshal(format.Schema())
+		instruction = "Reply with only a JSON value matching this JSON schema, without code fences or other text:\n" + string(schema)
+	}
+
+	msgs := make([]llm.Message, len(req.Messages))
+	copy(msgs, req.Messages)
+	if len(msgs) > 0 && msgs[0].Role == "system" {
+		msgs[0].Content = strings.TrimSpace(msgs[0].Content + "\n\n" + instruction)
+	} else {
+		msgs = append([]llm.Message{{Role: "system", Content: instruction}}, msgs...)
+	}
+	req.Messages = msgs
+}
+
+// parseStructured extracts the JSON value of a reply, tolerating code fences
+// and surrounding prose, and validates it against the format's schema. It
+// returns the value with coercions applied and the JSON text it was read from.
+func parseStructured(format *llm.ResponseFormat, content string) (interface{}, string, error) {
+	text := strings.TrimSpace(content)
+	if i := strings.Index(text, "```"); i >= 0 {
+		body := text[i+3:]
+		body = body[strings.IndexByte(body+"\n", '\n'):]
+		if j := strings.Index(body, "```"); j >= 0 {
+			text = strings.TrimSpace(body[:j])
+		}
+	}
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return nil, "", errors.New("the reply contains no JSON")
+	}
+	dec := json.NewDecoder(strings.NewReader(text[start:]))
+	var val interface{}
+	if err := dec.Decode(&val); err != nil {
+		return nil, "", fmt.Errorf("the reply is not valid JSON: %v", err)
+	}
+	text = text[start : start+int(dec.InputOffset())]
+
+	val, coerced, errs := tools.ValidateValue(format.Schema(), val)
+	if len(errs) > 0 {
+		msgs := make([]string, 0, len(errs))
+		for _, e := range errs {
+			if e.Path == "" {
+				msgs = append(msgs, e.Message)
+			} else {
+				msgs = append(msgs, e.Path+": "+e.Message)
+			}
+		}
+		return nil, "", fmt.Errorf("the reply does not match the schema: %s", strings.Join(msgs, "; "))
+	}
+	if coerced > 0 {
+		if data, err := json.Marshal(val); err == nil {
+			text = string(data)
+		}
+	}
+	return val, text, nil
+}
+
+// structuredRetry is the message asking the model to correct an invalid reply
+func structuredRetry(err error) Message {
+	log.Printf("[FORMAT] reply failed validation, asking again: %v", err)
+	return Message{Role: "user", Content: fmt.Sprintf("Your reply was rejected: %v. Reply again with only the corrected JSON.", err)}
+}
@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gliderlab/cogate/pkg/config"
+	"github.com/gliderlab/cogate/pkg/llm"
+	"github.com/gliderlab/cogate/pkg/llm/providers/mock"
+	"github.com/gliderlab/cogate/rpcproto"
+	"github.com/gliderlab/cogate/tools"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newStructuredAgent(t *testing.T, script string) (*Agent, *mock.Provider) {
+	t.Helper()
+	s, err := mock.Parse([]byte(script))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := mock.NewFromScript(s)
+	a := NewAgentDI().WithConfig(config.AgentConfig{
+		Model: "m", APIKey: "k", ContextTokens: 8192,
+	}).WithRegistry(tools.NewRegistry()).Build()
+	a.WithProvider(p)
+	return a, p
+}
+
+func countFormat() *llm.ResponseFormat {
+	f, _ := ParseResponseFormat([]byte(`{"type": "json_schema", "json_schema": {"name": "count",
+		"schema": {"type": "object", "properties": {"n": {"type": "integer"}}, "required": ["n"]}}}`))
+	return f
+}
+
+func TestChatStructuredRetriesInvalidReply(t *testing.T) {
+	a, p := newStructuredAgent(t, `
+responses:
+  - content: There are three.
+  - content: |
+      Here you go:
+      `+"```json"+`
+      {"n": "3"}
+      `+"```"+`
+`)
+	reply, obj, err := a.ChatStructured(context.Background(), "s", []Message{{Role: "user", Content: "how many?"}}, countFormat())
+	if err != nil {
+		t.Fatalf("ChatStructured: %v", err)
+	}
+	if reply != `{"n":3}` {
+		t.Errorf("reply = %q", reply)
+	}
+	if m, _ := obj.(map[string]interface{}); m["n"] != float64(3) {
+		t.Errorf("object = %#v", obj)
+	}
+	if p.Calls() != 2 {
+		t.Fatalf("LLM calls = %d", p.Calls())
+	}
+	reqs := p.Requests()
+	if reqs[0].ResponseFormat != nil {
+		t.Error("native response_format sent to a provider without support")
+	}
+	if sys := reqs[0].Messages[0]; sys.Role != "system" || !strings.Contains(sys.Content, `"required":["n"]`) {
+		t.Errorf("schema missing from system prompt: %+v", sys)
+	}
+	msgs := reqs[1].Messages
+	if last := msgs[len(msgs)-1]; last.Role != "user" || !strings.Contains(last.Content, "rejected") {
+		t.Errorf("retry message = %+v", last)
+	}
+}
+
+func TestChatStructuredGivesUp(t *testing.T) {
+	a, p := newStructuredAgent(t, `
+loop: true
+responses:
+  - content: '{"n": "many"}'
+`)
+	reply, _, err := a.ChatStructured(context.Background(), "s", []Message{{Role: "user", Content: "how many?"}}, countFormat())
+	if err == nil || !strings.Contains(err.Error(), "n: expected integer") {
+		t.Errorf("err = %v", err)
+	}
+	if reply != `{"n": "many"}` {
+		t.Errorf("reply = %q", reply)
+	}
+	if p.Calls() != 1+maxStructuredRetries {
+		t.Errorf("LLM calls = %d", p.Calls())
+	}
+}
+
+func TestChatStructuredNative(t *testing.T) {
+	a, p := newStructuredAgent(t, `
+capabilities: [structured_output]
+responses:
+  - content: '{"n": 7}'
+`)
+	_, obj, err := a.ChatStructured(context.Background(), "s", []Message{{Role: "user", Content: "how many?"}}, countFormat())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := obj.(map[string]interface{}); m["n"] != float64(7) {
+		t.Errorf("object = %#v", obj)
+	}
+	req := p.Requests()[0]
+	if req.ResponseFormat == nil || req.ResponseFormat.JSONSchema.Name != "count" {
+		t.Errorf("response_format = %+v", req.ResponseFormat)
+	}
+	if strings.Contains(req.Messages[0].Content, `"required"`) {
+		t.Error("schema repeated in the prompt despite native support")
+	}
+}
+
+func TestParseResponseFormat(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		want    string // Type, or "" for no constraint
+		wantErr bool
+	}{
+		{``, "", false},
+		{`null`, "", false},
+		{`{"type": "text"}`, "", false},
+		{`{"type": "json_object"}`, llm.ResponseFormatJSONObject, false},
+		{`{"type": "json_schema", "json_schema": {"schema": {"type": "object"}}}`, llm.ResponseFormatJSONSchema, false},
+		{`{"type": "json_schema"}`, "", true},
+		{`{"type": "yaml"}`, "", true},
+		{`{`, "", true},
+	} {
+		f, err := ParseResponseFormat([]byte(tc.in))
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: err = %v", tc.in, err)
+			continue
+		}
+		got := ""
+		if f != nil {
+			got = f.Type
+		}
+		if got != tc.want {
+			t.Errorf("%s: type = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestGRPCChatRejectsBadResponseFormat(t *testing.T) {
+	a, p := newStructuredAgent(t, "responses:\n  - content: hi\n")
+	_, err := NewGRPCService(a).Chat(context.Background(), &rpcproto.ChatArgs{
+		Messages:       []*rpcproto.Message{{Role: "user", Content: "hi"}},
+		ResponseFormat: `{"type": "yaml"}`,
+	})
+	if status.Code(err) != codes.InvalidArgument || p.Calls() != 0 {
+		t.Errorf("err = %v after %d calls", err, p.Calls())
+	}
+}
@@ -2,12 +2,13 @@
 package agent
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
 	"strings"
 	"time"
+
+	"github.com/gliderlab/cogate/pkg/llm"
 )
 
 // SubtaskInfo represents the result of task splitting
@@ -22,8 +23,8 @@ func (a *Agent) ShouldSplitTask(message string) bool {
 	return false
 }
 
-// SplitTask calls LLM to split a task into subtasks
-func (a *Agent) SplitTask(message string) ([]string, error) {
+// SplitTask calls LLM to split a task into subtasks, as a call of sessionKey
+func (a *Agent) SplitTask(ctx context.Context, sessionKey, message string) ([]string, error) {
 	prompt := fmt.Sprintf(`Split the following task into specific subtask steps.
 
 Requirements:
@@ -39,132 +40,43 @@ Return format:
 {"subtasks": ["subtask 1", "subtask 2", ...]}`, message)
 
 	// Call LLM to split the task
-	reqBody := map[string]interface{}{
-		"model": a.cfg.Model,
-		"messages": []map[string]interface{}{
-			{"role": "system", "content": "You are a task splitting assistant. Return JSON only, no other content."},
-			{"role": "user", "content": prompt},
-		},
-		"max_tokens": 2000,
-		"temperature": 0.3,
-	}
-
-	body, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("marshal failed: %v", err)
-	}
-
-	req, err := a.client.Post(a.cfg.BaseURL+"/chat/completions", "application/json", bytes.NewReader(body))
+	val, err := a.completeStructured(ctx, sessionKey, PurposeSplit,
+		"You are a task splitting assistant.", prompt, subtasksFormat, 2000, 0.3)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %v", err)
 	}
-	defer req.Body.Close()
-
-	if req.StatusCode != 200 {
-		return nil, fmt.Errorf("API error: %d", req.StatusCode)
-	}
-
-	var resp map[string]interface{}
-	if err := json.NewDecoder(req.Body).Decode(&resp); err != nil {
-		return nil, fmt.Errorf("decode failed: %v", err)
-	}
-
-	// Parse response
-	choices, ok := resp["choices"].([]interface{})
-	if !ok || len(choices) == 0 {
-		return nil, fmt.Errorf("no choices in response")
-	}
-
-	choice, ok := choices[0].(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid choice format")
-	}
-
-	delta, ok := choice["delta"].(map[string]interface{})
-	if !ok {
-		// Try content for non-streaming
-		content, ok := choice["message"].(map[string]interface{})["content"].(string)
-		if !ok {
-			return nil, fmt.Errorf("no content in response")
-		}
-		return parseSubtasksFromJSON(content)
-	}
-
-	content, ok := delta["content"].(string)
-	if !ok {
-		return nil, fmt.Errorf("no content in delta")
-	}
-
-	return parseSubtasksFromJSON(content)
-}
-
-// parseSubtasksFromJSON extracts subtasks from JSON response
-func parseSubtasksFromJSON(content string) ([]string, error) {
-	// Find JSON in response
-	start := strings.Index(content, "{")
-	if start == -1 {
-		return nil, fmt.Errorf("no JSON found")
-	}
 
-	// Find the matching closing brace
-	depth := 0
-	end := start
-	for i, c := range content[start:] {
-		if c == '{' {
-			depth++
-		} else if c == '}' {
-			depth--
-			if depth == 0 {
-				end = start + i + 1
-				break
-			}
-		}
-	}
-
-	jsonStr := content[start:end]
-	var result SubtaskInfo
-	if err := json.Unmarshal([]byte(jsonStr), &result); err != nil {
-		// Try to extract subtasks with regex
-		return extractSubtasksManually(content)
-	}
-
-	if len(result.Subtasks) == 0 {
-		return nil, fmt.Errorf("no subtasks found")
-	}
-
-	return result.Subtasks, nil
-}
-
-// extractSubtasksManually tries to extract subtasks without full JSON parsing
-func extractSubtasksManually(content string) ([]string, error) {
 	var subtasks []string
-
-	// Look for numbered items or bullet points
-	lines := strings.Split(content, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		line = strings.TrimPrefix(line, "- ")
-		line = strings.TrimPrefix(line, "* ")
-		line = strings.TrimPrefix(line, "1. ")
-		line = strings.TrimPrefix(line, "2. ")
-		line = strings.TrimPrefix(line, "3. ")
-		line = strings.TrimPrefix(line, "4. ")
-		line = strings.TrimPrefix(line, "5. ")
-
-		if len(line) > 5 {
-			subtasks = append(subtasks, line)
+	for _, s := range val.(map[string]interface{})["subtasks"].([]interface{}) {
+		if s, _ := s.(string); strings.TrimSpace(s) != "" {
+			subtasks = append(subtasks, s)
 		}
 	}
-
 	if len(subtasks) == 0 {
-		return nil, fmt.Errorf("could not extract subtasks")
+		return nil, fmt.Errorf("no subtasks found")
 	}
-
 	return subtasks, nil
 }
 
-// ExecuteSubtasks executes all subtasks for a given task
-func (a *Agent) ExecuteSubtasks(taskID, sessionKey string) (string, error) {
+// subtasksFormat is the structured reply SplitTask asks for
+var subtasksFormat = &llm.ResponseFormat{
+	Type: llm.ResponseFormatJSONSchema,
+	JSONSchema: &llm.JSONSchemaSpec{
+		Name: "subtasks",
+		Schema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"subtasks": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+				"reason":   map[string]interface{}{"type": "string"},
+			},
+			"required": []string{"subtasks"},
+		},
+	},
+}
+
+// ExecuteSubtasks executes all subtasks for a given task in sessionKey,
+// stopping when ctx is done
+func (a *Agent) ExecuteSubtasks(ctx context.Context, taskID, sessionKey string) (string, error) {
 	log.Printf("[TaskSplit] Executing subtasks for task: %s", taskID)
 
 	// Initialize task status in KV for fast access
@@ -189,6 +101,13 @@ func (a *Agent) ExecuteSubtasks(taskID, sessionKey string) (string, error) {
 			// No more pending subtasks
 			break
 		}
+		if err := ctx.Err(); err != nil {
+			a.store.UpdateUserTaskError(taskID, stoppedReply(ctx))
+			if a.kv != nil {
+				a.kv.SetTaskStatus(taskID, "failed")
+			}
+			return "", err
+		}
 
 		log.Printf("[TaskSplit] Executing subtask %d: %s", subtask.IndexNum+1, subtask.Description)
 
@@ -211,7 +130,7 @@ func (a *Agent) ExecuteSubtasks(taskID, sessionKey string) (string, error) {
 
 		// Execute the subtask as a mini LLM call
 		startTime := time.Now()
-		result := a.executeSubtask(subtask.Description)
+		result := a.executeSubtask(ctx, sessionKey, subtask.Description)
 		duration := time.Since(startTime)
 
 		// Build process log
@@ -246,7 +165,10 @@ func (a *Agent) ExecuteSubtasks(taskID, sessionKey string) (string, error) {
 			startTime.Format("2006-01-02 15:04:05"), duration, result))
 
 		// Small delay between subtasks
-		time.Sleep(500 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+		case <-time.After(500 * time.Millisecond):
+		}
 	}
 
 	// Combine all results
@@ -268,8 +190,8 @@ func (a *Agent) ExecuteSubtasks(taskID, sessionKey string) (string, error) {
 }
 
 // executeSubtask executes a single subtask
-func (a *Agent) executeSubtask(description string) string {
-	if a.cfg.APIKey == "" {
+func (a *Agent) executeSubtask(ctx context.Context, sessionKey, description string) string {
+	if !a.hasLLM() {
 		return "API key not configured"
 	}
 
@@ -278,15 +200,20 @@ func (a *Agent) executeSubtask(description string) string {
 	}
 
 	// Call LLM with timeout
+	callCtx, cancel := context.WithTimeout(ctx, 120*time.Second)
+	defer cancel()
 	resultChan := make(chan string, 1)
 	go func() {
-		resultChan <- a.callAPI(messages)
+		resultChan <- a.callAPI(callCtx, sessionKey, messages)
 	}()
 
 	select {
 	case result := <-resultChan:
 		return result
-	case <-time.After(120 * time.Second):
+	case <-callCtx.Done():
+		if ctx.Err() != nil {
+			return stoppedReply(ctx)
+		}
 		return "Execution timeout"
 	}
 }
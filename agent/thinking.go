@@ -2,8 +2,11 @@ package agent
 
 import (
 	"bufio"
+	"cmp"
 	"strings"
 	"sync"
+
+	"github.com/gliderlab/cogate/pkg/llm"
 )
 
 // ThinkingMode Thinking mode
@@ -28,6 +31,15 @@ var DefaultThinkingConfig = ThinkingConfig{
 	MaxTokens: 4000,
 }
 
+// requestOptions returns the extended thinking to request from the provider,
+// or nil when thinking is off
+func (c ThinkingConfig) requestOptions() *llm.ThinkingOptions {
+	if c.Mode == "" || c.Mode == ThinkingModeOff {
+		return nil
+	}
+	return &llm.ThinkingOptions{BudgetTokens: cmp.Or(c.MaxTokens, DefaultThinkingConfig.MaxTokens)}
+}
+
 // ParseThinkingMode Parse thinking mode
 func ParseThinkingMode(s string) ThinkingMode {
 	s = strings.ToLower(strings.TrimSpace(s))
@@ -53,7 +65,7 @@ type ThinkingProcessor struct {
 // NewThinkingProcessor Create thinking processor
 func NewThinkingProcessor(cfg ThinkingConfig) *ThinkingProcessor {
 	if cfg.MaxTokens == 0 {
-		cfg = DefaultThinkingConfig
+		cfg.MaxTokens = DefaultThinkingConfig.MaxTokens
 	}
 	return &ThinkingProcessor{
 		config: cfg,
@@ -104,6 +116,75 @@ func (p *ThinkingProcessor) GetThinking() string {
 	return p.finalText
 }
 
+// IsActive Is a thinking block in progress
+func (p *ThinkingProcessor) IsActive() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.isActive
+}
+
+// WithThinking Prepend provider thinking to a reply as a <think> block
+func (p *ThinkingProcessor) WithThinking(thinking, reply string) string {
+	if thinking == "" || !p.IsEnabled() {
+		return reply
+	}
+	p.Start()
+	p.Append(thinking)
+	p.End()
+	return "<think>" + p.GetThinking() + "</think>\n\n" + reply
+}
+
+// thinkingStream relays provider thinking deltas to a stream callback: stream
+// mode forwards them as they arrive inside <think> tags, on mode sends the
+// whole block once it ends, off mode drops them. Completed blocks are kept
+// so they can be returned to the provider with the turn's tool calls.
+type thinkingStream struct {
+	proc     *ThinkingProcessor
+	callback func(string)
+	text     strings.Builder
+	parts    []llm.ContentPart
+}
+
+func newThinkingStream(cfg ThinkingConfig, callback func(string)) *thinkingStream {
+	return &thinkingStream{proc: NewThinkingProcessor(cfg), callback: callback}
+}
+
+// add consumes the thinking fields of a stream delta
+func (s *thinkingStream) add(delta llm.StreamDelta) {
+	if delta.Thinking != "" {
+		if !s.proc.IsActive() {
+			s.proc.Start()
+			if s.proc.IsStream() {
+				s.callback("<think>")
+			}
+		}
+		s.proc.Append(delta.Thinking)
+		if s.proc.IsStream() {
+			s.callback(delta.Thinking)
+		}
+		s.text.WriteString(delta.Thinking)
+	}
+	if delta.ThinkingSignature != "" {
+		s.parts = append(s.parts, llm.ThinkingPart(s.text.String(), delta.ThinkingSignature))
+		s.text.Reset()
+	}
+}
+
+// end closes the thinking block in progress, if any; call it before
+// relaying answer text or tool events
+func (s *thinkingStream) end() {
+	if !s.proc.IsActive() {
+		return
+	}
+	s.proc.End()
+	switch {
+	case s.proc.IsStream():
+		s.callback("</think>\n\n")
+	case s.proc.IsEnabled():
+		s.callback("<think>" + s.proc.GetThinking() + "</think>\n\n")
+	}
+}
+
 // ExtractThinkingFromStream Extract thinking from stream
 func ExtractThinkingFromStream(data string) (thinking, remaining string, hasThinking bool) {
 	// Detect thinking tag
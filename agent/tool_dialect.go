@@ -0,0 +1,128 @@
+// tool_dialect.go - Tool calls written into the reply text, parsed by the model's dialect
+package agent
+
+import (
+	"cmp"
+	"log"
+	"strings"
+
+	"github.com/gliderlab/cogate/pkg/llm"
+	"github.com/gliderlab/cogate/pkg/llm/dialect"
+)
+
+// defaultToolAliases maps tool names models commonly invent to registered ones;
+// a model's toolAliases extend and override them
+var defaultToolAliases = map[string]string{
+	"read_file":       "read",
+	"cat":             "read",
+	"write_file":      "write",
+	"execute_command": "exec",
+	"exec_cmd":        "exec",
+}
+
+// modelConfig returns the models config entry of model, matching the longest
+// configured prefix when there is no exact entry ("qwen2.5" covers "qwen2.5:7b")
+func (a *Agent) modelConfig(model string) (llm.ModelConfig, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	models := a.cfg.Models.Models
+	if mc, ok := models[model]; ok {
+		return mc, true
+	}
+	best, found := "", false
+	for name := range models {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best, found = name, true
+		}
+	}
+	return models[best], found
+}
+
+// textToolCalls separates the tool calls a model writes into its reply from
+// the text shown to the user
+type textToolCalls struct {
+	dialect dialect.Dialect
+	stream  *dialect.Stream
+	aliases map[string]string
+}
+
+// defaultToolDialect is the dialect of models known to write their tool calls
+// as text: MiniMax models, on the minimax provider or behind another
+func defaultToolDialect(cfg llm.Config) string {
+	if cfg.Type == llm.ProviderMiniMax || strings.HasPrefix(strings.ToLower(cfg.Model), "minimax") {
+		return "minimax"
+	}
+	return ""
+}
+
+// textToolCalls returns the parser for the replies of cfg's model, or nil
+// when neither the models config nor defaultToolDialect gives it a dialect:
+// replies of models that call tools natively are left alone, so text that
+// merely looks like a call (a JSON example, a code sample) is never run.
+func (a *Agent) textToolCalls(cfg llm.Config) *textToolCalls {
+	model := cfg.Model
+	mc, _ := a.modelConfig(model)
+	name := cmp.Or(mc.ToolDialect, defaultToolDialect(cfg))
+	if name == "" || a.registry == nil {
+		return nil
+	}
+	d, ok := dialect.Get(name)
+	if !ok {
+		log.Printf("[WARN] model %s: unknown tool dialect %q (known: %s)", model, name, strings.Join(dialect.Names(), ", "))
+		return nil
+	}
+	aliases := make(map[string]string, len(defaultToolAliases)+len(mc.ToolAliases))
+	for k, v := range defaultToolAliases {
+		aliases[k] = v
+	}
+	for k, v := range mc.ToolAliases {
+		aliases[k] = v
+	}
+	// Calls to tools that are not registered are left in the text: it was
+	// most likely not meant as a call
+	stream := dialect.NewStream(d)
+	stream.Filter = func(name string) bool {
+		if _, ok := a.registry.Get(cmp.Or(aliases[name], name)); ok {
+			return true
+		}
+		log.Printf("[TOOL] model %s: ignoring %s call to unknown tool %q in reply text", model, d.Name(), name)
+		return false
+	}
+	return &textToolCalls{dialect: d, stream: stream, aliases: aliases}
+}
+
+// feed consumes a streamed chunk and returns the text safe to show now
+func (t *textToolCalls) feed(chunk string) string {
+	return t.stream.Feed(chunk)
+}
+
+// close returns the calls of the reply and the text held back until the end
+func (t *textToolCalls) close() ([]ToolCall, string) {
+	calls, rest := t.stream.Close()
+	return t.toolCalls(calls), rest
+}
+
+// parse splits a complete reply into its calls and remaining text
+func (t *textToolCalls) parse(content string) ([]ToolCall, string) {
+	calls, rest := t.stream.Parse(content)
+	return t.toolCalls(calls), rest
+}
+
+func (t *textToolCalls) toolCalls(calls []llm.ToolCall) []ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]ToolCall, 0, len(calls))
+	for _, c := range calls {
+		var call ToolCall
+		call.ID, call.Type = c.ID, c.Type
+		call.Function.Name = c.Function.Name
+		if alias, ok := t.aliases[call.Function.Name]; ok {
+			call.Function.Name = alias
+		}
+		call.Function.Arguments = c.Function.Arguments
+		out = append(out, call)
+	}
+	log.Printf("[TOOL] parsed %d %s tool call(s) from reply text", len(out), t.dialect.Name())
+	return out
+}
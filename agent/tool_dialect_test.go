@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gliderlab/cogate/pkg/config"
+	"github.com/gliderlab/cogate/pkg/llm"
+	"github.com/gliderlab/cogate/pkg/llm/providers/mock"
+	"github.com/gliderlab/cogate/tools"
+)
+
+func newDialectAgent(t *testing.T, models map[string]llm.ModelConfig, script string) (*Agent, *mock.Provider) {
+	t.Helper()
+	s, err := mock.Parse([]byte(script))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := tools.NewRegistry()
+	reg.Register(clockTool{})
+	p := mock.NewFromScript(s)
+	a := NewAgentDI().WithConfig(config.AgentConfig{
+		Model: "qwen2.5:7b", APIKey: "k", ContextTokens: 8192,
+		Models: llm.ModelsConfig{Models: models},
+	}).WithRegistry(reg).Build()
+	a.WithProvider(p)
+	return a, p
+}
+
+func TestStreamedTextToolCalls(t *testing.T) {
+	a, p := newDialectAgent(t, map[string]llm.ModelConfig{
+		"qwen2.5": {ToolDialect: "qwen", ToolAliases: map[string]string{"get_time": "clock"}},
+	}, `
+responses:
+  - chunks: ["Checking.<tool", "_call>{\"name\": \"get_time\", ", "\"arguments\": {\"zone\": \"UTC\"}}</tool_call>"]
+  - content: It is noon.
+`)
+	var shown strings.Builder
+	a.ChatStream([]Message{{Role: "user", Content: "time?"}}, func(s string) {
+		if !strings.HasPrefix(s, "[TOOL_EVENT]") {
+			shown.WriteString(s)
+		}
+	})
+	if got := shown.String(); got != "Checking.It is noon." {
+		t.Errorf("shown %q", got)
+	}
+	if p.Calls() != 2 {
+		t.Fatalf("LLM calls = %d", p.Calls())
+	}
+	msgs := p.Requests()[1].Messages
+	if tr := msgs[len(msgs)-1]; tr.Role != "tool" || !strings.Contains(tr.Content, "12:00 UTC") {
+		t.Errorf("tool result = %+v", tr)
+	}
+}
+
+func TestTextToolCallsNeedDialect(t *testing.T) {
+	// Without a dialect the reply is text, even when it looks like a call
+	a, p := newDialectAgent(t, nil, `
+responses:
+  - content: '[clock(zone="CET")]'
+`)
+	if got := a.Chat([]Message{{Role: "user", Content: "time?"}}); got != `[clock(zone="CET")]` || p.Calls() != 1 {
+		t.Errorf("reply = %q after %d calls", got, p.Calls())
+	}
+	if a.textToolCalls(llm.Config{Model: "qwen2.5:7b"}) != nil {
+		t.Error("parser without a configured dialect")
+	}
+
+	// Calls to tools that are not registered stay in the text
+	a, p = newDialectAgent(t, map[string]llm.ModelConfig{"qwen2.5": {ToolDialect: "auto"}}, `
+loop: true
+responses:
+  - content: 'Try [launch(target="moon")] or [clock(zone="CET")]'
+  - content: done
+`)
+	if got := a.Chat([]Message{{Role: "user", Content: "time?"}}); got != "done" || p.Calls() != 2 {
+		t.Fatalf("reply = %q after %d calls", got, p.Calls())
+	}
+	msgs := p.Requests()[1].Messages
+	if call := msgs[len(msgs)-2]; len(call.ToolCalls) != 1 || call.ToolCalls[0].Function.Name != "clock" || !strings.Contains(call.Content, "launch") {
+		t.Errorf("assistant message = %+v", call)
+	}
+
+	// A structured reply is never taken for a call
+	format, _ := ParseResponseFormat([]byte(`{"type": "json_object"}`))
+	a, p = newDialectAgent(t, map[string]llm.ModelConfig{"qwen2.5": {ToolDialect: "openai"}}, `
+responses:
+  - content: '{"name": "clock", "arguments": {"zone": "CET"}}'
+`)
+	reply, _, err := a.ChatStructured(context.Background(), "s", []Message{{Role: "user", Content: "json"}}, format)
+	if err != nil || p.Calls() != 1 || !strings.Contains(reply, `"clock"`) {
+		t.Errorf("structured reply = %q, %v after %d calls", reply, err, p.Calls())
+	}
+}
+
+func TestMiniMaxDefaultsToItsDialect(t *testing.T) {
+	// A plain MiniMax setup, with no models config, still runs <invoke> calls
+	s, err := mock.Parse([]byte(`
+responses:
+  - content: "<minimax:tool_call>\n<invoke name=\"clock\">\n<parameter name=\"zone\">UTC</parameter>\n</invoke>\n</minimax:tool_call>"
+  - content: It is noon.
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := tools.NewRegistry()
+	reg.Register(clockTool{})
+	p := mock.NewFromScript(s)
+	a := NewAgentDI().WithConfig(config.AgentConfig{
+		Provider: "minimax", Model: "MiniMax-M2", APIKey: "k", ContextTokens: 8192,
+	}).WithRegistry(reg).Build()
+	a.WithProvider(p)
+
+	if got := a.Chat([]Message{{Role: "user", Content: "time?"}}); got != "It is noon." || p.Calls() != 2 {
+		t.Errorf("reply = %q after %d calls", got, p.Calls())
+	}
+	if a.textToolCalls(llm.Config{Type: llm.ProviderOpenAI, Model: "MiniMax-Text-01"}) == nil {
+		t.Error("no parser for a MiniMax model behind another provider")
+	}
+}
@@ -0,0 +1,92 @@
+// tool_scheduler.go - parallel tool execution by concurrency class
+package agent
+
+import (
+	"cmp"
+	"sync"
+
+	"github.com/gliderlab/cogate/tools"
+)
+
+// defaultMaxParallelTools bounds how many tool calls of one turn run at once
+const defaultMaxParallelTools = 4
+
+// maxParallelTools returns the configured worker pool size for tool calls
+func (a *Agent) maxParallelTools() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return cmp.Or(a.cfg.MaxParallelTools, defaultMaxParallelTools)
+}
+
+// toolConcurrency returns the concurrency class and lock key of a call
+func (a *Agent) toolConcurrency(call ToolCall) (tools.ConcurrencyClass, string) {
+	if a.registry == nil {
+		return tools.ConcurrencySafe, ""
+	}
+	return a.registry.Concurrency(call.Function.Name, parseArgs(call.Function.Arguments))
+}
+
+// runToolCalls executes calls on up to limit workers and returns the results in
+// call order. Runs of consecutive non-exclusive calls execute together; within
+// a run, calls sharing a path key execute one after another in call order. An
+// exclusive call waits for every earlier call and runs alone. A limit of 1 or
+// less runs everything sequentially.
+func runToolCalls(calls []ToolCall, limit int, classify func(ToolCall) (tools.ConcurrencyClass, string), run func(ToolCall) ToolResult) []ToolResult {
+	results := make([]ToolResult, len(calls))
+	if limit <= 1 || len(calls) <= 1 {
+		for i, call := range calls {
+			results[i] = run(call)
+		}
+		return results
+	}
+
+	classes := make([]tools.ConcurrencyClass, len(calls))
+	keys := make([]string, len(calls))
+	for i, call := range calls {
+		classes[i], keys[i] = classify(call)
+	}
+
+	sem := make(chan struct{}, limit)
+	for i := 0; i < len(calls); {
+		if classes[i] == tools.ConcurrencyExclusive {
+			results[i] = run(calls[i])
+			i++
+			continue
+		}
+
+		// Group the run into jobs: one per safe call, one chain per path key
+		var jobs [][]int
+		chains := make(map[string]int)
+		j := i
+		for ; j < len(calls) && classes[j] != tools.ConcurrencyExclusive; j++ {
+			if classes[j] != tools.ConcurrencyPath {
+				jobs = append(jobs, []int{j})
+				continue
+			}
+			if k, ok := chains[keys[j]]; ok {
+				jobs[k] = append(jobs[k], j)
+				continue
+			}
+			chains[keys[j]] = len(jobs)
+			jobs = append(jobs, []int{j})
+		}
+
+		var wg sync.WaitGroup
+		for _, job := range jobs {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(job []int) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				for _, idx := range job {
+					results[idx] = run(calls[idx])
+				}
+			}(job)
+		}
+		wg.Wait()
+		i = j
+	}
+	return results
+}
@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gliderlab/cogate/tools"
+)
+
+func schedCall(id, name, path string) ToolCall {
+	var c ToolCall
+	c.ID = id
+	c.Function.Name = name
+	c.Function.Arguments = fmt.Sprintf(`{"path":%q}`, path)
+	return c
+}
+
+// schedClassify mirrors the built-in classes: read/write lock by path, web_fetch is safe
+func schedClassify(c ToolCall) (tools.ConcurrencyClass, string) {
+	switch c.Function.Name {
+	case "web_fetch":
+		return tools.ConcurrencySafe, ""
+	case "read", "write":
+		return tools.ConcurrencyPath, parseArgs(c.Function.Arguments)["path"].(string)
+	default:
+		return tools.ConcurrencyExclusive, ""
+	}
+}
+
+// schedRecorder runs fake tools, tracking overlap and the order calls started in
+type schedRecorder struct {
+	mu      sync.Mutex
+	active  int
+	peak    int
+	started []string
+	// activeAtStart records how many calls were running when each call began
+	activeAtStart map[string]int
+}
+
+func (r *schedRecorder) run(c ToolCall) ToolResult {
+	r.mu.Lock()
+	r.active++
+	if r.active > r.peak {
+		r.peak = r.active
+	}
+	r.started = append(r.started, c.ID)
+	if r.activeAtStart == nil {
+		r.activeAtStart = make(map[string]int)
+	}
+	r.activeAtStart[c.ID] = r.active
+	r.mu.Unlock()
+
+	time.Sleep(30 * time.Millisecond)
+
+	r.mu.Lock()
+	r.active--
+	r.mu.Unlock()
+	return ToolResult{ID: c.ID, Type: "function", Result: "done " + c.ID}
+}
+
+func TestRunToolCallsParallelInOrder(t *testing.T) {
+	var calls []ToolCall
+	for i := 0; i < 6; i++ {
+		calls = append(calls, schedCall(fmt.Sprintf("c%d", i), "web_fetch", ""))
+	}
+	rec := &schedRecorder{}
+	start := time.Now()
+	results := runToolCalls(calls, 3, schedClassify, rec.run)
+	elapsed := time.Since(start)
+
+	for i, r := range results {
+		if r.ID != calls[i].ID || r.Result != "done "+calls[i].ID {
+			t.Errorf("result %d = %+v, want %s", i, r, calls[i].ID)
+		}
+	}
+	if rec.peak != 3 {
+		t.Errorf("peak concurrency = %d, want 3", rec.peak)
+	}
+	if elapsed >= 6*30*time.Millisecond {
+		t.Errorf("calls ran sequentially (%v)", elapsed)
+	}
+}
+
+func TestRunToolCallsExclusiveRunsAlone(t *testing.T) {
+	calls := []ToolCall{
+		schedCall("a", "web_fetch", ""),
+		schedCall("b", "web_fetch", ""),
+		schedCall("x", "exec", ""),
+		schedCall("c", "web_fetch", ""),
+	}
+	rec := &schedRecorder{}
+	results := runToolCalls(calls, 4, schedClassify, rec.run)
+
+	if rec.activeAtStart["x"] != 1 {
+		t.Errorf("exclusive call overlapped %d calls", rec.activeAtStart["x"]-1)
+	}
+	idx := map[string]int{}
+	for i, id := range rec.started {
+		idx[id] = i
+	}
+	if idx["x"] < idx["a"] || idx["x"] < idx["b"] || idx["c"] < idx["x"] {
+		t.Errorf("start order = %v", rec.started)
+	}
+	for i, r := range results {
+		if r.ID != calls[i].ID {
+			t.Errorf("result %d = %s, want %s", i, r.ID, calls[i].ID)
+		}
+	}
+}
+
+func TestRunToolCallsPathLock(t *testing.T) {
+	calls := []ToolCall{
+		schedCall("w1", "write", "/tmp/a"),
+		schedCall("r1", "read", "/tmp/b"),
+		schedCall("r2", "read", "/tmp/a"),
+		schedCall("w2", "write", "/tmp/a"),
+	}
+	rec := &schedRecorder{}
+	results := runToolCalls(calls, 4, schedClassify, rec.run)
+
+	var onA []string
+	for _, id := range rec.started {
+		if id != "r1" {
+			onA = append(onA, id)
+		}
+	}
+	if fmt.Sprint(onA) != "[w1 r2 w2]" {
+		t.Errorf("calls on /tmp/a started in order %v", onA)
+	}
+	if rec.peak != 2 {
+		t.Errorf("peak concurrency = %d, want 2 (one per path)", rec.peak)
+	}
+	for i, r := range results {
+		if r.ID != calls[i].ID {
+			t.Errorf("result %d = %s, want %s", i, r.ID, calls[i].ID)
+		}
+	}
+}
+
+func TestRunToolCallsSequentialLimit(t *testing.T) {
+	calls := []ToolCall{
+		schedCall("a", "web_fetch", ""),
+		schedCall("b", "read", "/tmp/a"),
+		schedCall("c", "web_fetch", ""),
+	}
+	rec := &schedRecorder{}
+	runToolCalls(calls, 1, schedClassify, rec.run)
+	if rec.peak != 1 || fmt.Sprint(rec.started) != "[a b c]" {
+		t.Errorf("peak %d, order %v; want sequential", rec.peak, rec.started)
+	}
+}
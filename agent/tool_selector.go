@@ -0,0 +1,185 @@
+// tool_selector.go - Sending each request only the tools relevant to the user turn
+package agent
+
+import (
+	"cmp"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gliderlab/cogate/memory"
+	"github.com/gliderlab/cogate/pkg/llm"
+	"github.com/gliderlab/cogate/tools"
+)
+
+// defaultToolSelectionTopN is how many ranked tools a request gets besides pinned ones
+const defaultToolSelectionTopN = 12
+
+// toolSelector ranks tools by embedding similarity between the user turn and
+// the tool descriptions. Vectors are cached per tool and re-embedded when a
+// description changes.
+type toolSelector struct {
+	embedder memory.EmbeddingProvider
+
+	mu        sync.Mutex
+	vectors   map[string][]float32 // Tool name -> vector of its text
+	texts     map[string]string    // Tool name -> text the vector was computed from
+	lastQuery string               // The tool loop repeats the same turn; keep its vector
+	lastVec   []float32
+}
+
+func newToolSelector(embedder memory.EmbeddingProvider) *toolSelector {
+	return &toolSelector{
+		embedder: embedder,
+		vectors:  make(map[string][]float32),
+		texts:    make(map[string]string),
+	}
+}
+
+func (s *toolSelector) queryVector(query string) ([]float32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if query == s.lastQuery && s.lastVec != nil {
+		return s.lastVec, nil
+	}
+	vec, err := s.embedder.Embed(query)
+	if err != nil {
+		return nil, err
+	}
+	s.lastQuery, s.lastVec = query, vec
+	return vec, nil
+}
+
+func (s *toolSelector) toolVector(t llm.Tool) ([]float32, error) {
+	name := t.Function.Name
+	text := name + ": " + t.Function.Description
+	s.mu.Lock()
+	if vec, ok := s.vectors[name]; ok && s.texts[name] == text {
+		s.mu.Unlock()
+		return vec, nil
+	}
+	s.mu.Unlock()
+
+	vec, err := s.embedder.Embed(text)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.vectors[name], s.texts[name] = vec, text
+	s.mu.Unlock()
+	return vec, nil
+}
+
+// rank returns the names of the topN candidates most similar to query
+func (s *toolSelector) rank(query string, candidates []llm.Tool, topN int) (map[string]bool, error) {
+	qvec, err := s.queryVector(query)
+	if err != nil {
+		return nil, err
+	}
+	type scored struct {
+		name  string
+		score float32
+	}
+	ranked := make([]scored, 0, len(candidates))
+	for _, t := range candidates {
+		vec, err := s.toolVector(t)
+		if err != nil {
+			return nil, err
+		}
+		ranked = append(ranked, scored{t.Function.Name, memory.CosineSimilarity(qvec, vec)})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+
+	picked := make(map[string]bool, topN)
+	for i := 0; i < len(ranked) && i < topN; i++ {
+		picked[ranked[i].name] = true
+	}
+	return picked, nil
+}
+
+// toolEmbedder returns the embedding provider for tool selection: the injected
+// one, else the memory store's
+func (a *Agent) toolEmbedder() memory.EmbeddingProvider {
+	if a.embedder != nil {
+		return a.embedder
+	}
+	if a.memoryStore != nil {
+		return a.memoryStore.Embedder()
+	}
+	return nil
+}
+
+// selectTools trims the tools of a request to the pinned tools, any tool
+// already called in the conversation (so a tool the model asks for that was
+// not sent is offered on the next iteration) and the top-N by relevance to the
+// last user turn. It sends every tool when selection is off or fails.
+func (a *Agent) selectTools(messages []Message, all []llm.Tool) []llm.Tool {
+	a.mu.RLock()
+	sel := a.cfg.ToolSelection
+	a.mu.RUnlock()
+	topN := cmp.Or(sel.TopN, defaultToolSelectionTopN)
+	if !sel.Enabled || len(all) <= topN {
+		return all
+	}
+
+	query := ""
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" && strings.TrimSpace(messages[i].Content) != "" {
+			query = messages[i].Content
+			break
+		}
+	}
+	if query == "" {
+		return all
+	}
+
+	a.toolSelectorOnce.Do(func() {
+		if embedder := a.toolEmbedder(); embedder != nil {
+			a.toolSelector = newToolSelector(embedder)
+		} else {
+			log.Printf("[TOOL] tool selection enabled but no embedding provider; sending all tools")
+		}
+	})
+	if a.toolSelector == nil {
+		return all
+	}
+
+	keep := make(map[string]bool)
+	for _, entry := range sel.Pinned {
+		if members, ok := tools.ToolGroups[entry]; ok {
+			for _, m := range members {
+				keep[m] = true
+			}
+		} else {
+			keep[entry] = true
+		}
+	}
+	for _, m := range messages {
+		for _, tc := range m.ToolCalls {
+			keep[tc.Function.Name] = true
+		}
+	}
+
+	candidates := make([]llm.Tool, 0, len(all))
+	for _, t := range all {
+		if !keep[t.Function.Name] {
+			candidates = append(candidates, t)
+		}
+	}
+	picked, err := a.toolSelector.rank(query, candidates, topN)
+	if err != nil {
+		log.Printf("[TOOL] tool selection failed, sending all tools: %v", err)
+		return all
+	}
+
+	// Keep the order of the full list so identical selections produce identical requests
+	selected := make([]llm.Tool, 0, len(keep)+len(picked))
+	for _, t := range all {
+		if keep[t.Function.Name] || picked[t.Function.Name] {
+			selected = append(selected, t)
+		}
+	}
+	log.Printf("[TOOL] selected %d/%d tools", len(selected), len(all))
+	return selected
+}
@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"errors"
+	"hash/fnv"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gliderlab/cogate/pkg/config"
+	"github.com/gliderlab/cogate/pkg/llm"
+)
+
+// wordEmbedder embeds text as a bag of hashed words
+type wordEmbedder struct {
+	calls int
+	err   error
+}
+
+func (e *wordEmbedder) Embed(text string) ([]float32, error) {
+	e.calls++
+	if e.err != nil {
+		return nil, e.err
+	}
+	vec := make([]float32, 64)
+	for _, w := range strings.Fields(strings.ToLower(strings.Trim(text, "?.!"))) {
+		h := fnv.New32a()
+		h.Write([]byte(strings.Trim(w, ":?.,")))
+		vec[h.Sum32()%64]++
+	}
+	return vec, nil
+}
+func (e *wordEmbedder) Dim() int     { return 64 }
+func (e *wordEmbedder) Name() string { return "words" }
+
+func selectorTools(names ...string) []llm.Tool {
+	descs := map[string]string{
+		"weather": "rain and temperature forecast",
+		"stocks":  "share price quotes",
+		"email":   "send mail messages",
+		"exec":    "run shell commands",
+		"read":    "read file contents",
+	}
+	var out []llm.Tool
+	for _, n := range names {
+		out = append(out, llm.Tool{Type: "function", Function: &llm.ToolFunction{Name: n, Description: descs[n]}})
+	}
+	return out
+}
+
+func toolNames(ts []llm.Tool) []string {
+	var names []string
+	for _, t := range ts {
+		names = append(names, t.Function.Name)
+	}
+	return names
+}
+
+func TestSelectTools(t *testing.T) {
+	emb := &wordEmbedder{}
+	a := NewAgentDI().WithConfig(config.AgentConfig{
+		Model: "m", APIKey: "k", ContextTokens: 8192,
+		ToolSelection: config.ToolSelectionConfig{Enabled: true, TopN: 1, Pinned: []string{"group:runtime"}},
+	}).WithEmbedder(emb).Build()
+	all := selectorTools("weather", "stocks", "email", "exec", "read")
+
+	msgs := []Message{{Role: "user", Content: "will it rain tomorrow?"}}
+	if got := toolNames(a.selectTools(msgs, all)); !reflect.DeepEqual(got, []string{"weather", "exec"}) {
+		t.Errorf("selected %v", got)
+	}
+	// Tool vectors are cached; only the new query is embedded
+	before := emb.calls
+	a.selectTools([]Message{{Role: "user", Content: "what is the share price"}}, all)
+	if emb.calls != before+1 {
+		t.Errorf("embedded %d texts for a repeat selection", emb.calls-before)
+	}
+
+	// A tool the model called without being offered it is sent on the next iteration
+	var call ToolCall
+	call.Function.Name = "email"
+	msgs = append(msgs, Message{Role: "assistant", ToolCalls: []ToolCall{call}}, Message{Role: "tool", Content: "sent"})
+	if got := toolNames(a.selectTools(msgs, all)); !reflect.DeepEqual(got, []string{"weather", "email", "exec"}) {
+		t.Errorf("selected after call %v", got)
+	}
+
+	// Failing embeddings send every tool
+	emb.err = errors.New("down")
+	if got := a.selectTools([]Message{{Role: "user", Content: "anything new"}}, all); len(got) != len(all) {
+		t.Errorf("selected %d tools on embed failure", len(got))
+	}
+}
+
+func TestSelectToolsDisabled(t *testing.T) {
+	a := NewAgentDI().WithConfig(config.AgentConfig{Model: "m", APIKey: "k", ContextTokens: 8192}).
+		WithEmbedder(&wordEmbedder{}).Build()
+	all := selectorTools("weather", "stocks", "email")
+	if got := a.selectTools([]Message{{Role: "user", Content: "rain"}}, all); len(got) != 3 {
+		t.Errorf("selection off but got %v", toolNames(got))
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -18,17 +19,39 @@ import (
 	"github.com/gliderlab/cogate/pkg/binddb"
 	pkgconfig "github.com/gliderlab/cogate/pkg/config"
 	"github.com/gliderlab/cogate/pkg/kv"
+	"github.com/gliderlab/cogate/pkg/llm"
 	"github.com/gliderlab/cogate/rpcproto"
 	"github.com/gliderlab/cogate/storage"
 	"github.com/gliderlab/cogate/tools"
 )
 
 type Config struct {
-	APIKey  string `json:"apiKey"`
-	BaseURL string `json:"baseUrl"`
-	Model   string `json:"model"`
-	Port    int    `json:"port"`
-	DBPath  string `json:"dbPath"`
+	APIKey    string                            `json:"apiKey"`
+	BaseURL   string                            `json:"baseUrl"`
+	Model     string                            `json:"model"`
+	Port      int                               `json:"port"`
+	DBPath    string                            `json:"dbPath"`
+	Provider  string                            `json:"provider"`
+	Groups    map[string]pkgconfig.ConfigGroup  `json:"groups"`
+	Fallbacks []string                          `json:"fallbacks"`
+	Routes    map[string][]pkgconfig.ModelRoute `json:"routes"`
+	Prices    map[string]llm.ModelPrice         `json:"prices"`
+	Budgets   pkgconfig.BudgetConfig            `json:"budgets"`
+	Approvals pkgconfig.ApprovalConfig          `json:"approvals"`
+
+	ToolSelection pkgconfig.ToolSelectionConfig `json:"toolSelection"`
+
+	Profiles map[string]pkgconfig.AgentProfile `json:"profiles"`
+
+	Commands pkgconfig.CommandsConfig `json:"commands"`
+
+	MemoryConsolidation pkgconfig.MemoryConsolidationConfig `json:"memoryConsolidation"`
+	KnowledgeGraph      pkgconfig.KnowledgeGraphConfig      `json:"knowledgeGraph"`
+
+	ThinkingMode   string `json:"thinkingMode"`
+	ThinkingBudget int    `json:"thinkingBudget"`
+
+	MaxParallelTools int `json:"maxParallelTools"`
 }
 
 func main() {
@@ -116,12 +139,24 @@ func main() {
 	if hnswPath == "" {
 		hnswPath = filepath.Join(dbDir, "vector.index")
 	}
+	// HNSW_M, HNSW_EF_SEARCH, HNSW_EF_CONSTRUCT tune the index (0 = default)
+	hnswParam := func(key string) int {
+		v := envConfig[key]
+		if e := os.Getenv(key); e != "" {
+			v = e
+		}
+		n, _ := strconv.Atoi(v)
+		return n
+	}
 
 	memoryStore, err := memory.NewVectorMemoryStore(dbPath, memory.Config{
 		EmbeddingServer: embeddingServer,
 		EmbeddingModel:  embeddingModel,
 		ApiKey:          openaiKey,
 		HNSWPath:        hnswPath,
+		HNSWM:           hnswParam("HNSW_M"),
+		HNSWEfSearch:    hnswParam("HNSW_EF_SEARCH"),
+		HNSWEfConstruct: hnswParam("HNSW_EF_CONSTRUCT"),
 	})
 	if err != nil {
 		log.Printf("Vector memory init failed: %v", err)
@@ -185,12 +220,37 @@ func main() {
 				if c.APIKey != "" { cfg.APIKey = c.APIKey }
 				if c.BaseURL != "" { cfg.BaseURL = c.BaseURL }
 				if c.Model != "" { cfg.Model = c.Model }
+				if c.Provider != "" { cfg.Provider = c.Provider }
+				if len(c.Groups) > 0 { cfg.Groups = c.Groups }
+				if len(c.Fallbacks) > 0 { cfg.Fallbacks = c.Fallbacks }
+				if len(c.Routes) > 0 { cfg.Routes = c.Routes }
+				if len(c.Prices) > 0 { cfg.Prices = c.Prices }
+				if c.Budgets.Sessions != nil || c.Budgets.Channels != nil || c.Budgets.Cron != nil { cfg.Budgets = c.Budgets }
+				if len(c.Approvals.Tools) > 0 || len(c.Approvals.Patterns) > 0 { cfg.Approvals = c.Approvals }
+				if c.ToolSelection.Enabled { cfg.ToolSelection = c.ToolSelection }
+				if len(c.Profiles) > 0 { cfg.Profiles = c.Profiles }
+				if len(c.Commands.Admins) > 0 { cfg.Commands = c.Commands }
+				cfg.MemoryConsolidation = c.MemoryConsolidation
+				cfg.KnowledgeGraph = c.KnowledgeGraph
+				if c.ThinkingMode != "" { cfg.ThinkingMode = c.ThinkingMode }
+				if c.ThinkingBudget > 0 { cfg.ThinkingBudget = c.ThinkingBudget }
+				if c.MaxParallelTools > 0 { cfg.MaxParallelTools = c.MaxParallelTools }
 				if c.Port > 0 { os.Setenv("OCG_PORT", fmt.Sprintf("%d", c.Port)) }
 				log.Printf("Loaded config from config.json")
 			}
 		}
 	}
 
+	// 3.5 failover chain: comma-separated group names tried after the provider
+	if v := os.Getenv("OCG_FALLBACKS"); v != "" {
+		cfg.Fallbacks = nil
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				cfg.Fallbacks = append(cfg.Fallbacks, name)
+			}
+		}
+	}
+
 	autoRecall := strings.ToLower(os.Getenv("OCG_AUTO_RECALL"))
 	if autoRecall == "" {
 		autoRecall = strings.ToLower(envConfig["OCG_AUTO_RECALL"])
@@ -205,6 +265,12 @@ func main() {
 	} else {
 		registry = tools.NewDefaultRegistry()
 	}
+	if len(cfg.Approvals.Tools) > 0 || len(cfg.Approvals.Patterns) > 0 {
+		policy := tools.DefaultToolsPolicy()
+		policy.Ask = cfg.Approvals.Tools
+		policy.AskPatterns = cfg.Approvals.Patterns
+		registry.SetPolicy(policy)
+	}
 
 	recallLimit := 3
 	if v := os.Getenv("OCG_RECALL_LIMIT"); v != "" {
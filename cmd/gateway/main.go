@@ -141,11 +141,18 @@ func main() {
 		uiToken = newUIAuthToken
 	}
 
+	// Session-bound tokens for the memory API
+	memoryTokens := os.Getenv("OCG_MEMORY_TOKENS")
+	if memoryTokens == "" {
+		memoryTokens = envConfig["OCG_MEMORY_TOKENS"]
+	}
+
 	srv := gateway.New(config.GatewayConfig{
-		Host:        host,
-		Port:        p,
-		AgentAddr:   agentSock,
-		UIAuthToken: uiToken,
+		Host:         host,
+		Port:         p,
+		AgentAddr:    agentSock,
+		UIAuthToken:  uiToken,
+		MemoryTokens: config.ParseMemoryTokens(memoryTokens),
 	})
 	srv.SetClient(client)
 
@@ -72,6 +72,10 @@ func main() {
 		agentCmd(args)
 	case "llmhealth":
 		llmHealthCmd(args)
+	case "usage":
+		usageCmd(args)
+	case "memory":
+		memoryCmd(args)
 	case "hooks":
 		hooksCmd(args)
 	case "webhook":
@@ -650,6 +654,8 @@ func printUsage() {
 	fmt.Println("  task       Manage task execution history")
 	fmt.Println("  agent      Interactive chat with the agent")
 	fmt.Println("  llmhealth  LLM health check and failover management")
+	fmt.Println("  usage      LLM token usage and cost (by session, channel, model, ...)")
+	fmt.Println("  memory     Ingest documents into long-term memory")
 	fmt.Println("  hooks      Manage hooks (list, enable, disable, info, check)")
 	fmt.Println("  webhook    Manage webhooks (status, test, send, list)")
 	fmt.Println("")
@@ -664,14 +670,7 @@ func agentCmd(args []string) {
 	cfgPath, _ := resolveConfigPath("")
 	cfg := config.ReadEnvConfig(cfgPath)
 
-	// Get agent connection info (ENV → config → default)
-	agentSock := os.Getenv("OCG_AGENT_SOCK")
-	if agentSock == "" {
-		agentSock = cfg["OCG_AGENT_SOCK"]
-	}
-	if agentSock == "" {
-		agentSock = filepath.Join(os.TempDir(), "ocg-agent.sock")
-	}
+	agentSock := agentSocketPath(cfg)
 
 	// Also support HTTP via gateway
 	gatewayHost := cfg["OCG_HOST"]
@@ -766,6 +765,18 @@ func agentCmd(args []string) {
 	}
 }
 
+// agentSocketPath returns the agent's gRPC socket (ENV → config → default)
+func agentSocketPath(cfg map[string]string) string {
+	agentSock := os.Getenv("OCG_AGENT_SOCK")
+	if agentSock == "" {
+		agentSock = cfg["OCG_AGENT_SOCK"]
+	}
+	if agentSock == "" {
+		agentSock = filepath.Join(os.TempDir(), "ocg-agent.sock")
+	}
+	return agentSock
+}
+
 func sendViaSocket(client *grpc.ClientConn, agentSock, message string) (string, error) { //nolint:unused
 	// Call Chat via gRPC
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
@@ -1385,6 +1396,7 @@ func llmHealthCmd(args []string) {
 	fs := flag.NewFlagSet("llmhealth", flag.ExitOnError)
 	action := fs.String("action", "status", "Action: status, start, stop, failover, events, reset, test")
 	provider := fs.String("provider", "", "Provider type for failover/test")
+	limit := fs.Int("limit", 20, "Number of events to show (events)")
 	fs.Parse(args)
 
 	cfg := llmhealth.LoadConfigFromEnv()
@@ -1403,7 +1415,7 @@ func llmHealthCmd(args []string) {
 		}
 		manualFailover(*provider)
 	case "events":
-		printFailoverEvents()
+		printFailoverEvents(*limit)
 	case "reset":
 		// Reset health check state
 		fmt.Println("Resetting LLM health check state...")
@@ -1477,6 +1489,15 @@ func manualFailover(provider string) {
 	cfg.Enabled = true // Enable to allow operations
 	manager := llmhealth.NewManager(cfg)
 	
+	// Persist the event so it shows up in `ocg llmhealth --action events`
+	cfgPath, _ := resolveConfigPath("")
+	if store, err := openStorage(getDBPath(cfgPath)); err == nil {
+		defer store.Close()
+		manager.SetRecorder(func(e llmhealth.FailoverEvent) {
+			_, _ = store.AddFailoverEvent(string(e.FromProvider), string(e.ToProvider), e.Reason, e.Manual, e.Time)
+		})
+	}
+	
 	// Initialize status map
 	manager.SetPrimary(llm.ProviderOpenAI)
 	
@@ -1488,10 +1509,201 @@ func manualFailover(provider string) {
 	fmt.Printf("[OK] Manual failover to %s\n", provider)
 }
 
-func printFailoverEvents() {
+func printFailoverEvents(limit int) {
+	cfgPath, _ := resolveConfigPath("")
+	store, err := openStorage(getDBPath(cfgPath))
+	if err != nil {
+		fatalf("Failed to open storage: %v", err)
+	}
+	defer store.Close()
+
+	events, err := store.GetFailoverEvents(limit)
+	if err != nil {
+		fatalf("Failed to load failover events: %v", err)
+	}
+
 	fmt.Println("=== Recent Failover Events ===")
-	// In full implementation, would retrieve from storage or manager
-	fmt.Println("(No events recorded yet)")
+	if len(events) == 0 {
+		fmt.Println("(No events recorded yet)")
+		return
+	}
+	for _, e := range events {
+		kind := "auto"
+		if e.Manual {
+			kind = "manual"
+		}
+		fmt.Printf("%s  %s -> %s  [%s]  %s\n", e.CreatedAt.Local().Format("2006-01-02 15:04:05"), e.FromProvider, e.ToProvider, kind, e.Reason)
+	}
+}
+
+// ============ LLM Usage ============
+
+func usageCmd(args []string) {
+	fs := flag.NewFlagSet("usage", flag.ExitOnError)
+	by := fs.String("by", "session", "Group by: total, session, channel, provider, model, purpose, day")
+	since := fs.String("since", "", "Only count calls since a duration ago (24h, 168h) or an RFC3339 time")
+	session := fs.String("session", "", "List the individual calls of a session instead of totals")
+	limit := fs.Int("limit", 20, "Max rows to show")
+	fs.Parse(args)
+
+	var sinceTime time.Time
+	if *since != "" {
+		if d, err := time.ParseDuration(*since); err == nil {
+			sinceTime = time.Now().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, *since); err == nil {
+			sinceTime = t
+		} else {
+			fatalf("Invalid --since %q: use a duration (24h) or RFC3339 time", *since)
+		}
+	}
+
+	cfgPath, _ := resolveConfigPath("")
+	store, err := openStorage(getDBPath(cfgPath))
+	if err != nil {
+		fatalf("Failed to open storage: %v", err)
+	}
+	defer store.Close()
+
+	if *session != "" {
+		records, err := store.GetUsage(*session, *limit)
+		if err != nil {
+			fatalf("Failed to load usage: %v", err)
+		}
+		if len(records) == 0 {
+			fmt.Println("No usage recorded for this session")
+			return
+		}
+		fmt.Printf("%-19s %-14s %-28s %-13s %8s %8s %8s %10s\n", "TIME", "PROVIDER", "MODEL", "PURPOSE", "PROMPT", "COMPL", "CACHED", "COST")
+		fmt.Println(strings.Repeat("-", 115))
+		for _, r := range records {
+			mark := ""
+			if r.Estimated {
+				mark = " ~"
+			}
+			fmt.Printf("%-19s %-14s %-28s %-13s %8d %8d %8d %10.4f%s\n",
+				r.CreatedAt.Local().Format("2006-01-02 15:04:05"), r.Provider, r.Model, r.Purpose,
+				r.PromptTokens, r.CompletionTokens, r.CachedTokens, r.Cost, mark)
+		}
+		return
+	}
+
+	totals, err := store.UsageSummary(*by, sinceTime)
+	if err != nil {
+		fatalf("Failed to load usage: %v", err)
+	}
+	if len(totals) == 0 {
+		fmt.Println("No usage recorded")
+		return
+	}
+
+	fmt.Printf("%-32s %7s %10s %10s %10s %10s\n", strings.ToUpper(*by), "CALLS", "PROMPT", "COMPL", "CACHED", "COST($)")
+	fmt.Println(strings.Repeat("-", 84))
+	var sum storage.UsageTotal
+	for i, t := range totals {
+		sum.Calls += t.Calls
+		sum.PromptTokens += t.PromptTokens
+		sum.CompletionTokens += t.CompletionTokens
+		sum.CachedTokens += t.CachedTokens
+		sum.Cost += t.Cost
+		if i >= *limit {
+			continue
+		}
+		key := t.Key
+		if key == "" {
+			key = "(none)"
+		}
+		fmt.Printf("%-32s %7d %10d %10d %10d %10.4f\n", key, t.Calls, t.PromptTokens, t.CompletionTokens, t.CachedTokens, t.Cost)
+	}
+	if len(totals) > 1 {
+		fmt.Println(strings.Repeat("-", 84))
+		fmt.Printf("%-32s %7d %10d %10d %10d %10.4f\n", "TOTAL", sum.Calls, sum.PromptTokens, sum.CompletionTokens, sum.CachedTokens, sum.Cost)
+	}
+}
+
+// ============ Memory ============
+
+func memoryCmd(args []string) {
+	if len(args) < 1 {
+		memoryUsage()
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "ingest":
+		memoryIngest(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown memory command: %s\n", args[0])
+		memoryUsage()
+		os.Exit(1)
+	}
+}
+
+func memoryUsage() {
+	fmt.Println("Usage: ocg memory <command>")
+	fmt.Println("")
+	fmt.Println("Commands:")
+	fmt.Println("  ingest [--session key] [--agent id] [--force] <path|glob>...")
+	fmt.Println("         Read Markdown, text, source code, HTML and PDF files into memory;")
+	fmt.Println("         unchanged files are skipped, changed ones re-ingested, deleted ones removed")
+	fmt.Println("")
+	fmt.Println("Examples:")
+	fmt.Println("  ocg memory ingest ~/notes")
+	fmt.Println("  ocg memory ingest 'docs/**/*.md' README.md")
+	fmt.Println("  ocg memory ingest --session telegram_123 --force manual.pdf")
+}
+
+// memoryIngest asks the running agent to ingest documents. They are global
+// unless --session names the conversation that owns them
+func memoryIngest(args []string) {
+	fs := flag.NewFlagSet("memory ingest", flag.ExitOnError)
+	session := fs.String("session", "", "Session key that owns the documents (default: global)")
+	agentID := fs.String("agent", "", "Agent profile (default: from the session key)")
+	force := fs.Bool("force", false, "Re-chunk files whose content did not change")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		memoryUsage()
+		os.Exit(1)
+	}
+
+	// The agent resolves paths from its own working directory
+	paths := make([]string, 0, fs.NArg())
+	for _, p := range fs.Args() {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			fatalf("Invalid path %s: %v", p, err)
+		}
+		paths = append(paths, abs)
+	}
+
+	cfgPath, _ := resolveConfigPath("")
+	agentSock := agentSocketPath(config.ReadEnvConfig(cfgPath))
+	if _, err := os.Stat(agentSock); err != nil {
+		fatalf("Agent is not running (no socket at %s)", agentSock)
+	}
+	client, err := grpc.NewClient("unix://"+agentSock, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		fatalf("Failed to connect to agent: %v", err)
+	}
+	defer client.Close()
+
+	// No timeout: embedding a large tree takes a while
+	reply, err := rpcproto.NewAgentClient(client).MemoryIngest(context.Background(), &rpcproto.MemoryIngestArgs{
+		Paths:      paths,
+		SessionKey: *session,
+		AgentId:    *agentID,
+		Force:      *force,
+	})
+	if err != nil {
+		fatalf("Ingest failed: %v", err)
+	}
+	var result struct {
+		Result string `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(reply.Result), &result); err != nil || result.Result == "" {
+		fmt.Println(reply.Result)
+		return
+	}
+	fmt.Println(result.Result)
 }
 
 // hooksCmd handles hooks subcommands
@@ -4,8 +4,10 @@
 package cron
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -60,12 +62,13 @@ type Schedule struct {
 
 // Payload defines what the job should do
 type Payload struct {
-	Kind           string `json:"kind"`              // "systemEvent", "agentTurn"
-	Text           string `json:"text,omitempty"`    // for systemEvent
-	Message        string `json:"message,omitempty"` // for agentTurn
-	Model          string `json:"model,omitempty"`
-	Thinking       string `json:"thinking,omitempty"`
-	TimeoutSeconds int    `json:"timeoutSeconds,omitempty"`
+	Kind           string          `json:"kind"`              // "systemEvent", "agentTurn"
+	Text           string          `json:"text,omitempty"`    // for systemEvent
+	Message        string          `json:"message,omitempty"` // for agentTurn
+	Model          string          `json:"model,omitempty"`
+	Thinking       string          `json:"thinking,omitempty"`
+	TimeoutSeconds int             `json:"timeoutSeconds,omitempty"`
+	ResponseFormat json.RawMessage `json:"responseFormat,omitempty"` // agentTurn reply constraint, OpenAI response_format
 }
 
 // Delivery defines how to deliver job output
@@ -375,6 +378,9 @@ func (js *JobStore) Update(id string, updates map[string]interface{}) (*Job, err
 		if thinking, ok := v["thinking"].(string); ok {
 			job.Payload.Thinking = thinking
 		}
+		if rf, ok := v["responseFormat"]; ok {
+			job.Payload.ResponseFormat = responseFormatJSON(rf)
+		}
 	}
 
 	job.UpdatedAt = time.Now()
@@ -735,7 +741,7 @@ type CronHandler struct {
 	interval time.Duration
 	// Callbacks
 	onSystemEvent func(string)                                      // (message)
-	onAgentTurn   func(string, string, string) (string, error)    // (message, model, thinking)
+	onAgentTurn   func(context.Context, string, string, string, string, string) (string, error) // (ctx, jobID, message, model, thinking, responseFormat)
 	onBroadcast  func(string, string, string) error                // (message, channel, target)
 	onWebhook    func(string, string) error                        // (url, payload) - for webhook delivery
 	onWake       func() error                                       // trigger heartbeat for main session
@@ -757,8 +763,14 @@ func (c *CronHandler) SetSystemEventCallback(cb func(string)) {
 	c.onSystemEvent = cb
 }
 
-// SetAgentTurnCallback sets the callback for agent turns
-func (c *CronHandler) SetAgentTurnCallback(cb func(string, string, string) (string, error)) {
+// ErrResponseFormat is wrapped by the error of an agent turn whose reply did
+// not match the job's response format; such a reply is not delivered
+var ErrResponseFormat = errors.New("reply does not match response_format")
+
+// SetAgentTurnCallback sets the callback for agent turns. The context carries
+// the job's Payload.TimeoutSeconds deadline, if any; responseFormat is the
+// payload's ResponseFormat JSON, empty when the reply is free text.
+func (c *CronHandler) SetAgentTurnCallback(cb func(context.Context, string, string, string, string, string) (string, error)) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.onAgentTurn = cb
@@ -931,13 +943,18 @@ func (c *CronHandler) executeJob(job *Job) {
 		c.mu.RUnlock()
 
 		if cb != nil {
-			result, err = cb(job.Payload.Message, job.Payload.Model, job.Payload.Thinking)
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if job.Payload.TimeoutSeconds > 0 {
+				ctx, cancel = context.WithTimeout(ctx, time.Duration(job.Payload.TimeoutSeconds)*time.Second)
+			}
+			result, err = cb(ctx, job.ID, job.Payload.Message, job.Payload.Model, job.Payload.Thinking, string(job.Payload.ResponseFormat))
+			cancel()
 		} else {
 			err = fmt.Errorf("no callback configured")
 		}
 
-		// Handle delivery for agentTurn
-		if job.Delivery != nil && result != "" {
+		// Handle delivery for agentTurn; a reply that failed its response format isn't delivered
+		if job.Delivery != nil && result != "" && !errors.Is(err, ErrResponseFormat) {
 			switch job.Delivery.Mode {
 			case DeliveryModeAnnounce:
 				c.mu.RLock()
@@ -1124,6 +1141,25 @@ func generateJobID() string {
 	return fmt.Sprintf("job-%d-%x", time.Now().UnixMilli(), b)
 }
 
+// responseFormatJSON encodes a responseFormat given as an object or as a JSON string
+func responseFormatJSON(v interface{}) json.RawMessage {
+	switch x := v.(type) {
+	case nil:
+		return nil
+	case string:
+		if strings.TrimSpace(x) == "" {
+			return nil
+		}
+		return json.RawMessage(x)
+	default:
+		data, err := json.Marshal(x)
+		if err != nil {
+			return nil
+		}
+		return data
+	}
+}
+
 // CreateJobFromMap creates a Job from a map (for API calls)
 func CreateJobFromMap(data map[string]interface{}) (*Job, error) {
 	job := &Job{
@@ -1200,6 +1236,9 @@ func CreateJobFromMap(data map[string]interface{}) (*Job, error) {
 		if v, ok := payload["timeoutSeconds"].(float64); ok {
 			job.Payload.TimeoutSeconds = int(v)
 		}
+		if v, ok := payload["responseFormat"]; ok {
+			job.Payload.ResponseFormat = responseFormatJSON(v)
+		}
 	}
 
 	// Delivery
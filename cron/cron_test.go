@@ -1,6 +1,10 @@
 package cron
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -278,3 +282,31 @@ func TestParseCronSchedule(t *testing.T) {
 		}
 	}
 }
+
+func TestAgentTurnDeliverySkipsFormatErrors(t *testing.T) {
+	c := NewCronHandler(filepath.Join(t.TempDir(), "jobs.json"))
+	var delivered []string
+	c.SetBroadcastCallback(func(message, channel, target string) error {
+		delivered = append(delivered, message)
+		return nil
+	})
+	for _, tc := range []struct {
+		err     error
+		deliver bool
+	}{
+		{nil, true},
+		{fmt.Errorf("%w: n: expected integer", ErrResponseFormat), false},
+		{errors.New("agent busy"), true},
+	} {
+		c.SetAgentTurnCallback(func(context.Context, string, string, string, string, string) (string, error) {
+			return "reply", tc.err
+		})
+		delivered = nil
+		job := &Job{ID: "j", Name: "j", Payload: Payload{Kind: PayloadKindAgentTurn, Message: "go"},
+			Delivery: &Delivery{Mode: DeliveryModeAnnounce, Channel: "telegram", To: "1"}}
+		c.executeJob(job)
+		if got := len(delivered) == 1; got != tc.deliver {
+			t.Errorf("err %v: delivered = %v", tc.err, delivered)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package e2e_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gliderlab/cogate/agent"
+	"github.com/gliderlab/cogate/pkg/config"
+	"github.com/gliderlab/cogate/pkg/llm/providers/mock"
+	"github.com/gliderlab/cogate/storage"
+	"github.com/gliderlab/cogate/tools"
+)
+
+// echoTool is a deterministic tool for exercising the tool loop offline
+type echoTool struct{}
+
+func (echoTool) Name() string        { return "echo" }
+func (echoTool) Description() string { return "Echo the given text back" }
+func (echoTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}},
+		"required":   []string{"text"},
+	}
+}
+func (echoTool) Execute(args map[string]interface{}) (interface{}, error) {
+	return "echo:" + tools.GetString(args, "text"), nil
+}
+
+// setupMockAgent builds an agent whose LLM calls are answered by the tool_loop fixture
+func setupMockAgent(t *testing.T, cfg config.AgentConfig, inject bool) (*agent.Agent, *mock.Provider, *storage.Storage) {
+	t.Helper()
+	store, err := storage.New(t.TempDir() + "/test_storage.db")
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	script, err := mock.Load("testdata/tool_loop.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load fixture: %v", err)
+	}
+	p := mock.NewFromScript(script)
+
+	registry := tools.NewRegistry()
+	registry.Register(echoTool{})
+
+	di := agent.NewAgentDI().WithConfig(cfg).WithStorage(store).WithRegistry(registry)
+	if inject {
+		di = di.WithProvider(p)
+	}
+	return di.Build(), p, store
+}
+
+// TestE2E_MockToolLoop runs a full chat turn with a tool call round trip
+func TestE2E_MockToolLoop(t *testing.T) {
+	a, p, store := setupMockAgent(t, config.AgentConfig{Model: "mock-model"}, true)
+
+	reply := a.ChatWithSession("e2e-mock-tools", []agent.Message{{Role: "user", Content: "Echo something"}})
+	if !strings.Contains(reply, "replied pong") {
+		t.Fatalf("reply = %q", reply)
+	}
+	if p.Calls() != 2 {
+		t.Fatalf("expected 2 model calls (tool call + answer), got %d", p.Calls())
+	}
+
+	reqs := p.Requests()
+	if len(reqs[0].Tools) == 0 {
+		t.Error("first request carried no tool specs")
+	}
+	sawResult := false
+	for _, m := range reqs[1].Messages {
+		for _, r := range m.ToolResults() {
+			sawResult = sawResult || strings.Contains(r.Text, "echo:pong")
+		}
+	}
+	if !sawResult {
+		t.Error("second request did not carry the echo tool result")
+	}
+
+	msgs, err := store.GetMessages("e2e-mock-tools", 10)
+	if err != nil || len(msgs) == 0 {
+		t.Fatalf("session not stored: %v", err)
+	}
+}
+
+// TestE2E_MockStreaming checks streamed chunks reach the callback in order
+func TestE2E_MockStreaming(t *testing.T) {
+	a, _, _ := setupMockAgent(t, config.AgentConfig{Model: "mock-model"}, true)
+
+	var sb strings.Builder
+	a.ChatStreamWithSession("e2e-mock-stream", []agent.Message{{Role: "user", Content: "Stream please"}}, func(s string) {
+		sb.WriteString(s)
+	})
+	if !strings.Contains(sb.String(), "Streaming works offline.") {
+		t.Fatalf("streamed = %q", sb.String())
+	}
+}
+
+// TestE2E_MockHTTPStandIn drives the agent's default OpenAI-compatible client
+// against the in-process stand-in server
+func TestE2E_MockHTTPStandIn(t *testing.T) {
+	script, err := mock.Load("testdata/tool_loop.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load fixture: %v", err)
+	}
+	p := mock.NewFromScript(script)
+	srv := mock.NewServer(p)
+	defer srv.Close()
+
+	a, _, _ := setupMockAgent(t, config.AgentConfig{
+		Model:   "mock-model",
+		APIKey:  "test-key",
+		BaseURL: srv.URL,
+	}, false)
+
+	reply := a.ChatWithSession("e2e-mock-http", []agent.Message{{Role: "user", Content: "Echo something"}})
+	if !strings.Contains(reply, "replied pong") {
+		t.Fatalf("reply = %q", reply)
+	}
+	if p.Calls() != 2 {
+		t.Fatalf("expected 2 requests at the stand-in, got %d", p.Calls())
+	}
+}
+
+// TestE2E_MockProviderType configures the mock provider by type, as a config file would
+func TestE2E_MockProviderType(t *testing.T) {
+	a, _, _ := setupMockAgent(t, config.AgentConfig{
+		Provider: "mock",
+		BaseURL:  "testdata/tool_loop.yaml",
+	}, false)
+
+	reply := a.ChatWithSession("e2e-mock-type", []agent.Message{{Role: "user", Content: "Echo something"}})
+	if !strings.Contains(reply, "replied pong") {
+		t.Fatalf("reply = %q", reply)
+	}
+}
@@ -0,0 +1,100 @@
+// approvals.go - Relays tool calls waiting for human approval to the channel they came from
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gliderlab/cogate/gateway/channels"
+	"github.com/gliderlab/cogate/rpcproto"
+)
+
+// approvalRetryDelay is the pause before re-subscribing after the stream drops
+const approvalRetryDelay = 5 * time.Second
+
+// maxApprovalArgs bounds the arguments shown in an approval message
+const maxApprovalArgs = 500
+
+// watchApprovals follows the agent's approval stream until ctx is done,
+// re-subscribing whenever the stream drops
+func (g *Gateway) watchApprovals(ctx context.Context) {
+	for {
+		if err := g.relayApprovals(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("[APPROVAL] watch stream ended: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(approvalRetryDelay):
+		}
+	}
+}
+
+// relayApprovals delivers approval requests until the stream ends
+func (g *Gateway) relayApprovals(ctx context.Context) error {
+	client, err := g.clientOrError()
+	if err != nil {
+		return err
+	}
+	stream, err := rpcproto.NewAgentGRPCClient(client).WatchApprovals(ctx)
+	if err != nil {
+		return err
+	}
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		g.deliverApproval(req)
+	}
+}
+
+// deliverApproval sends an approval request, with Approve/Deny buttons, to the
+// chat whose session raised it. Sessions that don't belong to a channel (the
+// WebSocket UI, cron jobs) get their requests elsewhere.
+func (g *Gateway) deliverApproval(req *rpcproto.ApprovalRequest) {
+	chType, chatID, ok := approvalTarget(req.SessionKey)
+	if !ok || g.channelAdapter == nil || !g.channelAdapter.HasChannel(chType) {
+		return
+	}
+	if _, err := g.channelAdapter.SendMessage(chType, approvalMessage(req, chatID)); err != nil {
+		log.Printf("[APPROVAL] failed to deliver %s to %s: %v", req.Id, req.SessionKey, err)
+	}
+}
+
+// approvalTarget maps a channel session key ("telegram_123") to its channel and chat
+func approvalTarget(sessionKey string) (channels.ChannelType, int64, bool) {
+	name, id, ok := strings.Cut(sessionKey, "_")
+	if !ok {
+		return "", 0, false
+	}
+	chType := channelTypeFromString(name)
+	chatID, err := strconv.ParseInt(id, 10, 64)
+	if chType == "" || err != nil {
+		return "", 0, false
+	}
+	return chType, chatID, true
+}
+
+// approvalMessage renders a request. The buttons send the same /approve and
+// /deny commands the text tells users of button-less channels to type.
+func approvalMessage(req *rpcproto.ApprovalRequest, chatID int64) *channels.SendMessageRequest {
+	args := req.Arguments
+	if len(args) > maxApprovalArgs {
+		args = args[:maxApprovalArgs] + "..."
+	}
+	text := fmt.Sprintf("Approval needed to run `%s` (rule `%s`):\n```\n%s\n```\nReply /approve %s or /deny %s within %s.",
+		req.Tool, req.Reason, args, req.Id, req.Id, time.Until(time.Unix(req.ExpiresAt, 0)).Round(time.Second))
+	return &channels.SendMessageRequest{
+		ChatID: chatID,
+		Text:   text,
+		Buttons: [][]channels.Button{{
+			{Text: "Approve", CallbackData: "/approve " + req.Id},
+			{Text: "Deny", CallbackData: "/deny " + req.Id},
+		}},
+	}
+}
@@ -0,0 +1,48 @@
+package gateway
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gliderlab/cogate/gateway/channels"
+	"github.com/gliderlab/cogate/rpcproto"
+)
+
+func TestApprovalTarget(t *testing.T) {
+	cases := []struct {
+		key    string
+		chType channels.ChannelType
+		chatID int64
+		ok     bool
+	}{
+		{"telegram_42", channels.ChannelTelegram, 42, true},
+		{"telegram_-1001", channels.ChannelTelegram, -1001, true},
+		{"discord_7", channels.ChannelDiscord, 7, true},
+		{"default", "", 0, false},
+		{"cron:job1", "", 0, false},
+		{"nochannel_1", "", 0, false},
+		{"telegram_abc", "", 0, false},
+	}
+	for _, c := range cases {
+		chType, chatID, ok := approvalTarget(c.key)
+		if chType != c.chType || chatID != c.chatID || ok != c.ok {
+			t.Errorf("approvalTarget(%q) = %q, %d, %v", c.key, chType, chatID, ok)
+		}
+	}
+}
+
+func TestApprovalMessageButtons(t *testing.T) {
+	req := &rpcproto.ApprovalRequest{
+		Id: "approval-1", Tool: "exec", Arguments: `{"command":"rm -rf build"}`,
+		Reason: "exec", ExpiresAt: time.Now().Add(time.Minute).Unix(),
+	}
+	msg := approvalMessage(req, 42)
+	if msg.ChatID != 42 || !strings.Contains(msg.Text, "rm -rf build") || !strings.Contains(msg.Text, "/approve approval-1") {
+		t.Errorf("text = %q", msg.Text)
+	}
+	if len(msg.Buttons) != 1 || len(msg.Buttons[0]) != 2 ||
+		msg.Buttons[0][0].CallbackData != "/approve approval-1" || msg.Buttons[0][1].CallbackData != "/deny approval-1" {
+		t.Errorf("buttons = %+v", msg.Buttons)
+	}
+}
@@ -22,6 +22,7 @@ type Button = types.Button
 type ChannelMessage = types.ChannelMessage
 type ChannelMedia = types.ChannelMedia
 type Message = types.Message
+type BotCommand = types.BotCommand
 type AgentRPCInterface = types.AgentRPCInterface
 
 // Keep old constants for backward compatibility
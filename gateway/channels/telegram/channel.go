@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -90,6 +91,7 @@ func (b *TelegramBot) Start() error {
 	}
 	log.Printf("[START] Starting Telegram bot (mode: %s)...", b.mode)
 	b.running = true
+	go b.publishCommands()
 
 	if b.mode == "long_polling" {
 		// Delete webhook first to enable getUpdates
@@ -204,8 +206,8 @@ func (b *TelegramBot) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if update.Message.Text != "" {
-		go b.processMessage(update.Message)
+	if hasContent(update) {
+		go b.handleUpdate(update)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -236,11 +238,11 @@ func (b *TelegramBot) processMessage(msg TelegramMessage) {
 		text = strings.TrimSpace(msg.Caption)
 	}
 	hasVoice := msg.Voice.FileID != ""
-	if text == "" && !hasVoice {
+	if text == "" && !hasVoice && len(msg.Photo) == 0 {
 		return
 	}
 
-	log.Printf("📨 Received message from %s (@%s): text=%q voice=%v", msg.From.FirstName, username, text, hasVoice)
+	log.Printf("📨 Received message from %s (@%s): text=%q voice=%v photo=%v", msg.From.FirstName, username, text, hasVoice, len(msg.Photo) > 0)
 
 	// Handle commands first
 	if strings.HasPrefix(text, "/start") {
@@ -251,11 +253,6 @@ func (b *TelegramBot) processMessage(msg TelegramMessage) {
 		return
 	}
 
-	if strings.HasPrefix(text, "/help") {
-		b.sendSimpleMessage(chatID, "Commands:\n/start - Start bot\n/help - Help")
-		return
-	}
-
 	// Generate session key from chat ID (unique per user/chat)
 	sessionKey := fmt.Sprintf("telegram_%d", chatID)
 
@@ -287,6 +284,15 @@ func (b *TelegramBot) processMessage(msg TelegramMessage) {
 		{Role: "system", Content: fmt.Sprintf("You are an AI assistant. User @%s (ID: %d) sent a message in Telegram chat %d.", username, userID, chatID)},
 		{Role: "user", Content: userContent},
 	}
+	if len(msg.Photo) > 0 {
+		part, err := b.downloadTelegramPhoto(msg.Photo)
+		if err != nil {
+			log.Printf("[Telegram] photo download failed: %v", err)
+			b.sendSimpleMessage(chatID, "Sorry, I couldn't download that photo.")
+			return
+		}
+		messages[1].Parts = []types.MessagePart{part}
+	}
 
 	// Use ChatWithSession to load conversation history
 	response, err := b.agentRPC.ChatWithSession(sessionKey, messages)
@@ -333,7 +339,7 @@ func (b *TelegramBot) transcribeTelegramVoice(fileID string) (string, error) {
 		return "", err
 	}
 
-	resp, err := b.client.Get(fmt.Sprintf("https://api.telegram.org/file/bot%s/%s", b.token, filePath))
+	resp, err := b.client.Get(b.fileURL(filePath))
 	if err != nil {
 		return "", err
 	}
@@ -373,7 +379,7 @@ func (b *TelegramBot) downloadTelegramVoiceAsPCM(fileID string) (string, error)
 		return "", err
 	}
 
-	resp, err := b.client.Get(fmt.Sprintf("https://api.telegram.org/file/bot%s/%s", b.token, filePath))
+	resp, err := b.client.Get(b.fileURL(filePath))
 	if err != nil {
 		return "", err
 	}
@@ -401,6 +407,53 @@ func (b *TelegramBot) downloadTelegramVoiceAsPCM(fileID string) (string, error)
 	return pcmPath, nil
 }
 
+// maxPhotoBytes bounds a downloaded photo so it fits in an agent request
+const maxPhotoBytes = 3 << 20
+
+// downloadTelegramPhoto fetches the largest size of a photo that fits in
+// maxPhotoBytes as an image part
+func (b *TelegramBot) downloadTelegramPhoto(sizes []TelegramPhotoSize) (types.MessagePart, error) {
+	best := -1
+	for i, p := range sizes {
+		if p.FileSize > maxPhotoBytes {
+			continue
+		}
+		if best < 0 || p.Width*p.Height > sizes[best].Width*sizes[best].Height {
+			best = i
+		}
+	}
+	if best < 0 {
+		return types.MessagePart{}, fmt.Errorf("photo is larger than %d bytes", maxPhotoBytes)
+	}
+
+	filePath, err := b.getTelegramFilePath(sizes[best].FileID)
+	if err != nil {
+		return types.MessagePart{}, err
+	}
+	resp, err := b.client.Get(b.fileURL(filePath))
+	if err != nil {
+		return types.MessagePart{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return types.MessagePart{}, fmt.Errorf("download photo failed: status=%d body=%s", resp.StatusCode, string(body))
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPhotoBytes+1))
+	if err != nil {
+		return types.MessagePart{}, err
+	}
+	if len(data) > maxPhotoBytes {
+		return types.MessagePart{}, fmt.Errorf("photo is larger than %d bytes", maxPhotoBytes)
+	}
+	return types.MessagePart{Type: "image", MimeType: http.DetectContentType(data), Data: data}, nil
+}
+
+// fileURL returns the download URL of a file path from getFile
+func (b *TelegramBot) fileURL(filePath string) string {
+	return strings.Replace(b.baseURL, "/bot", "/file/bot", 1) + "/" + filePath
+}
+
 func (b *TelegramBot) getTelegramFilePath(fileID string) (string, error) {
 	resp, err := b.client.Get(fmt.Sprintf("%s/getFile?file_id=%s", b.baseURL, fileID))
 	if err != nil {
@@ -426,21 +479,76 @@ func (b *TelegramBot) getTelegramFilePath(fileID string) (string, error) {
 	return result.Result.FilePath, nil
 }
 
+// hasContent reports whether an update carries a text message, a photo or a button press
+func hasContent(update TelegramUpdate) bool {
+	return update.Message.Text != "" || len(update.Message.Photo) > 0 || (update.CallbackQuery != nil && update.CallbackQuery.Data != "")
+}
+
+// handleUpdate processes a message, or a button press as if the user had sent
+// the button's callback data (e.g. "/approve <id>") in that chat
+func (b *TelegramBot) handleUpdate(update TelegramUpdate) {
+	cq := update.CallbackQuery
+	if cq == nil {
+		b.processMessage(update.Message)
+		return
+	}
+	b.answerCallbackQuery(cq.ID)
+	if cq.Message == nil {
+		return
+	}
+	b.processMessage(TelegramMessage{
+		MessageID: cq.Message.MessageID,
+		From:      cq.From,
+		Chat:      cq.Message.Chat,
+		Date:      cq.Message.Date,
+		Text:      cq.Data,
+	})
+}
+
+// answerCallbackQuery stops the client's loading indicator on the pressed button
+func (b *TelegramBot) answerCallbackQuery(id string) {
+	payload, _ := json.Marshal(map[string]interface{}{"callback_query_id": id})
+	resp, err := b.client.Post(b.baseURL+"/answerCallbackQuery", "application/json", strings.NewReader(string(payload)))
+	if err != nil {
+		log.Printf("[Telegram] channel=telegram action=answerCallbackQuery error=%v", err)
+		return
+	}
+	resp.Body.Close()
+}
+
 // Telegram types
 type TelegramUpdate struct {
-	UpdateID int             `json:"update_id"`
-	Message  TelegramMessage `json:"message"`
+	UpdateID      int                    `json:"update_id"`
+	Message       TelegramMessage        `json:"message"`
+	CallbackQuery *TelegramCallbackQuery `json:"callback_query,omitempty"`
+}
+
+// TelegramCallbackQuery is a press of an inline keyboard button
+type TelegramCallbackQuery struct {
+	ID      string           `json:"id"`
+	From    TelegramUser     `json:"from"`
+	Message *TelegramMessage `json:"message,omitempty"`
+	Data    string           `json:"data"`
 }
 
 type TelegramMessage struct {
-	MessageID int           `json:"message_id"`
-	From      TelegramUser  `json:"from"`
-	Chat      TelegramChat  `json:"chat"`
-	Date      int           `json:"date"`
-	Text      string        `json:"text"`
-	Caption   string        `json:"caption,omitempty"`
-	Voice     TelegramVoice `json:"voice,omitempty"`
-	ThreadID  int           `json:"message_thread_id,omitempty"`
+	MessageID int                 `json:"message_id"`
+	From      TelegramUser        `json:"from"`
+	Chat      TelegramChat        `json:"chat"`
+	Date      int                 `json:"date"`
+	Text      string              `json:"text"`
+	Caption   string              `json:"caption,omitempty"`
+	Voice     TelegramVoice       `json:"voice,omitempty"`
+	Photo     []TelegramPhotoSize `json:"photo,omitempty"` // Sizes of one photo, smallest first
+	ThreadID  int                 `json:"message_thread_id,omitempty"`
+}
+
+// TelegramPhotoSize is one resolution of a sent photo
+type TelegramPhotoSize struct {
+	FileID   string `json:"file_id"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	FileSize int    `json:"file_size,omitempty"`
 }
 
 type TelegramVoice struct {
@@ -490,6 +598,59 @@ func (b *TelegramBot) deleteWebhook() {
 	}
 }
 
+// botCommandName is the form Telegram accepts for menu commands
+var botCommandName = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// publishCommands sets the bot's command menu (setMyCommands) to the agent's
+// slash commands, when the agent RPC can list them
+func (b *TelegramBot) publishCommands() {
+	lister, ok := b.agentRPC.(types.CommandLister)
+	if !ok {
+		return
+	}
+	list, err := lister.BotCommands()
+	if err != nil {
+		log.Printf("[Telegram] channel=telegram action=setMyCommands error=%v", err)
+		return
+	}
+	menu := []types.BotCommand{{Command: "start", Description: "Start bot"}}
+	for _, c := range list {
+		if !botCommandName.MatchString(c.Command) || c.Command == "start" {
+			continue
+		}
+		if c.Description == "" {
+			c.Description = "/" + c.Command
+		}
+		if len(c.Description) > 256 {
+			c.Description = c.Description[:253] + "..."
+		}
+		menu = append(menu, c)
+		if len(menu) == 100 {
+			break
+		}
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{"commands": menu})
+	resp, err := b.client.Post(b.baseURL+"/setMyCommands", "application/json", strings.NewReader(string(payload)))
+	if err != nil {
+		log.Printf("[Telegram] channel=telegram action=setMyCommands error=%v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+
+	if result.OK {
+		log.Printf("[Telegram] channel=telegram action=setMyCommands status=success count=%d", len(menu))
+	} else {
+		log.Printf("[Telegram] channel=telegram action=setMyCommands status=failed reason=%s", result.Description)
+	}
+}
+
 // startLongPolling starts the Long Polling loop with worker pool
 func (b *TelegramBot) startLongPolling() {
 	log.Printf("[RELOAD] Starting Long Polling loop with %d workers...", b.workerCnt)
@@ -519,8 +680,8 @@ func (b *TelegramBot) startLongPolling() {
 func (b *TelegramBot) messageWorker(id int) {
 	defer b.wg.Done()
 	for update := range b.msgCh {
-		if update.Message.Text != "" {
-			b.processMessage(update.Message)
+		if hasContent(update) {
+			b.handleUpdate(update)
 		}
 	}
 }
@@ -566,7 +727,7 @@ func (b *TelegramBot) pollUpdates() {
 		}
 		b.muOffset.Unlock()
 
-		if update.Message.Text != "" {
+		if hasContent(update) {
 			select {
 			case b.msgCh <- update:
 				// Sent to worker pool
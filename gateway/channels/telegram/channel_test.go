@@ -1,9 +1,16 @@
 package telegram
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/gliderlab/cogate/gateway/channels/types"
 )
 
 // mockAgentRPC implements a minimal mock for testing
@@ -132,3 +139,144 @@ func TestTelegramOffsetSync(t *testing.T) {
 		t.Errorf("expected offset 99, got %d", bot.offset)
 	}
 }
+
+// sessionRPC records the session and last message of each agent call
+type sessionRPC struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (m *sessionRPC) Chat(messages []types.Message) (string, error) { return "", nil }
+func (m *sessionRPC) ChatWithSession(sessionKey string, messages []types.Message) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, sessionKey+" "+messages[len(messages)-1].Content)
+	return "Approved.", nil
+}
+func (m *sessionRPC) GetStats() (map[string]int, error) { return nil, nil }
+
+func TestTelegramCallbackQuery(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		methods []string
+	)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, path.Base(r.URL.Path))
+		mu.Unlock()
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	defer api.Close()
+
+	rpc := &sessionRPC{}
+	bot := NewTelegramBot("test-token", rpc)
+	bot.baseURL = api.URL
+
+	update := `{"update_id":7,"callback_query":{"id":"cb1","from":{"id":5,"first_name":"A"},` +
+		`"message":{"message_id":9,"chat":{"id":42}},"data":"/approve approval-1"}}`
+	var parsed TelegramUpdate
+	if err := json.Unmarshal([]byte(update), &parsed); err != nil || !hasContent(parsed) {
+		t.Fatalf("callback update not recognised: %v", err)
+	}
+	bot.handleUpdate(parsed)
+
+	if len(rpc.calls) != 1 || rpc.calls[0] != "telegram_42 /approve approval-1" {
+		t.Errorf("agent calls = %v", rpc.calls)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(methods) == 0 || methods[0] != "answerCallbackQuery" {
+		t.Errorf("API calls = %v, want answerCallbackQuery first", methods)
+	}
+}
+
+// photoRPC records the user message of each agent call
+type photoRPC struct {
+	sessionRPC
+	user types.Message
+}
+
+func (m *photoRPC) ChatWithSession(sessionKey string, messages []types.Message) (string, error) {
+	m.user = messages[len(messages)-1]
+	return "A cat.", nil
+}
+
+func TestTelegramPhotoReachesAgent(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n large photo")
+	var fetched []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case path.Base(r.URL.Path) == "getFile":
+			fetched = append(fetched, r.URL.Query().Get("file_id"))
+			fmt.Fprint(w, `{"ok":true,"result":{"file_path":"photos/big.png"}}`)
+		case r.URL.Path == "/file/bottest-token/photos/big.png":
+			w.Write(png)
+		default:
+			fmt.Fprint(w, `{"ok":true}`)
+		}
+	}))
+	defer api.Close()
+
+	rpc := &photoRPC{}
+	bot := NewTelegramBot("test-token", rpc)
+	bot.baseURL = api.URL + "/bottest-token"
+
+	update := `{"update_id":8,"message":{"message_id":3,"from":{"id":5},"chat":{"id":42},"caption":"What is this?",` +
+		`"photo":[{"file_id":"small","width":90,"height":60},{"file_id":"big","width":1280,"height":853},` +
+		`{"file_id":"huge","width":2560,"height":1706,"file_size":9000000}]}}`
+	var parsed TelegramUpdate
+	if err := json.Unmarshal([]byte(update), &parsed); err != nil || !hasContent(parsed) {
+		t.Fatalf("photo update not recognised: %v", err)
+	}
+	bot.handleUpdate(parsed)
+
+	// The largest size that fits is sent as an image part with the caption
+	if len(fetched) != 1 || fetched[0] != "big" {
+		t.Errorf("downloaded sizes = %v", fetched)
+	}
+	if rpc.user.Content != "What is this?" || len(rpc.user.Parts) != 1 {
+		t.Fatalf("user message = %+v", rpc.user)
+	}
+	if p := rpc.user.Parts[0]; p.Type != "image" || p.MimeType != "image/png" || string(p.Data) != string(png) {
+		t.Errorf("image part = %+v", p)
+	}
+}
+
+// menuRPC lists a fixed set of slash commands
+type menuRPC struct{ sessionRPC }
+
+func (m *menuRPC) BotCommands() ([]types.BotCommand, error) {
+	return []types.BotCommand{
+		{Command: "help", Description: "List commands"},
+		{Command: "task", Description: "Task commands: detail, list"},
+		{Command: "live-audio-file", Description: "not a valid menu name"},
+		{Command: "new"},
+	}, nil
+}
+
+func TestTelegramPublishCommands(t *testing.T) {
+	var body struct {
+		Commands []types.BotCommand `json:"commands"`
+	}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if path.Base(r.URL.Path) == "setMyCommands" {
+			json.NewDecoder(r.Body).Decode(&body)
+		}
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	defer api.Close()
+
+	bot := NewTelegramBot("test-token", &menuRPC{})
+	bot.baseURL = api.URL
+	bot.publishCommands()
+
+	want := []types.BotCommand{
+		{Command: "start", Description: "Start bot"},
+		{Command: "help", Description: "List commands"},
+		{Command: "task", Description: "Task commands: detail, list"},
+		{Command: "new", Description: "/new"},
+	}
+	if fmt.Sprint(body.Commands) != fmt.Sprint(want) {
+		t.Errorf("setMyCommands = %v, want %v", body.Commands, want)
+	}
+}
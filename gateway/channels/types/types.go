@@ -111,8 +111,16 @@ type ChannelMedia struct {
 
 // Message represents a chat message
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string        `json:"role"`
+	Content string        `json:"content"`
+	Parts   []MessagePart `json:"parts,omitempty"` // Images or audio for multimodal models
+}
+
+// MessagePart is an inline attachment of a chat message
+type MessagePart struct {
+	Type     string `json:"type"` // "image" or "audio"
+	MimeType string `json:"mimeType"`
+	Data     []byte `json:"data"`
 }
 
 // AgentRPCInterface defines the interface for agent communication
@@ -121,3 +129,15 @@ type AgentRPCInterface interface {
 	ChatWithSession(sessionKey string, messages []Message) (string, error) // New: with session context
 	GetStats() (map[string]int, error)
 }
+
+// BotCommand is one entry of a channel's command menu
+type BotCommand struct {
+	Command     string `json:"command"` // Without the slash
+	Description string `json:"description"`
+}
+
+// CommandLister is implemented by agent RPCs that can list the agent's slash
+// commands; channels with command menus publish them at start
+type CommandLister interface {
+	BotCommands() ([]BotCommand, error)
+}
@@ -25,9 +25,12 @@ import (
 
 	"github.com/pkoukk/tiktoken-go"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/gliderlab/cogate/cron"
 	"github.com/gliderlab/cogate/gateway/channels"
+	"github.com/gliderlab/cogate/pkg/commands"
 	"github.com/gliderlab/cogate/pkg/config"
 	"github.com/gliderlab/cogate/pkg/hooks"
 	"github.com/gliderlab/cogate/pkg/hooks/bundled"
@@ -98,6 +101,9 @@ type Gateway struct {
 	configFile    string
 	configWatcher interface{ Close() error }
 	reloadCh      chan struct{}
+
+	// Approval relay (agent -> channels)
+	stopApprovals context.CancelFunc
 }
 
 // HTTPClient interface for dependency injection
@@ -393,6 +399,55 @@ func (g *Gateway) requireAuth(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// memoryTokenSession returns the session a memory token from the request is
+// bound to (see GatewayConfig.MemoryTokens)
+func (g *Gateway) memoryTokenSession(r *http.Request) (string, bool) {
+	candidates := []string{
+		strings.TrimSpace(r.Header.Get("X-OCG-UI-Token")),
+		strings.TrimSpace(r.URL.Query().Get("token")),
+	}
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	if len(header) >= 7 && strings.EqualFold(header[:7], "Bearer ") {
+		candidates = append(candidates, strings.TrimSpace(header[7:]))
+	}
+	for token, session := range g.cfg.MemoryTokens {
+		for _, candidate := range candidates {
+			if candidate != "" && len(candidate) == len(token) && subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+				return session, true
+			}
+		}
+	}
+	return "", false
+}
+
+// requireMemoryAuth admits the UI token and session-bound memory tokens
+func (g *Gateway) requireMemoryAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := g.memoryTokenSession(r); ok {
+			next(w, r)
+			return
+		}
+		g.requireAuth(next)(w, r)
+	}
+}
+
+// memorySessionKey resolves the session a memory request acts for. The UI
+// token is the operator's and may name any session, or none to reach every
+// memory; a memory token always acts for the session it is bound to
+func (g *Gateway) memorySessionKey(r *http.Request, requested string) (string, error) {
+	if g.validateToken(r) {
+		return requested, nil
+	}
+	bound, ok := g.memoryTokenSession(r)
+	if !ok {
+		return "", fmt.Errorf("unauthorized")
+	}
+	if requested != "" && requested != bound {
+		return "", fmt.Errorf("token is not valid for session %q", requested)
+	}
+	return bound, nil
+}
+
 func (g *Gateway) rateLimit(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if g.store == nil {
@@ -503,15 +558,17 @@ func (g *Gateway) Start() error {
 	// API routes (protected)
 	mux.HandleFunc("/v1/chat/completions", rateLimit(requireAuth(g.handleChat)))
 	mux.HandleFunc("/storage/stats", requireAuth(g.handleStorageStats))
+	mux.HandleFunc("/usage", requireAuth(g.handleUsage))
 	mux.HandleFunc("/sessions/list", requireAuth(g.handleSessions))
+	mux.HandleFunc("/sessions/fork", requireAuth(g.handleSessionFork))
 	mux.HandleFunc("/process/start", requireAuth(g.handleProcessStart))
 	mux.HandleFunc("/process/list", requireAuth(g.handleProcessList))
 	mux.HandleFunc("/process/log", requireAuth(g.handleProcessLog))
 	mux.HandleFunc("/process/write", requireAuth(g.handleProcessWrite))
 	mux.HandleFunc("/process/kill", requireAuth(g.handleProcessKill))
-	mux.HandleFunc("/memory/search", requireAuth(g.handleMemorySearch))
-	mux.HandleFunc("/memory/get", requireAuth(g.handleMemoryGet))
-	mux.HandleFunc("/memory/store", requireAuth(g.handleMemoryStore))
+	mux.HandleFunc("/memory/search", g.requireMemoryAuth(g.handleMemorySearch))
+	mux.HandleFunc("/memory/get", g.requireMemoryAuth(g.handleMemoryGet))
+	mux.HandleFunc("/memory/store", g.requireMemoryAuth(g.handleMemoryStore))
 
 	// Cron endpoints
 	mux.HandleFunc("/cron/status", requireAuth(g.handleCronStatus))
@@ -602,11 +659,17 @@ func (g *Gateway) Start() error {
 			log.Printf("[Cron] system event error: %v", err)
 		}
 	})
-	g.cronHandler.SetAgentTurnCallback(func(message, model, thinking string) (string, error) {
+	g.cronHandler.SetAgentTurnCallback(func(ctx context.Context, jobID, message, model, thinking, responseFormat string) (string, error) {
 		if g.client == nil {
 			return "", fmt.Errorf("agent not connected")
 		}
-		return (&GatewayAgentRPC{client: g.client}).Chat([]channels.Message{{Role: "user", Content: message}})
+		// Each job runs in its own cron:<jobID> session so its budget and usage apply to it
+		// The job's timeout cancels the RPC, which stops the turn and its tools
+		rpc := &GatewayAgentRPC{client: g.client}
+		if job, ok := g.cronHandler.GetJob(jobID); ok {
+			rpc.agentID = job.AgentID
+		}
+		return rpc.ChatStructuredContext(ctx, "cron:"+jobID, responseFormat, []channels.Message{{Role: "user", Content: message}})
 	})
 	g.cronHandler.SetBroadcastCallback(func(message, channel, target string) error {
 		if g.channelAdapter == nil {
@@ -660,7 +723,7 @@ func (g *Gateway) Start() error {
 	}
 	if telegramToken != "" {
 		if g.client != nil {
-			bot := channels.NewTelegramBot(telegramToken, &GatewayAgentRPC{client: g.client})
+			bot := channels.NewTelegramBot(telegramToken, g.channelAgentRPC("telegram"))
 			if err := g.channelAdapter.RegisterChannel(bot); err != nil {
 				log.Printf("[WARN] Failed to register Telegram channel: %v", err)
 			} else {
@@ -676,6 +739,13 @@ func (g *Gateway) Start() error {
 		log.Printf("ℹ️ No TELEGRAM_BOT_TOKEN environment variable found")
 	}
 
+	// Relay tool calls waiting for approval to the chats that made them
+	if g.client != nil {
+		approvalCtx, cancel := context.WithCancel(context.Background())
+		g.stopApprovals = cancel
+		go g.watchApprovals(approvalCtx)
+	}
+
 	// Start config file watcher for hot reload
 	g.StartConfigWatcher()
 
@@ -690,6 +760,9 @@ func (g *Gateway) Stop() {
 	if g.cronHandler != nil {
 		g.cronHandler.Stop()
 	}
+	if g.stopApprovals != nil {
+		g.stopApprovals()
+	}
 	if g.server != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 		defer cancel()
@@ -878,6 +951,12 @@ func (g *Gateway) handleChat(w http.ResponseWriter, r *http.Request) {
 
 	grpcClient := rpcproto.NewAgentGRPCClient(client)
 
+	// Structured replies are validated (and retried) whole, so they can't stream
+	if req.Stream && len(req.ResponseFormat) > 0 && string(req.ResponseFormat) != "null" {
+		http.Error(w, "response_format is not supported with stream", http.StatusBadRequest)
+		return
+	}
+
 	// Handle streaming request
 	if req.Stream {
 		ctx := r.Context()
@@ -886,7 +965,10 @@ func (g *Gateway) handleChat(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Connection", "keep-alive")
 		w.Header().Set("X-Accel-Buffering", "no")
 
-		stream, err := grpcClient.ChatStream(ctx, &rpcproto.ChatArgs{Messages: rpcproto.ToMessagesPtr(req.Messages)})
+		stream, err := grpcClient.ChatStream(ctx, &rpcproto.ChatArgs{
+			Messages: rpcproto.ToMessagesPtr(req.Messages),
+			AgentId:  modelAgentID(req.Model),
+		})
 		if err != nil {
 			fmt.Fprintf(w, "data: %s\n\n", fmt.Errorf("stream error: %v", err))
 			return
@@ -945,11 +1027,34 @@ func (g *Gateway) handleChat(w http.ResponseWriter, r *http.Request) {
 	// Non-streaming request (original behavior)
 	ctx, cancel := context.WithTimeout(r.Context(), rpcproto.DefaultGRPCTimeout())
 	defer cancel()
-	reply, err := grpcClient.Chat(ctx, &rpcproto.ChatArgs{Messages: rpcproto.ToMessagesPtr(req.Messages)})
+	reply, err := grpcClient.Chat(ctx, &rpcproto.ChatArgs{
+		Messages:       rpcproto.ToMessagesPtr(req.Messages),
+		ResponseFormat: string(req.ResponseFormat),
+		AgentId:        modelAgentID(req.Model),
+	})
+	if status.Code(err) == codes.InvalidArgument {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]interface{}{"error": map[string]string{
+			"type":    "invalid_response_format",
+			"message": status.Convert(err).Message(),
+		}})
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if reply.FormatError != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		writeJSON(w, map[string]interface{}{"error": map[string]string{
+			"type":    "invalid_response_format",
+			"message": reply.FormatError,
+			"content": reply.Content,
+		}})
+		return
+	}
 
 	promptTokens := countTokens(body)
 	completionTokens := countTokens([]byte(reply.Content))
@@ -966,6 +1071,7 @@ func (g *Gateway) handleChat(w http.ResponseWriter, r *http.Request) {
 					Content: reply.Content,
 				},
 				FinishReason: "stop",
+				Parsed:       json.RawMessage(reply.Object),
 			},
 		},
 		Usage: Usage{
@@ -1057,6 +1163,73 @@ func (g *Gateway) handleStorageStats(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, StatsResponse{Status: "ok", Stats: stats})
 }
 
+// handleUsage reports LLM token usage and cost.
+// Query: by=total|session|channel|provider|model|purpose|day (default: session),
+// since=<duration, e.g. 24h> or <RFC3339 time> (default: all time)
+func (g *Gateway) handleUsage(w http.ResponseWriter, r *http.Request) {
+	groupBy := r.URL.Query().Get("by")
+	if groupBy == "" {
+		groupBy = "session"
+	}
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			since = time.Now().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, v); err == nil {
+			since = t
+		} else {
+			http.Error(w, "invalid since: use a duration (24h) or RFC3339 time", http.StatusBadRequest)
+			return
+		}
+	}
+
+	client, err := g.clientOrError()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	grpcClient := rpcproto.NewAgentGRPCClient(client)
+	ctx, cancel := context.WithTimeout(r.Context(), rpcproto.DefaultGRPCTimeout())
+	defer cancel()
+	totals, err := grpcClient.Usage(ctx, groupBy, since)
+	if err != nil {
+		http.Error(w, "error getting usage: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	type UsageRow struct {
+		Key              string  `json:"key"`
+		Calls            int64   `json:"calls"`
+		PromptTokens     int64   `json:"prompt_tokens"`
+		CompletionTokens int64   `json:"completion_tokens"`
+		CachedTokens     int64   `json:"cached_tokens"`
+		Cost             float64 `json:"cost"`
+	}
+	type UsageResponse struct {
+		Status string     `json:"status"`
+		By     string     `json:"by"`
+		Since  string     `json:"since,omitempty"`
+		Usage  []UsageRow `json:"usage"`
+	}
+
+	resp := UsageResponse{Status: "ok", By: groupBy, Usage: []UsageRow{}}
+	if !since.IsZero() {
+		resp.Since = since.UTC().Format(time.RFC3339)
+	}
+	for _, t := range totals {
+		resp.Usage = append(resp.Usage, UsageRow{
+			Key:              t.Key,
+			Calls:            t.Calls,
+			PromptTokens:     t.PromptTokens,
+			CompletionTokens: t.CompletionTokens,
+			CachedTokens:     t.CachedTokens,
+			Cost:             t.Cost,
+		})
+	}
+	writeJSON(w, resp)
+}
+
 func (g *Gateway) handleSessions(w http.ResponseWriter, r *http.Request) {
 	client, err := g.clientOrError()
 	if err != nil {
@@ -1080,17 +1253,73 @@ func (g *Gateway) handleSessions(w http.ResponseWriter, r *http.Request) {
 
 	sessions := make([]map[string]interface{}, 0, len(reply.Sessions))
 	for _, s := range reply.Sessions {
-		sessions = append(sessions, map[string]interface{}{
-			"session_key":      s.SessionKey,
-			"total_tokens":     s.TotalTokens,
-			"compaction_count": s.CompactionCount,
-			"updated_at":       s.UpdatedAt,
-		})
+		sessions = append(sessions, sessionJSON(s))
 	}
 
 	writeJSON(w, SessionResponse{Sessions: sessions, Count: reply.Count})
 }
 
+// handleSessionFork handles POST /sessions/fork: copies a session up to a
+// message into a new session that records it as its parent
+func (g *Gateway) handleSessionFork(w http.ResponseWriter, r *http.Request) {
+	client, err := g.clientOrError()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, g.cfg.MaxBodyMemory)
+	var req struct {
+		SessionKey    string `json:"sessionKey"`
+		MessageID     int64  `json:"messageId,omitempty"`     // Default: the latest message
+		NewSessionKey string `json:"newSessionKey,omitempty"` // Default: generated
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Parse error: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.SessionKey == "" {
+		http.Error(w, "sessionKey is required", http.StatusBadRequest)
+		return
+	}
+
+	grpcClient := rpcproto.NewAgentGRPCClient(client)
+	ctx, cancel := context.WithTimeout(r.Context(), rpcproto.DefaultGRPCTimeout())
+	defer cancel()
+	info, err := grpcClient.ForkSession(ctx, &rpcproto.ForkSessionArgs{
+		SessionKey:    req.SessionKey,
+		NewSessionKey: req.NewSessionKey,
+		MessageId:     req.MessageID,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, sessionJSON(info))
+}
+
+func sessionJSON(s *rpcproto.SessionInfo) map[string]interface{} {
+	out := map[string]interface{}{
+		"session_key":      s.SessionKey,
+		"total_tokens":     s.TotalTokens,
+		"compaction_count": s.CompactionCount,
+		"updated_at":       s.UpdatedAt,
+	}
+	if s.ParentSessionKey != "" {
+		out["parent_session_key"] = s.ParentSessionKey
+		out["fork_message_id"] = s.ForkMessageId
+	}
+	if len(s.Branches) > 0 {
+		out["branches"] = s.Branches
+	}
+	return out
+}
+
 // Utility functions
 func countTokens(data []byte) int {
 	if len(data) == 0 {
@@ -1402,14 +1631,25 @@ func (g *Gateway) handleMemorySearch(w http.ResponseWriter, r *http.Request) {
 		minScore = 1
 	}
 
+	// sessionKey scopes the search to what that session may see; "own"
+	// drops the global and shared memories from it
+	sessionKey, err := g.memorySessionKey(r, r.URL.Query().Get("sessionKey"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
 	grpcClient := rpcproto.NewAgentGRPCClient(client)
 	ctx, cancel := context.WithTimeout(r.Context(), rpcproto.DefaultGRPCTimeout())
 	defer cancel()
 	args := rpcproto.MemorySearchArgs{
-		Query:    query,
-		Category: category,
-		Limit:    int32(limit),
-		MinScore: float32(minScore),
+		Query:      query,
+		Category:   category,
+		Limit:      int32(limit),
+		MinScore:   float32(minScore),
+		SessionKey: sessionKey,
+		AgentId:    r.URL.Query().Get("agent"),
+		OwnOnly:    r.URL.Query().Get("scope") == "own",
 	}
 	reply, err := grpcClient.MemorySearch(ctx, &args)
 	if err != nil {
@@ -1433,11 +1673,20 @@ func (g *Gateway) handleMemoryGet(w http.ResponseWriter, r *http.Request) {
 	}
 
 	path := r.URL.Query().Get("path")
+	sessionKey, err := g.memorySessionKey(r, r.URL.Query().Get("sessionKey"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 
 	grpcClient := rpcproto.NewAgentGRPCClient(client)
 	ctx, cancel := context.WithTimeout(r.Context(), rpcproto.DefaultGRPCTimeout())
 	defer cancel()
-	args := rpcproto.MemoryGetArgs{Path: path}
+	args := rpcproto.MemoryGetArgs{
+		Path:       path,
+		SessionKey: sessionKey,
+		AgentId:    r.URL.Query().Get("agent"),
+	}
 	reply, err := grpcClient.MemoryGet(ctx, &args)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -1471,14 +1720,23 @@ func (g *Gateway) handleMemoryStore(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var req struct {
-		Text       string  `json:"text"`
-		Category   string  `json:"category,omitempty"`
-		Importance float64 `json:"importance,omitempty"`
+		Text       string   `json:"text"`
+		Category   string   `json:"category,omitempty"`
+		Importance float64  `json:"importance,omitempty"`
+		SessionKey string   `json:"sessionKey,omitempty"`
+		Agent      string   `json:"agent,omitempty"`
+		Global     bool     `json:"global,omitempty"`
+		ShareWith  []string `json:"shareWith,omitempty"`
 	}
 	if err := json.Unmarshal(body, &req); err != nil {
 		http.Error(w, "Parse error: "+err.Error(), http.StatusBadRequest)
 		return
 	}
+	sessionKey, err := g.memorySessionKey(r, req.SessionKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 
 	grpcClient := rpcproto.NewAgentGRPCClient(client)
 	ctx, cancel := context.WithTimeout(r.Context(), rpcproto.DefaultGRPCTimeout())
@@ -1487,6 +1745,10 @@ func (g *Gateway) handleMemoryStore(w http.ResponseWriter, r *http.Request) {
 		Text:       req.Text,
 		Category:   req.Category,
 		Importance: float32(req.Importance),
+		SessionKey: sessionKey,
+		AgentId:    req.Agent,
+		Global:     req.Global,
+		ShareWith:  req.ShareWith,
 	}
 	reply, err := grpcClient.MemoryStore(ctx, &args)
 	if err != nil {
@@ -1734,38 +1996,61 @@ func (g *Gateway) handleCronWake(w http.ResponseWriter, r *http.Request) {
 
 // GatewayAgentRPC implements channels.AgentRPCInterface
 type GatewayAgentRPC struct {
-	client *grpc.ClientConn
+	client  *grpc.ClientConn
+	agentID string // Agent profile the turns run as (empty = default)
 }
 
 func (r *GatewayAgentRPC) Chat(messages []channels.Message) (string, error) {
-	return r.ChatWithSession("default", messages)
+	return r.chat(context.Background(), "", false, "", messages)
 }
 
 func (r *GatewayAgentRPC) ChatWithSession(sessionKey string, messages []channels.Message) (string, error) {
+	return r.chat(context.Background(), sessionKey, false, "", messages)
+}
+
+// ChatStructuredContext runs a turn under sessionKey without loading the
+// session's history, cancelled when ctx is done, with the reply constrained to
+// a response_format; a reply that never matched it is returned with an error
+func (r *GatewayAgentRPC) ChatStructuredContext(ctx context.Context, sessionKey, responseFormat string, messages []channels.Message) (string, error) {
+	return r.chat(ctx, sessionKey, true, responseFormat, messages)
+}
+
+func (r *GatewayAgentRPC) chat(ctx context.Context, sessionKey string, isolated bool, responseFormat string, messages []channels.Message) (string, error) {
 	if r.client == nil {
 		return "", fmt.Errorf("agent RPC client not connected")
 	}
 
 	rpcMessages := make([]rpcproto.Message, 0, len(messages))
 	for _, m := range messages {
+		var parts []*rpcproto.ContentPart
+		for _, p := range m.Parts {
+			parts = append(parts, &rpcproto.ContentPart{Type: p.Type, MimeType: p.MimeType, Data: p.Data})
+		}
 		rpcMessages = append(rpcMessages, rpcproto.Message{
 			Role:    m.Role,
 			Content: m.Content,
+			Parts:   parts,
 		})
 	}
 
 	client := rpcproto.NewAgentGRPCClient(r.client)
 	args := rpcproto.ChatArgs{
-		Messages:   rpcproto.ToMessagesPtr(rpcMessages),
-		SessionKey: sessionKey,
+		Messages:       rpcproto.ToMessagesPtr(rpcMessages),
+		SessionKey:     sessionKey,
+		Isolated:       isolated,
+		ResponseFormat: responseFormat,
+		AgentId:        r.agentID,
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), rpcproto.DefaultGRPCTimeout())
+	ctx, cancel := context.WithTimeout(ctx, rpcproto.DefaultGRPCTimeout())
 	defer cancel()
 
 	reply, err := client.Chat(ctx, &args)
 	if err != nil {
 		return "", err
 	}
+	if reply.FormatError != "" {
+		return reply.Content, fmt.Errorf("%w: %s", cron.ErrResponseFormat, reply.FormatError)
+	}
 
 	return reply.Content, nil
 }
@@ -1824,12 +2109,52 @@ func (r *GatewayAgentRPC) EndAudioStream(sessionKey string) error {
 	return nil
 }
 
+// BotCommands returns the agent's slash commands as a channel menu, with
+// subcommands collapsed into their group
+func (r *GatewayAgentRPC) BotCommands() ([]channels.BotCommand, error) {
+	if r.client == nil {
+		return nil, fmt.Errorf("agent RPC client not connected")
+	}
+	client := rpcproto.NewAgentGRPCClient(r.client)
+	ctx, cancel := context.WithTimeout(context.Background(), rpcproto.DefaultGRPCTimeout())
+	defer cancel()
+	infos, err := client.Commands(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	reg := commands.NewRegistry(nil)
+	for _, info := range infos {
+		_ = reg.Register(commands.Command{Name: info.Name, Help: info.Help})
+	}
+	var menu []channels.BotCommand
+	for _, e := range reg.Menu(commands.LevelUser) {
+		menu = append(menu, channels.BotCommand{Command: e.Command, Description: e.Description})
+	}
+	return menu, nil
+}
+
+// modelAgentID returns the agent ID of an "agent:<id>" model name, or ""
+func modelAgentID(model string) string {
+	if id, ok := strings.CutPrefix(model, "agent:"); ok {
+		return id
+	}
+	return ""
+}
+
+// channelAgentRPC returns the agent RPC for a channel, running its turns as
+// the agent profile named by <CHANNEL>_AGENT_ID (e.g. TELEGRAM_AGENT_ID)
+func (g *Gateway) channelAgentRPC(channel string) *GatewayAgentRPC {
+	return &GatewayAgentRPC{client: g.client, agentID: os.Getenv(strings.ToUpper(channel) + "_AGENT_ID")}
+}
+
 // ChatRequest represents OpenAI-compatible chat request
 // (kept local to avoid dependency on rpcproto types)
 type ChatRequest struct {
-	Model    string             `json:"model"`
-	Messages []rpcproto.Message `json:"messages"`
-	Stream   bool               `json:"stream,omitempty"`
+	Model          string             `json:"model"`
+	Messages       []rpcproto.Message `json:"messages"`
+	Stream         bool               `json:"stream,omitempty"`
+	ResponseFormat json.RawMessage    `json:"response_format,omitempty"` // Passed to the agent as is
 }
 
 type ChatResponse struct {
@@ -1845,6 +2170,7 @@ type Choice struct {
 	Index        int              `json:"index"`
 	Message      rpcproto.Message `json:"message"`
 	FinishReason string           `json:"finish_reason"`
+	Parsed       json.RawMessage  `json:"parsed,omitempty"` // Reply object of a response_format request
 }
 
 type Usage struct {
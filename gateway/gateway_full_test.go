@@ -233,6 +233,55 @@ func TestRequireAuthMiddleware(t *testing.T) {
 	}
 }
 
+func TestMemoryTokenBindsSession(t *testing.T) {
+	cfg := config.GatewayConfig{
+		Port:         55003,
+		UIAuthToken:  "secret-token",
+		MemoryTokens: map[string]string{"alice-token": "telegram_alice"},
+	}
+	gateway := New(cfg)
+
+	handler := gateway.requireMemoryAuth(func(w http.ResponseWriter, r *http.Request) {
+		key, err := gateway.memorySessionKey(r, r.URL.Query().Get("sessionKey"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(key))
+	})
+	for _, tc := range []struct {
+		token, query string
+		code         int
+		key          string
+	}{
+		{"", "", http.StatusUnauthorized, ""},
+		{"alice-token", "", http.StatusOK, "telegram_alice"},
+		{"alice-token", "?sessionKey=telegram_alice", http.StatusOK, "telegram_alice"},
+		{"alice-token", "?sessionKey=slack_bob", http.StatusForbidden, ""},
+		{"secret-token", "?sessionKey=slack_bob", http.StatusOK, "slack_bob"},
+		{"secret-token", "", http.StatusOK, ""},
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/memory/search"+tc.query, nil)
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.code || (tc.code == http.StatusOK && rec.Body.String() != tc.key) {
+			t.Errorf("%q %q: %d %q", tc.token, tc.query, rec.Code, rec.Body.String())
+		}
+	}
+
+	// Memory tokens grant nothing outside /memory/*
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer alice-token")
+	gateway.requireAuth(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401, got %d", rec.Code)
+	}
+}
+
 func TestAddCORS(t *testing.T) {
 	cfg := config.GatewayConfig{
 		Port: 55003,
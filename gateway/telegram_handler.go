@@ -51,7 +51,7 @@ func (g *Gateway) handleTelegramSetWebhook(w http.ResponseWriter, r *http.Reques
 			return
 		}
 
-		bot := channels.NewTelegramBot(telegramToken, &GatewayAgentRPC{client: g.client})
+		bot := channels.NewTelegramBot(telegramToken, g.channelAgentRPC("telegram"))
 		if err := g.channelAdapter.RegisterChannel(bot); err != nil {
 			http.Error(w, fmt.Sprintf("Failed to register Telegram channel: %v", err), http.StatusInternalServerError)
 			return
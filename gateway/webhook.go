@@ -82,17 +82,18 @@ type WakePayload struct {
 }
 
 type AgentPayload struct {
-	Message        string `json:"message"`
-	Name           string `json:"name"`
-	AgentID        string `json:"agentId"`
-	SessionKey     string `json:"sessionKey"`
-	WakeMode       string `json:"wakeMode"` // "now" or "next-heartbeat"
-	Deliver        bool   `json:"deliver"`
-	Channel        string `json:"channel"`
-	To             string `json:"to"`
-	Model          string `json:"model"`
-	Thinking       string `json:"thinking"`
-	TimeoutSeconds int    `json:"timeoutSeconds"`
+	Message        string          `json:"message"`
+	Name           string          `json:"name"`
+	AgentID        string          `json:"agentId"`
+	SessionKey     string          `json:"sessionKey"`
+	WakeMode       string          `json:"wakeMode"` // "now" or "next-heartbeat"
+	Deliver        bool            `json:"deliver"`
+	Channel        string          `json:"channel"`
+	To             string          `json:"to"`
+	Model          string          `json:"model"`
+	Thinking       string          `json:"thinking"`
+	TimeoutSeconds int             `json:"timeoutSeconds"`
+	ResponseFormat json.RawMessage `json:"responseFormat,omitempty"` // OpenAI response_format for the reply
 }
 
 // NewWebhookHandler creates a new webhook handler
@@ -409,7 +410,9 @@ func (h *WebhookHandler) HandleAgent(w http.ResponseWriter, r *http.Request) {
 			Messages: []*rpcproto.Message{
 				{Role: "user", Content: agentMessage},
 			},
-			SessionKey: sessionKey, // pass session key
+			SessionKey:     sessionKey, // pass session key
+			ResponseFormat: string(payload.ResponseFormat),
+			AgentId:        payload.AgentID,
 		}
 
 		// Apply model override if specified
@@ -431,6 +434,11 @@ func (h *WebhookHandler) HandleAgent(w http.ResponseWriter, r *http.Request) {
 			// Could return error to caller via a callback
 			return
 		}
+		if resp.FormatError != "" {
+			// Don't deliver a reply the caller can't parse
+			log.Printf("[Webhook] channel=webhook action=agent_chat run_id=%s format_error=%s", runID, resp.FormatError)
+			return
+		}
 
 		response := resp.Content
 
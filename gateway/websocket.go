@@ -26,8 +26,21 @@ const (
 	MsgTypePing    = "ping"
 	MsgTypePong    = "pong"
 	MsgTypeHistory = "history"
+	// MsgTypeApproval carries an approval request to the client, and the
+	// client's answer ({"id": ..., "approve": true|false}) back
+	MsgTypeApproval = "approval"
 )
 
+// approvalEventPrefix marks the stream chunk the agent emits when a tool call
+// of the turn waits for human approval
+const approvalEventPrefix = `[TOOL_EVENT]{"type":"approval_request","approval":`
+
+// WSApprovalAnswer is a client's answer to an approval request
+type WSApprovalAnswer struct {
+	ID      string `json:"id"`
+	Approve bool   `json:"approve"`
+}
+
 // WSMessage represents a WebSocket message
 type WSMessage struct {
 	Type    string          `json:"type"`
@@ -185,6 +198,8 @@ func (g *Gateway) handleWSConnection(ctx context.Context, conn *websocket.Conn,
 			// Fix B: Run in goroutine to avoid blocking the read loop
 			// This allows the connection to continue handling ping/pong while waiting for LLM
 			go g.handleWSChat(ctx, conn, &writeMu, msg.Content)
+		case MsgTypeApproval:
+			go g.handleWSApproval(ctx, conn, &writeMu, msg.Content)
 		case MsgTypePing:
 			// Respond with pong (bounded write timeout) - Fix A: use mutex
 			pong := WSMessage{Type: MsgTypePong}
@@ -247,7 +262,7 @@ func (g *Gateway) handleWSChat(ctx context.Context, conn *websocket.Conn, writeM
 	// Send request to agent via gRPC (streaming)
 	// FIX: Use connection context so gRPC call is cancelled when WS closes
 	grpcClient := rpcproto.NewAgentGRPCClient(client)
-	args := rpcproto.ChatArgs{Messages: rpcproto.ToMessagesPtr(req.Messages)}
+	args := rpcproto.ChatArgs{Messages: rpcproto.ToMessagesPtr(req.Messages), AgentId: modelAgentID(req.Model)}
 	ctxTimeout, cancel := context.WithTimeout(ctx, rpcproto.DefaultGRPCTimeout())
 	defer cancel()
 
@@ -268,6 +283,14 @@ func (g *Gateway) handleWSChat(ctx context.Context, conn *websocket.Conn, writeM
 		if chunk.Done {
 			break
 		}
+		if strings.HasPrefix(chunk.Content, approvalEventPrefix) {
+			approval := strings.TrimSuffix(strings.TrimPrefix(chunk.Content, approvalEventPrefix), "}")
+			if err := g.writeWS(ctx, conn, writeMu, WSMessage{Type: MsgTypeApproval, Content: json.RawMessage(approval)}); err != nil {
+				log.Printf("[WS] Write error: %v", err)
+				return
+			}
+			continue
+		}
 		if chunk.Content != "" {
 			contentBuilder.WriteString(chunk.Content)
 
@@ -345,6 +368,51 @@ func (g *Gateway) handleWSChat(ctx context.Context, conn *websocket.Conn, writeM
 	}
 }
 
+// handleWSApproval relays the client's answer to an approval request as the
+// /approve or /deny command of the session that raised it
+func (g *Gateway) handleWSApproval(ctx context.Context, conn *websocket.Conn, writeMu *sync.Mutex, content json.RawMessage) {
+	var answer WSApprovalAnswer
+	if err := json.Unmarshal(content, &answer); err != nil || answer.ID == "" {
+		g.sendWSError(ctx, conn, writeMu, "invalid approval answer")
+		return
+	}
+	client, err := g.clientOrError()
+	if err != nil {
+		g.sendWSError(ctx, conn, writeMu, "agent not connected")
+		return
+	}
+	command := "/deny "
+	if answer.Approve {
+		command = "/approve "
+	}
+	ctxTimeout, cancel := context.WithTimeout(ctx, rpcproto.DefaultGRPCTimeout())
+	defer cancel()
+	reply, err := rpcproto.NewAgentGRPCClient(client).Chat(ctxTimeout, &rpcproto.ChatArgs{
+		Messages: []*rpcproto.Message{{Role: "user", Content: command + answer.ID}},
+	})
+	if err != nil {
+		g.sendWSError(ctx, conn, writeMu, "approval error: "+err.Error())
+		return
+	}
+	resp, _ := json.Marshal(WSChatResponse{Content: reply.Content, Finish: true})
+	if err := g.writeWS(ctx, conn, writeMu, WSMessage{Type: MsgTypeApproval, Content: resp}); err != nil {
+		log.Printf("[WS] Write error: %v", err)
+	}
+}
+
+// writeWS sends one message with the usual write timeout
+func (g *Gateway) writeWS(ctx context.Context, conn *websocket.Conn, writeMu *sync.Mutex, msg WSMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	writeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	return conn.Write(writeCtx, websocket.MessageText, data)
+}
+
 func (g *Gateway) sendWSError(ctx context.Context, conn *websocket.Conn, writeMu *sync.Mutex, errMsg string) {
 	resp := WSChatResponse{
 		Error:  errMsg,
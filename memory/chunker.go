@@ -0,0 +1,300 @@
+// chunker.go - Structure-aware document chunking: headings, code declarations, token windows
+package memory
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Chunk is a piece of a document and where it sits in the source
+type Chunk struct {
+	Text      string
+	Start     int    // Byte offset of the first byte in the source
+	End       int    // Byte offset just past the last byte
+	StartLine int    // 1-based, inclusive
+	EndLine   int    // 1-based, inclusive
+	Heading   string // Enclosing section ("Setup > Linux") or code declaration
+}
+
+// ChunkOptions sizes chunks in estimated tokens
+type ChunkOptions struct {
+	MaxTokens int // Largest chunk (default: 400)
+	Overlap   int // Tokens repeated between consecutive windows of one section (default: 50)
+}
+
+func (o ChunkOptions) withDefaults() ChunkOptions {
+	if o.MaxTokens <= 0 {
+		o.MaxTokens = 400
+	}
+	if o.Overlap == 0 {
+		o.Overlap = 50
+	}
+	if o.Overlap < 0 {
+		o.Overlap = 0
+	} else if o.Overlap >= o.MaxTokens/2 {
+		o.Overlap = o.MaxTokens / 8
+	}
+	return o
+}
+
+// boundary starts a section: a heading (level 1-6) or a code declaration
+type boundary struct {
+	offset int
+	level  int
+	title  string
+}
+
+// estimateTokens approximates tokens as four bytes of ASCII or one and a
+// half per other rune, like the agent's context estimate
+func estimateTokens(s string) int {
+	ascii, other := 0, 0
+	for _, r := range s {
+		if r <= 127 {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return ascii/4 + other*3/2
+}
+
+var mdHeading = regexp.MustCompile(`^(#{1,6})[ \t]+(.+?)[ \t#]*$`)
+
+// markdownBoundaries finds ATX headings outside fenced code blocks
+func markdownBoundaries(text string) []boundary {
+	var bounds []boundary
+	fence := ""
+	offsets, all := splitLines(text)
+	for i, line := range all {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			switch {
+			case fence == "":
+				fence = trimmed[:3]
+			case strings.HasPrefix(trimmed, fence):
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+		if m := mdHeading.FindStringSubmatch(strings.TrimRight(line, "\r")); m != nil {
+			bounds = append(bounds, boundary{offset: offsets[i], level: len(m[1]), title: m[2]})
+		}
+	}
+	return bounds
+}
+
+// codeDecl matches the lines that start a top-level declaration in common
+// languages; nested code is indented and stays with its declaration
+var codeDecl = regexp.MustCompile(`^(?:` + strings.Join([]string{
+	// Go
+	`func\s`, `type\s`, `var\s`, `const\s`,
+	// Python, Ruby and others
+	`(?:async\s+)?def\s`, `class\s`,
+	// JavaScript, TypeScript
+	`(?:export\s+)?(?:default\s+)?(?:async\s+)?function\b`,
+	`(?:export\s+)?(?:abstract\s+)?class\s`,
+	`(?:export\s+)?(?:interface|enum)\s`,
+	`export\s+(?:const|let|type)\s`,
+	// Rust
+	`(?:pub(?:\([^)]*\))?\s+)?(?:async\s+)?(?:fn|struct|enum|trait|impl|mod)\b`,
+	// Java, C#, Kotlin
+	`(?:public|private|protected|internal)\s`,
+	// C, C++ function definitions
+	`[A-Za-z_][\w\s\*&:<>,]*\([^;]*\)\s*\{?\s*$`,
+}, "|") + `)`)
+
+// isCommentLine reports lines that document the declaration below them
+func isCommentLine(line string) bool {
+	t := strings.TrimSpace(line)
+	for _, p := range []string{"//", "#", "/*", "*", "--", "@", `"""`} {
+		if strings.HasPrefix(t, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// codeBoundaries starts a section at each top-level declaration, moved up
+// over the comments and annotations right above it
+func codeBoundaries(text string) []boundary {
+	var bounds []boundary
+	offsets, all := splitLines(text)
+	for i, line := range all {
+		if line == "" || line[0] == ' ' || line[0] == '\t' || isCommentLine(line) || !codeDecl.MatchString(line) {
+			continue
+		}
+		start := i
+		for start > 0 && isCommentLine(all[start-1]) && strings.TrimSpace(all[start-1]) != "" {
+			start--
+		}
+		if n := len(bounds); n > 0 && bounds[n-1].offset >= offsets[start] {
+			continue
+		}
+		title := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(line), "{"))
+		if len(title) > 80 {
+			title = truncateText(title, 77)
+		}
+		bounds = append(bounds, boundary{offset: offsets[start], level: 1, title: title})
+	}
+	return bounds
+}
+
+// splitLines returns the lines of text (without "\n") and their byte offsets
+func splitLines(text string) ([]int, []string) {
+	var offsets []int
+	var all []string
+	for off := 0; off < len(text); {
+		end := strings.IndexByte(text[off:], '\n')
+		if end < 0 {
+			end = len(text) - off
+		}
+		offsets = append(offsets, off)
+		all = append(all, text[off:off+end])
+		off += end + 1
+	}
+	return offsets, all
+}
+
+// section is a span of the source under one heading path
+type section struct {
+	start, end int
+	heading    string
+	decl       bool // Starts at a code declaration, so small ones may be grouped
+}
+
+// sections cuts text at the boundaries; each gets the path of the headings
+// enclosing it ("Setup > Linux"). Text before the first boundary is a
+// section of its own
+func sections(text string, bounds []boundary, code bool) []section {
+	var out []section
+	var path []boundary
+	prev := section{start: 0}
+	for _, b := range bounds {
+		if b.offset > prev.start {
+			prev.end = b.offset
+			out = append(out, prev)
+		}
+		for len(path) > 0 && path[len(path)-1].level >= b.level {
+			path = path[:len(path)-1]
+		}
+		path = append(path, b)
+		titles := make([]string, len(path))
+		for i, p := range path {
+			titles[i] = p.title
+		}
+		prev = section{start: b.offset, heading: strings.Join(titles, " > "), decl: code}
+	}
+	if len(text) > prev.start {
+		prev.end = len(text)
+		out = append(out, prev)
+	}
+	return out
+}
+
+// chunkText splits a document into chunks. view is the text chunks are
+// cut from and has the same length as the source: it equals the source
+// except for HTML, whose markup is blanked out. Sections that fit become one
+// chunk, small consecutive code declarations are grouped, and long sections
+// are cut into line-aligned token windows that overlap
+func chunkText(view string, bounds []boundary, code bool, opts ChunkOptions) []Chunk {
+	opts = opts.withDefaults()
+	var chunks []Chunk
+	secs := sections(view, bounds, code)
+	for i := 0; i < len(secs); i++ {
+		sec := secs[i]
+		// Group small declarations while they fit together
+		for sec.decl && i+1 < len(secs) && secs[i+1].decl &&
+			estimateTokens(view[sec.start:secs[i+1].end]) <= opts.MaxTokens {
+			i++
+			sec.end = secs[i].end
+		}
+		if estimateTokens(view[sec.start:sec.end]) <= opts.MaxTokens {
+			chunks = appendChunk(chunks, view, sec.start, sec.end, sec.heading)
+			continue
+		}
+		for _, w := range windows(view, sec.start, sec.end, opts) {
+			chunks = appendChunk(chunks, view, w[0], w[1], sec.heading)
+		}
+	}
+
+	// Line numbers in one pass over the source
+	line, pos := 1, 0
+	for i := range chunks {
+		line += strings.Count(view[pos:chunks[i].Start], "\n")
+		chunks[i].StartLine = line
+		chunks[i].EndLine = line + strings.Count(view[chunks[i].Start:chunks[i].End], "\n")
+		line, pos = chunks[i].StartLine, chunks[i].Start
+	}
+	return chunks
+}
+
+// appendChunk adds view[start:end] trimmed of surrounding whitespace,
+// unless nothing is left
+func appendChunk(chunks []Chunk, view string, start, end int, heading string) []Chunk {
+	for start < end && isSpace(view[start]) {
+		start++
+	}
+	for end > start && isSpace(view[end-1]) {
+		end--
+	}
+	if start == end {
+		return chunks
+	}
+	return append(chunks, Chunk{Text: view[start:end], Start: start, End: end, Heading: heading})
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f' || b == '\v'
+}
+
+// windows cuts view[start:end] into spans of at most opts.MaxTokens,
+// breaking between lines where possible; each window after the first
+// begins opts.Overlap tokens before the previous one ends
+func windows(view string, start, end int, opts ChunkOptions) [][2]int {
+	// Line starts within the span, with over-long lines cut at rune boundaries
+	maxBytes := opts.MaxTokens * 4
+	var cuts []int
+	for off := start; off < end; {
+		next := strings.IndexByte(view[off:end], '\n')
+		if next < 0 {
+			next = end - off
+		} else {
+			next++
+		}
+		lineEnd := off + next
+		for lineEnd-off > maxBytes {
+			cut := off + maxBytes
+			for cut > off && !utf8.RuneStart(view[cut]) {
+				cut--
+			}
+			cuts = append(cuts, off)
+			off = cut
+		}
+		cuts = append(cuts, off)
+		off = lineEnd
+	}
+	cuts = append(cuts, end)
+
+	var out [][2]int
+	for i := 0; i < len(cuts)-1; {
+		j := i + 1
+		for j < len(cuts)-1 && estimateTokens(view[cuts[i]:cuts[j+1]]) <= opts.MaxTokens {
+			j++
+		}
+		out = append(out, [2]int{cuts[i], cuts[j]})
+		if j >= len(cuts)-1 {
+			break
+		}
+		// Step back over whole lines worth about opts.Overlap tokens
+		next := j
+		for next > i+1 && estimateTokens(view[cuts[next-1]:cuts[j]]) <= opts.Overlap {
+			next--
+		}
+		i = next
+	}
+	return out
+}
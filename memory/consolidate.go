@@ -0,0 +1,407 @@
+// consolidate.go - memory consolidation: merge near-duplicates, decay and archive
+package memory
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConsolidateOptions tunes one consolidation pass
+type ConsolidateOptions struct {
+	Threshold    float32 // Similarity at which memories merge (default 0.92)
+	ArchiveBelow float32 // Archive memories whose decay weight falls below this (default 0.1; needs a decay half-life)
+	DryRun       bool    // Report what would change without changing it
+
+	// Merge rewrites the texts of a cluster, most important first, into one
+	// memory. Without it the cluster keeps the text of its first memory.
+	Merge func(texts []string) (string, error)
+}
+
+// MergeRecord is one cluster of near-duplicates folded into a memory
+type MergeRecord struct {
+	ID        string   `json:"id"`     // Memory that was kept
+	Merged    []string `json:"merged"` // Memories archived into it
+	Text      string   `json:"text"`   // Its text after the merge
+	Rewritten bool     `json:"rewritten,omitempty"`
+}
+
+// ConsolidationReport says what a consolidation pass changed
+type ConsolidationReport struct {
+	Scanned  int           `json:"scanned"`
+	Merges   []MergeRecord `json:"merges,omitempty"`
+	Archived []string      `json:"archived,omitempty"` // Memories archived for low value
+	DryRun   bool          `json:"dryRun,omitempty"`
+}
+
+// String summarizes the report for logs and command replies
+func (r *ConsolidationReport) String() string {
+	merged := 0
+	for _, m := range r.Merges {
+		merged += len(m.Merged)
+	}
+	var b strings.Builder
+	if r.DryRun {
+		b.WriteString("Dry run: ")
+	}
+	fmt.Fprintf(&b, "scanned %d memories, merged %d into %d, archived %d", r.Scanned, merged, len(r.Merges), len(r.Archived))
+	for _, m := range r.Merges {
+		fmt.Fprintf(&b, "\n- %s <- %s: %s", shortID(m.ID), shortIDs(m.Merged), truncateText(m.Text, 80))
+	}
+	if len(r.Archived) > 0 {
+		fmt.Fprintf(&b, "\n- archived: %s", shortIDs(r.Archived))
+	}
+	return b.String()
+}
+
+// SetDecayHalfLife changes Config.DecayHalfLife of a running store
+func (s *VectorMemoryStore) SetDecayHalfLife(d time.Duration) {
+	s.decayHalfLife.Store(int64(d))
+}
+
+// retention is the weight decay leaves a memory at now: it halves every
+// half-life since the memory was last updated, stretched by its importance
+// (an importance-1 memory decays at half the rate). Document chunks do not decay
+func (s *VectorMemoryStore) retention(e MemoryEntry, now time.Time) float32 {
+	halfLife := time.Duration(s.decayHalfLife.Load())
+	age := now.Sub(time.Unix(e.UpdatedAt, 0))
+	if halfLife <= 0 || age <= 0 || e.Category == DocumentCategory {
+		return 1
+	}
+	life := float64(halfLife) * (1 + math.Max(0, math.Min(1, e.Importance)))
+	return float32(math.Exp2(-float64(age) / life))
+}
+
+// applyDecay weighs search results by retention and re-sorts them
+func (s *VectorMemoryStore) applyDecay(results []MemoryResult, now time.Time) {
+	if s.decayHalfLife.Load() <= 0 {
+		return
+	}
+	for i := range results {
+		results[i].Score *= s.retention(results[i].Entry, now)
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+}
+
+// Consolidate merges clusters of near-duplicate memories and archives the
+// ones decay has made worthless. Memories only merge with memories of the
+// same namespace, owner and sharing list, so merging never widens who sees
+// a memory; the kept memory takes the highest importance and, with
+// opts.Merge, a rewritten text.
+// Merged and decayed memories move to the archive, which records what each
+// was merged into (see MergedFrom).
+func (s *VectorMemoryStore) Consolidate(opts ConsolidateOptions) (*ConsolidationReport, error) {
+	if opts.Threshold <= 0 {
+		opts.Threshold = 0.92
+	}
+	if opts.ArchiveBelow <= 0 {
+		opts.ArchiveBelow = 0.1
+	}
+
+	all, err := s.allEntries()
+	if err != nil {
+		return nil, err
+	}
+	// Document chunks mirror their files; re-ingesting maintains them
+	var entries []MemoryEntry
+	for _, e := range all {
+		if e.Category != DocumentCategory {
+			entries = append(entries, e)
+		}
+	}
+	report := &ConsolidationReport{Scanned: len(entries), DryRun: opts.DryRun}
+	now := time.Now()
+	defer s.flushHNSW()
+
+	groups := make(map[[3]string][]MemoryEntry)
+	for _, e := range entries {
+		shared := slices.Clone(e.SharedWith)
+		slices.Sort(shared)
+		key := [3]string{e.Namespace, e.Owner, joinOwners(shared)}
+		groups[key] = append(groups[key], e)
+	}
+	keys := make([][3]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b [3]string) int {
+		return cmp.Or(cmp.Compare(a[0], b[0]), cmp.Compare(a[1], b[1]), cmp.Compare(a[2], b[2]))
+	})
+
+	merged := make(map[string]bool)
+	rewritten := false
+	for _, key := range keys {
+		for _, cluster := range s.clusters(groups[key], opts.Threshold) {
+			rec, err := s.mergeCluster(cluster, opts, now)
+			if err != nil {
+				return report, err
+			}
+			rewritten = rewritten || rec.Rewritten
+			for _, id := range rec.Merged {
+				merged[id] = true
+			}
+			merged[rec.ID] = true // Just refreshed, so it does not decay out
+			report.Merges = append(report.Merges, rec)
+		}
+	}
+
+	if s.decayHalfLife.Load() > 0 {
+		for _, e := range entries {
+			if merged[e.ID] || s.retention(e, now) >= opts.ArchiveBelow {
+				continue
+			}
+			if !opts.DryRun {
+				if err := s.archive(e.ID, "decayed", ""); err != nil {
+					return report, err
+				}
+			}
+			report.Archived = append(report.Archived, e.ID)
+		}
+	}
+
+	if rewritten {
+		s.rebuildHNSW()
+	}
+	log.Printf("[Memory] Consolidation: %s", strings.SplitN(report.String(), "\n", 2)[0])
+	return report, nil
+}
+
+// clusterNeighbours is how many nearest neighbours of a seed are checked
+// for duplicates when the HNSW index is available
+const clusterNeighbours = 32
+
+// clusters groups near-duplicates around seeds taken in order of importance,
+// then age; a memory joins the first seed it is similar to. Clusters of one
+// are left out.
+func (s *VectorMemoryStore) clusters(entries []MemoryEntry, threshold float32) [][]MemoryEntry {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Importance != entries[j].Importance {
+			return entries[i].Importance > entries[j].Importance
+		}
+		return entries[i].CreatedAt < entries[j].CreatedAt
+	})
+	pos := make(map[string]int, len(entries))
+	byText := make(map[string][]int)
+	for i, e := range entries {
+		pos[e.ID] = i
+		key := normalizeText(e.Text)
+		byText[key] = append(byText[key], i)
+	}
+	taken := make([]bool, len(entries))
+	var out [][]MemoryEntry
+	for i := range entries {
+		if taken[i] {
+			continue
+		}
+		taken[i] = true
+		cluster := []MemoryEntry{entries[i]}
+		for _, j := range s.candidates(entries, i, pos, byText) {
+			if !taken[j] && s.similar(entries[i], entries[j], threshold) {
+				taken[j] = true
+				cluster = append(cluster, entries[j])
+			}
+		}
+		if len(cluster) > 1 {
+			out = append(out, cluster)
+		}
+	}
+	return out
+}
+
+// candidates lists, in order, the entries that may duplicate entries[i]:
+// those with the same text and its nearest HNSW neighbours among entries.
+// Without an index every entry is a candidate.
+func (s *VectorMemoryStore) candidates(entries []MemoryEntry, i int, pos map[string]int, byText map[string][]int) []int {
+	out := slices.Clone(byText[normalizeText(entries[i].Text)])
+	if s.embedding == nil || len(entries[i].Vector) == 0 {
+		return out
+	}
+	s.hnswMu.RLock()
+	defer s.hnswMu.RUnlock()
+	if s.hnsw == nil || s.hnsw.Count() == 0 {
+		all := make([]int, len(entries))
+		for j := range all {
+			all[j] = j
+		}
+		return all
+	}
+	entry := func(label int64) (int, bool) {
+		if label < 0 || label >= int64(len(s.hnswIDs)) {
+			return 0, false
+		}
+		j, ok := pos[s.hnswIDs[label]]
+		return j, ok
+	}
+	_, labels, err := s.hnsw.SearchFilter(entries[i].Vector, clusterNeighbours, func(label int64) bool {
+		_, ok := entry(label)
+		return ok
+	})
+	if err != nil {
+		log.Printf("[WARN] consolidation neighbour search failed: %v", err)
+	}
+	for _, label := range labels {
+		if j, ok := entry(label); ok {
+			out = append(out, j)
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// similar compares two memories by their vectors, or only by text when the
+// store has placeholder vectors
+func (s *VectorMemoryStore) similar(a, b MemoryEntry, threshold float32) bool {
+	if normalizeText(a.Text) == normalizeText(b.Text) {
+		return true
+	}
+	if s.embedding == nil || len(a.Vector) == 0 || len(a.Vector) != len(b.Vector) {
+		return false
+	}
+	return CosineSimilarity(a.Vector, b.Vector) >= threshold
+}
+
+func normalizeText(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+// mergeCluster folds a cluster into its first memory and archives the rest
+func (s *VectorMemoryStore) mergeCluster(cluster []MemoryEntry, opts ConsolidateOptions, now time.Time) (MergeRecord, error) {
+	kept := cluster[0]
+	rec := MergeRecord{ID: kept.ID, Text: kept.Text}
+	importance := kept.Importance
+	texts := make([]string, 0, len(cluster))
+	for _, e := range cluster {
+		texts = append(texts, e.Text)
+		importance = math.Max(importance, e.Importance)
+		if e.ID != kept.ID {
+			rec.Merged = append(rec.Merged, e.ID)
+		}
+	}
+
+	if opts.Merge != nil {
+		text, err := opts.Merge(texts)
+		if err != nil {
+			log.Printf("[WARN] memory merge rewrite failed, keeping %s: %v", shortID(kept.ID), err)
+		} else if text = strings.TrimSpace(text); text != "" && text != kept.Text {
+			rec.Text, rec.Rewritten = text, true
+		}
+	}
+	if opts.DryRun {
+		return rec, nil
+	}
+
+	vector := kept.Vector
+	if rec.Rewritten {
+		var err error
+		if vector, err = s.getEmbedding(rec.Text); err != nil {
+			return rec, fmt.Errorf("embedding failed: %v", err)
+		}
+	}
+	_, err := s.db.Exec(`
+		UPDATE vector_memories
+		SET text = ?, vector = ?, importance = ?, updated_at = ?
+		WHERE id = ?
+	`, rec.Text, serializeVector(vector), importance, now.Unix(), kept.ID)
+	if err != nil {
+		return rec, err
+	}
+	if rec.Rewritten {
+		s.upsertFTS(kept.ID, rec.Text, kept.Category)
+	}
+
+	for _, id := range rec.Merged {
+		if err := s.archive(id, "merged", kept.ID); err != nil {
+			return rec, err
+		}
+	}
+	return rec, nil
+}
+
+// archive moves a memory to the archive table
+func (s *VectorMemoryStore) archive(id, reason, mergedInto string) error {
+	_, err := s.db.Exec(`
+		INSERT OR REPLACE INTO vector_memories_archive
+			(id, text, vector, importance, category, source, namespace, owner, shared_with, embedding_dim, created_at, updated_at, archived_at, reason, merged_into)
+		SELECT id, text, vector, importance, category, source, namespace, owner, shared_with, embedding_dim, created_at, updated_at, ?, ?, ?
+		FROM vector_memories WHERE id = ?
+	`, time.Now().Unix(), reason, mergedInto, id)
+	if err != nil {
+		return fmt.Errorf("archive %s: %v", shortID(id), err)
+	}
+	_, err = s.Delete(id)
+	return err
+}
+
+// MergedFrom returns the archived memories consolidation merged into id
+func (s *VectorMemoryStore) MergedFrom(id string) ([]MemoryEntry, error) {
+	return s.queryEntries(`
+		SELECT id, text, importance, category, source, namespace, owner, shared_with, created_at, updated_at
+		FROM vector_memories_archive WHERE merged_into = ?
+		ORDER BY created_at
+	`, id)
+}
+
+// queryEntries scans memories selected without their vector, as MergedFrom does
+func (s *VectorMemoryStore) queryEntries(query string, args ...any) ([]MemoryEntry, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entries []MemoryEntry
+	for rows.Next() {
+		var e MemoryEntry
+		var sharedWith string
+		if err := rows.Scan(&e.ID, &e.Text, &e.Importance, &e.Category, &e.Source, &e.Namespace, &e.Owner, &sharedWith, &e.CreatedAt, &e.UpdatedAt); err != nil {
+			return nil, err
+		}
+		e.SharedWith = splitOwners(sharedWith)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// allEntries reads every memory with its vector
+func (s *VectorMemoryStore) allEntries() ([]MemoryEntry, error) {
+	rows, err := s.db.Query(`
+		SELECT id, text, vector, importance, category, source, namespace, owner, shared_with, created_at, updated_at
+		FROM vector_memories
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entries []MemoryEntry
+	for rows.Next() {
+		var e MemoryEntry
+		var vectorBlob []byte
+		var sharedWith string
+		if err := rows.Scan(&e.ID, &e.Text, &vectorBlob, &e.Importance, &e.Category, &e.Source, &e.Namespace, &e.Owner, &sharedWith, &e.CreatedAt, &e.UpdatedAt); err != nil {
+			return nil, err
+		}
+		e.Vector = deserializeVector(vectorBlob)
+		e.SharedWith = splitOwners(sharedWith)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func truncateText(text string, n int) string {
+	if r := []rune(text); len(r) > n {
+		return string(r[:n]) + "..."
+	}
+	return text
+}
+
+func shortIDs(ids []string) string {
+	short := make([]string, len(ids))
+	for i, id := range ids {
+		short[i] = shortID(id)
+	}
+	return strings.Join(short, ", ")
+}
@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsolidate(t *testing.T) {
+	store, err := NewVectorMemoryStore(filepath.Join(t.TempDir(), "vec.db"), Config{EmbeddingDim: 3, DecayHalfLife: 30 * 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+	if store.hnsw, err = NewHNSWIndex(HNSWConfig{Dim: 3, Distance: "cosine"}); err != nil {
+		t.Fatal(err)
+	}
+	store.embedding = axisProvider{
+		"likes apple pie":   {1, 0.05, 0},
+		"likes apple tart":  {1, 0, 0.05},
+		"apple desserts":    {1, 0, 0},
+		"bolt size is M8":   {0, 1, 0},
+		"old cloud account": {0, 0, 1},
+	}
+
+	pie, _ := store.StoreOwned(Ownership{SharedWith: []string{"telegram_2"}}, "likes apple pie", "preference", 0.6, "auto")
+	tart, _ := store.StoreOwned(Ownership{SharedWith: []string{"telegram_2"}}, "likes apple tart", "preference", 0.8, "auto")
+	tart3, _ := store.StoreOwned(Ownership{SharedWith: []string{"telegram_3"}}, "likes apple tart", "preference", 0.5, "auto")
+	dup, _ := store.Store("Bolt size is  M8", "fact", 0.5)
+	bolt, _ := store.Store("bolt size is M8", "fact", 0.5)
+	bobs, _ := store.StoreOwned(Ownership{Owner: "telegram_bob"}, "likes apple pie", "preference", 0.5, "auto")
+	old, _ := store.Store("old cloud account", "fact", 0.2)
+	year := time.Now().AddDate(-1, 0, 0).Unix()
+	if _, err := store.db.Exec(`UPDATE vector_memories SET created_at = ?, updated_at = ? WHERE id IN (?, ?)`, year, year, old, dup); err != nil {
+		t.Fatal(err)
+	}
+
+	// A dry run reports without changing anything
+	report, err := store.Consolidate(ConsolidateOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Merges) != 2 || len(report.Archived) != 1 {
+		t.Fatalf("dry run report:\n%s", report)
+	}
+	if n, _ := store.Count(); n != 7 {
+		t.Fatalf("dry run changed the store: %d memories", n)
+	}
+
+	var rewrites [][]string
+	report, err = store.Consolidate(ConsolidateOptions{Merge: func(texts []string) (string, error) {
+		rewrites = append(rewrites, texts)
+		if strings.Contains(texts[0], "apple") {
+			return "apple desserts", nil
+		}
+		return texts[0], nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Archived) != 1 || report.Archived[0] != old {
+		t.Errorf("archived %v, want the decayed memory %s", report.Archived, old)
+	}
+	if len(rewrites) != 2 || strings.Join(rewrites[1], "|") != "likes apple tart|likes apple pie" {
+		t.Errorf("merge rewrites = %q", rewrites)
+	}
+
+	// The most important memory is kept, with the merged text
+	kept, err := store.Get(tart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kept.Text != "apple desserts" || kept.Importance != 0.8 || strings.Join(kept.SharedWith, ",") != "telegram_2" {
+		t.Errorf("kept memory = %+v", kept)
+	}
+	if from, err := store.MergedFrom(tart); err != nil || len(from) != 1 || from[0].ID != pie || from[0].Text != "likes apple pie" {
+		t.Errorf("MergedFrom = %+v, %v", from, err)
+	}
+	// Of equally important duplicates the oldest is kept
+	if _, err := store.Get(dup); err != nil {
+		t.Errorf("oldest duplicate was not kept: %v", err)
+	}
+	for _, id := range []string{pie, bolt, old} {
+		if _, err := store.Get(id); err == nil {
+			t.Errorf("%s still in the store", id)
+		}
+	}
+	if _, err := store.Get(bobs); err != nil {
+		t.Error("another owner's memory was merged")
+	}
+	if _, err := store.Get(tart3); err != nil {
+		t.Error("a memory shared with someone else was merged")
+	}
+
+	// Older memories rank lower; importance slows the decay
+	now := time.Now()
+	results := []MemoryResult{
+		{Entry: MemoryEntry{ID: "stale", Importance: 0, UpdatedAt: now.AddDate(0, 0, -60).Unix()}, Score: 0.9},
+		{Entry: MemoryEntry{ID: "key", Importance: 1, UpdatedAt: now.AddDate(0, 0, -60).Unix()}, Score: 0.8},
+		{Entry: MemoryEntry{ID: "fresh", UpdatedAt: now.Unix()}, Score: 0.5},
+	}
+	store.applyDecay(results, now)
+	if results[0].Entry.ID != "fresh" || results[1].Entry.ID != "key" {
+		t.Errorf("decayed order = %s, %s, %s", results[0].Entry.ID, results[1].Entry.ID, results[2].Entry.ID)
+	}
+}
+
+func TestSearchDecayRefills(t *testing.T) {
+	store, err := NewVectorMemoryStore(filepath.Join(t.TempDir(), "vec.db"), Config{EmbeddingDim: 3, DecayHalfLife: 30 * 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+	store.embedding = axisProvider{"apple": {1, 0, 0}, "stale apple": {1, 0.1, 0}, "fresh apple": {1, 0.5, 0}}
+
+	stale, _ := store.Store("stale apple", "fact", 0.1)
+	store.Store("fresh apple", "fact", 0.5)
+	year := time.Now().AddDate(-1, 0, 0).Unix()
+	if _, err := store.db.Exec(`UPDATE vector_memories SET updated_at = ? WHERE id = ?`, year, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	// The closest match has decayed below the minimum; the next one takes its place
+	results, err := store.Search("apple", 1, 0)
+	if err != nil || len(results) != 1 || results[0].Entry.Text != "fresh apple" {
+		t.Errorf("Search = %+v, %v", results, err)
+	}
+}
@@ -0,0 +1,826 @@
+// doctext.go - Readable text from HTML and PDF documents for ingestion
+package memory
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ==================== HTML ====================
+
+// htmlSkipped are elements whose content is not readable text
+var htmlSkipped = map[string]bool{"script": true, "style": true, "noscript": true, "template": true, "svg": true}
+
+// htmlView blanks out the markup of an HTML document so the text keeps its
+// byte offsets and line numbers: tags, comments and the content of script,
+// style and similar elements become spaces, newlines stay. It also returns
+// the h1-h6 headings as section boundaries
+func htmlView(src string) (string, []boundary) {
+	view := []byte(src)
+	blank := func(from, to int) {
+		for i := from; i < to && i < len(view); i++ {
+			if view[i] != '\n' {
+				view[i] = ' '
+			}
+		}
+	}
+
+	var bounds []boundary
+	for i := 0; i < len(src); {
+		if src[i] != '<' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(src[i:], "<!--") {
+			end := len(src)
+			if e := strings.Index(src[i+4:], "-->"); e >= 0 {
+				end = i + 4 + e + 3
+			}
+			blank(i, end)
+			i = end
+			continue
+		}
+		end := tagEnd(src, i)
+		name := tagName(src[i:end])
+		blank(i, end)
+		switch {
+		case htmlSkipped[name]:
+			close := strings.Index(strings.ToLower(src[end:]), "</"+name)
+			closeEnd := len(src)
+			if close >= 0 {
+				closeEnd = tagEnd(src, end+close)
+			}
+			blank(end, closeEnd)
+			end = closeEnd
+		case len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6':
+			close := strings.Index(strings.ToLower(src[end:]), "</"+name)
+			if close < 0 {
+				break
+			}
+			title := cleanHTMLText(stripTags(src[end : end+close]))
+			if title != "" {
+				bounds = append(bounds, boundary{offset: i, level: int(name[1] - '0'), title: strings.ReplaceAll(title, "\n", " ")})
+			}
+		}
+		i = end
+	}
+	return string(view), bounds
+}
+
+// tagEnd returns the offset just past the tag starting at src[start],
+// skipping '>' inside quoted attribute values
+func tagEnd(src string, start int) int {
+	var quote byte
+	for i := start + 1; i < len(src); i++ {
+		switch c := src[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i + 1
+		}
+	}
+	return len(src)
+}
+
+// tagName is the lowercase element name of an opening tag ("" for closing
+// tags, doctypes and processing instructions)
+func tagName(tag string) string {
+	tag = strings.TrimPrefix(tag, "<")
+	end := strings.IndexFunc(tag, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+	if end < 0 {
+		end = len(tag)
+	}
+	return strings.ToLower(tag[:end])
+}
+
+var htmlTag = regexp.MustCompile(`(?s)<[^>]*>`)
+
+func stripTags(s string) string {
+	return htmlTag.ReplaceAllString(s, " ")
+}
+
+// cleanHTMLText decodes entities and drops the blank space markup left
+func cleanHTMLText(s string) string {
+	var out []string
+	for _, line := range strings.Split(html.UnescapeString(s), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// ==================== PDF ====================
+
+// pdfText extracts the text of a PDF, pages separated by "\f". It reads
+// uncompressed and Flate-compressed content, object streams and ToUnicode
+// font maps; scanned pages (images), other filters and encrypted files
+// yield no text
+func pdfText(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\r\n "), []byte("%PDF")) {
+		return "", fmt.Errorf("not a PDF file")
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return "", fmt.Errorf("encrypted PDFs are not supported")
+	}
+	p := &pdfReader{objects: make(map[int]*pdfObject)}
+	p.parseObjects(data)
+
+	var pages []string
+	for _, page := range p.pages() {
+		pages = append(pages, p.pageText(page))
+	}
+	text := strings.Join(pages, "\f")
+	if strings.TrimSpace(strings.ReplaceAll(text, "\f", "")) == "" {
+		return "", fmt.Errorf("no extractable text (scanned or unsupported PDF)")
+	}
+	return text, nil
+}
+
+type pdfObject struct {
+	dict   []byte
+	stream []byte // Raw (still encoded) stream data, nil without one
+}
+
+type pdfReader struct {
+	objects map[int]*pdfObject
+	cmaps   map[int]*pdfCMap // ToUnicode maps by object number
+}
+
+var (
+	pdfObjStart = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	pdfRef      = regexp.MustCompile(`^(\d+)\s+(\d+)\s+R\b`)
+	pdfRefs     = regexp.MustCompile(`(\d+)\s+\d+\s+R\b`)
+	pdfNamedRef = regexp.MustCompile(`/([^\s/<>\[\]()]+)\s+(\d+)\s+\d+\s+R\b`)
+)
+
+// parseObjects indexes every "N G obj ... endobj"; later definitions win,
+// as with incremental updates. Objects packed in object streams are added
+// where no direct object has the number
+func (p *pdfReader) parseObjects(data []byte) {
+	locs := pdfObjStart.FindAllSubmatchIndex(data, -1)
+	for i, loc := range locs {
+		num, _ := strconv.Atoi(string(data[loc[2]:loc[3]]))
+		end := len(data)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		body := data[loc[1]:end]
+		if e := bytes.Index(body, []byte("endobj")); e >= 0 {
+			if s := bytes.Index(body, []byte("stream")); s < 0 || s > e {
+				body = body[:e]
+			}
+		}
+		p.objects[num] = splitStream(body)
+	}
+
+	for _, obj := range p.objectsByNumber() {
+		if !bytes.Contains(obj.dict, []byte("/ObjStm")) {
+			continue
+		}
+		p.unpackObjStm(obj)
+	}
+}
+
+// splitStream separates an object's dictionary from its stream data
+func splitStream(body []byte) *pdfObject {
+	s := bytes.Index(body, []byte("stream"))
+	if s < 0 || bytes.Contains(body[:s], []byte("endstream")) {
+		return &pdfObject{dict: bytes.TrimSpace(body)}
+	}
+	obj := &pdfObject{dict: bytes.TrimSpace(body[:s])}
+	data := body[s+len("stream"):]
+	data = bytes.TrimPrefix(data, []byte("\r"))
+	data = bytes.TrimPrefix(data, []byte("\n"))
+	if n, err := strconv.Atoi(string(dictValue(obj.dict, "Length"))); err == nil && n >= 0 && n <= len(data) {
+		obj.stream = data[:n]
+	} else if e := bytes.Index(data, []byte("endstream")); e >= 0 {
+		obj.stream = bytes.TrimRight(data[:e], "\r\n")
+	} else {
+		obj.stream = data
+	}
+	return obj
+}
+
+func (p *pdfReader) objectsByNumber() []*pdfObject {
+	nums := make([]int, 0, len(p.objects))
+	for n := range p.objects {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	objs := make([]*pdfObject, len(nums))
+	for i, n := range nums {
+		objs[i] = p.objects[n]
+	}
+	return objs
+}
+
+// unpackObjStm adds the objects of an object stream
+func (p *pdfReader) unpackObjStm(obj *pdfObject) {
+	data := decodeStream(obj)
+	n, _ := strconv.Atoi(string(dictValue(obj.dict, "N")))
+	first, _ := strconv.Atoi(string(dictValue(obj.dict, "First")))
+	if data == nil || first <= 0 || first > len(data) {
+		return
+	}
+	header := strings.Fields(string(data[:first]))
+	for i := 0; i+1 < len(header) && i/2 < n; i += 2 {
+		num, err1 := strconv.Atoi(header[i])
+		off, err2 := strconv.Atoi(header[i+1])
+		if err1 != nil || err2 != nil || first+off > len(data) {
+			continue
+		}
+		end := len(data)
+		if i+3 < len(header) {
+			if next, err := strconv.Atoi(header[i+3]); err == nil && first+next <= len(data) && next >= off {
+				end = first + next
+			}
+		}
+		if _, ok := p.objects[num]; !ok {
+			p.objects[num] = &pdfObject{dict: bytes.TrimSpace(data[first+off : end])}
+		}
+	}
+}
+
+// decodeStream returns a stream's decoded data, or nil for filters other
+// than FlateDecode
+func decodeStream(obj *pdfObject) []byte {
+	if obj == nil || obj.stream == nil {
+		return nil
+	}
+	filter := dictValue(obj.dict, "Filter")
+	switch {
+	case len(filter) == 0:
+		return obj.stream
+	case bytes.Equal(bytes.Trim(filter, "[] \r\n"), []byte("/FlateDecode")):
+		r, err := zlib.NewReader(bytes.NewReader(obj.stream))
+		if err != nil {
+			return nil
+		}
+		out, _ := io.ReadAll(r) // keep what decoded before any corruption
+		return out
+	}
+	return nil
+}
+
+// dictValue returns the raw value of /key in a dictionary: a nested
+// dictionary or array with its delimiters, a reference "N G R", or a
+// single token
+func dictValue(dict []byte, key string) []byte {
+	for from := 0; ; {
+		i := bytes.Index(dict[from:], []byte("/"+key))
+		if i < 0 {
+			return nil
+		}
+		i += from + len(key) + 1
+		if i < len(dict) && isPDFRegular(dict[i]) {
+			from = i // a longer name, e.g. /Length1
+			continue
+		}
+		v := bytes.TrimLeft(dict[i:], " \t\r\n")
+		switch {
+		case bytes.HasPrefix(v, []byte("<<")):
+			return balanced(v, "<<", ">>")
+		case bytes.HasPrefix(v, []byte("[")):
+			return balanced(v, "[", "]")
+		}
+		if m := pdfRef.Find(v); m != nil {
+			return m
+		}
+		end := 1
+		for end < len(v) && isPDFRegular(v[end]) {
+			end++
+		}
+		return v[:end]
+	}
+}
+
+func isPDFRegular(c byte) bool {
+	return !bytes.ContainsRune([]byte(" \t\r\n\f\x00()<>[]{}/%"), rune(c))
+}
+
+// balanced returns the prefix of v from open to its matching close
+func balanced(v []byte, open, close string) []byte {
+	depth := 0
+	for i := 0; i < len(v); {
+		switch {
+		case bytes.HasPrefix(v[i:], []byte(open)):
+			depth++
+			i += len(open)
+		case bytes.HasPrefix(v[i:], []byte(close)):
+			depth--
+			i += len(close)
+			if depth == 0 {
+				return v[:i]
+			}
+		default:
+			i++
+		}
+	}
+	return v
+}
+
+// resolve follows a reference to its object's dictionary
+func (p *pdfReader) resolve(v []byte) []byte {
+	if m := pdfRef.FindSubmatch(v); m != nil {
+		n, _ := strconv.Atoi(string(m[1]))
+		if obj := p.objects[n]; obj != nil {
+			return obj.dict
+		}
+		return nil
+	}
+	return v
+}
+
+func refNumbers(v []byte) []int {
+	var nums []int
+	for _, m := range pdfRefs.FindAllSubmatch(v, -1) {
+		n, _ := strconv.Atoi(string(m[1]))
+		nums = append(nums, n)
+	}
+	return nums
+}
+
+var pdfPageType = regexp.MustCompile(`/Type\s*/Page\b`)
+
+// pages returns page object numbers in reading order from the page tree,
+// or all page objects by number when there is no usable tree
+func (p *pdfReader) pages() []int {
+	for _, obj := range p.objectsByNumber() {
+		if !bytes.Contains(obj.dict, []byte("/Catalog")) {
+			continue
+		}
+		if root := refNumbers(dictValue(obj.dict, "Pages")); len(root) == 1 {
+			var pages []int
+			p.walkPages(root[0], &pages, make(map[int]bool))
+			if len(pages) > 0 {
+				return pages
+			}
+		}
+	}
+	var pages []int
+	for n, obj := range p.objects {
+		if pdfPageType.Match(obj.dict) {
+			pages = append(pages, n)
+		}
+	}
+	sort.Ints(pages)
+	return pages
+}
+
+func (p *pdfReader) walkPages(n int, pages *[]int, seen map[int]bool) {
+	obj := p.objects[n]
+	if obj == nil || seen[n] {
+		return
+	}
+	seen[n] = true
+	if pdfPageType.Match(obj.dict) {
+		*pages = append(*pages, n)
+		return
+	}
+	for _, kid := range refNumbers(dictValue(obj.dict, "Kids")) {
+		p.walkPages(kid, pages, seen)
+	}
+}
+
+// inherited looks a page attribute up on the page or its ancestors
+func (p *pdfReader) inherited(n int, key string) []byte {
+	for depth := 0; depth < 32; depth++ {
+		obj := p.objects[n]
+		if obj == nil {
+			return nil
+		}
+		if v := dictValue(obj.dict, key); v != nil {
+			return p.resolve(v)
+		}
+		parent := refNumbers(dictValue(obj.dict, "Parent"))
+		if len(parent) != 1 {
+			return nil
+		}
+		n = parent[0]
+	}
+	return nil
+}
+
+// pageText interprets the text operators of a page's content streams
+func (p *pdfReader) pageText(n int) string {
+	obj := p.objects[n]
+	if obj == nil {
+		return ""
+	}
+	var content []byte
+	for _, c := range refNumbers(dictValue(obj.dict, "Contents")) {
+		content = append(append(content, decodeStream(p.objects[c])...), '\n')
+	}
+
+	// Font resource names to their ToUnicode maps
+	fonts := make(map[string]*pdfFont)
+	if res := p.inherited(n, "Resources"); res != nil {
+		fontDict := p.resolve(dictValue(res, "Font"))
+		for _, m := range pdfNamedRef.FindAllSubmatch(fontDict, -1) {
+			num, _ := strconv.Atoi(string(m[2]))
+			fonts[string(m[1])] = p.font(num)
+		}
+	}
+	return interpretText(content, fonts)
+}
+
+// pdfFont is what text extraction needs of a font
+type pdfFont struct {
+	cmap      *pdfCMap
+	composite bool // Type0: multi-byte codes that mean nothing without a map
+}
+
+func (p *pdfReader) font(n int) *pdfFont {
+	obj := p.objects[n]
+	if obj == nil {
+		return nil
+	}
+	f := &pdfFont{composite: bytes.Contains(obj.dict, []byte("/Type0"))}
+	if refs := refNumbers(dictValue(obj.dict, "ToUnicode")); len(refs) == 1 {
+		if p.cmaps == nil {
+			p.cmaps = make(map[int]*pdfCMap)
+		}
+		if _, ok := p.cmaps[refs[0]]; !ok {
+			p.cmaps[refs[0]] = parseCMap(decodeStream(p.objects[refs[0]]))
+		}
+		f.cmap = p.cmaps[refs[0]]
+	}
+	return f
+}
+
+// pdfCMap maps character codes to Unicode text
+type pdfCMap struct {
+	width int // Code length in bytes
+	codes map[uint32]string
+}
+
+var (
+	cmapSection = regexp.MustCompile(`(?s)begin(codespacerange|bfchar|bfrange)(.*?)end(?:codespacerange|bfchar|bfrange)`)
+	cmapToken   = regexp.MustCompile(`<([0-9A-Fa-f]*)>|\[[^\]]*\]`)
+)
+
+func parseCMap(data []byte) *pdfCMap {
+	if data == nil {
+		return nil
+	}
+	cm := &pdfCMap{width: 1, codes: make(map[uint32]string)}
+	for _, sec := range cmapSection.FindAllSubmatch(data, -1) {
+		toks := cmapToken.FindAll(sec[2], -1)
+		switch string(sec[1]) {
+		case "codespacerange":
+			if len(toks) > 0 {
+				cm.width = max(1, (len(toks[0])-2)/2)
+			}
+		case "bfchar":
+			for i := 0; i+1 < len(toks); i += 2 {
+				cm.codes[hexCode(toks[i])] = utf16Hex(toks[i+1])
+			}
+		case "bfrange":
+			for i := 0; i+2 < len(toks); i += 3 {
+				lo, hi := hexCode(toks[i]), hexCode(toks[i+1])
+				if hi < lo || hi-lo > 0xFFFF {
+					continue
+				}
+				if toks[i+2][0] == '[' {
+					for j, dst := range cmapToken.FindAll(toks[i+2][1:], -1) {
+						cm.codes[lo+uint32(j)] = utf16Hex(dst)
+					}
+					continue
+				}
+				base := []rune(utf16Hex(toks[i+2]))
+				if len(base) == 0 {
+					continue
+				}
+				for c := lo; c <= hi; c++ {
+					r := append([]rune{}, base...)
+					r[len(r)-1] += rune(c - lo)
+					cm.codes[c] = string(r)
+				}
+			}
+		}
+	}
+	return cm
+}
+
+func hexCode(tok []byte) uint32 {
+	v, _ := strconv.ParseUint(string(bytes.Trim(tok, "<>")), 16, 32)
+	return uint32(v)
+}
+
+func utf16Hex(tok []byte) string {
+	h := string(bytes.Trim(tok, "<>"))
+	var units []uint16
+	for i := 0; i+4 <= len(h); i += 4 {
+		v, _ := strconv.ParseUint(h[i:i+4], 16, 16)
+		units = append(units, uint16(v))
+	}
+	if len(h) == 2 { // single-byte destinations
+		v, _ := strconv.ParseUint(h, 16, 8)
+		units = append(units, uint16(v))
+	}
+	return string(utf16.Decode(units))
+}
+
+func (cm *pdfCMap) decode(s []byte) string {
+	var sb strings.Builder
+	for i := 0; i+cm.width <= len(s); i += cm.width {
+		var code uint32
+		for _, b := range s[i : i+cm.width] {
+			code = code<<8 | uint32(b)
+		}
+		sb.WriteString(cm.codes[code])
+	}
+	return sb.String()
+}
+
+// interpretText runs the text-showing operators of a content stream
+func interpretText(content []byte, fonts map[string]*pdfFont) string {
+	var sb strings.Builder
+	var operands [][]byte
+	var font *pdfFont
+	lastY := ""
+	newline := func() {
+		if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n") {
+			sb.WriteByte('\n')
+		}
+	}
+	show := func(s []byte) {
+		switch {
+		case font != nil && font.cmap != nil:
+			sb.WriteString(font.cmap.decode(s))
+		case font != nil && font.composite:
+			// Glyph IDs without a map are not text
+		default:
+			for _, b := range s {
+				sb.WriteRune(rune(b)) // Latin-1, close to PDFDocEncoding
+			}
+		}
+	}
+
+	lex := &pdfLexer{data: content}
+	for {
+		tok, kind := lex.next()
+		if kind == pdfEOF {
+			break
+		}
+		if kind != pdfOperator {
+			operands = append(operands, tok)
+			continue
+		}
+		arg := func(i int) []byte {
+			if i < len(operands) {
+				return operands[len(operands)-1-i]
+			}
+			return nil
+		}
+		switch string(tok) {
+		case "Tf":
+			font = fonts[strings.TrimPrefix(string(arg(1)), "/")]
+		case "Tj":
+			show(pdfString(arg(0)))
+		case "'", "\"":
+			newline()
+			show(pdfString(arg(0)))
+		case "TJ":
+			for _, el := range splitPDFArray(arg(0)) {
+				if el[0] == '(' || el[0] == '<' {
+					show(pdfString(el))
+				} else if f, err := strconv.ParseFloat(string(el), 64); err == nil && f < -180 {
+					sb.WriteByte(' ')
+				}
+			}
+		case "Td", "TD":
+			if y, _ := strconv.ParseFloat(string(arg(0)), 64); y != 0 {
+				newline()
+			} else {
+				sb.WriteByte(' ')
+			}
+		case "T*":
+			newline()
+		case "Tm":
+			if y := string(arg(0)); y != lastY {
+				newline()
+				lastY = y
+			}
+		case "ET":
+			sb.WriteByte(' ')
+		case "BI":
+			lex.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+
+	var lines []string
+	for _, line := range strings.Split(sb.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+type pdfTokenKind int
+
+const (
+	pdfEOF pdfTokenKind = iota
+	pdfOperand
+	pdfOperator
+)
+
+// pdfLexer splits a content stream into operands and operators
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func (l *pdfLexer) next() ([]byte, pdfTokenKind) {
+	d := l.data
+	for l.pos < len(d) {
+		c := d[l.pos]
+		switch {
+		case c == '%':
+			for l.pos < len(d) && d[l.pos] != '\n' && d[l.pos] != '\r' {
+				l.pos++
+			}
+		case !isPDFRegular(c) && c != '(' && c != '<' && c != '[' && c != '/':
+			l.pos++ // whitespace, stray delimiters
+		default:
+			start := l.pos
+			switch {
+			case c == '(':
+				l.pos = literalEnd(d, l.pos)
+			case bytes.HasPrefix(d[l.pos:], []byte("<<")):
+				l.pos = start + len(balanced(d[l.pos:], "<<", ">>"))
+			case c == '<':
+				if e := bytes.IndexByte(d[l.pos:], '>'); e >= 0 {
+					l.pos += e + 1
+				} else {
+					l.pos = len(d)
+				}
+			case c == '[':
+				l.pos = arrayEnd(d, l.pos)
+			case c == '/':
+				l.pos++
+				for l.pos < len(d) && isPDFRegular(d[l.pos]) {
+					l.pos++
+				}
+			default:
+				for l.pos < len(d) && isPDFRegular(d[l.pos]) {
+					l.pos++
+				}
+				tok := d[start:l.pos]
+				if (tok[0] >= '0' && tok[0] <= '9') || tok[0] == '-' || tok[0] == '+' || tok[0] == '.' {
+					return tok, pdfOperand
+				}
+				return tok, pdfOperator
+			}
+			return d[start:l.pos], pdfOperand
+		}
+	}
+	return nil, pdfEOF
+}
+
+// skipInlineImage moves past the binary data of BI ... ID ... EI
+func (l *pdfLexer) skipInlineImage() {
+	if i := bytes.Index(l.data[l.pos:], []byte("ID")); i >= 0 {
+		l.pos += i + 2
+	}
+	if i := bytes.Index(l.data[l.pos:], []byte("EI")); i >= 0 {
+		l.pos += i + 2
+	} else {
+		l.pos = len(l.data)
+	}
+}
+
+// literalEnd returns the offset past the literal string starting at d[i]
+func literalEnd(d []byte, i int) int {
+	depth := 0
+	for ; i < len(d); i++ {
+		switch d[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(d)
+}
+
+// arrayEnd returns the offset past the array starting at d[i], skipping
+// brackets inside strings
+func arrayEnd(d []byte, i int) int {
+	depth := 0
+	for i < len(d) {
+		switch d[i] {
+		case '(':
+			i = literalEnd(d, i)
+			continue
+		case '[':
+			depth++
+		case ']':
+			if depth--; depth == 0 {
+				return i + 1
+			}
+		}
+		i++
+	}
+	return len(d)
+}
+
+// splitPDFArray returns the elements of a TJ array
+func splitPDFArray(arr []byte) [][]byte {
+	if len(arr) < 2 || arr[0] != '[' {
+		return nil
+	}
+	l := &pdfLexer{data: arr[1 : len(arr)-1]}
+	var out [][]byte
+	for {
+		tok, kind := l.next()
+		if kind == pdfEOF {
+			return out
+		}
+		out = append(out, tok)
+	}
+}
+
+// pdfString decodes a literal "(...)" or hex "<...>" string to its bytes
+func pdfString(tok []byte) []byte {
+	if len(tok) < 2 {
+		return nil
+	}
+	if tok[0] == '<' {
+		h := bytes.Map(func(r rune) rune {
+			if r == ' ' || r == '\n' || r == '\r' || r == '\t' {
+				return -1
+			}
+			return r
+		}, tok[1:len(tok)-1])
+		if len(h)%2 == 1 {
+			h = append(h, '0')
+		}
+		out := make([]byte, 0, len(h)/2)
+		for i := 0; i+1 < len(h); i += 2 {
+			v, _ := strconv.ParseUint(string(h[i:i+2]), 16, 8)
+			out = append(out, byte(v))
+		}
+		return out
+	}
+	if tok[0] != '(' {
+		return nil
+	}
+	s := tok[1 : len(tok)-1]
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			out = append(out, s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 'n':
+			out = append(out, '\n')
+		case 'r':
+			out = append(out, '\r')
+		case 't':
+			out = append(out, '\t')
+		case 'b':
+			out = append(out, '\b')
+		case 'f':
+			out = append(out, '\f')
+		case '\r', '\n':
+			// line continuation
+		default:
+			if c >= '0' && c <= '7' {
+				j := i
+				for j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7' {
+					j++
+				}
+				v, _ := strconv.ParseUint(string(s[i:j]), 8, 8)
+				out = append(out, byte(v))
+				i = j - 1
+			} else {
+				out = append(out, c)
+			}
+		}
+	}
+	return out
+}
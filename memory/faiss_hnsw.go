@@ -163,6 +163,36 @@ func (idx *HNSWIndex) Search(query []float32, k int) (distances []float32, label
 	return distances, labels, nil
 }
 
+// SearchFilter is Search restricted to the labels allow accepts (nil = all).
+// FAISS can't filter during the graph walk, so it over-fetches and filters
+// the results.
+func (idx *HNSWIndex) SearchFilter(query []float32, k int, allow func(label int64) bool) ([]float32, []int64, error) {
+	if allow == nil {
+		return idx.Search(query, k)
+	}
+	if k <= 0 {
+		k = 5
+	}
+	fetch := max(min(k*8, int(idx.Count())), k)
+	distances, labels, err := idx.Search(query, fetch)
+	if err != nil {
+		return nil, nil, err
+	}
+	var keptD []float32
+	var keptL []int64
+	for i, label := range labels {
+		if label < 0 || !allow(label) {
+			continue
+		}
+		keptD = append(keptD, distances[i])
+		keptL = append(keptL, label)
+		if len(keptL) == k {
+			break
+		}
+	}
+	return keptD, keptL, nil
+}
+
 // Search and return scores (already converted)
 func (idx *HNSWIndex) SearchWithScores(query []float32, k int) ([]float32, []int64, error) {
 	distances, labels, err := idx.Search(query, k)
@@ -172,6 +202,12 @@ func (idx *HNSWIndex) SearchWithScores(query []float32, k int) ([]float32, []int
 	return distances, labels, nil
 }
 
+// Remove is not supported by the FAISS HNSW index; the vector store drops
+// the label from its ID mapping instead and rebuilds after enough deletions
+func (idx *HNSWIndex) Remove(label int64) error {
+	return fmt.Errorf("FAISS HNSW index does not support removal")
+}
+
 // Metric returns the distance metric used by the index
 func (idx *HNSWIndex) Metric() string {
 	return idx.cfg.Distance
@@ -0,0 +1,139 @@
+// graph_extract.go - Ingesting extracted entities and relations into the knowledge graph
+package memory
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Extraction is what an extractor (usually a model) pulled out of some text
+type Extraction struct {
+	Entities  []ExtractedEntity   `json:"entities"`
+	Relations []ExtractedRelation `json:"relations"`
+}
+
+// ExtractedEntity is an entity as mentioned in the text, with the other
+// names the text uses for it
+type ExtractedEntity struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Description string   `json:"description"`
+	Aliases     []string `json:"aliases"`
+}
+
+// ExtractedRelation is a directed fact between two entities; Weight is the
+// extractor's confidence (0 means 1)
+type ExtractedRelation struct {
+	Source   string  `json:"source"`
+	Target   string  `json:"target"`
+	Relation string  `json:"relation"`
+	Weight   float64 `json:"weight"`
+}
+
+// Ingest merges an extraction into the graph. Each entity is resolved to
+// the first of its name and aliases the graph already knows, so "Bob" lands
+// on an existing "robert" entity, and its other names become aliases.
+// Names that are already separate entities are left alone rather than
+// merged. Names resolve among o's own and the global entities; what is
+// learned (types, descriptions, aliases and relations) is stored under o,
+// and relations create missing endpoints
+func (gs *GraphStore) Ingest(o Ownership, x Extraction) (entities, relations int, err error) {
+	sc := ownScope(o)
+	for _, e := range x.Entities {
+		names := append([]string{e.Name}, e.Aliases...)
+		canonical := normalizeName(e.Name)
+		for _, n := range names {
+			if known, err := gs.GetEntityScope(sc, n); err != nil {
+				return entities, relations, err
+			} else if known != nil {
+				canonical = known.Name
+				break
+			}
+		}
+		if canonical == "" {
+			continue
+		}
+		if err := gs.AddEntityOwned(o, canonical, e.Type, e.Description); err != nil {
+			return entities, relations, err
+		}
+		entities++
+
+		for _, n := range names {
+			if n = normalizeName(n); n == "" || n == canonical {
+				continue
+			}
+			if other, err := gs.GetEntityScope(sc, n); err != nil {
+				return entities, relations, err
+			} else if other != nil {
+				continue
+			}
+			if err := gs.AddAlias(o, n, canonical); err != nil {
+				return entities, relations, err
+			}
+		}
+	}
+
+	for _, r := range x.Relations {
+		source, target := gs.Resolve(sc, r.Source), gs.Resolve(sc, r.Target)
+		if source == "" || target == "" || source == target || normalizeName(r.Relation) == "" {
+			continue
+		}
+		for _, n := range []string{source, target} {
+			if err := gs.ensureEntity(o, n); err != nil {
+				return entities, relations, err
+			}
+		}
+		weight := r.Weight
+		if weight <= 0 || weight > 1 {
+			weight = 1
+		}
+		if err := gs.AddRelationOwned(o, source, target, r.Relation, weight); err != nil {
+			return entities, relations, err
+		}
+		relations++
+	}
+	return entities, relations, nil
+}
+
+// ensureEntity creates a bare entity for name under o unless o's owner or
+// everyone already knows one
+func (gs *GraphStore) ensureEntity(o Ownership, name string) error {
+	now := time.Now().Unix()
+	_, err := gs.db.Exec(`
+		INSERT OR IGNORE INTO memory_entities (id, name, type, description, namespace, owner, created_at, updated_at)
+		SELECT ?, ?, 'concept', '', ?, ?, ?, ?
+		WHERE NOT EXISTS (SELECT 1 FROM memory_entities WHERE name = ? AND namespace = ? AND owner IN (?, ''))
+	`, uuid.New().String(), name, o.Namespace, o.Owner, now, now, name, o.Namespace, o.Owner)
+	return err
+}
+
+// GraphPending returns up to limit memories, oldest first, that have not
+// been through graph extraction since they were last written. Document
+// chunks are left out
+func (s *VectorMemoryStore) GraphPending(limit int) ([]MemoryEntry, error) {
+	if s.Graph == nil {
+		return nil, nil
+	}
+	return s.queryEntries(`
+		SELECT id, text, importance, category, source, namespace, owner, shared_with, created_at, updated_at
+		FROM vector_memories m
+		WHERE category != 'document' AND NOT EXISTS (
+			SELECT 1 FROM memory_graph_sources g
+			WHERE g.memory_id = m.id AND g.extracted_at >= m.updated_at
+		)
+		ORDER BY created_at
+		LIMIT ?
+	`, limit)
+}
+
+// MarkGraphExtracted records that memories went through graph extraction
+func (s *VectorMemoryStore) MarkGraphExtracted(ids ...string) error {
+	now := time.Now().Unix()
+	for _, id := range ids {
+		if _, err := s.db.Exec(`INSERT OR REPLACE INTO memory_graph_sources (memory_id, extracted_at) VALUES (?, ?)`, id, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+// graph_query.go - Knowledge graph traversal: neighborhoods, paths and mentions
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxExpand bounds the relations read when expanding one traversal step
+const maxExpand = 1000
+
+// Subgraph is a set of entities and the relations between them
+type Subgraph struct {
+	Entities  []Entity
+	Relations []Relation
+}
+
+// Fact renders a relation as "source -[relation]-> target"
+func (r Relation) Fact() string {
+	return fmt.Sprintf("%s -[%s]-> %s", r.Source, r.Relation, r.Target)
+}
+
+// Facts renders the subgraph's relations, one per line
+func (g *Subgraph) Facts() []string {
+	facts := make([]string, len(g.Relations))
+	for i, r := range g.Relations {
+		facts[i] = r.Fact()
+	}
+	return facts
+}
+
+// neighbors returns the relations touching any of names within the scope,
+// strongest first
+func (gs *GraphStore) neighbors(sc *Scope, names []string, limit int) ([]Relation, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 || limit > maxExpand {
+		limit = maxExpand
+	}
+	in := strings.TrimSuffix(strings.Repeat("?,", len(names)), ",")
+	args := make([]any, 0, 2*len(names)+4)
+	for _, n := range names {
+		args = append(args, n)
+	}
+	args = append(args, args...)
+	cond, scopeArgs := graphScope(sc)
+	args = append(append(args, scopeArgs...), limit)
+
+	return gs.queryRelations(`
+		SELECT id, source, target, relation, weight, namespace, owner, created_at, updated_at
+		FROM memory_relations
+		WHERE (source IN (`+in+`) OR target IN (`+in+`)) AND `+cond+`
+		ORDER BY weight DESC, updated_at DESC
+		LIMIT ?
+	`, args...)
+}
+
+// Neighborhood returns the subgraph within depth hops of the named entities
+// (breadth-first, following relations in either direction), with at most
+// limit relations. sc restricts the relations followed; nil follows all
+func (gs *GraphStore) Neighborhood(sc *Scope, names []string, depth, limit int) (*Subgraph, error) {
+	if depth <= 0 {
+		depth = 1
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+
+	visited := make(map[string]bool)
+	var order, frontier []string
+	for _, n := range names {
+		if n = gs.Resolve(sc, n); n != "" && !visited[n] {
+			visited[n] = true
+			order = append(order, n)
+			frontier = append(frontier, n)
+		}
+	}
+
+	g := &Subgraph{}
+	seen := make(map[string]bool)
+	for hop := 0; hop < depth && len(frontier) > 0 && len(g.Relations) < limit; hop++ {
+		rels, err := gs.neighbors(sc, frontier, limit+len(g.Relations))
+		if err != nil {
+			return nil, err
+		}
+		frontier = nil
+		for _, r := range rels {
+			if seen[r.ID] || len(g.Relations) >= limit {
+				continue
+			}
+			seen[r.ID] = true
+			g.Relations = append(g.Relations, r)
+			for _, n := range []string{r.Source, r.Target} {
+				if !visited[n] {
+					visited[n] = true
+					order = append(order, n)
+					frontier = append(frontier, n)
+				}
+			}
+		}
+	}
+
+	entities, err := gs.entities(sc, order)
+	if err != nil {
+		return nil, err
+	}
+	g.Entities = entities
+	return g, nil
+}
+
+// entities looks up names in order within the scope; names without an
+// entity row there come back with just the name
+func (gs *GraphStore) entities(sc *Scope, names []string) ([]Entity, error) {
+	out := make([]Entity, 0, len(names))
+	for _, n := range names {
+		e, err := gs.GetEntityScope(sc, n)
+		if err != nil {
+			return nil, err
+		}
+		if e == nil {
+			e = &Entity{Name: n}
+		}
+		out = append(out, *e)
+	}
+	return out, nil
+}
+
+// pathStep is how a traversal best reached a node
+type pathStep struct {
+	cost float64
+	prev string
+	via  Relation
+}
+
+// Path finds the chain of relations linking from to to within maxHops,
+// following relations in either direction. Unweighted it is a breadth-first
+// search for the fewest hops; weighted prefers strong relations, each hop
+// costing 1/weight. It returns nil when the entities are not connected
+func (gs *GraphStore) Path(sc *Scope, from, to string, maxHops int, weighted bool) ([]Relation, error) {
+	from, to = gs.Resolve(sc, from), gs.Resolve(sc, to)
+	if from == "" || to == "" {
+		return nil, fmt.Errorf("from and to cannot be empty")
+	}
+	if from == to {
+		return nil, nil
+	}
+	if maxHops <= 0 {
+		maxHops = 4
+	}
+
+	cost := func(r Relation) float64 {
+		if !weighted {
+			return 1
+		}
+		if r.Weight <= 0.001 {
+			return 1000
+		}
+		return 1 / r.Weight
+	}
+
+	// Relax layer by layer so the hop bound holds for weighted paths too
+	best := map[string]pathStep{from: {}}
+	frontier := []string{from}
+	for hop := 1; hop <= maxHops && len(frontier) > 0; hop++ {
+		rels, err := gs.neighbors(sc, frontier, 0)
+		if err != nil {
+			return nil, err
+		}
+		base := make(map[string]float64, len(frontier))
+		for _, n := range frontier {
+			base[n] = best[n].cost
+		}
+		improved := make(map[string]bool)
+		for _, r := range rels {
+			for _, e := range [][2]string{{r.Source, r.Target}, {r.Target, r.Source}} {
+				u, v := e[0], e[1]
+				c, ok := base[u]
+				if !ok || v == from {
+					continue
+				}
+				c += cost(r)
+				if cur, ok := best[v]; !ok || c < cur.cost-1e-9 {
+					best[v] = pathStep{cost: c, prev: u, via: r}
+					improved[v] = true
+				}
+			}
+		}
+		if _, ok := best[to]; ok && !weighted {
+			break
+		}
+		frontier = frontier[:0]
+		for n := range improved {
+			if n != to {
+				frontier = append(frontier, n)
+			}
+		}
+		sort.Strings(frontier)
+	}
+
+	if _, ok := best[to]; !ok {
+		return nil, nil
+	}
+	var path []Relation
+	for n := to; n != from && len(path) <= len(best); n = best[n].prev {
+		path = append(path, best[n].via)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, nil
+}
+
+// EntitiesIn finds the entities known within the scope (nil: all) that
+// text mentions by name or alias, longest mentions first. Names shorter
+// than three characters are ignored
+func (gs *GraphStore) EntitiesIn(sc *Scope, text string, limit int) ([]string, error) {
+	low := normalizeName(text)
+	cond, args := graphScope(sc)
+	own := scopeOwner(sc)
+	rows, err := gs.db.Query(`
+		SELECT name, name, owner = ? AS own FROM memory_entities WHERE length(name) >= 3 AND instr(?, name) > 0 AND `+cond+`
+		UNION ALL
+		SELECT alias, entity, owner = ? AS own FROM memory_entity_aliases WHERE length(alias) >= 3 AND instr(?, alias) > 0 AND `+cond+`
+		ORDER BY own DESC
+	`, append(append(append([]any{own, low}, args...), own, low), args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type mention struct{ text, entity string }
+	var mentions []mention
+	for rows.Next() {
+		var m mention
+		var own bool
+		if err := rows.Scan(&m.text, &m.entity, &own); err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(mentions, func(i, j int) bool { return len(mentions[i].text) > len(mentions[j].text) })
+
+	// A name the scope owner gave its own meaning shadows the global one
+	var found []string
+	seen, claimed := make(map[string]bool), make(map[string]bool)
+	for _, m := range mentions {
+		if seen[m.entity] || claimed[m.text] || !containsWord(low, m.text) {
+			continue
+		}
+		seen[m.entity], claimed[m.text] = true, true
+		found = append(found, m.entity)
+		if limit > 0 && len(found) >= limit {
+			break
+		}
+	}
+	return found, nil
+}
+
+// containsWord reports whether word occurs in text on word boundaries, so
+// "go" is not found in "good"
+func containsWord(text, word string) bool {
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	for off := 0; ; {
+		i := strings.Index(text[off:], word)
+		if i < 0 {
+			return false
+		}
+		start, end := off+i, off+i+len(word)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if !isWord(before) && !isWord(after) {
+			return true
+		}
+		off = start + 1
+	}
+}
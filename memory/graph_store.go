@@ -15,23 +15,30 @@ type GraphStore struct {
 	db *sql.DB
 }
 
-// Entity represents a node in the knowledge graph
+// Entity represents a node in the knowledge graph. Namespace and Owner
+// scope what is known about it like relations; each owner can describe the
+// same name
 type Entity struct {
 	ID          string
 	Name        string
 	Type        string
 	Description string
+	Namespace   string
+	Owner       string
 	CreatedAt   int64
 	UpdatedAt   int64
 }
 
-// Relation represents an edge between two entities
+// Relation represents an edge between two entities. Namespace and Owner
+// scope it like the memory it was extracted from ("" owner is global)
 type Relation struct {
 	ID        string
 	Source    string
 	Target    string
 	Relation  string
 	Weight    float64
+	Namespace string
+	Owner     string
 	CreatedAt int64
 	UpdatedAt int64
 }
@@ -47,29 +54,35 @@ func NewGraphStore(db *sql.DB) (*GraphStore, error) {
 
 func (gs *GraphStore) initSchema() error {
 	queries := []string{
-		`CREATE TABLE IF NOT EXISTS memory_entities (
-			id TEXT PRIMARY KEY,
-			name TEXT NOT NULL UNIQUE,
-			type TEXT NOT NULL,
-			description TEXT,
-			created_at INTEGER,
-			updated_at INTEGER
+		entitiesTable("memory_entities"),
+		relationsTable("memory_relations"),
+		`CREATE TABLE IF NOT EXISTS memory_entity_aliases (
+			alias TEXT NOT NULL,
+			entity TEXT NOT NULL,
+			namespace TEXT NOT NULL DEFAULT '',
+			owner TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (alias, namespace, owner)
 		)`,
-		`CREATE TABLE IF NOT EXISTS memory_relations (
-			id TEXT PRIMARY KEY,
-			source TEXT NOT NULL,
-			target TEXT NOT NULL,
-			relation TEXT NOT NULL,
-			weight REAL DEFAULT 1.0,
-			created_at INTEGER,
-			updated_at INTEGER,
-			UNIQUE(source, target, relation)
+		`CREATE TABLE IF NOT EXISTS memory_graph_sources (
+			memory_id TEXT PRIMARY KEY,
+			extracted_at INTEGER
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_entities_name ON memory_entities(name)`,
+		`CREATE INDEX IF NOT EXISTS idx_aliases_entity ON memory_entity_aliases(entity)`,
 		`CREATE INDEX IF NOT EXISTS idx_relations_source ON memory_relations(source)`,
 		`CREATE INDEX IF NOT EXISTS idx_relations_target ON memory_relations(target)`,
 	}
 
+	if err := gs.migrateScoped("memory_relations", relationsTable,
+		"id, source, target, relation, weight, created_at, updated_at"); err != nil {
+		log.Printf("[ERROR] Graph relations migration failed: %v", err)
+		return err
+	}
+	if err := gs.migrateScoped("memory_entities", entitiesTable,
+		"id, name, type, description, created_at, updated_at"); err != nil {
+		log.Printf("[ERROR] Graph entities migration failed: %v", err)
+		return err
+	}
 	for _, query := range queries {
 		if _, err := gs.db.Exec(query); err != nil {
 			log.Printf("[ERROR] Graph schema init failed for query %s: %v", query, err)
@@ -79,9 +92,196 @@ func (gs *GraphStore) initSchema() error {
 	return nil
 }
 
-// AddEntity adds or updates an entity
+func relationsTable(name string) string {
+	return `CREATE TABLE IF NOT EXISTS ` + name + ` (
+			id TEXT PRIMARY KEY,
+			source TEXT NOT NULL,
+			target TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			weight REAL DEFAULT 1.0,
+			namespace TEXT NOT NULL DEFAULT '',
+			owner TEXT NOT NULL DEFAULT '',
+			created_at INTEGER,
+			updated_at INTEGER,
+			UNIQUE(source, target, relation, namespace, owner)
+		)`
+}
+
+func entitiesTable(name string) string {
+	return `CREATE TABLE IF NOT EXISTS ` + name + ` (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			type TEXT NOT NULL,
+			description TEXT,
+			namespace TEXT NOT NULL DEFAULT '',
+			owner TEXT NOT NULL DEFAULT '',
+			created_at INTEGER,
+			updated_at INTEGER,
+			UNIQUE(name, namespace, owner)
+		)`
+}
+
+// migrateScoped rebuilds a graph table (created by create) from before it
+// was scoped: namespace and owner are part of its unique key, which SQLite
+// cannot alter in place. Existing rows, with their columns, go to
+// LegacyOwner like legacy memories
+func (gs *GraphStore) migrateScoped(table string, create func(string) string, columns string) error {
+	var n, hasOwner int
+	if err := gs.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(name = 'owner'), 0) FROM pragma_table_info(?)`, table).Scan(&n, &hasOwner); err != nil || n == 0 || hasOwner > 0 {
+		return err
+	}
+	tx, err := gs.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, q := range []string{
+		create(table + "_v2"),
+		`INSERT INTO ` + table + `_v2 (` + columns + `, owner) SELECT ` + columns + `, '` + LegacyOwner + `' FROM ` + table,
+		`DROP TABLE ` + table,
+		`ALTER TABLE ` + table + `_v2 RENAME TO ` + table,
+	} {
+		if _, err := tx.Exec(q); err != nil {
+			return err
+		}
+	}
+	log.Printf("[GRAPH] migrated %s to scoped rows", table)
+	return tx.Commit()
+}
+
+// normalizeName is the canonical form of entity and relation names:
+// lowercase with single spaces
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// ownScope is the scope writes under o resolve names in: o's own rows
+// and the global ones
+func ownScope(o Ownership) *Scope {
+	return &Scope{Namespace: o.Namespace, Owner: o.Owner, Global: true}
+}
+
+// Resolve maps a name or alias within the scope (nil: any) to the canonical
+// entity name, preferring the scope owner's alias to a global one. Unknown
+// names come back normalized
+func (gs *GraphStore) Resolve(sc *Scope, name string) string {
+	name = normalizeName(name)
+	cond, args := graphScope(sc)
+	var entity string
+	if err := gs.db.QueryRow(`
+		SELECT entity FROM memory_entity_aliases WHERE alias = ? AND `+cond+`
+		ORDER BY owner = ? DESC LIMIT 1
+	`, append(append([]any{name}, args...), scopeOwner(sc))...).Scan(&entity); err == nil {
+		return entity
+	}
+	return name
+}
+
+// AddAlias makes alias resolve to entity for o's owner. If alias is itself
+// one of o's entities it is merged into entity, along with its relations
+// and aliases
+func (gs *GraphStore) AddAlias(o Ownership, alias, entity string) error {
+	alias = normalizeName(alias)
+	entity = gs.Resolve(ownScope(o), entity)
+	if alias == "" || entity == "" {
+		return fmt.Errorf("alias and entity cannot be empty")
+	}
+	if alias == entity {
+		return nil
+	}
+	var own int
+	if err := gs.db.QueryRow(`SELECT COUNT(*) FROM memory_entities WHERE name = ? AND namespace = ? AND owner = ?`,
+		alias, o.Namespace, o.Owner).Scan(&own); err != nil {
+		return err
+	}
+	if own > 0 {
+		return gs.MergeEntities(o, alias, entity)
+	}
+	_, err := gs.db.Exec(`INSERT OR REPLACE INTO memory_entity_aliases (alias, entity, namespace, owner) VALUES (?, ?, ?, ?)`,
+		alias, entity, o.Namespace, o.Owner)
+	return err
+}
+
+// MergeEntities folds entity from into entity into for o's owner: o's
+// relations, aliases and description of from are moved over and from
+// becomes o's alias of into. Other owners' rows are left alone
+func (gs *GraphStore) MergeEntities(o Ownership, from, into string) error {
+	from, into = normalizeName(from), gs.Resolve(ownScope(o), into)
+	if from == "" || into == "" || from == into {
+		return fmt.Errorf("cannot merge %q into %q", from, into)
+	}
+	tx, err := gs.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	const own = ` AND namespace = ? AND owner = ?`
+	for _, q := range []string{
+		`UPDATE OR IGNORE memory_relations SET source = ? WHERE source = ?` + own,
+		`UPDATE OR IGNORE memory_relations SET target = ? WHERE target = ?` + own,
+		`UPDATE memory_entity_aliases SET entity = ? WHERE entity = ?` + own,
+	} {
+		if _, err := tx.Exec(q, into, from, o.Namespace, o.Owner); err != nil {
+			return err
+		}
+	}
+	// Duplicates UPDATE OR IGNORE skipped; the ones kept on into win
+	if _, err := tx.Exec(`DELETE FROM memory_relations WHERE (source = ? OR target = ?)`+own, from, from, o.Namespace, o.Owner); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		UPDATE memory_entities SET
+			description = COALESCE(NULLIF(description, ''), (SELECT description FROM memory_entities WHERE name = ?`+own+`)),
+			updated_at = ?
+		WHERE name = ?`+own,
+		from, o.Namespace, o.Owner, time.Now().Unix(), into, o.Namespace, o.Owner); err != nil {
+		return err
+	}
+	// Without a row of its own for into, o's row of from becomes it
+	if _, err := tx.Exec(`UPDATE OR IGNORE memory_entities SET name = ? WHERE name = ?`+own, into, from, o.Namespace, o.Owner); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM memory_entities WHERE name = ?`+own, from, o.Namespace, o.Owner); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO memory_entity_aliases (alias, entity, namespace, owner) VALUES (?, ?, ?, ?)`,
+		from, into, o.Namespace, o.Owner); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Aliases lists the aliases that resolve to entity within the scope (nil: any)
+func (gs *GraphStore) Aliases(sc *Scope, entity string) ([]string, error) {
+	cond, args := graphScope(sc)
+	rows, err := gs.db.Query(`
+		SELECT DISTINCT alias FROM memory_entity_aliases WHERE entity = ? AND `+cond+`
+		ORDER BY alias
+	`, append([]any{gs.Resolve(sc, entity)}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var aliases []string
+	for rows.Next() {
+		var a string
+		if err := rows.Scan(&a); err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases, rows.Err()
+}
+
+// AddEntity adds or updates a global entity
 func (gs *GraphStore) AddEntity(name, entityType, description string) error {
-	name = strings.ToLower(strings.TrimSpace(name))
+	return gs.AddEntityOwned(Ownership{}, name, entityType, description)
+}
+
+// AddEntityOwned adds or updates what the ownership's owner knows about an
+// entity; other owners do not see its type or description
+func (gs *GraphStore) AddEntityOwned(o Ownership, name, entityType, description string) error {
+	name = gs.Resolve(ownScope(o), name)
 	if name == "" {
 		return fmt.Errorf("entity name cannot be empty")
 	}
@@ -89,23 +289,30 @@ func (gs *GraphStore) AddEntity(name, entityType, description string) error {
 	now := time.Now().Unix()
 	id := uuid.New().String()
 
+	// Empty fields keep what is already known
 	_, err := gs.db.Exec(`
-		INSERT INTO memory_entities (id, name, type, description, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)
-		ON CONFLICT(name) DO UPDATE SET
-			type = excluded.type,
-			description = excluded.description,
+		INSERT INTO memory_entities (id, name, type, description, namespace, owner, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(name, namespace, owner) DO UPDATE SET
+			type = COALESCE(NULLIF(excluded.type, ''), type),
+			description = COALESCE(NULLIF(excluded.description, ''), description),
 			updated_at = excluded.updated_at
-	`, id, name, entityType, description, now, now)
+	`, id, name, entityType, description, o.Namespace, o.Owner, now, now)
 
 	return err
 }
 
-// AddRelation adds or updates a relation between two entities
+// AddRelation adds or updates a global relation between two entities
 func (gs *GraphStore) AddRelation(source, target, relation string, weight float64) error {
-	source = strings.ToLower(strings.TrimSpace(source))
-	target = strings.ToLower(strings.TrimSpace(target))
-	relation = strings.ToLower(strings.TrimSpace(relation))
+	return gs.AddRelationOwned(Ownership{}, source, target, relation, weight)
+}
+
+// AddRelationOwned adds or updates a relation visible in the ownership's
+// namespace to its owner (SharedWith is not kept for relations)
+func (gs *GraphStore) AddRelationOwned(o Ownership, source, target, relation string, weight float64) error {
+	source = gs.Resolve(ownScope(o), source)
+	target = gs.Resolve(ownScope(o), target)
+	relation = strings.ReplaceAll(normalizeName(relation), " ", "_")
 
 	if source == "" || target == "" || relation == "" {
 		return fmt.Errorf("source, target, and relation cannot be empty")
@@ -115,26 +322,35 @@ func (gs *GraphStore) AddRelation(source, target, relation string, weight float6
 	id := uuid.New().String()
 
 	_, err := gs.db.Exec(`
-		INSERT INTO memory_relations (id, source, target, relation, weight, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT(source, target, relation) DO UPDATE SET
+		INSERT INTO memory_relations (id, source, target, relation, weight, namespace, owner, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(source, target, relation, namespace, owner) DO UPDATE SET
 			weight = excluded.weight,
 			updated_at = excluded.updated_at
-	`, id, source, target, relation, weight, now, now)
+	`, id, source, target, relation, weight, o.Namespace, o.Owner, now, now)
 
 	return err
 }
 
-// GetEntity gets an entity by name
+// GetEntity gets an entity by name or alias
 func (gs *GraphStore) GetEntity(name string) (*Entity, error) {
-	name = strings.ToLower(strings.TrimSpace(name))
-	
+	return gs.GetEntityScope(nil, name)
+}
+
+// GetEntityScope gets an entity by name or alias within a scope (nil: all),
+// preferring a row with a description, then the scope owner's own
+func (gs *GraphStore) GetEntityScope(sc *Scope, name string) (*Entity, error) {
+	name = gs.Resolve(sc, name)
+	cond, args := graphScope(sc)
+
 	var e Entity
 	err := gs.db.QueryRow(`
-		SELECT id, name, type, description, created_at, updated_at
-		FROM memory_entities WHERE name = ?
-	`, name).Scan(&e.ID, &e.Name, &e.Type, &e.Description, &e.CreatedAt, &e.UpdatedAt)
-	
+		SELECT id, name, type, COALESCE(description, ''), namespace, owner, created_at, updated_at
+		FROM memory_entities WHERE name = ? AND `+cond+`
+		ORDER BY COALESCE(description, '') = '', owner = ? DESC
+		LIMIT 1
+	`, append(append([]any{name}, args...), scopeOwner(sc))...).Scan(&e.ID, &e.Name, &e.Type, &e.Description, &e.Namespace, &e.Owner, &e.CreatedAt, &e.UpdatedAt)
+
 	if err == sql.ErrNoRows {
 		return nil, nil // Not found
 	}
@@ -146,16 +362,25 @@ func (gs *GraphStore) GetEntity(name string) (*Entity, error) {
 
 // SearchRelations finds relations connected to a specific entity
 func (gs *GraphStore) SearchRelations(entityName string) ([]Relation, error) {
-	entityName = strings.ToLower(strings.TrimSpace(entityName))
-	
-	rows, err := gs.db.Query(`
-		SELECT id, source, target, relation, weight, created_at, updated_at
-		FROM memory_relations 
-		WHERE source = ? OR target = ?
+	return gs.SearchRelationsScope(nil, entityName)
+}
+
+// SearchRelationsScope finds the relations of an entity within a scope (nil: all)
+func (gs *GraphStore) SearchRelationsScope(sc *Scope, entityName string) ([]Relation, error) {
+	entityName = gs.Resolve(sc, entityName)
+	cond, args := graphScope(sc)
+
+	return gs.queryRelations(`
+		SELECT id, source, target, relation, weight, namespace, owner, created_at, updated_at
+		FROM memory_relations
+		WHERE (source = ? OR target = ?) AND `+cond+`
 		ORDER BY weight DESC, updated_at DESC
 		LIMIT 50
-	`, entityName, entityName)
-	
+	`, append([]any{entityName, entityName}, args...)...)
+}
+
+func (gs *GraphStore) queryRelations(query string, args ...any) ([]Relation, error) {
+	rows, err := gs.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -164,10 +389,29 @@ func (gs *GraphStore) SearchRelations(entityName string) ([]Relation, error) {
 	var rels []Relation
 	for rows.Next() {
 		var r Relation
-		if err := rows.Scan(&r.ID, &r.Source, &r.Target, &r.Relation, &r.Weight, &r.CreatedAt, &r.UpdatedAt); err != nil {
+		if err := rows.Scan(&r.ID, &r.Source, &r.Target, &r.Relation, &r.Weight, &r.Namespace, &r.Owner, &r.CreatedAt, &r.UpdatedAt); err != nil {
 			return nil, err
 		}
 		rels = append(rels, r)
 	}
-	return rels, nil
+	return rels, rows.Err()
+}
+
+// graphScope is Scope.where for the graph tables, which have no sharing list
+func graphScope(sc *Scope) (string, []any) {
+	if sc == nil {
+		return "1=1", nil
+	}
+	rs := *sc
+	rs.Shared = false
+	return rs.where("")
+}
+
+// scopeOwner is the owner whose rows a lookup in sc prefers; global rows
+// when sc is nil
+func scopeOwner(sc *Scope) string {
+	if sc == nil {
+		return ""
+	}
+	return sc.Owner
 }
@@ -0,0 +1,157 @@
+package memory
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGraphIngestAndTraverse(t *testing.T) {
+	store, err := NewVectorMemoryStore(filepath.Join(t.TempDir(), "vec.db"), Config{EmbeddingDim: 3})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+	g := store.Graph
+
+	if err := g.AddEntity("Robert Smith", "person", "backend lead"); err != nil {
+		t.Fatal(err)
+	}
+	alice := Ownership{Owner: "telegram_alice"}
+	_, n, err := g.Ingest(alice, Extraction{
+		Entities: []ExtractedEntity{
+			{Name: "Bob", Type: "person", Aliases: []string{"Robert  Smith"}},
+			{Name: "Project Atlas", Type: "project", Description: "codename for the merger", Aliases: []string{"atlas"}},
+		},
+		Relations: []ExtractedRelation{
+			{Source: "bob", Target: "atlas", Relation: "works on", Weight: 0.9},
+			{Source: "Atlas", Target: "Postgres", Relation: "depends_on", Weight: 0.8},
+			{Source: "Postgres", Target: "AWS", Relation: "runs_on", Weight: 0.2},
+			{Source: "Project Atlas", Target: "AWS", Relation: "deployed_on", Weight: 0.1},
+		},
+	})
+	if err != nil || n != 4 {
+		t.Fatalf("ingest = %d relations, %v", n, err)
+	}
+	store.Graph.AddRelation("aws", "amazon", "owned_by", 1)
+	store.Graph.AddRelationOwned(Ownership{Owner: "telegram_carol"}, "bob", "carol", "manages", 1)
+
+	// Aliases resolve to the entity that was already known
+	if e, _ := g.GetEntity("BOB"); e == nil || e.Name != "robert smith" || e.Description != "backend lead" {
+		t.Errorf("GetEntity(bob) = %+v", e)
+	}
+	sc := UserScope("", "telegram_alice")
+	if found, _ := g.EntitiesIn(&sc, "Is Bob still on Atlas? He's good at Go", 0); strings.Join(found, ",") != "project atlas,robert smith" {
+		t.Errorf("EntitiesIn = %v", found)
+	}
+
+	// What Alice's turns taught the graph stays hers; others cannot redirect it
+	carol := Ownership{Owner: "telegram_carol"}
+	carolScope := UserScope("", "telegram_carol")
+	if found, _ := g.EntitiesIn(&carolScope, "Is Bob still on Atlas?", 0); len(found) != 0 {
+		t.Errorf("carol sees alice's entities: %v", found)
+	}
+	if e, _ := g.GetEntityScope(&carolScope, "project atlas"); e != nil {
+		t.Errorf("carol gets alice's entity: %+v", e)
+	}
+	if e, _ := g.GetEntityScope(&sc, "atlas"); e == nil || e.Description != "codename for the merger" {
+		t.Errorf("alice's entity = %+v", e)
+	}
+	if err := g.AddAlias(carol, "bob", "carol"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Resolve(&sc, "bob") != "robert smith" || g.Resolve(&carolScope, "bob") != "carol" {
+		t.Errorf("bob resolves to %q for alice, %q for carol", g.Resolve(&sc, "bob"), g.Resolve(&carolScope, "bob"))
+	}
+
+	// Neighborhoods follow both directions and stay within the scope
+	sub, err := g.Neighborhood(&sc, []string{"bob"}, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	facts := strings.Join(sub.Facts(), "\n")
+	if want := "robert smith -[works_on]-> project atlas\nproject atlas -[depends_on]-> postgres\nproject atlas -[deployed_on]-> aws"; facts != want {
+		t.Errorf("neighborhood facts:\n%s\nwant:\n%s", facts, want)
+	}
+	if len(sub.Entities) != 4 || sub.Entities[0].Type != "person" {
+		t.Errorf("neighborhood entities = %+v", sub.Entities)
+	}
+
+	// Fewest hops, or strongest relations when weighted
+	path, err := g.Path(&sc, "bob", "amazon", 4, false)
+	if err != nil || len(path) != 3 || path[1].Relation != "deployed_on" {
+		t.Errorf("path = %v, %v", path, err)
+	}
+	path, err = g.Path(&sc, "bob", "amazon", 4, true)
+	if err != nil || len(path) != 4 || path[2].Relation != "runs_on" {
+		t.Errorf("weighted path = %v, %v", path, err)
+	}
+	if path, _ := g.Path(&sc, "bob", "amazon", 2, true); path != nil {
+		t.Errorf("path beyond maxHops = %v", path)
+	}
+	if path, _ := g.Path(&sc, "bob", "carol", 4, false); path != nil {
+		t.Errorf("path through another owner's relation = %v", path)
+	}
+
+	// Making an entity an alias merges it
+	g.AddEntity("pg", "technology", "")
+	g.AddRelation("pg", "amazon", "hosted_by", 1)
+	if err := g.AddAlias(Ownership{}, "pg", "postgres"); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := g.GetEntity("pg"); e == nil || e.Name != "postgres" {
+		t.Errorf("merged entity = %+v", e)
+	}
+	if rels, _ := g.SearchRelations("amazon"); len(rels) != 2 {
+		t.Errorf("relations after merge = %v", rels)
+	}
+
+	// Pending memories until marked, and again once rewritten
+	id, _ := store.Store("Bob works on Atlas", "fact", 0.5)
+	if pending, _ := store.GraphPending(10); len(pending) != 1 || pending[0].ID != id {
+		t.Fatalf("pending = %+v", pending)
+	}
+	store.MarkGraphExtracted(id)
+	if pending, _ := store.GraphPending(10); len(pending) != 0 {
+		t.Errorf("pending after mark = %+v", pending)
+	}
+}
+
+func TestGraphMigratesUnscopedRows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vec.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`CREATE TABLE memory_relations (
+		id TEXT PRIMARY KEY, source TEXT NOT NULL, target TEXT NOT NULL, relation TEXT NOT NULL,
+		weight REAL DEFAULT 1.0, created_at INTEGER, updated_at INTEGER,
+		UNIQUE(source, target, relation))`); err != nil {
+		t.Fatal(err)
+	}
+	db.Exec(`INSERT INTO memory_relations VALUES ('r1', 'bob', 'atlas', 'works_on', 1, 0, 0)`)
+	if _, err := db.Exec(`CREATE TABLE memory_entities (
+		id TEXT PRIMARY KEY, name TEXT NOT NULL UNIQUE, type TEXT NOT NULL, description TEXT,
+		created_at INTEGER, updated_at INTEGER)`); err != nil {
+		t.Fatal(err)
+	}
+	db.Exec(`INSERT INTO memory_entities VALUES ('e1', 'bob', 'person', 'backend lead', 0, 0)`)
+	db.Close()
+
+	store, err := NewVectorMemoryStore(path, Config{EmbeddingDim: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if err := store.Graph.AddRelationOwned(Ownership{Owner: "telegram_1"}, "bob", "atlas", "works_on", 1); err != nil {
+		t.Fatal(err)
+	}
+	rels, err := store.Graph.SearchRelations("bob")
+	if err != nil || len(rels) != 2 {
+		t.Fatalf("relations = %+v, %v", rels, err)
+	}
+	if e, err := store.Graph.GetEntity("bob"); err != nil || e == nil || e.Description != "backend lead" || e.Owner != LegacyOwner {
+		t.Errorf("migrated entity = %+v, %v", e, err)
+	}
+}
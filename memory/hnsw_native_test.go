@@ -0,0 +1,181 @@
+//go:build !faiss
+
+package memory
+
+import (
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func randomVectors(r *rand.Rand, n, dim int) [][]float32 {
+	vecs := make([][]float32, n)
+	for i := range vecs {
+		vecs[i] = make([]float32, dim)
+		for j := range vecs[i] {
+			vecs[i][j] = r.Float32()*2 - 1
+		}
+	}
+	return vecs
+}
+
+func TestHNSWRecall(t *testing.T) {
+	const n, dim, k = 3000, 32, 10
+	r := rand.New(rand.NewSource(1))
+	vecs := randomVectors(r, n, dim)
+
+	for _, metric := range []string{"l2", "cosine", "ip"} {
+		idx, err := NewHNSWIndex(HNSWConfig{Dim: dim, M: 12, EfConstruct: 100, EfSearch: 64, Distance: metric})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := idx.Add(vecs); err != nil {
+			t.Fatal(err)
+		}
+
+		hits, total := 0, 0
+		for _, q := range randomVectors(r, 50, dim) {
+			q := idx.prepare(q)
+			exact := make([]int, n)
+			for i := range exact {
+				exact[i] = i
+			}
+			sort.Slice(exact, func(a, b int) bool {
+				return idx.distance(q, idx.nodes[exact[a]].vec) < idx.distance(q, idx.nodes[exact[b]].vec)
+			})
+			want := make(map[int64]bool, k)
+			for _, i := range exact[:k] {
+				want[int64(i)] = true
+			}
+
+			_, labels, err := idx.Search(q, k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, l := range labels {
+				if want[l] {
+					hits++
+				}
+			}
+			total += k
+		}
+		if recall := float64(hits) / float64(total); recall < 0.9 {
+			t.Errorf("%s: recall@%d = %.2f, want >= 0.9", metric, k, recall)
+		}
+	}
+}
+
+func TestHNSWRemove(t *testing.T) {
+	idx, err := NewHNSWIndex(HNSWConfig{Dim: 8, Distance: "cosine"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vecs := randomVectors(rand.New(rand.NewSource(2)), 200, 8)
+	if err := idx.Add(vecs); err != nil {
+		t.Fatal(err)
+	}
+
+	for label := int64(0); label < 200; label += 2 {
+		if err := idx.Remove(label); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := idx.Remove(200); err == nil {
+		t.Error("Remove accepted an unknown label")
+	}
+	if idx.Count() != 200 {
+		t.Errorf("Count() = %d, labels are not reused", idx.Count())
+	}
+
+	distances, labels, err := idx.Search(vecs[10], 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 20 {
+		t.Fatalf("got %d results, want 20", len(labels))
+	}
+	for i, l := range labels {
+		if l%2 == 0 {
+			t.Errorf("removed label %d returned", l)
+		}
+		if i > 0 && distances[i] < distances[i-1] {
+			t.Errorf("results not ordered: %v", distances)
+		}
+	}
+
+	// Filters compose with removals: only labels divisible by 3 of the odd ones
+	_, labels, _ = idx.SearchFilter(vecs[10], 20, func(l int64) bool { return l%3 == 0 })
+	if len(labels) != 20 {
+		t.Fatalf("filtered search got %d results, want 20", len(labels))
+	}
+	for _, l := range labels {
+		if l%2 == 0 || l%3 != 0 {
+			t.Errorf("filtered search returned label %d", l)
+		}
+	}
+}
+
+func TestHNSWSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vector.index")
+	cfg := HNSWConfig{Dim: 16, M: 8, EfConstruct: 64, Distance: "l2", StoragePath: path}
+	idx, err := NewHNSWIndex(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx.Loaded() {
+		t.Fatal("new index reports loaded")
+	}
+	vecs := randomVectors(rand.New(rand.NewSource(3)), 500, 16)
+	if err := idx.Add(vecs); err != nil {
+		t.Fatal(err)
+	}
+	idx.Remove(7)
+	if err := idx.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// M and efConstruction come from the file
+	reopened, err := NewHNSWIndex(HNSWConfig{Dim: 16, Distance: "l2", StoragePath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reopened.Loaded() || reopened.Count() != 500 || reopened.Config().M != 8 {
+		t.Fatalf("reopened: loaded=%v count=%d M=%d", reopened.Loaded(), reopened.Count(), reopened.Config().M)
+	}
+	for _, q := range [][]float32{vecs[7], vecs[42]} {
+		wantD, wantL, _ := idx.Search(q, 5)
+		gotD, gotL, _ := reopened.Search(q, 5)
+		for i := range wantL {
+			if gotL[i] != wantL[i] || gotD[i] != wantD[i] {
+				t.Fatalf("search after load = %v %v, want %v %v", gotL, gotD, wantL, wantD)
+			}
+		}
+	}
+
+	if err := (&HNSWIndex{cfg: HNSWConfig{Dim: 8, Distance: "l2"}}).Load(path); err == nil {
+		t.Error("loaded an index of another dimension")
+	}
+	if err := (&HNSWIndex{cfg: HNSWConfig{Dim: 16, Distance: "ip"}}).Load(path); err == nil {
+		t.Error("loaded an index with another metric")
+	}
+
+	// A link to a node that is not on the link's level is corrupt
+	top := idx.nodes[idx.entry]
+	if len(top.links) < 2 || len(top.links[1]) == 0 {
+		t.Fatal("entry has no level 1 links")
+	}
+	for j, n := range idx.nodes {
+		if len(n.links) == 1 {
+			top.links[1][0] = int32(j)
+			break
+		}
+	}
+	if err := idx.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&HNSWIndex{cfg: cfg}).Load(path); err == nil || !strings.Contains(err.Error(), "has no level") {
+		t.Errorf("loaded a link below its level: %v", err)
+	}
+}
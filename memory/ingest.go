@@ -0,0 +1,561 @@
+// ingest.go - Document ingestion: files become cited chunk memories, re-ingested incrementally
+package memory
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DocumentCategory is the category of memories holding document chunks
+const DocumentCategory = "document"
+
+// maxDocumentSize is the default limit above which files are skipped
+const maxDocumentSize = 10 << 20
+
+// Citation locates a document chunk in its file. Offsets and lines refer to
+// the file itself, except for PDFs, where they refer to the extracted text
+// and Page is set
+type Citation struct {
+	Path      string
+	Start     int // Byte offsets
+	End       int
+	StartLine int // 1-based, inclusive
+	EndLine   int
+	Page      int // 1-based PDF page, 0 for other documents
+	Heading   string
+	Hash      string // sha256 of the chunk text
+}
+
+// String formats the citation as "path:12-40", "path:12" or, for PDFs,
+// "path p.3"
+func (c *Citation) String() string {
+	if c.Page > 0 {
+		return fmt.Sprintf("%s p.%d", c.Path, c.Page)
+	}
+	if c.EndLine > c.StartLine {
+		return fmt.Sprintf("%s:%d-%d", c.Path, c.StartLine, c.EndLine)
+	}
+	return fmt.Sprintf("%s:%d", c.Path, c.StartLine)
+}
+
+// citation returns where a document chunk memory comes from, nil when the
+// memory is not one
+func (s *VectorMemoryStore) citation(id string) *Citation {
+	c := &Citation{}
+	err := s.db.QueryRow(`
+		SELECT path, start_offset, end_offset, start_line, end_line, page, heading, hash
+		FROM memory_doc_chunks WHERE memory_id = ?
+	`, id).Scan(&c.Path, &c.Start, &c.End, &c.StartLine, &c.EndLine, &c.Page, &c.Heading, &c.Hash)
+	if err != nil {
+		return nil
+	}
+	return c
+}
+
+// attachCitations sets the citation of document chunks among results
+func (s *VectorMemoryStore) attachCitations(results []MemoryResult) {
+	for i := range results {
+		if results[i].Entry.Category == DocumentCategory && results[i].Entry.Citation == nil {
+			results[i].Entry.Citation = s.citation(results[i].Entry.ID)
+		}
+	}
+}
+
+// IngestOptions controls how documents are ingested
+type IngestOptions struct {
+	Owner       Ownership    // Who sees the chunks (zero value: global)
+	Chunk       ChunkOptions // Chunk sizes
+	MaxFileSize int64        // Larger files are skipped (default 10 MB)
+	Force       bool         // Re-chunk files whose content did not change
+}
+
+// FileIngest is the outcome of ingesting one file
+type FileIngest struct {
+	Path    string
+	Status  string // added, updated, unchanged, removed, skipped or failed
+	Chunks  int    // Chunks the file has now
+	Added   int    // Chunks embedded by this run
+	Removed int    // Chunks deleted by this run
+	Reason  string // Why the file was skipped or failed
+}
+
+// IngestReport lists what an ingestion did, file by file
+type IngestReport struct {
+	Files []FileIngest
+}
+
+// Count returns how many files ended with status
+func (r *IngestReport) Count(status string) int {
+	n := 0
+	for _, f := range r.Files {
+		if f.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// String summarizes the report, listing every file that was not unchanged
+func (r *IngestReport) String() string {
+	added, removed := 0, 0
+	for _, f := range r.Files {
+		added += f.Added
+		removed += f.Removed
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d files: %d added, %d updated, %d unchanged, %d removed, %d skipped, %d failed (%d chunks embedded, %d deleted)",
+		len(r.Files), r.Count("added"), r.Count("updated"), r.Count("unchanged"), r.Count("removed"),
+		r.Count("skipped"), r.Count("failed"), added, removed)
+	for _, f := range r.Files {
+		switch f.Status {
+		case "unchanged":
+		case "skipped", "failed":
+			fmt.Fprintf(&sb, "\n%s %s: %s", f.Status, f.Path, f.Reason)
+		default:
+			fmt.Fprintf(&sb, "\n%s %s (%d chunks, +%d -%d)", f.Status, f.Path, f.Chunks, f.Added, f.Removed)
+		}
+	}
+	return sb.String()
+}
+
+// Ingest reads the files that patterns name (files, directories walked
+// recursively, or globs where "**" spans directories) and stores them as
+// document chunk memories. Unchanged files are skipped, changed ones only
+// re-embed the chunks whose text changed, and documents opts.Owner ingested
+// earlier under a pattern whose files are gone are removed. Each owner has
+// its own copy of a document, so ingesting never touches other owners'
+func (s *VectorMemoryStore) Ingest(patterns []string, opts IngestOptions) (*IngestReport, error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("no paths to ingest")
+	}
+	s.ingestMu.Lock()
+	defer s.ingestMu.Unlock()
+
+	report := &IngestReport{}
+	seen := make(map[string]bool)
+	var matchers []func(string) bool
+	for _, pattern := range patterns {
+		files, match, err := expandPattern(pattern)
+		if err != nil {
+			report.Files = append(report.Files, FileIngest{Path: pattern, Status: "failed", Reason: err.Error()})
+			continue
+		}
+		matchers = append(matchers, match)
+		for _, f := range files {
+			if seen[f] {
+				continue
+			}
+			seen[f] = true
+			report.Files = append(report.Files, s.ingestFile(f, opts))
+		}
+	}
+
+	known, err := s.documentPaths(opts.Owner)
+	if err != nil {
+		return report, err
+	}
+	for _, path := range known {
+		if seen[path] {
+			continue
+		}
+		for _, match := range matchers {
+			if !match(path) {
+				continue
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				break
+			}
+			n, err := s.ForgetDocument(opts.Owner, path)
+			if err != nil {
+				return report, err
+			}
+			report.Files = append(report.Files, FileIngest{Path: path, Status: "removed", Removed: n})
+			break
+		}
+	}
+	log.Printf("[INGEST] %s", strings.SplitN(report.String(), "\n", 2)[0])
+	return report, nil
+}
+
+// IngestFile ingests a single file, see Ingest
+func (s *VectorMemoryStore) IngestFile(path string, opts IngestOptions) (FileIngest, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return FileIngest{Path: path, Status: "failed", Reason: err.Error()}, err
+	}
+	s.ingestMu.Lock()
+	defer s.ingestMu.Unlock()
+	res := s.ingestFile(abs, opts)
+	if res.Status == "failed" {
+		return res, fmt.Errorf("%s: %s", abs, res.Reason)
+	}
+	return res, nil
+}
+
+// ingestFile brings the chunks of one file (an absolute path) up to date
+func (s *VectorMemoryStore) ingestFile(path string, opts IngestOptions) FileIngest {
+	res := FileIngest{Path: path}
+	fail := func(status string, err error) FileIngest {
+		res.Status, res.Reason = status, err.Error()
+		return res
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fail("failed", err)
+	}
+	limit := opts.MaxFileSize
+	if limit <= 0 {
+		limit = maxDocumentSize
+	}
+	if info.Size() > limit {
+		return fail("skipped", fmt.Errorf("larger than %d bytes", limit))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fail("failed", err)
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	o := opts.Owner
+	var oldHash string
+	exists := s.db.QueryRow(`SELECT hash FROM memory_documents WHERE path = ? AND namespace = ? AND owner = ?`,
+		path, o.Namespace, o.Owner).Scan(&oldHash) == nil
+	if exists && oldHash == hash && !opts.Force {
+		s.db.QueryRow(`SELECT COUNT(*) FROM memory_doc_chunks WHERE path = ? AND namespace = ? AND owner = ?`,
+			path, o.Namespace, o.Owner).Scan(&res.Chunks)
+		res.Status = "unchanged"
+		return res
+	}
+
+	kind := documentKind(path, data)
+	if kind == "" {
+		return fail("skipped", fmt.Errorf("binary file"))
+	}
+	chunks, err := chunkDocument(kind, data, opts.Chunk)
+	if err != nil {
+		return fail("failed", err)
+	}
+
+	// The owner's existing chunks by content hash, reused when the text is unchanged
+	reusable := make(map[string][]string)
+	rows, err := s.db.Query(`SELECT memory_id, hash FROM memory_doc_chunks WHERE path = ? AND namespace = ? AND owner = ?`,
+		path, o.Namespace, o.Owner)
+	if err != nil {
+		return fail("failed", err)
+	}
+	for rows.Next() {
+		var id, h string
+		if rows.Scan(&id, &h) == nil {
+			reusable[h] = append(reusable[h], id)
+		}
+	}
+	rows.Close()
+
+	defer func() {
+		if res.Added > 0 {
+			s.saveHNSW()
+		} else {
+			s.flushHNSW()
+		}
+	}()
+	for _, c := range chunks {
+		h := sha256.Sum256([]byte(c.text))
+		chunkHash := hex.EncodeToString(h[:])
+		if ids := reusable[chunkHash]; len(ids) > 0 {
+			reusable[chunkHash] = ids[1:]
+			if _, err := s.db.Exec(`
+				UPDATE memory_doc_chunks SET start_offset = ?, end_offset = ?, start_line = ?, end_line = ?, page = ?, heading = ?
+				WHERE memory_id = ?
+			`, c.Start, c.End, c.StartLine, c.EndLine, c.page, c.Heading, ids[0]); err != nil {
+				return fail("failed", err)
+			}
+			continue
+		}
+		id, err := s.insert(o, c.text, DocumentCategory, 0.5, path)
+		if err != nil {
+			return fail("failed", err)
+		}
+		if _, err := s.db.Exec(`
+			INSERT INTO memory_doc_chunks (memory_id, path, namespace, owner, start_offset, end_offset, start_line, end_line, page, heading, hash)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, id, path, o.Namespace, o.Owner, c.Start, c.End, c.StartLine, c.EndLine, c.page, c.Heading, chunkHash); err != nil {
+			return fail("failed", err)
+		}
+		res.Added++
+	}
+	for _, ids := range reusable {
+		for _, id := range ids {
+			s.Delete(id)
+			s.db.Exec(`DELETE FROM memory_doc_chunks WHERE memory_id = ?`, id)
+			res.Removed++
+		}
+	}
+
+	if _, err := s.db.Exec(`
+		INSERT OR REPLACE INTO memory_documents (path, kind, hash, size, mod_time, chunks, namespace, owner, ingested_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, path, kind, hash, info.Size(), info.ModTime().Unix(), len(chunks), o.Namespace, o.Owner, time.Now().Unix()); err != nil {
+		return fail("failed", err)
+	}
+	res.Chunks = len(chunks)
+	res.Status = "added"
+	if exists {
+		res.Status = "updated"
+	}
+	return res
+}
+
+// ForgetDocument deletes the copy of an ingested document that o's
+// namespace and owner hold, and its chunk memories, returning how many
+// chunks were deleted
+func (s *VectorMemoryStore) ForgetDocument(o Ownership, path string) (int, error) {
+	rows, err := s.db.Query(`SELECT memory_id FROM memory_doc_chunks WHERE path = ? AND namespace = ? AND owner = ?`,
+		path, o.Namespace, o.Owner)
+	if err != nil {
+		return 0, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if rows.Scan(&id) == nil {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+
+	defer s.flushHNSW()
+	for _, id := range ids {
+		if _, err := s.Delete(id); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := s.db.Exec(`DELETE FROM memory_doc_chunks WHERE path = ? AND namespace = ? AND owner = ?`,
+		path, o.Namespace, o.Owner); err != nil {
+		return 0, err
+	}
+	_, err = s.db.Exec(`DELETE FROM memory_documents WHERE path = ? AND namespace = ? AND owner = ?`,
+		path, o.Namespace, o.Owner)
+	return len(ids), err
+}
+
+// documentPaths lists the paths of the documents o's namespace and owner
+// ingested
+func (s *VectorMemoryStore) documentPaths(o Ownership) ([]string, error) {
+	rows, err := s.db.Query(`SELECT path FROM memory_documents WHERE namespace = ? AND owner = ? ORDER BY path`,
+		o.Namespace, o.Owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, rows.Err()
+}
+
+// ==================== Documents ====================
+
+// codeExtensions are the source files chunked at declarations
+var codeExtensions = map[string]bool{
+	".go": true, ".py": true, ".rb": true, ".js": true, ".jsx": true, ".mjs": true, ".ts": true, ".tsx": true,
+	".rs": true, ".java": true, ".kt": true, ".cs": true, ".c": true, ".h": true, ".cc": true, ".cpp": true,
+	".hpp": true, ".swift": true, ".php": true, ".scala": true, ".lua": true, ".sh": true,
+}
+
+// documentKind tells how a file is read: markdown, html, pdf, code or
+// text, or "" for binary files
+func documentKind(path string, data []byte) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch {
+	case ext == ".md" || ext == ".markdown" || ext == ".mdx":
+		return "markdown"
+	case ext == ".html" || ext == ".htm" || ext == ".xhtml":
+		return "html"
+	case ext == ".pdf" || bytes.HasPrefix(data, []byte("%PDF")):
+		return "pdf"
+	case bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data):
+		return ""
+	case codeExtensions[ext]:
+		return "code"
+	}
+	return "text"
+}
+
+// docChunk is a chunk with the text stored as its memory and its PDF page
+type docChunk struct {
+	Chunk
+	text string
+	page int
+}
+
+// chunkDocument reads a document of kind and cuts it into chunks
+func chunkDocument(kind string, data []byte, opts ChunkOptions) ([]docChunk, error) {
+	var chunks []Chunk
+	src := string(data)
+	switch kind {
+	case "markdown":
+		chunks = chunkText(src, markdownBoundaries(src), false, opts)
+	case "html":
+		view, bounds := htmlView(src)
+		chunks = chunkText(view, bounds, false, opts)
+	case "code":
+		chunks = chunkText(src, codeBoundaries(src), true, opts)
+	case "pdf":
+		text, err := pdfText(data)
+		if err != nil {
+			return nil, err
+		}
+		src = text
+		chunks = chunkText(text, pageBoundaries(text), false, opts)
+	default:
+		chunks = chunkText(src, nil, false, opts)
+	}
+
+	out := make([]docChunk, 0, len(chunks))
+	for _, c := range chunks {
+		dc := docChunk{Chunk: c, text: c.Text}
+		switch kind {
+		case "html":
+			dc.text = cleanHTMLText(c.Text)
+		case "pdf":
+			dc.page = strings.Count(src[:c.Start], "\f") + 1
+			dc.text = strings.ReplaceAll(c.Text, "\f", "\n")
+		}
+		if dc.text == "" {
+			continue
+		}
+		dc.text = withHeading(dc.text, c.Heading)
+		out = append(out, dc)
+	}
+	return out, nil
+}
+
+// pageBoundaries makes each page of extracted PDF text a section
+func pageBoundaries(text string) []boundary {
+	var bounds []boundary
+	for i, off := 1, 0; off < len(text); i++ {
+		bounds = append(bounds, boundary{offset: off, level: 1, title: fmt.Sprintf("Page %d", i)})
+		next := strings.IndexByte(text[off:], '\f')
+		if next < 0 {
+			break
+		}
+		off += next + 1
+	}
+	return bounds
+}
+
+// withHeading prefixes a chunk with its heading path unless the chunk
+// starts with its only heading, so windows cut from the middle of a
+// section keep their context
+func withHeading(text, heading string) string {
+	if heading == "" || strings.HasPrefix(heading, "Page ") {
+		return text
+	}
+	first, _, _ := strings.Cut(text, "\n")
+	if !strings.Contains(heading, " > ") && strings.Contains(first, heading) {
+		return text
+	}
+	return "[" + heading + "]\n" + text
+}
+
+// ==================== Paths ====================
+
+// expandPattern lists the regular files a path, directory or glob names and
+// returns a matcher for the documents that fall under it, so ingested
+// files that were deleted since can be found
+func expandPattern(pattern string) ([]string, func(string) bool, error) {
+	abs, err := filepath.Abs(pattern)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info, err := os.Stat(abs); err == nil {
+		if !info.IsDir() {
+			return []string{abs}, func(p string) bool { return p == abs }, nil
+		}
+		files, err := walkFiles(abs, nil)
+		return files, func(p string) bool { return strings.HasPrefix(p, abs+string(filepath.Separator)) }, err
+	} else if !strings.ContainsAny(pattern, "*?[") {
+		return nil, nil, err
+	}
+
+	// Walk from the deepest directory without wildcards
+	base := abs
+	for strings.ContainsAny(base, "*?[") {
+		base = filepath.Dir(base)
+	}
+	match := func(p string) bool { return globMatch(filepath.ToSlash(abs), filepath.ToSlash(p)) }
+	files, err := walkFiles(base, match)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+	if len(files) == 0 {
+		return nil, nil, fmt.Errorf("no files match %s", pattern)
+	}
+	return files, match, nil
+}
+
+// walkFiles lists the regular files under root that match (all when match
+// is nil), leaving out hidden files and directories and node_modules
+func walkFiles(root string, match func(string) bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if p != root && (strings.HasPrefix(name, ".") || name == "node_modules") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && (match == nil || match(p)) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// globMatch matches slash-separated paths segment by segment, where a "**"
+// segment matches any number of directories
+func globMatch(pattern, path string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(path, "/"))
+}
+
+func matchSegments(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				if matchSegments(pattern[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 {
+			return false
+		}
+		if ok, _ := filepath.Match(pattern[0], path[0]); !ok {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
@@ -0,0 +1,189 @@
+package memory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChunkMarkdownAndCode(t *testing.T) {
+	md := "Intro line\n\n# Setup\n\nInstall it.\n\n```\n# not a heading\n```\n\n## Linux\n\nUse apt.\n"
+	chunks := chunkText(md, markdownBoundaries(md), false, ChunkOptions{})
+	if len(chunks) != 3 {
+		t.Fatalf("chunks = %+v", chunks)
+	}
+	if c := chunks[2]; c.Heading != "Setup > Linux" || c.StartLine != 11 || c.EndLine != 13 || md[c.Start:c.End] != c.Text {
+		t.Errorf("linux chunk = %+v", c)
+	}
+	if c := chunks[1]; !strings.Contains(c.Text, "# not a heading") || c.StartLine != 3 {
+		t.Errorf("setup chunk = %+v", c)
+	}
+
+	src := "package x\n\n// Add adds\nfunc Add(a, b int) int {\n\treturn a + b\n}\n\nfunc Sub(a, b int) int {\n\treturn a - b\n}\n"
+	code := chunkText(src, codeBoundaries(src), true, ChunkOptions{MaxTokens: 15, Overlap: -1})
+	if len(code) != 3 || code[1].Heading != "func Add(a, b int) int" || code[1].StartLine != 3 || code[2].EndLine != 10 {
+		t.Errorf("code chunks = %+v", code)
+	}
+
+	// Long sections become overlapping windows
+	long := strings.Repeat("word word word word\n", 100)
+	wins := chunkText(long, nil, false, ChunkOptions{MaxTokens: 50, Overlap: 10})
+	if len(wins) < 5 || wins[1].Start >= wins[0].End || wins[1].StartLine <= wins[0].StartLine {
+		t.Errorf("windows = %d, first two %+v %+v", len(wins), wins[0], wins[1])
+	}
+}
+
+func TestHTMLView(t *testing.T) {
+	src := "<html><head><style>p{}</style></head>\n<body><h1>Title</h1>\n<p>Hello &amp; bye</p>\n<script>x()</script></body></html>"
+	view, bounds := htmlView(src)
+	if len(view) != len(src) || len(bounds) != 1 || bounds[0].title != "Title" {
+		t.Fatalf("bounds = %+v", bounds)
+	}
+	chunks := chunkText(view, bounds, false, ChunkOptions{})
+	if len(chunks) != 1 || cleanHTMLText(chunks[0].Text) != "Title\nHello & bye" || chunks[0].StartLine != 2 {
+		t.Errorf("chunks = %+v", chunks)
+	}
+}
+
+func TestPDFText(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("%PDF-1.4\n1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n")
+	sb.WriteString("2 0 obj << /Type /Pages /Kids [3 0 R 5 0 R] /Count 2 >> endobj\n")
+	for i, text := range []string{"(Hello) Tj [(wor) -20 (ld)] TJ", "(Second) Tj T* (page) Tj"} {
+		content := "BT /F1 12 Tf 72 700 Td " + text + " ET"
+		fmt.Fprintf(&sb, "%d 0 obj << /Type /Page /Parent 2 0 R /Contents %d 0 R >> endobj\n", 3+2*i, 4+2*i)
+		fmt.Fprintf(&sb, "%d 0 obj << /Length %d >> stream\n%s\nendstream endobj\n", 4+2*i, len(content), content)
+	}
+	sb.WriteString("trailer << /Root 1 0 R >>\n%%EOF\n")
+
+	text, err := pdfText([]byte(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := strings.Split(text, "\f")
+	if len(pages) != 2 || strings.TrimSpace(pages[0]) != "Helloworld" || !strings.Contains(pages[1], "Second\npage") {
+		t.Errorf("pdf text = %q", text)
+	}
+	if _, err := pdfText([]byte("hello")); err == nil {
+		t.Error("expected error for non-PDF")
+	}
+}
+
+func TestIngestIncremental(t *testing.T) {
+	store, err := NewVectorMemoryStore(filepath.Join(t.TempDir(), "vec.db"), Config{EmbeddingDim: 3})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	dir := t.TempDir()
+	doc := filepath.Join(dir, "docs", "guide.md")
+	os.MkdirAll(filepath.Dir(doc), 0755)
+	os.WriteFile(doc, []byte("# Install\n\nRun the zanzibar installer.\n\n# Usage\n\nStart it.\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "docs", "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "docs", "blob.bin"), []byte{0, 1, 2}, 0644)
+	os.WriteFile(filepath.Join(dir, ".hidden.md"), []byte("# Secret\n"), 0644)
+
+	report, err := store.Ingest([]string{filepath.Join(dir, "**", "*")}, IngestOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Count("added") != 2 || report.Count("skipped") != 1 {
+		t.Fatalf("report:\n%s", report)
+	}
+
+	// Search results cite file and lines
+	results, err := store.Search("zanzibar", 5, 0)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("search = %+v, %v", results, err)
+	}
+	if c := results[0].Entry.Citation; c == nil || c.String() != doc+":1-3" || c.Heading != "Install" {
+		t.Errorf("citation = %+v", c)
+	}
+
+	// Unchanged files are skipped; edits only re-embed changed chunks
+	report, _ = store.Ingest([]string{dir}, IngestOptions{})
+	if report.Count("unchanged") != 2 {
+		t.Errorf("second run:\n%s", report)
+	}
+	os.WriteFile(doc, []byte("Preface.\n\n# Install\n\nRun the zanzibar installer.\n\n# Usage\n\nStart it twice.\n"), 0644)
+	report, _ = store.Ingest([]string{dir}, IngestOptions{})
+	var res FileIngest
+	for _, f := range report.Files {
+		if f.Path == doc {
+			res = f
+		}
+	}
+	if res.Status != "updated" || res.Chunks != 3 || res.Added != 2 || res.Removed != 1 {
+		t.Errorf("update = %+v", res)
+	}
+	results, _ = store.Search("zanzibar", 5, 0)
+	if len(results) != 1 || results[0].Entry.Citation.StartLine != 3 {
+		t.Errorf("moved chunk = %+v", results)
+	}
+
+	// Deleted files are pruned
+	os.Remove(doc)
+	report, _ = store.Ingest([]string{dir}, IngestOptions{})
+	if report.Count("removed") != 1 {
+		t.Errorf("prune:\n%s", report)
+	}
+	if n, _ := store.Count(); n != 2 { // main.go's package clause and func
+		t.Errorf("memories after prune = %d", n)
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/a/**/*.md", "/a/x.md", true},
+		{"/a/**/*.md", "/a/b/c/x.md", true},
+		{"/a/*.md", "/a/b/x.md", false},
+		{"/a/**", "/a/b/x.go", true},
+	} {
+		if got := globMatch(tc.pattern, tc.path); got != tc.want {
+			t.Errorf("globMatch(%q, %q) = %v", tc.pattern, tc.path, got)
+		}
+	}
+}
+
+func TestIngestOwnersKeepTheirCopies(t *testing.T) {
+	store, err := NewVectorMemoryStore(filepath.Join(t.TempDir(), "vec.db"), Config{EmbeddingDim: 3})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	dir := t.TempDir()
+	doc := filepath.Join(dir, "plan.md")
+	os.WriteFile(doc, []byte("# Plan\n\nShip the walrus release.\n"), 0644)
+	alice := IngestOptions{Owner: Ownership{Owner: "telegram_alice"}}
+	bob := IngestOptions{Owner: Ownership{Owner: "telegram_bob"}}
+	if _, err := store.IngestFile(doc, alice); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := store.IngestFile(doc, bob); err != nil || res.Status != "added" || res.Removed != 0 {
+		t.Fatalf("bob's ingest = %+v, %v", res, err)
+	}
+
+	// Bob's edit and pruning leave Alice's copy alone
+	os.WriteFile(doc, []byte("# Plan\n\nShip the narwhal release.\n"), 0644)
+	if res, _ := store.IngestFile(doc, bob); res.Status != "updated" {
+		t.Errorf("bob's update = %+v", res)
+	}
+	os.Remove(doc)
+	if report, _ := store.Ingest([]string{dir}, bob); report.Count("removed") != 1 {
+		t.Errorf("bob's prune:\n%s", report)
+	}
+	results, _ := store.SearchScope(UserScope("", "telegram_alice"), "walrus", 5, 0)
+	if len(results) != 1 || results[0].Entry.Citation == nil {
+		t.Errorf("alice's copy = %+v", results)
+	}
+	if n, _ := store.Count(); n != 1 {
+		t.Errorf("memories = %d, want alice's chunk", n)
+	}
+}
@@ -0,0 +1,121 @@
+// scope.go - Memory ownership: namespaces, owners and sharing
+package memory
+
+import (
+	"log"
+	"slices"
+	"strings"
+)
+
+// LegacyOwner owns memories and graph rows stored before ownership existed.
+// No session has this key, so only unscoped (operator) access sees them
+// until they are given an owner, or "" to make them global
+const LegacyOwner = "@legacy"
+
+// Ownership places a memory: the agent namespace it belongs to, the identity
+// that owns it (usually a session key; "" makes it global) and the other
+// owners it is shared with
+type Ownership struct {
+	Namespace  string
+	Owner      string
+	SharedWith []string
+}
+
+// Scope selects the memories a caller may see: those of Owner in Namespace,
+// plus global ones and those shared with Owner when Global and Shared are set
+type Scope struct {
+	Namespace string
+	Owner     string
+	Global    bool
+	Shared    bool
+}
+
+// UserScope is the default view of a session: its own memories in the
+// namespace plus the global ones and those shared with it
+func UserScope(namespace, owner string) Scope {
+	return Scope{Namespace: namespace, Owner: owner, Global: true, Shared: true}
+}
+
+// Ownership returns the ownership of memories stored under the scope
+func (sc Scope) Ownership() Ownership {
+	return Ownership{Namespace: sc.Namespace, Owner: sc.Owner}
+}
+
+// Allows reports whether a memory with ownership o is in the scope
+func (sc Scope) Allows(o Ownership) bool {
+	if o.Namespace != sc.Namespace {
+		return false
+	}
+	return o.Owner == sc.Owner ||
+		(sc.Global && o.Owner == "") ||
+		(sc.Shared && sc.Owner != "" && slices.Contains(o.SharedWith, sc.Owner))
+}
+
+// where returns the SQL condition matching the scope's rows of
+// vector_memories, with columns qualified by prefix ("m." or "")
+func (sc *Scope) where(prefix string) (string, []any) {
+	if sc == nil {
+		return "1=1", nil
+	}
+	cond := prefix + "namespace = ? AND (" + prefix + "owner = ?"
+	args := []any{sc.Namespace, sc.Owner}
+	if sc.Global {
+		cond += " OR " + prefix + "owner = ''"
+	}
+	if sc.Shared && sc.Owner != "" {
+		cond += " OR instr(" + prefix + "shared_with, ?) > 0"
+		args = append(args, ","+sc.Owner+",")
+	}
+	return cond + ")", args
+}
+
+// joinOwners encodes SharedWith for the shared_with column as ",a,b," so a
+// single owner can be matched with instr()
+func joinOwners(owners []string) string {
+	var kept []string
+	for _, o := range owners {
+		if o = strings.TrimSpace(o); o != "" && !strings.Contains(o, ",") {
+			kept = append(kept, o)
+		}
+	}
+	if len(kept) == 0 {
+		return ""
+	}
+	return "," + strings.Join(kept, ",") + ","
+}
+
+func splitOwners(s string) []string {
+	if s = strings.Trim(s, ","); s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// setOwner records a memory's ownership for scoped HNSW search; hnswMu must be held
+func (s *VectorMemoryStore) setOwner(id string, o Ownership) {
+	if s.owners == nil {
+		s.owners = make(map[string]Ownership)
+	}
+	s.owners[id] = o
+}
+
+// loadOwners reads the ownership of every memory
+func (s *VectorMemoryStore) loadOwners() map[string]Ownership {
+	owners := make(map[string]Ownership)
+	rows, err := s.db.Query(`SELECT id, COALESCE(namespace, ''), COALESCE(owner, ''), COALESCE(shared_with, '') FROM vector_memories`)
+	if err != nil {
+		log.Printf("[WARN] load memory owners failed: %v", err)
+		return owners
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, sharedWith string
+		var o Ownership
+		if err := rows.Scan(&id, &o.Namespace, &o.Owner, &sharedWith); err != nil {
+			continue
+		}
+		o.SharedWith = splitOwners(sharedWith)
+		owners[id] = o
+	}
+	return owners
+}
@@ -2,6 +2,7 @@
 package memory
 
 import (
+	"cmp"
 	"context"
 	"crypto/rand"
 	"database/sql"
@@ -14,10 +15,12 @@ import (
 	"net/http"
 	"os"
 	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -27,10 +30,14 @@ import (
 // Vector memory store - unified architecture
 type VectorMemoryStore struct {
 	db               *sql.DB
-	hnsw             *HNSWIndex   // FAISS HNSW index
-	hnswMu           sync.RWMutex // Protects hnswIDs
-	hnswIDs          []string     // HNSW index -> memory ID mapping
-	hnswDeletedCount int          // Track deletions for periodic rebuild
+	hnsw             *HNSWIndex           // HNSW index (native Go, or FAISS with -tags faiss)
+	hnswMu           sync.RWMutex         // Protects hnswIDs
+	hnswIDs          []string             // HNSW index -> memory ID mapping
+	hnswDeletedCount int                  // Track deletions for periodic rebuild
+	hnswUnsaved      atomic.Int32         // Deletions since the index was last saved, see flushHNSW
+	decayHalfLife    atomic.Int64         // Config.DecayHalfLife, see SetDecayHalfLife
+	owners           map[string]Ownership // Memory ID -> ownership, for scoped HNSW search (hnswMu)
+	ingestMu         sync.Mutex           // Serializes document ingestion
 	embedding        EmbeddingProvider
 	ftsAvailable     bool
 	cfg              Config
@@ -46,11 +53,19 @@ type Config struct {
 	MaxResults      int     // Max results (default 5)
 	MinScore        float32 // Minimum similarity score (default 0.7)
 	HNSWPath        string  // HNSW index file path
+	HNSWM           int     // HNSW links per node (default 16)
+	HNSWEfSearch    int     // HNSW search beam width (default 100)
+	HNSWEfConstruct int     // HNSW construction beam width (default 200)
 	HybridEnabled   bool    // Enable hybrid search (default true)
 	VectorWeight    float32 // Vector weight (default 0.7)
 	TextWeight      float32 // Keyword weight (default 0.3)
 	CandidateMult   int     // Candidate multiplier (default 4)
 	BatchSize       int     // Vector load batch size (default 1000)
+
+	// DecayHalfLife ages retrieval scores: a memory's weight halves every
+	// half-life since it was last updated, more slowly the more important
+	// it is (0 = no decay)
+	DecayHalfLife time.Duration
 }
 
 // Embedding provider interface
@@ -82,10 +97,19 @@ type MemoryEntry struct {
 	Importance float64
 	Category   string
 	Source     string
+	Namespace  string    // Agent profile namespace ("" = shared default)
+	Owner      string    // Session identity that owns it ("" = global)
+	SharedWith []string  // Other owners that see it
+	Citation   *Citation // Where a document chunk comes from (nil for other memories)
 	CreatedAt  int64
 	UpdatedAt  int64
 }
 
+// Ownership returns where the memory belongs and who sees it
+func (e *MemoryEntry) Ownership() Ownership {
+	return Ownership{Namespace: e.Namespace, Owner: e.Owner, SharedWith: e.SharedWith}
+}
+
 // Search result (with similarity score)
 type MemoryResult struct {
 	Entry   MemoryEntry
@@ -302,6 +326,7 @@ func NewVectorMemoryStore(dbPath string, cfg Config) (*VectorMemoryStore, error)
 	}
 
 	store := &VectorMemoryStore{db: db, cfg: cfg}
+	store.decayHalfLife.Store(int64(cfg.DecayHalfLife))
 	
 	graphStore, err := NewGraphStore(db)
 	if err != nil {
@@ -353,34 +378,34 @@ func NewVectorMemoryStore(dbPath string, cfg Config) (*VectorMemoryStore, error)
 	// Backfill embedding_dim for old rows when NULL/0
 	store.backfillEmbeddingDim()
 
-	// Initialize FAISS HNSW when embedding is available
+	// Initialize HNSW when embedding is available
 	if store.embedding != nil {
 		hnswCfg := HNSWConfig{
 			Dim:         cfg.EmbeddingDim,
-			M:           16,
-			EfSearch:    100,
-			EfConstruct: 200,
+			M:           cmp.Or(cfg.HNSWM, 16),
+			EfSearch:    cmp.Or(cfg.HNSWEfSearch, 100),
+			EfConstruct: cmp.Or(cfg.HNSWEfConstruct, 200),
 			Distance:    "cosine",
 			StoragePath: cfg.HNSWPath,
 		}
 
 		hnsw, err := NewHNSWIndex(hnswCfg)
 		if err != nil {
-			log.Printf("FAISS HNSW init failed: %v", err)
+			log.Printf("HNSW init failed: %v", err)
 			log.Printf("Falling back to SQLite linear search")
 			store.hnsw = nil
 		} else {
 			store.hnsw = hnsw
-			log.Printf("FAISS HNSW index enabled")
+			log.Printf("HNSW index enabled (faiss: %s)", FAISSVersion())
 
 			// Load existing vectors
 			store.loadExistingVectors()
 		}
 	} else {
-		log.Printf("No embedding service, skipping FAISS init")
+		log.Printf("No embedding service, skipping HNSW init")
 	}
 
-	log.Printf("Vector memory store initialized: faiss=%v, embedding=%v", store.hnsw != nil, store.embedding != nil)
+	log.Printf("Vector memory store initialized: hnsw=%v, embedding=%v", store.hnsw != nil, store.embedding != nil)
 	return store, nil
 }
 
@@ -395,6 +420,9 @@ func initSchema(db *sql.DB) error {
 			importance REAL DEFAULT 0.5,
 			category TEXT DEFAULT 'other',
 			source TEXT DEFAULT 'manual',
+			namespace TEXT DEFAULT '',
+			owner TEXT DEFAULT '',
+			shared_with TEXT DEFAULT '',
 			embedding_dim INTEGER,
 			created_at INTEGER DEFAULT (strftime('%s','now')),
 			updated_at INTEGER DEFAULT (strftime('%s','now'))
@@ -411,6 +439,9 @@ func initSchema(db *sql.DB) error {
 		hasDim := false
 		hasSource := false
 		hasUpdated := false
+		hasNamespace := false
+		hasOwner := false
+		hasSharedWith := false
 		for rows.Next() {
 			var cid int
 			var name, ctype string
@@ -428,6 +459,12 @@ func initSchema(db *sql.DB) error {
 				hasSource = true
 			case "updated_at":
 				hasUpdated = true
+			case "namespace":
+				hasNamespace = true
+			case "owner":
+				hasOwner = true
+			case "shared_with":
+				hasSharedWith = true
 			}
 		}
 		if !hasDim {
@@ -445,10 +482,94 @@ func initSchema(db *sql.DB) error {
 				log.Printf("[WARN] initSchema failed to add updated_at: %v", err)
 			}
 		}
+		if !hasNamespace {
+			if _, err := db.Exec(`ALTER TABLE vector_memories ADD COLUMN namespace TEXT DEFAULT ''`); err != nil {
+				log.Printf("[WARN] initSchema failed to add namespace: %v", err)
+			}
+		}
+		if !hasOwner {
+			// Memories from before ownership may hold any session's facts,
+			// so they go to LegacyOwner rather than becoming global
+			if _, err := db.Exec(`ALTER TABLE vector_memories ADD COLUMN owner TEXT DEFAULT ''`); err != nil {
+				log.Printf("[WARN] initSchema failed to add owner: %v", err)
+			} else if res, err := db.Exec(`UPDATE vector_memories SET owner = ?`, LegacyOwner); err != nil {
+				log.Printf("[WARN] initSchema failed to mark legacy memories: %v", err)
+			} else if n, _ := res.RowsAffected(); n > 0 {
+				log.Printf("[Memory] %d memories from before ownership moved to owner %q, out of recall", n, LegacyOwner)
+			}
+		}
+		if !hasSharedWith {
+			if _, err := db.Exec(`ALTER TABLE vector_memories ADD COLUMN shared_with TEXT DEFAULT ''`); err != nil {
+				log.Printf("[WARN] initSchema failed to add shared_with: %v", err)
+			}
+		}
 	}
 
 	db.Exec(`CREATE INDEX IF NOT EXISTS idx_vm_category ON vector_memories(category)`)
 	db.Exec(`CREATE INDEX IF NOT EXISTS idx_vm_created ON vector_memories(created_at)`)
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_vm_namespace ON vector_memories(namespace)`)
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_vm_owner ON vector_memories(namespace, owner)`)
+
+	// Archive: memories consolidation merged away or let decay out of search
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS vector_memories_archive (
+			id TEXT PRIMARY KEY,
+			text TEXT NOT NULL,
+			vector BLOB,
+			importance REAL,
+			category TEXT,
+			source TEXT,
+			namespace TEXT DEFAULT '',
+			owner TEXT DEFAULT '',
+			shared_with TEXT DEFAULT '',
+			embedding_dim INTEGER,
+			created_at INTEGER,
+			updated_at INTEGER,
+			archived_at INTEGER DEFAULT (strftime('%s','now')),
+			reason TEXT DEFAULT '',
+			merged_into TEXT DEFAULT ''
+		)
+	`); err != nil {
+		return err
+	}
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_vma_merged ON vector_memories_archive(merged_into)`)
+
+	// Ingested documents and where each of their chunk memories comes from;
+	// every owner of a document has its own copy
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS memory_documents (
+			path TEXT NOT NULL,
+			kind TEXT,
+			hash TEXT,
+			size INTEGER,
+			mod_time INTEGER,
+			chunks INTEGER,
+			namespace TEXT NOT NULL DEFAULT '',
+			owner TEXT NOT NULL DEFAULT '',
+			ingested_at INTEGER,
+			PRIMARY KEY (path, namespace, owner)
+		)
+	`); err != nil {
+		return err
+	}
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS memory_doc_chunks (
+			memory_id TEXT PRIMARY KEY,
+			path TEXT NOT NULL,
+			namespace TEXT NOT NULL DEFAULT '',
+			owner TEXT NOT NULL DEFAULT '',
+			start_offset INTEGER,
+			end_offset INTEGER,
+			start_line INTEGER,
+			end_line INTEGER,
+			page INTEGER DEFAULT 0,
+			heading TEXT DEFAULT '',
+			hash TEXT
+		)
+	`); err != nil {
+		return err
+	}
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_mdc_path ON memory_doc_chunks(path, namespace, owner)`)
 
 	// FTS5 index (keyword search)
 	if _, err := db.Exec(`
@@ -567,6 +688,9 @@ func (s *VectorMemoryStore) StoreBatch(entries []struct {
 		} else {
 			s.hnswMu.Lock()
 			s.hnswIDs = append(s.hnswIDs, hnswTargetIDs...)
+			for _, id := range hnswTargetIDs {
+				s.setOwner(id, Ownership{})
+			}
 			s.hnswMu.Unlock()
 			s.saveHNSW()
 		}
@@ -589,6 +713,24 @@ func (s *VectorMemoryStore) StoreBatch(entries []struct {
 }
 
 func (s *VectorMemoryStore) StoreWithSource(text string, category string, importance float64, source string) (string, error) {
+	return s.StoreOwned(Ownership{}, text, category, importance, source)
+}
+
+// StoreOwned stores a memory in a namespace under an owner ("" = global),
+// shared with o.SharedWith
+func (s *VectorMemoryStore) StoreOwned(o Ownership, text string, category string, importance float64, source string) (string, error) {
+	id, err := s.insert(o, text, category, importance, source)
+	if err != nil {
+		return "", err
+	}
+	s.saveHNSW()
+	log.Printf("[OK] Memory stored: %s [%s]", shortID(id), category)
+	return id, nil
+}
+
+// insert adds a memory to the table, FTS and HNSW index without saving the
+// index, so callers storing many can save once
+func (s *VectorMemoryStore) insert(o Ownership, text string, category string, importance float64, source string) (string, error) {
 	vector, err := s.getEmbedding(text)
 	if err != nil {
 		return "", fmt.Errorf("embedding failed: %v", err)
@@ -609,9 +751,9 @@ func (s *VectorMemoryStore) StoreWithSource(text string, category string, import
 	}
 
 	_, err = s.db.Exec(`
-		INSERT INTO vector_memories (id, text, vector, importance, category, source, embedding_dim, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, id, text, vectorBlob, importance, category, source, s.cfg.EmbeddingDim, now, now)
+		INSERT INTO vector_memories (id, text, vector, importance, category, source, namespace, owner, shared_with, embedding_dim, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, text, vectorBlob, importance, category, source, o.Namespace, o.Owner, joinOwners(o.SharedWith), s.cfg.EmbeddingDim, now, now)
 
 	if err != nil {
 		if s.hnsw != nil {
@@ -626,11 +768,10 @@ func (s *VectorMemoryStore) StoreWithSource(text string, category string, import
 	if s.hnsw != nil {
 		s.hnswMu.Lock()
 		s.hnswIDs = append(s.hnswIDs, id)
+		o.SharedWith = splitOwners(joinOwners(o.SharedWith))
+		s.setOwner(id, o)
 		s.hnswMu.Unlock()
-		s.saveHNSW()
 	}
-
-	log.Printf("[OK] Memory stored: %s [%s]", shortID(id), category)
 	return id, nil
 }
 
@@ -773,6 +914,47 @@ func (s *VectorMemoryStore) getEmbeddingsBatch(texts []string) ([][]float32, err
 
 // Search - with similarity scores
 func (s *VectorMemoryStore) Search(query string, limit int, minScore float32) ([]MemoryResult, error) {
+	return s.search(nil, query, limit, minScore)
+}
+
+// SearchScope is Search limited to the memories sc allows. The filter is
+// applied inside each search path (SQL, FTS and the HNSW walk), so limit
+// results are found even when the scope holds few of the memories.
+func (s *VectorMemoryStore) SearchScope(sc Scope, query string, limit int, minScore float32) ([]MemoryResult, error) {
+	return s.search(&sc, query, limit, minScore)
+}
+
+// search runs Search; a nil scope sees every memory. With decay on it
+// fetches CandidateMult times limit, since decay reorders them, and drops
+// those decayed below minScore.
+func (s *VectorMemoryStore) search(sc *Scope, query string, limit int, minScore float32) ([]MemoryResult, error) {
+	if limit <= 0 {
+		limit = s.cfg.MaxResults
+	}
+	if minScore == 0 {
+		minScore = s.cfg.MinScore
+	}
+	decay := s.decayHalfLife.Load() > 0
+	fetch := limit
+	if decay {
+		fetch = limit * max(s.cfg.CandidateMult, 1)
+	}
+	results, err := s.searchRaw(sc, query, fetch, minScore)
+	if err != nil {
+		return nil, err
+	}
+	if decay {
+		s.applyDecay(results, time.Now())
+		if s.embedding != nil { // Keyword matches all score 1
+			results = slices.DeleteFunc(results, func(r MemoryResult) bool { return r.Score < minScore })
+		}
+		results = results[:min(len(results), limit)]
+	}
+	s.attachCitations(results)
+	return results, nil
+}
+
+func (s *VectorMemoryStore) searchRaw(sc *Scope, query string, limit int, minScore float32) ([]MemoryResult, error) {
 	if limit <= 0 {
 		limit = s.cfg.MaxResults
 	}
@@ -781,7 +963,7 @@ func (s *VectorMemoryStore) Search(query string, limit int, minScore float32) ([
 	}
 
 	if s.embedding == nil {
-		return s.keywordSearch(query, limit)
+		return s.keywordSearch(sc, query, limit)
 	}
 
 	queryVec, err := s.getEmbedding(query)
@@ -790,34 +972,44 @@ func (s *VectorMemoryStore) Search(query string, limit int, minScore float32) ([
 	}
 
 	if s.cfg.HybridEnabled {
-		return s.hybridSearch(query, queryVec, limit, minScore)
+		return s.hybridSearch(sc, query, queryVec, limit, minScore)
 	}
 
 	var results []MemoryResult
 
-	// FAISS HNSW search (preferred)
+	// HNSW search (preferred)
 	s.hnswMu.RLock()
 	hasHNSW := s.hnsw != nil && s.hnsw.Count() > 0
 	s.hnswMu.RUnlock()
 	if hasHNSW {
-		results, err = s.hnswSearch(queryVec, limit, minScore)
+		results, err = s.hnswSearch(sc, queryVec, limit, minScore)
 	} else {
 		// Fallback to SQLite linear search
-		results, err = s.linearSearch(queryVec, limit, minScore)
+		results, err = s.linearSearch(sc, queryVec, limit, minScore)
 	}
 
 	return results, err
 }
 
 // HNSW search
-func (s *VectorMemoryStore) hnswSearch(queryVec []float32, limit int, minScore float32) ([]MemoryResult, error) {
+func (s *VectorMemoryStore) hnswSearch(sc *Scope, queryVec []float32, limit int, minScore float32) ([]MemoryResult, error) {
 	s.hnswMu.RLock()
 	defer s.hnswMu.RUnlock()
 	if s.hnsw == nil {
 		return nil, fmt.Errorf("hnsw index not available")
 	}
 
-	distances, labels, err := s.hnsw.SearchWithScores(queryVec, limit)
+	var allow func(label int64) bool
+	if sc != nil {
+		allow = func(label int64) bool {
+			if label < 0 || label >= int64(len(s.hnswIDs)) {
+				return false
+			}
+			o, ok := s.owners[s.hnswIDs[label]]
+			return ok && sc.Allows(o)
+		}
+	}
+	distances, labels, err := s.hnsw.SearchFilter(queryVec, limit, allow)
 	if err != nil {
 		return nil, err
 	}
@@ -836,6 +1028,9 @@ func (s *VectorMemoryStore) hnswSearch(queryVec []float32, limit int, minScore f
 			continue
 		}
 		id := s.hnswIDs[label]
+		if id == "" {
+			continue // deleted
+		}
 		entry, err := s.getByID(id)
 		if err != nil {
 			continue
@@ -871,7 +1066,7 @@ func (s *VectorMemoryStore) hnswSearch(queryVec []float32, limit int, minScore f
 }
 
 // SQLite linear search (fallback)
-func (s *VectorMemoryStore) linearSearch(queryVec []float32, limit int, minScore float32) ([]MemoryResult, error) {
+func (s *VectorMemoryStore) linearSearch(sc *Scope, queryVec []float32, limit int, minScore float32) ([]MemoryResult, error) {
 	if limit <= 0 {
 		limit = 5
 	}
@@ -887,12 +1082,14 @@ func (s *VectorMemoryStore) linearSearch(queryVec []float32, limit int, minScore
 		maxCandidates = 2000
 	}
 
+	cond, args := sc.where("")
 	rows, err := s.db.Query(`
-		SELECT id, text, vector, importance, category, source, created_at, updated_at
+		SELECT id, text, vector, importance, category, source, namespace, owner, shared_with, created_at, updated_at
 		FROM vector_memories
+		WHERE `+cond+`
 		ORDER BY updated_at DESC
 		LIMIT ?
-	`, maxCandidates)
+	`, append(args, maxCandidates)...)
 	if err != nil {
 		return nil, err
 	}
@@ -907,13 +1104,15 @@ func (s *VectorMemoryStore) linearSearch(queryVec []float32, limit int, minScore
 	for rows.Next() {
 		var w withScore
 		var vectorBlob []byte
+		var sharedWith string
 		if err := rows.Scan(&w.entry.ID, &w.entry.Text, &vectorBlob,
-			&w.entry.Importance, &w.entry.Category, &w.entry.Source, &w.entry.CreatedAt, &w.entry.UpdatedAt); err != nil {
+			&w.entry.Importance, &w.entry.Category, &w.entry.Source, &w.entry.Namespace, &w.entry.Owner, &sharedWith, &w.entry.CreatedAt, &w.entry.UpdatedAt); err != nil {
 			return nil, err
 		}
+		w.entry.SharedWith = splitOwners(sharedWith)
 		w.entry.Vector = deserializeVector(vectorBlob)
 		if len(w.entry.Vector) == len(queryVec) {
-			w.score = cosineSimilarity(queryVec, w.entry.Vector)
+			w.score = CosineSimilarity(queryVec, w.entry.Vector)
 		}
 		all = append(all, w)
 	}
@@ -941,14 +1140,15 @@ func (s *VectorMemoryStore) linearSearch(queryVec []float32, limit int, minScore
 }
 
 // Keyword search (fallback when no embedding service)
-func (s *VectorMemoryStore) keywordSearch(query string, limit int) ([]MemoryResult, error) {
+func (s *VectorMemoryStore) keywordSearch(sc *Scope, query string, limit int) ([]MemoryResult, error) {
+	cond, args := sc.where("")
 	rows, err := s.db.Query(`
-		SELECT id, text, importance, category, source, created_at, updated_at
+		SELECT id, text, importance, category, source, namespace, owner, shared_with, created_at, updated_at
 		FROM vector_memories
-		WHERE text LIKE ? OR category LIKE ?
+		WHERE (text LIKE ? OR category LIKE ?) AND `+cond+`
 		ORDER BY importance DESC, created_at DESC
 		LIMIT ?
-	`, "%"+query+"%", "%"+query+"%", limit)
+	`, append(append([]any{"%" + query + "%", "%" + query + "%"}, args...), limit)...)
 	if err != nil {
 		return nil, err
 	}
@@ -957,9 +1157,11 @@ func (s *VectorMemoryStore) keywordSearch(query string, limit int) ([]MemoryResu
 	results := make([]MemoryResult, 0, limit)
 	for rows.Next() {
 		var entry MemoryEntry
-		if err := rows.Scan(&entry.ID, &entry.Text, &entry.Importance, &entry.Category, &entry.Source, &entry.CreatedAt, &entry.UpdatedAt); err != nil {
+		var sharedWith string
+		if err := rows.Scan(&entry.ID, &entry.Text, &entry.Importance, &entry.Category, &entry.Source, &entry.Namespace, &entry.Owner, &sharedWith, &entry.CreatedAt, &entry.UpdatedAt); err != nil {
 			return nil, err
 		}
+		entry.SharedWith = splitOwners(sharedWith)
 		results = append(results, MemoryResult{
 			Entry:   entry,
 			Score:   1.0,
@@ -975,15 +1177,18 @@ func (s *VectorMemoryStore) keywordSearch(query string, limit int) ([]MemoryResu
 	return results, nil
 }
 
-// FTS5 keyword search (returns bm25 score)
-func (s *VectorMemoryStore) ftsSearch(query string, limit int) (map[string]float32, error) {
+// FTS5 keyword search (returns bm25 score); FTS rows are scoped through
+// their memory row
+func (s *VectorMemoryStore) ftsSearch(sc *Scope, query string, limit int) (map[string]float32, error) {
+	cond, args := sc.where("m.")
 	rows, err := s.db.Query(`
-		SELECT id, bm25(vector_memories_fts) AS score
+		SELECT vector_memories_fts.id, bm25(vector_memories_fts) AS score
 		FROM vector_memories_fts
-		WHERE vector_memories_fts MATCH ?
+		JOIN vector_memories m ON m.id = vector_memories_fts.id
+		WHERE vector_memories_fts MATCH ? AND `+cond+`
 		ORDER BY score ASC
 		LIMIT ?
-	`, query, limit)
+	`, append(append([]any{query}, args...), limit)...)
 	if err != nil {
 		return nil, err
 	}
@@ -1004,14 +1209,15 @@ func (s *VectorMemoryStore) ftsSearch(query string, limit int) (map[string]float
 	return out, nil
 }
 
-func (s *VectorMemoryStore) likeScores(query string, limit int) map[string]float32 {
+func (s *VectorMemoryStore) likeScores(sc *Scope, query string, limit int) map[string]float32 {
+	cond, args := sc.where("")
 	rows, err := s.db.Query(`
 		SELECT id
 		FROM vector_memories
-		WHERE text LIKE ? OR category LIKE ?
+		WHERE (text LIKE ? OR category LIKE ?) AND `+cond+`
 		ORDER BY importance DESC, created_at DESC
 		LIMIT ?
-	`, "%"+query+"%", "%"+query+"%", limit)
+	`, append(append([]any{"%" + query + "%", "%" + query + "%"}, args...), limit)...)
 	if err != nil {
 		return map[string]float32{}
 	}
@@ -1029,18 +1235,18 @@ func (s *VectorMemoryStore) likeScores(query string, limit int) map[string]float
 }
 
 // Hybrid search: vector + BM25
-func (s *VectorMemoryStore) hybridSearch(query string, queryVec []float32, limit int, minScore float32) ([]MemoryResult, error) {
+func (s *VectorMemoryStore) hybridSearch(sc *Scope, query string, queryVec []float32, limit int, minScore float32) ([]MemoryResult, error) {
 	cand := limit * s.cfg.CandidateMult
-	vecResults, err := s.vectorSearch(queryVec, cand)
+	vecResults, err := s.vectorSearch(sc, queryVec, cand)
 	if err != nil {
 		return nil, err
 	}
 
 	var textScores map[string]float32
 	if s.ftsAvailable {
-		textScores, _ = s.ftsSearch(query, cand)
+		textScores, _ = s.ftsSearch(sc, query, cand)
 	} else {
-		textScores = s.likeScores(query, cand)
+		textScores = s.likeScores(sc, query, cand)
 	}
 
 	type scored struct {
@@ -1094,24 +1300,29 @@ func (s *VectorMemoryStore) hybridSearch(query string, queryVec []float32, limit
 }
 
 // Unified vector search (for hybrid candidate pool)
-func (s *VectorMemoryStore) vectorSearch(queryVec []float32, limit int) ([]MemoryResult, error) {
+func (s *VectorMemoryStore) vectorSearch(sc *Scope, queryVec []float32, limit int) ([]MemoryResult, error) {
 	if s.hnsw != nil && s.hnsw.Count() > 0 {
-		return s.hnswSearch(queryVec, limit, 0)
+		return s.hnswSearch(sc, queryVec, limit, 0)
 	}
-	return s.linearSearch(queryVec, limit, 0)
+	return s.linearSearch(sc, queryVec, limit, 0)
 }
 
 func (s *VectorMemoryStore) getByID(id string) (MemoryEntry, error) {
 	var entry MemoryEntry
 	var vectorBlob []byte
+	var sharedWith string
 	err := s.db.QueryRow(`
-		SELECT text, vector, importance, category, source, created_at, updated_at FROM vector_memories WHERE id = ?
-	`, id).Scan(&entry.Text, &vectorBlob, &entry.Importance, &entry.Category, &entry.Source, &entry.CreatedAt, &entry.UpdatedAt)
+		SELECT text, vector, importance, category, source, namespace, owner, shared_with, created_at, updated_at FROM vector_memories WHERE id = ?
+	`, id).Scan(&entry.Text, &vectorBlob, &entry.Importance, &entry.Category, &entry.Source, &entry.Namespace, &entry.Owner, &sharedWith, &entry.CreatedAt, &entry.UpdatedAt)
 	if err != nil {
 		return entry, err
 	}
 	entry.ID = id
+	entry.SharedWith = splitOwners(sharedWith)
 	entry.Vector = deserializeVector(vectorBlob)
+	if entry.Category == DocumentCategory {
+		entry.Citation = s.citation(id)
+	}
 	return entry, nil
 }
 
@@ -1134,18 +1345,18 @@ func (s *VectorMemoryStore) Delete(id string) (bool, error) {
 
 	// Fix Bug #1: Track deleted IDs and rebuild HNSW when threshold exceeded
 	if s.hnsw != nil {
-		// Remove from ID mapping
+		// Tombstone the label: labels are positions, so the mapping must not shift
 		s.hnswMu.Lock()
-		newIDs := make([]string, 0, len(s.hnswIDs))
 		removed := false
-		for _, existingID := range s.hnswIDs {
-			if existingID != id {
-				newIDs = append(newIDs, existingID)
-			} else {
+		for label, existingID := range s.hnswIDs {
+			if existingID == id {
+				s.hnswIDs[label] = ""
+				delete(s.owners, id)
+				s.hnsw.Remove(int64(label)) // unsupported by FAISS; the tombstone suffices
 				removed = true
+				break
 			}
 		}
-		s.hnswIDs = newIDs
 
 		// Track deletions for periodic rebuild
 		if removed {
@@ -1161,6 +1372,9 @@ func (s *VectorMemoryStore) Delete(id string) (bool, error) {
 			}
 		}
 		s.hnswMu.Unlock()
+		if removed && s.hnswUnsaved.Add(1) >= hnswSaveEvery {
+			s.saveHNSW()
+		}
 	}
 
 	return true, nil
@@ -1174,6 +1388,7 @@ func (s *VectorMemoryStore) rebuildHNSW() {
 		return
 	}
 	cfg := old.Config()
+	cfg.StoragePath = "" // build from the DB, not the saved file
 	s.hnswMu.Unlock()
 
 	idx, err := NewHNSWIndex(cfg)
@@ -1190,14 +1405,18 @@ func (s *VectorMemoryStore) rebuildHNSW() {
 		return
 	}
 
+	owners := s.loadOwners()
+
 	// Atomic swap: old index is replaced only after new one is fully populated
 	s.hnswMu.Lock()
 	s.hnsw = idx
 	s.hnswIDs = newIDs
+	s.owners = owners
 	s.hnswDeletedCount = 0
 	s.hnswMu.Unlock()
 
 	old.Close()
+	s.saveHNSW()
 	log.Printf("[OK] HNSW rebuild completed (atomic), %d vectors loaded", len(newIDs))
 }
 
@@ -1274,6 +1493,11 @@ func (s *VectorMemoryStore) loadVectorsIntoIndex(idx *HNSWIndex) ([]string, erro
 	return allIDs, nil
 }
 
+// Embedder returns the embedding provider the store indexes with
+func (s *VectorMemoryStore) Embedder() EmbeddingProvider {
+	return s.embedding
+}
+
 func (s *VectorMemoryStore) Count() (int, error) {
 	var count int
 	return count, s.db.QueryRow("SELECT COUNT(*) FROM vector_memories").Scan(&count)
@@ -1281,9 +1505,7 @@ func (s *VectorMemoryStore) Count() (int, error) {
 
 func (s *VectorMemoryStore) Close() error {
 	if s.hnsw != nil {
-		if s.cfg.HNSWPath != "" {
-			s.hnsw.Save(s.cfg.HNSWPath)
-		}
+		s.saveHNSW()
 		s.hnsw.Close()
 	}
 	return s.db.Close()
@@ -1296,6 +1518,33 @@ func (s *VectorMemoryStore) loadExistingVectors() {
 	// Check total count first
 	var totalCount int
 	s.db.QueryRow("SELECT COUNT(*) FROM vector_memories").Scan(&totalCount)
+
+	owners := s.loadOwners()
+	s.hnswMu.Lock()
+	s.owners = owners
+	s.hnswMu.Unlock()
+
+	// An index loaded from disk is used as-is when its saved ID mapping still
+	// covers every row; otherwise it is rebuilt from the database
+	if s.hnsw != nil && s.hnsw.Loaded() {
+		if ids, ok := s.loadHNSWIDs(totalCount); ok {
+			s.hnswMu.Lock()
+			s.hnswIDs = ids
+			s.hnswMu.Unlock()
+			log.Printf("HNSW index restored from disk: %d vectors", totalCount)
+			return
+		}
+		log.Printf("[WARN] HNSW index on disk is out of sync with the database, rebuilding")
+		cfg := s.hnsw.Config()
+		cfg.StoragePath = ""
+		idx, err := NewHNSWIndex(cfg)
+		if err != nil {
+			log.Printf("HNSW reset failed: %v", err)
+			return
+		}
+		s.hnsw.Close()
+		s.hnsw = idx
+	}
 	if totalCount == 0 {
 		return
 	}
@@ -1382,19 +1631,12 @@ func (s *VectorMemoryStore) loadExistingVectors() {
 
 		// Add batch to HNSW
 		if s.hnsw != nil && len(vectors) > 0 {
-			if s.hnsw.Loaded() {
-				// Already loaded from disk, restore ID mappings
+			if err := s.hnsw.Add(vectors); err != nil {
+				log.Printf("Load existing vectors add failed: %v", err)
+			} else {
 				s.hnswMu.Lock()
 				s.hnswIDs = append(s.hnswIDs, ids...)
 				s.hnswMu.Unlock()
-			} else {
-				if err := s.hnsw.Add(vectors); err != nil {
-					log.Printf("Load existing vectors add failed: %v", err)
-				} else {
-					s.hnswMu.Lock()
-					s.hnswIDs = append(s.hnswIDs, ids...)
-					s.hnswMu.Unlock()
-				}
 			}
 		}
 
@@ -1414,12 +1656,61 @@ func (s *VectorMemoryStore) loadExistingVectors() {
 	}
 }
 
+// hnswSaveEvery is how many deletions Delete lets pass before saving the
+// index. Callers deleting in bulk flush once at the end; an index saved
+// before a crash lists memories that are gone, so it is rebuilt on load.
+const hnswSaveEvery = 64
+
+// flushHNSW saves the index if deletions are pending
+func (s *VectorMemoryStore) flushHNSW() {
+	if s.hnswUnsaved.Load() > 0 {
+		s.saveHNSW()
+	}
+}
+
+// saveHNSW writes the index to HNSWPath and its label -> memory ID mapping
+// to HNSWPath.ids (a JSON array, "" for deleted labels)
 func (s *VectorMemoryStore) saveHNSW() {
-	if s.hnsw != nil && s.cfg.HNSWPath != "" {
-		if err := s.hnsw.Save(s.cfg.HNSWPath); err != nil {
-			log.Printf("save hnsw failed: %v", err)
+	if s.hnsw == nil || s.cfg.HNSWPath == "" {
+		return
+	}
+	s.hnswMu.RLock()
+	defer s.hnswMu.RUnlock()
+	s.hnswUnsaved.Store(0)
+	if err := s.hnsw.Save(s.cfg.HNSWPath); err != nil {
+		log.Printf("save hnsw failed: %v", err)
+		return
+	}
+	data, err := json.Marshal(s.hnswIDs)
+	if err == nil {
+		tmp := s.cfg.HNSWPath + ".ids.tmp"
+		if err = os.WriteFile(tmp, data, 0o644); err == nil {
+			err = os.Rename(tmp, s.cfg.HNSWPath+".ids")
+		}
+	}
+	if err != nil {
+		log.Printf("save hnsw ids failed: %v", err)
+	}
+}
+
+// loadHNSWIDs reads the mapping saved with the index; ok only when it has a
+// label for every vector in the index and an ID for each of totalCount rows
+func (s *VectorMemoryStore) loadHNSWIDs(totalCount int) ([]string, bool) {
+	data, err := os.ReadFile(s.cfg.HNSWPath + ".ids")
+	if err != nil {
+		return nil, false
+	}
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err != nil || int64(len(ids)) != s.hnsw.Count() {
+		return nil, false
+	}
+	live := 0
+	for _, id := range ids {
+		if id != "" {
+			live++
 		}
 	}
+	return ids, live == totalCount
 }
 
 // ==================== Utils ====================
@@ -1596,7 +1887,8 @@ func deserializeVector(b []byte) []float32 {
 	return result
 }
 
-func cosineSimilarity(a, b []float32) float32 {
+// CosineSimilarity returns the cosine of the angle between two vectors (0 if either is zero)
+func CosineSimilarity(a, b []float32) float32 {
 	var dot, normA, normB float32
 	for i := 0; i < len(a); i++ {
 		dot += a[i] * b[i]
@@ -12,6 +12,9 @@ func NewVectorMemoryStoreWithConfig(cfg config.MemoryConfig) (*VectorMemoryStore
 		MaxResults:      cfg.MaxResults,
 		MinScore:        cfg.MinScore,
 		HNSWPath:        cfg.HNSWPath,
+		HNSWM:           cfg.HNSWM,
+		HNSWEfSearch:    cfg.HNSWEfSearch,
+		HNSWEfConstruct: cfg.HNSWEfConstruct,
 		HybridEnabled:   cfg.HybridEnabled,
 		VectorWeight:    cfg.VectorWeight,
 		TextWeight:      cfg.TextWeight,
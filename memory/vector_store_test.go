@@ -1,7 +1,11 @@
 package memory
 
 import (
+	"database/sql"
+	"fmt"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -197,3 +201,185 @@ func TestVectorStore_ConcurrentAccess(t *testing.T) {
 		t.Fatalf("expected %d items but got %d", workers*itemsPerWorker, count)
 	}
 }
+
+func TestVectorStore_SearchScope(t *testing.T) {
+	store, err := NewVectorMemoryStore(filepath.Join(t.TempDir(), "vec.db"), Config{EmbeddingDim: 3})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+	store.embedding = &MockProvider{dim: 3}
+
+	ids := map[string]string{}
+	for name, o := range map[string]Ownership{
+		"global":  {},
+		"alice":   {Owner: "telegram_1"},
+		"bob":     {Owner: "slack_2"},
+		"shared":  {Owner: "slack_2", SharedWith: []string{"telegram_1"}},
+		"support": {Namespace: "support", Owner: "telegram_1"},
+	} {
+		id, err := store.StoreOwned(o, "deploys run on fridays ("+name+")", "fact", 0.5, "auto")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[id] = name
+	}
+
+	cases := []struct {
+		scope Scope
+		want  string
+	}{
+		{UserScope("", "telegram_1"), "alice global shared"},
+		{UserScope("", "slack_2"), "bob global shared"},
+		{Scope{Owner: "telegram_1"}, "alice"},
+		{Scope{Owner: "telegram_1", Shared: true}, "alice shared"},
+		{UserScope("support", "telegram_1"), "support"},
+		{UserScope("", ""), "global"},
+	}
+	for _, tc := range cases {
+		results, err := store.SearchScope(tc.scope, "deploys", 10, 0.0)
+		if err != nil {
+			t.Fatalf("SearchScope(%+v): %v", tc.scope, err)
+		}
+		var got []string
+		for _, r := range results {
+			got = append(got, ids[r.Entry.ID])
+		}
+		sort.Strings(got)
+		if strings.Join(got, " ") != tc.want {
+			t.Errorf("SearchScope(%+v) = %v, want %s", tc.scope, got, tc.want)
+		}
+	}
+	if results, _ := store.Search("deploys", 10, 0.0); len(results) != 5 {
+		t.Errorf("Search should cover every memory, got %d results", len(results))
+	}
+
+	entry, err := store.Get(firstKey(ids, "shared"))
+	if err != nil || entry.Owner != "slack_2" || fmt.Sprint(entry.SharedWith) != "[telegram_1]" {
+		t.Errorf("Get(shared) = %+v, %v", entry, err)
+	}
+}
+
+func firstKey(m map[string]string, value string) string {
+	for k, v := range m {
+		if v == value {
+			return k
+		}
+	}
+	return ""
+}
+
+// axisProvider embeds each known word as its own axis, anything else as the diagonal
+type axisProvider map[string][]float32
+
+func (p axisProvider) Embed(text string) ([]float32, error) {
+	if v, ok := p[text]; ok {
+		return v, nil
+	}
+	return []float32{1, 1, 1}, nil
+}
+
+func (p axisProvider) Dim() int     { return 3 }
+func (p axisProvider) Name() string { return "axis" }
+
+func TestVectorStore_HNSWDeleteAndReopen(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{EmbeddingDim: 3, HNSWPath: filepath.Join(dir, "vector.index")}
+	embed := axisProvider{"apple": {1, 0, 0}, "bolt": {0, 1, 0}, "cloud": {0, 0, 1}}
+	open := func() *VectorMemoryStore {
+		store, err := NewVectorMemoryStore(filepath.Join(dir, "vec.db"), cfg)
+		if err != nil {
+			t.Fatalf("new store: %v", err)
+		}
+		store.embedding = embed
+		store.hnsw, err = NewHNSWIndex(HNSWConfig{Dim: 3, Distance: "cosine", StoragePath: cfg.HNSWPath})
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.loadExistingVectors()
+		return store
+	}
+	nearest := func(store *VectorMemoryStore, word string) string {
+		results, err := store.hnswSearch(nil, embed[word], 1, 0)
+		if err != nil || len(results) != 1 {
+			t.Fatalf("hnswSearch(%s) = %v, %v", word, results, err)
+		}
+		return results[0].Entry.Text
+	}
+
+	store := open()
+	ids := map[string]string{}
+	// Enough rows that one deletion stays below the 10% rebuild threshold
+	for i := 0; i < 10; i++ {
+		if _, err := store.Store(fmt.Sprintf("note %d", i), "fact", 0.5); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, word := range []string{"apple", "bolt", "cloud"} {
+		id, err := store.Store(word, "fact", 0.5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[word] = id
+	}
+	if ok, err := store.Delete(ids["apple"]); !ok || err != nil {
+		t.Fatalf("Delete = %v, %v", ok, err)
+	}
+	if n := store.hnswUnsaved.Load(); n != 1 {
+		t.Errorf("unsaved deletions = %d; Delete should leave saving to Close", n)
+	}
+	for _, word := range []string{"bolt", "cloud"} {
+		if got := nearest(store, word); got != word {
+			t.Errorf("nearest(%s) = %s after delete", word, got)
+		}
+	}
+	store.Close()
+
+	store = open()
+	defer store.Close()
+	if !store.hnsw.Loaded() || len(store.hnswIDs) != 13 || store.hnswIDs[10] != "" {
+		t.Fatalf("index not restored from disk: loaded=%v ids=%q", store.hnsw.Loaded(), store.hnswIDs)
+	}
+	if got := nearest(store, "cloud"); got != "cloud" {
+		t.Errorf("nearest(cloud) = %s after reopen", got)
+	}
+
+	// Scoped searches walk the graph past memories outside the scope
+	if _, err := store.StoreOwned(Ownership{Owner: "telegram_1"}, "apple", "fact", 0.5, "auto"); err != nil {
+		t.Fatal(err)
+	}
+	results, err := store.hnswSearch(&Scope{Owner: "telegram_1"}, embed["cloud"], 3, 0)
+	if err != nil || len(results) != 1 || results[0].Entry.Text != "apple" {
+		t.Errorf("scoped hnswSearch = %+v, %v; want only the owned memory", results, err)
+	}
+}
+
+func TestLegacyMemoriesLeaveRecall(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vec.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`CREATE TABLE vector_memories (
+		id TEXT PRIMARY KEY, text TEXT NOT NULL, vector BLOB, importance REAL, category TEXT,
+		source TEXT, created_at INTEGER, updated_at INTEGER, embedding_dim INTEGER)`); err != nil {
+		t.Fatal(err)
+	}
+	db.Exec(`INSERT INTO vector_memories VALUES ('m1', 'alice lives in lisbon', NULL, 0.5, 'fact', 'manual', 0, 0, 0)`)
+	db.Close()
+
+	store, err := NewVectorMemoryStore(path, Config{EmbeddingDim: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if e, err := store.Get("m1"); err != nil || e.Owner != LegacyOwner {
+		t.Fatalf("legacy memory = %+v, %v", e, err)
+	}
+	if results, _ := store.SearchScope(UserScope("", "telegram_bob"), "lisbon", 5, 0); len(results) != 0 {
+		t.Errorf("a session recalls a legacy memory: %+v", results)
+	}
+	if results, _ := store.Search("lisbon", 5, 0); len(results) != 1 {
+		t.Errorf("unscoped search = %+v", results)
+	}
+}
@@ -0,0 +1,313 @@
+// Package commands is the slash-command registry: built-ins, skills and
+// plugins register commands with their arguments, help text and permission
+// level, and channels list them for /help and command menus.
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Level is the permission a caller needs to run a command
+type Level int
+
+const (
+	LevelUser  Level = iota // Anyone who can chat with the agent
+	LevelAdmin              // Operators (see AgentConfig.Commands)
+)
+
+func (l Level) String() string {
+	if l == LevelAdmin {
+		return "admin"
+	}
+	return "user"
+}
+
+// Arg describes one positional argument of a command
+type Arg struct {
+	Name     string
+	Help     string
+	Int      bool // Must be an integer
+	Required bool
+	Rest     bool // Takes the rest of the line; only valid as the last argument
+}
+
+// Command is a slash command. Name may be several words for subcommands
+// ("task list"); the words before the last group them in /help.
+type Command struct {
+	Name      string
+	Help      string
+	Args      []Arg
+	Level     Level
+	Source    string // "builtin", "skill" or "plugin"
+	Immediate bool   // Runs without waiting for the session's running turn (/stop)
+	ExtraArgs bool   // Ignores text after its arguments instead of rejecting it ("/new hi")
+
+	// Handler produces the reply. Without a handler the command is a
+	// directive that chat routing interprets itself (/text, /live).
+	Handler func(ctx context.Context, inv *Invocation) (string, error)
+
+	// Prompt, when set instead of Handler, turns the command into the user
+	// message the model answers.
+	Prompt func(inv *Invocation) string
+}
+
+// Usage returns the command line synopsis, e.g. "/task detail <task-id> [page]"
+func (c *Command) Usage() string {
+	var b strings.Builder
+	b.WriteString("/" + c.Name)
+	for _, a := range c.Args {
+		name := a.Name
+		if a.Rest {
+			name += "..."
+		}
+		if a.Required {
+			b.WriteString(" <" + name + ">")
+		} else {
+			b.WriteString(" [" + name + "]")
+		}
+	}
+	return b.String()
+}
+
+// Invocation is one parsed use of a command
+type Invocation struct {
+	Command    *Command
+	SessionKey string
+	Level      Level
+	Args       map[string]string
+}
+
+// Arg returns a string argument, or "" when it was not given
+func (inv *Invocation) Arg(name string) string {
+	return inv.Args[name]
+}
+
+// Int returns an integer argument, or def when it was not given
+func (inv *Invocation) Int(name string, def int) int {
+	if n, err := strconv.Atoi(inv.Args[name]); err == nil {
+		return n
+	}
+	return def
+}
+
+// Registry holds commands by name. A registry created with a parent also
+// offers the parent's commands, its own taking precedence.
+type Registry struct {
+	mu     sync.RWMutex
+	cmds   map[string]*Command
+	parent *Registry
+}
+
+// Default is the process-wide registry plugins register with
+var Default = NewRegistry(nil)
+
+// Register adds a command to the Default registry
+func Register(c Command) error {
+	return Default.Register(c)
+}
+
+// NewRegistry creates a registry that falls back to parent (may be nil)
+func NewRegistry(parent *Registry) *Registry {
+	return &Registry{cmds: make(map[string]*Command), parent: parent}
+}
+
+// Register adds a command; names must be unique within the registry
+func (r *Registry) Register(c Command) error {
+	c.Name = normalize(c.Name)
+	if c.Name == "" {
+		return fmt.Errorf("command name is empty")
+	}
+	if c.Handler != nil && c.Prompt != nil {
+		return fmt.Errorf("command /%s: set either Handler or Prompt", c.Name)
+	}
+	for i, a := range c.Args {
+		if a.Rest && i != len(c.Args)-1 {
+			return fmt.Errorf("command /%s: rest argument %s must be last", c.Name, a.Name)
+		}
+	}
+	if c.Source == "" {
+		c.Source = "builtin"
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.cmds[c.Name]; ok {
+		return fmt.Errorf("command /%s already registered", c.Name)
+	}
+	r.cmds[c.Name] = &c
+	return nil
+}
+
+// Unregister removes a command by name
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	delete(r.cmds, normalize(name))
+	r.mu.Unlock()
+}
+
+// Get returns a command by name
+func (r *Registry) Get(name string) (*Command, bool) {
+	name = normalize(name)
+	r.mu.RLock()
+	c, ok := r.cmds[name]
+	r.mu.RUnlock()
+	if !ok && r.parent != nil {
+		return r.parent.Get(name)
+	}
+	return c, ok
+}
+
+// normalize lower-cases a command name and drops its slash and extra spaces
+func normalize(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(strings.TrimPrefix(name, "/")), " "))
+}
+
+// Commands returns every command, sorted by name
+func (r *Registry) Commands() []*Command {
+	byName := make(map[string]*Command)
+	for reg := r; reg != nil; reg = reg.parent {
+		reg.mu.RLock()
+		for name, c := range reg.cmds {
+			if _, ok := byName[name]; !ok {
+				byName[name] = c
+			}
+		}
+		reg.mu.RUnlock()
+	}
+	list := make([]*Command, 0, len(byName))
+	for _, c := range byName {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Group returns the commands under a name prefix ("task" for "task list")
+func (r *Registry) Group(prefix string) []*Command {
+	var group []*Command
+	for _, c := range r.Commands() {
+		if strings.HasPrefix(c.Name, prefix+" ") {
+			group = append(group, c)
+		}
+	}
+	return group
+}
+
+// Match finds the command a message invokes: the longest registered name
+// formed by its leading words. A "@botname" suffix on the first word, as
+// Telegram sends in groups, is ignored. rest is the text after the name.
+func (r *Registry) Match(text string) (c *Command, rest string, ok bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return nil, "", false
+	}
+	words := strings.Fields(text[1:])
+	if len(words) == 0 {
+		return nil, "", false
+	}
+	words[0], _, _ = strings.Cut(words[0], "@")
+	for n := len(words); n > 0; n-- {
+		if c, ok := r.Get(strings.Join(words[:n], " ")); ok {
+			return c, restAfter(text, n), true
+		}
+	}
+	return nil, "", false
+}
+
+// restAfter returns text with its first n words removed
+func restAfter(text string, n int) string {
+	for i := 0; i < n; i++ {
+		text = strings.TrimLeft(text, " \t\n")
+		if end := strings.IndexAny(text, " \t\n"); end >= 0 {
+			text = text[end:]
+		} else {
+			text = ""
+		}
+	}
+	return strings.TrimSpace(text)
+}
+
+// Parse binds the words of rest to the command's arguments
+func (c *Command) Parse(rest string) (map[string]string, error) {
+	args := make(map[string]string, len(c.Args))
+	for _, a := range c.Args {
+		rest = strings.TrimSpace(rest)
+		if rest == "" {
+			if a.Required {
+				return nil, fmt.Errorf("missing %s", a.Name)
+			}
+			continue
+		}
+		value := rest
+		if a.Rest {
+			rest = ""
+		} else if end := strings.IndexAny(rest, " \t\n"); end >= 0 {
+			value, rest = rest[:end], rest[end:]
+		} else {
+			rest = ""
+		}
+		if a.Int {
+			if _, err := strconv.Atoi(value); err != nil {
+				return nil, fmt.Errorf("%s must be a number", a.Name)
+			}
+		}
+		args[a.Name] = value
+	}
+	if strings.TrimSpace(rest) != "" && !c.ExtraArgs {
+		return nil, fmt.Errorf("unexpected argument %q", strings.Fields(rest)[0])
+	}
+	return args, nil
+}
+
+// Help lists the commands a caller at level may run, one usage line each
+func (r *Registry) Help(level Level) string {
+	var b strings.Builder
+	b.WriteString("Commands:")
+	for _, c := range r.Commands() {
+		if c.Level > level {
+			continue
+		}
+		b.WriteString("\n" + c.Usage())
+		if c.Help != "" {
+			b.WriteString(" - " + c.Help)
+		}
+	}
+	return b.String()
+}
+
+// MenuEntry is one item of a channel command menu
+type MenuEntry struct {
+	Command     string // Top-level name without the slash
+	Description string
+}
+
+// Menu returns the top-level commands a caller at level may run, with
+// subcommand groups collapsed into one entry, for channel command menus
+func (r *Registry) Menu(level Level) []MenuEntry {
+	var menu []MenuEntry
+	seen := make(map[string]int)
+	for _, c := range r.Commands() {
+		if c.Level > level {
+			continue
+		}
+		top, sub, grouped := strings.Cut(c.Name, " ")
+		if i, ok := seen[top]; ok {
+			if grouped {
+				menu[i].Description += ", " + sub
+			}
+			continue
+		}
+		desc := c.Help
+		if grouped {
+			desc = strings.ToUpper(top[:1]) + top[1:] + " commands: " + sub
+		}
+		seen[top] = len(menu)
+		menu = append(menu, MenuEntry{Command: top, Description: desc})
+	}
+	return menu
+}
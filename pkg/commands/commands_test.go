@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func newTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+	r := NewRegistry(nil)
+	for _, c := range []Command{
+		{Name: "help", Help: "List commands"},
+		{Name: "task list", Help: "List tasks", Args: []Arg{{Name: "limit", Int: true}}},
+		{Name: "task detail", Help: "Show a task", Args: []Arg{{Name: "task-id", Required: true}, {Name: "page", Int: true}}},
+		{Name: "split", Help: "Split a task", Args: []Arg{{Name: "task", Required: true, Rest: true}}},
+		{Name: "debug archive", Help: "Show archives", Level: LevelAdmin},
+	} {
+		if err := r.Register(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+func TestRegistryMatchAndParse(t *testing.T) {
+	r := newTestRegistry(t)
+	cases := []struct {
+		text, name string
+		args       map[string]string
+		err        string
+	}{
+		{text: "/task detail t-1 2", name: "task detail", args: map[string]string{"task-id": "t-1", "page": "2"}},
+		{text: "/Task@ocg_bot list", name: "task list", args: map[string]string{}},
+		{text: "/split  sum up\tthe notes ", name: "split", args: map[string]string{"task": "sum up\tthe notes"}},
+		{text: "/task detail", name: "task detail", err: "missing task-id"},
+		{text: "/task list ten", name: "task list", err: "limit must be a number"},
+		{text: "/help me now", name: "help", err: `unexpected argument "me"`},
+		{text: "/task", name: ""},
+		{text: "task list", name: ""},
+	}
+	for _, tc := range cases {
+		c, rest, ok := r.Match(tc.text)
+		if tc.name == "" {
+			if ok {
+				t.Errorf("Match(%q) = /%s, want no match", tc.text, c.Name)
+			}
+			continue
+		}
+		if !ok || c.Name != tc.name {
+			t.Errorf("Match(%q) = %v, %v; want /%s", tc.text, c, ok, tc.name)
+			continue
+		}
+		args, err := c.Parse(rest)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("Parse(%q) error = %v, want %s", tc.text, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || fmt.Sprint(args) != fmt.Sprint(tc.args) {
+			t.Errorf("Parse(%q) = %v, %v; want %v", tc.text, args, err, tc.args)
+		}
+	}
+
+	// ExtraArgs commands ignore what follows their arguments
+	c := Command{Name: "new", ExtraArgs: true}
+	if args, err := c.Parse(" let's plan"); err != nil || len(args) != 0 {
+		t.Errorf("Parse with extra args = %v, %v", args, err)
+	}
+}
+
+func TestRegistryRegister(t *testing.T) {
+	r := newTestRegistry(t)
+	if err := r.Register(Command{Name: "/Help"}); err == nil {
+		t.Error("duplicate command registered")
+	}
+	if err := r.Register(Command{Name: "bad", Args: []Arg{{Name: "a", Rest: true}, {Name: "b"}}}); err == nil {
+		t.Error("rest argument accepted before another argument")
+	}
+
+	child := NewRegistry(r)
+	if err := child.Register(Command{Name: "help", Help: "Own help"}); err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := child.Get("help"); !ok || c.Help != "Own help" {
+		t.Errorf("child Get(help) = %+v", c)
+	}
+	if _, ok := child.Get("split"); !ok {
+		t.Error("parent commands not visible through the child")
+	}
+	if n := len(child.Commands()); n != 5 {
+		t.Errorf("child has %d commands, want 5", n)
+	}
+}
+
+func TestRegistryHelpAndMenu(t *testing.T) {
+	r := newTestRegistry(t)
+	if c, _ := r.Get("task detail"); c.Usage() != "/task detail <task-id> [page]" {
+		t.Errorf("usage = %q", c.Usage())
+	}
+
+	help := r.Help(LevelUser)
+	if !strings.Contains(help, "/split <task...> - Split a task") || strings.Contains(help, "debug") {
+		t.Errorf("user help = %q", help)
+	}
+	if !strings.Contains(r.Help(LevelAdmin), "/debug archive - Show archives") {
+		t.Error("admin help misses admin commands")
+	}
+
+	want := []MenuEntry{
+		{Command: "help", Description: "List commands"},
+		{Command: "split", Description: "Split a task"},
+		{Command: "task", Description: "Task commands: detail, list"},
+	}
+	if got := r.Menu(LevelUser); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("menu = %v, want %v", got, want)
+	}
+}
@@ -140,3 +140,10 @@ func TestServerConfig(t *testing.T) {
 		t.Fatal("Agent should not be nil")
 	}
 }
+
+func TestParseMemoryTokens(t *testing.T) {
+	tokens := ParseMemoryTokens(" tok1=telegram_1, bad, tok2 = slack_2,=x")
+	if len(tokens) != 2 || tokens["tok1"] != "telegram_1" || tokens["tok2"] != "slack_2" {
+		t.Errorf("ParseMemoryTokens = %v", tokens)
+	}
+}
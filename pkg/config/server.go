@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gliderlab/cogate/pkg/llm"
@@ -36,6 +37,9 @@ type GatewayConfig struct {
 	CronJobsPath  string // Cron jobs file path (override)
 	TelegramToken string // Telegram bot token (override)
 
+	// Tokens for /memory/* bound to one session each (token -> session key)
+	MemoryTokens map[string]string
+
 	// Hot reload config
 	ReloadMode    string        // "hot", "hybrid", "restart", "off" (default: "hybrid")
 	ReloadDebounce time.Duration // Debounce for file changes (default: 300ms)
@@ -165,10 +169,119 @@ type ConfigGroup struct {
 	ContextWindow int    `json:"contextWindow,omitempty"`
 }
 
+// ModelRoute sends one kind of LLM call to a provider group and/or model.
+// Rules for a purpose are checked in order and the first match wins; a rule
+// without conditions always matches.
+type ModelRoute struct {
+	Provider        string `json:"provider,omitempty"`        // Config group name (empty = default provider)
+	Model           string `json:"model,omitempty"`           // Model override (empty = the group's model)
+	MinPromptTokens int    `json:"minPromptTokens,omitempty"` // Match only prompts with at least this many tokens
+	MaxPromptTokens int    `json:"maxPromptTokens,omitempty"` // Match only prompts with at most this many tokens
+	ToolResults     *bool  `json:"toolResults,omitempty"`     // Match only prompts with (true) or without (false) tool results
+}
+
+// BudgetLimits caps what one scope may use; zero fields are unlimited
+type BudgetLimits struct {
+	TokensPerDay int     `json:"tokensPerDay,omitempty"` // Prompt + completion tokens per UTC day
+	ToolDepth    int     `json:"toolDepth,omitempty"`    // Tool-call rounds per turn
+	CostPerTurn  float64 `json:"costPerTurn,omitempty"`  // USD per turn, tool rounds included
+}
+
+// Budget pairs soft limits, which only warn, with hard limits, which stop the turn
+type Budget struct {
+	Soft BudgetLimits `json:"soft,omitempty"`
+	Hard BudgetLimits `json:"hard,omitempty"`
+}
+
+// BudgetConfig assigns budgets to sessions, channels and cron jobs. The key "*"
+// applies to every session, channel or job that has no entry of its own; all
+// matching scopes are enforced.
+type BudgetConfig struct {
+	Sessions map[string]Budget `json:"sessions,omitempty"` // By session key
+	Channels map[string]Budget `json:"channels,omitempty"` // By channel (telegram, discord, hook, cron, ...)
+	Cron     map[string]Budget `json:"cron,omitempty"`     // By cron job ID
+}
+
+// ApprovalConfig lists tool calls that pause until a human approves them in
+// the originating channel ("ask" policy)
+type ApprovalConfig struct {
+	Tools          []string `json:"tools,omitempty"`          // Tool names, groups or tool:action pairs (process:start, gateway:update.run)
+	Patterns       []string `json:"patterns,omitempty"`       // Regexps matched against "tool {json args}"
+	TimeoutSeconds int      `json:"timeoutSeconds,omitempty"` // Unanswered requests are denied after this (default: 50)
+}
+
+// ToolSelectionConfig sends each request only the tools most relevant to the
+// user turn, ranked by embedding similarity to their descriptions
+type ToolSelectionConfig struct {
+	Enabled bool     `json:"enabled,omitempty"`
+	TopN    int      `json:"topN,omitempty"`   // Ranked tools sent besides pinned ones (default: 12)
+	Pinned  []string `json:"pinned,omitempty"` // Tool names or groups always sent
+}
+
+// AgentProfile is a named agent served by the same agent process. Requests
+// pick it by agent ID; unset fields fall back to the top-level settings.
+type AgentProfile struct {
+	Name            string             `json:"name,omitempty"`            // Display name
+	SystemPrompt    string             `json:"systemPrompt,omitempty"`    // Replaces the built-in system prompt
+	BootFile        string             `json:"bootFile,omitempty"`        // BOOT.md injected into new sessions (relative to the workspace)
+	Provider        string             `json:"provider,omitempty"`        // Config group for chat turns
+	Model           string             `json:"model,omitempty"`           // Model for chat turns
+	Tools           ProfileToolsConfig `json:"tools,omitempty"`           // Tools the agent may see and call
+	Skills          []string           `json:"skills,omitempty"`          // Skills the agent may use (nil = all)
+	MemoryNamespace string             `json:"memoryNamespace,omitempty"` // Memories the agent captures and recalls (empty = shared default)
+}
+
+// ProfileToolsConfig is the tool policy of one agent profile, the JSON form
+// of tools.ToolsPolicy. Allow and Deny narrow the registry's tools (names,
+// groups or "*"; an allowed tool overrides a deny). Ask and AskPatterns hold
+// calls for approval on top of the top-level approvals.
+type ProfileToolsConfig struct {
+	Allow       []string `json:"allow,omitempty"` // Empty allows every tool not denied
+	Deny        []string `json:"deny,omitempty"`
+	Ask         []string `json:"ask,omitempty"`         // Tool names, groups or tool:action pairs
+	AskPatterns []string `json:"askPatterns,omitempty"` // Regexps matched against "tool {json args}"
+}
+
+// CommandsConfig sets who may run admin-level slash commands (/debug)
+type CommandsConfig struct {
+	Admins []string `json:"admins,omitempty"` // Session keys or "prefix*"; empty makes everyone an admin
+}
+
+// MemoryConsolidationConfig tunes memory consolidation, which merges
+// near-duplicate memories and archives decayed ones. The pulse loop runs it
+// every IntervalMinutes; /memory consolidate runs it on demand.
+type MemoryConsolidationConfig struct {
+	IntervalMinutes int     `json:"intervalMinutes,omitempty"` // Pulse runs it this often (0 = only on demand)
+	Threshold       float64 `json:"threshold,omitempty"`       // Similarity at which memories merge (default: 0.92)
+	Rewrite         bool    `json:"rewrite,omitempty"`         // Merge texts with the consolidate model route instead of keeping the best one
+	HalfLifeDays    float64 `json:"halfLifeDays,omitempty"`    // Retrieval weight halves over this many days, slower for important memories (0 = no decay)
+	ArchiveBelow    float64 `json:"archiveBelow,omitempty"`    // Archive memories whose decay weight falls below this (default: 0.1)
+}
+
+// KnowledgeGraphConfig turns on automatic knowledge-graph building and
+// graph-expanded recall. Extraction uses the graph-extract model route, or
+// the chat model when none is set.
+type KnowledgeGraphConfig struct {
+	Extract     bool `json:"extract,omitempty"`     // Extract entities and relations from turns and stored memories
+	BatchSize   int  `json:"batchSize,omitempty"`   // Pending memories extracted per turn (default: 5)
+	RecallHops  int  `json:"recallHops,omitempty"`  // Expand auto-recall with graph facts this many hops from mentioned entities (0 = off)
+	RecallFacts int  `json:"recallFacts,omitempty"` // Max graph facts added to recall (default: 10)
+}
+
 // AgentConfig holds all configurable Agent parameters
 type AgentConfig struct {
 	Provider         string                 `json:"provider,omitempty"` // Default provider name
 	Groups           map[string]ConfigGroup `json:"groups,omitempty"`   // Configuration groups (provider settings)
+	Fallbacks        []string               `json:"fallbacks,omitempty"` // Group names tried in order when the provider fails
+	Routes           map[string][]ModelRoute `json:"routes,omitempty"`   // Per-purpose model routing (chat, summarize, split, recall-rerank, title, consolidate, graph-extract)
+	Prices           map[string]llm.ModelPrice `json:"prices,omitempty"` // USD per 1M tokens by model or "provider/model"; merged over llm.DefaultPriceTable
+	Budgets          BudgetConfig              `json:"budgets,omitempty"` // Spend and turn limits per session, channel and cron job
+	Approvals        ApprovalConfig            `json:"approvals,omitempty"` // Tool calls that wait for human approval
+	ToolSelection    ToolSelectionConfig       `json:"toolSelection,omitempty"` // Send only the tools relevant to the turn
+	Profiles         map[string]AgentProfile   `json:"profiles,omitempty"` // Named agents by agent ID
+	Commands         CommandsConfig            `json:"commands,omitempty"` // Slash command permissions
+	MemoryConsolidation MemoryConsolidationConfig `json:"memoryConsolidation,omitempty"` // Memory dedupe, merge and decay
+	KnowledgeGraph   KnowledgeGraphConfig      `json:"knowledgeGraph,omitempty"` // Graph extraction and graph-expanded recall
 	Model            string        // LLM model name
 	APIKey           string        // API key for LLM provider
 	BaseURL          string        // Base URL for LLM API
@@ -178,6 +291,8 @@ type AgentConfig struct {
 	SoftTokens       int           // Soft limit tokens (default: 800)
 	Greeting         string        // Custom greeting message (default: English greeting)
 	ThinkingMode     string        // Thinking mode: off, on, stream (default: off)
+	ThinkingBudget   int           // Extended thinking token budget when thinking is on (default: 4000)
+	MaxParallelTools int           // Max tool calls of one turn run at once (default: 4; 1 = sequential)
 	CompactionThreshold float64    // Compress when context usage exceeds this ratio (default: 0.7 = 70%)
 	KeepMessages     int           // Messages to keep after compaction (default: 30)
 	AutoRecall       bool          // Enable automatic memory recall
@@ -263,6 +378,9 @@ func DefaultStorageConfig() *StorageConfig {
 type MemoryConfig struct {
 	DBPath          string  // Database path
 	HNSWPath        string  // HNSW index file path
+	HNSWM           int     // HNSW links per node (default: 16)
+	HNSWEfSearch    int     // HNSW search beam width (default: 100)
+	HNSWEfConstruct int     // HNSW construction beam width (default: 200)
 	EmbeddingServer string  // Local embedding server URL
 	EmbeddingModel  string  // OpenAI embedding model
 	EmbeddingApiKey string  // OpenAI embedding API key
@@ -452,6 +570,9 @@ func (c *ServerConfig) LoadFromEnv(prefix string) {
 	if v := getEnv(prefix + "UI_TOKEN"); v != "" {
 		c.Gateway.UIAuthToken = v
 	}
+	if v := getEnv(prefix + "MEMORY_TOKENS"); v != "" {
+		c.Gateway.MemoryTokens = ParseMemoryTokens(v)
+	}
 
 	// Agent overrides
 	if v := getEnv(prefix + "MODEL"); v != "" {
@@ -483,6 +604,15 @@ func (c *ServerConfig) LoadFromEnv(prefix string) {
 	if v := getEnv(prefix + "HNSW_PATH"); v != "" {
 		c.Memory.HNSWPath = v
 	}
+	if v := getEnv(prefix + "HNSW_M"); v != "" {
+		c.Memory.HNSWM = parseInt(v, c.Memory.HNSWM)
+	}
+	if v := getEnv(prefix + "HNSW_EF_SEARCH"); v != "" {
+		c.Memory.HNSWEfSearch = parseInt(v, c.Memory.HNSWEfSearch)
+	}
+	if v := getEnv(prefix + "HNSW_EF_CONSTRUCT"); v != "" {
+		c.Memory.HNSWEfConstruct = parseInt(v, c.Memory.HNSWEfConstruct)
+	}
 }
 
 // Helper functions
@@ -490,6 +620,19 @@ func getEnv(key string) string {
 	return os.Getenv(key)
 }
 
+// ParseMemoryTokens reads "token=sessionKey,..." pairs; malformed pairs are skipped
+func ParseMemoryTokens(s string) map[string]string {
+	tokens := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		token, session, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		token, session = strings.TrimSpace(token), strings.TrimSpace(session)
+		if ok && token != "" && session != "" {
+			tokens[token] = session
+		}
+	}
+	return tokens
+}
+
 func parseInt(s string, defaultVal int) int {
 	n, err := strconv.Atoi(s)
 	if err != nil {
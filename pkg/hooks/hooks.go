@@ -25,6 +25,15 @@ const (
 	// Gateway events
 	EventTypeGatewayStartup EventType = "gateway:startup"
 
+	// Budget events
+	EventTypeBudget         EventType = "budget"
+	EventTypeBudgetWarning  EventType = "budget:warning"
+	EventTypeBudgetExceeded EventType = "budget:exceeded"
+
+	// Approval events
+	EventTypeApproval         EventType = "approval"
+	EventTypeApprovalDecision EventType = "approval:decision"
+
 	// Message events
 	EventTypeMessage         EventType = "message"
 	EventTypeMessageReceived EventType = "message:received"
@@ -105,6 +114,16 @@ func ParseEventType(s string) EventType {
 		return EventTypeMessageReceived
 	case "message:sent":
 		return EventTypeMessageSent
+	case "budget":
+		return EventTypeBudget
+	case "budget:warning":
+		return EventTypeBudgetWarning
+	case "budget:exceeded":
+		return EventTypeBudgetExceeded
+	case "approval":
+		return EventTypeApproval
+	case "approval:decision":
+		return EventTypeApprovalDecision
 	default:
 		return EventType(s)
 	}
@@ -0,0 +1,368 @@
+// content.go - Multimodal content parts and the OpenAI-compatible message wire format
+package llm
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ContentPartType identifies the kind of an ordered message content part
+type ContentPartType string
+
+const (
+	PartText       ContentPartType = "text"
+	PartImage      ContentPartType = "image"
+	PartAudio      ContentPartType = "audio"
+	PartToolUse    ContentPartType = "tool_use"
+	PartToolResult ContentPartType = "tool_result"
+	PartThinking   ContentPartType = "thinking"
+)
+
+// ContentPart is one piece of a multimodal message. Which fields are used
+// depends on Type:
+//
+//	text        - Text
+//	image/audio - MimeType plus Data (inline bytes) or URL (remote image)
+//	tool_use    - ToolCallID, ToolName, Arguments (JSON)
+//	tool_result - ToolCallID, ToolName (optional), Text, IsError
+//	thinking    - Text, Signature; a redacted block has no Text and carries
+//	              its opaque payload in Signature
+//
+// Thinking parts are only understood by providers with extended thinking,
+// which need them returned unchanged alongside the turn's tool calls.
+type ContentPart struct {
+	Type       ContentPartType `json:"type"`
+	Text       string          `json:"text,omitempty"`
+	MimeType   string          `json:"mimeType,omitempty"`
+	Data       []byte          `json:"data,omitempty"`
+	URL        string          `json:"url,omitempty"`
+	ToolCallID string          `json:"toolCallId,omitempty"`
+	ToolName   string          `json:"toolName,omitempty"`
+	Arguments  string          `json:"arguments,omitempty"`
+	IsError    bool            `json:"isError,omitempty"`
+	Signature  string          `json:"signature,omitempty"`
+}
+
+// TextPart returns a text content part
+func TextPart(text string) ContentPart {
+	return ContentPart{Type: PartText, Text: text}
+}
+
+// ImagePart returns an inline image content part (e.g. a PNG screenshot)
+func ImagePart(mimeType string, data []byte) ContentPart {
+	return ContentPart{Type: PartImage, MimeType: mimeType, Data: data}
+}
+
+// ImageURLPart returns a remote image content part
+func ImageURLPart(url string) ContentPart {
+	return ContentPart{Type: PartImage, URL: url}
+}
+
+// AudioPart returns an inline audio content part
+func AudioPart(mimeType string, data []byte) ContentPart {
+	return ContentPart{Type: PartAudio, MimeType: mimeType, Data: data}
+}
+
+// ToolUsePart returns a tool call content part
+func ToolUsePart(id, name, arguments string) ContentPart {
+	return ContentPart{Type: PartToolUse, ToolCallID: id, ToolName: name, Arguments: arguments}
+}
+
+// ToolResultPart returns a tool result content part answering the call with the given ID
+func ToolResultPart(toolCallID, text string, isError bool) ContentPart {
+	return ContentPart{Type: PartToolResult, ToolCallID: toolCallID, Text: text, IsError: isError}
+}
+
+// ThinkingPart returns an extended thinking content part
+func ThinkingPart(text, signature string) ContentPart {
+	return ContentPart{Type: PartThinking, Text: text, Signature: signature}
+}
+
+// DataURL returns the part's inline data as a data: URL, or its URL if it has no data
+func (p ContentPart) DataURL() string {
+	if len(p.Data) == 0 {
+		return p.URL
+	}
+	mime := p.MimeType
+	if mime == "" {
+		mime = "application/octet-stream"
+	}
+	return "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(p.Data)
+}
+
+// ContentParts returns the message content in order. Content, when set, is
+// treated as a leading text part; tool calls from ToolCalls/ToolCall are not
+// included (see AllToolCalls).
+func (m Message) ContentParts() []ContentPart {
+	if m.Content == "" {
+		return m.Parts
+	}
+	parts := make([]ContentPart, 0, len(m.Parts)+1)
+	parts = append(parts, TextPart(m.Content))
+	return append(parts, m.Parts...)
+}
+
+// Text returns all text content of the message joined together
+func (m Message) Text() string {
+	if len(m.Parts) == 0 {
+		return m.Content
+	}
+	var sb strings.Builder
+	sb.WriteString(m.Content)
+	for _, p := range m.Parts {
+		if p.Type == PartText {
+			sb.WriteString(p.Text)
+		}
+	}
+	return sb.String()
+}
+
+// Thinking returns the extended thinking text of the message joined together
+func (m Message) Thinking() string {
+	var sb strings.Builder
+	for _, p := range m.Parts {
+		if p.Type == PartThinking {
+			sb.WriteString(p.Text)
+		}
+	}
+	return sb.String()
+}
+
+// HasMedia reports whether the message carries image or audio parts
+func (m Message) HasMedia() bool {
+	for _, p := range m.Parts {
+		if p.Type == PartImage || p.Type == PartAudio {
+			return true
+		}
+	}
+	return false
+}
+
+// AllToolCalls returns every tool call on the message: ToolCalls, the legacy
+// single ToolCall, and tool_use parts, in that order.
+func (m Message) AllToolCalls() []ToolCall {
+	calls := make([]ToolCall, 0, len(m.ToolCalls)+1)
+	calls = append(calls, m.ToolCalls...)
+	if m.ToolCall != nil && (len(m.ToolCalls) == 0 || m.ToolCalls[0].ID != m.ToolCall.ID) {
+		calls = append(calls, *m.ToolCall)
+	}
+	for _, p := range m.Parts {
+		if p.Type == PartToolUse {
+			calls = append(calls, ToolCall{
+				ID:       p.ToolCallID,
+				Type:     "function",
+				Function: &ToolFunction{Name: p.ToolName, Arguments: p.Arguments},
+			})
+		}
+	}
+	return calls
+}
+
+// ToolResults returns the tool results carried by the message: a role "tool"
+// message with ToolCallID is one result, and each tool_result part is another.
+func (m Message) ToolResults() []ContentPart {
+	var results []ContentPart
+	if m.Role == "tool" && m.ToolCallID != "" {
+		results = append(results, ContentPart{Type: PartToolResult, ToolCallID: m.ToolCallID, ToolName: m.Name, Text: m.Content})
+	}
+	for _, p := range m.Parts {
+		if p.Type == PartToolResult {
+			results = append(results, p)
+		}
+	}
+	return results
+}
+
+// ExpandToolResults splits messages that carry tool_result parts into one
+// role "tool" message per result, as required by OpenAI-compatible APIs.
+// Other content of such a message is kept in a leading message.
+func ExpandToolResults(msgs []Message) []Message {
+	out := make([]Message, 0, len(msgs))
+	for _, m := range msgs {
+		var rest []ContentPart
+		var results []ContentPart
+		for _, p := range m.Parts {
+			if p.Type == PartToolResult {
+				results = append(results, p)
+			} else {
+				rest = append(rest, p)
+			}
+		}
+		if len(results) == 0 {
+			out = append(out, m)
+			continue
+		}
+		lead := m
+		lead.Parts = rest
+		if lead.Content != "" || len(lead.Parts) > 0 || len(lead.ToolCalls) > 0 || lead.ToolCall != nil || lead.ToolCallID != "" {
+			out = append(out, lead)
+		}
+		for _, r := range results {
+			out = append(out, Message{Role: "tool", Name: r.ToolName, Content: r.Text, ToolCallID: r.ToolCallID})
+		}
+	}
+	return out
+}
+
+// ============ OpenAI-compatible wire format ============
+
+type openAIMessage struct {
+	Role       string          `json:"role"`
+	Content    json.RawMessage `json:"content"`
+	Name       string          `json:"name,omitempty"`
+	ToolCalls  []ToolCall      `json:"tool_calls,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+	ToolCall   *ToolCall       `json:"tool_call,omitempty"`
+}
+
+type openAIContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url,omitempty"`
+	InputAudio *struct {
+		Data   string `json:"data"`
+		Format string `json:"format"`
+	} `json:"input_audio,omitempty"`
+}
+
+// MarshalJSON encodes the message in the OpenAI chat format: content is a
+// string unless the message carries image/audio parts, and tool_use parts
+// become tool_calls. Tool_result parts are handled by ExpandToolResults.
+func (m Message) MarshalJSON() ([]byte, error) {
+	wire := openAIMessage{
+		Role:       m.Role,
+		Name:       m.Name,
+		ToolCallID: m.ToolCallID,
+		ToolCalls:  m.AllToolCalls(),
+	}
+	if len(wire.ToolCalls) == 0 {
+		wire.ToolCalls = nil
+	}
+
+	var content any = m.Text()
+	if m.HasMedia() {
+		parts := make([]openAIContentPart, 0, len(m.Parts)+1)
+		for _, p := range m.ContentParts() {
+			switch p.Type {
+			case PartText:
+				parts = append(parts, openAIContentPart{Type: "text", Text: p.Text})
+			case PartImage:
+				cp := openAIContentPart{Type: "image_url"}
+				cp.ImageURL = &struct {
+					URL string `json:"url"`
+				}{URL: p.DataURL()}
+				parts = append(parts, cp)
+			case PartAudio:
+				cp := openAIContentPart{Type: "input_audio"}
+				cp.InputAudio = &struct {
+					Data   string `json:"data"`
+					Format string `json:"format"`
+				}{Data: base64.StdEncoding.EncodeToString(p.Data), Format: AudioFormat(p.MimeType)}
+				parts = append(parts, cp)
+			}
+		}
+		content = parts
+	}
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	wire.Content = raw
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON decodes an OpenAI chat message whose content may be a string,
+// null, or an array of typed parts.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var wire openAIMessage
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	*m = Message{
+		Role:       wire.Role,
+		Name:       wire.Name,
+		ToolCalls:  wire.ToolCalls,
+		ToolCallID: wire.ToolCallID,
+		ToolCall:   wire.ToolCall,
+	}
+	if len(wire.Content) == 0 || string(wire.Content) == "null" {
+		return nil
+	}
+	if wire.Content[0] == '"' {
+		return json.Unmarshal(wire.Content, &m.Content)
+	}
+
+	var parts []openAIContentPart
+	if err := json.Unmarshal(wire.Content, &parts); err != nil {
+		return fmt.Errorf("message content: %w", err)
+	}
+	textOnly := true
+	for _, p := range parts {
+		if p.Type != "text" {
+			textOnly = false
+			break
+		}
+	}
+	for _, p := range parts {
+		switch {
+		case p.Type == "text" && textOnly:
+			m.Content += p.Text
+		case p.Type == "text":
+			m.Parts = append(m.Parts, TextPart(p.Text))
+		case p.Type == "image_url" && p.ImageURL != nil:
+			m.Parts = append(m.Parts, imagePartFromURL(p.ImageURL.URL))
+		case p.Type == "input_audio" && p.InputAudio != nil:
+			audio, err := base64.StdEncoding.DecodeString(p.InputAudio.Data)
+			if err != nil {
+				return fmt.Errorf("input_audio: %w", err)
+			}
+			m.Parts = append(m.Parts, AudioPart("audio/"+p.InputAudio.Format, audio))
+		}
+	}
+	return nil
+}
+
+// MarshalJSON encodes the request in the OpenAI chat format, expanding
+// tool_result parts into separate tool messages.
+func (r ChatRequest) MarshalJSON() ([]byte, error) {
+	type plain ChatRequest
+	p := plain(r)
+	p.Messages = ExpandToolResults(r.Messages)
+	return json.Marshal(p)
+}
+
+// imagePartFromURL decodes data: URLs into inline image parts
+func imagePartFromURL(url string) ContentPart {
+	if !strings.HasPrefix(url, "data:") {
+		return ImageURLPart(url)
+	}
+	meta, payload, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+	if !ok || !strings.HasSuffix(meta, ";base64") {
+		return ImageURLPart(url)
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return ImageURLPart(url)
+	}
+	return ImagePart(strings.TrimSuffix(meta, ";base64"), data)
+}
+
+// AudioFormat maps an audio MIME type to the short format name used by
+// OpenAI-style APIs (wav, mp3, ...). Unknown types default to wav.
+func AudioFormat(mimeType string) string {
+	_, sub, ok := strings.Cut(strings.ToLower(mimeType), "/")
+	if !ok || sub == "" {
+		return "wav"
+	}
+	switch sub {
+	case "mpeg":
+		return "mp3"
+	case "x-wav", "wave":
+		return "wav"
+	}
+	return sub
+}
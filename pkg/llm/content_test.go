@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMessageMarshalTextOnly(t *testing.T) {
+	data, err := json.Marshal(Message{Role: "user", Content: "Hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"role":"user","content":"Hello"}` {
+		t.Errorf("unexpected wire format: %s", data)
+	}
+}
+
+func TestMessageMultimodalRoundTrip(t *testing.T) {
+	png := []byte{0x89, 'P', 'N', 'G'}
+	msg := Message{
+		Role:    "user",
+		Content: "What is in this screenshot?",
+		Parts:   []ContentPart{ImagePart("image/png", png)},
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"type":"image_url"`) || !strings.Contains(string(data), "data:image/png;base64,") {
+		t.Fatalf("expected image_url content part, got %s", data)
+	}
+
+	var back Message
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Text() != msg.Content {
+		t.Errorf("text lost: %q", back.Text())
+	}
+	if !back.HasMedia() {
+		t.Fatal("image part lost")
+	}
+	for _, p := range back.Parts {
+		if p.Type == PartImage && (p.MimeType != "image/png" || !bytes.Equal(p.Data, png)) {
+			t.Errorf("image not decoded: %+v", p)
+		}
+	}
+}
+
+func TestMessageUnmarshalContentForms(t *testing.T) {
+	var m Message
+	if err := json.Unmarshal([]byte(`{"role":"assistant","content":null,"tool_calls":[{"id":"a","type":"function","function":{"name":"read","arguments":"{}"}},{"id":"b","type":"function","function":{"name":"exec","arguments":"{}"}}]}`), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Content != "" || len(m.AllToolCalls()) != 2 {
+		t.Errorf("expected 2 tool calls and no content, got %+v", m)
+	}
+
+	if err := json.Unmarshal([]byte(`{"role":"user","content":[{"type":"text","text":"a"},{"type":"text","text":"b"}]}`), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Content != "ab" || len(m.Parts) != 0 {
+		t.Errorf("text-only parts should collapse into Content, got %+v", m)
+	}
+}
+
+func TestExpandToolResults(t *testing.T) {
+	msgs := []Message{
+		{Role: "assistant", Parts: []ContentPart{ToolUsePart("a", "read", `{}`), ToolUsePart("b", "exec", `{}`)}},
+		{Role: "user", Parts: []ContentPart{ToolResultPart("a", "file", false), ToolResultPart("b", "boom", true)}},
+	}
+
+	out := ExpandToolResults(msgs)
+	if len(out) != 3 {
+		t.Fatalf("expected 3 messages, got %d: %+v", len(out), out)
+	}
+	if out[1].Role != "tool" || out[1].ToolCallID != "a" || out[2].ToolCallID != "b" {
+		t.Errorf("tool results not expanded in order: %+v", out[1:])
+	}
+
+	data, err := json.Marshal(msgs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"tool_calls":[{"id":"a"`) {
+		t.Errorf("tool_use parts should marshal as tool_calls: %s", data)
+	}
+}
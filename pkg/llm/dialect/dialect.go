@@ -0,0 +1,253 @@
+// dialect.go - Registry of text tool-call dialects and the parsers built on them
+//
+// Models without native tool calling (many local models served by Ollama or
+// llama.cpp) write their calls into the reply text in a format learned during
+// fine-tuning. A Dialect locates and parses one such format; Parse and Stream
+// use it to split a reply into user-visible text and tool calls.
+package dialect
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gliderlab/cogate/pkg/llm"
+)
+
+// Auto is the dialect name that tries every built-in dialect
+const Auto = "auto"
+
+// Call is one tool call recovered from text; Arguments is a JSON object
+type Call struct {
+	Name      string
+	Arguments string
+}
+
+// Dialect is one text format for tool calls
+type Dialect interface {
+	// Name is the key the dialect is selected by in the models config
+	Name() string
+	// Next locates the first call block in text. start is where the block
+	// begins, or where text ends with what may become its opening; -1 when
+	// there is neither. end is just past the block, -1 while it is incomplete.
+	Next(text string) (start, end int)
+	// Block parses a block found by Next. Final is set at the end of a reply,
+	// when a block that never closed is parsed as far as it goes.
+	Block(block string, final bool) ([]Call, error)
+}
+
+var (
+	mu       sync.RWMutex
+	registry = make(map[string]Dialect)
+	aliases  = make(map[string]string)
+	// order is the order Auto tries the dialects in
+	order []string
+)
+
+// Register adds a dialect, optionally under alternative names. Dialects
+// registered later are tried last by Auto.
+func Register(d Dialect, alias ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, exists := registry[d.Name()]; !exists {
+		order = append(order, d.Name())
+	}
+	registry[d.Name()] = d
+	for _, a := range alias {
+		aliases[a] = d.Name()
+	}
+}
+
+// Get returns the dialect registered under name or one of its aliases.
+// "auto" returns a dialect that recognizes every registered format.
+func Get(name string) (Dialect, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == Auto {
+		return autoDialect{}, true
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	if canonical, ok := aliases[name]; ok {
+		name = canonical
+	}
+	d, ok := registry[name]
+	return d, ok
+}
+
+// Names lists the registered dialects and aliases
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := []string{Auto}
+	for name := range registry {
+		names = append(names, name)
+	}
+	for alias := range aliases {
+		names = append(names, alias)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register(minimaxDialect{})
+	Register(anthropicDialect{})
+	Register(hermesDialect{}, "qwen")
+	Register(openaiDialect{}, "json")
+	Register(llamaDialect{}, "pythonic")
+}
+
+// Parse splits a complete reply into its tool calls and the remaining text.
+// Blocks that fail to parse stay in the text.
+func Parse(d Dialect, text string) ([]llm.ToolCall, string) {
+	return NewStream(d).Parse(text)
+}
+
+// Stream parses a reply as it streams in, holding back text that may be call
+// markup so it never reaches the user
+type Stream struct {
+	// Filter, when set, keeps only the calls to tools it accepts. A block
+	// with no call kept is not taken for markup and stays in the text.
+	Filter func(name string) bool
+
+	dialect Dialect
+	buf     string
+	calls   []llm.ToolCall
+}
+
+// NewStream returns an incremental parser for one reply
+func NewStream(d Dialect) *Stream {
+	return &Stream{dialect: d}
+}
+
+// Feed consumes a chunk and returns the text that is safe to show now
+func (s *Stream) Feed(chunk string) string {
+	s.buf += chunk
+	var out strings.Builder
+	for s.buf != "" {
+		start, end := s.dialect.Next(s.buf)
+		if start < 0 {
+			out.WriteString(s.buf)
+			s.buf = ""
+			break
+		}
+		out.WriteString(s.buf[:start])
+		s.buf = s.buf[start:]
+		if end < 0 {
+			break
+		}
+		end -= start
+		if !s.add(s.buf[:end], false) {
+			out.WriteString(s.buf[:end])
+		}
+		s.buf = s.buf[end:]
+	}
+	return out.String()
+}
+
+// Parse splits a complete reply like the package-level Parse, with the
+// stream's filter
+func (s *Stream) Parse(text string) ([]llm.ToolCall, string) {
+	visible := s.Feed(text)
+	calls, rest := s.Close()
+	return calls, strings.TrimSpace(visible + rest)
+}
+
+// Close parses whatever is held back and returns every call of the reply with
+// the text still to show
+func (s *Stream) Close() ([]llm.ToolCall, string) {
+	rest := s.buf
+	s.buf = ""
+	if rest != "" && s.add(rest, true) {
+		rest = ""
+	}
+	return s.calls, rest
+}
+
+// add parses a block and reports whether it held any calls
+func (s *Stream) add(block string, final bool) bool {
+	calls, err := s.dialect.Block(block, final)
+	if err != nil {
+		return false
+	}
+	kept := false
+	for _, c := range calls {
+		if s.Filter != nil && !s.Filter(c.Name) {
+			continue
+		}
+		s.calls = append(s.calls, llm.ToolCall{
+			ID:       fmt.Sprintf("call_%d", len(s.calls)),
+			Type:     "function",
+			Function: &llm.ToolFunction{Name: c.Name, Arguments: c.Arguments},
+		})
+		kept = true
+	}
+	return kept
+}
+
+// autoDialect recognizes every registered dialect, for models whose format is
+// not configured
+type autoDialect struct{}
+
+func (autoDialect) Name() string { return Auto }
+
+func (autoDialect) members() []Dialect {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make([]Dialect, 0, len(order))
+	for _, name := range order {
+		out = append(out, registry[name])
+	}
+	return out
+}
+
+// Next returns the earliest block any dialect finds, preferring complete ones
+func (a autoDialect) Next(text string) (int, int) {
+	start, end := -1, -1
+	for _, d := range a.members() {
+		s, e := d.Next(text)
+		if s < 0 {
+			continue
+		}
+		if start < 0 || s < start || (s == start && end < 0 && e >= 0) {
+			start, end = s, e
+		}
+	}
+	return start, end
+}
+
+func (a autoDialect) Block(block string, final bool) ([]Call, error) {
+	for _, d := range a.members() {
+		if s, e := d.Next(block); s != 0 || (e != len(block) && !final) {
+			continue
+		}
+		if calls, err := d.Block(block, final); err == nil && len(calls) > 0 {
+			return calls, nil
+		}
+	}
+	return nil, fmt.Errorf("no dialect parses the block")
+}
+
+// partialSuffix returns where text ends with a proper prefix of marker, or -1
+func partialSuffix(text, marker string) int {
+	for n := len(marker) - 1; n > 0; n-- {
+		if strings.HasSuffix(text, marker[:n]) {
+			return len(text) - n
+		}
+	}
+	return -1
+}
+
+// tagNext implements Next for blocks delimited by an opening and closing tag
+func tagNext(text, open, close string) (int, int) {
+	i := strings.Index(text, open)
+	if i < 0 {
+		return partialSuffix(text, open), -1
+	}
+	j := strings.Index(text[i+len(open):], close)
+	if j < 0 {
+		return i, -1
+	}
+	return i, i + len(open) + j + len(close)
+}
@@ -0,0 +1,157 @@
+package dialect
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gliderlab/cogate/pkg/llm"
+)
+
+func callList(calls []llm.ToolCall) []Call {
+	var out []Call
+	for _, c := range calls {
+		out = append(out, Call{Name: c.Function.Name, Arguments: c.Function.Arguments})
+	}
+	return out
+}
+
+var dialectCases = []struct {
+	dialect string
+	reply   string
+	calls   []Call
+	text    string
+}{
+	{
+		dialect: "minimax",
+		reply: "Reading it.\n<minimax:tool_call>\n<invoke name=\"read\">\n<parameter name=\"path\">a &amp; b.txt</parameter>\n" +
+			"<parameter name=\"code\">if x < 1 {}</parameter>\n</invoke>\n</minimax:tool_call>",
+		calls: []Call{{"read", `{"code":"if x < 1 {}","path":"a & b.txt"}`}},
+		text:  "Reading it.",
+	},
+	{
+		dialect: "anthropic",
+		reply: "<function_calls>\n<invoke name=\"exec\"><parameter name=\"command\">ls</parameter></invoke>\n" +
+			"<invoke name=\"read\"><parameter name=\"path\">x</parameter></invoke>\n</function_calls>\nDone.",
+		calls: []Call{{"exec", `{"command":"ls"}`}, {"read", `{"path":"x"}`}},
+		text:  "Done.",
+	},
+	{
+		dialect: "qwen",
+		reply:   "<tool_call>\n{\"name\": \"exec\", \"arguments\": {\"command\": \"ls -la\", \"timeout\": 5}}\n</tool_call>",
+		calls:   []Call{{"exec", `{"command":"ls -la","timeout":5}`}},
+	},
+	{
+		dialect: "openai",
+		reply:   "Sure:\n```json\n{\"tool_calls\": [{\"function\": {\"name\": \"read\", \"arguments\": \"{\\\"path\\\": \\\"x\\\"}\"}}]}\n```",
+		calls:   []Call{{"read", `{"path":"x"}`}},
+		text:    "Sure:",
+	},
+	{
+		dialect: "openai",
+		reply:   `The map {"a": 1} is not a call. [{"name": "exec", "parameters": {"command": "ls"}}]`,
+		calls:   []Call{{"exec", `{"command":"ls"}`}},
+		text:    `The map {"a": 1} is not a call.`,
+	},
+	{
+		dialect: "llama",
+		reply:   `[read(path="a.txt"), exec(command='echo "hi"', timeout=10, env={"A": [1, True, None]})]`,
+		calls:   []Call{{"read", `{"path":"a.txt"}`}, {"exec", `{"command":"echo \"hi\"","env":{"A":[1,true,null]},"timeout":10}`}},
+	},
+	{
+		dialect: "llama",
+		reply:   `<|python_tag|>{"name": "web_fetch", "parameters": {"url": "https://x"}}<|eom_id|>`,
+		calls:   []Call{{"web_fetch", `{"url":"https://x"}`}},
+	},
+	{
+		dialect: "llama",
+		reply:   "See [the docs](https://example.com) and arr[0].",
+		text:    "See [the docs](https://example.com) and arr[0].",
+	},
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range dialectCases {
+		d, ok := Get(tc.dialect)
+		if !ok {
+			t.Fatalf("dialect %s not registered", tc.dialect)
+		}
+		calls, text := Parse(d, tc.reply)
+		if got := callList(calls); !reflect.DeepEqual(got, tc.calls) {
+			t.Errorf("%s: calls = %v, want %v", tc.dialect, got, tc.calls)
+		}
+		if text != tc.text {
+			t.Errorf("%s: text = %q, want %q", tc.dialect, text, tc.text)
+		}
+	}
+}
+
+// TestStream feeds every reply a few bytes at a time and checks that no call
+// markup is ever shown and the calls match a one-shot parse
+func TestStream(t *testing.T) {
+	for _, tc := range dialectCases {
+		for _, size := range []int{1, 3, 7} {
+			d, _ := Get(tc.dialect)
+			s := NewStream(d)
+			var shown strings.Builder
+			for i := 0; i < len(tc.reply); i += size {
+				shown.WriteString(s.Feed(tc.reply[i:min(i+size, len(tc.reply))]))
+			}
+			calls, rest := s.Close()
+			shown.WriteString(rest)
+			if got := callList(calls); !reflect.DeepEqual(got, tc.calls) {
+				t.Errorf("%s/%d: calls = %v, want %v", tc.dialect, size, got, tc.calls)
+			}
+			if got := strings.TrimSpace(shown.String()); got != tc.text {
+				t.Errorf("%s/%d: shown %q, want %q", tc.dialect, size, got, tc.text)
+			}
+		}
+	}
+}
+
+func TestStreamReleasesTextEarly(t *testing.T) {
+	d, _ := Get("hermes")
+	s := NewStream(d)
+	if got := s.Feed("Let me check <tool"); got != "Let me check " {
+		t.Errorf("shown %q before a possible tag", got)
+	}
+	if got := s.Feed("s are fun"); got != "<tools are fun" {
+		t.Errorf("shown %q once the tag was ruled out", got)
+	}
+	// A block that never closes is parsed at the end of the reply
+	s.Feed(`<tool_call>{"name": "exec", "arguments": {}}`)
+	calls, rest := s.Close()
+	if len(calls) != 1 || calls[0].ID != "call_0" || rest != "" {
+		t.Errorf("unterminated block: calls %v, rest %q", callList(calls), rest)
+	}
+}
+
+func TestStreamFilter(t *testing.T) {
+	d, _ := Get("hermes")
+	s := NewStream(d)
+	s.Filter = func(name string) bool { return name == "read" }
+	reply := `Example: <tool_call>{"name": "foo", "arguments": {}}</tool_call> then <tool_call>{"name": "read", "arguments": {}}</tool_call>`
+	calls, text := s.Parse(reply)
+	if got := callList(calls); len(got) != 1 || got[0].Name != "read" {
+		t.Errorf("calls = %v", got)
+	}
+	if !strings.Contains(text, `"foo"`) || strings.Contains(text, `"read"`) {
+		t.Errorf("text = %q", text)
+	}
+}
+
+func TestAuto(t *testing.T) {
+	d, _ := Get(Auto)
+	for _, tc := range dialectCases {
+		calls, _ := Parse(d, tc.reply)
+		if got := callList(calls); !reflect.DeepEqual(got, tc.calls) {
+			t.Errorf("auto on %s: calls = %v, want %v", tc.dialect, got, tc.calls)
+		}
+	}
+	if calls, text := Parse(d, "Nothing to call here."); calls != nil || text != "Nothing to call here." {
+		t.Errorf("plain reply: calls %v, text %q", calls, text)
+	}
+	if _, ok := Get("no-such-dialect"); ok {
+		t.Error("unknown dialect resolved")
+	}
+}
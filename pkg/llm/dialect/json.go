@@ -0,0 +1,184 @@
+// json.go - OpenAI-style JSON tool calls written into the reply text
+package dialect
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// openaiDialect parses tool calls written as OpenAI-style JSON, bare or in a
+// ```json fence, in any of the shapes models produce:
+//
+//	{"name": "read", "arguments": {"path": "a.txt"}}
+//	[{"name": "read", "parameters": {...}}, ...]
+//	{"tool_calls": [{"function": {"name": "read", "arguments": "{...}"}}]}
+type openaiDialect struct{}
+
+func (openaiDialect) Name() string { return "openai" }
+
+func (openaiDialect) Next(text string) (int, int) {
+	for i := 0; i < len(text); i++ {
+		if text[i] != '{' && text[i] != '[' {
+			continue
+		}
+		n, err := jsonValueLen(text[i:])
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return fenceStart(text, i), -1
+		}
+		if err != nil {
+			continue
+		}
+		start, end := fenceStart(text, i), i+n
+		if start == i {
+			return start, end
+		}
+		// Fenced: the block runs through the closing fence
+		after := strings.TrimLeft(text[end:], " \t\r\n")
+		if strings.HasPrefix(after, "```") {
+			return start, len(text) - len(after) + 3
+		}
+		if strings.HasPrefix("```", after) {
+			return start, -1
+		}
+		return start, end
+	}
+	// Hold back what may be the opening fence of a call
+	trimmed := strings.TrimRight(text, " \t\r\n")
+	if strings.HasSuffix(trimmed, "```json") {
+		return len(trimmed) - len("```json"), -1
+	}
+	return partialSuffix(trimmed, "```json"), -1
+}
+
+func (openaiDialect) Block(block string, final bool) ([]Call, error) {
+	body := strings.TrimSpace(block)
+	if strings.HasPrefix(body, "```") {
+		body = strings.TrimPrefix(strings.TrimPrefix(body, "```"), "json")
+		body = strings.TrimSpace(strings.TrimSuffix(body, "```"))
+	}
+	return parseJSONCalls(body)
+}
+
+// fenceStart returns where a ```json or ``` fence directly before text[i] begins, else i
+func fenceStart(text string, i int) int {
+	before := strings.TrimRight(text[:i], " \t\r\n")
+	for _, fence := range []string{"```json", "```"} {
+		if strings.HasSuffix(before, fence) {
+			return len(before) - len(fence)
+		}
+	}
+	return i
+}
+
+// jsonValueLen returns the length of the JSON value text starts with
+func jsonValueLen(text string) (int, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	return int(dec.InputOffset()), nil
+}
+
+// marshalArgs encodes parsed arguments without escaping <, > and &, which
+// are common in commands and code
+func marshalArgs(args map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(args); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// jsonCall is the union of the call shapes models write
+type jsonCall struct {
+	Name       string          `json:"name"`
+	Arguments  json.RawMessage `json:"arguments"`
+	Parameters json.RawMessage `json:"parameters"`
+	Function   *jsonCall       `json:"function"`
+}
+
+// parseJSONCalls decodes a call, a list of calls or a {"tool_calls": [...]} object
+func parseJSONCalls(body string) ([]Call, error) {
+	data := []byte(body)
+	if len(data) > maxBlockBytes {
+		return nil, fmt.Errorf("tool call block of %d bytes is too large", len(data))
+	}
+	var list []jsonCall
+	if bytes.HasPrefix(data, []byte("[")) {
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, err
+		}
+	} else {
+		var wrapper struct {
+			ToolCalls []jsonCall `json:"tool_calls"`
+		}
+		if err := json.Unmarshal(data, &wrapper); err != nil {
+			return nil, err
+		}
+		list = wrapper.ToolCalls
+		if len(list) == 0 {
+			var one jsonCall
+			if err := json.Unmarshal(data, &one); err != nil {
+				return nil, err
+			}
+			list = []jsonCall{one}
+		}
+	}
+
+	calls := make([]Call, 0, len(list))
+	for _, jc := range list {
+		if jc.Function != nil {
+			jc = *jc.Function
+		}
+		if jc.Name == "" {
+			return nil, errors.New("tool call without a name")
+		}
+		args, err := jsonArguments(jc.Arguments, jc.Parameters)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", jc.Name, err)
+		}
+		calls = append(calls, Call{Name: jc.Name, Arguments: args})
+	}
+	if len(calls) == 0 {
+		return nil, errNoCalls
+	}
+	return calls, nil
+}
+
+// jsonArguments normalizes arguments given as an object or a JSON-encoded
+// string to a JSON object
+func jsonArguments(candidates ...json.RawMessage) (string, error) {
+	for _, raw := range candidates {
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 || string(raw) == "null" {
+			continue
+		}
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			raw = []byte(strings.TrimSpace(s))
+			if len(raw) == 0 {
+				continue
+			}
+		}
+		var obj map[string]interface{}
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return "", errors.New("arguments are not a JSON object")
+		}
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, raw); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+	return "{}", nil
+}
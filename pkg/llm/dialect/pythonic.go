@@ -0,0 +1,351 @@
+// pythonic.go - Llama pythonic tool calls: [get_weather(city="Paris"), ...]
+package dialect
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	pythonTag = "<|python_tag|>"
+	eomToken  = "<|eom_id|>"
+	eotToken  = "<|eot_id|>"
+)
+
+// llamaDialect parses the pythonic call lists of Llama 3.2 and 4:
+//
+//	[read(path="a.txt"), exec(command="ls", timeout=10)]
+//
+// and Llama 3.1 calls after <|python_tag|>, written as JSON or a single
+// pythonic call and ended by <|eom_id|>, <|eot_id|> or the end of the reply.
+type llamaDialect struct{}
+
+func (llamaDialect) Name() string { return "llama" }
+
+func (llamaDialect) Next(text string) (int, int) {
+	tagStart, tagEnd := -1, -1
+	if i := strings.Index(text, pythonTag); i >= 0 {
+		tagStart = i
+		if j := indexAny(text[i:], eomToken, eotToken); j >= 0 {
+			tagEnd = i + j
+		}
+	} else {
+		tagStart = partialSuffix(text, pythonTag)
+	}
+
+	for i := 0; i < len(text) && (tagStart < 0 || i < tagStart); i++ {
+		if text[i] != '[' {
+			continue
+		}
+		p := &pyParser{s: text[i:]}
+		_, err := p.callList()
+		if errors.Is(err, errIncomplete) {
+			return i, -1
+		}
+		if err == nil {
+			return i, i + p.i
+		}
+	}
+	return tagStart, tagEnd
+}
+
+func (llamaDialect) Block(block string, final bool) ([]Call, error) {
+	body := strings.TrimSpace(block)
+	if strings.HasPrefix(body, pythonTag) {
+		body = strings.TrimSpace(strings.TrimPrefix(body, pythonTag))
+		for _, tok := range []string{eomToken, eotToken} {
+			if i := strings.Index(body, tok); i >= 0 {
+				body = strings.TrimSpace(body[:i])
+			}
+		}
+		if strings.HasPrefix(body, "{") {
+			return parseJSONCalls(body)
+		}
+		if !strings.HasPrefix(body, "[") {
+			p := &pyParser{s: body}
+			call, err := p.call()
+			if err != nil {
+				return nil, err
+			}
+			return []Call{call}, nil
+		}
+	}
+	p := &pyParser{s: body}
+	calls, err := p.callList()
+	if err != nil {
+		return nil, err
+	}
+	if p.ws(); p.i != len(body) {
+		return nil, fmt.Errorf("unexpected text after calls: %q", body[p.i:])
+	}
+	return calls, nil
+}
+
+// indexAny returns the end of the first of subs found in s, or -1
+func indexAny(s string, subs ...string) int {
+	first, end := -1, -1
+	for _, sub := range subs {
+		if i := strings.Index(s, sub); i >= 0 && (first < 0 || i < first) {
+			first, end = i, i+len(sub)
+		}
+	}
+	return end
+}
+
+// errIncomplete reports that text ended inside a call
+var errIncomplete = errors.New("incomplete call")
+
+// pyParser reads Python call syntax with keyword arguments whose values are
+// literals: strings, numbers, booleans, None, lists and dicts
+type pyParser struct {
+	s string
+	i int
+}
+
+func (p *pyParser) ws() {
+	for p.i < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.i]) >= 0 {
+		p.i++
+	}
+}
+
+// peek skips whitespace and returns the next byte
+func (p *pyParser) peek() (byte, error) {
+	p.ws()
+	if p.i >= len(p.s) {
+		return 0, errIncomplete
+	}
+	return p.s[p.i], nil
+}
+
+func (p *pyParser) expect(c byte) error {
+	got, err := p.peek()
+	if err != nil {
+		return err
+	}
+	if got != c {
+		return fmt.Errorf("expected %q at %d, got %q", c, p.i, got)
+	}
+	p.i++
+	return nil
+}
+
+func (p *pyParser) callList() ([]Call, error) {
+	if err := p.expect('['); err != nil {
+		return nil, err
+	}
+	var calls []Call
+	for {
+		call, err := p.call()
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, call)
+		c, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		p.i++
+		switch c {
+		case ']':
+			return calls, nil
+		case ',':
+		default:
+			return nil, fmt.Errorf("expected , or ] at %d", p.i-1)
+		}
+	}
+}
+
+func (p *pyParser) call() (Call, error) {
+	name, err := p.ident(true)
+	if err != nil {
+		return Call{}, err
+	}
+	if err := p.expect('('); err != nil {
+		return Call{}, err
+	}
+	args := make(map[string]interface{})
+	for {
+		c, err := p.peek()
+		if err != nil {
+			return Call{}, err
+		}
+		if c == ')' {
+			p.i++
+			break
+		}
+		key, err := p.ident(false)
+		if err != nil {
+			return Call{}, err
+		}
+		if err := p.expect('='); err != nil {
+			return Call{}, err
+		}
+		val, err := p.value()
+		if err != nil {
+			return Call{}, err
+		}
+		args[key] = val
+		if c, err = p.peek(); err != nil {
+			return Call{}, err
+		}
+		if c == ',' {
+			p.i++
+		} else if c != ')' {
+			return Call{}, fmt.Errorf("expected , or ) at %d", p.i)
+		}
+	}
+	data, err := marshalArgs(args)
+	if err != nil {
+		return Call{}, err
+	}
+	return Call{Name: name, Arguments: data}, nil
+}
+
+// ident reads a Python identifier; dotted names are allowed for functions
+func (p *pyParser) ident(dotted bool) (string, error) {
+	if _, err := p.peek(); err != nil {
+		return "", err
+	}
+	start := p.i
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+			p.i > start && (c >= '0' && c <= '9' || dotted && c == '.') {
+			p.i++
+			continue
+		}
+		break
+	}
+	if p.i == start {
+		return "", fmt.Errorf("expected a name at %d", p.i)
+	}
+	if p.i == len(p.s) {
+		return "", errIncomplete
+	}
+	return p.s[start:p.i], nil
+}
+
+func (p *pyParser) value() (interface{}, error) {
+	c, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case c == '"' || c == '\'':
+		return p.str()
+	case c == '[':
+		p.i++
+		list := []interface{}{}
+		err := p.items(']', func() error {
+			v, err := p.value()
+			list = append(list, v)
+			return err
+		})
+		return list, err
+	case c == '{':
+		p.i++
+		obj := make(map[string]interface{})
+		err := p.items('}', func() error {
+			key, err := p.str()
+			if err != nil {
+				return err
+			}
+			if err := p.expect(':'); err != nil {
+				return err
+			}
+			obj[key], err = p.value()
+			return err
+		})
+		return obj, err
+	case c == '-' || c >= '0' && c <= '9':
+		start := p.i
+		p.i++
+		for p.i < len(p.s) && strings.IndexByte("0123456789.eE+-_", p.s[p.i]) >= 0 {
+			p.i++
+		}
+		if p.i == len(p.s) {
+			return nil, errIncomplete
+		}
+		return strconv.ParseFloat(strings.ReplaceAll(p.s[start:p.i], "_", ""), 64)
+	}
+	word, err := p.ident(false)
+	if err != nil {
+		return nil, err
+	}
+	switch word {
+	case "True", "true":
+		return true, nil
+	case "False", "false":
+		return false, nil
+	case "None", "null":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported value %q", word)
+}
+
+// items reads comma-separated elements up to the closing byte
+func (p *pyParser) items(end byte, elem func() error) error {
+	for {
+		c, err := p.peek()
+		if err != nil {
+			return err
+		}
+		if c == end {
+			p.i++
+			return nil
+		}
+		if err := elem(); err != nil {
+			return err
+		}
+		if c, err = p.peek(); err != nil {
+			return err
+		}
+		if c == ',' {
+			p.i++
+		} else if c != end {
+			return fmt.Errorf("expected , or %q at %d", end, p.i)
+		}
+	}
+}
+
+// str reads a single- or double-quoted string literal
+func (p *pyParser) str() (string, error) {
+	quote, err := p.peek()
+	if err != nil {
+		return "", err
+	}
+	if quote != '"' && quote != '\'' {
+		return "", fmt.Errorf("expected a string at %d", p.i)
+	}
+	p.i++
+	var b strings.Builder
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		p.i++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c == '\\':
+			if p.i >= len(p.s) {
+				return "", errIncomplete
+			}
+			esc := p.s[p.i]
+			p.i++
+			switch esc {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(esc)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", errIncomplete
+}
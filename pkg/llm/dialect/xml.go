@@ -0,0 +1,96 @@
+// xml.go - Tag-delimited dialects: MiniMax and Anthropic <invoke>, Hermes/Qwen <tool_call>
+package dialect
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	reInvoke      = regexp.MustCompile(`(?s)<invoke\s+name\s*=\s*"([^"]+)"\s*>(.*?)(?:</invoke>|$)`)
+	reParameter   = regexp.MustCompile(`(?s)<parameter\s+name\s*=\s*"([^"]+)"\s*>(.*?)</parameter>`)
+	errNoCalls    = errors.New("no tool calls in block")
+	maxBlockBytes = 1 << 20
+)
+
+// minimaxDialect parses MiniMax's format:
+//
+//	<minimax:tool_call>
+//	<invoke name="read"><parameter name="path">a.txt</parameter></invoke>
+//	</minimax:tool_call>
+type minimaxDialect struct{}
+
+func (minimaxDialect) Name() string { return "minimax" }
+
+func (minimaxDialect) Next(text string) (int, int) {
+	return tagNext(text, "<minimax:tool_call>", "</minimax:tool_call>")
+}
+
+func (minimaxDialect) Block(block string, final bool) ([]Call, error) {
+	return parseInvokes(block)
+}
+
+// anthropicDialect parses the XML format Claude models use when tools are
+// described in the prompt:
+//
+//	<function_calls>
+//	<invoke name="read"><parameter name="path">a.txt</parameter></invoke>
+//	</function_calls>
+type anthropicDialect struct{}
+
+func (anthropicDialect) Name() string { return "anthropic" }
+
+func (anthropicDialect) Next(text string) (int, int) {
+	return tagNext(text, "<function_calls>", "</function_calls>")
+}
+
+func (anthropicDialect) Block(block string, final bool) ([]Call, error) {
+	return parseInvokes(block)
+}
+
+// parseInvokes reads the <invoke> elements of a block. Parameter values are
+// passed as strings (unescaped); argument validation coerces them to the
+// types of the tool's schema.
+func parseInvokes(block string) ([]Call, error) {
+	if len(block) > maxBlockBytes {
+		return nil, fmt.Errorf("tool call block of %d bytes is too large", len(block))
+	}
+	var calls []Call
+	for _, m := range reInvoke.FindAllStringSubmatch(block, -1) {
+		args := make(map[string]interface{})
+		for _, p := range reParameter.FindAllStringSubmatch(m[2], -1) {
+			args[p[1]] = html.UnescapeString(strings.TrimSpace(p[2]))
+		}
+		data, err := marshalArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, Call{Name: strings.TrimSpace(m[1]), Arguments: data})
+	}
+	if len(calls) == 0 {
+		return nil, errNoCalls
+	}
+	return calls, nil
+}
+
+// hermesDialect parses the Hermes format also used by Qwen models:
+//
+//	<tool_call>
+//	{"name": "read", "arguments": {"path": "a.txt"}}
+//	</tool_call>
+type hermesDialect struct{}
+
+func (hermesDialect) Name() string { return "hermes" }
+
+func (hermesDialect) Next(text string) (int, int) {
+	return tagNext(text, "<tool_call>", "</tool_call>")
+}
+
+func (hermesDialect) Block(block string, final bool) ([]Call, error) {
+	body := strings.TrimPrefix(block, "<tool_call>")
+	body = strings.TrimSuffix(body, "</tool_call>")
+	return parseJSONCalls(strings.TrimSpace(body))
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/gliderlab/cogate/pkg/llm/providers/qianfan"
 	"github.com/gliderlab/cogate/pkg/llm/providers/vercel"
 	"github.com/gliderlab/cogate/pkg/llm/providers/zai"
+	"github.com/gliderlab/cogate/pkg/llm/providers/mock"
 )
 
 // InitProviders initializes all available LLM providers
@@ -100,9 +101,52 @@ func InitProviders() error {
 		fmt.Printf("[OK] Registered provider: Z.AI (model: %s)\n", zaiProvider.GetConfig().Model)
 	}
 
+	// Mock (scripted fixture, for offline tests)
+	if mockProvider := mock.NewFromEnv(); mockProvider != nil {
+		llm.RegisterProvider(mockProvider)
+		fmt.Printf("[OK] Registered provider: Mock (model: %s)\n", mockProvider.GetConfig().Model)
+	}
+
 	return nil
 }
 
+// NewProvider creates a provider instance of cfg.Type from an explicit config,
+// without touching the global registry. An empty type means OpenAI-compatible.
+func NewProvider(cfg llm.Config) (llm.Provider, error) {
+	switch cfg.Type {
+	case "", llm.ProviderOpenAI:
+		cfg.Type = llm.ProviderOpenAI
+		return openai.New(cfg), nil
+	case llm.ProviderAnthropic:
+		return anthropic.New(cfg), nil
+	case llm.ProviderGoogle:
+		return google.New(cfg), nil
+	case llm.ProviderMiniMax:
+		return minimax.New(cfg), nil
+	case llm.ProviderOllama:
+		return ollama.New(cfg), nil
+	case llm.ProviderCustom:
+		return custom.New(cfg), nil
+	case llm.ProviderOpenRouter:
+		return openrouter.New(cfg), nil
+	case llm.ProviderBedrock:
+		return bedrock.New(cfg), nil
+	case llm.ProviderMoonshot:
+		return moonshot.New(cfg), nil
+	case llm.ProviderGLM:
+		return glm.New(cfg), nil
+	case llm.ProviderQianfan:
+		return qianfan.New(cfg), nil
+	case llm.ProviderVercel:
+		return vercel.New(cfg), nil
+	case llm.ProviderZAi:
+		return zai.New(cfg), nil
+	case llm.ProviderMock:
+		return mock.New(cfg), nil
+	}
+	return nil, fmt.Errorf("unknown provider type: %s", cfg.Type)
+}
+
 // GetDefaultProvider returns the default provider based on available API keys
 func GetDefaultProvider() (llm.Provider, error) {
 	// Priority: OpenAI > Anthropic > Google > MiniMax > Ollama > Custom
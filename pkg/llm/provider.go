@@ -31,6 +31,7 @@ const (
 	ProviderQianfan    ProviderType = "qianfan"
 	ProviderVercel     ProviderType = "vercel"
 	ProviderZAi        ProviderType = "zai"
+	ProviderMock       ProviderType = "mock" // Scripted responses for offline tests
 )
 
 // Capability represents optional provider capabilities
@@ -42,20 +43,29 @@ const (
 	CapabilityTTS          Capability = "tts"
 	CapabilityTranscription Capability = "transcription"
 	CapabilityEmbeddings   Capability = "embeddings"
+	// CapabilityStructuredOutput marks providers that honor ChatRequest.ResponseFormat natively
+	CapabilityStructuredOutput Capability = "structured_output"
 )
 
 // Message represents a chat message
 type Message struct {
-	Role    string    `json:"role"`
-	Content string    `json:"content"`
-	Name    string    `json:"name,omitempty"`
-	ToolCall *ToolCall `json:"tool_call,omitempty"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	Name       string     `json:"name,omitempty"`
+	ToolCall   *ToolCall  `json:"tool_call,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // assistant: tool calls requested by the model
+	ToolCallID string     `json:"tool_call_id,omitempty"` // tool: ID of the call this message answers
+
+	// Parts holds ordered multimodal content (images, audio, tool_use, tool_result).
+	// Content, when set, is treated as a leading text part. See content.go.
+	Parts []ContentPart `json:"parts,omitempty"`
 }
 
 // ToolCall represents a function tool call
 type ToolCall struct {
-	ID       string `json:"id"`
-	Type     string `json:"type"`
+	Index    int           `json:"index,omitempty"` // position within a streamed delta
+	ID       string        `json:"id"`
+	Type     string        `json:"type"`
 	Function *ToolFunction `json:"function"`
 }
 
@@ -68,6 +78,75 @@ type ChatRequest struct {
 	TopP        float64   `json:"top_p,omitempty"`
 	Stream      bool      `json:"stream,omitempty"`
 	Tools       []Tool    `json:"tools,omitempty"`
+
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+
+	// ResponseFormat constrains the reply to JSON, optionally matching a schema.
+	// Only set it for providers with CapabilityStructuredOutput.
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+
+	// Thinking requests extended thinking from providers that support it
+	// (Anthropic); others ignore it. Thinking comes back as thinking parts.
+	Thinking *ThinkingOptions `json:"-"`
+}
+
+// ThinkingOptions configures extended thinking for a request
+type ThinkingOptions struct {
+	BudgetTokens int // Max tokens the model may spend thinking
+}
+
+// ResponseFormat is the OpenAI response_format: "json_object" for any JSON
+// object, "json_schema" for a reply matching JSONSchema.Schema
+type ResponseFormat struct {
+	Type       string          `json:"type"`
+	JSONSchema *JSONSchemaSpec `json:"json_schema,omitempty"`
+}
+
+// JSONSchemaSpec names the schema of a "json_schema" response format
+type JSONSchemaSpec struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Schema      map[string]interface{} `json:"schema,omitempty"`
+	Strict      bool                   `json:"strict,omitempty"`
+}
+
+// Response format types
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// Schema returns the JSON schema the reply must match; json_object only
+// requires an object
+func (f *ResponseFormat) Schema() map[string]interface{} {
+	if f == nil {
+		return nil
+	}
+	if f.JSONSchema != nil && f.JSONSchema.Schema != nil {
+		return f.JSONSchema.Schema
+	}
+	return map[string]interface{}{"type": "object"}
+}
+
+// Structured reports whether the format asks for JSON rather than free text
+func (f *ResponseFormat) Structured() bool {
+	return f != nil && (f.Type == ResponseFormatJSONObject || f.Type == ResponseFormatJSONSchema)
+}
+
+// HasCapability reports whether p lists c among its capabilities
+func HasCapability(p Provider, c Capability) bool {
+	for _, have := range p.Capabilities() {
+		if have == c {
+			return true
+		}
+	}
+	return false
+}
+
+// StreamOptions asks OpenAI-compatible APIs for a final usage chunk when streaming
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // Tool represents a function tool
@@ -78,8 +157,9 @@ type Tool struct {
 
 type ToolFunction struct {
 	Name        string      `json:"name"`
-	Description string      `json:"description"`
-	Parameters  interface{} `json:"parameters"`
+	Description string      `json:"description,omitempty"`
+	Parameters  interface{} `json:"parameters,omitempty"`
+	Arguments   string      `json:"arguments,omitempty"` // JSON-encoded arguments (tool calls only)
 }
 
 // ChatResponse represents a chat completion response
@@ -97,9 +177,10 @@ type Choice struct {
 }
 
 type Usage struct {
-	PromptTokens     int `json:"prompt_tokens"`
+	PromptTokens     int `json:"prompt_tokens"` // Includes CachedTokens
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+	CachedTokens     int `json:"cached_tokens,omitempty"` // Prompt tokens served from the provider's cache
 }
 
 // StreamChunk represents a streaming response chunk
@@ -107,6 +188,7 @@ type StreamChunk struct {
 	ID      string         `json:"id"`
 	Model   string         `json:"model"`
 	Choices []StreamChoice `json:"choices"`
+	Usage   *Usage         `json:"usage,omitempty"` // Set on the chunk that reports token usage (usually the last)
 }
 
 type StreamChoice struct {
@@ -119,6 +201,12 @@ type StreamDelta struct {
 	Content   string    `json:"content"`
 	Role      string    `json:"role,omitempty"`
 	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
+	// Thinking carries a fragment of extended thinking text. ThinkingSignature
+	// closes the current thinking block; without preceding thinking text it is
+	// a redacted block's opaque payload. See PartThinking.
+	Thinking          string `json:"thinking,omitempty"`
+	ThinkingSignature string `json:"thinking_signature,omitempty"`
 }
 
 // ============ New Types for Extended Capabilities ============
@@ -323,6 +411,16 @@ type Provider interface {
 // ErrCapabilityNotSupported is returned when a capability is not supported
 var ErrCapabilityNotSupported = fmt.Errorf("capability not supported")
 
+// APIError is returned when a provider responds with a non-success HTTP status
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (%d): %s", e.StatusCode, e.Body)
+}
+
 // Config holds provider configuration
 type Config struct {
 	Type            ProviderType       `json:"type"`
@@ -345,10 +443,12 @@ type ModelsConfig struct {
 
 // ModelConfig holds individual model configuration
 type ModelConfig struct {
-	Alias         string `json:"alias,omitempty"`
-	APIKey        string `json:"apiKey,omitempty"`
-	BaseURL       string `json:"baseUrl,omitempty"`
-	ContextWindow int    `json:"contextWindow,omitempty"`
+	Alias         string            `json:"alias,omitempty"`
+	APIKey        string            `json:"apiKey,omitempty"`
+	BaseURL       string            `json:"baseUrl,omitempty"`
+	ContextWindow int               `json:"contextWindow,omitempty"`
+	ToolDialect   string            `json:"toolDialect,omitempty"` // Text tool-call format: auto, openai, anthropic, minimax, hermes/qwen, llama; see pkg/llm/dialect
+	ToolAliases   map[string]string `json:"toolAliases,omitempty"` // Tool names the model uses -> registered tool names
 }
 
 // GetContextWindow attempts to get context window from API, falls back to config
@@ -758,7 +858,7 @@ func (p *BaseProvider) DoRequest(ctx context.Context, req *http.Request) ([]byte
 					continue
 				}
 			}
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+			return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 		}
 		return body, nil
 	}
@@ -2,8 +2,10 @@
 package anthropic
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -71,14 +73,7 @@ func (p *Provider) Type() llm.ProviderType { return llm.ProviderAnthropic }
 
 // Chat implements llm.Provider.Chat
 func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
-	anthropicReq := map[string]interface{}{
-		"model":       req.Model,
-		"max_tokens":  4096,
-		"messages":     convertToAnthropicMessages(req.Messages),
-		"temperature": req.Temperature,
-	}
-
-	httpReq, err := p.buildRequest("/messages", anthropicReq)
+	httpReq, err := p.buildRequest("/messages", p.messagesRequest(req, false))
 	if err != nil {
 		return nil, err
 	}
@@ -91,61 +86,55 @@ func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatRes
 	}
 
 	var resp struct {
-		ID     string `json:"id"`
-		Type   string `json:"type"`
-		Role   string `json:"role"`
-		Content []struct {
-			Type string `json:"type"`
-			Text string `json:"text"`
-		} `json:"content"`
-		Usage struct {
-			InputTokens  int `json:"input_tokens"`
-			OutputTokens int `json:"output_tokens"`
-		} `json:"usage"`
+		ID         string           `json:"id"`
+		Type       string           `json:"type"`
+		Role       string           `json:"role"`
+		Content    []anthropicBlock `json:"content"`
+		StopReason string           `json:"stop_reason"`
+		Usage      anthropicUsage   `json:"usage"`
 	}
 
 	if err := json.Unmarshal(body, &resp); err != nil {
 		return nil, err
 	}
 
-	content := ""
-	if len(resp.Content) > 0 {
-		content = resp.Content[0].Text
-	}
-
 	return &llm.ChatResponse{
-		ID:   resp.ID,
+		ID:    resp.ID,
 		Model: req.Model,
 		Choices: []llm.Choice{
 			{
-				Index: 0,
-				Message: llm.Message{
-					Role:    "assistant",
-					Content: content,
-				},
-				FinishReason: "stop",
+				Index:        0,
+				Message:      convertFromAnthropicBlocks(resp.Content),
+				FinishReason: convertStopReason(resp.StopReason),
 			},
 		},
-		Usage: llm.Usage{
-			PromptTokens:     resp.Usage.InputTokens,
-			CompletionTokens: resp.Usage.OutputTokens,
-			TotalTokens:      resp.Usage.InputTokens + resp.Usage.OutputTokens,
-		},
+		Usage: resp.Usage.toUsage(),
 	}, nil
 }
 
+// anthropicUsage is the usage block of Messages API responses and stream events
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// toUsage converts to llm.Usage, whose prompt tokens include cache reads and writes
+func (u anthropicUsage) toUsage() llm.Usage {
+	prompt := u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+	return llm.Usage{
+		PromptTokens:     prompt,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      prompt + u.OutputTokens,
+		CachedTokens:     u.CacheReadInputTokens,
+	}
+}
+
 // ChatStream implements llm.Provider.ChatStream
 func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, fn func(*llm.StreamChunk)) error {
 	// Anthropic uses SSE (Server-Sent Events) for streaming
-	anthropicReq := map[string]interface{}{
-		"model":       req.Model,
-		"max_tokens":  4096,
-		"messages":     convertToAnthropicMessages(req.Messages),
-		"temperature": req.Temperature,
-		"stream":      true,
-	}
-
-	httpReq, err := p.buildRequest("/messages", anthropicReq)
+	httpReq, err := p.buildRequest("/messages", p.messagesRequest(req, true))
 	if err != nil {
 		return err
 	}
@@ -158,59 +147,106 @@ func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, fn func
 	}
 	defer resp.Body.Close()
 
-	// Read SSE stream
-	buf := make([]byte, 0, 4096)
-	chunkBuf := make([]byte, 0, 1024)
+	if err := llm.CheckResponse(resp.StatusCode, resp.Body); err != nil {
+		return err
+	}
+
+	// Content block index -> tool call index, for routing input_json_delta fragments
+	toolIndex := make(map[int]int)
+	var usage anthropicUsage
+	emit := func(delta llm.StreamDelta, finish string) {
+		fn(&llm.StreamChunk{Choices: []llm.StreamChoice{{Index: 0, Delta: delta, FinishReason: finish}}})
+	}
 
+	reader := bufio.NewReader(resp.Body)
 	for {
-		n, err := resp.Body.Read(chunkBuf)
-		if n > 0 {
-			buf = append(buf, chunkBuf[:n]...)
-			// Process complete events
-			for {
-				content, remaining, found := strings.Cut(string(buf), "\n")
-				buf = []byte(remaining)
-				if !found {
-					break
-				}
-				content = strings.TrimSpace(content)
-				if strings.HasPrefix(content, "data: ") {
-					data := strings.TrimPrefix(content, "data: ")
-					if data == "[DONE]" {
-						return nil
-					}
-					// Parse SSE chunk
-					var event struct {
-						Type string `json:"type"`
-						Delta struct {
-							Text string `json:"text"`
-						} `json:"delta"`
-						Usage struct {
-							OutputTokens int `json:"output_tokens"`
-						} `json:"usage"`
+		line, readErr := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "data:") {
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			var event struct {
+				Type         string         `json:"type"`
+				Index        int            `json:"index"`
+				ContentBlock anthropicBlock `json:"content_block"`
+				Delta        struct {
+					Type        string `json:"type"`
+					Text        string `json:"text"`
+					Thinking    string `json:"thinking"`
+					Signature   string `json:"signature"`
+					PartialJSON string `json:"partial_json"`
+					StopReason  string `json:"stop_reason"`
+				} `json:"delta"`
+				Message struct {
+					Usage anthropicUsage `json:"usage"`
+				} `json:"message"`
+				Usage *anthropicUsage `json:"usage"`
+				Error struct {
+					Type    string `json:"type"`
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal([]byte(data), &event); err == nil {
+				switch event.Type {
+				case "message_start":
+					usage = event.Message.Usage
+				case "content_block_start":
+					switch event.ContentBlock.Type {
+					case "redacted_thinking":
+						emit(llm.StreamDelta{ThinkingSignature: event.ContentBlock.Data}, "")
+					case "tool_use":
+						idx := len(toolIndex)
+						toolIndex[event.Index] = idx
+						emit(llm.StreamDelta{ToolCalls: []llm.ToolCall{{
+							Index:    idx,
+							ID:       event.ContentBlock.ID,
+							Type:     "function",
+							Function: &llm.ToolFunction{Name: event.ContentBlock.Name},
+						}}}, "")
 					}
-					if err := json.Unmarshal([]byte(data), &event); err == nil {
-						if event.Type == "content_block_delta" && event.Delta.Text != "" {
-							fn(&llm.StreamChunk{
-								Choices: []llm.StreamChoice{
-									{
-										Index: 0,
-										Delta: llm.StreamDelta{
-											Content: event.Delta.Text,
-										},
-									},
-								},
-							})
+				case "content_block_delta":
+					switch event.Delta.Type {
+					case "thinking_delta":
+						if event.Delta.Thinking != "" {
+							emit(llm.StreamDelta{Thinking: event.Delta.Thinking}, "")
 						}
+					case "signature_delta":
+						emit(llm.StreamDelta{ThinkingSignature: event.Delta.Signature}, "")
+					case "input_json_delta":
+						if idx, ok := toolIndex[event.Index]; ok && event.Delta.PartialJSON != "" {
+							emit(llm.StreamDelta{ToolCalls: []llm.ToolCall{{
+								Index:    idx,
+								Function: &llm.ToolFunction{Arguments: event.Delta.PartialJSON},
+							}}}, "")
+						}
+					default:
+						if event.Delta.Text != "" {
+							emit(llm.StreamDelta{Content: event.Delta.Text}, "")
+						}
+					}
+				case "message_delta":
+					if event.Delta.StopReason != "" {
+						emit(llm.StreamDelta{}, convertStopReason(event.Delta.StopReason))
 					}
+					if event.Usage != nil {
+						// Output tokens are cumulative; input counts arrive with message_start
+						usage.OutputTokens = event.Usage.OutputTokens
+						u := usage.toUsage()
+						fn(&llm.StreamChunk{Usage: &u})
+					}
+				case "message_stop":
+					return nil
+				case "error":
+					return fmt.Errorf("anthropic stream error: %s: %s", event.Error.Type, event.Error.Message)
 				}
 			}
 		}
-		if err != nil {
-			break
+		if readErr != nil {
+			if readErr == io.EOF {
+				return nil
+			}
+			return readErr
 		}
 	}
-	return nil
 }
 
 // Embeddings implements llm.Provider.Embeddings
@@ -305,17 +341,255 @@ func (p *Provider) Embeddings(ctx context.Context, req *llm.EmbedRequest) (*llm.
 	return &result, nil
 }
 
-func convertToAnthropicMessages(msgs []llm.Message) []map[string]string {
-	result := make([]map[string]string, len(msgs))
-	for i, m := range msgs {
-		result[i] = map[string]string{
-			"role":    m.Role,
-			"content": m.Content,
+// anthropicBlock is a Messages API content block (request and response)
+type anthropicBlock struct {
+	Type      string           `json:"type"`
+	Text      string           `json:"text,omitempty"`
+	Source    *anthropicSource `json:"source,omitempty"`
+	ID        string           `json:"id,omitempty"`
+	Name      string           `json:"name,omitempty"`
+	Input     json.RawMessage  `json:"input,omitempty"`
+	ToolUseID string           `json:"tool_use_id,omitempty"`
+	Content   string           `json:"content,omitempty"`
+	IsError   bool             `json:"is_error,omitempty"`
+	Thinking  string           `json:"thinking,omitempty"`
+	Signature string           `json:"signature,omitempty"`
+	Data      string           `json:"data,omitempty"` // redacted_thinking payload
+
+	CacheControl *cacheControl `json:"cache_control,omitempty"`
+}
+
+// cacheControl marks the end of a prompt prefix the API should cache
+type cacheControl struct {
+	Type string `json:"type"` // ephemeral
+}
+
+// ephemeral is the cache breakpoint placed on stable prompt prefixes
+var ephemeral = &cacheControl{Type: "ephemeral"}
+
+// minThinkingBudget is the smallest thinking budget the API accepts
+const minThinkingBudget = 1024
+
+type anthropicSource struct {
+	Type      string `json:"type"` // base64 | url
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+// messagesRequest builds the /messages request body. Cache breakpoints are
+// placed on the tool definitions, the system prompt and the conversation
+// prefix (see addCacheBreakpoints), so long tool-heavy sessions pay for
+// their unchanged prefix at the cache-read rate.
+func (p *Provider) messagesRequest(req *llm.ChatRequest, stream bool) map[string]interface{} {
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = 4096
+	}
+	system, messages := convertToAnthropicMessages(req.Messages)
+	addCacheBreakpoints(messages)
+	body := map[string]interface{}{
+		"model":       req.Model,
+		"max_tokens":  maxTokens,
+		"messages":    messages,
+		"temperature": req.Temperature,
+	}
+	if req.Thinking != nil && req.Thinking.BudgetTokens > 0 {
+		budget := max(req.Thinking.BudgetTokens, minThinkingBudget)
+		// max_tokens covers thinking plus the answer, and thinking
+		// does not support a temperature other than the default
+		body["max_tokens"] = budget + maxTokens
+		body["thinking"] = map[string]interface{}{"type": "enabled", "budget_tokens": budget}
+		delete(body, "temperature")
+	}
+	if system != "" {
+		body["system"] = []anthropicBlock{{Type: "text", Text: system, CacheControl: ephemeral}}
+	}
+	if tools := convertToAnthropicTools(req.Tools); len(tools) > 0 {
+		tools[len(tools)-1]["cache_control"] = ephemeral
+		body["tools"] = tools
+	}
+	if stream {
+		body["stream"] = true
+	}
+	return body
+}
+
+// convertToAnthropicMessages maps llm messages onto Messages API content blocks.
+// System messages are lifted into the top-level system prompt, tool results
+// become user tool_result blocks, and consecutive same-role turns are merged
+// (the API requires alternating user/assistant turns).
+func convertToAnthropicMessages(msgs []llm.Message) (string, []anthropicMessage) {
+	var system []string
+	result := make([]anthropicMessage, 0, len(msgs))
+
+	for _, m := range msgs {
+		if m.Role == "system" {
+			if text := m.Text(); text != "" {
+				system = append(system, text)
+			}
+			continue
+		}
+
+		role := "user"
+		if m.Role == "assistant" {
+			role = "assistant"
+		}
+
+		// Thinking blocks must lead the assistant turn they belong to
+		var thinking, blocks []anthropicBlock
+		if m.Role == "tool" {
+			for _, r := range m.ToolResults() {
+				blocks = append(blocks, anthropicBlock{Type: "tool_result", ToolUseID: r.ToolCallID, Content: r.Text, IsError: r.IsError})
+			}
+		} else {
+			for _, part := range m.ContentParts() {
+				switch part.Type {
+				case llm.PartText:
+					if part.Text != "" {
+						blocks = append(blocks, anthropicBlock{Type: "text", Text: part.Text})
+					}
+				case llm.PartImage:
+					blocks = append(blocks, anthropicImageBlock(part))
+				case llm.PartAudio:
+					// Claude does not accept audio input; keep a marker so the turn is not empty
+					blocks = append(blocks, anthropicBlock{Type: "text", Text: "[audio attachment omitted]"})
+				case llm.PartToolResult:
+					blocks = append(blocks, anthropicBlock{Type: "tool_result", ToolUseID: part.ToolCallID, Content: part.Text, IsError: part.IsError})
+				case llm.PartThinking:
+					if role != "assistant" {
+						continue
+					}
+					if part.Text == "" {
+						thinking = append(thinking, anthropicBlock{Type: "redacted_thinking", Data: part.Signature})
+					} else {
+						thinking = append(thinking, anthropicBlock{Type: "thinking", Thinking: part.Text, Signature: part.Signature})
+					}
+				}
+			}
+			for _, tc := range m.AllToolCalls() {
+				if tc.Function == nil {
+					continue
+				}
+				input := json.RawMessage(tc.Function.Arguments)
+				if len(input) == 0 || !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: tc.ID, Name: tc.Function.Name, Input: input})
+			}
+		}
+		blocks = append(thinking, blocks...)
+		if len(blocks) == 0 {
+			continue
+		}
+
+		if n := len(result); n > 0 && result[n-1].Role == role {
+			result[n-1].Content = append(result[n-1].Content, blocks...)
+			continue
 		}
+		result = append(result, anthropicMessage{Role: role, Content: blocks})
+	}
+	return strings.Join(system, "\n\n"), result
+}
+
+// addCacheBreakpoints marks the last block of the two most recent user turns.
+// The latest one caches the whole conversation for the next request; the one
+// before lets this request read the prefix cached by the previous one. With
+// the tools and system breakpoints this uses all four the API allows.
+func addCacheBreakpoints(messages []anthropicMessage) {
+	marked := 0
+	for i := len(messages) - 1; i >= 0 && marked < 2; i-- {
+		if messages[i].Role != "user" || len(messages[i].Content) == 0 {
+			continue
+		}
+		blocks := messages[i].Content
+		blocks[len(blocks)-1].CacheControl = ephemeral
+		marked++
+	}
+}
+
+func anthropicImageBlock(part llm.ContentPart) anthropicBlock {
+	if len(part.Data) == 0 {
+		return anthropicBlock{Type: "image", Source: &anthropicSource{Type: "url", URL: part.URL}}
+	}
+	mediaType := part.MimeType
+	if mediaType == "" {
+		mediaType = "image/png"
+	}
+	return anthropicBlock{Type: "image", Source: &anthropicSource{
+		Type:      "base64",
+		MediaType: mediaType,
+		Data:      base64.StdEncoding.EncodeToString(part.Data),
+	}}
+}
+
+// convertToAnthropicTools maps OpenAI-style function tools to Anthropic tool definitions
+func convertToAnthropicTools(tools []llm.Tool) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(tools))
+	for _, t := range tools {
+		if t.Function == nil || t.Function.Name == "" {
+			continue
+		}
+		schema := t.Function.Parameters
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		tool := map[string]interface{}{
+			"name":         t.Function.Name,
+			"input_schema": schema,
+		}
+		if t.Function.Description != "" {
+			tool["description"] = t.Function.Description
+		}
+		result = append(result, tool)
 	}
 	return result
 }
 
+// convertFromAnthropicBlocks maps response content blocks back to an llm message
+func convertFromAnthropicBlocks(blocks []anthropicBlock) llm.Message {
+	msg := llm.Message{Role: "assistant"}
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			msg.Content += b.Text
+		case "thinking":
+			msg.Parts = append(msg.Parts, llm.ThinkingPart(b.Thinking, b.Signature))
+		case "redacted_thinking":
+			msg.Parts = append(msg.Parts, llm.ThinkingPart("", b.Data))
+		case "tool_use":
+			args := string(b.Input)
+			if args == "" {
+				args = "{}"
+			}
+			msg.ToolCalls = append(msg.ToolCalls, llm.ToolCall{
+				ID:       b.ID,
+				Type:     "function",
+				Function: &llm.ToolFunction{Name: b.Name, Arguments: args},
+			})
+		}
+	}
+	return msg
+}
+
+// convertStopReason maps Anthropic stop reasons to OpenAI finish reasons
+func convertStopReason(reason string) string {
+	switch reason {
+	case "tool_use":
+		return "tool_calls"
+	case "max_tokens":
+		return "length"
+	case "":
+		return ""
+	}
+	return "stop"
+}
+
 func (p *Provider) buildRequest(endpoint string, body any) (*http.Request, error) {
 	url := p.config.BaseURL + endpoint
 	var bodyStr string
@@ -372,7 +646,7 @@ func (p *Provider) doRequest(ctx context.Context, req *http.Request) ([]byte, er
 					continue
 				}
 			}
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+			return nil, &llm.APIError{StatusCode: resp.StatusCode, Body: string(body)}
 		}
 		return body, nil
 	}
@@ -0,0 +1,231 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gliderlab/cogate/pkg/llm"
+)
+
+func TestConvertToAnthropicMessages(t *testing.T) {
+	system, msgs := convertToAnthropicMessages([]llm.Message{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "look", Parts: []llm.ContentPart{llm.ImagePart("image/png", []byte{1, 2, 3})}},
+		{Role: "assistant", ToolCalls: []llm.ToolCall{
+			{ID: "t1", Type: "function", Function: &llm.ToolFunction{Name: "read", Arguments: `{"path":"a"}`}},
+			{ID: "t2", Type: "function", Function: &llm.ToolFunction{Name: "exec"}},
+		}},
+		{Role: "tool", ToolCallID: "t1", Content: "A"},
+		{Role: "tool", ToolCallID: "t2", Content: "B"},
+	})
+
+	if system != "be brief" {
+		t.Errorf("system prompt not lifted: %q", system)
+	}
+	if len(msgs) != 3 {
+		t.Fatalf("expected user/assistant/user turns, got %d", len(msgs))
+	}
+	if img := msgs[0].Content[1]; img.Type != "image" || img.Source == nil || img.Source.Data != "AQID" {
+		t.Errorf("image block not converted: %+v", img)
+	}
+	if uses := msgs[1].Content; len(uses) != 2 || uses[1].Type != "tool_use" || string(uses[1].Input) != "{}" {
+		t.Errorf("tool_use blocks not converted: %+v", uses)
+	}
+	if results := msgs[2].Content; len(results) != 2 || results[0].ToolUseID != "t1" || results[1].ToolUseID != "t2" {
+		t.Errorf("tool results should merge into one user turn: %+v", results)
+	}
+}
+
+func TestChatParsesToolUse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if _, ok := body["tools"]; !ok {
+			t.Errorf("tools not sent: %v", body)
+		}
+		fmt.Fprint(w, `{"id":"msg_1","content":[{"type":"text","text":"checking"},{"type":"tool_use","id":"t1","name":"read","input":{"path":"a"}}],"stop_reason":"tool_use","usage":{"input_tokens":3,"output_tokens":4}}`)
+	}))
+	defer srv.Close()
+
+	p := New(llm.Config{APIKey: "k", BaseURL: srv.URL})
+	resp, err := p.Chat(context.Background(), &llm.ChatRequest{
+		Model:    "claude",
+		Messages: []llm.Message{{Role: "user", Content: "read a"}},
+		Tools:    []llm.Tool{{Type: "function", Function: &llm.ToolFunction{Name: "read"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	choice := resp.Choices[0]
+	if choice.FinishReason != "tool_calls" || choice.Message.Content != "checking" {
+		t.Errorf("unexpected choice: %+v", choice)
+	}
+	if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].Function.Arguments != `{"path":"a"}` {
+		t.Errorf("tool call not parsed: %+v", choice.Message.ToolCalls)
+	}
+}
+
+func TestChatStreamToolUse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, ev := range []string{
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hi"}}`,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"t1","name":"read"}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"path\":"}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"a\"}"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"tool_use"}}`,
+			`{"type":"message_stop"}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", ev)
+		}
+	}))
+	defer srv.Close()
+
+	p := New(llm.Config{APIKey: "k", BaseURL: srv.URL})
+	var text, args strings.Builder
+	var name, finish string
+	err := p.ChatStream(context.Background(), &llm.ChatRequest{Model: "claude", Messages: []llm.Message{{Role: "user", Content: "x"}}}, func(c *llm.StreamChunk) {
+		d := c.Choices[0]
+		text.WriteString(d.Delta.Content)
+		for _, tc := range d.Delta.ToolCalls {
+			if tc.Function.Name != "" {
+				name = tc.Function.Name
+			}
+			args.WriteString(tc.Function.Arguments)
+		}
+		if d.FinishReason != "" {
+			finish = d.FinishReason
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text.String() != "hi" || name != "read" || args.String() != `{"path":"a"}` || finish != "tool_calls" {
+		t.Errorf("unexpected stream result: text=%q name=%q args=%q finish=%q", text.String(), name, args.String(), finish)
+	}
+}
+
+func TestMessagesRequestCacheBreakpoints(t *testing.T) {
+	p := New(llm.Config{})
+	body := p.messagesRequest(&llm.ChatRequest{
+		Model: "claude",
+		Messages: []llm.Message{
+			{Role: "system", Content: "be brief"},
+			{Role: "user", Content: "one"},
+			{Role: "assistant", Content: "1"},
+			{Role: "user", Content: "two"},
+			{Role: "assistant", Content: "2"},
+			{Role: "user", Content: "three"},
+		},
+		Tools: []llm.Tool{
+			{Type: "function", Function: &llm.ToolFunction{Name: "read"}},
+			{Type: "function", Function: &llm.ToolFunction{Name: "exec"}},
+		},
+	}, false)
+
+	if system := body["system"].([]anthropicBlock); len(system) != 1 || system[0].CacheControl == nil {
+		t.Errorf("system prompt not cached: %+v", system)
+	}
+	tools := body["tools"].([]map[string]interface{})
+	if _, ok := tools[0]["cache_control"]; ok {
+		t.Errorf("only the last tool should carry a breakpoint")
+	}
+	if _, ok := tools[1]["cache_control"]; !ok {
+		t.Errorf("last tool not cached: %v", tools[1])
+	}
+	var marked []string
+	for _, m := range body["messages"].([]anthropicMessage) {
+		for _, b := range m.Content {
+			if b.CacheControl != nil {
+				marked = append(marked, b.Text)
+			}
+		}
+	}
+	if strings.Join(marked, ",") != "two,three" {
+		t.Errorf("expected breakpoints on the last two user turns, got %v", marked)
+	}
+}
+
+func TestChatThinking(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		thinking, _ := body["thinking"].(map[string]any)
+		if thinking["type"] != "enabled" || thinking["budget_tokens"] != float64(minThinkingBudget) {
+			t.Errorf("thinking not requested: %v", body["thinking"])
+		}
+		if body["max_tokens"] != float64(minThinkingBudget+500) {
+			t.Errorf("max_tokens should cover thinking and answer: %v", body["max_tokens"])
+		}
+		if _, ok := body["temperature"]; ok {
+			t.Errorf("temperature must not be sent with thinking")
+		}
+		fmt.Fprint(w, `{"id":"msg_1","content":[{"type":"thinking","thinking":"let me see","signature":"sig"},{"type":"redacted_thinking","data":"opaque"},{"type":"text","text":"done"}],"stop_reason":"end_turn"}`)
+	}))
+	defer srv.Close()
+
+	p := New(llm.Config{APIKey: "k", BaseURL: srv.URL})
+	resp, err := p.Chat(context.Background(), &llm.ChatRequest{
+		Model:       "claude",
+		Messages:    []llm.Message{{Role: "user", Content: "think"}},
+		MaxTokens:   500,
+		Temperature: 0.7,
+		Thinking:    &llm.ThinkingOptions{BudgetTokens: 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := resp.Choices[0].Message
+	if msg.Content != "done" || msg.Thinking() != "let me see" || len(msg.Parts) != 2 {
+		t.Fatalf("thinking not parsed: %+v", msg)
+	}
+
+	// Thinking blocks go back first in the assistant turn, with their signatures
+	msg.ToolCalls = []llm.ToolCall{{ID: "t1", Type: "function", Function: &llm.ToolFunction{Name: "read", Arguments: "{}"}}}
+	_, msgs := convertToAnthropicMessages([]llm.Message{{Role: "user", Content: "think"}, msg})
+	blocks := msgs[1].Content
+	if len(blocks) != 4 || blocks[0].Type != "thinking" || blocks[0].Signature != "sig" ||
+		blocks[1].Type != "redacted_thinking" || blocks[1].Data != "opaque" || blocks[2].Type != "text" {
+		t.Errorf("thinking blocks not returned: %+v", blocks)
+	}
+}
+
+func TestChatStreamThinking(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, ev := range []string{
+			`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"hmm, "}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"yes"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig"}}`,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"ok"}}`,
+			`{"type":"message_stop"}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", ev)
+		}
+	}))
+	defer srv.Close()
+
+	p := New(llm.Config{APIKey: "k", BaseURL: srv.URL})
+	var text, thinking strings.Builder
+	var signature string
+	err := p.ChatStream(context.Background(), &llm.ChatRequest{Model: "claude", Messages: []llm.Message{{Role: "user", Content: "x"}}}, func(c *llm.StreamChunk) {
+		d := c.Choices[0].Delta
+		text.WriteString(d.Content)
+		thinking.WriteString(d.Thinking)
+		if d.ThinkingSignature != "" {
+			signature = d.ThinkingSignature
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text.String() != "ok" || thinking.String() != "hmm, yes" || signature != "sig" {
+		t.Errorf("unexpected stream result: text=%q thinking=%q signature=%q", text.String(), thinking.String(), signature)
+	}
+}
@@ -0,0 +1,116 @@
+// eventstream.go - Decoder for the AWS binary event stream (application/vnd.amazon.eventstream)
+package bedrock
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// maxEventSize bounds one event stream message (the format allows 16 MB)
+const maxEventSize = 16 << 20
+
+// eventMessage is one decoded event stream message
+type eventMessage struct {
+	Headers map[string]string // String-valued headers; other value types are skipped
+	Payload []byte
+}
+
+// eventStreamReader decodes event stream messages:
+//
+//	total length (4) | headers length (4) | prelude CRC (4) | headers | payload | message CRC (4)
+//
+// Both CRCs are CRC-32 (IEEE) and are verified.
+type eventStreamReader struct {
+	r io.Reader
+}
+
+func newEventStreamReader(r io.Reader) *eventStreamReader {
+	return &eventStreamReader{r: r}
+}
+
+// Next returns the next message, or io.EOF at a clean end of stream
+func (e *eventStreamReader) Next() (*eventMessage, error) {
+	prelude := make([]byte, 12)
+	if _, err := io.ReadFull(e.r, prelude); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("event stream: truncated prelude")
+		}
+		return nil, err
+	}
+	total := binary.BigEndian.Uint32(prelude[0:4])
+	headersLen := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+		return nil, errors.New("event stream: prelude checksum mismatch")
+	}
+	if total < 16 || total > maxEventSize || headersLen > total-16 {
+		return nil, fmt.Errorf("event stream: invalid message length %d (headers %d)", total, headersLen)
+	}
+
+	rest := make([]byte, total-12)
+	if _, err := io.ReadFull(e.r, rest); err != nil {
+		return nil, fmt.Errorf("event stream: truncated message: %w", err)
+	}
+	body, crc := rest[:len(rest)-4], binary.BigEndian.Uint32(rest[len(rest)-4:])
+	sum := crc32.NewIEEE()
+	sum.Write(prelude)
+	sum.Write(body)
+	if sum.Sum32() != crc {
+		return nil, errors.New("event stream: message checksum mismatch")
+	}
+
+	headers, err := decodeEventHeaders(body[:headersLen])
+	if err != nil {
+		return nil, err
+	}
+	return &eventMessage{Headers: headers, Payload: body[headersLen:]}, nil
+}
+
+// decodeEventHeaders parses name/type/value header entries
+func decodeEventHeaders(b []byte) (map[string]string, error) {
+	headers := make(map[string]string)
+	for len(b) > 0 {
+		nameLen := int(b[0])
+		if len(b) < 1+nameLen+1 {
+			return nil, errors.New("event stream: truncated header")
+		}
+		name := string(b[1 : 1+nameLen])
+		valueType := b[1+nameLen]
+		b = b[2+nameLen:]
+
+		// Fixed sizes by value type; 6 (bytes) and 7 (string) are length-prefixed
+		var size int
+		switch valueType {
+		case 0, 1: // bool true / false
+			size = 0
+		case 2:
+			size = 1
+		case 3:
+			size = 2
+		case 4:
+			size = 4
+		case 5, 8: // int64, timestamp
+			size = 8
+		case 9: // uuid
+			size = 16
+		case 6, 7:
+			if len(b) < 2 {
+				return nil, errors.New("event stream: truncated header value")
+			}
+			size = int(binary.BigEndian.Uint16(b[:2]))
+			b = b[2:]
+		default:
+			return nil, fmt.Errorf("event stream: unknown header type %d", valueType)
+		}
+		if len(b) < size {
+			return nil, errors.New("event stream: truncated header value")
+		}
+		if valueType == 7 {
+			headers[name] = string(b[:size])
+		}
+		b = b[size:]
+	}
+	return headers, nil
+}
@@ -1,27 +1,47 @@
-// Package bedrock provides Amazon Bedrock provider
+// Package bedrock provides the Amazon Bedrock provider: chat through the
+// Converse and ConverseStream APIs and Titan/Cohere embeddings through
+// InvokeModel, with every request signed by AWS Signature Version 4.
 package bedrock
 
 import (
+	"bytes"
+	"cmp"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gliderlab/cogate/pkg/llm"
 )
 
+const (
+	defaultModel      = "anthropic.claude-3-sonnet-20240229-v1:0"
+	defaultEmbedModel = "amazon.titan-embed-text-v2:0"
+	defaultRegion     = "us-east-1"
+)
+
+// NewFromEnv creates a provider from the AWS environment: a region from
+// AWS_REGION (or AWS_DEFAULT_REGION) and credentials from AWS_ACCESS_KEY_ID /
+// AWS_SECRET_ACCESS_KEY or the shared credentials file. It returns nil when
+// either is missing.
 func NewFromEnv() *Provider {
-	apiKey := os.Getenv("AWS_ACCESS_KEY_ID")
-	region := os.Getenv("AWS_REGION")
-	if apiKey == "" || region == "" {
+	region := cmp.Or(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))
+	creds := resolveCredentials(os.Getenv("AWS_ACCESS_KEY_ID"))
+	if region == "" || !creds.Valid() {
 		return nil
 	}
 	cfg := llm.Config{
-		Type:    llm.ProviderBedrock,
-		APIKey:  apiKey,
-		BaseURL: region,
-		Model:   getEnv("BEDROCK_MODEL", "anthropic.claude-3-sonnet-20240229-v1:0"),
-		Timeout: 60,
+		Type:       llm.ProviderBedrock,
+		APIKey:     creds.AccessKeyID,
+		BaseURL:    region,
+		Model:      getEnv("BEDROCK_MODEL", defaultModel),
+		Timeout:    60,
+		EmbedModel: getEnv("BEDROCK_EMBED_MODEL", defaultEmbedModel),
 	}
 	return New(cfg)
 }
@@ -33,35 +53,548 @@ func getEnv(key, def string) string {
 	return def
 }
 
+// Provider implements llm.Provider for Amazon Bedrock
 type Provider struct {
-	config llm.Config
-	client *http.Client
+	config   llm.Config
+	client   *http.Client
+	endpoint string // bedrock-runtime base URL
+	region   string
 }
 
+// New creates a Bedrock provider. cfg.BaseURL is either a region ("us-west-2")
+// or an endpoint URL; cfg.APIKey is an access key ID, or
+// "accessKeyId:secretAccessKey[:sessionToken]" to carry the whole key.
 func New(cfg llm.Config) *Provider {
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 60
+	}
+	endpoint, region := resolveEndpoint(cfg.BaseURL)
 	return &Provider{
-		config: cfg,
-		client: &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+		config:   cfg,
+		client:   &http.Client{Timeout: time.Duration(timeout) * time.Second},
+		endpoint: endpoint,
+		region:   region,
+	}
+}
+
+// resolveEndpoint maps a region or endpoint URL to the runtime endpoint and
+// its signing region. Endpoints outside amazonaws.com (proxies, local
+// stand-ins) sign for the environment's region.
+func resolveEndpoint(baseURL string) (string, string) {
+	region := cmp.Or(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), defaultRegion)
+	if baseURL == "" || !strings.Contains(baseURL, "://") {
+		region = cmp.Or(baseURL, region)
+		return "https://bedrock-runtime." + region + ".amazonaws.com", region
 	}
+	endpoint := strings.TrimRight(baseURL, "/")
+	if u, err := url.Parse(endpoint); err == nil {
+		// bedrock-runtime.<region>.amazonaws.com, bedrock-runtime-fips.<region>...
+		labels := strings.Split(u.Hostname(), ".")
+		if len(labels) >= 4 && strings.HasPrefix(labels[0], "bedrock-runtime") && labels[len(labels)-2] == "amazonaws" {
+			region = labels[1]
+		}
+	}
+	return endpoint, region
 }
 
 func (p *Provider) Name() string           { return "bedrock" }
 func (p *Provider) Type() llm.ProviderType { return llm.ProviderBedrock }
 func (p *Provider) GetConfig() llm.Config  { return p.config }
 
+// Chat implements llm.Provider.Chat using the Converse API
 func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
-	// Bedrock uses AWS Signature V4 - simplified placeholder
-	// Full implementation requires AWS Signature V4 signing
-	return nil, nil
+	model := cmp.Or(req.Model, p.config.Model)
+	resp, err := p.post(ctx, model, "converse", converseRequestBody(model, req))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Output struct {
+			Message bedrockMessage `json:"message"`
+		} `json:"output"`
+		StopReason string       `json:"stopReason"`
+		Usage      bedrockUsage `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("bedrock: decode converse response: %w", err)
+	}
+
+	return &llm.ChatResponse{
+		ID:    resp.Header.Get("X-Amzn-Requestid"),
+		Model: model,
+		Choices: []llm.Choice{{
+			Message:      convertFromBedrockContent(out.Output.Message.Content),
+			FinishReason: convertStopReason(out.StopReason),
+		}},
+		Usage: out.Usage.toUsage(),
+	}, nil
 }
 
+// ChatStream implements llm.Provider.ChatStream using the ConverseStream API,
+// whose response is an AWS binary event stream
 func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, fn func(*llm.StreamChunk)) error {
-	return nil
+	model := cmp.Or(req.Model, p.config.Model)
+	resp, err := p.post(ctx, model, "converse-stream", converseRequestBody(model, req))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Content block index -> tool call index, for routing toolUse input fragments
+	toolIndex := make(map[int]int)
+	emit := func(delta llm.StreamDelta, finish string) {
+		fn(&llm.StreamChunk{Model: model, Choices: []llm.StreamChoice{{Index: 0, Delta: delta, FinishReason: finish}}})
+	}
+
+	events := newEventStreamReader(resp.Body)
+	for {
+		msg, err := events.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch msg.Headers[":message-type"] {
+		case "exception":
+			var e struct {
+				Message string `json:"message"`
+			}
+			_ = json.Unmarshal(msg.Payload, &e)
+			return fmt.Errorf("bedrock stream %s: %s", msg.Headers[":exception-type"], e.Message)
+		case "error":
+			return fmt.Errorf("bedrock stream %s: %s", msg.Headers[":error-code"], msg.Headers[":error-message"])
+		}
+
+		var event struct {
+			ContentBlockIndex int `json:"contentBlockIndex"`
+			Start             struct {
+				ToolUse *bedrockToolUse `json:"toolUse"`
+			} `json:"start"`
+			Delta struct {
+				Text    string `json:"text"`
+				ToolUse *struct {
+					Input string `json:"input"`
+				} `json:"toolUse"`
+				ReasoningContent *struct {
+					Text            string `json:"text"`
+					Signature       string `json:"signature"`
+					RedactedContent []byte `json:"redactedContent"`
+				} `json:"reasoningContent"`
+			} `json:"delta"`
+			StopReason string        `json:"stopReason"`
+			Usage      *bedrockUsage `json:"usage"`
+		}
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			continue
+		}
+
+		switch msg.Headers[":event-type"] {
+		case "contentBlockStart":
+			if tu := event.Start.ToolUse; tu != nil {
+				idx := len(toolIndex)
+				toolIndex[event.ContentBlockIndex] = idx
+				emit(llm.StreamDelta{ToolCalls: []llm.ToolCall{{
+					Index:    idx,
+					ID:       tu.ToolUseID,
+					Type:     "function",
+					Function: &llm.ToolFunction{Name: tu.Name},
+				}}}, "")
+			}
+		case "contentBlockDelta":
+			d := event.Delta
+			switch {
+			case d.ToolUse != nil:
+				if idx, ok := toolIndex[event.ContentBlockIndex]; ok && d.ToolUse.Input != "" {
+					emit(llm.StreamDelta{ToolCalls: []llm.ToolCall{{
+						Index:    idx,
+						Function: &llm.ToolFunction{Arguments: d.ToolUse.Input},
+					}}}, "")
+				}
+			case d.ReasoningContent != nil:
+				rc := d.ReasoningContent
+				switch {
+				case rc.Text != "":
+					emit(llm.StreamDelta{Thinking: rc.Text}, "")
+				case rc.Signature != "":
+					emit(llm.StreamDelta{ThinkingSignature: rc.Signature}, "")
+				case len(rc.RedactedContent) > 0:
+					emit(llm.StreamDelta{ThinkingSignature: string(rc.RedactedContent)}, "")
+				}
+			case d.Text != "":
+				emit(llm.StreamDelta{Content: d.Text}, "")
+			}
+		case "messageStop":
+			emit(llm.StreamDelta{}, convertStopReason(event.StopReason))
+		case "metadata":
+			if event.Usage != nil {
+				u := event.Usage.toUsage()
+				fn(&llm.StreamChunk{Model: model, Usage: &u})
+			}
+		}
+	}
 }
 
+// Embeddings implements llm.Provider.Embeddings with Titan or Cohere
+// embedding models through InvokeModel
 func (p *Provider) Embeddings(ctx context.Context, req *llm.EmbedRequest) (*llm.EmbedResponse, error) {
-	// Bedrock Titan embeddings
-	return nil, nil
+	model := cmp.Or(p.config.EmbedModel, getEnv("BEDROCK_EMBED_MODEL", defaultEmbedModel))
+	if isBedrockEmbedModel(req.Model) {
+		model = req.Model
+	}
+
+	cohere := strings.HasPrefix(model, "cohere.")
+	var payload any = map[string]any{"inputText": req.Input}
+	if cohere {
+		payload = map[string]any{"texts": []string{req.Input}, "input_type": "search_document"}
+	}
+	resp, err := p.post(ctx, model, "invoke", payload)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Embedding           []float64   `json:"embedding"`           // Titan
+		InputTextTokenCount int         `json:"inputTextTokenCount"` // Titan
+		Embeddings          [][]float64 `json:"embeddings"`          // Cohere
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("bedrock: decode embedding response: %w", err)
+	}
+
+	vector := out.Embedding
+	if cohere && len(out.Embeddings) > 0 {
+		vector = out.Embeddings[0]
+	}
+	if len(vector) == 0 {
+		return nil, fmt.Errorf("bedrock: %s returned no embedding", model)
+	}
+	return &llm.EmbedResponse{
+		Data:  []llm.Embedding{{Object: "embedding", Embedding: vector}},
+		Usage: llm.Usage{PromptTokens: out.InputTextTokenCount, TotalTokens: out.InputTextTokenCount},
+	}, nil
+}
+
+// isBedrockEmbedModel reports whether a requested model names a supported
+// embedding model (other names, e.g. OpenAI defaults, fall back to the config)
+func isBedrockEmbedModel(model string) bool {
+	return strings.HasPrefix(model, "amazon.titan-embed") || strings.HasPrefix(model, "cohere.embed")
+}
+
+// post sends a signed JSON request to /model/{modelId}/{action}
+func (p *Provider) post(ctx context.Context, model, action string, payload any) (*http.Response, error) {
+	creds := resolveCredentials(p.config.APIKey)
+	if !creds.Valid() {
+		return nil, fmt.Errorf("bedrock: no AWS credentials (set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY or configure %s)", credentialsFilePath())
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	// Model IDs may contain ':' and ARNs '/', which must arrive escaped
+	u := p.endpoint + "/model/" + uriEncode(model) + "/" + action
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+	for k, v := range p.config.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	signRequest(httpReq, body, creds, p.region, signingService, time.Now())
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if err := llm.CheckResponse(resp.StatusCode, resp.Body); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ============ Converse wire format ============
+
+type bedrockMessage struct {
+	Role    string           `json:"role"`
+	Content []bedrockContent `json:"content"`
+}
+
+// bedrockContent is a Converse content block; exactly one field is set
+type bedrockContent struct {
+	Text             string             `json:"text,omitempty"`
+	Image            *bedrockImage      `json:"image,omitempty"`
+	ToolUse          *bedrockToolUse    `json:"toolUse,omitempty"`
+	ToolResult       *bedrockToolResult `json:"toolResult,omitempty"`
+	ReasoningContent *bedrockReasoning  `json:"reasoningContent,omitempty"`
+}
+
+type bedrockImage struct {
+	Format string `json:"format"` // png | jpeg | gif | webp
+	Source struct {
+		Bytes []byte `json:"bytes"`
+	} `json:"source"`
+}
+
+type bedrockToolUse struct {
+	ToolUseID string          `json:"toolUseId"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input,omitempty"`
+}
+
+type bedrockToolResult struct {
+	ToolUseID string           `json:"toolUseId"`
+	Content   []bedrockContent `json:"content"`
+	Status    string           `json:"status,omitempty"` // success | error
+}
+
+type bedrockReasoning struct {
+	ReasoningText *struct {
+		Text      string `json:"text"`
+		Signature string `json:"signature,omitempty"`
+	} `json:"reasoningText,omitempty"`
+	RedactedContent []byte `json:"redactedContent,omitempty"`
+}
+
+type bedrockUsage struct {
+	InputTokens           int `json:"inputTokens"`
+	OutputTokens          int `json:"outputTokens"`
+	CacheReadInputTokens  int `json:"cacheReadInputTokens"`
+	CacheWriteInputTokens int `json:"cacheWriteInputTokens"`
+}
+
+// toUsage converts to llm.Usage, whose prompt tokens include cache reads and writes
+func (u bedrockUsage) toUsage() llm.Usage {
+	prompt := u.InputTokens + u.CacheReadInputTokens + u.CacheWriteInputTokens
+	return llm.Usage{
+		PromptTokens:     prompt,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      prompt + u.OutputTokens,
+		CachedTokens:     u.CacheReadInputTokens,
+	}
+}
+
+// converseRequestBody builds the Converse / ConverseStream request body
+func converseRequestBody(model string, req *llm.ChatRequest) map[string]any {
+	system, messages := convertToBedrockMessages(req.Messages)
+	body := map[string]any{"messages": messages}
+	if len(system) > 0 {
+		body["system"] = system
+	}
+
+	inference := map[string]any{}
+	if req.MaxTokens > 0 {
+		inference["maxTokens"] = req.MaxTokens
+	}
+	if req.Temperature > 0 {
+		inference["temperature"] = req.Temperature
+	}
+	if req.TopP > 0 {
+		inference["topP"] = req.TopP
+	}
+	// Claude models take extended thinking as a model-specific field
+	if req.Thinking != nil && req.Thinking.BudgetTokens > 0 && strings.Contains(model, "anthropic.") {
+		budget := max(req.Thinking.BudgetTokens, 1024)
+		inference["maxTokens"] = budget + cmp.Or(req.MaxTokens, 4096)
+		delete(inference, "temperature")
+		delete(inference, "topP")
+		body["additionalModelRequestFields"] = map[string]any{
+			"thinking": map[string]any{"type": "enabled", "budget_tokens": budget},
+		}
+	}
+	if len(inference) > 0 {
+		body["inferenceConfig"] = inference
+	}
+
+	if tools := convertToBedrockTools(req.Tools); len(tools) > 0 {
+		body["toolConfig"] = map[string]any{"tools": tools}
+	}
+	return body
+}
+
+// convertToBedrockMessages maps llm messages onto Converse content blocks.
+// System messages become the system prompt, tool results become user
+// toolResult blocks, and consecutive same-role turns are merged (Converse
+// requires alternating user/assistant turns).
+func convertToBedrockMessages(msgs []llm.Message) ([]bedrockContent, []bedrockMessage) {
+	var system []bedrockContent
+	result := make([]bedrockMessage, 0, len(msgs))
+
+	for _, m := range msgs {
+		if m.Role == "system" {
+			if text := m.Text(); text != "" {
+				system = append(system, bedrockContent{Text: text})
+			}
+			continue
+		}
+
+		role := "user"
+		if m.Role == "assistant" {
+			role = "assistant"
+		}
+
+		// Reasoning blocks must lead the assistant turn they belong to
+		var reasoning, blocks []bedrockContent
+		if m.Role == "tool" {
+			for _, r := range m.ToolResults() {
+				blocks = append(blocks, bedrockToolResultBlock(r))
+			}
+		} else {
+			for _, part := range m.ContentParts() {
+				switch part.Type {
+				case llm.PartText:
+					if part.Text != "" {
+						blocks = append(blocks, bedrockContent{Text: part.Text})
+					}
+				case llm.PartImage:
+					if len(part.Data) == 0 {
+						// Converse only takes inline image bytes
+						blocks = append(blocks, bedrockContent{Text: "[image omitted: " + part.URL + "]"})
+						continue
+					}
+					img := &bedrockImage{Format: imageFormat(part.MimeType)}
+					img.Source.Bytes = part.Data
+					blocks = append(blocks, bedrockContent{Image: img})
+				case llm.PartAudio:
+					blocks = append(blocks, bedrockContent{Text: "[audio attachment omitted]"})
+				case llm.PartToolResult:
+					blocks = append(blocks, bedrockToolResultBlock(part))
+				case llm.PartThinking:
+					if role != "assistant" {
+						continue
+					}
+					rc := &bedrockReasoning{}
+					if part.Text == "" {
+						rc.RedactedContent = []byte(part.Signature)
+					} else {
+						rc.ReasoningText = &struct {
+							Text      string `json:"text"`
+							Signature string `json:"signature,omitempty"`
+						}{Text: part.Text, Signature: part.Signature}
+					}
+					reasoning = append(reasoning, bedrockContent{ReasoningContent: rc})
+				}
+			}
+			for _, tc := range m.AllToolCalls() {
+				if tc.Function == nil {
+					continue
+				}
+				input := json.RawMessage(tc.Function.Arguments)
+				if len(input) == 0 || !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, bedrockContent{ToolUse: &bedrockToolUse{ToolUseID: tc.ID, Name: tc.Function.Name, Input: input}})
+			}
+		}
+		blocks = append(reasoning, blocks...)
+		if len(blocks) == 0 {
+			continue
+		}
+
+		if n := len(result); n > 0 && result[n-1].Role == role {
+			result[n-1].Content = append(result[n-1].Content, blocks...)
+			continue
+		}
+		result = append(result, bedrockMessage{Role: role, Content: blocks})
+	}
+	return system, result
+}
+
+func bedrockToolResultBlock(r llm.ContentPart) bedrockContent {
+	text := r.Text
+	if text == "" {
+		text = "(no output)"
+	}
+	tr := &bedrockToolResult{ToolUseID: r.ToolCallID, Content: []bedrockContent{{Text: text}}}
+	if r.IsError {
+		tr.Status = "error"
+	}
+	return bedrockContent{ToolResult: tr}
+}
+
+// imageFormat maps a MIME type to a Converse image format
+func imageFormat(mimeType string) string {
+	switch format := strings.TrimPrefix(mimeType, "image/"); format {
+	case "jpeg", "gif", "webp":
+		return format
+	case "jpg":
+		return "jpeg"
+	}
+	return "png"
+}
+
+// convertToBedrockTools maps OpenAI-style function tools to Converse tool specs
+func convertToBedrockTools(tools []llm.Tool) []map[string]any {
+	result := make([]map[string]any, 0, len(tools))
+	for _, t := range tools {
+		if t.Function == nil || t.Function.Name == "" {
+			continue
+		}
+		schema := t.Function.Parameters
+		if schema == nil {
+			schema = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		spec := map[string]any{
+			"name":        t.Function.Name,
+			"inputSchema": map[string]any{"json": schema},
+		}
+		if t.Function.Description != "" {
+			spec["description"] = t.Function.Description
+		}
+		result = append(result, map[string]any{"toolSpec": spec})
+	}
+	return result
+}
+
+// convertFromBedrockContent maps Converse output blocks back to an llm message
+func convertFromBedrockContent(blocks []bedrockContent) llm.Message {
+	msg := llm.Message{Role: "assistant"}
+	for _, b := range blocks {
+		switch {
+		case b.ToolUse != nil:
+			args := string(b.ToolUse.Input)
+			if args == "" {
+				args = "{}"
+			}
+			msg.ToolCalls = append(msg.ToolCalls, llm.ToolCall{
+				ID:       b.ToolUse.ToolUseID,
+				Type:     "function",
+				Function: &llm.ToolFunction{Name: b.ToolUse.Name, Arguments: args},
+			})
+		case b.ReasoningContent != nil:
+			if rt := b.ReasoningContent.ReasoningText; rt != nil {
+				msg.Parts = append(msg.Parts, llm.ThinkingPart(rt.Text, rt.Signature))
+			} else if len(b.ReasoningContent.RedactedContent) > 0 {
+				msg.Parts = append(msg.Parts, llm.ThinkingPart("", string(b.ReasoningContent.RedactedContent)))
+			}
+		default:
+			msg.Content += b.Text
+		}
+	}
+	return msg
+}
+
+// convertStopReason maps Converse stop reasons to OpenAI finish reasons
+func convertStopReason(reason string) string {
+	switch reason {
+	case "tool_use":
+		return "tool_calls"
+	case "max_tokens":
+		return "length"
+	case "guardrail_intervened", "content_filtered":
+		return "content_filter"
+	case "":
+		return ""
+	}
+	return "stop"
 }
 
 var _ llm.Provider = (*Provider)(nil)
@@ -0,0 +1,341 @@
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gliderlab/cogate/pkg/llm"
+)
+
+const (
+	testKeyID  = "AKIDEXAMPLE"
+	testSecret = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+)
+
+// From the AWS SigV4 test suite (get-vanilla)
+func TestSignRequestMatchesAWSVector(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	now, _ := time.Parse(amzDateFormat, "20150830T123600Z")
+	signRequest(req, nil, Credentials{AccessKeyID: testKeyID, SecretAccessKey: testSecret}, "us-east-1", "service", now)
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization =\n%s\nwant\n%s", got, want)
+	}
+}
+
+var authPattern = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=([^/]+)/(\d{8})/([^/]+)/bedrock/aws4_request, SignedHeaders=([^,]+), Signature=([0-9a-f]{64})$`)
+
+// standIn is a local Bedrock runtime that rejects requests whose SigV4
+// signature does not verify against the test secret
+func standIn(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, body []byte)) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := verifySignature(r, body); err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprintf(w, `{"message":%q}`, err.Error())
+			return
+		}
+		handler(w, r, body)
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("AWS_REGION", "us-west-2")
+	return srv
+}
+
+// verifySignature recomputes the signature from the request as received
+func verifySignature(r *http.Request, body []byte) error {
+	m := authPattern.FindStringSubmatch(r.Header.Get("Authorization"))
+	if m == nil {
+		return fmt.Errorf("malformed Authorization: %q", r.Header.Get("Authorization"))
+	}
+	keyID, date, region, signed, signature := m[1], m[2], m[3], strings.Split(m[4], ";"), m[5]
+	if keyID != testKeyID || region != "us-west-2" {
+		return fmt.Errorf("unexpected credential scope %s/%s", keyID, region)
+	}
+	amzDate := r.Header.Get("X-Amz-Date")
+	if !strings.HasPrefix(amzDate, date) {
+		return errors.New("X-Amz-Date does not match the credential scope")
+	}
+
+	var canonical strings.Builder
+	canonical.WriteString(r.Method + "\n")
+	segments := strings.Split(r.URL.EscapedPath(), "/")
+	for i, s := range segments {
+		segments[i] = strings.ReplaceAll(s, "%", "%25")
+	}
+	canonical.WriteString(strings.Join(segments, "/") + "\n\n")
+	for _, name := range signed {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonical.WriteString(name + ":" + value + "\n")
+	}
+	sum := hashHex(body)
+	canonical.WriteString("\n" + m[4] + "\n" + sum)
+
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + date + "/" + region + "/bedrock/aws4_request\n" + hashHex([]byte(canonical.String()))
+	want := hex.EncodeToString(hmacSHA256(signingKey(testSecret, date, region, "bedrock"), []byte(toSign)))
+	if signature != want {
+		return fmt.Errorf("signature mismatch for canonical request:\n%s", canonical.String())
+	}
+	return nil
+}
+
+func newTestProvider(baseURL string) *Provider {
+	return New(llm.Config{Type: llm.ProviderBedrock, APIKey: testKeyID + ":" + testSecret, BaseURL: baseURL, Model: "anthropic.claude-3-sonnet-20240229-v1:0"})
+}
+
+func TestChatConverseToolUse(t *testing.T) {
+	var sent map[string]any
+	srv := standIn(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		if r.URL.EscapedPath() != "/model/anthropic.claude-3-sonnet-20240229-v1%3A0/converse" {
+			t.Errorf("unexpected path %s", r.URL.EscapedPath())
+		}
+		_ = json.Unmarshal(body, &sent)
+		fmt.Fprint(w, `{"output":{"message":{"role":"assistant","content":[{"text":"checking"},{"toolUse":{"toolUseId":"t1","name":"read","input":{"path":"a"}}}]}},"stopReason":"tool_use","usage":{"inputTokens":10,"outputTokens":5,"cacheReadInputTokens":4}}`)
+	})
+
+	p := newTestProvider(srv.URL)
+	resp, err := p.Chat(context.Background(), &llm.ChatRequest{
+		Messages: []llm.Message{
+			{Role: "system", Content: "be brief"},
+			{Role: "user", Content: "read a"},
+			{Role: "assistant", ToolCalls: []llm.ToolCall{{ID: "t0", Type: "function", Function: &llm.ToolFunction{Name: "read", Arguments: `{"path":"b"}`}}}},
+			{Role: "tool", ToolCallID: "t0", Content: "B"},
+		},
+		MaxTokens: 100,
+		Tools:     []llm.Tool{{Type: "function", Function: &llm.ToolFunction{Name: "read", Description: "read a file"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	choice := resp.Choices[0]
+	if choice.FinishReason != "tool_calls" || choice.Message.Content != "checking" {
+		t.Errorf("unexpected choice: %+v", choice)
+	}
+	if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].Function.Arguments != `{"path":"a"}` {
+		t.Errorf("tool call not parsed: %+v", choice.Message.ToolCalls)
+	}
+	if resp.Usage.PromptTokens != 14 || resp.Usage.CachedTokens != 4 || resp.Usage.CompletionTokens != 5 {
+		t.Errorf("unexpected usage: %+v", resp.Usage)
+	}
+
+	if sys := sent["system"].([]any); len(sys) != 1 {
+		t.Errorf("system prompt not sent: %v", sent["system"])
+	}
+	msgs := sent["messages"].([]any)
+	if len(msgs) != 3 {
+		t.Fatalf("expected user/assistant/user turns, got %v", msgs)
+	}
+	result := msgs[2].(map[string]any)["content"].([]any)[0].(map[string]any)["toolResult"].(map[string]any)
+	if result["toolUseId"] != "t0" {
+		t.Errorf("tool result not converted: %v", result)
+	}
+	tool := sent["toolConfig"].(map[string]any)["tools"].([]any)[0].(map[string]any)["toolSpec"].(map[string]any)
+	if tool["name"] != "read" || tool["inputSchema"].(map[string]any)["json"] == nil {
+		t.Errorf("tool spec not converted: %v", tool)
+	}
+}
+
+func TestChatRejectsBadSignature(t *testing.T) {
+	srv := standIn(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		t.Error("request with a wrong secret was accepted")
+	})
+
+	p := New(llm.Config{APIKey: testKeyID + ":wrong-secret", BaseURL: srv.URL, Model: "m"})
+	_, err := p.Chat(context.Background(), &llm.ChatRequest{Messages: []llm.Message{{Role: "user", Content: "hi"}}})
+	var apiErr *llm.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 APIError, got %v", err)
+	}
+}
+
+// encodeEvent encodes one event stream message with string headers
+func encodeEvent(headers map[string]string, payload string) []byte {
+	var hb bytes.Buffer
+	for name, value := range headers {
+		hb.WriteByte(byte(len(name)))
+		hb.WriteString(name)
+		hb.WriteByte(7)
+		_ = binary.Write(&hb, binary.BigEndian, uint16(len(value)))
+		hb.WriteString(value)
+	}
+	var msg bytes.Buffer
+	_ = binary.Write(&msg, binary.BigEndian, uint32(12+hb.Len()+len(payload)+4))
+	_ = binary.Write(&msg, binary.BigEndian, uint32(hb.Len()))
+	_ = binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	msg.Write(hb.Bytes())
+	msg.WriteString(payload)
+	_ = binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	return msg.Bytes()
+}
+
+func event(eventType, payload string) []byte {
+	return encodeEvent(map[string]string{":message-type": "event", ":event-type": eventType, ":content-type": "application/json"}, payload)
+}
+
+func TestChatStreamEventStream(t *testing.T) {
+	srv := standIn(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		if !strings.HasSuffix(r.URL.Path, "/converse-stream") {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+		for _, ev := range [][]byte{
+			event("messageStart", `{"role":"assistant"}`),
+			event("contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"hi"}}`),
+			event("contentBlockStop", `{"contentBlockIndex":0}`),
+			event("contentBlockStart", `{"contentBlockIndex":1,"start":{"toolUse":{"toolUseId":"t1","name":"read"}}}`),
+			event("contentBlockDelta", `{"contentBlockIndex":1,"delta":{"toolUse":{"input":"{\"path\":"}}}`),
+			event("contentBlockDelta", `{"contentBlockIndex":1,"delta":{"toolUse":{"input":"\"a\"}"}}}`),
+			event("messageStop", `{"stopReason":"tool_use"}`),
+			event("metadata", `{"usage":{"inputTokens":7,"outputTokens":3,"totalTokens":10}}`),
+		} {
+			w.Write(ev)
+		}
+	})
+
+	p := newTestProvider(srv.URL)
+	var text, args strings.Builder
+	var name, finish string
+	var usage *llm.Usage
+	err := p.ChatStream(context.Background(), &llm.ChatRequest{Messages: []llm.Message{{Role: "user", Content: "x"}}}, func(c *llm.StreamChunk) {
+		if c.Usage != nil {
+			usage = c.Usage
+		}
+		if len(c.Choices) == 0 {
+			return
+		}
+		d := c.Choices[0]
+		text.WriteString(d.Delta.Content)
+		for _, tc := range d.Delta.ToolCalls {
+			if tc.Function.Name != "" {
+				name = tc.Function.Name
+			}
+			args.WriteString(tc.Function.Arguments)
+		}
+		if d.FinishReason != "" {
+			finish = d.FinishReason
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text.String() != "hi" || name != "read" || args.String() != `{"path":"a"}` || finish != "tool_calls" {
+		t.Errorf("unexpected stream result: text=%q name=%q args=%q finish=%q", text.String(), name, args.String(), finish)
+	}
+	if usage == nil || usage.PromptTokens != 7 || usage.CompletionTokens != 3 {
+		t.Errorf("usage not reported: %+v", usage)
+	}
+}
+
+func TestChatStreamException(t *testing.T) {
+	srv := standIn(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		w.Write(event("contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"par"}}`))
+		w.Write(encodeEvent(map[string]string{":message-type": "exception", ":exception-type": "throttlingException"}, `{"message":"slow down"}`))
+	})
+
+	err := newTestProvider(srv.URL).ChatStream(context.Background(), &llm.ChatRequest{Messages: []llm.Message{{Role: "user", Content: "x"}}}, func(*llm.StreamChunk) {})
+	if err == nil || !strings.Contains(err.Error(), "throttlingException") || !strings.Contains(err.Error(), "slow down") {
+		t.Errorf("expected stream exception, got %v", err)
+	}
+}
+
+func TestEventStreamChecksum(t *testing.T) {
+	msg := event("messageStop", `{"stopReason":"end_turn"}`)
+	msg[len(msg)-6] ^= 0xff // corrupt the payload
+	if _, err := newEventStreamReader(bytes.NewReader(msg)).Next(); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("expected checksum error, got %v", err)
+	}
+}
+
+func TestEmbeddings(t *testing.T) {
+	srv := standIn(t, func(w http.ResponseWriter, r *http.Request, body []byte) {
+		var req map[string]any
+		_ = json.Unmarshal(body, &req)
+		switch r.URL.EscapedPath() {
+		case "/model/amazon.titan-embed-text-v2%3A0/invoke":
+			if req["inputText"] != "hello" {
+				t.Errorf("titan input = %v", req)
+			}
+			fmt.Fprint(w, `{"embedding":[0.1,0.2],"inputTextTokenCount":2}`)
+		case "/model/cohere.embed-english-v3/invoke":
+			if texts, _ := req["texts"].([]any); len(texts) != 1 || req["input_type"] == nil {
+				t.Errorf("cohere input = %v", req)
+			}
+			fmt.Fprint(w, `{"id":"e1","embeddings":[[0.3,0.4,0.5]],"texts":["hello"]}`)
+		default:
+			t.Errorf("unexpected path %s", r.URL.EscapedPath())
+		}
+	})
+
+	p := newTestProvider(srv.URL)
+	p.config.EmbedModel = "amazon.titan-embed-text-v2:0"
+	titan, err := p.Embeddings(context.Background(), &llm.EmbedRequest{Model: "text-embedding-3-small", Input: "hello"})
+	if err != nil || len(titan.Data[0].Embedding) != 2 || titan.Usage.PromptTokens != 2 {
+		t.Errorf("titan embedding = %+v, %v", titan, err)
+	}
+	cohere, err := p.Embeddings(context.Background(), &llm.EmbedRequest{Model: "cohere.embed-english-v3", Input: "hello"})
+	if err != nil || len(cohere.Data[0].Embedding) != 3 {
+		t.Errorf("cohere embedding = %+v, %v", cohere, err)
+	}
+}
+
+func TestResolveCredentials(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "credentials")
+	os.WriteFile(path, []byte("[default]\naws_access_key_id = AKIDDEFAULT\naws_secret_access_key = s1\n\n[work]\naws_access_key_id=AKIDWORK\naws_secret_access_key=s2\naws_session_token=tok\n"), 0600)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", path)
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_PROFILE", "work")
+
+	if c := resolveCredentials(""); c.AccessKeyID != "AKIDWORK" || c.SecretAccessKey != "s2" || c.SessionToken != "tok" {
+		t.Errorf("profile credentials = %+v", c)
+	}
+	if c := resolveCredentials("AKIDOTHER"); c.Valid() {
+		t.Errorf("key ID without a known secret should not resolve: %+v", c)
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDENV")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "s3")
+	if c := resolveCredentials("AKIDENV"); c.SecretAccessKey != "s3" {
+		t.Errorf("environment credentials = %+v", c)
+	}
+	if c := resolveCredentials("AKIDINLINE:s4:tok2"); c.AccessKeyID != "AKIDINLINE" || c.SecretAccessKey != "s4" || c.SessionToken != "tok2" {
+		t.Errorf("inline credentials = %+v", c)
+	}
+}
+
+func TestResolveEndpoint(t *testing.T) {
+	t.Setenv("AWS_REGION", "eu-west-1")
+	for base, want := range map[string][2]string{
+		"":          {"https://bedrock-runtime.eu-west-1.amazonaws.com", "eu-west-1"},
+		"us-east-2": {"https://bedrock-runtime.us-east-2.amazonaws.com", "us-east-2"},
+		"https://bedrock-runtime.ap-south-1.amazonaws.com/": {"https://bedrock-runtime.ap-south-1.amazonaws.com", "ap-south-1"},
+		"http://127.0.0.1:9000":                             {"http://127.0.0.1:9000", "eu-west-1"},
+	} {
+		endpoint, region := resolveEndpoint(base)
+		if endpoint != want[0] || region != want[1] {
+			t.Errorf("resolveEndpoint(%q) = %s, %s", base, endpoint, region)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+// sigv4.go - AWS Signature Version 4 request signing and credential lookup
+package bedrock
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	signingAlgorithm = "AWS4-HMAC-SHA256"
+	signingService   = "bedrock"
+	amzDateFormat    = "20060102T150405Z"
+)
+
+// Credentials are AWS access keys
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Set for temporary (STS) credentials
+}
+
+// Valid reports whether the credentials can sign requests
+func (c Credentials) Valid() bool {
+	return c.AccessKeyID != "" && c.SecretAccessKey != ""
+}
+
+// HasCredentials reports whether requests made with apiKey as the configured
+// key can be signed, looking where resolveCredentials does
+func HasCredentials(apiKey string) bool {
+	return resolveCredentials(apiKey).Valid()
+}
+
+// resolveCredentials finds credentials in order: an "accessKeyId:secret[:sessionToken]"
+// API key, the AWS_* environment variables (the API key may name the access
+// key ID on its own), then the shared credentials file.
+func resolveCredentials(apiKey string) Credentials {
+	if parts := strings.SplitN(apiKey, ":", 3); len(parts) >= 2 {
+		creds := Credentials{AccessKeyID: parts[0], SecretAccessKey: parts[1]}
+		if len(parts) == 3 {
+			creds.SessionToken = parts[2]
+		}
+		return creds
+	}
+
+	env := Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if apiKey != "" && apiKey != env.AccessKeyID {
+		// A different key ID than the environment's: only the file can know its secret
+		env = Credentials{AccessKeyID: apiKey}
+	}
+	if env.Valid() {
+		return env
+	}
+
+	file, err := loadCredentialsFile(credentialsFilePath(), getEnv("AWS_PROFILE", "default"))
+	if err != nil || !file.Valid() || (apiKey != "" && file.AccessKeyID != apiKey) {
+		return env
+	}
+	return file
+}
+
+func credentialsFilePath() string {
+	if path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".aws", "credentials")
+}
+
+// loadCredentialsFile reads one profile of an INI-style AWS credentials file
+func loadCredentialsFile(path, profile string) (Credentials, error) {
+	var creds Credentials
+	if path == "" {
+		return creds, fmt.Errorf("no credentials file")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return creds, err
+	}
+	defer f.Close()
+
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(strings.TrimPrefix(line[1:len(line)-1], "profile "))
+			continue
+		}
+		if section != profile {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "aws_access_key_id":
+			creds.AccessKeyID = value
+		case "aws_secret_access_key":
+			creds.SecretAccessKey = value
+		case "aws_session_token":
+			creds.SessionToken = value
+		}
+	}
+	return creds, scanner.Err()
+}
+
+// signRequest signs req for region and service with SigV4. The body must be
+// the exact bytes sent; the Host, X-Amz-Date and (for temporary credentials)
+// X-Amz-Security-Token headers are set here.
+func signRequest(req *http.Request, body []byte, creds Credentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format(amzDateFormat)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	canonical, signedHeaders := canonicalRequest(req, body)
+	scope := credentialScope(amzDate, region, service)
+	signature := hex.EncodeToString(hmacSHA256(
+		signingKey(creds.SecretAccessKey, amzDate[:8], region, service),
+		stringToSign(amzDate, scope, canonical),
+	))
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		signingAlgorithm, creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalRequest builds the SigV4 canonical request and its signed header list.
+// Host, Content-Type and all X-Amz-* headers are signed.
+func canonicalRequest(req *http.Request, body []byte) (string, string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.Join(values, ",")
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString(req.Method)
+	sb.WriteString("\n")
+	sb.WriteString(canonicalURI(req.URL))
+	sb.WriteString("\n")
+	sb.WriteString(canonicalQuery(req.URL))
+	sb.WriteString("\n")
+	for _, name := range names {
+		sb.WriteString(name)
+		sb.WriteString(":")
+		sb.WriteString(strings.Join(strings.Fields(headers[name]), " "))
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+	signedHeaders := strings.Join(names, ";")
+	sb.WriteString(signedHeaders)
+	sb.WriteString("\n")
+	sb.WriteString(hashHex(body))
+	return sb.String(), signedHeaders
+}
+
+// canonicalURI escapes each segment of the already escaped request path once
+// more, as SigV4 requires for every service but S3
+func canonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = uriEncode(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+func canonicalQuery(u *url.URL) string {
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes everything but the SigV4 unreserved characters
+func uriEncode(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+func credentialScope(amzDate, region, service string) string {
+	return amzDate[:8] + "/" + region + "/" + service + "/aws4_request"
+}
+
+func stringToSign(amzDate, scope, canonical string) []byte {
+	return []byte(signingAlgorithm + "\n" + amzDate + "\n" + scope + "\n" + hashHex([]byte(canonical)))
+}
+
+// signingKey derives the SigV4 key for one day, region and service
+func signingKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), []byte(date))
+	key = hmacSHA256(key, []byte(region))
+	key = hmacSHA256(key, []byte(service))
+	return hmacSHA256(key, []byte("aws4_request"))
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
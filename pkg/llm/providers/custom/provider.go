@@ -95,24 +95,18 @@ func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, fn func
 		return err
 	}
 
+	httpReq.Header.Set("Accept", "text/event-stream")
+
 	resp, err := p.client.Do(httpReq.WithContext(ctx))
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	decoder := json.NewDecoder(resp.Body)
-	for {
-		var chunk llm.StreamChunk
-		if err := decoder.Decode(&chunk); err != nil {
-			break
-		}
-		fn(&chunk)
-		if len(chunk.Choices) > 0 && chunk.Choices[0].FinishReason != "" {
-			break
-		}
+	if err := llm.CheckResponse(resp.StatusCode, resp.Body); err != nil {
+		return err
 	}
-	return nil
+	return llm.ReadSSE(resp.Body, fn)
 }
 
 // Embeddings implements llm.Provider.Embeddings
@@ -193,7 +187,7 @@ func (p *Provider) doRequest(ctx context.Context, req *http.Request) ([]byte, er
 					continue
 				}
 			}
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+			return nil, &llm.APIError{StatusCode: resp.StatusCode, Body: string(body)}
 		}
 		return body, nil
 	}
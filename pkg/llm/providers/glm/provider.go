@@ -52,12 +52,30 @@ func (p *Provider) Type() llm.ProviderType { return llm.ProviderGLM }
 func (p *Provider) GetConfig() llm.Config  { return p.config }
 
 func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
-	return p.doChat(ctx, req, false)
+	resp, err := p.doChat(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var result llm.ChatResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
 }
 
 func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, fn func(*llm.StreamChunk)) error {
-	_, err := p.doChat(ctx, req, true)
-	return err
+	resp, err := p.doChat(ctx, req, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return llm.ReadSSE(resp.Body, fn)
 }
 
 func (p *Provider) Embeddings(ctx context.Context, req *llm.EmbedRequest) (*llm.EmbedResponse, error) {
@@ -80,25 +98,30 @@ func (p *Provider) Embeddings(ctx context.Context, req *llm.EmbedRequest) (*llm.
 	return &result, nil
 }
 
-func (p *Provider) doChat(ctx context.Context, req *llm.ChatRequest, stream bool) (*llm.ChatResponse, error) {
+func (p *Provider) doChat(ctx context.Context, req *llm.ChatRequest, stream bool) (*http.Response, error) {
 	url := p.config.BaseURL + "/chat/completions"
 	req.Stream = stream
-	body, _ := json.Marshal(req)
-	
-	httpReq, _ := http.NewRequest("POST", url, strings.NewReader(string(body)))
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequest("POST", url, strings.NewReader(string(body)))
+	if err != nil {
+		return nil, err
+	}
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+p.config.APIKey)
-	
+
 	resp, err := p.client.Do(httpReq.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	
-	data, _ := io.ReadAll(resp.Body)
-	var result llm.ChatResponse
-	json.Unmarshal(data, &result)
-	return &result, nil
+	if err := llm.CheckResponse(resp.StatusCode, resp.Body); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
 }
 
 var _ llm.Provider = (*Provider)(nil)
@@ -2,8 +2,10 @@
 package google
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -72,28 +74,7 @@ func (p *Provider) Type() llm.ProviderType { return llm.ProviderGoogle }
 
 // Chat implements llm.Provider.Chat
 func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
-	contents := make([]map[string]interface{}, len(req.Messages))
-	for i, m := range req.Messages {
-		role := "user"
-		if m.Role == "assistant" {
-			role = "model"
-		}
-		contents[i] = map[string]interface{}{
-			"role": role,
-			"parts": []map[string]string{{"text": m.Content}},
-		}
-	}
-
-	googleReq := map[string]interface{}{
-		"contents":           contents,
-		"generationConfig": map[string]interface{}{
-			"temperature":     req.Temperature,
-			"maxOutputTokens": req.MaxTokens,
-			"topP":            req.TopP,
-		},
-	}
-
-	httpReq, err := p.buildRequest("/models/"+req.Model+":generateContent", googleReq)
+	httpReq, err := p.buildRequest("/models/"+req.Model+":generateContent", p.generateRequest(req))
 	if err != nil {
 		return nil, err
 	}
@@ -103,96 +84,299 @@ func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatRes
 		return nil, err
 	}
 
-	var resp struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
-	}
-
+	var resp geminiResponse
 	if err := json.Unmarshal(body, &resp); err != nil {
 		return nil, err
 	}
 
-	content := ""
-	if len(resp.Candidates) > 0 && len(resp.Candidates[0].Content.Parts) > 0 {
-		content = resp.Candidates[0].Content.Parts[0].Text
+	msg := llm.Message{Role: "assistant"}
+	finish := "stop"
+	if len(resp.Candidates) > 0 {
+		msg = convertFromGeminiParts(resp.Candidates[0].Content.Parts, 0)
+		if len(msg.ToolCalls) > 0 {
+			finish = "tool_calls"
+		} else if resp.Candidates[0].FinishReason == "MAX_TOKENS" {
+			finish = "length"
+		}
 	}
 
 	return &llm.ChatResponse{
 		ID:      "",
 		Model:   req.Model,
-		Choices: []llm.Choice{{Index: 0, Message: llm.Message{Role: "assistant", Content: content}, FinishReason: "stop"}},
-		Usage:   llm.Usage{},
+		Choices: []llm.Choice{{Index: 0, Message: msg, FinishReason: finish}},
+		Usage:   resp.UsageMetadata.toUsage(),
 	}, nil
 }
 
 // ChatStream implements llm.Provider.ChatStream
 func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, fn func(*llm.StreamChunk)) error {
-	contents := make([]map[string]interface{}, len(req.Messages))
-	for i, m := range req.Messages {
-		role := "user"
-		if m.Role == "assistant" {
-			role = "model"
+	httpReq, err := p.buildRequest("/models/"+req.Model+":streamGenerateContent?alt=sse", p.generateRequest(req))
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(httpReq.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := llm.CheckResponse(resp.StatusCode, resp.Body); err != nil {
+		return err
+	}
+
+	toolIndex := 0
+	var usage geminiUsage
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, readErr := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "data:") {
+			var chunk geminiResponse
+			err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &chunk)
+			if err == nil && chunk.UsageMetadata.TotalTokenCount > 0 {
+				// Each chunk carries the running totals; the last one wins
+				usage = chunk.UsageMetadata
+			}
+			if err == nil && len(chunk.Candidates) > 0 {
+				// Gemini sends function calls whole, so each one is a complete delta
+				msg := convertFromGeminiParts(chunk.Candidates[0].Content.Parts, toolIndex)
+				for i := range msg.ToolCalls {
+					msg.ToolCalls[i].Index = toolIndex
+					toolIndex++
+				}
+				if msg.Content != "" || len(msg.ToolCalls) > 0 {
+					fn(&llm.StreamChunk{
+						Choices: []llm.StreamChoice{
+							{
+								Index: 0,
+								Delta: llm.StreamDelta{
+									Content:   msg.Content,
+									ToolCalls: msg.ToolCalls,
+								},
+							},
+						},
+					})
+				}
+			}
 		}
-		contents[i] = map[string]interface{}{
-			"role": role,
-			"parts": []map[string]string{{"text": m.Content}},
+		if readErr != nil {
+			if readErr == io.EOF {
+				if usage.TotalTokenCount > 0 {
+					u := usage.toUsage()
+					fn(&llm.StreamChunk{Usage: &u})
+				}
+				return nil
+			}
+			return readErr
 		}
 	}
+}
+
+// ============ Gemini wire format ============
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	InlineData       *geminiBlob             `json:"inlineData,omitempty"`
+	FileData         *geminiFileData         `json:"fileData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiFileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+type geminiFunctionCall struct {
+	Name string         `json:"name"`
+	Args map[string]any `json:"args"`
+}
+
+type geminiFunctionResponse struct {
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata geminiUsage `json:"usageMetadata"`
+}
+
+type geminiUsage struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	TotalTokenCount         int `json:"totalTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount"`
+}
+
+func (u geminiUsage) toUsage() llm.Usage {
+	return llm.Usage{
+		PromptTokens:     u.PromptTokenCount,
+		CompletionTokens: u.CandidatesTokenCount,
+		TotalTokens:      u.TotalTokenCount,
+		CachedTokens:     u.CachedContentTokenCount,
+	}
+}
 
+// generateRequest builds the generateContent request body
+func (p *Provider) generateRequest(req *llm.ChatRequest) map[string]interface{} {
+	system, contents := convertToGeminiContents(req.Messages)
 	googleReq := map[string]interface{}{
 		"contents": contents,
 		"generationConfig": map[string]interface{}{
-			"temperature":      req.Temperature,
-			"maxOutputTokens":  req.MaxTokens,
-			"topP":             req.TopP,
-			"stream":           true,
+			"temperature":     req.Temperature,
+			"maxOutputTokens": req.MaxTokens,
+			"topP":            req.TopP,
 		},
 	}
-
-	httpReq, err := p.buildRequest("/models/"+req.Model+":streamGenerateContent", googleReq)
-	if err != nil {
-		return err
+	if system != nil {
+		googleReq["systemInstruction"] = system
 	}
+	if decls := convertToGeminiFunctions(req.Tools); len(decls) > 0 {
+		googleReq["tools"] = []map[string]interface{}{{"functionDeclarations": decls}}
+	}
+	if f := req.ResponseFormat; f.Structured() {
+		gen := googleReq["generationConfig"].(map[string]interface{})
+		gen["responseMimeType"] = "application/json"
+		gen["responseJsonSchema"] = f.Schema()
+	}
+	return googleReq
+}
 
-	resp, err := p.client.Do(httpReq.WithContext(ctx))
-	if err != nil {
-		return err
+// convertToGeminiContents maps llm messages onto Gemini contents. System
+// messages become the systemInstruction; tool results become functionResponse
+// parts, named after the call they answer since Gemini matches by name.
+func convertToGeminiContents(msgs []llm.Message) (*geminiContent, []geminiContent) {
+	var system *geminiContent
+	callNames := make(map[string]string)
+	contents := make([]geminiContent, 0, len(msgs))
+
+	for _, m := range msgs {
+		if m.Role == "system" {
+			if text := m.Text(); text != "" {
+				if system == nil {
+					system = &geminiContent{}
+				}
+				system.Parts = append(system.Parts, geminiPart{Text: text})
+			}
+			continue
+		}
+
+		role := "user"
+		if m.Role == "assistant" {
+			role = "model"
+		}
+
+		var parts []geminiPart
+		if m.Role != "tool" {
+			for _, part := range m.ContentParts() {
+				switch part.Type {
+				case llm.PartText:
+					if part.Text != "" {
+						parts = append(parts, geminiPart{Text: part.Text})
+					}
+				case llm.PartImage, llm.PartAudio:
+					if len(part.Data) > 0 {
+						parts = append(parts, geminiPart{InlineData: &geminiBlob{
+							MimeType: part.MimeType,
+							Data:     base64.StdEncoding.EncodeToString(part.Data),
+						}})
+					} else if part.URL != "" {
+						parts = append(parts, geminiPart{FileData: &geminiFileData{MimeType: part.MimeType, FileURI: part.URL}})
+					}
+				}
+			}
+			for _, tc := range m.AllToolCalls() {
+				if tc.Function == nil {
+					continue
+				}
+				callNames[tc.ID] = tc.Function.Name
+				args := map[string]any{}
+				_ = json.Unmarshal([]byte(tc.Function.Arguments), &args)
+				parts = append(parts, geminiPart{FunctionCall: &geminiFunctionCall{Name: tc.Function.Name, Args: args}})
+			}
+		}
+		for _, r := range m.ToolResults() {
+			name := r.ToolName
+			if name == "" {
+				name = callNames[r.ToolCallID]
+			}
+			response := map[string]any{}
+			if err := json.Unmarshal([]byte(r.Text), &response); err != nil {
+				response = map[string]any{"result": r.Text}
+			}
+			if r.IsError {
+				response = map[string]any{"error": r.Text}
+			}
+			parts = append(parts, geminiPart{FunctionResponse: &geminiFunctionResponse{Name: name, Response: response}})
+		}
+		if len(parts) == 0 {
+			continue
+		}
+
+		if n := len(contents); n > 0 && contents[n-1].Role == role {
+			contents[n-1].Parts = append(contents[n-1].Parts, parts...)
+			continue
+		}
+		contents = append(contents, geminiContent{Role: role, Parts: parts})
 	}
-	defer resp.Body.Close()
+	return system, contents
+}
 
-	decoder := json.NewDecoder(resp.Body)
-	for {
-		var chunk struct {
-			Candidates []struct {
-				Content struct {
-					Parts []struct {
-						Text string `json:"text"`
-					} `json:"parts"`
-				} `json:"content"`
-			} `json:"candidates"`
+// convertToGeminiFunctions maps OpenAI-style function tools to Gemini function declarations
+func convertToGeminiFunctions(tools []llm.Tool) []map[string]interface{} {
+	decls := make([]map[string]interface{}, 0, len(tools))
+	for _, t := range tools {
+		if t.Function == nil || t.Function.Name == "" {
+			continue
 		}
-		if err := decoder.Decode(&chunk); err != nil {
-			break
+		decl := map[string]interface{}{"name": t.Function.Name}
+		if t.Function.Description != "" {
+			decl["description"] = t.Function.Description
 		}
-		if len(chunk.Candidates) > 0 && len(chunk.Candidates[0].Content.Parts) > 0 {
-			fn(&llm.StreamChunk{
-				Choices: []llm.StreamChoice{
-					{
-						Index: 0,
-						Delta: llm.StreamDelta{
-							Content: chunk.Candidates[0].Content.Parts[0].Text,
-						},
-					},
-				},
+		if t.Function.Parameters != nil {
+			decl["parameters"] = t.Function.Parameters
+		}
+		decls = append(decls, decl)
+	}
+	return decls
+}
+
+// convertFromGeminiParts maps response parts back to an llm message. Gemini
+// does not assign call IDs, so they are generated from the call position.
+func convertFromGeminiParts(parts []geminiPart, offset int) llm.Message {
+	msg := llm.Message{Role: "assistant"}
+	for _, part := range parts {
+		if part.Text != "" {
+			msg.Content += part.Text
+		}
+		if part.FunctionCall != nil {
+			args, _ := json.Marshal(part.FunctionCall.Args)
+			if part.FunctionCall.Args == nil {
+				args = []byte("{}")
+			}
+			msg.ToolCalls = append(msg.ToolCalls, llm.ToolCall{
+				ID:       fmt.Sprintf("call_%s_%d", part.FunctionCall.Name, offset+len(msg.ToolCalls)),
+				Type:     "function",
+				Function: &llm.ToolFunction{Name: part.FunctionCall.Name, Arguments: string(args)},
 			})
 		}
 	}
-	return nil
+	return msg
 }
 
 // Embeddings implements llm.Provider.Embeddings
@@ -289,8 +473,12 @@ func (p *Provider) Embeddings(ctx context.Context, req *llm.EmbedRequest) (*llm.
 
 func (p *Provider) buildRequest(endpoint string, body any) (*http.Request, error) {
 	url := p.config.BaseURL + endpoint
-	if !strings.Contains(url, "?key=") && p.config.APIKey != "" {
-		url += "?key=" + p.config.APIKey
+	if !strings.Contains(url, "key=") && p.config.APIKey != "" {
+		sep := "?"
+		if strings.Contains(url, "?") {
+			sep = "&"
+		}
+		url += sep + "key=" + p.config.APIKey
 	}
 	var bodyStr string
 	if body != nil {
@@ -346,7 +534,7 @@ func (p *Provider) doRequest(ctx context.Context, req *http.Request) ([]byte, er
 					continue
 				}
 			}
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+			return nil, &llm.APIError{StatusCode: resp.StatusCode, Body: string(body)}
 		}
 		return body, nil
 	}
@@ -378,6 +566,7 @@ func (p *Provider) Capabilities() []llm.Capability {
 		llm.CapabilityVision,
 		llm.CapabilityTranscription,
 		llm.CapabilityRealtime,
+		llm.CapabilityStructuredOutput,
 	}
 }
 
@@ -0,0 +1,50 @@
+package google
+
+import (
+	"testing"
+
+	"github.com/gliderlab/cogate/pkg/llm"
+)
+
+func TestConvertToGeminiContents(t *testing.T) {
+	system, contents := convertToGeminiContents([]llm.Message{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "look", Parts: []llm.ContentPart{llm.ImagePart("image/jpeg", []byte{1, 2, 3})}},
+		{Role: "assistant", ToolCalls: []llm.ToolCall{{ID: "c1", Type: "function", Function: &llm.ToolFunction{Name: "read", Arguments: `{"path":"a"}`}}}},
+		{Role: "tool", ToolCallID: "c1", Content: `{"content":"A"}`},
+	})
+
+	if system == nil || system.Parts[0].Text != "be brief" {
+		t.Errorf("system instruction not set: %+v", system)
+	}
+	if len(contents) != 3 {
+		t.Fatalf("expected 3 contents, got %d", len(contents))
+	}
+	if blob := contents[0].Parts[1].InlineData; blob == nil || blob.MimeType != "image/jpeg" || blob.Data != "AQID" {
+		t.Errorf("image not inlined: %+v", contents[0].Parts[1])
+	}
+	if call := contents[1].Parts[0].FunctionCall; contents[1].Role != "model" || call == nil || call.Args["path"] != "a" {
+		t.Errorf("function call not converted: %+v", contents[1])
+	}
+	if resp := contents[2].Parts[0].FunctionResponse; resp == nil || resp.Name != "read" || resp.Response["content"] != "A" {
+		t.Errorf("function response not named after its call: %+v", contents[2])
+	}
+}
+
+func TestConvertFromGeminiParts(t *testing.T) {
+	msg := convertFromGeminiParts([]geminiPart{
+		{Text: "ok"},
+		{FunctionCall: &geminiFunctionCall{Name: "read", Args: map[string]any{"path": "a"}}},
+		{FunctionCall: &geminiFunctionCall{Name: "exec"}},
+	}, 0)
+
+	if msg.Content != "ok" || len(msg.ToolCalls) != 2 {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	if msg.ToolCalls[0].ID == msg.ToolCalls[1].ID {
+		t.Error("tool call IDs must be unique")
+	}
+	if msg.ToolCalls[1].Function.Arguments != "{}" {
+		t.Errorf("nil args should become {}: %s", msg.ToolCalls[1].Function.Arguments)
+	}
+}
@@ -71,10 +71,13 @@ func (p *Provider) Type() llm.ProviderType { return llm.ProviderMiniMax }
 func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
 	minimaxReq := map[string]interface{}{
 		"model":       req.Model,
-		"messages":     req.Messages,
+		"messages":    llm.ExpandToolResults(req.Messages),
 		"temperature": req.Temperature,
 		"tokens":      req.MaxTokens,
 	}
+	if len(req.Tools) > 0 {
+		minimaxReq["tools"] = req.Tools
+	}
 
 	httpReq, err := p.buildRequest("/text/chatcompletion_v2", minimaxReq)
 	if err != nil {
@@ -110,12 +113,13 @@ func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatRes
 	}
 
 	if len(resp.Choices) > 0 {
-		content := resp.Choices[0].Message.Content
+		msg := resp.Choices[0].Message
+		msg.Role = "assistant"
 		finishReason := resp.Choices[0].FinishReason
 		return &llm.ChatResponse{
 			ID:      "",
 			Model:   req.Model,
-			Choices: []llm.Choice{{Index: 0, Message: llm.Message{Role: "assistant", Content: content}, FinishReason: finishReason}},
+			Choices: []llm.Choice{{Index: 0, Message: msg, FinishReason: finishReason}},
 			Usage:   resp.Usage,
 		}, nil
 	}
@@ -133,11 +137,14 @@ func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatRes
 func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, fn func(*llm.StreamChunk)) error {
 	minimaxReq := map[string]interface{}{
 		"model":       req.Model,
-		"messages":     req.Messages,
+		"messages":    llm.ExpandToolResults(req.Messages),
 		"temperature": req.Temperature,
 		"tokens":      req.MaxTokens,
 		"stream":      true,
 	}
+	if len(req.Tools) > 0 {
+		minimaxReq["tools"] = req.Tools
+	}
 
 	httpReq, err := p.buildRequest("/text/chatcompletion_v2", minimaxReq)
 	if err != nil {
@@ -153,37 +160,11 @@ func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, fn func
 	}
 	defer resp.Body.Close()
 
-	decoder := json.NewDecoder(resp.Body)
-	for {
-		var chunk struct {
-			Choices []struct {
-				Delta struct {
-					Content string `json:"content"`
-				} `json:"delta"`
-				FinishReason string `json:"finish_reason"`
-			} `json:"choices"`
-		}
-		if err := decoder.Decode(&chunk); err != nil {
-			break
-		}
-		if len(chunk.Choices) > 0 {
-			fn(&llm.StreamChunk{
-				Choices: []llm.StreamChoice{
-					{
-						Index: 0,
-						Delta: llm.StreamDelta{
-							Content: chunk.Choices[0].Delta.Content,
-						},
-						FinishReason: chunk.Choices[0].FinishReason,
-					},
-				},
-			})
-			if chunk.Choices[0].FinishReason != "" {
-				break
-			}
-		}
+	if err := llm.CheckResponse(resp.StatusCode, resp.Body); err != nil {
+		return err
 	}
-	return nil
+
+	return llm.ReadSSE(resp.Body, fn)
 }
 
 // Embeddings implements llm.Provider.Embeddings
@@ -270,7 +251,7 @@ func (p *Provider) doRequest(ctx context.Context, req *http.Request) ([]byte, er
 					continue
 				}
 			}
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+			return nil, &llm.APIError{StatusCode: resp.StatusCode, Body: string(body)}
 		}
 		return body, nil
 	}
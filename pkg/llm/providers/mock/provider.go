@@ -0,0 +1,447 @@
+// Package mock provides a scriptable LLM provider that replays fixture responses.
+// It is meant for offline, deterministic tests of the agent, gateway and channels.
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/gliderlab/cogate/pkg/llm"
+)
+
+// Script is a mock fixture: an ordered list of responses, loaded from YAML or JSON.
+//
+//	model: mock-model
+//	responses:
+//	  - tool_calls:
+//	      - name: echo
+//	        arguments: {text: hi}
+//	  - match: "echo:"
+//	    thinking: The tool answered.
+//	    chunks: ["The tool ", "said hi."]
+//	    delay: 20ms
+//	  - error: rate limited
+//	    status: 429
+type Script struct {
+	Model     string     `yaml:"model"`
+	Loop      bool       `yaml:"loop"` // Start over when all responses are used
+	Responses []Response `yaml:"responses"`
+
+	// Capabilities the provider reports, e.g. structured_output
+	Capabilities []llm.Capability `yaml:"capabilities"`
+}
+
+// Response is one scripted model turn
+type Response struct {
+	// Match, when set, only lets this response answer a request whose last
+	// message contains the given text. Unmatched responses wait their turn.
+	Match string `yaml:"match"`
+
+	Content      string     `yaml:"content"`
+	Chunks       []string   `yaml:"chunks"` // Streamed content deltas (default: Content in one chunk)
+	ToolCalls    []ToolCall `yaml:"tool_calls"`
+	FinishReason string     `yaml:"finish_reason"`
+	Usage        *Usage     `yaml:"usage"` // Default: estimated from the text
+
+	// Thinking is extended thinking text, returned (as a signed thinking
+	// block) only when the request asks for thinking
+	Thinking string `yaml:"thinking"`
+
+	// Error fails the request. With Status it is returned as an *llm.APIError;
+	// in a stream it is returned after Chunks were delivered.
+	Error  string `yaml:"error"`
+	Status int    `yaml:"status"`
+
+	Delay      time.Duration `yaml:"delay"`       // Latency before the response starts
+	ChunkDelay time.Duration `yaml:"chunk_delay"` // Latency between streamed chunks
+}
+
+// ToolCall is a scripted tool call. Arguments may be an object or a JSON string.
+type ToolCall struct {
+	ID        string `yaml:"id"`
+	Name      string `yaml:"name"`
+	Arguments any    `yaml:"arguments"`
+}
+
+// Usage is scripted token usage
+type Usage struct {
+	PromptTokens     int `yaml:"prompt_tokens"`
+	CompletionTokens int `yaml:"completion_tokens"`
+}
+
+// Parse decodes a YAML or JSON fixture
+func Parse(data []byte) (*Script, error) {
+	var s Script
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("mock script: %w", err)
+	}
+	return &s, nil
+}
+
+// Load reads a YAML or JSON fixture file
+func Load(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Provider implements llm.Provider by replaying a Script
+type Provider struct {
+	config  llm.Config
+	script  *Script
+	loadErr error
+
+	mu       sync.Mutex
+	used     []bool
+	calls    int
+	requests []llm.ChatRequest
+}
+
+// New creates a mock provider. The fixture path is taken from cfg.BaseURL
+// (a file path or file:// URL) or MOCK_LLM_FIXTURE; load errors are returned
+// by every request.
+func New(cfg llm.Config) *Provider {
+	cfg.Type = llm.ProviderMock
+	path := strings.TrimPrefix(cfg.BaseURL, "file://")
+	if path == "" {
+		path = os.Getenv("MOCK_LLM_FIXTURE")
+	}
+	if path == "" {
+		p := NewFromScript(&Script{})
+		p.config = cfg
+		p.loadErr = fmt.Errorf("mock provider: no fixture configured (set baseUrl or MOCK_LLM_FIXTURE)")
+		return p
+	}
+	script, err := Load(path)
+	if err != nil {
+		script = &Script{}
+	}
+	p := NewFromScript(script)
+	p.config = cfg
+	if p.config.Model == "" {
+		p.config.Model = script.Model
+	}
+	p.loadErr = err
+	return p
+}
+
+// NewFromEnv creates a mock provider from MOCK_LLM_FIXTURE, or returns nil if it is not set
+func NewFromEnv() *Provider {
+	if os.Getenv("MOCK_LLM_FIXTURE") == "" {
+		return nil
+	}
+	return New(llm.Config{Model: os.Getenv("MOCK_LLM_MODEL")})
+}
+
+// NewFromScript creates a mock provider that replays script
+func NewFromScript(script *Script) *Provider {
+	model := script.Model
+	if model == "" {
+		model = "mock-model"
+	}
+	return &Provider{
+		config: llm.Config{Type: llm.ProviderMock, Model: model},
+		script: script,
+		used:   make([]bool, len(script.Responses)),
+	}
+}
+
+// Name returns the provider name
+func (p *Provider) Name() string { return "mock" }
+
+// Type returns the provider type
+func (p *Provider) Type() llm.ProviderType { return llm.ProviderMock }
+
+// GetConfig returns the provider config
+func (p *Provider) GetConfig() llm.Config { return p.config }
+
+// Requests returns a copy of every chat request received so far
+func (p *Provider) Requests() []llm.ChatRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]llm.ChatRequest(nil), p.requests...)
+}
+
+// Calls returns the number of chat requests received so far
+func (p *Provider) Calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+// Remaining returns the number of scripted responses not used yet
+func (p *Provider) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for _, u := range p.used {
+		if !u {
+			n++
+		}
+	}
+	return n
+}
+
+// next records req and picks the first unused response that matches it.
+// It also returns the 1-based request number.
+func (p *Provider) next(req *llm.ChatRequest) (*Response, int, error) {
+	if p.loadErr != nil {
+		return nil, 0, p.loadErr
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.calls++
+	p.requests = append(p.requests, *req)
+
+	last := ""
+	if n := len(req.Messages); n > 0 {
+		last = req.Messages[n-1].Text()
+		for _, r := range req.Messages[n-1].ToolResults() {
+			last += "\n" + r.Text
+		}
+	}
+
+	for pass := 0; pass < 2; pass++ {
+		for i := range p.script.Responses {
+			r := &p.script.Responses[i]
+			if p.used[i] || (r.Match != "" && !strings.Contains(last, r.Match)) {
+				continue
+			}
+			p.used[i] = true
+			return r, p.calls, nil
+		}
+		if !p.script.Loop {
+			break
+		}
+		for i := range p.used {
+			p.used[i] = false
+		}
+	}
+	return nil, p.calls, fmt.Errorf("mock script exhausted after %d responses (request %d)", len(p.script.Responses), p.calls)
+}
+
+// Chat implements llm.Provider.Chat
+func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	r, n, err := p.next(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := sleep(ctx, r.Delay); err != nil {
+		return nil, err
+	}
+	if r.Error != "" {
+		return nil, r.err()
+	}
+
+	msg := llm.Message{Role: "assistant", Content: r.text()}
+	calls, err := r.toolCalls(n)
+	if err != nil {
+		return nil, err
+	}
+	msg.ToolCalls = calls
+	if r.Thinking != "" && req.Thinking != nil {
+		msg.Parts = append(msg.Parts, llm.ThinkingPart(r.Thinking, fmt.Sprintf("mock-%d-signature", n)))
+	}
+
+	return &llm.ChatResponse{
+		ID:    fmt.Sprintf("mock-%d", n),
+		Model: p.model(req),
+		Choices: []llm.Choice{{
+			Message:      msg,
+			FinishReason: r.finishReason(),
+		}},
+		Usage: r.usage(req),
+	}, nil
+}
+
+// ChatStream implements llm.Provider.ChatStream
+func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, fn func(*llm.StreamChunk)) error {
+	r, n, err := p.next(req)
+	if err != nil {
+		return err
+	}
+	if err := sleep(ctx, r.Delay); err != nil {
+		return err
+	}
+	if r.Error != "" && len(r.Chunks) == 0 {
+		return r.err()
+	}
+
+	id := fmt.Sprintf("mock-%d", n)
+	model := p.model(req)
+	send := func(delta llm.StreamDelta, finish string) {
+		fn(&llm.StreamChunk{ID: id, Model: model, Choices: []llm.StreamChoice{{Delta: delta, FinishReason: finish}}})
+	}
+
+	if r.Thinking != "" && req.Thinking != nil {
+		send(llm.StreamDelta{Role: "assistant", Thinking: r.Thinking}, "")
+		send(llm.StreamDelta{ThinkingSignature: id + "-signature"}, "")
+	}
+	chunks := r.Chunks
+	if len(chunks) == 0 && r.Content != "" {
+		chunks = []string{r.Content}
+	}
+	for i, c := range chunks {
+		if i > 0 {
+			if err := sleep(ctx, r.ChunkDelay); err != nil {
+				return err
+			}
+		}
+		delta := llm.StreamDelta{Content: c}
+		if i == 0 {
+			delta.Role = "assistant"
+		}
+		send(delta, "")
+	}
+	if r.Error != "" {
+		return r.err()
+	}
+
+	calls, err := r.toolCalls(n)
+	if err != nil {
+		return err
+	}
+	for i := range calls {
+		calls[i].Index = i
+	}
+	if len(calls) > 0 {
+		send(llm.StreamDelta{ToolCalls: calls}, "")
+	}
+	send(llm.StreamDelta{}, r.finishReason())
+	usage := r.usage(req)
+	fn(&llm.StreamChunk{ID: id, Model: model, Usage: &usage})
+	return nil
+}
+
+func (p *Provider) model(req *llm.ChatRequest) string {
+	if req.Model != "" {
+		return req.Model
+	}
+	return p.config.Model
+}
+
+func (r *Response) text() string {
+	if r.Content != "" || len(r.Chunks) == 0 {
+		return r.Content
+	}
+	return strings.Join(r.Chunks, "")
+}
+
+func (r *Response) err() error {
+	if r.Status > 0 {
+		return &llm.APIError{StatusCode: r.Status, Body: r.Error}
+	}
+	return errors.New(r.Error)
+}
+
+func (r *Response) finishReason() string {
+	if r.FinishReason != "" {
+		return r.FinishReason
+	}
+	if len(r.ToolCalls) > 0 {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+// toolCalls converts the scripted calls; IDs default to call_<request>_<n>
+func (r *Response) toolCalls(request int) ([]llm.ToolCall, error) {
+	var calls []llm.ToolCall
+	for i, tc := range r.ToolCalls {
+		args := "{}"
+		switch v := tc.Arguments.(type) {
+		case nil:
+		case string:
+			args = v
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("mock tool call %s: %w", tc.Name, err)
+			}
+			args = string(b)
+		}
+		id := tc.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d_%d", request, i)
+		}
+		calls = append(calls, llm.ToolCall{
+			ID:       id,
+			Type:     "function",
+			Function: &llm.ToolFunction{Name: tc.Name, Arguments: args},
+		})
+	}
+	return calls, nil
+}
+
+// usage returns the scripted usage or a rough estimate (4 characters per token)
+func (r *Response) usage(req *llm.ChatRequest) llm.Usage {
+	if r.Usage != nil {
+		return llm.Usage{
+			PromptTokens:     r.Usage.PromptTokens,
+			CompletionTokens: r.Usage.CompletionTokens,
+			TotalTokens:      r.Usage.PromptTokens + r.Usage.CompletionTokens,
+		}
+	}
+	prompt := 0
+	for _, m := range req.Messages {
+		prompt += len(m.Text())/4 + 1
+	}
+	completion := len(r.text())/4 + 1
+	return llm.Usage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Capabilities returns supported capabilities
+func (p *Provider) Capabilities() []llm.Capability { return p.script.Capabilities }
+
+// Embeddings implements llm.Provider.Embeddings (not supported)
+func (p *Provider) Embeddings(ctx context.Context, req *llm.EmbedRequest) (*llm.EmbedResponse, error) {
+	return nil, llm.ErrCapabilityNotSupported
+}
+
+// Vision implements llm.Provider.Vision (not supported)
+func (p *Provider) Vision(ctx context.Context, req *llm.VisionRequest) (*llm.VisionResponse, error) {
+	return nil, llm.ErrCapabilityNotSupported
+}
+
+// TTS implements llm.Provider.TTS (not supported)
+func (p *Provider) TTS(ctx context.Context, req *llm.TTSRequest) (*llm.TTSResponse, error) {
+	return nil, llm.ErrCapabilityNotSupported
+}
+
+// Transcription implements llm.Provider.Transcription (not supported)
+func (p *Provider) Transcription(ctx context.Context, req *llm.TranscriptionRequest) (*llm.TranscriptionResponse, error) {
+	return nil, llm.ErrCapabilityNotSupported
+}
+
+// Realtime implements llm.Provider.Realtime (not supported)
+func (p *Provider) Realtime(ctx context.Context, cfg llm.RealtimeConfig) (llm.RealtimeProvider, error) {
+	return nil, llm.ErrCapabilityNotSupported
+}
+
+// Ensure Provider implements llm.Provider
+var _ llm.Provider = (*Provider)(nil)
@@ -0,0 +1,235 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gliderlab/cogate/pkg/llm"
+	"github.com/gliderlab/cogate/pkg/llm/providers/openai"
+)
+
+const fixture = `
+model: scripted
+responses:
+  - tool_calls:
+      - name: echo
+        arguments: {text: hi}
+  - match: "echoed:"
+    chunks: ["The tool ", "said hi."]
+    usage: {prompt_tokens: 10, completion_tokens: 4}
+  - error: rate limited
+    status: 429
+  - content: done
+`
+
+func mustParse(t *testing.T, src string) *Script {
+	t.Helper()
+	s, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return s
+}
+
+func userReq(text string) *llm.ChatRequest {
+	return &llm.ChatRequest{Messages: []llm.Message{{Role: "user", Content: text}}}
+}
+
+func TestChatReplaysScript(t *testing.T) {
+	p := NewFromScript(mustParse(t, fixture))
+	ctx := context.Background()
+
+	resp, err := p.Chat(ctx, userReq("say hi"))
+	if err != nil {
+		t.Fatalf("Chat 1: %v", err)
+	}
+	calls := resp.Choices[0].Message.ToolCalls
+	if len(calls) != 1 || calls[0].Function.Name != "echo" || calls[0].Function.Arguments != `{"text":"hi"}` {
+		t.Fatalf("tool calls = %+v", calls)
+	}
+	if calls[0].ID != "call_1_0" || resp.Choices[0].FinishReason != "tool_calls" || resp.Model != "scripted" {
+		t.Fatalf("resp = %+v", resp)
+	}
+
+	// The matched response waits until a request contains its text
+	req := &llm.ChatRequest{Messages: []llm.Message{
+		{Role: "assistant", ToolCalls: calls},
+		{Role: "tool", ToolCallID: calls[0].ID, Content: "echoed: hi"},
+	}}
+	resp, err = p.Chat(ctx, req)
+	if err != nil {
+		t.Fatalf("Chat 2: %v", err)
+	}
+	if got := resp.Choices[0].Message.Content; got != "The tool said hi." {
+		t.Fatalf("content = %q", got)
+	}
+	if resp.Usage.TotalTokens != 14 {
+		t.Fatalf("usage = %+v", resp.Usage)
+	}
+
+	_, err = p.Chat(ctx, userReq("again"))
+	var apiErr *llm.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 429 {
+		t.Fatalf("expected 429 APIError, got %v", err)
+	}
+
+	if _, err := p.Chat(ctx, userReq("last")); err != nil {
+		t.Fatalf("Chat 4: %v", err)
+	}
+	if _, err := p.Chat(ctx, userReq("extra")); err == nil || !strings.Contains(err.Error(), "exhausted") {
+		t.Fatalf("expected exhausted error, got %v", err)
+	}
+	if p.Calls() != 5 || len(p.Requests()) != 5 || p.Remaining() != 0 {
+		t.Fatalf("calls=%d requests=%d remaining=%d", p.Calls(), len(p.Requests()), p.Remaining())
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	p := NewFromScript(mustParse(t, `
+responses:
+  - chunks: ["a", "b", "c"]
+    tool_calls:
+      - id: t1
+        name: read
+        arguments: '{"path":"x"}'
+  - chunks: ["partial"]
+    error: connection reset
+`))
+
+	var content strings.Builder
+	var calls []llm.ToolCall
+	var finish string
+	var usage *llm.Usage
+	err := p.ChatStream(context.Background(), userReq("go"), func(c *llm.StreamChunk) {
+		if c.Usage != nil {
+			usage = c.Usage
+			return
+		}
+		content.WriteString(c.Choices[0].Delta.Content)
+		calls = append(calls, c.Choices[0].Delta.ToolCalls...)
+		if c.Choices[0].FinishReason != "" {
+			finish = c.Choices[0].FinishReason
+		}
+	})
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if content.String() != "abc" || len(calls) != 1 || calls[0].ID != "t1" || finish != "tool_calls" {
+		t.Fatalf("content=%q calls=%+v finish=%q", content.String(), calls, finish)
+	}
+	if usage == nil || usage.CompletionTokens == 0 {
+		t.Fatalf("expected a final usage chunk, got %+v", usage)
+	}
+
+	got := 0
+	err = p.ChatStream(context.Background(), userReq("go"), func(c *llm.StreamChunk) { got++ })
+	if err == nil || got != 1 {
+		t.Fatalf("expected error after 1 chunk, got %d chunks, err %v", got, err)
+	}
+}
+
+func TestDelayHonoursContext(t *testing.T) {
+	p := NewFromScript(mustParse(t, "responses:\n  - content: slow\n    delay: 1s\n"))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := p.Chat(ctx, userReq("hi")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatal("delay did not stop on context cancellation")
+	}
+}
+
+func TestLoopAndJSONFixture(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.json")
+	if err := os.WriteFile(path, []byte(`{"loop": true, "responses": [{"content": "pong"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := New(llm.Config{BaseURL: "file://" + path})
+	for i := 0; i < 3; i++ {
+		resp, err := p.Chat(context.Background(), userReq("ping"))
+		if err != nil || resp.Choices[0].Message.Content != "pong" {
+			t.Fatalf("call %d: %v %+v", i, err, resp)
+		}
+	}
+
+	missing := New(llm.Config{BaseURL: filepath.Join(t.TempDir(), "missing.yaml")})
+	if _, err := missing.Chat(context.Background(), userReq("ping")); err == nil {
+		t.Fatal("expected load error")
+	}
+}
+
+func TestServerSpeaksOpenAI(t *testing.T) {
+	p := NewFromScript(mustParse(t, fixture))
+	srv := NewServer(p)
+	defer srv.Close()
+
+	client := openai.New(llm.Config{Type: llm.ProviderOpenAI, BaseURL: srv.URL + "/v1", Model: "gpt-test", Timeout: 5})
+	ctx := context.Background()
+
+	first := userReq("say hi")
+	first.Model = "gpt-test"
+	resp, err := client.Chat(ctx, first)
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	calls := resp.Choices[0].Message.ToolCalls
+	if len(calls) != 1 || calls[0].Function.Name != "echo" || resp.Model != "gpt-test" {
+		t.Fatalf("resp = %+v", resp)
+	}
+
+	var content strings.Builder
+	req := &llm.ChatRequest{Model: "gpt-test", Messages: []llm.Message{
+		{Role: "assistant", ToolCalls: calls},
+		{Role: "tool", ToolCallID: calls[0].ID, Content: "echoed: hi"},
+	}}
+	var usage *llm.Usage
+	if err := client.ChatStream(ctx, req, func(c *llm.StreamChunk) {
+		if c.Usage != nil {
+			usage = c.Usage
+		}
+		if len(c.Choices) > 0 {
+			content.WriteString(c.Choices[0].Delta.Content)
+		}
+	}); err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if content.String() != "The tool said hi." {
+		t.Fatalf("streamed %q", content.String())
+	}
+	if usage == nil || usage.TotalTokens != 14 {
+		t.Fatalf("stream usage = %+v", usage)
+	}
+
+	// The scripted 429 is retried by the client (Retry-After: 0) and the
+	// retry is answered by the next response
+	resp, err = client.Chat(ctx, userReq("again"))
+	if err != nil || resp.Choices[0].Message.Content != "done" {
+		t.Fatalf("retry: %v %+v", err, resp)
+	}
+	if got := p.Requests()[1].Messages[1].ToolCallID; got != calls[0].ID {
+		t.Fatalf("server decoded tool message id %q", got)
+	}
+}
+
+func TestServerStreamAbort(t *testing.T) {
+	p := NewFromScript(mustParse(t, "responses:\n  - chunks: [\"half\"]\n    error: boom\n"))
+	srv := NewServer(p)
+	defer srv.Close()
+
+	client := openai.New(llm.Config{BaseURL: srv.URL, Timeout: 5})
+	got := ""
+	err := client.ChatStream(context.Background(), userReq("go"), func(c *llm.StreamChunk) {
+		got += c.Choices[0].Delta.Content
+	})
+	if err == nil || got != "half" {
+		t.Fatalf("expected broken stream after %q, got %q, err %v", "half", got, err)
+	}
+}
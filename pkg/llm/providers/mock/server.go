@@ -0,0 +1,111 @@
+// server.go - In-process HTTP stand-in speaking the OpenAI-compatible chat API
+package mock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gliderlab/cogate/pkg/llm"
+)
+
+// NewServer starts an HTTP server that answers OpenAI-compatible requests
+// from p's script. Point an openai provider (or any OpenAI-compatible client)
+// at srv.URL. Callers must Close the server.
+func NewServer(p *Provider) *httptest.Server {
+	return httptest.NewServer(Handler(p))
+}
+
+// Handler returns an http.Handler serving POST .../chat/completions and
+// GET .../models. Scripted errors with a status are sent as that status;
+// an error after streamed chunks aborts the connection mid-stream.
+func Handler(p *Provider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/models"):
+			writeJSON(w, http.StatusOK, map[string]any{
+				"object": "list",
+				"data":   []map[string]any{{"id": p.GetConfig().Model, "object": "model", "owned_by": "mock"}},
+			})
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/chat/completions"):
+			serveChat(p, w, r)
+		default:
+			writeError(w, http.StatusNotFound, fmt.Sprintf("unknown endpoint %s %s", r.Method, r.URL.Path))
+		}
+	})
+}
+
+func serveChat(p *Provider, w http.ResponseWriter, r *http.Request) {
+	var req llm.ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	if !req.Stream {
+		resp, err := p.Chat(r.Context(), &req)
+		if err != nil {
+			writeError(w, statusOf(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	started := false
+	err := p.ChatStream(r.Context(), &req, func(chunk *llm.StreamChunk) {
+		if !started {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	})
+	if err != nil {
+		if started {
+			// Headers are gone; drop the connection so the client sees a broken stream
+			panic(http.ErrAbortHandler)
+		}
+		writeError(w, statusOf(err), err.Error())
+		return
+	}
+	if !started {
+		w.Header().Set("Content-Type", "text/event-stream")
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// statusOf maps a provider error to an HTTP status (500 unless it is an *llm.APIError)
+func statusOf(err error) int {
+	var apiErr *llm.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode > 0 {
+		return apiErr.StatusCode
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError sends an OpenAI-style error body. Retryable statuses carry
+// Retry-After: 0 so client retries do not slow tests down.
+func writeError(w http.ResponseWriter, status int, message string) {
+	if status == http.StatusTooManyRequests || status >= 500 {
+		w.Header().Set("Retry-After", "0")
+	}
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{"message": message, "type": "mock_error", "code": status},
+	})
+}
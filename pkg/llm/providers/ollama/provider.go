@@ -3,6 +3,7 @@ package ollama
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -67,17 +68,7 @@ func (p *Provider) Type() llm.ProviderType { return llm.ProviderOllama }
 
 // Chat implements llm.Provider.Chat
 func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
-	ollamaReq := map[string]interface{}{
-		"model":      req.Model,
-		"messages":   req.Messages,
-		"stream":     false,
-		"options": map[string]interface{}{
-			"temperature": req.Temperature,
-			"num_predict": req.MaxTokens,
-		},
-	}
-
-	httpReq, err := p.buildRequest("/api/chat", ollamaReq)
+	httpReq, err := p.buildRequest("/api/chat", chatRequest(req, false))
 	if err != nil {
 		return nil, err
 	}
@@ -88,20 +79,30 @@ func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatRes
 	}
 
 	var resp struct {
-		Message      llm.Message `json:"message"`
-		Done         bool        `json:"done"`
-		PromptEvalCount int      `json:"prompt_eval_count"`
-		EvalCount    int         `json:"eval_count"`
+		Message         ollamaMessage `json:"message"`
+		Done            bool          `json:"done"`
+		PromptEvalCount int           `json:"prompt_eval_count"`
+		EvalCount       int           `json:"eval_count"`
 	}
 
 	if err := json.Unmarshal(body, &resp); err != nil {
 		return nil, err
 	}
 
+	msg := llm.Message{
+		Role:      resp.Message.Role,
+		Content:   resp.Message.Content,
+		ToolCalls: convertFromOllamaToolCalls(resp.Message.ToolCalls, 0),
+	}
+	finish := "stop"
+	if len(msg.ToolCalls) > 0 {
+		finish = "tool_calls"
+	}
+
 	return &llm.ChatResponse{
 		ID:      "",
 		Model:   req.Model,
-		Choices: []llm.Choice{{Index: 0, Message: resp.Message, FinishReason: "stop"}},
+		Choices: []llm.Choice{{Index: 0, Message: msg, FinishReason: finish}},
 		Usage: llm.Usage{
 			PromptTokens:     resp.PromptEvalCount,
 			CompletionTokens: resp.EvalCount,
@@ -112,17 +113,7 @@ func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatRes
 
 // ChatStream implements llm.Provider.ChatStream
 func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, fn func(*llm.StreamChunk)) error {
-	ollamaReq := map[string]interface{}{
-		"model":      req.Model,
-		"messages":   req.Messages,
-		"stream":     true,
-		"options": map[string]interface{}{
-			"temperature": req.Temperature,
-			"num_predict": req.MaxTokens,
-		},
-	}
-
-	httpReq, err := p.buildRequest("/api/chat", ollamaReq)
+	httpReq, err := p.buildRequest("/api/chat", chatRequest(req, true))
 	if err != nil {
 		return err
 	}
@@ -133,38 +124,149 @@ func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, fn func
 	}
 	defer resp.Body.Close()
 
+	if err := llm.CheckResponse(resp.StatusCode, resp.Body); err != nil {
+		return err
+	}
+
+	toolIndex := 0
 	decoder := json.NewDecoder(resp.Body)
 	for {
 		var chunk struct {
-			Message struct {
-				Content string `json:"content"`
-				Role    string `json:"role"`
-			} `json:"message"`
-			Done         bool `json:"done"`
-			PromptEvalCount int `json:"prompt_eval_count"`
-			EvalCount    int  `json:"eval_count"`
+			Message         ollamaMessage `json:"message"`
+			Done            bool          `json:"done"`
+			PromptEvalCount int           `json:"prompt_eval_count"`
+			EvalCount       int           `json:"eval_count"`
 		}
 		if err := decoder.Decode(&chunk); err != nil {
 			break
 		}
+		// Ollama sends tool calls whole, so each one is a complete delta
+		calls := convertFromOllamaToolCalls(chunk.Message.ToolCalls, toolIndex)
+		toolIndex += len(calls)
 		fn(&llm.StreamChunk{
 			Choices: []llm.StreamChoice{
 				{
 					Index: 0,
 					Delta: llm.StreamDelta{
-						Content: chunk.Message.Content,
-						Role:    chunk.Message.Role,
+						Content:   chunk.Message.Content,
+						Role:      chunk.Message.Role,
+						ToolCalls: calls,
 					},
 				},
 			},
 		})
 		if chunk.Done {
+			fn(&llm.StreamChunk{Usage: &llm.Usage{
+				PromptTokens:     chunk.PromptEvalCount,
+				CompletionTokens: chunk.EvalCount,
+				TotalTokens:      chunk.PromptEvalCount + chunk.EvalCount,
+			}})
 			break
 		}
 	}
 	return nil
 }
 
+// ============ Ollama wire format ============
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+func chatRequest(req *llm.ChatRequest, stream bool) map[string]interface{} {
+	ollamaReq := map[string]interface{}{
+		"model":    req.Model,
+		"messages": convertToOllamaMessages(req.Messages),
+		"stream":   stream,
+		"options": map[string]interface{}{
+			"temperature": req.Temperature,
+			"num_predict": req.MaxTokens,
+		},
+	}
+	if len(req.Tools) > 0 {
+		ollamaReq["tools"] = req.Tools
+	}
+	// Ollama takes the schema itself as format, or "json" for any JSON
+	if f := req.ResponseFormat; f.Structured() {
+		if f.Type == llm.ResponseFormatJSONSchema {
+			ollamaReq["format"] = f.Schema()
+		} else {
+			ollamaReq["format"] = "json"
+		}
+	}
+	return ollamaReq
+}
+
+// convertToOllamaMessages maps llm messages onto /api/chat messages. Images
+// travel base64-encoded beside the text, tool call arguments as JSON objects,
+// and each tool result as its own role "tool" message.
+func convertToOllamaMessages(msgs []llm.Message) []ollamaMessage {
+	callNames := make(map[string]string)
+	out := make([]ollamaMessage, 0, len(msgs))
+	for _, m := range llm.ExpandToolResults(msgs) {
+		om := ollamaMessage{Role: m.Role, Content: m.Text()}
+		for _, part := range m.Parts {
+			if part.Type == llm.PartImage && len(part.Data) > 0 {
+				om.Images = append(om.Images, base64.StdEncoding.EncodeToString(part.Data))
+			}
+		}
+		for _, tc := range m.AllToolCalls() {
+			if tc.Function == nil {
+				continue
+			}
+			callNames[tc.ID] = tc.Function.Name
+			var call ollamaToolCall
+			call.Function.Name = tc.Function.Name
+			call.Function.Arguments = json.RawMessage(tc.Function.Arguments)
+			if len(call.Function.Arguments) == 0 || !json.Valid(call.Function.Arguments) {
+				call.Function.Arguments = json.RawMessage("{}")
+			}
+			om.ToolCalls = append(om.ToolCalls, call)
+		}
+		if m.Role == "tool" {
+			om.ToolName = m.Name
+			if om.ToolName == "" {
+				om.ToolName = callNames[m.ToolCallID]
+			}
+		}
+		out = append(out, om)
+	}
+	return out
+}
+
+// convertFromOllamaToolCalls maps Ollama tool calls (object arguments, no IDs)
+// to llm tool calls with string arguments and generated IDs
+func convertFromOllamaToolCalls(calls []ollamaToolCall, offset int) []llm.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]llm.ToolCall, 0, len(calls))
+	for i, c := range calls {
+		args := string(c.Function.Arguments)
+		if args == "" || args == "null" {
+			args = "{}"
+		}
+		out = append(out, llm.ToolCall{
+			Index:    offset + i,
+			ID:       fmt.Sprintf("call_%s_%d", c.Function.Name, offset+i),
+			Type:     "function",
+			Function: &llm.ToolFunction{Name: c.Function.Name, Arguments: args},
+		})
+	}
+	return out
+}
+
 // Embeddings implements llm.Provider.Embeddings
 func (p *Provider) Embeddings(ctx context.Context, req *llm.EmbedRequest) (*llm.EmbedResponse, error) {
 	embedReq := map[string]interface{}{
@@ -252,7 +354,7 @@ func (p *Provider) doRequest(ctx context.Context, req *http.Request) ([]byte, er
 					continue
 				}
 			}
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+			return nil, &llm.APIError{StatusCode: resp.StatusCode, Body: string(body)}
 		}
 		return body, nil
 	}
@@ -281,6 +383,7 @@ var _ llm.Provider = (*Provider)(nil)
 func (p *Provider) Capabilities() []llm.Capability {
 	return []llm.Capability{
 		llm.CapabilityEmbeddings,
+		llm.CapabilityStructuredOutput,
 	}
 }
 
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/gliderlab/cogate/pkg/llm"
+	openaiRealtime "github.com/gliderlab/cogate/pkg/llm/providers/openai/realtime"
 )
 
 // Provider implements llm.Provider for OpenAI
@@ -87,29 +88,24 @@ func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatRes
 // ChatStream implements llm.Provider.ChatStream
 func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, fn func(*llm.StreamChunk)) error {
 	req.Stream = true
+	req.StreamOptions = &llm.StreamOptions{IncludeUsage: true}
 	httpReq, err := p.buildRequest("/chat/completions", req)
 	if err != nil {
 		return err
 	}
 
+	httpReq.Header.Set("Accept", "text/event-stream")
+
 	resp, err := p.client.Do(httpReq.WithContext(ctx))
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	decoder := json.NewDecoder(resp.Body)
-	for {
-		var chunk llm.StreamChunk
-		if err := decoder.Decode(&chunk); err != nil {
-			break
-		}
-		fn(&chunk)
-		if len(chunk.Choices) > 0 && chunk.Choices[0].FinishReason != "" {
-			break
-		}
+	if err := llm.CheckResponse(resp.StatusCode, resp.Body); err != nil {
+		return err
 	}
-	return nil
+	return llm.ReadSSE(resp.Body, fn)
 }
 
 // Embeddings implements llm.Provider.Embeddings
@@ -190,7 +186,7 @@ func (p *Provider) doRequest(ctx context.Context, req *http.Request) ([]byte, er
 					continue
 				}
 			}
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(body))
+			return nil, &llm.APIError{StatusCode: resp.StatusCode, Body: string(body)}
 		}
 		return body, nil
 	}
@@ -220,6 +216,7 @@ func (p *Provider) Capabilities() []llm.Capability {
 		llm.CapabilityTTS,
 		llm.CapabilityTranscription,
 		llm.CapabilityRealtime,
+		llm.CapabilityStructuredOutput,
 	}
 }
 
@@ -314,7 +311,19 @@ func (p *Provider) Transcription(ctx context.Context, req *llm.TranscriptionRequ
 
 // Realtime implements llm.Provider.Realtime
 func (p *Provider) Realtime(ctx context.Context, cfg llm.RealtimeConfig) (llm.RealtimeProvider, error) {
-	return nil, llm.ErrCapabilityNotSupported
+	// Use API key and endpoint from config or provider config
+	if cfg.APIKey == "" {
+		cfg.APIKey = p.config.APIKey
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = p.config.BaseURL
+	}
+	if cfg.Model == "" && strings.Contains(strings.ToLower(p.config.Model), "realtime") {
+		cfg.Model = p.config.Model
+	}
+
+	rt := openaiRealtime.New(cfg)
+	return rt, nil
 }
 
 // Ensure Provider implements llm.Provider
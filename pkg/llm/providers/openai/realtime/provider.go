@@ -0,0 +1,523 @@
+// provider.go - OpenAI Realtime protocol client over WebSocket
+package realtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/coder/websocket"
+	"github.com/gliderlab/cogate/pkg/llm"
+)
+
+const (
+	defaultBaseURL = "https://api.openai.com/v1"
+	defaultModel   = "gpt-4o-realtime-preview"
+	defaultVoice   = "alloy"
+
+	// sampleRate is the rate of pcm16 audio in both directions
+	sampleRate = 24000
+
+	// maxMessageSize bounds one server event; audio deltas easily exceed the
+	// library's 32 KB default
+	maxMessageSize = 16 << 20
+)
+
+// Provider implements llm.RealtimeProvider for the OpenAI Realtime API and
+// compatible backends. Audio is 24 kHz mono pcm16 in both directions.
+type Provider struct {
+	config    llm.RealtimeConfig
+	conn      *websocket.Conn
+	connected bool
+	mu        sync.RWMutex
+	writeMu   sync.Mutex
+
+	pcmBuf      bytes.Buffer
+	uncommitted bool // audio appended since the input buffer was last committed
+
+	onAudioCb         func([]byte)
+	onTextCb          func(string)
+	onToolCallCb      func(llm.ToolCall)
+	onTranscriptionCb func(llm.TranscriptionResult)
+	onVADCb           func(llm.VADSignal)
+	onGoAwayCb        func(string)
+	onSessionUpdateCb func(bool)
+	onUsageCb         func(int, int)
+	onErrorCb         func(error)
+	onDisconnectCb    func()
+}
+
+func New(cfg llm.RealtimeConfig) *Provider { return &Provider{config: cfg} }
+
+// Connect dials the realtime endpoint and configures the session
+func (p *Provider) Connect(ctx context.Context, cfg llm.RealtimeConfig) error {
+	p.config = cfg
+	if p.config.APIKey == "" {
+		return fmt.Errorf("API key is required")
+	}
+	model := p.config.Model
+	if model == "" {
+		model = defaultModel
+	}
+
+	endpoint, err := realtimeURL(p.config.BaseURL, model)
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+p.config.APIKey)
+	header.Set("OpenAI-Beta", "realtime=v1")
+
+	conn, _, err := websocket.Dial(ctx, endpoint, &websocket.DialOptions{HTTPHeader: header})
+	if err != nil {
+		return fmt.Errorf("realtime connect failed: %w", err)
+	}
+	conn.SetReadLimit(maxMessageSize)
+
+	p.mu.Lock()
+	p.conn = conn
+	p.connected = true
+	p.pcmBuf.Reset()
+	p.uncommitted = false
+	p.mu.Unlock()
+
+	if err := p.send(ctx, map[string]any{"type": "session.update", "session": p.sessionConfig()}); err != nil {
+		_ = p.Disconnect()
+		return fmt.Errorf("session update failed: %w", err)
+	}
+
+	go p.receiveLoop(conn)
+	return nil
+}
+
+// realtimeURL turns an HTTP(S) API base URL into the realtime WebSocket URL.
+// A base URL already ending in /realtime is used as is.
+func realtimeURL(baseURL, model string) (string, error) {
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "https://" + baseURL
+	}
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return "", fmt.Errorf("invalid realtime base URL %q: %w", baseURL, err)
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	}
+	if !strings.HasSuffix(u.Path, "/realtime") {
+		u.Path += "/realtime"
+	}
+	q := u.Query()
+	q.Set("model", model)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// sessionConfig builds the session.update payload from the config
+func (p *Provider) sessionConfig() map[string]any {
+	voice := p.config.Voice
+	if voice == "" {
+		voice = defaultVoice
+	}
+	session := map[string]any{
+		"modalities":          []string{"text", "audio"},
+		"voice":               voice,
+		"input_audio_format":  "pcm16",
+		"output_audio_format": "pcm16",
+	}
+	if p.config.Instructions != "" {
+		session["instructions"] = p.config.Instructions
+	}
+	if p.config.Temperature > 0 {
+		session["temperature"] = p.config.Temperature
+	}
+	if p.config.MaxTokens > 0 {
+		session["max_response_output_tokens"] = p.config.MaxTokens
+	}
+	if p.config.InputAudioTranscription {
+		transcription := map[string]any{"model": "whisper-1"}
+		if p.config.InputLanguage != "" {
+			transcription["language"] = p.config.InputLanguage
+		}
+		session["input_audio_transcription"] = transcription
+	}
+
+	// Server VAD unless explicitly disabled; then the client commits turns itself
+	if p.config.AutoVADDisabled != nil && *p.config.AutoVADDisabled {
+		session["turn_detection"] = nil
+	} else {
+		vad := map[string]any{"type": "server_vad"}
+		if p.config.VADPrefixPaddingMs > 0 {
+			vad["prefix_padding_ms"] = p.config.VADPrefixPaddingMs
+		}
+		if p.config.VADSilenceDurationMs > 0 {
+			vad["silence_duration_ms"] = p.config.VADSilenceDurationMs
+		}
+		session["turn_detection"] = vad
+	}
+
+	if len(p.config.Tools) > 0 {
+		tools := make([]map[string]any, 0, len(p.config.Tools))
+		for _, t := range p.config.Tools {
+			if t.Function == nil {
+				continue
+			}
+			tool := map[string]any{"type": "function", "name": t.Function.Name}
+			if t.Function.Description != "" {
+				tool["description"] = t.Function.Description
+			}
+			if t.Function.Parameters != nil {
+				tool["parameters"] = t.Function.Parameters
+			}
+			tools = append(tools, tool)
+		}
+		session["tools"] = tools
+		session["tool_choice"] = "auto"
+	}
+	return session
+}
+
+func (p *Provider) Disconnect() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn != nil {
+		p.conn.Close(websocket.StatusNormalClosure, "")
+		p.conn = nil
+	}
+	p.connected = false
+	p.pcmBuf.Reset()
+	return nil
+}
+
+func (p *Provider) IsConnected() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.connected
+}
+
+// send writes one client event
+func (p *Provider) send(ctx context.Context, event map[string]any) error {
+	p.mu.RLock()
+	conn := p.conn
+	ok := p.connected
+	p.mu.RUnlock()
+	if !ok || conn == nil {
+		return fmt.Errorf("not connected")
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	return conn.Write(ctx, websocket.MessageText, data)
+}
+
+func (p *Provider) SendAudio(ctx context.Context, audioData []byte) error {
+	if len(audioData) == 0 {
+		return nil
+	}
+	// Marked before sending so a fast server commit is not overwritten
+	p.mu.Lock()
+	p.uncommitted = true
+	p.mu.Unlock()
+	return p.send(ctx, map[string]any{
+		"type":  "input_audio_buffer.append",
+		"audio": base64.StdEncoding.EncodeToString(audioData),
+	})
+}
+
+// EndAudio commits buffered input audio and asks for a response. When server
+// VAD already committed the turn there is nothing left to do.
+func (p *Provider) EndAudio(ctx context.Context) error {
+	p.mu.RLock()
+	pending := p.uncommitted
+	p.mu.RUnlock()
+	if !pending {
+		if !p.IsConnected() {
+			return fmt.Errorf("not connected")
+		}
+		return nil
+	}
+	if err := p.send(ctx, map[string]any{"type": "input_audio_buffer.commit"}); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.uncommitted = false
+	p.mu.Unlock()
+	return p.send(ctx, map[string]any{"type": "response.create"})
+}
+
+func (p *Provider) SendText(ctx context.Context, text string) error {
+	p.mu.Lock()
+	p.pcmBuf.Reset()
+	p.mu.Unlock()
+	if err := p.send(ctx, map[string]any{
+		"type": "conversation.item.create",
+		"item": map[string]any{
+			"type":    "message",
+			"role":    "user",
+			"content": []map[string]any{{"type": "input_text", "text": text}},
+		},
+	}); err != nil {
+		return err
+	}
+	return p.send(ctx, map[string]any{"type": "response.create"})
+}
+
+// SendToolResponse returns a function result and lets the model continue
+func (p *Provider) SendToolResponse(ctx context.Context, resp llm.ToolResponse) error {
+	output, ok := resp.Result.(string)
+	if !ok {
+		data, err := json.Marshal(resp.Result)
+		if err != nil {
+			return fmt.Errorf("encode tool result: %w", err)
+		}
+		output = string(data)
+	}
+	if err := p.send(ctx, map[string]any{
+		"type": "conversation.item.create",
+		"item": map[string]any{
+			"type":    "function_call_output",
+			"call_id": resp.ID,
+			"output":  output,
+		},
+	}); err != nil {
+		return err
+	}
+	return p.send(ctx, map[string]any{"type": "response.create"})
+}
+
+// Callback setters
+func (p *Provider) OnAudio(fn func([]byte))          { p.mu.Lock(); p.onAudioCb = fn; p.mu.Unlock() }
+func (p *Provider) OnText(fn func(string))           { p.mu.Lock(); p.onTextCb = fn; p.mu.Unlock() }
+func (p *Provider) OnToolCall(fn func(llm.ToolCall)) { p.mu.Lock(); p.onToolCallCb = fn; p.mu.Unlock() }
+func (p *Provider) OnTranscription(fn func(llm.TranscriptionResult)) {
+	p.mu.Lock()
+	p.onTranscriptionCb = fn
+	p.mu.Unlock()
+}
+func (p *Provider) OnVAD(fn func(llm.VADSignal)) { p.mu.Lock(); p.onVADCb = fn; p.mu.Unlock() }
+func (p *Provider) OnGoAway(fn func(string))     { p.mu.Lock(); p.onGoAwayCb = fn; p.mu.Unlock() }
+func (p *Provider) OnSessionUpdate(fn func(bool)) {
+	p.mu.Lock()
+	p.onSessionUpdateCb = fn
+	p.mu.Unlock()
+}
+func (p *Provider) OnUsage(fn func(int, int)) { p.mu.Lock(); p.onUsageCb = fn; p.mu.Unlock() }
+func (p *Provider) OnError(fn func(error))    { p.mu.Lock(); p.onErrorCb = fn; p.mu.Unlock() }
+func (p *Provider) OnDisconnect(fn func())    { p.mu.Lock(); p.onDisconnectCb = fn; p.mu.Unlock() }
+
+func (p *Provider) receiveLoop(conn *websocket.Conn) {
+	for {
+		_, data, err := conn.Read(context.Background())
+		if err != nil {
+			p.mu.RLock()
+			closed := p.conn != conn
+			p.mu.RUnlock()
+			if closed {
+				// Disconnect was called; not an error
+				return
+			}
+			p.emitError(fmt.Errorf("receive error: %w", err))
+			p.emitDisconnect()
+			return
+		}
+		p.processEvent(data)
+	}
+}
+
+// serverEvent holds the fields of the server events handled here
+type serverEvent struct {
+	Type       string `json:"type"`
+	Delta      string `json:"delta"`
+	Transcript string `json:"transcript"`
+	CallID     string `json:"call_id"`
+	Name       string `json:"name"`
+	Arguments  string `json:"arguments"`
+	Error      *struct {
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Response *struct {
+		Status string `json:"status"`
+		Usage  *struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	} `json:"response"`
+}
+
+func (p *Provider) processEvent(data []byte) {
+	var ev serverEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		p.emitError(fmt.Errorf("decode server event: %w", err))
+		return
+	}
+
+	switch ev.Type {
+	case "session.created", "session.updated":
+		p.mu.RLock()
+		cb := p.onSessionUpdateCb
+		p.mu.RUnlock()
+		if cb != nil {
+			// Realtime sessions cannot be resumed after the socket closes
+			cb(false)
+		}
+
+	case "input_audio_buffer.speech_started", "input_audio_buffer.speech_stopped":
+		p.mu.RLock()
+		cb := p.onVADCb
+		p.mu.RUnlock()
+		if cb != nil {
+			started := ev.Type == "input_audio_buffer.speech_started"
+			signal := llm.VADSignal{Active: started, Type: "end"}
+			if started {
+				signal.Type = "start"
+			}
+			cb(signal)
+		}
+
+	case "input_audio_buffer.committed":
+		p.mu.Lock()
+		p.uncommitted = false
+		p.mu.Unlock()
+
+	case "conversation.item.input_audio_transcription.completed":
+		p.emitTranscription(ev.Transcript, "input")
+
+	case "response.text.delta", "response.audio_transcript.delta":
+		// The transcript of a spoken reply is its text
+		p.mu.RLock()
+		cb := p.onTextCb
+		p.mu.RUnlock()
+		if cb != nil && ev.Delta != "" {
+			cb(ev.Delta)
+		}
+
+	case "response.audio_transcript.done":
+		if p.config.OutputAudioTranscription {
+			p.emitTranscription(ev.Transcript, "output")
+		}
+
+	case "response.audio.delta":
+		pcm, err := base64.StdEncoding.DecodeString(ev.Delta)
+		if err != nil {
+			p.emitError(fmt.Errorf("decode audio delta: %w", err))
+			return
+		}
+		p.mu.Lock()
+		_, _ = p.pcmBuf.Write(pcm)
+		p.mu.Unlock()
+
+	case "response.function_call_arguments.done":
+		p.mu.RLock()
+		cb := p.onToolCallCb
+		p.mu.RUnlock()
+		if cb != nil && ev.CallID != "" && ev.Name != "" {
+			var args map[string]interface{}
+			_ = json.Unmarshal([]byte(ev.Arguments), &args)
+			cb(llm.ToolCall{
+				ID:   ev.CallID,
+				Type: "function",
+				Function: &llm.ToolFunction{
+					Name:       ev.Name,
+					Arguments:  ev.Arguments,
+					Parameters: args,
+				},
+			})
+		}
+
+	case "response.done":
+		// Response complete - emit full WAV and usage
+		p.mu.Lock()
+		pcm := append([]byte(nil), p.pcmBuf.Bytes()...)
+		p.pcmBuf.Reset()
+		audioCb := p.onAudioCb
+		usageCb := p.onUsageCb
+		p.mu.Unlock()
+		if audioCb != nil && len(pcm) > 0 {
+			audioCb(pcmToWav(pcm, sampleRate, 1, 16))
+		}
+		if ev.Response != nil && ev.Response.Usage != nil && usageCb != nil {
+			usageCb(ev.Response.Usage.InputTokens, ev.Response.Usage.OutputTokens)
+		}
+
+	case "error":
+		if ev.Error != nil {
+			msg := ev.Error.Message
+			if ev.Error.Code != "" {
+				msg = ev.Error.Code + ": " + msg
+			}
+			p.emitError(fmt.Errorf("realtime error: %s", msg))
+		}
+	}
+}
+
+func (p *Provider) emitTranscription(text, kind string) {
+	p.mu.RLock()
+	cb := p.onTranscriptionCb
+	p.mu.RUnlock()
+	if cb != nil && text != "" {
+		cb(llm.TranscriptionResult{Text: text, Type: kind})
+	}
+}
+
+func pcmToWav(pcm []byte, sampleRate, channels, bitsPerSample int) []byte {
+	byteRate := sampleRate * channels * bitsPerSample / 8
+	blockAlign := channels * bitsPerSample / 8
+	dataLen := uint32(len(pcm))
+	riffLen := 36 + dataLen
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("RIFF")
+	_ = binary.Write(buf, binary.LittleEndian, uint32(riffLen))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	_ = binary.Write(buf, binary.LittleEndian, uint32(16))
+	_ = binary.Write(buf, binary.LittleEndian, uint16(1))
+	_ = binary.Write(buf, binary.LittleEndian, uint16(channels))
+	_ = binary.Write(buf, binary.LittleEndian, uint32(sampleRate))
+	_ = binary.Write(buf, binary.LittleEndian, uint32(byteRate))
+	_ = binary.Write(buf, binary.LittleEndian, uint16(blockAlign))
+	_ = binary.Write(buf, binary.LittleEndian, uint16(bitsPerSample))
+	buf.WriteString("data")
+	_ = binary.Write(buf, binary.LittleEndian, dataLen)
+	buf.Write(pcm)
+	return buf.Bytes()
+}
+
+func (p *Provider) emitError(err error) {
+	p.mu.RLock()
+	cb := p.onErrorCb
+	p.mu.RUnlock()
+	if cb != nil {
+		cb(err)
+	}
+}
+
+func (p *Provider) emitDisconnect() {
+	p.mu.Lock()
+	p.connected = false
+	p.conn = nil
+	p.mu.Unlock()
+	p.mu.RLock()
+	cb := p.onDisconnectCb
+	p.mu.RUnlock()
+	if cb != nil {
+		cb()
+	}
+}
@@ -0,0 +1,451 @@
+package realtime
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/gliderlab/cogate/pkg/llm"
+)
+
+// standIn is a local OpenAI Realtime server. Every client event is recorded;
+// respond returns the server events to send back for it.
+type standIn struct {
+	t       *testing.T
+	server  *httptest.Server
+	respond func(event map[string]any) []map[string]any
+	closeOn string // client event type after which the server hangs up
+
+	mu     sync.Mutex
+	events []map[string]any
+	header http.Header
+	query  string
+}
+
+func newStandIn(t *testing.T, respond func(event map[string]any) []map[string]any) *standIn {
+	s := &standIn{t: t, respond: respond}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *standIn) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/realtime" {
+		http.NotFound(w, r)
+		return
+	}
+	s.mu.Lock()
+	s.header = r.Header.Clone()
+	s.query = r.URL.RawQuery
+	s.mu.Unlock()
+
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.CloseNow()
+	ctx := context.Background()
+	_ = conn.Write(ctx, websocket.MessageText, []byte(`{"type":"session.created"}`))
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			return
+		}
+		var event map[string]any
+		if err := json.Unmarshal(data, &event); err != nil {
+			s.t.Errorf("client sent invalid JSON: %s", data)
+			return
+		}
+		s.mu.Lock()
+		s.events = append(s.events, event)
+		s.mu.Unlock()
+		if event["type"] == s.closeOn {
+			return
+		}
+		if s.respond == nil {
+			continue
+		}
+		for _, reply := range s.respond(event) {
+			data, _ := json.Marshal(reply)
+			if err := conn.Write(ctx, websocket.MessageText, data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// eventTypes lists the types of the client events received so far
+func (s *standIn) eventTypes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	types := make([]string, 0, len(s.events))
+	for _, e := range s.events {
+		types = append(types, e["type"].(string))
+	}
+	return types
+}
+
+func (s *standIn) event(i int) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.events[i]
+}
+
+func (s *standIn) connect(t *testing.T, cfg llm.RealtimeConfig) *Provider {
+	t.Helper()
+	cfg.APIKey = "test-key"
+	cfg.BaseURL = s.server.URL + "/v1"
+	p := New(cfg)
+	if err := p.Connect(context.Background(), cfg); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { _ = p.Disconnect() })
+	return p
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestConnectSendsSessionUpdate(t *testing.T) {
+	s := newStandIn(t, nil)
+	disabled := false
+	s.connect(t, llm.RealtimeConfig{
+		Model:                   "gpt-4o-realtime-preview",
+		Instructions:            "Be brief.",
+		InputAudioTranscription: true,
+		AutoVADDisabled:         &disabled,
+		VADSilenceDurationMs:    400,
+		Tools: []llm.Tool{{Type: "function", Function: &llm.ToolFunction{
+			Name:        "get_time",
+			Description: "Current time",
+			Parameters:  map[string]any{"type": "object"},
+		}}},
+	})
+	waitFor(t, "session.update", func() bool { return len(s.eventTypes()) == 1 })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if got := s.header.Get("Authorization"); got != "Bearer test-key" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if got := s.header.Get("OpenAI-Beta"); got != "realtime=v1" {
+		t.Errorf("OpenAI-Beta = %q", got)
+	}
+	if s.query != "model=gpt-4o-realtime-preview" {
+		t.Errorf("query = %q", s.query)
+	}
+
+	ev := s.events[0]
+	if ev["type"] != "session.update" {
+		t.Fatalf("first event = %v", ev["type"])
+	}
+	session := ev["session"].(map[string]any)
+	if session["instructions"] != "Be brief." || session["voice"] != defaultVoice || session["input_audio_format"] != "pcm16" {
+		t.Errorf("session = %v", session)
+	}
+	vad := session["turn_detection"].(map[string]any)
+	if vad["type"] != "server_vad" || vad["silence_duration_ms"] != float64(400) {
+		t.Errorf("turn_detection = %v", vad)
+	}
+	if tr := session["input_audio_transcription"].(map[string]any); tr["model"] != "whisper-1" {
+		t.Errorf("input_audio_transcription = %v", tr)
+	}
+	tools := session["tools"].([]any)
+	if len(tools) != 1 || tools[0].(map[string]any)["name"] != "get_time" || tools[0].(map[string]any)["type"] != "function" {
+		t.Errorf("tools = %v", tools)
+	}
+}
+
+func TestSendTextStreamsTextAudioAndUsage(t *testing.T) {
+	pcm := []byte{1, 2, 3, 4, 5, 6}
+	s := newStandIn(t, func(ev map[string]any) []map[string]any {
+		if ev["type"] != "response.create" {
+			return nil
+		}
+		return []map[string]any{
+			{"type": "response.audio_transcript.delta", "delta": "Hel"},
+			{"type": "response.audio.delta", "delta": base64.StdEncoding.EncodeToString(pcm[:4])},
+			{"type": "response.audio_transcript.delta", "delta": "lo"},
+			{"type": "response.audio.delta", "delta": base64.StdEncoding.EncodeToString(pcm[4:])},
+			{"type": "response.audio_transcript.done", "transcript": "Hello"},
+			{"type": "response.done", "response": map[string]any{
+				"status": "completed",
+				"usage":  map[string]any{"input_tokens": 12, "output_tokens": 7},
+			}},
+		}
+	})
+	p := s.connect(t, llm.RealtimeConfig{OutputAudioTranscription: true})
+
+	var (
+		mu         sync.Mutex
+		text       strings.Builder
+		wav        []byte
+		transcript llm.TranscriptionResult
+		usage      [2]int
+	)
+	p.OnText(func(t string) { mu.Lock(); text.WriteString(t); mu.Unlock() })
+	p.OnAudio(func(a []byte) { mu.Lock(); wav = a; mu.Unlock() })
+	p.OnTranscription(func(r llm.TranscriptionResult) { mu.Lock(); transcript = r; mu.Unlock() })
+	p.OnUsage(func(in, out int) { mu.Lock(); usage = [2]int{in, out}; mu.Unlock() })
+
+	if err := p.SendText(context.Background(), "Hi"); err != nil {
+		t.Fatalf("SendText: %v", err)
+	}
+	waitFor(t, "response.done", func() bool { mu.Lock(); defer mu.Unlock(); return wav != nil })
+
+	mu.Lock()
+	defer mu.Unlock()
+	if text.String() != "Hello" {
+		t.Errorf("text = %q", text.String())
+	}
+	if len(wav) != 44+len(pcm) || string(wav[:4]) != "RIFF" || string(wav[44:]) != string(pcm) {
+		t.Errorf("wav = %v", wav)
+	}
+	if transcript != (llm.TranscriptionResult{Text: "Hello", Type: "output"}) {
+		t.Errorf("transcript = %+v", transcript)
+	}
+	if usage != [2]int{12, 7} {
+		t.Errorf("usage = %v", usage)
+	}
+
+	types := s.eventTypes()
+	if strings.Join(types, ",") != "session.update,conversation.item.create,response.create" {
+		t.Errorf("client events = %v", types)
+	}
+	item := s.event(1)["item"].(map[string]any)
+	content := item["content"].([]any)[0].(map[string]any)
+	if item["role"] != "user" || content["type"] != "input_text" || content["text"] != "Hi" {
+		t.Errorf("item = %v", item)
+	}
+}
+
+func TestServerVADAndInputTranscription(t *testing.T) {
+	s := newStandIn(t, func(ev map[string]any) []map[string]any {
+		if ev["type"] != "input_audio_buffer.append" {
+			return nil
+		}
+		return []map[string]any{
+			{"type": "input_audio_buffer.speech_started", "audio_start_ms": 0},
+			{"type": "input_audio_buffer.speech_stopped", "audio_end_ms": 900},
+			{"type": "input_audio_buffer.committed", "item_id": "item_1"},
+			{"type": "conversation.item.input_audio_transcription.completed", "item_id": "item_1", "transcript": "what time is it"},
+		}
+	})
+	p := s.connect(t, llm.RealtimeConfig{InputAudioTranscription: true})
+
+	var (
+		mu      sync.Mutex
+		signals []llm.VADSignal
+		input   llm.TranscriptionResult
+	)
+	p.OnVAD(func(v llm.VADSignal) { mu.Lock(); signals = append(signals, v); mu.Unlock() })
+	p.OnTranscription(func(r llm.TranscriptionResult) { mu.Lock(); input = r; mu.Unlock() })
+
+	if err := p.SendAudio(context.Background(), []byte{0, 1, 0, 1}); err != nil {
+		t.Fatalf("SendAudio: %v", err)
+	}
+	waitFor(t, "transcription", func() bool { mu.Lock(); defer mu.Unlock(); return input.Text != "" })
+
+	mu.Lock()
+	if len(signals) != 2 || signals[0] != (llm.VADSignal{Active: true, Type: "start"}) || signals[1] != (llm.VADSignal{Active: false, Type: "end"}) {
+		t.Errorf("VAD signals = %+v", signals)
+	}
+	if input != (llm.TranscriptionResult{Text: "what time is it", Type: "input"}) {
+		t.Errorf("transcription = %+v", input)
+	}
+	mu.Unlock()
+
+	// Server VAD committed the turn, so EndAudio has nothing to send
+	if err := p.EndAudio(context.Background()); err != nil {
+		t.Fatalf("EndAudio: %v", err)
+	}
+	if types := s.eventTypes(); strings.Join(types, ",") != "session.update,input_audio_buffer.append" {
+		t.Errorf("client events = %v", types)
+	}
+	if audio := s.event(1)["audio"]; audio != base64.StdEncoding.EncodeToString([]byte{0, 1, 0, 1}) {
+		t.Errorf("appended audio = %v", audio)
+	}
+}
+
+func TestEndAudioCommitsWithoutServerVAD(t *testing.T) {
+	s := newStandIn(t, nil)
+	disabled := true
+	p := s.connect(t, llm.RealtimeConfig{AutoVADDisabled: &disabled})
+	waitFor(t, "session.update", func() bool { return len(s.eventTypes()) == 1 })
+	if session := s.event(0)["session"].(map[string]any); session["turn_detection"] != nil {
+		t.Errorf("turn_detection = %v, want null", session["turn_detection"])
+	}
+
+	if err := p.SendAudio(context.Background(), []byte{0, 1}); err != nil {
+		t.Fatalf("SendAudio: %v", err)
+	}
+	if err := p.EndAudio(context.Background()); err != nil {
+		t.Fatalf("EndAudio: %v", err)
+	}
+	want := "session.update,input_audio_buffer.append,input_audio_buffer.commit,response.create"
+	waitFor(t, "commit", func() bool { return strings.Join(s.eventTypes(), ",") == want })
+}
+
+func TestToolCallRoundTrip(t *testing.T) {
+	var s *standIn
+	s = newStandIn(t, func(ev map[string]any) []map[string]any {
+		if ev["type"] != "response.create" {
+			return nil
+		}
+		// First response calls the tool; the one after its output answers
+		if s.countItems("function_call_output") == 0 {
+			return []map[string]any{
+				{"type": "response.function_call_arguments.done", "call_id": "call_1", "name": "get_time", "arguments": `{"zone":"UTC"}`},
+				{"type": "response.done", "response": map[string]any{"status": "completed"}},
+			}
+		}
+		return []map[string]any{
+			{"type": "response.text.delta", "delta": "It is noon."},
+			{"type": "response.done", "response": map[string]any{"status": "completed"}},
+		}
+	})
+	p := s.connect(t, llm.RealtimeConfig{})
+
+	var (
+		mu    sync.Mutex
+		calls []llm.ToolCall
+		text  strings.Builder
+	)
+	p.OnToolCall(func(tc llm.ToolCall) {
+		mu.Lock()
+		calls = append(calls, tc)
+		mu.Unlock()
+		if err := p.SendToolResponse(context.Background(), llm.ToolResponse{ID: tc.ID, Name: tc.Function.Name, Result: map[string]any{"time": "12:00"}}); err != nil {
+			t.Errorf("SendToolResponse: %v", err)
+		}
+	})
+	p.OnText(func(t string) { mu.Lock(); text.WriteString(t); mu.Unlock() })
+
+	if err := p.SendText(context.Background(), "What time is it?"); err != nil {
+		t.Fatalf("SendText: %v", err)
+	}
+	waitFor(t, "answer", func() bool { mu.Lock(); defer mu.Unlock(); return text.Len() > 0 })
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != 1 {
+		t.Fatalf("tool calls = %+v", calls)
+	}
+	tc := calls[0]
+	if tc.ID != "call_1" || tc.Function.Name != "get_time" || tc.Function.Arguments != `{"zone":"UTC"}` {
+		t.Errorf("tool call = %+v / %+v", tc, tc.Function)
+	}
+	if args, _ := tc.Function.Parameters.(map[string]interface{}); args["zone"] != "UTC" {
+		t.Errorf("parameters = %v", tc.Function.Parameters)
+	}
+	if text.String() != "It is noon." {
+		t.Errorf("text = %q", text.String())
+	}
+
+	var output map[string]any
+	for _, e := range s.snapshot() {
+		if item, ok := e["item"].(map[string]any); ok && item["type"] == "function_call_output" {
+			output = item
+		}
+	}
+	if output["call_id"] != "call_1" || output["output"] != `{"time":"12:00"}` {
+		t.Errorf("function_call_output = %v", output)
+	}
+}
+
+func TestServerErrorEvent(t *testing.T) {
+	s := newStandIn(t, func(ev map[string]any) []map[string]any {
+		if ev["type"] != "response.create" {
+			return nil
+		}
+		return []map[string]any{{"type": "error", "error": map[string]any{
+			"type": "invalid_request_error", "code": "invalid_value", "message": "bad voice",
+		}}}
+	})
+	p := s.connect(t, llm.RealtimeConfig{})
+
+	errs := make(chan error, 1)
+	p.OnError(func(err error) { errs <- err })
+	if err := p.SendText(context.Background(), "hi"); err != nil {
+		t.Fatalf("SendText: %v", err)
+	}
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "invalid_value: bad voice") {
+			t.Errorf("error = %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no error reported")
+	}
+}
+
+func TestDisconnectByServer(t *testing.T) {
+	s := newStandIn(t, nil)
+	s.closeOn = "input_audio_buffer.append"
+	p := s.connect(t, llm.RealtimeConfig{})
+
+	disconnected := make(chan struct{})
+	p.OnDisconnect(func() { close(disconnected) })
+	if err := p.SendAudio(context.Background(), []byte{0, 1}); err != nil {
+		t.Fatalf("SendAudio: %v", err)
+	}
+	select {
+	case <-disconnected:
+	case <-time.After(3 * time.Second):
+		t.Fatal("OnDisconnect not called")
+	}
+	if p.IsConnected() {
+		t.Error("still connected after server closed the socket")
+	}
+	if err := p.SendText(context.Background(), "hi"); err == nil {
+		t.Error("SendText after disconnect succeeded")
+	}
+}
+
+func TestRealtimeURL(t *testing.T) {
+	cases := map[string]string{
+		"":                                "wss://api.openai.com/v1/realtime?model=m",
+		"https://api.openai.com/v1/":      "wss://api.openai.com/v1/realtime?model=m",
+		"http://localhost:8080/v1":        "ws://localhost:8080/v1/realtime?model=m",
+		"wss://proxy.example/v1/realtime": "wss://proxy.example/v1/realtime?model=m",
+		"api.example.com/openai/v1":       "wss://api.example.com/openai/v1/realtime?model=m",
+	}
+	for base, want := range cases {
+		got, err := realtimeURL(base, "m")
+		if err != nil || got != want {
+			t.Errorf("realtimeURL(%q) = %q, %v; want %q", base, got, err, want)
+		}
+	}
+}
+
+func (s *standIn) snapshot() []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]map[string]any(nil), s.events...)
+}
+
+// countItems counts conversation items of the given type sent by the client
+func (s *standIn) countItems(itemType string) int {
+	n := 0
+	for _, e := range s.snapshot() {
+		if item, ok := e["item"].(map[string]any); ok && item["type"] == itemType {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// ReadSSE reads an OpenAI-compatible server-sent event stream and calls fn for
+// every decoded chunk. It returns when the stream sends [DONE], ends, or fails.
+func ReadSSE(body io.Reader, fn func(*StreamChunk)) error {
+	reader := bufio.NewReader(body)
+	for {
+		line, err := reader.ReadString('\n')
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "data:") {
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if data == "[DONE]" {
+				return nil
+			}
+			var chunk StreamChunk
+			if jsonErr := json.Unmarshal([]byte(data), &chunk); jsonErr == nil {
+				fn(&chunk)
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// CheckResponse reads the body of a failed response and returns it as an *APIError.
+// It returns nil for 2xx responses.
+func CheckResponse(statusCode int, body io.Reader) error {
+	if statusCode >= 200 && statusCode < 300 {
+		return nil
+	}
+	data, _ := io.ReadAll(body)
+	return &APIError{StatusCode: statusCode, Body: string(data)}
+}
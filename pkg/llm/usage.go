@@ -0,0 +1,125 @@
+// usage.go - Token usage decoding and model price table for cost accounting
+package llm
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// UnmarshalJSON decodes usage in the OpenAI format, reading cached prompt
+// tokens from either cached_tokens or prompt_tokens_details.cached_tokens.
+func (u *Usage) UnmarshalJSON(data []byte) error {
+	type plain Usage
+	var wire struct {
+		plain
+		PromptTokensDetails *struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"prompt_tokens_details"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	*u = Usage(wire.plain)
+	if u.CachedTokens == 0 && wire.PromptTokensDetails != nil {
+		u.CachedTokens = wire.PromptTokensDetails.CachedTokens
+	}
+	if u.TotalTokens == 0 {
+		u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	}
+	return nil
+}
+
+// IsZero reports whether no tokens were reported
+func (u Usage) IsZero() bool {
+	return u.PromptTokens == 0 && u.CompletionTokens == 0 && u.TotalTokens == 0
+}
+
+// ModelPrice is a model's price in USD per million tokens
+type ModelPrice struct {
+	Input       float64 `json:"input"`
+	Output      float64 `json:"output"`
+	CachedInput float64 `json:"cachedInput,omitempty"` // Cached prompt tokens (default: Input)
+}
+
+// PriceTable maps a model name, or "provider/model", to its price. Lookups
+// fall back to the longest key that prefixes the model, so "gpt-4o" also
+// prices "gpt-4o-2024-08-06".
+type PriceTable map[string]ModelPrice
+
+// DefaultPriceTable returns list prices for common hosted models. Local
+// models (Ollama) are free and have no entry.
+func DefaultPriceTable() PriceTable {
+	return PriceTable{
+		"gpt-4o":            {Input: 2.50, Output: 10, CachedInput: 1.25},
+		"gpt-4o-mini":       {Input: 0.15, Output: 0.60, CachedInput: 0.075},
+		"gpt-4.1":           {Input: 2, Output: 8, CachedInput: 0.50},
+		"gpt-4.1-mini":      {Input: 0.40, Output: 1.60, CachedInput: 0.10},
+		"gpt-4.1-nano":      {Input: 0.10, Output: 0.40, CachedInput: 0.025},
+		"o3":                {Input: 2, Output: 8, CachedInput: 0.50},
+		"o4-mini":           {Input: 1.10, Output: 4.40, CachedInput: 0.275},
+		"claude-opus-4":     {Input: 15, Output: 75, CachedInput: 1.50},
+		"claude-sonnet-4":   {Input: 3, Output: 15, CachedInput: 0.30},
+		"claude-3-7-sonnet": {Input: 3, Output: 15, CachedInput: 0.30},
+		"claude-3-5-sonnet": {Input: 3, Output: 15, CachedInput: 0.30},
+		"claude-3-5-haiku":  {Input: 0.80, Output: 4, CachedInput: 0.08},
+		"gemini-2.5-pro":    {Input: 1.25, Output: 10, CachedInput: 0.31},
+		"gemini-2.5-flash":  {Input: 0.30, Output: 2.50, CachedInput: 0.075},
+		"gemini-2.0-flash":  {Input: 0.10, Output: 0.40, CachedInput: 0.025},
+	}
+}
+
+// Merge returns a copy of t with the entries of other added or replaced
+func (t PriceTable) Merge(other map[string]ModelPrice) PriceTable {
+	out := make(PriceTable, len(t)+len(other))
+	for k, v := range t {
+		out[k] = v
+	}
+	for k, v := range other {
+		out[k] = v
+	}
+	return out
+}
+
+// Lookup returns the price of a model: "provider/model" first, then the
+// model name, then the longest matching prefix of either.
+func (t PriceTable) Lookup(provider ProviderType, model string) (ModelPrice, bool) {
+	if model == "" {
+		return ModelPrice{}, false
+	}
+	qualified := string(provider) + "/" + model
+	if p, ok := t[qualified]; ok {
+		return p, true
+	}
+	if p, ok := t[model]; ok {
+		return p, true
+	}
+	best := ""
+	for k := range t {
+		if len(k) > len(best) && (strings.HasPrefix(model, k) || strings.HasPrefix(qualified, k)) {
+			best = k
+		}
+	}
+	if best == "" {
+		return ModelPrice{}, false
+	}
+	return t[best], true
+}
+
+// Cost returns the USD cost of u, or false when the model has no price.
+// Cached tokens are part of PromptTokens and billed at CachedInput.
+func (t PriceTable) Cost(provider ProviderType, model string, u Usage) (float64, bool) {
+	p, ok := t.Lookup(provider, model)
+	if !ok {
+		return 0, false
+	}
+	cachedPrice := p.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = p.Input
+	}
+	cached := u.CachedTokens
+	if cached > u.PromptTokens {
+		cached = u.PromptTokens
+	}
+	uncached := u.PromptTokens - cached
+	return (float64(uncached)*p.Input + float64(cached)*cachedPrice + float64(u.CompletionTokens)*p.Output) / 1e6, true
+}
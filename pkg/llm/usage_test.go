@@ -0,0 +1,45 @@
+package llm
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestUsageUnmarshalCachedTokens(t *testing.T) {
+	var u Usage
+	data := `{"prompt_tokens":100,"completion_tokens":20,"prompt_tokens_details":{"cached_tokens":80}}`
+	if err := json.Unmarshal([]byte(data), &u); err != nil {
+		t.Fatal(err)
+	}
+	if u.CachedTokens != 80 || u.TotalTokens != 120 {
+		t.Fatalf("usage = %+v", u)
+	}
+}
+
+func TestPriceTableCost(t *testing.T) {
+	prices := DefaultPriceTable().Merge(map[string]ModelPrice{
+		"ollama/llama3": {Input: 0, Output: 0},
+		"openai/gpt-4o": {Input: 5, Output: 15, CachedInput: 1},
+	})
+
+	// Dated model names fall back to their prefix
+	cost, ok := prices.Cost(ProviderOpenRouter, "gpt-4o-mini-2024-07-18", Usage{PromptTokens: 1_000_000, CompletionTokens: 1_000_000})
+	if !ok || math.Abs(cost-0.75) > 1e-9 {
+		t.Fatalf("gpt-4o-mini cost = %v, %v", cost, ok)
+	}
+
+	// Provider-qualified entries win over the bare model, cached tokens use CachedInput
+	cost, ok = prices.Cost(ProviderOpenAI, "gpt-4o", Usage{PromptTokens: 1_000_000, CachedTokens: 500_000})
+	if !ok || math.Abs(cost-3) > 1e-9 {
+		t.Fatalf("openai/gpt-4o cost = %v, %v", cost, ok)
+	}
+	cost, _ = prices.Cost(ProviderAnthropic, "claude-sonnet-4-20250514", Usage{PromptTokens: 1_000_000, CachedTokens: 1_000_000})
+	if math.Abs(cost-0.30) > 1e-9 {
+		t.Fatalf("cached sonnet cost = %v", cost)
+	}
+
+	if _, ok := prices.Cost(ProviderOllama, "unknown-model", Usage{PromptTokens: 10}); ok {
+		t.Error("expected no price for unknown model")
+	}
+}
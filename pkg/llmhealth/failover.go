@@ -0,0 +1,329 @@
+// failover.go - Request-time provider failover chain with per-provider circuit breakers
+package llmhealth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gliderlab/cogate/pkg/llm"
+)
+
+// BreakerState is the state of a provider circuit breaker
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // Requests flow normally
+	BreakerOpen                         // Provider is skipped until the cooldown elapses
+	BreakerHalfOpen                     // One probe request is allowed through
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// CircuitBreaker opens after consecutive failures and lets a single probe
+// request through once the cooldown has elapsed.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	state     BreakerState
+	openedAt  time.Time
+	now       func() time.Time
+}
+
+// NewCircuitBreaker creates a breaker that opens after threshold consecutive failures
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = 3
+	}
+	if cooldown <= 0 {
+		cooldown = time.Minute
+	}
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// Allow reports whether a request may be sent to the provider
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		return true
+	case BreakerHalfOpen:
+		// A probe is already in flight
+		return false
+	}
+	return true
+}
+
+// Success records a successful request and closes the breaker
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.state = BreakerClosed
+}
+
+// Failure records a failed request, opening the breaker at the threshold
+// or immediately when a half-open probe fails
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// Cancel releases a half-open probe the caller abandoned without counting a
+// failure; the breaker reopens and the next probe waits another cooldown
+func (b *CircuitBreaker) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerHalfOpen {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// State returns the current breaker state
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// ChainEntry is one provider in a failover chain
+type ChainEntry struct {
+	Name     string       // Display name (config group name or provider type)
+	Provider llm.Provider // Provider instance
+	Model    string       // Model to request; empty keeps the request's model
+}
+
+// FailoverChain is an llm.Provider that tries each entry in order, moving on
+// when a provider fails with a retryable error (timeouts, 429s, 5xx,
+// connection errors) or its circuit breaker is open. Both kinds of move are
+// reported to OnFailover.
+type FailoverChain struct {
+	entries    []ChainEntry
+	breakers   []*CircuitBreaker
+	onFailover func(FailoverEvent)
+}
+
+// NewFailoverChain creates a chain; the first entry is the primary provider.
+// Breakers use cfg.FailureThreshold and cfg.BreakerCooldown.
+func NewFailoverChain(cfg *Config, entries ...ChainEntry) *FailoverChain {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	c := &FailoverChain{entries: entries}
+	for i := range entries {
+		if c.entries[i].Name == "" {
+			c.entries[i].Name = string(entries[i].Provider.Type())
+		}
+		c.breakers = append(c.breakers, NewCircuitBreaker(cfg.FailureThreshold, cfg.BreakerCooldown))
+	}
+	return c
+}
+
+// OnFailover registers a callback invoked each time a request moves to the next provider
+func (c *FailoverChain) OnFailover(fn func(FailoverEvent)) *FailoverChain {
+	c.onFailover = fn
+	return c
+}
+
+// Breaker returns the circuit breaker of the named entry, or nil
+func (c *FailoverChain) Breaker(name string) *CircuitBreaker {
+	for i, e := range c.entries {
+		if e.Name == name {
+			return c.breakers[i]
+		}
+	}
+	return nil
+}
+
+// IsFailoverError reports whether err should move a request to the next
+// provider: 429, 408 and 5xx responses, timeouts and transport errors
+// (connection refused/reset, EOF). Cancellation, a bare context deadline and
+// local errors such as a request that could not be built or encoded do not.
+func IsFailoverError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var apiErr *llm.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == 429 || apiErr.StatusCode == 408 || apiErr.StatusCode >= 500
+	}
+	// HTTP client errors, including Client.Timeout, come wrapped in *url.Error
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// Chat implements llm.Provider.Chat
+func (c *FailoverChain) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	var resp *llm.ChatResponse
+	err := c.run(ctx, req, func(p llm.Provider, r *llm.ChatRequest) (bool, error) {
+		var err error
+		resp, err = p.Chat(ctx, r)
+		return false, err
+	})
+	return resp, err
+}
+
+// ChatStream implements llm.Provider.ChatStream. A stream that already
+// produced output is not retried on another provider.
+func (c *FailoverChain) ChatStream(ctx context.Context, req *llm.ChatRequest, fn func(*llm.StreamChunk)) error {
+	return c.run(ctx, req, func(p llm.Provider, r *llm.ChatRequest) (bool, error) {
+		emitted := false
+		err := p.ChatStream(ctx, r, func(chunk *llm.StreamChunk) {
+			emitted = true
+			fn(chunk)
+		})
+		return emitted, err
+	})
+}
+
+// run calls do on each available provider until one succeeds. do reports
+// whether partial output was delivered, which stops further failover.
+func (c *FailoverChain) run(ctx context.Context, req *llm.ChatRequest, do func(llm.Provider, *llm.ChatRequest) (bool, error)) error {
+	var lastErr error
+	from := -1
+	for i, e := range c.entries {
+		if !c.breakers[i].Allow() {
+			// A skipped primary is a failover too; after a failure the
+			// event names the provider that failed
+			if from < 0 {
+				lastErr = fmt.Errorf("circuit open for %s", e.Name)
+				from = i
+			}
+			continue
+		}
+		if from >= 0 {
+			c.emit(from, i, lastErr)
+		}
+
+		r := req
+		if i > 0 && e.Model != "" {
+			cp := *req
+			cp.Model = e.Model
+			r = &cp
+		}
+
+		partial, err := do(e.Provider, r)
+		if err == nil {
+			c.breakers[i].Success()
+			return nil
+		}
+		if ctx.Err() != nil {
+			// The caller gave up; the provider is not to blame
+			c.breakers[i].Cancel()
+			return err
+		}
+		if !IsFailoverError(err) {
+			// The provider answered; the request itself is bad
+			c.breakers[i].Success()
+			return err
+		}
+		c.breakers[i].Failure()
+		log.Printf("[LLMHealth] %s failed: %v", e.Name, err)
+		if partial {
+			return err
+		}
+		lastErr = err
+		from = i
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no providers configured")
+	}
+	return lastErr
+}
+
+func (c *FailoverChain) emit(from, to int, cause error) {
+	event := FailoverEvent{
+		Time:         time.Now(),
+		FromProvider: llm.ProviderType(c.entries[from].Name),
+		ToProvider:   llm.ProviderType(c.entries[to].Name),
+		Reason:       cause.Error(),
+	}
+	log.Printf("[LLMHealth] FAILOVER: %s -> %s (%s)", event.FromProvider, event.ToProvider, event.Reason)
+	if c.onFailover != nil {
+		c.onFailover(event)
+	}
+}
+
+func (c *FailoverChain) primary() llm.Provider {
+	return c.entries[0].Provider
+}
+
+// Name returns the provider name
+func (c *FailoverChain) Name() string { return c.primary().Name() }
+
+// Type returns the primary provider type
+func (c *FailoverChain) Type() llm.ProviderType { return c.primary().Type() }
+
+// GetConfig returns the primary provider config
+func (c *FailoverChain) GetConfig() llm.Config { return c.primary().GetConfig() }
+
+// Capabilities returns the primary provider capabilities
+func (c *FailoverChain) Capabilities() []llm.Capability { return c.primary().Capabilities() }
+
+// Embeddings implements llm.Provider.Embeddings (primary only)
+func (c *FailoverChain) Embeddings(ctx context.Context, req *llm.EmbedRequest) (*llm.EmbedResponse, error) {
+	return c.primary().Embeddings(ctx, req)
+}
+
+// Vision implements llm.Provider.Vision (primary only)
+func (c *FailoverChain) Vision(ctx context.Context, req *llm.VisionRequest) (*llm.VisionResponse, error) {
+	return c.primary().Vision(ctx, req)
+}
+
+// TTS implements llm.Provider.TTS (primary only)
+func (c *FailoverChain) TTS(ctx context.Context, req *llm.TTSRequest) (*llm.TTSResponse, error) {
+	return c.primary().TTS(ctx, req)
+}
+
+// Transcription implements llm.Provider.Transcription (primary only)
+func (c *FailoverChain) Transcription(ctx context.Context, req *llm.TranscriptionRequest) (*llm.TranscriptionResponse, error) {
+	return c.primary().Transcription(ctx, req)
+}
+
+// Realtime implements llm.Provider.Realtime (primary only)
+func (c *FailoverChain) Realtime(ctx context.Context, cfg llm.RealtimeConfig) (llm.RealtimeProvider, error) {
+	return c.primary().Realtime(ctx, cfg)
+}
+
+// Ensure FailoverChain implements llm.Provider
+var _ llm.Provider = (*FailoverChain)(nil)
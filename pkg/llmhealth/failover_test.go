@@ -0,0 +1,205 @@
+package llmhealth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gliderlab/cogate/pkg/llm"
+	"github.com/gliderlab/cogate/pkg/llm/providers/openai"
+)
+
+// stubServer answers chat completions with the given status and counts calls
+func stubServer(t *testing.T, status int, reply string, calls *int32) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%q}}]}`, reply)
+			return
+		}
+		fmt.Fprint(w, `{"error":"nope"}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newEntry(name, url string) ChainEntry {
+	return ChainEntry{Name: name, Provider: openai.New(llm.Config{APIKey: "k", BaseURL: url, Timeout: 5})}
+}
+
+func TestFailoverChainFallsBackOn429(t *testing.T) {
+	var primaryCalls, backupCalls int32
+	primary := stubServer(t, http.StatusTooManyRequests, "", &primaryCalls)
+	backup := stubServer(t, http.StatusOK, "from backup", &backupCalls)
+
+	var events []FailoverEvent
+	cfg := DefaultConfig()
+	cfg.FailureThreshold = 2
+	chain := NewFailoverChain(cfg, newEntry("main", primary.URL), newEntry("backup", backup.URL)).
+		OnFailover(func(e FailoverEvent) { events = append(events, e) })
+
+	req := &llm.ChatRequest{Model: "m", Messages: []llm.Message{{Role: "user", Content: "hi"}}}
+	for i := 0; i < 3; i++ {
+		resp, err := chain.Chat(context.Background(), req)
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		if resp.Choices[0].Message.Content != "from backup" {
+			t.Errorf("call %d: unexpected reply %q", i, resp.Choices[0].Message.Content)
+		}
+	}
+
+	// Two failures open the primary's breaker, so the third call skips it
+	if primaryCalls < 2 || primaryCalls > 2*3 {
+		t.Errorf("unexpected primary calls: %d", primaryCalls)
+	}
+	if got := chain.Breaker("main").State(); got != BreakerOpen {
+		t.Errorf("expected open breaker, got %s", got)
+	}
+	// The skip is recorded as a failover as well
+	if len(events) != 3 || events[0].FromProvider != "main" || events[0].ToProvider != "backup" ||
+		events[2].FromProvider != "main" || !strings.Contains(events[2].Reason, "circuit open") {
+		t.Errorf("unexpected failover events: %+v", events)
+	}
+}
+
+func TestIsFailoverError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, connErr := http.Get("http://127.0.0.1:1")
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{&llm.APIError{StatusCode: 503}, true},
+		{&llm.APIError{StatusCode: 400}, false},
+		{connErr, true},
+		{io.ErrUnexpectedEOF, true},
+		{ctx.Err(), false},
+		{fmt.Errorf("call: %w", context.DeadlineExceeded), false},
+		{fmt.Errorf("marshal request: json: unsupported value"), false},
+	} {
+		if got := IsFailoverError(tc.err); got != tc.want {
+			t.Errorf("IsFailoverError(%v) = %v", tc.err, got)
+		}
+	}
+}
+
+func TestFailoverChainStopsOnCancel(t *testing.T) {
+	var primaryCalls, backupCalls int32
+	primary := stubServer(t, http.StatusOK, "late", &primaryCalls)
+	backup := stubServer(t, http.StatusOK, "from backup", &backupCalls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	chain := NewFailoverChain(nil, newEntry("main", primary.URL), newEntry("backup", backup.URL))
+	if _, err := chain.Chat(ctx, &llm.ChatRequest{Model: "m", Messages: []llm.Message{{Role: "user", Content: "hi"}}}); err == nil {
+		t.Fatal("expected error")
+	}
+	if backupCalls != 0 || chain.Breaker("main").State() != BreakerClosed {
+		t.Errorf("cancellation failed over (backup calls %d, breaker %s)", backupCalls, chain.Breaker("main").State())
+	}
+}
+
+func TestFailoverChainDoesNotFailOverOnClientError(t *testing.T) {
+	var primaryCalls, backupCalls int32
+	primary := stubServer(t, http.StatusBadRequest, "", &primaryCalls)
+	backup := stubServer(t, http.StatusOK, "from backup", &backupCalls)
+
+	chain := NewFailoverChain(nil, newEntry("main", primary.URL), newEntry("backup", backup.URL))
+	_, err := chain.Chat(context.Background(), &llm.ChatRequest{Model: "m", Messages: []llm.Message{{Role: "user", Content: "hi"}}})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if backupCalls != 0 {
+		t.Errorf("400 should not fail over, backup called %d times", backupCalls)
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	now := time.Now()
+	b := NewCircuitBreaker(1, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.Failure()
+	if b.Allow() {
+		t.Fatal("open breaker should reject requests")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if !b.Allow() {
+		t.Fatal("breaker should allow a probe after the cooldown")
+	}
+	if b.Allow() {
+		t.Fatal("only one probe should be allowed while half-open")
+	}
+	b.Success()
+	if b.State() != BreakerClosed || !b.Allow() {
+		t.Error("successful probe should close the breaker")
+	}
+}
+
+func TestFailoverChainReleasesCancelledProbe(t *testing.T) {
+	var primaryCalls, backupCalls int32
+	primary := stubServer(t, http.StatusOK, "from main", &primaryCalls)
+	backup := stubServer(t, http.StatusOK, "from backup", &backupCalls)
+
+	chain := NewFailoverChain(nil, newEntry("main", primary.URL), newEntry("backup", backup.URL))
+	now := time.Now()
+	b := chain.Breaker("main")
+	b.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		b.Failure()
+	}
+
+	// The probe's caller gives up, so the breaker reopens rather than
+	// waiting on a probe that never reports
+	now = now.Add(2 * time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := &llm.ChatRequest{Model: "m", Messages: []llm.Message{{Role: "user", Content: "hi"}}}
+	if _, err := chain.Chat(ctx, req); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := b.State(); got != BreakerOpen {
+		t.Fatalf("cancelled probe left breaker %s", got)
+	}
+
+	now = now.Add(2 * time.Minute)
+	resp, err := chain.Chat(context.Background(), req)
+	if err != nil || resp.Choices[0].Message.Content != "from main" {
+		t.Fatalf("primary not retried after the cooldown: %+v, %v", resp, err)
+	}
+	if b.State() != BreakerClosed {
+		t.Errorf("successful probe left breaker %s", b.State())
+	}
+}
+
+func TestManagerRecordsFailoverEvents(t *testing.T) {
+	m := NewManager(DefaultConfig())
+	var recorded int
+	m.SetRecorder(func(FailoverEvent) { recorded++ })
+
+	for i := 0; i < 15; i++ {
+		m.RecordFailover(FailoverEvent{Time: time.Now(), FromProvider: "a", ToProvider: "b"})
+	}
+	if err := m.ManualFailover(llm.ProviderOllama); err != nil {
+		t.Fatal(err)
+	}
+
+	events := m.GetFailoverEvents()
+	if len(events) != 10 || !events[len(events)-1].Manual {
+		t.Errorf("expected last 10 events ending with the manual one, got %+v", events)
+	}
+	if recorded != 16 {
+		t.Errorf("expected 16 recorded events, got %d", recorded)
+	}
+}
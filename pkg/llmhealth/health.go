@@ -21,6 +21,7 @@ type Config struct {
 	SuccessThreshold int        // Successes before recover (default 2)
 	TestPrompt     string        // Test prompt (default "hello")
 	Timeout        time.Duration // Request timeout (default 30s)
+	BreakerCooldown time.Duration // How long an open circuit skips a provider (default 1m)
 }
 
 // DefaultConfig returns default configuration
@@ -32,6 +33,7 @@ func DefaultConfig() *Config {
 		SuccessThreshold: 2,
 		TestPrompt:     "hello",
 		Timeout:        30 * time.Second,
+		BreakerCooldown: time.Minute,
 	}
 }
 
@@ -64,8 +66,14 @@ type Manager struct {
 	running      bool
 	stopCh       chan struct{}
 	currentPrimary llm.ProviderType // Current primary provider
+	events       []FailoverEvent    // Recent failover events (newest last)
+	recorder     func(FailoverEvent) // Optional persistence hook
+	providers    []llm.ProviderType  // Providers to check (nil = built-in list)
 }
 
+// maxFailoverEvents bounds the in-memory event history
+const maxFailoverEvents = 100
+
 // NewManager creates a new health check manager
 func NewManager(cfg *Config) *Manager {
 	return &Manager{
@@ -84,6 +92,29 @@ func (m *Manager) SetPrimary(p llm.ProviderType) {
 	m.currentPrimary = p
 }
 
+// SetProviders limits health checks to the given providers (e.g. the configured failover chain)
+func (m *Manager) SetProviders(providers []llm.ProviderType) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.providers = append([]llm.ProviderType(nil), providers...)
+}
+
+func (m *Manager) checkedProviders() []llm.ProviderType {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.providers) > 0 {
+		return append([]llm.ProviderType(nil), m.providers...)
+	}
+	return []llm.ProviderType{
+		llm.ProviderOpenAI,
+		llm.ProviderAnthropic,
+		llm.ProviderGoogle,
+		llm.ProviderMiniMax,
+		llm.ProviderOllama,
+		llm.ProviderCustom,
+	}
+}
+
 // GetPrimary returns the current primary provider
 func (m *Manager) GetPrimary() llm.ProviderType {
 	m.mu.RLock()
@@ -105,14 +136,7 @@ func (m *Manager) Start() error {
 		m.config.Interval, m.config.FailureThreshold)
 
 	// Initialize status for all providers
-	providers := []llm.ProviderType{
-		llm.ProviderOpenAI,
-		llm.ProviderAnthropic,
-		llm.ProviderGoogle,
-		llm.ProviderMiniMax,
-		llm.ProviderOllama,
-		llm.ProviderCustom,
-	}
+	providers := m.checkedProviders()
 	for _, p := range providers {
 		m.status[p] = &HealthStatus{
 			Provider:  p,
@@ -154,9 +178,46 @@ func (m *Manager) GetStatus() map[llm.ProviderType]*HealthStatus {
 
 // GetFailoverEvents returns recent failover events (last 10)
 func (m *Manager) GetFailoverEvents() []FailoverEvent {
-	// In a real implementation, we'd store events in a slice
-	// For now, return empty slice
-	return []FailoverEvent{}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	start := len(m.events) - 10
+	if start < 0 {
+		start = 0
+	}
+	events := make([]FailoverEvent, len(m.events)-start)
+	copy(events, m.events[start:])
+	return events
+}
+
+// SetRecorder sets a hook that persists failover events (e.g. to storage)
+func (m *Manager) SetRecorder(fn func(FailoverEvent)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recorder = fn
+}
+
+// RecordFailover records a failover event, e.g. one raised by a FailoverChain
+func (m *Manager) RecordFailover(event FailoverEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recordLocked(event)
+}
+
+// recordLocked stores the event, notifies listeners without blocking and
+// hands it to the recorder. Caller must hold m.mu.
+func (m *Manager) recordLocked(event FailoverEvent) {
+	m.events = append(m.events, event)
+	if len(m.events) > maxFailoverEvents {
+		m.events = m.events[len(m.events)-maxFailoverEvents:]
+	}
+	select {
+	case m.failoverCh <- event:
+	default:
+	}
+	if m.recorder != nil {
+		m.recorder(event)
+	}
 }
 
 func (m *Manager) runLoop() {
@@ -174,14 +235,7 @@ func (m *Manager) runLoop() {
 }
 
 func (m *Manager) checkAll() {
-	providers := []llm.ProviderType{
-		llm.ProviderOpenAI,
-		llm.ProviderAnthropic,
-		llm.ProviderGoogle,
-		llm.ProviderMiniMax,
-		llm.ProviderOllama,
-		llm.ProviderCustom,
-	}
+	providers := m.checkedProviders()
 
 	for _, p := range providers {
 		m.checkProvider(p)
@@ -274,7 +328,7 @@ func (m *Manager) evaluateFailover() {
 				ToProvider:  newPrimary,
 				Reason:      fmt.Sprintf("failure threshold reached (%d failures)", primaryStatus.FailCount),
 			}
-			m.failoverCh <- event
+			m.recordLocked(event)
 
 			m.currentPrimary = newPrimary
 		}
@@ -282,14 +336,17 @@ func (m *Manager) evaluateFailover() {
 }
 
 func (m *Manager) findBestFallback() llm.ProviderType {
-	// Priority order: OpenAI > Anthropic > Google > MiniMax > Ollama > Custom
-	priority := []llm.ProviderType{
-		llm.ProviderOpenAI,
-		llm.ProviderAnthropic,
-		llm.ProviderGoogle,
-		llm.ProviderMiniMax,
-		llm.ProviderOllama,
-		llm.ProviderCustom,
+	// Priority order: configured providers, else OpenAI > Anthropic > Google > MiniMax > Ollama > Custom
+	priority := m.providers
+	if len(priority) == 0 {
+		priority = []llm.ProviderType{
+			llm.ProviderOpenAI,
+			llm.ProviderAnthropic,
+			llm.ProviderGoogle,
+			llm.ProviderMiniMax,
+			llm.ProviderOllama,
+			llm.ProviderCustom,
+		}
 	}
 
 	for _, p := range priority {
@@ -335,7 +392,7 @@ func (m *Manager) ManualFailover(target llm.ProviderType) error {
 		Reason:      "manual failover",
 		Manual:      true,
 	}
-	m.failoverCh <- event
+	m.recordLocked(event)
 
 	log.Printf("[LLMHealth] MANUAL FAILOVER: %s -> %s", oldPrimary, target)
 	return nil
@@ -361,5 +418,11 @@ func LoadConfigFromEnv() *Config {
 		}
 	}
 
+	if v := os.Getenv("LLM_HEALTH_BREAKER_COOLDOWN"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.BreakerCooldown = d
+		}
+	}
+
 	return cfg
 }
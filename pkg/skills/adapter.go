@@ -12,6 +12,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/gliderlab/cogate/pkg/commands"
 	"github.com/gliderlab/cogate/pkg/config"
 	"github.com/gliderlab/cogate/tools"
 	"github.com/google/shlex"
@@ -58,6 +59,47 @@ func (a *Adapter) GenerateTools() []tools.Tool {
 	return result
 }
 
+// GenerateCommands returns a slash command for each user-invocable skill with
+// a generated tool: "/<name> <request>" asks the model to use the skill.
+// Call it after GenerateTools.
+func (a *Adapter) GenerateCommands() []commands.Command {
+	var result []commands.Command
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for _, skill := range a.registry.List() {
+		tool, ok := a.tools[skill.Name]
+		if !ok || !skill.UserInvocable {
+			continue
+		}
+		skillName, toolName := skill.Name, tool.Name()
+		result = append(result, commands.Command{
+			Name:   CommandName(skill.Name),
+			Help:   skill.Description,
+			Args:   []commands.Arg{{Name: "request", Rest: true}},
+			Source: "skill",
+			Prompt: func(inv *commands.Invocation) string {
+				if req := inv.Arg("request"); req != "" {
+					return fmt.Sprintf("Use the %s skill (tool %s) for this request: %s", skillName, toolName, req)
+				}
+				return fmt.Sprintf("Use the %s skill (tool %s).", skillName, toolName)
+			},
+		})
+	}
+	return result
+}
+
+// CommandName turns a skill name into a slash command name: lower case, with
+// characters other than letters, digits and underscores replaced by "_"
+func CommandName(skillName string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(skillName))
+}
+
 // createSkillTool creates an OCG tool from a skill
 func (a *Adapter) createSkillTool(skill *Skill) tools.Tool {
 	// Determine skill type and create appropriate tool
@@ -124,6 +166,11 @@ func (t *shellSkillTool) Parameters() map[string]interface{} {
 }
 
 func (t *shellSkillTool) Execute(args map[string]interface{}) (interface{}, error) {
+	return t.ExecuteContext(context.Background(), args)
+}
+
+// ExecuteContext runs the command, killing it on timeout or when parent is done
+func (t *shellSkillTool) ExecuteContext(parent context.Context, args map[string]interface{}) (interface{}, error) {
 	command := tools.GetString(args, "command")
 	timeout := tools.GetInt(args, "timeout")
 	if command == "" {
@@ -148,14 +195,18 @@ func (t *shellSkillTool) Execute(args map[string]interface{}) (interface{}, erro
 	workdir := workspaceDir
 
 	// Execute the command with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	ctx, cancel := context.WithTimeout(parent, time.Duration(timeout)*time.Second)
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, "sh", "-c", command)
 	cmd.Dir = workdir
 	cmd.Env = os.Environ()
+	cmd.WaitDelay = time.Second
 
 	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.Canceled {
+		return string(output), fmt.Errorf("command cancelled")
+	}
 	if ctx.Err() == context.DeadlineExceeded {
 		return string(output), fmt.Errorf("command timed out after %d seconds", timeout)
 	}
@@ -201,6 +252,11 @@ func (t *cliSkillTool) Parameters() map[string]interface{} {
 }
 
 func (t *cliSkillTool) Execute(args map[string]interface{}) (interface{}, error) {
+	return t.ExecuteContext(context.Background(), args)
+}
+
+// ExecuteContext runs the CLI and kills it when ctx is done
+func (t *cliSkillTool) ExecuteContext(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	argsStr := tools.GetString(args, "args")
 
 	// Sandbox: restrict to bin/work
@@ -216,17 +272,20 @@ func (t *cliSkillTool) Execute(args map[string]interface{}) (interface{}, error)
 
 	var cmd *exec.Cmd
 	if argsStr == "" {
-		cmd = exec.Command(t.bin)
+		cmd = exec.CommandContext(ctx, t.bin)
 	} else {
 		parts, err := shlex.Split(argsStr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse args: %w", err)
 		}
-		cmd = exec.Command(t.bin, parts...)
+		cmd = exec.CommandContext(ctx, t.bin, parts...)
 	}
 	cmd.Dir = workdir
 	cmd.Env = os.Environ()
 	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return string(output), fmt.Errorf("%s stopped: %w", t.bin, ctx.Err())
+	}
 	if err != nil {
 		return string(output), fmt.Errorf("%s failed: %w", t.bin, err)
 	}
@@ -379,6 +438,11 @@ func (t *nodeSkillTool) Parameters() map[string]interface{} {
 }
 
 func (t *nodeSkillTool) Execute(args map[string]interface{}) (interface{}, error) {
+	return t.ExecuteContext(context.Background(), args)
+}
+
+// ExecuteContext runs the script and kills it when ctx is done
+func (t *nodeSkillTool) ExecuteContext(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	argsStr := tools.GetString(args, "args")
 	
 	scriptPath := filepath.Join(t.skill.Path, "index.js")
@@ -393,11 +457,14 @@ func (t *nodeSkillTool) Execute(args map[string]interface{}) (interface{}, error
 		cmdArgs = append(cmdArgs, parts...)
 	}
 
-	cmd := exec.Command("node", cmdArgs...)
+	cmd := exec.CommandContext(ctx, "node", cmdArgs...)
 	cmd.Dir = t.skill.Path
 	cmd.Env = os.Environ()
 	
 	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return string(output), fmt.Errorf("Node.js script stopped: %w", ctx.Err())
+	}
 	if err != nil {
 		return string(output), fmt.Errorf("Node.js script failed: %w", err)
 	}
@@ -435,6 +502,11 @@ func (t *pythonSkillTool) Parameters() map[string]interface{} {
 }
 
 func (t *pythonSkillTool) Execute(args map[string]interface{}) (interface{}, error) {
+	return t.ExecuteContext(context.Background(), args)
+}
+
+// ExecuteContext runs the script and kills it when ctx is done
+func (t *pythonSkillTool) ExecuteContext(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	argsStr := tools.GetString(args, "args")
 	
 	scriptPath := filepath.Join(t.skill.Path, "main.py")
@@ -449,11 +521,14 @@ func (t *pythonSkillTool) Execute(args map[string]interface{}) (interface{}, err
 		cmdArgs = append(cmdArgs, parts...)
 	}
 
-	cmd := exec.Command("python3", cmdArgs...)
+	cmd := exec.CommandContext(ctx, "python3", cmdArgs...)
 	cmd.Dir = t.skill.Path
 	cmd.Env = os.Environ()
 	
 	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return string(output), fmt.Errorf("Python script stopped: %w", ctx.Err())
+	}
 	if err != nil {
 		return string(output), fmt.Errorf("Python script failed: %w", err)
 	}
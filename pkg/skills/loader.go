@@ -13,18 +13,19 @@ import (
 
 // Skill represents a loaded skill
 type Skill struct {
-	Name        string
-	Description string
-	Emoji       string
-	Metadata    SkillMetadata
-	Content     string      // Full SKILL.md content
-	Path        string      // Skill directory path
-	BinRequires []string    // Required binaries
-	EnvRequires []string    // Required environment variables
-	OS          []string    // Supported OSes
-	Always      bool        // Always load regardless of requirements
-	HasNodeJS   bool        // Has index.js
-	HasPython   bool        // Has main.py
+	Name          string
+	Description   string
+	Emoji         string
+	Metadata      SkillMetadata
+	Content       string   // Full SKILL.md content
+	Path          string   // Skill directory path
+	BinRequires   []string // Required binaries
+	EnvRequires   []string // Required environment variables
+	OS            []string // Supported OSes
+	Always        bool     // Always load regardless of requirements
+	HasNodeJS     bool     // Has index.js
+	HasPython     bool     // Has main.py
+	UserInvocable bool     // Offered as a /<name> slash command
 }
 
 // InstallItem represents a skill installation method
@@ -126,9 +127,10 @@ func parseFrontmatter(content string, skill *Skill) error {
 			Env     []string `yaml:"env"`
 			AnyBins []string `yaml:"anyBins"`
 		} `yaml:"requires"`
-		OS      []string `yaml:"os"`
-		Always  bool     `yaml:"always"`
-		Install []struct {
+		OS            []string `yaml:"os"`
+		Always        bool     `yaml:"always"`
+		UserInvocable bool     `yaml:"user-invocable"`
+		Install       []struct {
 			ID      string   `yaml:"id"`
 			Kind    string   `yaml:"kind"`
 			Formula string   `yaml:"formula"`
@@ -153,6 +155,7 @@ func parseFrontmatter(content string, skill *Skill) error {
 		skill.EnvRequires = meta.Requires.Env
 		skill.OS = meta.OS
 		skill.Always = meta.Always
+		skill.UserInvocable = meta.UserInvocable
 
 		// Parse install items
 		for _, item := range meta.Install {
@@ -1,7 +1,11 @@
 package skills
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestSkillBasic(t *testing.T) {
@@ -147,3 +151,25 @@ func TestGenerateInstallCommand(t *testing.T) {
 		}
 	}
 }
+
+func TestShellSkillToolContextCancel(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip("no executable path")
+	}
+	if err := os.MkdirAll(filepath.Join(filepath.Dir(exe), "work"), 0755); err != nil {
+		t.Skip("cannot create workspace")
+	}
+
+	tool := newShellSkillTool(&Skill{Name: "sh"}).(*shellSkillTool)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = tool.ExecuteContext(ctx, map[string]interface{}{"command": "sleep 10"})
+	if err == nil || err.Error() != "command cancelled" {
+		t.Errorf("expected command cancelled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("cancelled command returned after %v", elapsed)
+	}
+}
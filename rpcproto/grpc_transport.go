@@ -39,6 +39,19 @@ func (c *AgentGRPCClient) Stats(ctx context.Context) (*StatsReply, error) {
 	return resp, nil
 }
 
+// Usage returns token usage and cost totals grouped by groupBy since the given time
+func (c *AgentGRPCClient) Usage(ctx context.Context, groupBy string, since time.Time) ([]*UsageTotal, error) {
+	args := &StatsArgs{UsageGroupBy: groupBy}
+	if !since.IsZero() {
+		args.UsageSince = since.Unix()
+	}
+	resp, err := c.client.Stats(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Usage, nil
+}
+
 func (c *AgentGRPCClient) ChatStream(ctx context.Context, args *ChatArgs) (Agent_ChatStreamClient, error) {
 	stream, err := c.client.ChatStream(ctx, args)
 	if err != nil {
@@ -55,6 +68,11 @@ func (c *AgentGRPCClient) Sessions(ctx context.Context, args *SessionsArgs) (*Se
 	return resp, nil
 }
 
+// ForkSession copies a session up to a message into a new session
+func (c *AgentGRPCClient) ForkSession(ctx context.Context, args *ForkSessionArgs) (*SessionInfo, error) {
+	return c.client.ForkSession(ctx, args)
+}
+
 func (c *AgentGRPCClient) MemorySearch(ctx context.Context, args *MemorySearchArgs) (*ToolResultReply, error) {
 	resp, err := c.client.MemorySearch(ctx, args)
 	if err != nil {
@@ -103,6 +121,20 @@ func (c *AgentGRPCClient) EndAudioStream(ctx context.Context, args *AudioArgs) (
 	return c.client.EndAudioStream(ctx, args)
 }
 
+// WatchApprovals streams tool calls that wait for human approval until ctx is done
+func (c *AgentGRPCClient) WatchApprovals(ctx context.Context) (Agent_WatchApprovalsClient, error) {
+	return c.client.WatchApprovals(ctx, &ApprovalsArgs{})
+}
+
+// Commands lists the slash commands a session may run
+func (c *AgentGRPCClient) Commands(ctx context.Context, sessionKey string) ([]*CommandInfo, error) {
+	resp, err := c.client.Commands(ctx, &CommandsArgs{SessionKey: sessionKey})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Commands, nil
+}
+
 // DialAgent connects to the agent via gRPC Unix socket.
 func DialAgent(addr string, timeout time.Duration) (*grpc.ClientConn, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
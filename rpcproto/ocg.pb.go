@@ -27,6 +27,7 @@ type Message struct {
 	Content              string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
 	ToolCalls            []*ToolCall            `protobuf:"bytes,3,rep,name=tool_calls,json=toolCalls,proto3" json:"tool_calls,omitempty"`
 	ToolExecutionResults []*ToolResult          `protobuf:"bytes,4,rep,name=tool_execution_results,json=toolExecutionResults,proto3" json:"tool_execution_results,omitempty"`
+	Parts                []*ContentPart         `protobuf:"bytes,5,rep,name=parts,proto3" json:"parts,omitempty"` // Images and audio sent with the message (e.g. a channel photo)
 	unknownFields        protoimpl.UnknownFields
 	sizeCache            protoimpl.SizeCache
 }
@@ -89,6 +90,82 @@ func (x *Message) GetToolExecutionResults() []*ToolResult {
 	return nil
 }
 
+func (x *Message) GetParts() []*ContentPart {
+	if x != nil {
+		return x.Parts
+	}
+	return nil
+}
+
+// ContentPart is an inline or remote image or audio attachment (see llm.ContentPart)
+type ContentPart struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"` // "image" or "audio"
+	MimeType      string                 `protobuf:"bytes,2,opt,name=mime_type,json=mimeType,proto3" json:"mime_type,omitempty"`
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	Url           string                 `protobuf:"bytes,4,opt,name=url,proto3" json:"url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContentPart) Reset() {
+	*x = ContentPart{}
+	mi := &file_ocg_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContentPart) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContentPart) ProtoMessage() {}
+
+func (x *ContentPart) ProtoReflect() protoreflect.Message {
+	mi := &file_ocg_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContentPart.ProtoReflect.Descriptor instead.
+func (*ContentPart) Descriptor() ([]byte, []int) {
+	return file_ocg_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ContentPart) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ContentPart) GetMimeType() string {
+	if x != nil {
+		return x.MimeType
+	}
+	return ""
+}
+
+func (x *ContentPart) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *ContentPart) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
 type ToolCall struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -100,7 +177,7 @@ type ToolCall struct {
 
 func (x *ToolCall) Reset() {
 	*x = ToolCall{}
-	mi := &file_ocg_proto_msgTypes[1]
+	mi := &file_ocg_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -112,7 +189,7 @@ func (x *ToolCall) String() string {
 func (*ToolCall) ProtoMessage() {}
 
 func (x *ToolCall) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[1]
+	mi := &file_ocg_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -125,7 +202,7 @@ func (x *ToolCall) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ToolCall.ProtoReflect.Descriptor instead.
 func (*ToolCall) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{1}
+	return file_ocg_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *ToolCall) GetId() string {
@@ -159,7 +236,7 @@ type Function struct {
 
 func (x *Function) Reset() {
 	*x = Function{}
-	mi := &file_ocg_proto_msgTypes[2]
+	mi := &file_ocg_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -171,7 +248,7 @@ func (x *Function) String() string {
 func (*Function) ProtoMessage() {}
 
 func (x *Function) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[2]
+	mi := &file_ocg_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -184,7 +261,7 @@ func (x *Function) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Function.ProtoReflect.Descriptor instead.
 func (*Function) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{2}
+	return file_ocg_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *Function) GetName() string {
@@ -212,7 +289,7 @@ type ToolFunction struct {
 
 func (x *ToolFunction) Reset() {
 	*x = ToolFunction{}
-	mi := &file_ocg_proto_msgTypes[3]
+	mi := &file_ocg_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -224,7 +301,7 @@ func (x *ToolFunction) String() string {
 func (*ToolFunction) ProtoMessage() {}
 
 func (x *ToolFunction) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[3]
+	mi := &file_ocg_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -237,7 +314,7 @@ func (x *ToolFunction) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ToolFunction.ProtoReflect.Descriptor instead.
 func (*ToolFunction) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{3}
+	return file_ocg_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *ToolFunction) GetName() string {
@@ -272,7 +349,7 @@ type Tool struct {
 
 func (x *Tool) Reset() {
 	*x = Tool{}
-	mi := &file_ocg_proto_msgTypes[4]
+	mi := &file_ocg_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -284,7 +361,7 @@ func (x *Tool) String() string {
 func (*Tool) ProtoMessage() {}
 
 func (x *Tool) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[4]
+	mi := &file_ocg_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -297,7 +374,7 @@ func (x *Tool) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Tool.ProtoReflect.Descriptor instead.
 func (*Tool) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{4}
+	return file_ocg_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *Tool) GetType() string {
@@ -332,7 +409,7 @@ type ToolResult struct {
 
 func (x *ToolResult) Reset() {
 	*x = ToolResult{}
-	mi := &file_ocg_proto_msgTypes[5]
+	mi := &file_ocg_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -344,7 +421,7 @@ func (x *ToolResult) String() string {
 func (*ToolResult) ProtoMessage() {}
 
 func (x *ToolResult) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[5]
+	mi := &file_ocg_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -357,7 +434,7 @@ func (x *ToolResult) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ToolResult.ProtoReflect.Descriptor instead.
 func (*ToolResult) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{5}
+	return file_ocg_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *ToolResult) GetId() string {
@@ -382,16 +459,19 @@ func (x *ToolResult) GetResult() string {
 }
 
 type ChatArgs struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Messages      []*Message             `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
-	SessionKey    string                 `protobuf:"bytes,2,opt,name=session_key,json=sessionKey,proto3" json:"session_key,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Messages       []*Message             `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	SessionKey     string                 `protobuf:"bytes,2,opt,name=session_key,json=sessionKey,proto3" json:"session_key,omitempty"`
+	Isolated       bool                   `protobuf:"varint,3,opt,name=isolated,proto3" json:"isolated,omitempty"`                                  // Run without loading the session's history
+	ResponseFormat string                 `protobuf:"bytes,4,opt,name=response_format,json=responseFormat,proto3" json:"response_format,omitempty"` // OpenAI-style response_format JSON; the reply must match it (Chat only)
+	AgentId        string                 `protobuf:"bytes,5,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`                      // Agent profile to run as (empty = from the session key, else the default agent)
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *ChatArgs) Reset() {
 	*x = ChatArgs{}
-	mi := &file_ocg_proto_msgTypes[6]
+	mi := &file_ocg_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -403,7 +483,7 @@ func (x *ChatArgs) String() string {
 func (*ChatArgs) ProtoMessage() {}
 
 func (x *ChatArgs) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[6]
+	mi := &file_ocg_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -416,7 +496,7 @@ func (x *ChatArgs) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ChatArgs.ProtoReflect.Descriptor instead.
 func (*ChatArgs) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{6}
+	return file_ocg_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *ChatArgs) GetMessages() []*Message {
@@ -433,17 +513,40 @@ func (x *ChatArgs) GetSessionKey() string {
 	return ""
 }
 
+func (x *ChatArgs) GetIsolated() bool {
+	if x != nil {
+		return x.Isolated
+	}
+	return false
+}
+
+func (x *ChatArgs) GetResponseFormat() string {
+	if x != nil {
+		return x.ResponseFormat
+	}
+	return ""
+}
+
+func (x *ChatArgs) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
 type ChatReply struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Content       string                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
 	Tools         []*ToolCall            `protobuf:"bytes,2,rep,name=tools,proto3" json:"tools,omitempty"`
+	Object        string                 `protobuf:"bytes,3,opt,name=object,proto3" json:"object,omitempty"`                              // Parsed reply as JSON when a response_format was given
+	FormatError   string                 `protobuf:"bytes,4,opt,name=format_error,json=formatError,proto3" json:"format_error,omitempty"` // Why the reply failed the response_format after retries
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ChatReply) Reset() {
 	*x = ChatReply{}
-	mi := &file_ocg_proto_msgTypes[7]
+	mi := &file_ocg_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -455,7 +558,7 @@ func (x *ChatReply) String() string {
 func (*ChatReply) ProtoMessage() {}
 
 func (x *ChatReply) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[7]
+	mi := &file_ocg_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -468,7 +571,7 @@ func (x *ChatReply) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ChatReply.ProtoReflect.Descriptor instead.
 func (*ChatReply) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{7}
+	return file_ocg_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *ChatReply) GetContent() string {
@@ -485,6 +588,20 @@ func (x *ChatReply) GetTools() []*ToolCall {
 	return nil
 }
 
+func (x *ChatReply) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *ChatReply) GetFormatError() string {
+	if x != nil {
+		return x.FormatError
+	}
+	return ""
+}
+
 type ChatStreamReply struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Content       string                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
@@ -495,7 +612,7 @@ type ChatStreamReply struct {
 
 func (x *ChatStreamReply) Reset() {
 	*x = ChatStreamReply{}
-	mi := &file_ocg_proto_msgTypes[8]
+	mi := &file_ocg_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -507,7 +624,7 @@ func (x *ChatStreamReply) String() string {
 func (*ChatStreamReply) ProtoMessage() {}
 
 func (x *ChatStreamReply) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[8]
+	mi := &file_ocg_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -520,7 +637,7 @@ func (x *ChatStreamReply) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ChatStreamReply.ProtoReflect.Descriptor instead.
 func (*ChatStreamReply) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{8}
+	return file_ocg_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *ChatStreamReply) GetContent() string {
@@ -539,13 +656,15 @@ func (x *ChatStreamReply) GetDone() bool {
 
 type StatsArgs struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	UsageGroupBy  string                 `protobuf:"bytes,1,opt,name=usage_group_by,json=usageGroupBy,proto3" json:"usage_group_by,omitempty"` // total, session, channel, provider, model, purpose or day
+	UsageSince    int64                  `protobuf:"varint,2,opt,name=usage_since,json=usageSince,proto3" json:"usage_since,omitempty"`        // Unix seconds; 0 = all time
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *StatsArgs) Reset() {
 	*x = StatsArgs{}
-	mi := &file_ocg_proto_msgTypes[9]
+	mi := &file_ocg_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -557,7 +676,7 @@ func (x *StatsArgs) String() string {
 func (*StatsArgs) ProtoMessage() {}
 
 func (x *StatsArgs) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[9]
+	mi := &file_ocg_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -570,19 +689,34 @@ func (x *StatsArgs) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StatsArgs.ProtoReflect.Descriptor instead.
 func (*StatsArgs) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{9}
+	return file_ocg_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *StatsArgs) GetUsageGroupBy() string {
+	if x != nil {
+		return x.UsageGroupBy
+	}
+	return ""
+}
+
+func (x *StatsArgs) GetUsageSince() int64 {
+	if x != nil {
+		return x.UsageSince
+	}
+	return 0
 }
 
 type StatsReply struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Stats         map[string]int32       `protobuf:"bytes,1,rep,name=stats,proto3" json:"stats,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	Usage         []*UsageTotal          `protobuf:"bytes,2,rep,name=usage,proto3" json:"usage,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *StatsReply) Reset() {
 	*x = StatsReply{}
-	mi := &file_ocg_proto_msgTypes[10]
+	mi := &file_ocg_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -594,7 +728,7 @@ func (x *StatsReply) String() string {
 func (*StatsReply) ProtoMessage() {}
 
 func (x *StatsReply) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[10]
+	mi := &file_ocg_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -607,7 +741,7 @@ func (x *StatsReply) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StatsReply.ProtoReflect.Descriptor instead.
 func (*StatsReply) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{10}
+	return file_ocg_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *StatsReply) GetStats() map[string]int32 {
@@ -617,6 +751,97 @@ func (x *StatsReply) GetStats() map[string]int32 {
 	return nil
 }
 
+func (x *StatsReply) GetUsage() []*UsageTotal {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+type UsageTotal struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Key              string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Calls            int64                  `protobuf:"varint,2,opt,name=calls,proto3" json:"calls,omitempty"`
+	PromptTokens     int64                  `protobuf:"varint,3,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	CompletionTokens int64                  `protobuf:"varint,4,opt,name=completion_tokens,json=completionTokens,proto3" json:"completion_tokens,omitempty"`
+	CachedTokens     int64                  `protobuf:"varint,5,opt,name=cached_tokens,json=cachedTokens,proto3" json:"cached_tokens,omitempty"`
+	Cost             float64                `protobuf:"fixed64,6,opt,name=cost,proto3" json:"cost,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *UsageTotal) Reset() {
+	*x = UsageTotal{}
+	mi := &file_ocg_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UsageTotal) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UsageTotal) ProtoMessage() {}
+
+func (x *UsageTotal) ProtoReflect() protoreflect.Message {
+	mi := &file_ocg_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UsageTotal.ProtoReflect.Descriptor instead.
+func (*UsageTotal) Descriptor() ([]byte, []int) {
+	return file_ocg_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *UsageTotal) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *UsageTotal) GetCalls() int64 {
+	if x != nil {
+		return x.Calls
+	}
+	return 0
+}
+
+func (x *UsageTotal) GetPromptTokens() int64 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *UsageTotal) GetCompletionTokens() int64 {
+	if x != nil {
+		return x.CompletionTokens
+	}
+	return 0
+}
+
+func (x *UsageTotal) GetCachedTokens() int64 {
+	if x != nil {
+		return x.CachedTokens
+	}
+	return 0
+}
+
+func (x *UsageTotal) GetCost() float64 {
+	if x != nil {
+		return x.Cost
+	}
+	return 0
+}
+
 type SessionsArgs struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Limit         int32                  `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
@@ -626,7 +851,7 @@ type SessionsArgs struct {
 
 func (x *SessionsArgs) Reset() {
 	*x = SessionsArgs{}
-	mi := &file_ocg_proto_msgTypes[11]
+	mi := &file_ocg_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -638,7 +863,7 @@ func (x *SessionsArgs) String() string {
 func (*SessionsArgs) ProtoMessage() {}
 
 func (x *SessionsArgs) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[11]
+	mi := &file_ocg_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -651,7 +876,7 @@ func (x *SessionsArgs) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SessionsArgs.ProtoReflect.Descriptor instead.
 func (*SessionsArgs) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{11}
+	return file_ocg_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *SessionsArgs) GetLimit() int32 {
@@ -671,7 +896,7 @@ type SessionsReply struct {
 
 func (x *SessionsReply) Reset() {
 	*x = SessionsReply{}
-	mi := &file_ocg_proto_msgTypes[12]
+	mi := &file_ocg_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -683,7 +908,7 @@ func (x *SessionsReply) String() string {
 func (*SessionsReply) ProtoMessage() {}
 
 func (x *SessionsReply) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[12]
+	mi := &file_ocg_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -696,7 +921,7 @@ func (x *SessionsReply) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SessionsReply.ProtoReflect.Descriptor instead.
 func (*SessionsReply) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{12}
+	return file_ocg_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *SessionsReply) GetSessions() []*SessionInfo {
@@ -714,18 +939,21 @@ func (x *SessionsReply) GetCount() int32 {
 }
 
 type SessionInfo struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	SessionKey      string                 `protobuf:"bytes,1,opt,name=session_key,json=sessionKey,proto3" json:"session_key,omitempty"`
-	TotalTokens     int32                  `protobuf:"varint,2,opt,name=total_tokens,json=totalTokens,proto3" json:"total_tokens,omitempty"`
-	CompactionCount int32                  `protobuf:"varint,3,opt,name=compaction_count,json=compactionCount,proto3" json:"compaction_count,omitempty"`
-	UpdatedAt       string                 `protobuf:"bytes,4,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	SessionKey       string                 `protobuf:"bytes,1,opt,name=session_key,json=sessionKey,proto3" json:"session_key,omitempty"`
+	TotalTokens      int32                  `protobuf:"varint,2,opt,name=total_tokens,json=totalTokens,proto3" json:"total_tokens,omitempty"`
+	CompactionCount  int32                  `protobuf:"varint,3,opt,name=compaction_count,json=compactionCount,proto3" json:"compaction_count,omitempty"`
+	UpdatedAt        string                 `protobuf:"bytes,4,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	ParentSessionKey string                 `protobuf:"bytes,5,opt,name=parent_session_key,json=parentSessionKey,proto3" json:"parent_session_key,omitempty"` // Set on forks
+	ForkMessageId    int64                  `protobuf:"varint,6,opt,name=fork_message_id,json=forkMessageId,proto3" json:"fork_message_id,omitempty"`
+	Branches         []string               `protobuf:"bytes,7,rep,name=branches,proto3" json:"branches,omitempty"` // Sessions forked from this one
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
 func (x *SessionInfo) Reset() {
 	*x = SessionInfo{}
-	mi := &file_ocg_proto_msgTypes[13]
+	mi := &file_ocg_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -737,7 +965,7 @@ func (x *SessionInfo) String() string {
 func (*SessionInfo) ProtoMessage() {}
 
 func (x *SessionInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[13]
+	mi := &file_ocg_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -750,7 +978,7 @@ func (x *SessionInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SessionInfo.ProtoReflect.Descriptor instead.
 func (*SessionInfo) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{13}
+	return file_ocg_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *SessionInfo) GetSessionKey() string {
@@ -781,19 +1009,106 @@ func (x *SessionInfo) GetUpdatedAt() string {
 	return ""
 }
 
-type MemorySearchArgs struct {
+func (x *SessionInfo) GetParentSessionKey() string {
+	if x != nil {
+		return x.ParentSessionKey
+	}
+	return ""
+}
+
+func (x *SessionInfo) GetForkMessageId() int64 {
+	if x != nil {
+		return x.ForkMessageId
+	}
+	return 0
+}
+
+func (x *SessionInfo) GetBranches() []string {
+	if x != nil {
+		return x.Branches
+	}
+	return nil
+}
+
+type ForkSessionArgs struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Query         string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
-	Category      string                 `protobuf:"bytes,2,opt,name=category,proto3" json:"category,omitempty"`
-	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
-	MinScore      float32                `protobuf:"fixed32,4,opt,name=min_score,json=minScore,proto3" json:"min_score,omitempty"`
+	SessionKey    string                 `protobuf:"bytes,1,opt,name=session_key,json=sessionKey,proto3" json:"session_key,omitempty"`
+	NewSessionKey string                 `protobuf:"bytes,2,opt,name=new_session_key,json=newSessionKey,proto3" json:"new_session_key,omitempty"` // Generated when empty
+	MessageId     int64                  `protobuf:"varint,3,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`              // Last message to copy; 0 for all
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *MemorySearchArgs) Reset() {
-	*x = MemorySearchArgs{}
-	mi := &file_ocg_proto_msgTypes[14]
+func (x *ForkSessionArgs) Reset() {
+	*x = ForkSessionArgs{}
+	mi := &file_ocg_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ForkSessionArgs) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForkSessionArgs) ProtoMessage() {}
+
+func (x *ForkSessionArgs) ProtoReflect() protoreflect.Message {
+	mi := &file_ocg_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForkSessionArgs.ProtoReflect.Descriptor instead.
+func (*ForkSessionArgs) Descriptor() ([]byte, []int) {
+	return file_ocg_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ForkSessionArgs) GetSessionKey() string {
+	if x != nil {
+		return x.SessionKey
+	}
+	return ""
+}
+
+func (x *ForkSessionArgs) GetNewSessionKey() string {
+	if x != nil {
+		return x.NewSessionKey
+	}
+	return ""
+}
+
+func (x *ForkSessionArgs) GetMessageId() int64 {
+	if x != nil {
+		return x.MessageId
+	}
+	return 0
+}
+
+// Memory calls with a session_key act as that session: they see its own,
+// global and shared memories in its agent's namespace. Without one they see
+// every memory.
+type MemorySearchArgs struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Query         string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Category      string                 `protobuf:"bytes,2,opt,name=category,proto3" json:"category,omitempty"`
+	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	MinScore      float32                `protobuf:"fixed32,4,opt,name=min_score,json=minScore,proto3" json:"min_score,omitempty"`
+	SessionKey    string                 `protobuf:"bytes,5,opt,name=session_key,json=sessionKey,proto3" json:"session_key,omitempty"`
+	AgentId       string                 `protobuf:"bytes,6,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`  // Agent profile; default from session_key
+	OwnOnly       bool                   `protobuf:"varint,7,opt,name=own_only,json=ownOnly,proto3" json:"own_only,omitempty"` // Skip global and shared memories
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MemorySearchArgs) Reset() {
+	*x = MemorySearchArgs{}
+	mi := &file_ocg_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -805,7 +1120,7 @@ func (x *MemorySearchArgs) String() string {
 func (*MemorySearchArgs) ProtoMessage() {}
 
 func (x *MemorySearchArgs) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[14]
+	mi := &file_ocg_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -818,7 +1133,7 @@ func (x *MemorySearchArgs) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MemorySearchArgs.ProtoReflect.Descriptor instead.
 func (*MemorySearchArgs) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{14}
+	return file_ocg_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *MemorySearchArgs) GetQuery() string {
@@ -849,16 +1164,39 @@ func (x *MemorySearchArgs) GetMinScore() float32 {
 	return 0
 }
 
+func (x *MemorySearchArgs) GetSessionKey() string {
+	if x != nil {
+		return x.SessionKey
+	}
+	return ""
+}
+
+func (x *MemorySearchArgs) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *MemorySearchArgs) GetOwnOnly() bool {
+	if x != nil {
+		return x.OwnOnly
+	}
+	return false
+}
+
 type MemoryGetArgs struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	SessionKey    string                 `protobuf:"bytes,2,opt,name=session_key,json=sessionKey,proto3" json:"session_key,omitempty"`
+	AgentId       string                 `protobuf:"bytes,3,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *MemoryGetArgs) Reset() {
 	*x = MemoryGetArgs{}
-	mi := &file_ocg_proto_msgTypes[15]
+	mi := &file_ocg_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -870,7 +1208,7 @@ func (x *MemoryGetArgs) String() string {
 func (*MemoryGetArgs) ProtoMessage() {}
 
 func (x *MemoryGetArgs) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[15]
+	mi := &file_ocg_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -883,7 +1221,7 @@ func (x *MemoryGetArgs) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MemoryGetArgs.ProtoReflect.Descriptor instead.
 func (*MemoryGetArgs) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{15}
+	return file_ocg_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *MemoryGetArgs) GetPath() string {
@@ -893,18 +1231,36 @@ func (x *MemoryGetArgs) GetPath() string {
 	return ""
 }
 
+func (x *MemoryGetArgs) GetSessionKey() string {
+	if x != nil {
+		return x.SessionKey
+	}
+	return ""
+}
+
+func (x *MemoryGetArgs) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
 type MemoryStoreArgs struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
 	Category      string                 `protobuf:"bytes,2,opt,name=category,proto3" json:"category,omitempty"`
 	Importance    float32                `protobuf:"fixed32,3,opt,name=importance,proto3" json:"importance,omitempty"`
+	SessionKey    string                 `protobuf:"bytes,4,opt,name=session_key,json=sessionKey,proto3" json:"session_key,omitempty"`
+	AgentId       string                 `protobuf:"bytes,5,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	Global        bool                   `protobuf:"varint,6,opt,name=global,proto3" json:"global,omitempty"`                       // Visible to every session
+	ShareWith     []string               `protobuf:"bytes,7,rep,name=share_with,json=shareWith,proto3" json:"share_with,omitempty"` // Other session keys that see it
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *MemoryStoreArgs) Reset() {
 	*x = MemoryStoreArgs{}
-	mi := &file_ocg_proto_msgTypes[16]
+	mi := &file_ocg_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -916,7 +1272,7 @@ func (x *MemoryStoreArgs) String() string {
 func (*MemoryStoreArgs) ProtoMessage() {}
 
 func (x *MemoryStoreArgs) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[16]
+	mi := &file_ocg_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -929,7 +1285,7 @@ func (x *MemoryStoreArgs) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MemoryStoreArgs.ProtoReflect.Descriptor instead.
 func (*MemoryStoreArgs) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{16}
+	return file_ocg_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *MemoryStoreArgs) GetText() string {
@@ -953,6 +1309,111 @@ func (x *MemoryStoreArgs) GetImportance() float32 {
 	return 0
 }
 
+func (x *MemoryStoreArgs) GetSessionKey() string {
+	if x != nil {
+		return x.SessionKey
+	}
+	return ""
+}
+
+func (x *MemoryStoreArgs) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *MemoryStoreArgs) GetGlobal() bool {
+	if x != nil {
+		return x.Global
+	}
+	return false
+}
+
+func (x *MemoryStoreArgs) GetShareWith() []string {
+	if x != nil {
+		return x.ShareWith
+	}
+	return nil
+}
+
+// Paths are read by the agent process, so they should be absolute.
+type MemoryIngestArgs struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Paths         []string               `protobuf:"bytes,1,rep,name=paths,proto3" json:"paths,omitempty"` // Files, directories or globs
+	SessionKey    string                 `protobuf:"bytes,2,opt,name=session_key,json=sessionKey,proto3" json:"session_key,omitempty"`
+	AgentId       string                 `protobuf:"bytes,3,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	Global        bool                   `protobuf:"varint,4,opt,name=global,proto3" json:"global,omitempty"` // Visible to every session
+	Force         bool                   `protobuf:"varint,5,opt,name=force,proto3" json:"force,omitempty"`   // Re-chunk unchanged files
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MemoryIngestArgs) Reset() {
+	*x = MemoryIngestArgs{}
+	mi := &file_ocg_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MemoryIngestArgs) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MemoryIngestArgs) ProtoMessage() {}
+
+func (x *MemoryIngestArgs) ProtoReflect() protoreflect.Message {
+	mi := &file_ocg_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MemoryIngestArgs.ProtoReflect.Descriptor instead.
+func (*MemoryIngestArgs) Descriptor() ([]byte, []int) {
+	return file_ocg_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *MemoryIngestArgs) GetPaths() []string {
+	if x != nil {
+		return x.Paths
+	}
+	return nil
+}
+
+func (x *MemoryIngestArgs) GetSessionKey() string {
+	if x != nil {
+		return x.SessionKey
+	}
+	return ""
+}
+
+func (x *MemoryIngestArgs) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *MemoryIngestArgs) GetGlobal() bool {
+	if x != nil {
+		return x.Global
+	}
+	return false
+}
+
+func (x *MemoryIngestArgs) GetForce() bool {
+	if x != nil {
+		return x.Force
+	}
+	return false
+}
+
 type ToolResultReply struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Result        string                 `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
@@ -962,7 +1423,7 @@ type ToolResultReply struct {
 
 func (x *ToolResultReply) Reset() {
 	*x = ToolResultReply{}
-	mi := &file_ocg_proto_msgTypes[17]
+	mi := &file_ocg_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -974,7 +1435,7 @@ func (x *ToolResultReply) String() string {
 func (*ToolResultReply) ProtoMessage() {}
 
 func (x *ToolResultReply) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[17]
+	mi := &file_ocg_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -987,7 +1448,7 @@ func (x *ToolResultReply) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ToolResultReply.ProtoReflect.Descriptor instead.
 func (*ToolResultReply) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{17}
+	return file_ocg_proto_rawDescGZIP(), []int{21}
 }
 
 func (x *ToolResultReply) GetResult() string {
@@ -1011,7 +1472,7 @@ type PulseArgs struct {
 
 func (x *PulseArgs) Reset() {
 	*x = PulseArgs{}
-	mi := &file_ocg_proto_msgTypes[18]
+	mi := &file_ocg_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1023,7 +1484,7 @@ func (x *PulseArgs) String() string {
 func (*PulseArgs) ProtoMessage() {}
 
 func (x *PulseArgs) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[18]
+	mi := &file_ocg_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1036,7 +1497,7 @@ func (x *PulseArgs) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PulseArgs.ProtoReflect.Descriptor instead.
 func (*PulseArgs) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{18}
+	return file_ocg_proto_rawDescGZIP(), []int{22}
 }
 
 func (x *PulseArgs) GetAction() string {
@@ -1092,7 +1553,7 @@ type PulseReply struct {
 
 func (x *PulseReply) Reset() {
 	*x = PulseReply{}
-	mi := &file_ocg_proto_msgTypes[19]
+	mi := &file_ocg_proto_msgTypes[23]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1104,7 +1565,7 @@ func (x *PulseReply) String() string {
 func (*PulseReply) ProtoMessage() {}
 
 func (x *PulseReply) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[19]
+	mi := &file_ocg_proto_msgTypes[23]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1117,7 +1578,7 @@ func (x *PulseReply) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PulseReply.ProtoReflect.Descriptor instead.
 func (*PulseReply) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{19}
+	return file_ocg_proto_rawDescGZIP(), []int{23}
 }
 
 func (x *PulseReply) GetResult() string {
@@ -1150,7 +1611,7 @@ type AudioArgs struct {
 
 func (x *AudioArgs) Reset() {
 	*x = AudioArgs{}
-	mi := &file_ocg_proto_msgTypes[20]
+	mi := &file_ocg_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1162,7 +1623,7 @@ func (x *AudioArgs) String() string {
 func (*AudioArgs) ProtoMessage() {}
 
 func (x *AudioArgs) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[20]
+	mi := &file_ocg_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1175,7 +1636,7 @@ func (x *AudioArgs) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AudioArgs.ProtoReflect.Descriptor instead.
 func (*AudioArgs) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{20}
+	return file_ocg_proto_rawDescGZIP(), []int{24}
 }
 
 func (x *AudioArgs) GetSessionKey() string {
@@ -1195,7 +1656,7 @@ type AudioChunkArgs struct {
 
 func (x *AudioChunkArgs) Reset() {
 	*x = AudioChunkArgs{}
-	mi := &file_ocg_proto_msgTypes[21]
+	mi := &file_ocg_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1207,7 +1668,7 @@ func (x *AudioChunkArgs) String() string {
 func (*AudioChunkArgs) ProtoMessage() {}
 
 func (x *AudioChunkArgs) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[21]
+	mi := &file_ocg_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1220,7 +1681,7 @@ func (x *AudioChunkArgs) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AudioChunkArgs.ProtoReflect.Descriptor instead.
 func (*AudioChunkArgs) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{21}
+	return file_ocg_proto_rawDescGZIP(), []int{25}
 }
 
 func (x *AudioChunkArgs) GetSessionKey() string {
@@ -1246,7 +1707,7 @@ type AudioReply struct {
 
 func (x *AudioReply) Reset() {
 	*x = AudioReply{}
-	mi := &file_ocg_proto_msgTypes[22]
+	mi := &file_ocg_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1258,7 +1719,7 @@ func (x *AudioReply) String() string {
 func (*AudioReply) ProtoMessage() {}
 
 func (x *AudioReply) ProtoReflect() protoreflect.Message {
-	mi := &file_ocg_proto_msgTypes[22]
+	mi := &file_ocg_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1271,7 +1732,7 @@ func (x *AudioReply) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AudioReply.ProtoReflect.Descriptor instead.
 func (*AudioReply) Descriptor() ([]byte, []int) {
-	return file_ocg_proto_rawDescGZIP(), []int{22}
+	return file_ocg_proto_rawDescGZIP(), []int{26}
 }
 
 func (x *AudioReply) GetError() string {
@@ -1281,17 +1742,307 @@ func (x *AudioReply) GetError() string {
 	return ""
 }
 
+type ApprovalsArgs struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApprovalsArgs) Reset() {
+	*x = ApprovalsArgs{}
+	mi := &file_ocg_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApprovalsArgs) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApprovalsArgs) ProtoMessage() {}
+
+func (x *ApprovalsArgs) ProtoReflect() protoreflect.Message {
+	mi := &file_ocg_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApprovalsArgs.ProtoReflect.Descriptor instead.
+func (*ApprovalsArgs) Descriptor() ([]byte, []int) {
+	return file_ocg_proto_rawDescGZIP(), []int{27}
+}
+
+type ApprovalRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	SessionKey    string                 `protobuf:"bytes,2,opt,name=session_key,json=sessionKey,proto3" json:"session_key,omitempty"`
+	Tool          string                 `protobuf:"bytes,3,opt,name=tool,proto3" json:"tool,omitempty"`
+	Arguments     string                 `protobuf:"bytes,4,opt,name=arguments,proto3" json:"arguments,omitempty"`                   // JSON
+	Reason        string                 `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"`                         // The ask rule that matched
+	ExpiresAt     int64                  `protobuf:"varint,6,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // Unix seconds
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApprovalRequest) Reset() {
+	*x = ApprovalRequest{}
+	mi := &file_ocg_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApprovalRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApprovalRequest) ProtoMessage() {}
+
+func (x *ApprovalRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ocg_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApprovalRequest.ProtoReflect.Descriptor instead.
+func (*ApprovalRequest) Descriptor() ([]byte, []int) {
+	return file_ocg_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *ApprovalRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ApprovalRequest) GetSessionKey() string {
+	if x != nil {
+		return x.SessionKey
+	}
+	return ""
+}
+
+func (x *ApprovalRequest) GetTool() string {
+	if x != nil {
+		return x.Tool
+	}
+	return ""
+}
+
+func (x *ApprovalRequest) GetArguments() string {
+	if x != nil {
+		return x.Arguments
+	}
+	return ""
+}
+
+func (x *ApprovalRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *ApprovalRequest) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+type CommandsArgs struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionKey    string                 `protobuf:"bytes,1,opt,name=session_key,json=sessionKey,proto3" json:"session_key,omitempty"` // Only commands this session may run (empty = the default permission level)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandsArgs) Reset() {
+	*x = CommandsArgs{}
+	mi := &file_ocg_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandsArgs) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandsArgs) ProtoMessage() {}
+
+func (x *CommandsArgs) ProtoReflect() protoreflect.Message {
+	mi := &file_ocg_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandsArgs.ProtoReflect.Descriptor instead.
+func (*CommandsArgs) Descriptor() ([]byte, []int) {
+	return file_ocg_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *CommandsArgs) GetSessionKey() string {
+	if x != nil {
+		return x.SessionKey
+	}
+	return ""
+}
+
+type CommandInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`   // Without the slash; subcommands contain spaces ("task list")
+	Usage         string                 `protobuf:"bytes,2,opt,name=usage,proto3" json:"usage,omitempty"` // e.g. "/task detail <task-id> [page]"
+	Help          string                 `protobuf:"bytes,3,opt,name=help,proto3" json:"help,omitempty"`
+	Level         string                 `protobuf:"bytes,4,opt,name=level,proto3" json:"level,omitempty"`   // "user" or "admin"
+	Source        string                 `protobuf:"bytes,5,opt,name=source,proto3" json:"source,omitempty"` // "builtin", "skill" or "plugin"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandInfo) Reset() {
+	*x = CommandInfo{}
+	mi := &file_ocg_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandInfo) ProtoMessage() {}
+
+func (x *CommandInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_ocg_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandInfo.ProtoReflect.Descriptor instead.
+func (*CommandInfo) Descriptor() ([]byte, []int) {
+	return file_ocg_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *CommandInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CommandInfo) GetUsage() string {
+	if x != nil {
+		return x.Usage
+	}
+	return ""
+}
+
+func (x *CommandInfo) GetHelp() string {
+	if x != nil {
+		return x.Help
+	}
+	return ""
+}
+
+func (x *CommandInfo) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *CommandInfo) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+type CommandsReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Commands      []*CommandInfo         `protobuf:"bytes,1,rep,name=commands,proto3" json:"commands,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandsReply) Reset() {
+	*x = CommandsReply{}
+	mi := &file_ocg_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandsReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandsReply) ProtoMessage() {}
+
+func (x *CommandsReply) ProtoReflect() protoreflect.Message {
+	mi := &file_ocg_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandsReply.ProtoReflect.Descriptor instead.
+func (*CommandsReply) Descriptor() ([]byte, []int) {
+	return file_ocg_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *CommandsReply) GetCommands() []*CommandInfo {
+	if x != nil {
+		return x.Commands
+	}
+	return nil
+}
+
 var File_ocg_proto protoreflect.FileDescriptor
 
 const file_ocg_proto_rawDesc = "" +
 	"\n" +
-	"\tocg.proto\x12\x03ocg\"\xac\x01\n" +
+	"\tocg.proto\x12\x03ocg\"\xd4\x01\n" +
 	"\aMessage\x12\x12\n" +
 	"\x04role\x18\x01 \x01(\tR\x04role\x12\x18\n" +
 	"\acontent\x18\x02 \x01(\tR\acontent\x12,\n" +
 	"\n" +
 	"tool_calls\x18\x03 \x03(\v2\r.ocg.ToolCallR\ttoolCalls\x12E\n" +
-	"\x16tool_execution_results\x18\x04 \x03(\v2\x0f.ocg.ToolResultR\x14toolExecutionResults\"Y\n" +
+	"\x16tool_execution_results\x18\x04 \x03(\v2\x0f.ocg.ToolResultR\x14toolExecutionResults\x12&\n" +
+	"\x05parts\x18\x05 \x03(\v2\x10.ocg.ContentPartR\x05parts\"d\n" +
+	"\vContentPart\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x1b\n" +
+	"\tmime_type\x18\x02 \x01(\tR\bmimeType\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x12\x10\n" +
+	"\x03url\x18\x04 \x01(\tR\x03url\"Y\n" +
 	"\bToolCall\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
 	"\x04type\x18\x02 \x01(\tR\x04type\x12)\n" +
@@ -1313,50 +2064,96 @@ const file_ocg_proto_rawDesc = "" +
 	"ToolResult\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
 	"\x04type\x18\x02 \x01(\tR\x04type\x12\x16\n" +
-	"\x06result\x18\x03 \x01(\tR\x06result\"U\n" +
+	"\x06result\x18\x03 \x01(\tR\x06result\"\xb5\x01\n" +
 	"\bChatArgs\x12(\n" +
 	"\bmessages\x18\x01 \x03(\v2\f.ocg.MessageR\bmessages\x12\x1f\n" +
 	"\vsession_key\x18\x02 \x01(\tR\n" +
-	"sessionKey\"J\n" +
+	"sessionKey\x12\x1a\n" +
+	"\bisolated\x18\x03 \x01(\bR\bisolated\x12'\n" +
+	"\x0fresponse_format\x18\x04 \x01(\tR\x0eresponseFormat\x12\x19\n" +
+	"\bagent_id\x18\x05 \x01(\tR\aagentId\"\x85\x01\n" +
 	"\tChatReply\x12\x18\n" +
 	"\acontent\x18\x01 \x01(\tR\acontent\x12#\n" +
-	"\x05tools\x18\x02 \x03(\v2\r.ocg.ToolCallR\x05tools\"?\n" +
+	"\x05tools\x18\x02 \x03(\v2\r.ocg.ToolCallR\x05tools\x12\x16\n" +
+	"\x06object\x18\x03 \x01(\tR\x06object\x12!\n" +
+	"\fformat_error\x18\x04 \x01(\tR\vformatError\"?\n" +
 	"\x0fChatStreamReply\x12\x18\n" +
 	"\acontent\x18\x01 \x01(\tR\acontent\x12\x12\n" +
-	"\x04done\x18\x02 \x01(\bR\x04done\"\v\n" +
-	"\tStatsArgs\"x\n" +
+	"\x04done\x18\x02 \x01(\bR\x04done\"R\n" +
+	"\tStatsArgs\x12$\n" +
+	"\x0eusage_group_by\x18\x01 \x01(\tR\fusageGroupBy\x12\x1f\n" +
+	"\vusage_since\x18\x02 \x01(\x03R\n" +
+	"usageSince\"\x9f\x01\n" +
 	"\n" +
 	"StatsReply\x120\n" +
-	"\x05stats\x18\x01 \x03(\v2\x1a.ocg.StatsReply.StatsEntryR\x05stats\x1a8\n" +
+	"\x05stats\x18\x01 \x03(\v2\x1a.ocg.StatsReply.StatsEntryR\x05stats\x12%\n" +
+	"\x05usage\x18\x02 \x03(\v2\x0f.ocg.UsageTotalR\x05usage\x1a8\n" +
 	"\n" +
 	"StatsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"$\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"\xbf\x01\n" +
+	"\n" +
+	"UsageTotal\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05calls\x18\x02 \x01(\x03R\x05calls\x12#\n" +
+	"\rprompt_tokens\x18\x03 \x01(\x03R\fpromptTokens\x12+\n" +
+	"\x11completion_tokens\x18\x04 \x01(\x03R\x10completionTokens\x12#\n" +
+	"\rcached_tokens\x18\x05 \x01(\x03R\fcachedTokens\x12\x12\n" +
+	"\x04cost\x18\x06 \x01(\x01R\x04cost\"$\n" +
 	"\fSessionsArgs\x12\x14\n" +
 	"\x05limit\x18\x01 \x01(\x05R\x05limit\"S\n" +
 	"\rSessionsReply\x12,\n" +
 	"\bsessions\x18\x01 \x03(\v2\x10.ocg.SessionInfoR\bsessions\x12\x14\n" +
-	"\x05count\x18\x02 \x01(\x05R\x05count\"\x9b\x01\n" +
+	"\x05count\x18\x02 \x01(\x05R\x05count\"\x8d\x02\n" +
 	"\vSessionInfo\x12\x1f\n" +
 	"\vsession_key\x18\x01 \x01(\tR\n" +
 	"sessionKey\x12!\n" +
 	"\ftotal_tokens\x18\x02 \x01(\x05R\vtotalTokens\x12)\n" +
 	"\x10compaction_count\x18\x03 \x01(\x05R\x0fcompactionCount\x12\x1d\n" +
 	"\n" +
-	"updated_at\x18\x04 \x01(\tR\tupdatedAt\"w\n" +
+	"updated_at\x18\x04 \x01(\tR\tupdatedAt\x12,\n" +
+	"\x12parent_session_key\x18\x05 \x01(\tR\x10parentSessionKey\x12&\n" +
+	"\x0ffork_message_id\x18\x06 \x01(\x03R\rforkMessageId\x12\x1a\n" +
+	"\bbranches\x18\a \x03(\tR\bbranches\"y\n" +
+	"\x0fForkSessionArgs\x12\x1f\n" +
+	"\vsession_key\x18\x01 \x01(\tR\n" +
+	"sessionKey\x12&\n" +
+	"\x0fnew_session_key\x18\x02 \x01(\tR\rnewSessionKey\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x03 \x01(\x03R\tmessageId\"\xce\x01\n" +
 	"\x10MemorySearchArgs\x12\x14\n" +
 	"\x05query\x18\x01 \x01(\tR\x05query\x12\x1a\n" +
 	"\bcategory\x18\x02 \x01(\tR\bcategory\x12\x14\n" +
 	"\x05limit\x18\x03 \x01(\x05R\x05limit\x12\x1b\n" +
-	"\tmin_score\x18\x04 \x01(\x02R\bminScore\"#\n" +
+	"\tmin_score\x18\x04 \x01(\x02R\bminScore\x12\x1f\n" +
+	"\vsession_key\x18\x05 \x01(\tR\n" +
+	"sessionKey\x12\x19\n" +
+	"\bagent_id\x18\x06 \x01(\tR\aagentId\x12\x19\n" +
+	"\bown_only\x18\a \x01(\bR\aownOnly\"_\n" +
 	"\rMemoryGetArgs\x12\x12\n" +
-	"\x04path\x18\x01 \x01(\tR\x04path\"a\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1f\n" +
+	"\vsession_key\x18\x02 \x01(\tR\n" +
+	"sessionKey\x12\x19\n" +
+	"\bagent_id\x18\x03 \x01(\tR\aagentId\"\xd4\x01\n" +
 	"\x0fMemoryStoreArgs\x12\x12\n" +
 	"\x04text\x18\x01 \x01(\tR\x04text\x12\x1a\n" +
 	"\bcategory\x18\x02 \x01(\tR\bcategory\x12\x1e\n" +
 	"\n" +
 	"importance\x18\x03 \x01(\x02R\n" +
-	"importance\")\n" +
+	"importance\x12\x1f\n" +
+	"\vsession_key\x18\x04 \x01(\tR\n" +
+	"sessionKey\x12\x19\n" +
+	"\bagent_id\x18\x05 \x01(\tR\aagentId\x12\x16\n" +
+	"\x06global\x18\x06 \x01(\bR\x06global\x12\x1d\n" +
+	"\n" +
+	"share_with\x18\a \x03(\tR\tshareWith\"\x92\x01\n" +
+	"\x10MemoryIngestArgs\x12\x14\n" +
+	"\x05paths\x18\x01 \x03(\tR\x05paths\x12\x1f\n" +
+	"\vsession_key\x18\x02 \x01(\tR\n" +
+	"sessionKey\x12\x19\n" +
+	"\bagent_id\x18\x03 \x01(\tR\aagentId\x12\x16\n" +
+	"\x06global\x18\x04 \x01(\bR\x06global\x12\x14\n" +
+	"\x05force\x18\x05 \x01(\bR\x05force\")\n" +
 	"\x0fToolResultReply\x12\x16\n" +
 	"\x06result\x18\x01 \x01(\tR\x06result\"\x9f\x01\n" +
 	"\tPulseArgs\x12\x16\n" +
@@ -1381,20 +2178,45 @@ const file_ocg_proto_rawDesc = "" +
 	"audio_data\x18\x02 \x01(\fR\taudioData\"\"\n" +
 	"\n" +
 	"AudioReply\x12\x14\n" +
-	"\x05error\x18\x01 \x01(\tR\x05error2\xb7\x04\n" +
+	"\x05error\x18\x01 \x01(\tR\x05error\"\x0f\n" +
+	"\rApprovalsArgs\"\xab\x01\n" +
+	"\x0fApprovalRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1f\n" +
+	"\vsession_key\x18\x02 \x01(\tR\n" +
+	"sessionKey\x12\x12\n" +
+	"\x04tool\x18\x03 \x01(\tR\x04tool\x12\x1c\n" +
+	"\targuments\x18\x04 \x01(\tR\targuments\x12\x16\n" +
+	"\x06reason\x18\x05 \x01(\tR\x06reason\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x06 \x01(\x03R\texpiresAt\"/\n" +
+	"\fCommandsArgs\x12\x1f\n" +
+	"\vsession_key\x18\x01 \x01(\tR\n" +
+	"sessionKey\"y\n" +
+	"\vCommandInfo\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05usage\x18\x02 \x01(\tR\x05usage\x12\x12\n" +
+	"\x04help\x18\x03 \x01(\tR\x04help\x12\x14\n" +
+	"\x05level\x18\x04 \x01(\tR\x05level\x12\x16\n" +
+	"\x06source\x18\x05 \x01(\tR\x06source\"=\n" +
+	"\rCommandsReply\x12,\n" +
+	"\bcommands\x18\x01 \x03(\v2\x10.ocg.CommandInfoR\bcommands2\x9c\x06\n" +
 	"\x05Agent\x12%\n" +
 	"\x04Chat\x12\r.ocg.ChatArgs\x1a\x0e.ocg.ChatReply\x123\n" +
 	"\n" +
 	"ChatStream\x12\r.ocg.ChatArgs\x1a\x14.ocg.ChatStreamReply0\x01\x12(\n" +
 	"\x05Stats\x12\x0e.ocg.StatsArgs\x1a\x0f.ocg.StatsReply\x121\n" +
-	"\bSessions\x12\x11.ocg.SessionsArgs\x1a\x12.ocg.SessionsReply\x12;\n" +
+	"\bSessions\x12\x11.ocg.SessionsArgs\x1a\x12.ocg.SessionsReply\x125\n" +
+	"\vForkSession\x12\x14.ocg.ForkSessionArgs\x1a\x10.ocg.SessionInfo\x12;\n" +
 	"\fMemorySearch\x12\x15.ocg.MemorySearchArgs\x1a\x14.ocg.ToolResultReply\x125\n" +
 	"\tMemoryGet\x12\x12.ocg.MemoryGetArgs\x1a\x14.ocg.ToolResultReply\x129\n" +
-	"\vMemoryStore\x12\x14.ocg.MemoryStoreArgs\x1a\x14.ocg.ToolResultReply\x12+\n" +
+	"\vMemoryStore\x12\x14.ocg.MemoryStoreArgs\x1a\x14.ocg.ToolResultReply\x12;\n" +
+	"\fMemoryIngest\x12\x15.ocg.MemoryIngestArgs\x1a\x14.ocg.ToolResultReply\x12+\n" +
 	"\bPulseAdd\x12\x0e.ocg.PulseArgs\x1a\x0f.ocg.PulseReply\x12.\n" +
 	"\vPulseStatus\x12\x0e.ocg.PulseArgs\x1a\x0f.ocg.PulseReply\x126\n" +
 	"\x0eSendAudioChunk\x12\x13.ocg.AudioChunkArgs\x1a\x0f.ocg.AudioReply\x121\n" +
-	"\x0eEndAudioStream\x12\x0e.ocg.AudioArgs\x1a\x0f.ocg.AudioReplyB&Z$github.com/gliderlab/cogate/rpcprotob\x06proto3"
+	"\x0eEndAudioStream\x12\x0e.ocg.AudioArgs\x1a\x0f.ocg.AudioReply\x12<\n" +
+	"\x0eWatchApprovals\x12\x12.ocg.ApprovalsArgs\x1a\x14.ocg.ApprovalRequest0\x01\x121\n" +
+	"\bCommands\x12\x11.ocg.CommandsArgs\x1a\x12.ocg.CommandsReplyB&Z$github.com/gliderlab/cogate/rpcprotob\x06proto3"
 
 var (
 	file_ocg_proto_rawDescOnce sync.Once
@@ -1408,69 +2230,89 @@ func file_ocg_proto_rawDescGZIP() []byte {
 	return file_ocg_proto_rawDescData
 }
 
-var file_ocg_proto_msgTypes = make([]protoimpl.MessageInfo, 24)
+var file_ocg_proto_msgTypes = make([]protoimpl.MessageInfo, 33)
 var file_ocg_proto_goTypes = []any{
 	(*Message)(nil),          // 0: ocg.Message
-	(*ToolCall)(nil),         // 1: ocg.ToolCall
-	(*Function)(nil),         // 2: ocg.Function
-	(*ToolFunction)(nil),     // 3: ocg.ToolFunction
-	(*Tool)(nil),             // 4: ocg.Tool
-	(*ToolResult)(nil),       // 5: ocg.ToolResult
-	(*ChatArgs)(nil),         // 6: ocg.ChatArgs
-	(*ChatReply)(nil),        // 7: ocg.ChatReply
-	(*ChatStreamReply)(nil),  // 8: ocg.ChatStreamReply
-	(*StatsArgs)(nil),        // 9: ocg.StatsArgs
-	(*StatsReply)(nil),       // 10: ocg.StatsReply
-	(*SessionsArgs)(nil),     // 11: ocg.SessionsArgs
-	(*SessionsReply)(nil),    // 12: ocg.SessionsReply
-	(*SessionInfo)(nil),      // 13: ocg.SessionInfo
-	(*MemorySearchArgs)(nil), // 14: ocg.MemorySearchArgs
-	(*MemoryGetArgs)(nil),    // 15: ocg.MemoryGetArgs
-	(*MemoryStoreArgs)(nil),  // 16: ocg.MemoryStoreArgs
-	(*ToolResultReply)(nil),  // 17: ocg.ToolResultReply
-	(*PulseArgs)(nil),        // 18: ocg.PulseArgs
-	(*PulseReply)(nil),       // 19: ocg.PulseReply
-	(*AudioArgs)(nil),        // 20: ocg.AudioArgs
-	(*AudioChunkArgs)(nil),   // 21: ocg.AudioChunkArgs
-	(*AudioReply)(nil),       // 22: ocg.AudioReply
-	nil,                      // 23: ocg.StatsReply.StatsEntry
+	(*ContentPart)(nil),      // 1: ocg.ContentPart
+	(*ToolCall)(nil),         // 2: ocg.ToolCall
+	(*Function)(nil),         // 3: ocg.Function
+	(*ToolFunction)(nil),     // 4: ocg.ToolFunction
+	(*Tool)(nil),             // 5: ocg.Tool
+	(*ToolResult)(nil),       // 6: ocg.ToolResult
+	(*ChatArgs)(nil),         // 7: ocg.ChatArgs
+	(*ChatReply)(nil),        // 8: ocg.ChatReply
+	(*ChatStreamReply)(nil),  // 9: ocg.ChatStreamReply
+	(*StatsArgs)(nil),        // 10: ocg.StatsArgs
+	(*StatsReply)(nil),       // 11: ocg.StatsReply
+	(*UsageTotal)(nil),       // 12: ocg.UsageTotal
+	(*SessionsArgs)(nil),     // 13: ocg.SessionsArgs
+	(*SessionsReply)(nil),    // 14: ocg.SessionsReply
+	(*SessionInfo)(nil),      // 15: ocg.SessionInfo
+	(*ForkSessionArgs)(nil),  // 16: ocg.ForkSessionArgs
+	(*MemorySearchArgs)(nil), // 17: ocg.MemorySearchArgs
+	(*MemoryGetArgs)(nil),    // 18: ocg.MemoryGetArgs
+	(*MemoryStoreArgs)(nil),  // 19: ocg.MemoryStoreArgs
+	(*MemoryIngestArgs)(nil), // 20: ocg.MemoryIngestArgs
+	(*ToolResultReply)(nil),  // 21: ocg.ToolResultReply
+	(*PulseArgs)(nil),        // 22: ocg.PulseArgs
+	(*PulseReply)(nil),       // 23: ocg.PulseReply
+	(*AudioArgs)(nil),        // 24: ocg.AudioArgs
+	(*AudioChunkArgs)(nil),   // 25: ocg.AudioChunkArgs
+	(*AudioReply)(nil),       // 26: ocg.AudioReply
+	(*ApprovalsArgs)(nil),    // 27: ocg.ApprovalsArgs
+	(*ApprovalRequest)(nil),  // 28: ocg.ApprovalRequest
+	(*CommandsArgs)(nil),     // 29: ocg.CommandsArgs
+	(*CommandInfo)(nil),      // 30: ocg.CommandInfo
+	(*CommandsReply)(nil),    // 31: ocg.CommandsReply
+	nil,                      // 32: ocg.StatsReply.StatsEntry
 }
 var file_ocg_proto_depIdxs = []int32{
-	1,  // 0: ocg.Message.tool_calls:type_name -> ocg.ToolCall
-	5,  // 1: ocg.Message.tool_execution_results:type_name -> ocg.ToolResult
-	2,  // 2: ocg.ToolCall.function:type_name -> ocg.Function
-	3,  // 3: ocg.Tool.function:type_name -> ocg.ToolFunction
-	0,  // 4: ocg.ChatArgs.messages:type_name -> ocg.Message
-	1,  // 5: ocg.ChatReply.tools:type_name -> ocg.ToolCall
-	23, // 6: ocg.StatsReply.stats:type_name -> ocg.StatsReply.StatsEntry
-	13, // 7: ocg.SessionsReply.sessions:type_name -> ocg.SessionInfo
-	6,  // 8: ocg.Agent.Chat:input_type -> ocg.ChatArgs
-	6,  // 9: ocg.Agent.ChatStream:input_type -> ocg.ChatArgs
-	9,  // 10: ocg.Agent.Stats:input_type -> ocg.StatsArgs
-	11, // 11: ocg.Agent.Sessions:input_type -> ocg.SessionsArgs
-	14, // 12: ocg.Agent.MemorySearch:input_type -> ocg.MemorySearchArgs
-	15, // 13: ocg.Agent.MemoryGet:input_type -> ocg.MemoryGetArgs
-	16, // 14: ocg.Agent.MemoryStore:input_type -> ocg.MemoryStoreArgs
-	18, // 15: ocg.Agent.PulseAdd:input_type -> ocg.PulseArgs
-	18, // 16: ocg.Agent.PulseStatus:input_type -> ocg.PulseArgs
-	21, // 17: ocg.Agent.SendAudioChunk:input_type -> ocg.AudioChunkArgs
-	20, // 18: ocg.Agent.EndAudioStream:input_type -> ocg.AudioArgs
-	7,  // 19: ocg.Agent.Chat:output_type -> ocg.ChatReply
-	8,  // 20: ocg.Agent.ChatStream:output_type -> ocg.ChatStreamReply
-	10, // 21: ocg.Agent.Stats:output_type -> ocg.StatsReply
-	12, // 22: ocg.Agent.Sessions:output_type -> ocg.SessionsReply
-	17, // 23: ocg.Agent.MemorySearch:output_type -> ocg.ToolResultReply
-	17, // 24: ocg.Agent.MemoryGet:output_type -> ocg.ToolResultReply
-	17, // 25: ocg.Agent.MemoryStore:output_type -> ocg.ToolResultReply
-	19, // 26: ocg.Agent.PulseAdd:output_type -> ocg.PulseReply
-	19, // 27: ocg.Agent.PulseStatus:output_type -> ocg.PulseReply
-	22, // 28: ocg.Agent.SendAudioChunk:output_type -> ocg.AudioReply
-	22, // 29: ocg.Agent.EndAudioStream:output_type -> ocg.AudioReply
-	19, // [19:30] is the sub-list for method output_type
-	8,  // [8:19] is the sub-list for method input_type
-	8,  // [8:8] is the sub-list for extension type_name
-	8,  // [8:8] is the sub-list for extension extendee
-	0,  // [0:8] is the sub-list for field type_name
+	2,  // 0: ocg.Message.tool_calls:type_name -> ocg.ToolCall
+	6,  // 1: ocg.Message.tool_execution_results:type_name -> ocg.ToolResult
+	1,  // 2: ocg.Message.parts:type_name -> ocg.ContentPart
+	3,  // 3: ocg.ToolCall.function:type_name -> ocg.Function
+	4,  // 4: ocg.Tool.function:type_name -> ocg.ToolFunction
+	0,  // 5: ocg.ChatArgs.messages:type_name -> ocg.Message
+	2,  // 6: ocg.ChatReply.tools:type_name -> ocg.ToolCall
+	32, // 7: ocg.StatsReply.stats:type_name -> ocg.StatsReply.StatsEntry
+	12, // 8: ocg.StatsReply.usage:type_name -> ocg.UsageTotal
+	15, // 9: ocg.SessionsReply.sessions:type_name -> ocg.SessionInfo
+	30, // 10: ocg.CommandsReply.commands:type_name -> ocg.CommandInfo
+	7,  // 11: ocg.Agent.Chat:input_type -> ocg.ChatArgs
+	7,  // 12: ocg.Agent.ChatStream:input_type -> ocg.ChatArgs
+	10, // 13: ocg.Agent.Stats:input_type -> ocg.StatsArgs
+	13, // 14: ocg.Agent.Sessions:input_type -> ocg.SessionsArgs
+	16, // 15: ocg.Agent.ForkSession:input_type -> ocg.ForkSessionArgs
+	17, // 16: ocg.Agent.MemorySearch:input_type -> ocg.MemorySearchArgs
+	18, // 17: ocg.Agent.MemoryGet:input_type -> ocg.MemoryGetArgs
+	19, // 18: ocg.Agent.MemoryStore:input_type -> ocg.MemoryStoreArgs
+	20, // 19: ocg.Agent.MemoryIngest:input_type -> ocg.MemoryIngestArgs
+	22, // 20: ocg.Agent.PulseAdd:input_type -> ocg.PulseArgs
+	22, // 21: ocg.Agent.PulseStatus:input_type -> ocg.PulseArgs
+	25, // 22: ocg.Agent.SendAudioChunk:input_type -> ocg.AudioChunkArgs
+	24, // 23: ocg.Agent.EndAudioStream:input_type -> ocg.AudioArgs
+	27, // 24: ocg.Agent.WatchApprovals:input_type -> ocg.ApprovalsArgs
+	29, // 25: ocg.Agent.Commands:input_type -> ocg.CommandsArgs
+	8,  // 26: ocg.Agent.Chat:output_type -> ocg.ChatReply
+	9,  // 27: ocg.Agent.ChatStream:output_type -> ocg.ChatStreamReply
+	11, // 28: ocg.Agent.Stats:output_type -> ocg.StatsReply
+	14, // 29: ocg.Agent.Sessions:output_type -> ocg.SessionsReply
+	15, // 30: ocg.Agent.ForkSession:output_type -> ocg.SessionInfo
+	21, // 31: ocg.Agent.MemorySearch:output_type -> ocg.ToolResultReply
+	21, // 32: ocg.Agent.MemoryGet:output_type -> ocg.ToolResultReply
+	21, // 33: ocg.Agent.MemoryStore:output_type -> ocg.ToolResultReply
+	21, // 34: ocg.Agent.MemoryIngest:output_type -> ocg.ToolResultReply
+	23, // 35: ocg.Agent.PulseAdd:output_type -> ocg.PulseReply
+	23, // 36: ocg.Agent.PulseStatus:output_type -> ocg.PulseReply
+	26, // 37: ocg.Agent.SendAudioChunk:output_type -> ocg.AudioReply
+	26, // 38: ocg.Agent.EndAudioStream:output_type -> ocg.AudioReply
+	28, // 39: ocg.Agent.WatchApprovals:output_type -> ocg.ApprovalRequest
+	31, // 40: ocg.Agent.Commands:output_type -> ocg.CommandsReply
+	26, // [26:41] is the sub-list for method output_type
+	11, // [11:26] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
 }
 
 func init() { file_ocg_proto_init() }
@@ -1484,7 +2326,7 @@ func file_ocg_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ocg_proto_rawDesc), len(file_ocg_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   24,
+			NumMessages:   33,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -23,13 +23,17 @@ const (
 	Agent_ChatStream_FullMethodName     = "/ocg.Agent/ChatStream"
 	Agent_Stats_FullMethodName          = "/ocg.Agent/Stats"
 	Agent_Sessions_FullMethodName       = "/ocg.Agent/Sessions"
+	Agent_ForkSession_FullMethodName    = "/ocg.Agent/ForkSession"
 	Agent_MemorySearch_FullMethodName   = "/ocg.Agent/MemorySearch"
 	Agent_MemoryGet_FullMethodName      = "/ocg.Agent/MemoryGet"
 	Agent_MemoryStore_FullMethodName    = "/ocg.Agent/MemoryStore"
+	Agent_MemoryIngest_FullMethodName   = "/ocg.Agent/MemoryIngest"
 	Agent_PulseAdd_FullMethodName       = "/ocg.Agent/PulseAdd"
 	Agent_PulseStatus_FullMethodName    = "/ocg.Agent/PulseStatus"
 	Agent_SendAudioChunk_FullMethodName = "/ocg.Agent/SendAudioChunk"
 	Agent_EndAudioStream_FullMethodName = "/ocg.Agent/EndAudioStream"
+	Agent_WatchApprovals_FullMethodName = "/ocg.Agent/WatchApprovals"
+	Agent_Commands_FullMethodName       = "/ocg.Agent/Commands"
 )
 
 // AgentClient is the client API for Agent service.
@@ -40,14 +44,21 @@ type AgentClient interface {
 	ChatStream(ctx context.Context, in *ChatArgs, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChatStreamReply], error)
 	Stats(ctx context.Context, in *StatsArgs, opts ...grpc.CallOption) (*StatsReply, error)
 	Sessions(ctx context.Context, in *SessionsArgs, opts ...grpc.CallOption) (*SessionsReply, error)
+	// Copies a session up to a message into a new session linked to it
+	ForkSession(ctx context.Context, in *ForkSessionArgs, opts ...grpc.CallOption) (*SessionInfo, error)
 	MemorySearch(ctx context.Context, in *MemorySearchArgs, opts ...grpc.CallOption) (*ToolResultReply, error)
 	MemoryGet(ctx context.Context, in *MemoryGetArgs, opts ...grpc.CallOption) (*ToolResultReply, error)
 	MemoryStore(ctx context.Context, in *MemoryStoreArgs, opts ...grpc.CallOption) (*ToolResultReply, error)
+	MemoryIngest(ctx context.Context, in *MemoryIngestArgs, opts ...grpc.CallOption) (*ToolResultReply, error)
 	PulseAdd(ctx context.Context, in *PulseArgs, opts ...grpc.CallOption) (*PulseReply, error)
 	PulseStatus(ctx context.Context, in *PulseArgs, opts ...grpc.CallOption) (*PulseReply, error)
 	// Audio streaming
 	SendAudioChunk(ctx context.Context, in *AudioChunkArgs, opts ...grpc.CallOption) (*AudioReply, error)
 	EndAudioStream(ctx context.Context, in *AudioArgs, opts ...grpc.CallOption) (*AudioReply, error)
+	// Tool calls waiting for human approval, streamed as they are raised
+	WatchApprovals(ctx context.Context, in *ApprovalsArgs, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ApprovalRequest], error)
+	// Slash commands, for /help and channel command menus
+	Commands(ctx context.Context, in *CommandsArgs, opts ...grpc.CallOption) (*CommandsReply, error)
 }
 
 type agentClient struct {
@@ -107,6 +118,16 @@ func (c *agentClient) Sessions(ctx context.Context, in *SessionsArgs, opts ...gr
 	return out, nil
 }
 
+func (c *agentClient) ForkSession(ctx context.Context, in *ForkSessionArgs, opts ...grpc.CallOption) (*SessionInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SessionInfo)
+	err := c.cc.Invoke(ctx, Agent_ForkSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *agentClient) MemorySearch(ctx context.Context, in *MemorySearchArgs, opts ...grpc.CallOption) (*ToolResultReply, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ToolResultReply)
@@ -137,6 +158,16 @@ func (c *agentClient) MemoryStore(ctx context.Context, in *MemoryStoreArgs, opts
 	return out, nil
 }
 
+func (c *agentClient) MemoryIngest(ctx context.Context, in *MemoryIngestArgs, opts ...grpc.CallOption) (*ToolResultReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ToolResultReply)
+	err := c.cc.Invoke(ctx, Agent_MemoryIngest_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *agentClient) PulseAdd(ctx context.Context, in *PulseArgs, opts ...grpc.CallOption) (*PulseReply, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(PulseReply)
@@ -177,6 +208,35 @@ func (c *agentClient) EndAudioStream(ctx context.Context, in *AudioArgs, opts ..
 	return out, nil
 }
 
+func (c *agentClient) WatchApprovals(ctx context.Context, in *ApprovalsArgs, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ApprovalRequest], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Agent_ServiceDesc.Streams[1], Agent_WatchApprovals_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ApprovalsArgs, ApprovalRequest]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Agent_WatchApprovalsClient = grpc.ServerStreamingClient[ApprovalRequest]
+
+func (c *agentClient) Commands(ctx context.Context, in *CommandsArgs, opts ...grpc.CallOption) (*CommandsReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandsReply)
+	err := c.cc.Invoke(ctx, Agent_Commands_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AgentServer is the server API for Agent service.
 // All implementations must embed UnimplementedAgentServer
 // for forward compatibility.
@@ -185,14 +245,21 @@ type AgentServer interface {
 	ChatStream(*ChatArgs, grpc.ServerStreamingServer[ChatStreamReply]) error
 	Stats(context.Context, *StatsArgs) (*StatsReply, error)
 	Sessions(context.Context, *SessionsArgs) (*SessionsReply, error)
+	// Copies a session up to a message into a new session linked to it
+	ForkSession(context.Context, *ForkSessionArgs) (*SessionInfo, error)
 	MemorySearch(context.Context, *MemorySearchArgs) (*ToolResultReply, error)
 	MemoryGet(context.Context, *MemoryGetArgs) (*ToolResultReply, error)
 	MemoryStore(context.Context, *MemoryStoreArgs) (*ToolResultReply, error)
+	MemoryIngest(context.Context, *MemoryIngestArgs) (*ToolResultReply, error)
 	PulseAdd(context.Context, *PulseArgs) (*PulseReply, error)
 	PulseStatus(context.Context, *PulseArgs) (*PulseReply, error)
 	// Audio streaming
 	SendAudioChunk(context.Context, *AudioChunkArgs) (*AudioReply, error)
 	EndAudioStream(context.Context, *AudioArgs) (*AudioReply, error)
+	// Tool calls waiting for human approval, streamed as they are raised
+	WatchApprovals(*ApprovalsArgs, grpc.ServerStreamingServer[ApprovalRequest]) error
+	// Slash commands, for /help and channel command menus
+	Commands(context.Context, *CommandsArgs) (*CommandsReply, error)
 	mustEmbedUnimplementedAgentServer()
 }
 
@@ -215,6 +282,9 @@ func (UnimplementedAgentServer) Stats(context.Context, *StatsArgs) (*StatsReply,
 func (UnimplementedAgentServer) Sessions(context.Context, *SessionsArgs) (*SessionsReply, error) {
 	return nil, status.Error(codes.Unimplemented, "method Sessions not implemented")
 }
+func (UnimplementedAgentServer) ForkSession(context.Context, *ForkSessionArgs) (*SessionInfo, error) {
+	return nil, status.Error(codes.Unimplemented, "method ForkSession not implemented")
+}
 func (UnimplementedAgentServer) MemorySearch(context.Context, *MemorySearchArgs) (*ToolResultReply, error) {
 	return nil, status.Error(codes.Unimplemented, "method MemorySearch not implemented")
 }
@@ -224,6 +294,9 @@ func (UnimplementedAgentServer) MemoryGet(context.Context, *MemoryGetArgs) (*Too
 func (UnimplementedAgentServer) MemoryStore(context.Context, *MemoryStoreArgs) (*ToolResultReply, error) {
 	return nil, status.Error(codes.Unimplemented, "method MemoryStore not implemented")
 }
+func (UnimplementedAgentServer) MemoryIngest(context.Context, *MemoryIngestArgs) (*ToolResultReply, error) {
+	return nil, status.Error(codes.Unimplemented, "method MemoryIngest not implemented")
+}
 func (UnimplementedAgentServer) PulseAdd(context.Context, *PulseArgs) (*PulseReply, error) {
 	return nil, status.Error(codes.Unimplemented, "method PulseAdd not implemented")
 }
@@ -236,6 +309,12 @@ func (UnimplementedAgentServer) SendAudioChunk(context.Context, *AudioChunkArgs)
 func (UnimplementedAgentServer) EndAudioStream(context.Context, *AudioArgs) (*AudioReply, error) {
 	return nil, status.Error(codes.Unimplemented, "method EndAudioStream not implemented")
 }
+func (UnimplementedAgentServer) WatchApprovals(*ApprovalsArgs, grpc.ServerStreamingServer[ApprovalRequest]) error {
+	return status.Error(codes.Unimplemented, "method WatchApprovals not implemented")
+}
+func (UnimplementedAgentServer) Commands(context.Context, *CommandsArgs) (*CommandsReply, error) {
+	return nil, status.Error(codes.Unimplemented, "method Commands not implemented")
+}
 func (UnimplementedAgentServer) mustEmbedUnimplementedAgentServer() {}
 func (UnimplementedAgentServer) testEmbeddedByValue()               {}
 
@@ -322,6 +401,24 @@ func _Agent_Sessions_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Agent_ForkSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ForkSessionArgs)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).ForkSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Agent_ForkSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).ForkSession(ctx, req.(*ForkSessionArgs))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Agent_MemorySearch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(MemorySearchArgs)
 	if err := dec(in); err != nil {
@@ -376,6 +473,24 @@ func _Agent_MemoryStore_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Agent_MemoryIngest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MemoryIngestArgs)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).MemoryIngest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Agent_MemoryIngest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).MemoryIngest(ctx, req.(*MemoryIngestArgs))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Agent_PulseAdd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(PulseArgs)
 	if err := dec(in); err != nil {
@@ -448,6 +563,35 @@ func _Agent_EndAudioStream_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Agent_WatchApprovals_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ApprovalsArgs)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AgentServer).WatchApprovals(m, &grpc.GenericServerStream[ApprovalsArgs, ApprovalRequest]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Agent_WatchApprovalsServer = grpc.ServerStreamingServer[ApprovalRequest]
+
+func _Agent_Commands_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandsArgs)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).Commands(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Agent_Commands_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).Commands(ctx, req.(*CommandsArgs))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Agent_ServiceDesc is the grpc.ServiceDesc for Agent service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -467,6 +611,10 @@ var Agent_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Sessions",
 			Handler:    _Agent_Sessions_Handler,
 		},
+		{
+			MethodName: "ForkSession",
+			Handler:    _Agent_ForkSession_Handler,
+		},
 		{
 			MethodName: "MemorySearch",
 			Handler:    _Agent_MemorySearch_Handler,
@@ -479,6 +627,10 @@ var Agent_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "MemoryStore",
 			Handler:    _Agent_MemoryStore_Handler,
 		},
+		{
+			MethodName: "MemoryIngest",
+			Handler:    _Agent_MemoryIngest_Handler,
+		},
 		{
 			MethodName: "PulseAdd",
 			Handler:    _Agent_PulseAdd_Handler,
@@ -495,6 +647,10 @@ var Agent_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "EndAudioStream",
 			Handler:    _Agent_EndAudioStream_Handler,
 		},
+		{
+			MethodName: "Commands",
+			Handler:    _Agent_Commands_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -502,6 +658,11 @@ var Agent_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _Agent_ChatStream_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "WatchApprovals",
+			Handler:       _Agent_WatchApprovals_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "ocg.proto",
 }
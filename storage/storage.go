@@ -93,6 +93,8 @@ type SessionMeta struct {
 	MemoryFlushAt            time.Time `json:"memory_flush_at"`
 	MemoryFlushCompactionCnt int       `json:"memory_flush_compaction_count"`
 	UpdatedAt                time.Time `json:"updated_at"`
+	ParentSessionKey         string    `json:"parent_session_key,omitempty"` // Session this one was forked from
+	ForkMessageID            int64     `json:"fork_message_id,omitempty"`    // Last parent message copied into the fork
 }
 
 // EventPriority levels (lower = higher priority)
@@ -318,6 +320,9 @@ func (s *Storage) initSchema() error {
 	addColumnSafe(s.db, "session_meta", "provider_type", "TEXT DEFAULT ''")
 	addColumnSafe(s.db, "session_meta", "realtime_last_active_at", "DATETIME")
 	addColumnSafe(s.db, "session_meta", "last_compacted_message_id", "INTEGER DEFAULT 0")
+	// Migration: fork link to the parent session
+	addColumnSafe(s.db, "session_meta", "parent_session_key", "TEXT DEFAULT ''")
+	addColumnSafe(s.db, "session_meta", "fork_message_id", "INTEGER DEFAULT 0")
 
 	// Archive table (optional)
 	_, err = s.db.Exec(`
@@ -503,6 +508,54 @@ func (s *Storage) initSchema() error {
 		return err
 	}
 
+	// LLM provider failover events
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS failover_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			from_provider TEXT NOT NULL,
+			to_provider TEXT NOT NULL,
+			reason TEXT,
+			manual INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_failover_events_created ON failover_events(created_at)`); err != nil {
+		return err
+	}
+
+	// LLM token usage and cost, one row per provider call
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS llm_usage (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_key TEXT NOT NULL DEFAULT '',
+			channel TEXT NOT NULL DEFAULT '',
+			provider TEXT NOT NULL DEFAULT '',
+			model TEXT NOT NULL DEFAULT '',
+			purpose TEXT NOT NULL DEFAULT '',
+			prompt_tokens INTEGER DEFAULT 0,
+			completion_tokens INTEGER DEFAULT 0,
+			cached_tokens INTEGER DEFAULT 0,
+			cost REAL DEFAULT 0,
+			estimated INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_llm_usage_session ON llm_usage(session_key, created_at)`); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_llm_usage_created ON llm_usage(created_at)`); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_llm_usage_channel ON llm_usage(channel, created_at)`); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -578,9 +631,10 @@ func (s *Storage) GetSessionMeta(sessionKey string) (SessionMeta, error) {
 		       COALESCE(last_compacted_message_id, 0),
 		       COALESCE(memory_flush_at, datetime('now')),
 		       COALESCE(memory_flush_compaction_count, 0),
-		       COALESCE(updated_at, datetime('now'))
+		       COALESCE(updated_at, datetime('now')),
+		       COALESCE(parent_session_key, ''), COALESCE(fork_message_id, 0)
 		FROM session_meta WHERE session_key = ?
-	`, sessionKey).Scan(&meta.SessionKey, &meta.ProviderType, &realtimeLastActiveAt, &meta.TotalTokens, &meta.CompactionCount, &meta.LastSummary, &meta.LastCompactedMessageID, &memoryFlushAt, &meta.MemoryFlushCompactionCnt, &updatedAt)
+	`, sessionKey).Scan(&meta.SessionKey, &meta.ProviderType, &realtimeLastActiveAt, &meta.TotalTokens, &meta.CompactionCount, &meta.LastSummary, &meta.LastCompactedMessageID, &memoryFlushAt, &meta.MemoryFlushCompactionCnt, &updatedAt, &meta.ParentSessionKey, &meta.ForkMessageID)
 	if err == sql.ErrNoRows {
 		return SessionMeta{SessionKey: sessionKey}, nil
 	}
@@ -698,7 +752,8 @@ func (s *Storage) GetSessionsForReset(mode string, atHour int, idleMinutes int)
 
 func (s *Storage) GetAllSessions() ([]SessionMeta, error) {
 	rows, err := s.db.Query(`
-		SELECT session_key, total_tokens, compaction_count, last_summary, memory_flush_at, memory_flush_compaction_count, updated_at
+		SELECT session_key, total_tokens, compaction_count, last_summary, memory_flush_at, memory_flush_compaction_count, updated_at,
+		       COALESCE(parent_session_key, ''), COALESCE(fork_message_id, 0)
 		FROM session_meta
 		ORDER BY updated_at DESC
 		LIMIT 50
@@ -711,7 +766,7 @@ func (s *Storage) GetAllSessions() ([]SessionMeta, error) {
 	var sessions []SessionMeta
 	for rows.Next() {
 		var m SessionMeta
-		if err := rows.Scan(&m.SessionKey, &m.TotalTokens, &m.CompactionCount, &m.LastSummary, &m.MemoryFlushAt, &m.MemoryFlushCompactionCnt, &m.UpdatedAt); err != nil {
+		if err := rows.Scan(&m.SessionKey, &m.TotalTokens, &m.CompactionCount, &m.LastSummary, &m.MemoryFlushAt, &m.MemoryFlushCompactionCnt, &m.UpdatedAt, &m.ParentSessionKey, &m.ForkMessageID); err != nil {
 			continue
 		}
 		sessions = append(sessions, m)
@@ -743,6 +798,104 @@ func (s *Storage) GetArchiveStats(sessionKey string) (ArchiveStats, error) {
 	return stats, err
 }
 
+// ForkSession copies a session into newKey: its messages up to and including
+// messageID (0 for all of them), the archived messages before them and its
+// metadata, including the archive watermark. The fork records its parent and
+// fork point; newKey must not be in use yet.
+func (s *Storage) ForkSession(sourceKey, newKey string, messageID int64) (SessionMeta, error) {
+	if sourceKey == newKey {
+		return SessionMeta{}, fmt.Errorf("cannot fork session %s into itself", sourceKey)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return SessionMeta{}, err
+	}
+	defer tx.Rollback()
+
+	var used int
+	if err := tx.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM messages WHERE session_key = ?) + (SELECT COUNT(*) FROM session_meta WHERE session_key = ?)
+	`, newKey, newKey).Scan(&used); err != nil {
+		return SessionMeta{}, err
+	}
+	if used > 0 {
+		return SessionMeta{}, fmt.Errorf("session already exists: %s", newKey)
+	}
+
+	if messageID == 0 {
+		if err := tx.QueryRow("SELECT COALESCE(MAX(id), 0) FROM messages WHERE session_key = ?", sourceKey).Scan(&messageID); err != nil {
+			return SessionMeta{}, err
+		}
+		if messageID == 0 {
+			return SessionMeta{}, fmt.Errorf("session has no messages: %s", sourceKey)
+		}
+	} else {
+		var n int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM messages WHERE session_key = ? AND id = ?", sourceKey, messageID).Scan(&n); err != nil {
+			return SessionMeta{}, err
+		}
+		if n == 0 {
+			return SessionMeta{}, fmt.Errorf("message %d not found in session %s", messageID, sourceKey)
+		}
+	}
+
+	// Copies keep created_at, which orders the history
+	if _, err := tx.Exec(`
+		INSERT INTO messages (session_key, role, content, created_at)
+		SELECT ?, role, content, created_at FROM messages
+		WHERE session_key = ? AND id <= ?
+		ORDER BY id
+	`, newKey, sourceKey, messageID); err != nil {
+		return SessionMeta{}, err
+	}
+	if _, err := tx.Exec(`
+		INSERT OR IGNORE INTO messages_archive (session_key, source_message_id, role, content, created_at)
+		SELECT ?, source_message_id, role, content, created_at FROM messages_archive
+		WHERE session_key = ? AND source_message_id <= ?
+	`, newKey, sourceKey, messageID); err != nil {
+		return SessionMeta{}, err
+	}
+	// The copies get new IDs above the watermark, so it still marks what was archived
+	if _, err := tx.Exec(`
+		INSERT INTO session_meta (session_key, provider_type, realtime_last_active_at, total_tokens, compaction_count, last_summary,
+			last_compacted_message_id, memory_flush_at, memory_flush_compaction_count, parent_session_key, fork_message_id, updated_at)
+		SELECT ?, COALESCE(sm.provider_type, ''), sm.realtime_last_active_at, COALESCE(sm.total_tokens, 0),
+			COALESCE(sm.compaction_count, 0), COALESCE(sm.last_summary, ''), COALESCE(sm.last_compacted_message_id, 0),
+			sm.memory_flush_at, COALESCE(sm.memory_flush_compaction_count, 0), ?, ?, CURRENT_TIMESTAMP
+		FROM (SELECT 1) LEFT JOIN session_meta sm ON sm.session_key = ?
+	`, newKey, sourceKey, messageID, sourceKey); err != nil {
+		return SessionMeta{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return SessionMeta{}, err
+	}
+	return s.GetSessionMeta(newKey)
+}
+
+// GetSessionBranches maps each forked session to the sessions forked from it
+func (s *Storage) GetSessionBranches() (map[string][]string, error) {
+	rows, err := s.db.Query(`
+		SELECT parent_session_key, session_key FROM session_meta
+		WHERE COALESCE(parent_session_key, '') != ''
+		ORDER BY updated_at ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	branches := make(map[string][]string)
+	for rows.Next() {
+		var parent, child string
+		if err := rows.Scan(&parent, &child); err != nil {
+			return nil, err
+		}
+		branches[parent] = append(branches[parent], child)
+	}
+	return branches, rows.Err()
+}
+
 // ============ Memories ============
 
 func (s *Storage) SetMemory(key, text, category string) error {
@@ -948,6 +1101,13 @@ func (s *Storage) Stats() (map[string]int, error) {
 		}
 	}
 
+	// LLM calls and tokens recorded in llm_usage
+	var calls, tokens int
+	if err := s.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(prompt_tokens + completion_tokens), 0) FROM llm_usage`).Scan(&calls, &tokens); err == nil {
+		stats["llm_calls"] = calls
+		stats["llm_tokens"] = tokens
+	}
+
 	return stats, nil
 }
 
@@ -1098,7 +1258,7 @@ func (s *Storage) AddEvent(title, content string, priority EventPriority, channe
 // AddHookEvent adds a new hook event to the database
 // Hook events have PriorityHigh (1) by default for immediate processing
 func (s *Storage) AddHookEvent(eventType, hookName, content, metadata string) (int64, error) {
-	title := fmt.Sprintf("hook:%s:%s", eventType, hookName)
+	title := fmt.Sprintf("hook:%s:%s", strings.TrimPrefix(eventType, "hook:"), hookName)
 	result, err := s.db.Exec(
 		"INSERT INTO events (title, content, priority, status, channel, event_type, hook_name, metadata) VALUES (?, ?, ?, 'pending', '', ?, ?, ?)",
 		title, content, PriorityHigh, eventType, hookName, metadata,
@@ -1111,7 +1271,7 @@ func (s *Storage) AddHookEvent(eventType, hookName, content, metadata string) (i
 
 // GetHookEvents returns hook events by event type and/or hook name
 func (s *Storage) GetHookEvents(eventType, hookName string, limit int) ([]Event, error) {
-	query := "SELECT id, title, content, response, priority, status, channel, created_at, processed_at, event_type, hook_name, metadata FROM events WHERE event_type != ''"
+	query := "SELECT id, title, content, COALESCE(response, ''), priority, status, channel, created_at, processed_at, event_type, hook_name, metadata FROM events WHERE event_type != ''"
 	args := []interface{}{}
 
 	if eventType != "" {
@@ -1151,7 +1311,8 @@ func (s *Storage) GetHookEvents(eventType, hookName string, limit int) ([]Event,
 // GetPendingEvents returns pending events ordered by priority (0 first)
 func (s *Storage) GetPendingEvents(limit int) ([]Event, error) {
 	rows, err := s.db.Query(`
-		SELECT id, title, content, response, priority, status, channel, created_at, processed_at
+		SELECT id, title, content, COALESCE(response, ''), priority, status, channel, created_at, processed_at,
+			COALESCE(event_type, ''), COALESCE(hook_name, ''), COALESCE(metadata, '')
 		FROM events
 		WHERE status = 'pending'
 		ORDER BY priority ASC, created_at ASC
@@ -1166,7 +1327,7 @@ func (s *Storage) GetPendingEvents(limit int) ([]Event, error) {
 	for rows.Next() {
 		var e Event
 		var processedAt sql.NullTime
-		if err := rows.Scan(&e.ID, &e.Title, &e.Content, &e.Response, &e.Priority, &e.Status, &e.Channel, &e.CreatedAt, &processedAt); err != nil {
+		if err := rows.Scan(&e.ID, &e.Title, &e.Content, &e.Response, &e.Priority, &e.Status, &e.Channel, &e.CreatedAt, &processedAt, &e.EventType, &e.HookName, &e.Metadata); err != nil {
 			return nil, err
 		}
 		if processedAt.Valid {
@@ -1182,12 +1343,13 @@ func (s *Storage) GetNextEvent() (*Event, error) {
 	var e Event
 	var processedAt sql.NullTime
 	err := s.db.QueryRow(`
-		SELECT id, title, content, response, priority, status, channel, created_at, processed_at
+		SELECT id, title, content, COALESCE(response, ''), priority, status, channel, created_at, processed_at,
+			COALESCE(event_type, ''), COALESCE(hook_name, ''), COALESCE(metadata, '')
 		FROM events
 		WHERE status = 'pending'
 		ORDER BY priority ASC, created_at ASC
 		LIMIT 1
-	`).Scan(&e.ID, &e.Title, &e.Content, &e.Response, &e.Priority, &e.Status, &e.Channel, &e.CreatedAt, &processedAt)
+	`).Scan(&e.ID, &e.Title, &e.Content, &e.Response, &e.Priority, &e.Status, &e.Channel, &e.CreatedAt, &processedAt, &e.EventType, &e.HookName, &e.Metadata)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -1248,13 +1410,14 @@ func (s *Storage) ClaimNextEventV2() (*Event, error) {
 	var e Event
 	var processedAt sql.NullTime
 	row := tx.QueryRow(`
-		SELECT id, title, content, response, priority, status, channel, created_at, processed_at
+		SELECT id, title, content, COALESCE(response, ''), priority, status, channel, created_at, processed_at,
+			COALESCE(event_type, ''), COALESCE(hook_name, ''), COALESCE(metadata, '')
 		FROM events
 		WHERE status = 'processing'
 		ORDER BY priority ASC, created_at ASC
 		LIMIT 1
 	`)
-	if scanErr := row.Scan(&e.ID, &e.Title, &e.Content, &e.Response, &e.Priority, &e.Status, &e.Channel, &e.CreatedAt, &processedAt); scanErr != nil {
+	if scanErr := row.Scan(&e.ID, &e.Title, &e.Content, &e.Response, &e.Priority, &e.Status, &e.Channel, &e.CreatedAt, &processedAt, &e.EventType, &e.HookName, &e.Metadata); scanErr != nil {
 		if scanErr == sql.ErrNoRows {
 			_ = tx.Rollback()
 			return nil, nil
@@ -1279,12 +1442,13 @@ func (s *Storage) PeekNextEvent() (*Event, error) {
 	var e Event
 	var processedAt sql.NullTime
 	err := s.db.QueryRow(`
-		SELECT id, title, content, response, priority, status, channel, created_at, processed_at
+		SELECT id, title, content, COALESCE(response, ''), priority, status, channel, created_at, processed_at,
+			COALESCE(event_type, ''), COALESCE(hook_name, ''), COALESCE(metadata, '')
 		FROM events
 		WHERE status = 'pending'
 		ORDER BY priority ASC, created_at ASC
 		LIMIT 1
-	`).Scan(&e.ID, &e.Title, &e.Content, &e.Response, &e.Priority, &e.Status, &e.Channel, &e.CreatedAt, &processedAt)
+	`).Scan(&e.ID, &e.Title, &e.Content, &e.Response, &e.Priority, &e.Status, &e.Channel, &e.CreatedAt, &processedAt, &e.EventType, &e.HookName, &e.Metadata)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -1788,7 +1952,8 @@ func (s *Storage) GetUserTask(id string) (*UserTask, error) {
 // GetUserSubtasks gets all subtasks for a user task
 func (s *Storage) GetUserSubtasks(taskID string) ([]UserSubtask, error) {
 	rows, err := s.db.Query(`
-		SELECT id, task_id, index_num, description, status, result, process, started_at, completed_at, error
+		SELECT id, task_id, index_num, description, COALESCE(status, 'pending'), COALESCE(result, ''),
+		       COALESCE(process, ''), started_at, completed_at, COALESCE(error, '')
 		FROM user_subtasks WHERE task_id = ? ORDER BY index_num
 	`, taskID)
 	if err != nil {
@@ -1814,14 +1979,18 @@ func (s *Storage) GetUserSubtasks(taskID string) ([]UserSubtask, error) {
 	return subtasks, nil
 }
 
-// GetPendingSubtask gets the next pending subtask
+// GetPendingSubtask gets the next pending subtask, or nil when none is left
 func (s *Storage) GetPendingSubtask(taskID string) (*UserSubtask, error) {
 	var st UserSubtask
 	var completedAt, startedAt sql.NullInt64
 	err := s.db.QueryRow(`
-		SELECT id, task_id, index_num, description, status, result, process, started_at, completed_at, error
+		SELECT id, task_id, index_num, description, COALESCE(status, 'pending'), COALESCE(result, ''),
+		       COALESCE(process, ''), started_at, completed_at, COALESCE(error, '')
 		FROM user_subtasks WHERE task_id = ? AND status = 'pending' ORDER BY index_num LIMIT 1
 	`, taskID).Scan(&st.ID, &st.TaskID, &st.IndexNum, &st.Description, &st.Status, &st.Result, &st.Process, &startedAt, &completedAt, &st.Error)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -1952,3 +2121,197 @@ func (s *Storage) GetUserTasksBySession(session string, limit int) ([]UserTask,
 	}
 	return tasks, nil
 }
+
+// ============ LLM Failover Events ============
+
+type FailoverEvent struct {
+	ID           int64     `json:"id"`
+	FromProvider string    `json:"from_provider"`
+	ToProvider   string    `json:"to_provider"`
+	Reason       string    `json:"reason"`
+	Manual       bool      `json:"manual"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// AddFailoverEvent records a provider failover
+func (s *Storage) AddFailoverEvent(from, to, reason string, manual bool, at time.Time) (int64, error) {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	result, err := s.db.Exec(`
+		INSERT INTO failover_events (from_provider, to_provider, reason, manual, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, from, to, reason, manual, at.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetFailoverEvents returns the most recent failover events, newest first
+func (s *Storage) GetFailoverEvents(limit int) ([]FailoverEvent, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	rows, err := s.db.Query(`
+		SELECT id, from_provider, to_provider, COALESCE(reason, ''), manual, created_at
+		FROM failover_events
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []FailoverEvent
+	for rows.Next() {
+		var e FailoverEvent
+		if err := rows.Scan(&e.ID, &e.FromProvider, &e.ToProvider, &e.Reason, &e.Manual, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// ============ LLM Usage ============
+
+// UsageRecord is the token usage and cost of one LLM call
+type UsageRecord struct {
+	ID               int64     `json:"id"`
+	SessionKey       string    `json:"session_key"`
+	Channel          string    `json:"channel"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	Purpose          string    `json:"purpose"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	CachedTokens     int       `json:"cached_tokens"`
+	Cost             float64   `json:"cost"`      // USD; 0 when the model has no price
+	Estimated        bool      `json:"estimated"` // Token counts estimated locally (provider sent none)
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// UsageTotal aggregates usage records sharing a group key
+type UsageTotal struct {
+	Key              string  `json:"key"`
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CachedTokens     int     `json:"cached_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// usageGroupColumns maps UsageSummary group names to SQL expressions
+var usageGroupColumns = map[string]string{
+	"":         "'total'",
+	"total":    "'total'",
+	"session":  "session_key",
+	"channel":  "channel",
+	"provider": "provider",
+	"model":    "model",
+	"purpose":  "purpose",
+	"day":      "substr(created_at, 1, 10)",
+}
+
+// AddUsage records the usage of one LLM call
+func (s *Storage) AddUsage(rec UsageRecord) (int64, error) {
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = time.Now()
+	}
+	result, err := s.db.Exec(`
+		INSERT INTO llm_usage (session_key, channel, provider, model, purpose,
+			prompt_tokens, completion_tokens, cached_tokens, cost, estimated, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, rec.SessionKey, rec.Channel, rec.Provider, rec.Model, rec.Purpose,
+		rec.PromptTokens, rec.CompletionTokens, rec.CachedTokens, rec.Cost, rec.Estimated, rec.CreatedAt.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// UsageSummary aggregates usage since the given time (zero = all time), grouped
+// by total, session, channel, provider, model, purpose or day. Groups are
+// ordered by cost, then tokens, highest first.
+func (s *Storage) UsageSummary(groupBy string, since time.Time) ([]UsageTotal, error) {
+	col, ok := usageGroupColumns[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid usage grouping: %s", groupBy)
+	}
+	rows, err := s.db.Query(`
+		SELECT `+col+` AS k, COUNT(*), COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0),
+			COALESCE(SUM(cached_tokens), 0), COALESCE(SUM(cost), 0)
+		FROM llm_usage
+		WHERE created_at >= ?
+		GROUP BY k
+		ORDER BY 6 DESC, 3 + 4 DESC, k
+	`, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []UsageTotal
+	for rows.Next() {
+		var t UsageTotal
+		if err := rows.Scan(&t.Key, &t.Calls, &t.PromptTokens, &t.CompletionTokens, &t.CachedTokens, &t.Cost); err != nil {
+			return nil, err
+		}
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
+
+// UsageFor totals the usage of one session or channel (groupBy "session" or
+// "channel") since the given time
+func (s *Storage) UsageFor(groupBy, key string, since time.Time) (UsageTotal, error) {
+	t := UsageTotal{Key: key}
+	var col string
+	switch groupBy {
+	case "session":
+		col = "session_key"
+	case "channel":
+		col = "channel"
+	default:
+		return t, fmt.Errorf("invalid usage scope: %s", groupBy)
+	}
+	err := s.db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0),
+			COALESCE(SUM(cached_tokens), 0), COALESCE(SUM(cost), 0)
+		FROM llm_usage
+		WHERE `+col+` = ? AND created_at >= ?
+	`, key, since.UTC()).Scan(&t.Calls, &t.PromptTokens, &t.CompletionTokens, &t.CachedTokens, &t.Cost)
+	return t, err
+}
+
+// GetUsage returns the most recent usage records of a session (all sessions if empty), newest first
+func (s *Storage) GetUsage(sessionKey string, limit int) ([]UsageRecord, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	rows, err := s.db.Query(`
+		SELECT id, session_key, channel, provider, model, purpose,
+			prompt_tokens, completion_tokens, cached_tokens, cost, estimated, created_at
+		FROM llm_usage
+		WHERE ? = '' OR session_key = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, sessionKey, sessionKey, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []UsageRecord
+	for rows.Next() {
+		var r UsageRecord
+		if err := rows.Scan(&r.ID, &r.SessionKey, &r.Channel, &r.Provider, &r.Model, &r.Purpose,
+			&r.PromptTokens, &r.CompletionTokens, &r.CachedTokens, &r.Cost, &r.Estimated, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
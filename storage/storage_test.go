@@ -104,3 +104,100 @@ func TestStorageConfig(t *testing.T) {
 		t.Errorf("Expected section 'llm', got '%s'", cfg.Section)
 	}
 }
+
+func TestUsageSummary(t *testing.T) {
+	s, err := New(t.TempDir() + "/usage.db")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer s.Close()
+
+	old := time.Now().Add(-48 * time.Hour)
+	records := []UsageRecord{
+		{SessionKey: "telegram_1", Channel: "telegram", Provider: "openai", Model: "gpt-4o", Purpose: "chat", PromptTokens: 100, CompletionTokens: 20, Cost: 0.01},
+		{SessionKey: "telegram_1", Channel: "telegram", Provider: "openai", Model: "gpt-4o-mini", Purpose: "summarize", PromptTokens: 50, CompletionTokens: 10, CachedTokens: 40, Cost: 0.001},
+		{SessionKey: "cron:job-1", Channel: "cron", Provider: "anthropic", Model: "claude-sonnet-4", Purpose: "chat", PromptTokens: 10, CompletionTokens: 5, Cost: 0.02, Estimated: true},
+		{SessionKey: "cron:job-1", Channel: "cron", Provider: "anthropic", Model: "claude-sonnet-4", Purpose: "chat", PromptTokens: 1000, CompletionTokens: 500, Cost: 1, CreatedAt: old},
+	}
+	for _, r := range records {
+		if _, err := s.AddUsage(r); err != nil {
+			t.Fatalf("AddUsage: %v", err)
+		}
+	}
+
+	byChannel, err := s.UsageSummary("channel", time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("UsageSummary: %v", err)
+	}
+	if len(byChannel) != 2 || byChannel[0].Key != "cron" || byChannel[0].Calls != 1 || byChannel[0].PromptTokens != 10 {
+		t.Fatalf("by channel = %+v", byChannel)
+	}
+	if tg := byChannel[1]; tg.Calls != 2 || tg.PromptTokens != 150 || tg.CachedTokens != 40 {
+		t.Errorf("telegram total = %+v", tg)
+	}
+
+	total, err := s.UsageSummary("total", time.Time{})
+	if err != nil || len(total) != 1 || total[0].Calls != 4 || total[0].Cost < 1.03 {
+		t.Fatalf("total = %+v, %v", total, err)
+	}
+
+	if _, err := s.UsageSummary("bogus", time.Time{}); err == nil {
+		t.Error("expected error for unknown grouping")
+	}
+
+	recent, err := s.GetUsage("cron:job-1", 10)
+	if err != nil || len(recent) != 2 || !recent[0].Estimated || recent[1].PromptTokens != 1000 {
+		t.Fatalf("GetUsage = %+v, %v", recent, err)
+	}
+}
+
+func TestForkSession(t *testing.T) {
+	s, err := New(t.TempDir() + "/fork.db")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer s.Close()
+
+	for _, c := range []string{"one", "two", "three", "four"} {
+		if err := s.AddMessage("main", "user", c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	msgs, _ := s.GetMessages("main", 10)
+	if err := s.ArchiveMessages("main", msgs[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpsertSessionMeta(SessionMeta{SessionKey: "main", TotalTokens: 42, LastSummary: "so far", LastCompactedMessageID: msgs[1].ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := s.ForkSession("main", "alt", msgs[2].ID)
+	if err != nil {
+		t.Fatalf("ForkSession: %v", err)
+	}
+	if meta.ParentSessionKey != "main" || meta.ForkMessageID != msgs[2].ID || meta.TotalTokens != 42 || meta.LastSummary != "so far" || meta.LastCompactedMessageID != msgs[1].ID {
+		t.Errorf("fork meta = %+v", meta)
+	}
+	forked, _ := s.GetMessages("alt", 10)
+	if len(forked) != 3 || forked[2].Content != "three" || forked[0].ID <= msgs[3].ID {
+		t.Errorf("fork messages = %+v", forked)
+	}
+	if stats, _ := s.GetArchiveStats("alt"); stats.ArchivedCount != 2 {
+		t.Errorf("fork archive = %+v", stats)
+	}
+
+	if _, err := s.ForkSession("main", "alt", 0); err == nil {
+		t.Error("forked into an existing session")
+	}
+	if _, err := s.ForkSession("main", "other", msgs[3].ID+100); err == nil {
+		t.Error("forked at a message of another session")
+	}
+	if latest, err := s.ForkSession("alt", "alt-2", 0); err != nil || latest.ForkMessageID != forked[2].ID {
+		t.Errorf("fork at latest = %+v, %v", latest, err)
+	}
+
+	branches, err := s.GetSessionBranches()
+	if err != nil || len(branches["main"]) != 1 || branches["main"][0] != "alt" || branches["alt"][0] != "alt-2" {
+		t.Errorf("branches = %v, %v", branches, err)
+	}
+}
@@ -3,12 +3,16 @@
 package adapter
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"reflect"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/gliderlab/cogate/pkg/commands"
 )
 
 // PluginInfo contains metadata about a plugin
@@ -39,6 +43,17 @@ type PluginLoader interface {
 	HealthCheck() error
 }
 
+// ContextPlugin is implemented by plugins that stop their work when ctx is done
+type ContextPlugin interface {
+	ExecuteContext(ctx context.Context, args map[string]interface{}) (interface{}, error)
+}
+
+// CommandPlugin is implemented by plugins that add slash commands; they are
+// registered with commands.Default while the plugin is loaded
+type CommandPlugin interface {
+	Commands() []commands.Command
+}
+
 // PluginBase provides common functionality for plugins
 type PluginBase struct {
 	mu     sync.RWMutex
@@ -178,6 +193,15 @@ func (a *ToolAdapter) RegisterPlugin(name string, plugin PluginLoader) error {
 	a.plugins[name] = plugin
 	a.registry.Add(info)
 
+	if cp, ok := plugin.(CommandPlugin); ok {
+		for _, c := range cp.Commands() {
+			c.Source = "plugin"
+			if err := commands.Register(c); err != nil {
+				log.Printf("[WARN] plugin %s: %v", name, err)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -199,11 +223,24 @@ func (a *ToolAdapter) UnregisterPlugin(name string) error {
 	delete(a.plugins, name)
 	a.registry.Remove(name)
 
+	if cp, ok := plugin.(CommandPlugin); ok {
+		for _, c := range cp.Commands() {
+			commands.Default.Unregister(c.Name)
+		}
+	}
+
 	return nil
 }
 
 // ExecuteTool runs a tool by name
 func (a *ToolAdapter) ExecuteTool(name string, args map[string]interface{}, ctx *Context) *Result {
+	return a.ExecuteToolContext(context.Background(), name, args, ctx)
+}
+
+// ExecuteToolContext runs a tool by name under runCtx, bounded by the
+// configured default timeout. Plugins implementing ContextPlugin are
+// cancelled; for the others the result is abandoned once runCtx is done.
+func (a *ToolAdapter) ExecuteToolContext(runCtx context.Context, name string, args map[string]interface{}, ctx *Context) *Result {
 	a.mu.RLock()
 	plugin, exists := a.plugins[name]
 	a.mu.RUnlock()
@@ -223,13 +260,45 @@ func (a *ToolAdapter) ExecuteTool(name string, args map[string]interface{}, ctx
 		}
 	}
 
-	result, err := plugin.Execute(args)
+	if a.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(runCtx, time.Duration(a.config.Timeout)*time.Second)
+		defer cancel()
+	}
+
+	result, err := executePlugin(runCtx, plugin, args)
 	if err != nil {
 		return NewErrorResult(err)
 	}
 	return NewResult(result)
 }
 
+// executePlugin runs plugin under ctx, returning ctx.Err() once ctx is done
+func executePlugin(ctx context.Context, plugin PluginLoader, args map[string]interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if cp, ok := plugin.(ContextPlugin); ok {
+		return cp.ExecuteContext(ctx, args)
+	}
+
+	type outcome struct {
+		result interface{}
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := plugin.Execute(args)
+		done <- outcome{result, err}
+	}()
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 // GetToolSpec returns the OpenAI-compatible tool specification
 func (a *ToolAdapter) GetToolSpec(name string) (*ToolSpec, error) {
 	a.mu.RLock()
@@ -0,0 +1,54 @@
+// approval.go - "ask" policy: tool calls that wait for a human to approve them
+package tools
+
+import (
+	"encoding/json"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// NeedsApproval reports whether the registry's policy holds a call for approval
+func (r *Registry) NeedsApproval(name string, args map[string]interface{}) (bool, string) {
+	return r.policy.NeedsApproval(name, args)
+}
+
+// NeedsApproval reports whether a call must be approved before it runs and
+// the rule that matched. Ask entries are tool names, groups, "*" or
+// tool:action pairs ("process:start", "gateway:update.run") matched against
+// the "action" argument; AskPatterns match "tool {json args}". A nil policy
+// asks for nothing.
+func (p *ToolsPolicy) NeedsApproval(name string, args map[string]interface{}) (bool, string) {
+	if p == nil {
+		return false, ""
+	}
+	action := GetString(args, "action")
+	for _, entry := range p.Ask {
+		if entry == "*" || entry == name || (action != "" && entry == name+":"+action) {
+			return true, entry
+		}
+		if strings.HasPrefix(entry, "group:") {
+			for _, member := range ToolGroups[entry] {
+				if member == name {
+					return true, entry
+				}
+			}
+		}
+	}
+	if len(p.AskPatterns) == 0 {
+		return false, ""
+	}
+	data, _ := json.Marshal(args)
+	call := name + " " + string(data)
+	for _, pattern := range p.AskPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Printf("[WARN] invalid ask pattern %q: %v", pattern, err)
+			continue
+		}
+		if re.MatchString(call) {
+			return true, pattern
+		}
+	}
+	return false, ""
+}
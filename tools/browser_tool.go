@@ -4,6 +4,7 @@ package tools
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -182,6 +183,21 @@ func (t *BrowserTool) Parameters() map[string]interface{} {
 	}
 }
 
+// ExecuteContext runs a browser action under ctx. "wait" stops polling when
+// ctx is done; other actions are single CDP calls left to finish on their own.
+func (t *BrowserTool) ExecuteContext(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	if GetString(args, "action") == "wait" {
+		profile := GetString(args, "profile")
+		if profile == "" {
+			profile = t.config.DefaultProfile
+		}
+		return t.service.WaitContext(ctx, args, profile)
+	}
+	return callWithContext(ctx, func() (interface{}, error) {
+		return t.Execute(args)
+	})
+}
+
 func (t *BrowserTool) Execute(args map[string]interface{}) (interface{}, error) {
 	action := GetString(args, "action")
 	if action == "" {
@@ -759,6 +775,11 @@ func (s *BrowserService) Fill(args map[string]interface{}, profile string) (inte
 }
 
 func (s *BrowserService) Wait(args map[string]interface{}, profile string) (interface{}, error) {
+	return s.WaitContext(context.Background(), args, profile)
+}
+
+// WaitContext is Wait that gives up when ctx is done
+func (s *BrowserService) WaitContext(ctx context.Context, args map[string]interface{}, profile string) (interface{}, error) {
 	cdpPort, err := s.getCDPPort(profile)
 	if err != nil {
 		return nil, err
@@ -808,7 +829,11 @@ func (s *BrowserService) Wait(args map[string]interface{}, profile string) (inte
 				}
 			}
 		}
-		time.Sleep(500 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
 	}
 }
 
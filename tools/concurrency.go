@@ -0,0 +1,48 @@
+// concurrency.go - Concurrency classes for running the tool calls of one turn in parallel
+package tools
+
+import "path/filepath"
+
+// ConcurrencyClass says how a tool call may overlap with the other calls of a turn
+type ConcurrencyClass string
+
+const (
+	// ConcurrencySafe tools (read-only or network) run alongside any other safe or path call
+	ConcurrencySafe ConcurrencyClass = "safe"
+	// ConcurrencyExclusive tools run alone, after every earlier call of the turn finished
+	ConcurrencyExclusive ConcurrencyClass = "exclusive"
+	// ConcurrencyPath tools run in parallel, except that calls on the same path run in order
+	ConcurrencyPath ConcurrencyClass = "path"
+)
+
+// ConcurrentTool is implemented by tools that declare a concurrency class.
+// Tools that don't are exclusive.
+type ConcurrentTool interface {
+	Concurrency() ConcurrencyClass
+}
+
+// Concurrency returns the class of a call and, for path tools, its lock key:
+// the absolute form of the "path" argument.
+func (r *Registry) Concurrency(name string, args map[string]interface{}) (ConcurrencyClass, string) {
+	t, ok := r.Get(name)
+	if !ok {
+		return ConcurrencyExclusive, ""
+	}
+	ct, ok := t.(ConcurrentTool)
+	if !ok {
+		return ConcurrencyExclusive, ""
+	}
+	class := ct.Concurrency()
+	switch class {
+	case ConcurrencySafe:
+		return class, ""
+	case ConcurrencyPath:
+		path := GetString(args, "path")
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		return class, path
+	default:
+		return ConcurrencyExclusive, ""
+	}
+}
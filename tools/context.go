@@ -0,0 +1,97 @@
+// context.go - Cancellable tool execution (/stop, client disconnects, cron timeouts)
+package tools
+
+import (
+	"context"
+
+	"github.com/gliderlab/cogate/memory"
+)
+
+// ContextTool is implemented by tools that stop their work when ctx is done.
+// Their Execute runs with a background context.
+type ContextTool interface {
+	ExecuteContext(ctx context.Context, args map[string]interface{}) (interface{}, error)
+}
+
+// ExecuteContext runs t under ctx. A tool that doesn't implement ContextTool
+// keeps running in the background once ctx is done, but the caller gets
+// ctx.Err() right away instead of waiting for it.
+func ExecuteContext(ctx context.Context, t Tool, args map[string]interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if ct, ok := t.(ContextTool); ok {
+		return ct.ExecuteContext(ctx, args)
+	}
+	return callWithContext(ctx, func() (interface{}, error) {
+		return t.Execute(args)
+	})
+}
+
+type sessionKeyCtx struct{}
+
+// WithSessionKey tags a run context with the session its tool calls act for
+func WithSessionKey(ctx context.Context, sessionKey string) context.Context {
+	return context.WithValue(ctx, sessionKeyCtx{}, sessionKey)
+}
+
+// SessionKeyFrom returns the session a tool call runs in, or ""
+func SessionKeyFrom(ctx context.Context) string {
+	key, _ := ctx.Value(sessionKeyCtx{}).(string)
+	return key
+}
+
+type memoryScopeCtx struct{}
+
+// WithMemoryScope confines the memory tools of a run to the memories of one
+// owner (the caller's session) in one agent namespace
+func WithMemoryScope(ctx context.Context, scope memory.Scope) context.Context {
+	return context.WithValue(ctx, memoryScopeCtx{}, scope)
+}
+
+// MemoryScopeFrom returns the memory scope of a run; ok is false when the
+// run isn't confined and the memory tools see every memory
+func MemoryScopeFrom(ctx context.Context) (scope memory.Scope, ok bool) {
+	scope, ok = ctx.Value(memoryScopeCtx{}).(memory.Scope)
+	return scope, ok
+}
+
+type memoryAdminCtx struct{}
+
+// WithMemoryAdmin records whether a confined run may write outside its own
+// scope: global memories and documents, and memories shared with others
+func WithMemoryAdmin(ctx context.Context, admin bool) context.Context {
+	return context.WithValue(ctx, memoryAdminCtx{}, admin)
+}
+
+// memoryAdmin reports whether a run may write outside its memory scope;
+// runs that aren't confined always may
+func memoryAdmin(ctx context.Context) bool {
+	if _, ok := MemoryScopeFrom(ctx); !ok {
+		return true
+	}
+	admin, _ := ctx.Value(memoryAdminCtx{}).(bool)
+	return admin
+}
+
+// callWithContext runs fn and returns its result, or ctx.Err() if ctx is done first
+func callWithContext(ctx context.Context, fn func() (interface{}, error)) (interface{}, error) {
+	if ctx.Done() == nil {
+		return fn()
+	}
+	type outcome struct {
+		result interface{}
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := fn()
+		done <- outcome{result, err}
+	}()
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
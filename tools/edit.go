@@ -13,6 +13,10 @@ func (t *EditTool) Name() string {
 	return "edit"
 }
 
+func (t *EditTool) Concurrency() ConcurrencyClass {
+	return ConcurrencyPath
+}
+
 func (t *EditTool) Description() string {
 	return "Precisely replace a text snippet. oldText must match exactly and appear only once."
 }
@@ -47,6 +47,11 @@ func (t *ExecTool) Parameters() map[string]interface{} {
 }
 
 func (t *ExecTool) Execute(args map[string]interface{}) (interface{}, error) {
+	return t.ExecuteContext(context.Background(), args)
+}
+
+// ExecuteContext runs the command and kills it when ctx is done
+func (t *ExecTool) ExecuteContext(parent context.Context, args map[string]interface{}) (interface{}, error) {
 	command := GetString(args, "command")
 	timeout := GetInt(args, "timeout")
 	workdir := GetString(args, "workdir")
@@ -93,7 +98,7 @@ func (t *ExecTool) Execute(args map[string]interface{}) (interface{}, error) {
 		return nil, &ExecError{Message: "timeout cannot exceed 300 seconds"}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	ctx, cancel := context.WithTimeout(parent, time.Duration(timeout)*time.Second)
 	defer cancel()
 
 	allowShell := strings.ToLower(strings.TrimSpace(os.Getenv("OCG_EXEC_ALLOW_SHELL"))) == "true"
@@ -158,6 +163,8 @@ func (t *ExecTool) Execute(args map[string]interface{}) (interface{}, error) {
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	// Don't wait on output pipes held open by children of a killed shell
+	cmd.WaitDelay = time.Second
 
 	runErr := cmd.Run()
 
@@ -175,6 +182,12 @@ func (t *ExecTool) Execute(args map[string]interface{}) (interface{}, error) {
 	result.Stdout = Truncate(stdout.String(), 10000)
 	result.Stderr = Truncate(stderr.String(), 2000)
 
+	if ctx.Err() == context.Canceled {
+		return nil, &ExecError{
+			Message:  "command cancelled",
+			Metadata: map[string]interface{}{"command": command},
+		}
+	}
 	if ctx.Err() == context.DeadlineExceeded {
 		return nil, &ExecError{
 			Message:  "command timed out",
@@ -65,6 +65,10 @@ func (t *ImageTool) Name() string {
 	return "image"
 }
 
+func (t *ImageTool) Concurrency() ConcurrencyClass {
+	return ConcurrencySafe
+}
+
 func (t *ImageTool) Description() string {
 	return "Analyze images using vision models. Supports local paths and URLs. Returns detailed description."
 }
@@ -2,8 +2,10 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -23,6 +25,8 @@ func NewMemoryTool(store *memory.VectorMemoryStore) *MemoryTool {
 
 func (t *MemoryTool) Name() string { return "memory_search" }
 
+func (t *MemoryTool) Concurrency() ConcurrencyClass { return ConcurrencySafe }
+
 func (t *MemoryTool) Description() string {
 	return "Search long-term memory (vector search) and return similarity scores."
 }
@@ -37,10 +41,10 @@ func (t *MemoryTool) Parameters() map[string]interface{} {
 			},
 			"category": map[string]interface{}{
 				"type":        "string",
-				"description": "Optional category filter (preference/decision/fact/entity/other)",
+				"description": "Optional category filter (preference/decision/fact/entity/other, or document for ingested files)",
 			},
 			"limit": map[string]interface{}{
-				"type":        "integer",
+				"type": "integer",
 				"description": "Max results (default 5)",
 				"default":     5,
 			},
@@ -49,12 +53,23 @@ func (t *MemoryTool) Parameters() map[string]interface{} {
 				"description": "Min similarity 0-1 (default 0.7)",
 				"default":     0.7,
 			},
+			"scope": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"all", "own"},
+				"description": "all: your memories plus global and shared ones (default); own: only your memories",
+			},
 		},
 		"required": []string{"query"},
 	}
 }
 
 func (t *MemoryTool) Execute(args map[string]interface{}) (interface{}, error) {
+	return t.ExecuteContext(context.Background(), args)
+}
+
+// ExecuteContext searches the memories the run's scope allows, or every
+// memory when the run isn't confined
+func (t *MemoryTool) ExecuteContext(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	query := GetString(args, "query")
 	category := GetString(args, "category")
 	limit := GetInt(args, "limit")
@@ -74,7 +89,16 @@ func (t *MemoryTool) Execute(args map[string]interface{}) (interface{}, error) {
 		return nil, fmt.Errorf("memory store is not initialized")
 	}
 
-	results, err := t.Store.Search(query, limit, float32(minScore))
+	var results []memory.MemoryResult
+	var err error
+	if scope, ok := MemoryScopeFrom(ctx); ok {
+		if GetString(args, "scope") == "own" {
+			scope.Global, scope.Shared = false, false
+		}
+		results, err = t.Store.SearchScope(scope, query, limit, float32(minScore))
+	} else {
+		results, err = t.Store.Search(query, limit, float32(minScore))
+	}
 	if err != nil {
 		return nil, fmt.Errorf("search failed: %v", err)
 	}
@@ -98,8 +122,7 @@ func (t *MemoryTool) Execute(args map[string]interface{}) (interface{}, error) {
 	items := make([]map[string]interface{}, 0, len(results))
 	for i, r := range results {
 		scorePct := int(r.Score * 100)
-		resultText += fmt.Sprintf("%d. [%s] %s (similarity %d%%)\n", i+1, r.Entry.Category, r.Entry.Text, scorePct)
-		items = append(items, map[string]interface{}{
+		item := map[string]interface{}{
 			"id":         r.Entry.ID,
 			"text":       r.Entry.Text,
 			"category":   r.Entry.Category,
@@ -107,9 +130,17 @@ func (t *MemoryTool) Execute(args map[string]interface{}) (interface{}, error) {
 			"score":      fmt.Sprintf("%.4f", r.Score),
 			"matched":    r.Matched,
 			"source":     r.Entry.Source,
+			"owner":      ownerLabel(r.Entry.Owner),
 			"createdAt":  time.Unix(r.Entry.CreatedAt, 0).Format("2006-01-02 15:04"),
 			"updatedAt":  time.Unix(r.Entry.UpdatedAt, 0).Format("2006-01-02 15:04"),
-		})
+		}
+		if c := r.Entry.Citation; c != nil {
+			item["citation"] = c.String()
+			resultText += fmt.Sprintf("%d. [%s] %s (similarity %d%%)\n", i+1, c, r.Entry.Text, scorePct)
+		} else {
+			resultText += fmt.Sprintf("%d. [%s] %s (similarity %d%%)\n", i+1, r.Entry.Category, r.Entry.Text, scorePct)
+		}
+		items = append(items, item)
 	}
 
 	return MemorySearchResult{Query: query, Count: len(results), Items: items, Result: resultText}, nil
@@ -127,6 +158,8 @@ func NewMemoryGetTool(store *memory.VectorMemoryStore) *MemoryGetTool {
 
 func (t *MemoryGetTool) Name() string { return "memory_get" }
 
+func (t *MemoryGetTool) Concurrency() ConcurrencyClass { return ConcurrencySafe }
+
 func (t *MemoryGetTool) Description() string {
 	return "Get details of a single memory."
 }
@@ -145,6 +178,12 @@ func (t *MemoryGetTool) Parameters() map[string]interface{} {
 }
 
 func (t *MemoryGetTool) Execute(args map[string]interface{}) (interface{}, error) {
+	return t.ExecuteContext(context.Background(), args)
+}
+
+// ExecuteContext returns a memory the run's scope allows; one outside it is
+// reported as not found
+func (t *MemoryGetTool) ExecuteContext(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	id := GetString(args, "path")
 	if id == "" {
 		return nil, fmt.Errorf("path is required")
@@ -157,16 +196,39 @@ func (t *MemoryGetTool) Execute(args map[string]interface{}) (interface{}, error
 	if err != nil {
 		return nil, fmt.Errorf("memory not found or failed to fetch: %v", err)
 	}
+	if scope, ok := MemoryScopeFrom(ctx); ok && !scope.Allows(entry.Ownership()) {
+		return nil, fmt.Errorf("memory not found or failed to fetch: %s", id)
+	}
 
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"id":         entry.ID,
 		"text":       entry.Text,
 		"category":   entry.Category,
 		"importance": entry.Importance,
 		"source":     entry.Source,
+		"owner":      ownerLabel(entry.Owner),
+		"sharedWith": entry.SharedWith,
 		"createdAt":  time.Unix(entry.CreatedAt, 0).Format("2006-01-02 15:04:05"),
 		"updatedAt":  time.Unix(entry.UpdatedAt, 0).Format("2006-01-02 15:04:05"),
-	}, nil
+	}
+	if c := entry.Citation; c != nil {
+		result["citation"] = c.String()
+		result["heading"] = c.Heading
+	}
+	// Provenance: memories consolidation merged into this one
+	if merged, err := t.Store.MergedFrom(entry.ID); err == nil && len(merged) > 0 {
+		from := make([]map[string]interface{}, 0, len(merged))
+		for _, m := range merged {
+			from = append(from, map[string]interface{}{
+				"id":        m.ID,
+				"text":      m.Text,
+				"source":    m.Source,
+				"createdAt": time.Unix(m.CreatedAt, 0).Format("2006-01-02 15:04:05"),
+			})
+		}
+		result["mergedFrom"] = from
+	}
+	return result, nil
 }
 
 // ===================== memory_store =====================
@@ -203,12 +265,27 @@ func (t *MemoryStoreTool) Parameters() map[string]interface{} {
 				"description": "Importance 0-1",
 				"default":     0.7,
 			},
+			"global": map[string]interface{}{
+				"type": "boolean",
+				"description": "Make the memory visible to every user instead of only this conversation (admins only, default false)",
+			},
+			"shareWith": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Session keys of other conversations that may also see the memory (admins only)",
+			},
 		},
 		"required": []string{"text"},
 	}
 }
 
 func (t *MemoryStoreTool) Execute(args map[string]interface{}) (interface{}, error) {
+	return t.ExecuteContext(context.Background(), args)
+}
+
+// ExecuteContext stores a memory owned by the run's scope (global in the
+// shared default namespace when the run isn't confined)
+func (t *MemoryStoreTool) ExecuteContext(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	text := GetString(args, "text")
 	category := GetString(args, "category")
 	importance := GetFloat64(args, "importance")
@@ -225,8 +302,21 @@ func (t *MemoryStoreTool) Execute(args map[string]interface{}) (interface{}, err
 		return nil, fmt.Errorf("memory store is not initialized")
 	}
 
-	// Approximate duplicate detection (similarity > 0.95)
-	results, _ := t.Store.Search(text, 3, 0.95)
+	// Global and shared memories are recalled into other users' prompts
+	scope, _ := MemoryScopeFrom(ctx)
+	shareWith := GetStringSlice(args, "shareWith")
+	if (GetBool(args, "global") || len(shareWith) > 0) && !memoryAdmin(ctx) {
+		return nil, fmt.Errorf("only admins can store global or shared memories")
+	}
+	if GetBool(args, "global") {
+		scope.Owner = ""
+	}
+	owner := scope.Ownership()
+	owner.SharedWith = shareWith
+
+	// Approximate duplicate detection (similarity > 0.95) among the memories
+	// of the same owner
+	results, _ := t.Store.SearchScope(memory.Scope{Namespace: owner.Namespace, Owner: owner.Owner}, text, 3, 0.95)
 	for _, r := range results {
 		if strings.TrimSpace(r.Entry.Text) == strings.TrimSpace(text) {
 			return map[string]interface{}{
@@ -237,7 +327,7 @@ func (t *MemoryStoreTool) Execute(args map[string]interface{}) (interface{}, err
 		}
 	}
 
-	id, err := t.Store.Store(text, category, importance)
+	id, err := t.Store.StoreOwned(owner, text, category, importance, "manual")
 	if err != nil {
 		return nil, fmt.Errorf("store failed: %v", err)
 	}
@@ -252,6 +342,14 @@ func (t *MemoryStoreTool) Execute(args map[string]interface{}) (interface{}, err
 
 // ===================== Helpers =====================
 
+// ownerLabel names a memory's owner for tool output
+func ownerLabel(owner string) string {
+	if owner == "" {
+		return "global"
+	}
+	return owner
+}
+
 type MemorySearchResult struct {
 	Query  string                   `json:"query"`
 	Count  int                      `json:"count"`
@@ -273,7 +371,7 @@ func ShouldCapture(text string) bool {
 	if len(text) < 10 || len(text) > 500 {
 		return false
 	}
-	if strings.Contains(text, "<relevant-memories>") {
+	if strings.Contains(text, "<relevant-memories>") || strings.Contains(text, "<related-facts>") {
 		return false
 	}
 	if strings.HasPrefix(text, "<") && strings.Contains(text, "</") {
@@ -333,18 +431,31 @@ func FindRelevantMemories(store *memory.VectorMemoryStore, prompt string, limit
 	return results, nil
 }
 
-// Format memories for context injection
+// Format memories for context injection; document chunks are labelled
+// with the file and lines they come from
 func FormatMemoriesForContext(results []memory.MemoryResult) string {
 	if len(results) == 0 {
 		return ""
 	}
 	lines := make([]string, 0, len(results))
 	for _, r := range results {
-		lines = append(lines, fmt.Sprintf("- [%s] %s", r.Entry.Category, r.Entry.Text))
+		label := r.Entry.Category
+		if c := r.Entry.Citation; c != nil {
+			label = c.String()
+		}
+		lines = append(lines, fmt.Sprintf("- [%s] %s", label, r.Entry.Text))
 	}
 	return fmt.Sprintf("<relevant-memories>\nThe following memories may be relevant to the current conversation:\n%s\n</relevant-memories>", strings.Join(lines, "\n"))
 }
 
+// Format knowledge-graph facts for context injection, after the memories
+func FormatGraphFactsForContext(facts []string) string {
+	if len(facts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("<related-facts>\nKnown relations between entities in the conversation and memories:\n- %s\n</related-facts>", strings.Join(facts, "\n- "))
+}
+
 // Keyword extraction (very simple)
 func extractKeywords(prompt string) []string {
 	stopWords := map[string]bool{
@@ -399,7 +510,7 @@ func NewMemoryGraphTool(store *memory.VectorMemoryStore) *MemoryGraphTool {
 func (t *MemoryGraphTool) Name() string { return "memory_graph" }
 
 func (t *MemoryGraphTool) Description() string {
-	return `Interact with the Knowledge Graph. Use this to explicitly extract and save structured relationships (Entities and Relations) about the user, projects, or important concepts. Action can be 'add_entity', 'add_relation', 'get_entity', or 'search_relations'.`
+	return `Interact with the Knowledge Graph. Use this to explicitly extract and save structured relationships (Entities and Relations) about the user, projects, or important concepts. Action can be 'add_entity', 'add_relation', 'add_alias', 'get_entity', 'search_relations', 'neighborhood' (entities and relations within depth hops of an entity) or 'path' (how two entities are connected).`
 }
 
 func (t *MemoryGraphTool) Parameters() map[string]interface{} {
@@ -408,12 +519,12 @@ func (t *MemoryGraphTool) Parameters() map[string]interface{} {
 		"properties": map[string]interface{}{
 			"action": map[string]interface{}{
 				"type": "string",
-				"enum": []string{"add_entity", "add_relation", "get_entity", "search_relations"},
+				"enum": []string{"add_entity", "add_relation", "add_alias", "get_entity", "search_relations", "neighborhood", "path"},
 				"description": "The graph action to perform.",
 			},
 			"name": map[string]interface{}{
 				"type": "string",
-				"description": "Name of the entity (for add_entity, get_entity, search_relations). Convert to lowercase.",
+				"description": "Name of the entity (for add_entity, add_alias, get_entity, search_relations, neighborhood). Convert to lowercase.",
 			},
 			"entity_type": map[string]interface{}{
 				"type": "string",
@@ -425,11 +536,11 @@ func (t *MemoryGraphTool) Parameters() map[string]interface{} {
 			},
 			"source": map[string]interface{}{
 				"type": "string",
-				"description": "Source entity name (for add_relation).",
+				"description": "Source entity name (for add_relation, path).",
 			},
 			"target": map[string]interface{}{
 				"type": "string",
-				"description": "Target entity name (for add_relation).",
+				"description": "Target entity name (for add_relation, path).",
 			},
 			"relation": map[string]interface{}{
 				"type": "string",
@@ -439,17 +550,41 @@ func (t *MemoryGraphTool) Parameters() map[string]interface{} {
 				"type": "number",
 				"description": "Relationship weight (0.0 to 1.0, default 1.0) (for add_relation).",
 			},
+			"alias": map[string]interface{}{
+				"type": "string",
+				"description": "Another name for the entity, e.g. a nickname (for add_alias). An existing entity with this name is merged into it.",
+			},
+			"depth": map[string]interface{}{
+				"type": "integer",
+				"description": "Hops to expand, default 1 (for neighborhood) or maximum hops, default 4 (for path).",
+			},
+			"weighted": map[string]interface{}{
+				"type": "boolean",
+				"description": "Prefer the strongest relations over the fewest hops (for path).",
+			},
 		},
 		"required": []string{"action"},
 	}
 }
 
 func (t *MemoryGraphTool) Execute(args map[string]interface{}) (interface{}, error) {
+	return t.ExecuteContext(context.Background(), args)
+}
+
+// ExecuteContext runs a graph action; entities, aliases and relations are
+// added and looked up within the run's memory scope, or globally when the
+// run isn't confined
+func (t *MemoryGraphTool) ExecuteContext(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	if t.Store == nil || t.Store.Graph == nil {
 		return nil, fmt.Errorf("knowledge graph is disabled or unavailable")
 	}
 
 	action := GetString(args, "action")
+	var scope *memory.Scope
+	var owner memory.Ownership
+	if sc, ok := MemoryScopeFrom(ctx); ok {
+		scope, owner = &sc, sc.Ownership()
+	}
 
 	switch action {
 	case "add_entity":
@@ -462,7 +597,7 @@ func (t *MemoryGraphTool) Execute(args map[string]interface{}) (interface{}, err
 		if entityType == "" {
 			entityType = "concept"
 		}
-		err := t.Store.Graph.AddEntity(name, entityType, desc)
+		err := t.Store.Graph.AddEntityOwned(owner, name, entityType, desc)
 		if err != nil {
 			return nil, err
 		}
@@ -479,7 +614,7 @@ func (t *MemoryGraphTool) Execute(args map[string]interface{}) (interface{}, err
 		if source == "" || target == "" || relation == "" {
 			return nil, fmt.Errorf("missing required parameters: source, target, relation")
 		}
-		err := t.Store.Graph.AddRelation(source, target, relation, weight)
+		err := t.Store.Graph.AddRelationOwned(owner, source, target, relation, weight)
 		if err != nil {
 			return nil, err
 		}
@@ -490,28 +625,43 @@ func (t *MemoryGraphTool) Execute(args map[string]interface{}) (interface{}, err
 		if name == "" {
 			return nil, fmt.Errorf("missing required parameter: name")
 		}
-		e, err := t.Store.Graph.GetEntity(name)
+		e, err := t.Store.Graph.GetEntityScope(scope, name)
 		if err != nil {
 			return nil, err
 		}
 		if e == nil {
 			return fmt.Sprintf("Entity '%s' not found.", name), nil
 		}
-		return fmt.Sprintf("Entity: %s\nType: %s\nDescription: %s", e.Name, e.Type, e.Description), nil
+		out := fmt.Sprintf("Entity: %s\nType: %s\nDescription: %s", e.Name, e.Type, e.Description)
+		if aliases, _ := t.Store.Graph.Aliases(scope, e.Name); len(aliases) > 0 {
+			out += "\nAliases: " + strings.Join(aliases, ", ")
+		}
+		return out, nil
+
+	case "add_alias":
+		name := GetString(args, "name")
+		alias := GetString(args, "alias")
+		if name == "" || alias == "" {
+			return nil, fmt.Errorf("missing required parameters: name, alias")
+		}
+		if err := t.Store.Graph.AddAlias(owner, alias, name); err != nil {
+			return nil, err
+		}
+		return fmt.Sprintf("'%s' now refers to %s", alias, t.Store.Graph.Resolve(scope, name)), nil
 
 	case "search_relations":
 		name := GetString(args, "name")
 		if name == "" {
 			return nil, fmt.Errorf("missing required parameter: name")
 		}
-		rels, err := t.Store.Graph.SearchRelations(name)
+		rels, err := t.Store.Graph.SearchRelationsScope(scope, name)
 		if err != nil {
 			return nil, err
 		}
 		if len(rels) == 0 {
 			return fmt.Sprintf("No relations found for '%s'.", name), nil
 		}
-		
+
 		var sb strings.Builder
 		sb.WriteString(fmt.Sprintf("Relations for '%s':\n", name))
 		for _, r := range rels {
@@ -519,7 +669,165 @@ func (t *MemoryGraphTool) Execute(args map[string]interface{}) (interface{}, err
 		}
 		return sb.String(), nil
 
+	case "neighborhood":
+		name := GetString(args, "name")
+		if name == "" {
+			return nil, fmt.Errorf("missing required parameter: name")
+		}
+		g, err := t.Store.Graph.Neighborhood(scope, []string{name}, GetInt(args, "depth"), 50)
+		if err != nil {
+			return nil, err
+		}
+		if len(g.Relations) == 0 {
+			return fmt.Sprintf("No relations found around '%s'.", name), nil
+		}
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("Entities around '%s':\n", name))
+		for _, e := range g.Entities {
+			if e.Type != "" {
+				sb.WriteString(fmt.Sprintf("- %s (%s)\n", e.Name, e.Type))
+			} else {
+				sb.WriteString(fmt.Sprintf("- %s\n", e.Name))
+			}
+		}
+		sb.WriteString("Relations:\n")
+		for _, r := range g.Relations {
+			sb.WriteString(fmt.Sprintf("- %s (weight: %.2f)\n", r.Fact(), r.Weight))
+		}
+		return sb.String(), nil
+
+	case "path":
+		source := GetString(args, "source")
+		target := GetString(args, "target")
+		if source == "" || target == "" {
+			return nil, fmt.Errorf("missing required parameters: source, target")
+		}
+		path, err := t.Store.Graph.Path(scope, source, target, GetInt(args, "depth"), GetBool(args, "weighted"))
+		if err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return fmt.Sprintf("No path found between '%s' and '%s'.", source, target), nil
+		}
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("Path from '%s' to '%s' (%d hops):\n", source, target, len(path)))
+		for _, r := range path {
+			sb.WriteString(fmt.Sprintf("- %s (weight: %.2f)\n", r.Fact(), r.Weight))
+		}
+		return sb.String(), nil
+
 	default:
 		return nil, fmt.Errorf("unknown action: %s", action)
 	}
 }
+
+// ==================== Memory Ingest Tool ====================
+
+// MemoryIngestTool ingests files into memory as cited document chunks
+type MemoryIngestTool struct {
+	Store   *memory.VectorMemoryStore
+	AnyPath bool // Skip the allowed-directory check (for the operator's CLI)
+}
+
+func NewMemoryIngestTool(store *memory.VectorMemoryStore) *MemoryIngestTool {
+	return &MemoryIngestTool{Store: store}
+}
+
+func (t *MemoryIngestTool) Name() string { return "memory_ingest" }
+
+func (t *MemoryIngestTool) Description() string {
+	return "Ingest Markdown, text, source code, HTML or PDF files into long-term memory so memory_search finds them with file and line citations. Accepts files, directories and globs (** spans directories); unchanged files are skipped and changed ones updated."
+}
+
+func (t *MemoryIngestTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"paths": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Files, directories or globs such as docs/**/*.md",
+			},
+			"global": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Make the documents visible to every user instead of only this conversation (default false)",
+			},
+			"force": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Re-chunk files even when their content did not change",
+			},
+		},
+		"required": []string{"paths"},
+	}
+}
+
+func (t *MemoryIngestTool) Execute(args map[string]interface{}) (interface{}, error) {
+	return t.ExecuteContext(context.Background(), args)
+}
+
+// ExecuteContext ingests documents owned by the run's scope (global when
+// the run isn't confined or global is set, which needs an admin run unless
+// AnyPath is set). Paths must be inside the allowed directories unless
+// AnyPath is set
+func (t *MemoryIngestTool) ExecuteContext(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	paths := GetStringSlice(args, "paths")
+	if len(paths) == 0 {
+		if p := GetString(args, "paths"); p != "" {
+			paths = []string{p}
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("paths is required")
+	}
+	if t.Store == nil {
+		return nil, fmt.Errorf("memory store is not initialized")
+	}
+	if !t.AnyPath {
+		for _, p := range paths {
+			// Globs are checked at the directory they are walked from
+			base := p
+			for strings.ContainsAny(base, "*?[") {
+				base = filepath.Dir(base)
+			}
+			if _, err := IsPathAllowed(base); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	scope, _ := MemoryScopeFrom(ctx)
+	if GetBool(args, "global") {
+		if !t.AnyPath && !memoryAdmin(ctx) {
+			return nil, fmt.Errorf("only admins can ingest global documents")
+		}
+		scope.Owner = ""
+	}
+	report, err := t.Store.Ingest(paths, memory.IngestOptions{Owner: scope.Ownership(), Force: GetBool(args, "force")})
+	if err != nil {
+		return nil, fmt.Errorf("ingest failed: %v", err)
+	}
+
+	files := make([]map[string]interface{}, 0, len(report.Files))
+	for _, f := range report.Files {
+		item := map[string]interface{}{
+			"path":   f.Path,
+			"status": f.Status,
+			"chunks": f.Chunks,
+		}
+		if f.Added > 0 || f.Removed > 0 {
+			item["added"], item["removed"] = f.Added, f.Removed
+		}
+		if f.Reason != "" {
+			item["reason"] = f.Reason
+		}
+		files = append(files, item)
+	}
+	return map[string]interface{}{
+		"action": "ingested",
+		"owner":  ownerLabel(scope.Owner),
+		"files":  files,
+		"result": report.String(),
+	}, nil
+}
@@ -32,6 +32,7 @@ func NewDefaultRegistry() *Registry {
 	// registry.Register(&NodesTool{})
 	registry.Register(&SessionsListTool{})
 	registry.Register(&SessionsSendTool{})
+	registry.Register(&SessionsForkTool{})
 	registry.Register(&SessionsSpawnTool{})
 	registry.Register(&SessionsHistoryTool{})
 	registry.Register(&SessionStatusTool{})
@@ -41,6 +42,7 @@ func NewDefaultRegistry() *Registry {
 	registry.Register(&MemoryGetTool{Store: nil})
 	registry.Register(&MemoryStoreTool{Store: nil})
 	registry.Register(&MemoryGraphTool{Store: nil})
+	registry.Register(&MemoryIngestTool{Store: nil})
 
 	return registry
 }
@@ -67,6 +69,7 @@ func NewMemoryRegistry(store *memory.VectorMemoryStore) *Registry {
 	// registry.Register(&NodesTool{})
 	registry.Register(&SessionsListTool{})
 	registry.Register(&SessionsSendTool{})
+	registry.Register(&SessionsForkTool{})
 	registry.Register(&SessionsSpawnTool{})
 	registry.Register(&SessionsHistoryTool{})
 	registry.Register(&SessionStatusTool{})
@@ -75,6 +78,7 @@ func NewMemoryRegistry(store *memory.VectorMemoryStore) *Registry {
 	registry.Register(&MemoryGetTool{Store: store})
 	registry.Register(&MemoryStoreTool{Store: store})
 	registry.Register(&MemoryGraphTool{Store: store})
+	registry.Register(&MemoryIngestTool{Store: store})
 
 	return registry
 }
@@ -13,6 +13,10 @@ func (t *ReadTool) Name() string {
 	return "read"
 }
 
+func (t *ReadTool) Concurrency() ConcurrencyClass {
+	return ConcurrencyPath
+}
+
 func (t *ReadTool) Description() string {
 	return "Read file content. Supports text files and images (base64). Max 50KB per call."
 }
@@ -0,0 +1,400 @@
+// schema.go - Validating and coercing tool arguments against the tool's JSON schema
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ArgError is one problem with a call's arguments, phrased for the model to fix
+type ArgError struct {
+	Path     string      `json:"path"`    // "command", "items[2].name"; empty for the whole call
+	Problem  string      `json:"problem"` // invalid_json, required, type, enum
+	Message  string      `json:"message"`
+	Expected interface{} `json:"expected,omitempty"` // Type name or allowed values
+}
+
+// ValidationError reports every argument problem of a call at once
+type ValidationError struct {
+	Tool   string     `json:"tool"`
+	Errors []ArgError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, ae := range e.Errors {
+		if ae.Path == "" {
+			msgs = append(msgs, ae.Message)
+		} else {
+			msgs = append(msgs, ae.Path+": "+ae.Message)
+		}
+	}
+	return fmt.Sprintf("invalid arguments for %s: %s", e.Tool, strings.Join(msgs, "; "))
+}
+
+// ValidationStats counts the argument validation outcomes of one tool
+type ValidationStats struct {
+	Calls    int            `json:"calls"`
+	Coerced  int            `json:"coerced"` // Calls that needed at least one value coerced
+	Failed   int            `json:"failed"`
+	Problems map[string]int `json:"problems,omitempty"` // Argument errors by problem
+}
+
+// validationStats holds the per-tool counters of every registry
+type validationStats struct {
+	mu    sync.Mutex
+	tools map[string]*ValidationStats
+}
+
+// ValidateCall parses a call's JSON arguments and validates them against the
+// tool's schema, coercing mistyped values and filling in defaults. Calls to
+// unknown tools are only parsed and not counted, so made-up names don't grow
+// the stats; the call itself reports the missing tool.
+func (r *Registry) ValidateCall(name, argsJSON string) (map[string]interface{}, error) {
+	t, known := r.Get(name)
+	args := map[string]interface{}{}
+	if s := strings.TrimSpace(argsJSON); s != "" && s != "null" {
+		if err := json.Unmarshal([]byte(s), &args); err != nil || args == nil {
+			args = map[string]interface{}{}
+			errs := []ArgError{{Problem: "invalid_json", Message: "arguments must be a JSON object", Expected: "object"}}
+			if known {
+				r.recordValidation(name, 0, errs)
+			}
+			return args, &ValidationError{Tool: name, Errors: errs}
+		}
+	}
+	if !known {
+		return args, nil
+	}
+	coerced, errs := ValidateArgs(t.Parameters(), args)
+	r.recordValidation(name, coerced, errs)
+	if len(errs) > 0 {
+		return args, &ValidationError{Tool: name, Errors: errs}
+	}
+	return args, nil
+}
+
+// ValidationStats returns a copy of the per-tool validation counters
+func (r *Registry) ValidationStats() map[string]ValidationStats {
+	r.validation.mu.Lock()
+	defer r.validation.mu.Unlock()
+	out := make(map[string]ValidationStats, len(r.validation.tools))
+	for name, st := range r.validation.tools {
+		cp := *st
+		cp.Problems = make(map[string]int, len(st.Problems))
+		for k, v := range st.Problems {
+			cp.Problems[k] = v
+		}
+		out[name] = cp
+	}
+	return out
+}
+
+func (r *Registry) recordValidation(name string, coerced int, errs []ArgError) {
+	r.validation.mu.Lock()
+	defer r.validation.mu.Unlock()
+	if r.validation.tools == nil {
+		r.validation.tools = make(map[string]*ValidationStats)
+	}
+	st := r.validation.tools[name]
+	if st == nil {
+		st = &ValidationStats{Problems: make(map[string]int)}
+		r.validation.tools[name] = st
+	}
+	st.Calls++
+	if coerced > 0 {
+		st.Coerced++
+	}
+	if len(errs) > 0 {
+		st.Failed++
+	}
+	for _, e := range errs {
+		st.Problems[e.Problem]++
+	}
+}
+
+// ValidateArgs checks args against a JSON schema in place. Values the model
+// sent as the wrong JSON type are coerced when the intent is unambiguous ("5"
+// for an integer, "true" for a boolean, a lone value for an array, a JSON
+// string for an object), enum values are matched case-insensitively, nulls
+// count as absent and missing properties get their default. It returns the
+// number of values coerced and the problems left.
+func ValidateArgs(schema, args map[string]interface{}) (int, []ArgError) {
+	v := &argValidator{}
+	v.object("", schema, args)
+	return v.coerced, v.errs
+}
+
+// ValidateValue checks any decoded JSON value against a schema the way
+// ValidateArgs checks arguments, and returns the value with coercions applied
+func ValidateValue(schema map[string]interface{}, val interface{}) (interface{}, int, []ArgError) {
+	v := &argValidator{}
+	val = v.value("", schema, val)
+	return val, v.coerced, v.errs
+}
+
+type argValidator struct {
+	coerced int
+	errs    []ArgError
+}
+
+func (v *argValidator) fail(path, problem, msg string, expected interface{}) {
+	v.errs = append(v.errs, ArgError{Path: path, Problem: problem, Message: msg, Expected: expected})
+}
+
+func (v *argValidator) object(path string, schema, obj map[string]interface{}) {
+	props, _ := schema["properties"].(map[string]interface{})
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ps, ok := props[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		val, present := obj[name]
+		if present && val == nil && !hasType(schemaTypes(ps), "null") {
+			delete(obj, name)
+			present = false
+		}
+		if !present {
+			if def, ok := ps["default"]; ok {
+				obj[name] = jsonValue(def)
+			}
+			continue
+		}
+		obj[name] = v.value(joinPath(path, name), ps, val)
+	}
+
+	for _, name := range stringList(schema["required"]) {
+		if _, ok := obj[name]; !ok {
+			var expected interface{}
+			if ps, ok := props[name].(map[string]interface{}); ok {
+				if types := schemaTypes(ps); len(types) > 0 {
+					expected = strings.Join(types, "|")
+				}
+			}
+			v.fail(joinPath(path, name), "required", "missing required argument", expected)
+		}
+	}
+}
+
+func (v *argValidator) value(path string, schema map[string]interface{}, val interface{}) interface{} {
+	types := schemaTypes(schema)
+	if len(types) > 0 && !matchesAny(types, val) {
+		converted, ok := coerceValue(types, val)
+		if !ok {
+			v.fail(path, "type", fmt.Sprintf("expected %s, got %s", strings.Join(types, " or "), jsonType(val)), strings.Join(types, "|"))
+			return val
+		}
+		val = converted
+		v.coerced++
+	}
+
+	if enum := valueList(schema["enum"]); len(enum) > 0 && !containsValue(enum, val) {
+		if canonical, ok := enumMatch(enum, val); ok {
+			val = canonical
+			v.coerced++
+		} else {
+			v.fail(path, "enum", fmt.Sprintf("%v is not one of the allowed values", val), enum)
+			return val
+		}
+	}
+
+	switch x := val.(type) {
+	case map[string]interface{}:
+		if _, ok := schema["properties"]; ok {
+			v.object(path, schema, x)
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i := range x {
+				x[i] = v.value(fmt.Sprintf("%s[%d]", path, i), items, x[i])
+			}
+		}
+	}
+	return val
+}
+
+// coerceValue converts val to the first of types it unambiguously represents
+func coerceValue(types []string, val interface{}) (interface{}, bool) {
+	for _, t := range types {
+		switch t {
+		case "integer", "number":
+			var f float64
+			switch x := val.(type) {
+			case string:
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+				if err != nil {
+					continue
+				}
+				f = parsed
+			case float64:
+				f = x
+			default:
+				continue
+			}
+			if t == "integer" && f != math.Trunc(f) {
+				continue
+			}
+			return f, true
+		case "boolean":
+			if s, ok := val.(string); ok {
+				if b, err := strconv.ParseBool(strings.ToLower(strings.TrimSpace(s))); err == nil {
+					return b, true
+				}
+			}
+		case "string":
+			switch x := val.(type) {
+			case float64:
+				return strconv.FormatFloat(x, 'f', -1, 64), true
+			case bool:
+				return strconv.FormatBool(x), true
+			}
+		case "array":
+			if s, ok := val.(string); ok && strings.HasPrefix(strings.TrimSpace(s), "[") {
+				var arr []interface{}
+				if json.Unmarshal([]byte(s), &arr) == nil {
+					return arr, true
+				}
+			}
+			if _, isObj := val.(map[string]interface{}); val != nil && !isObj {
+				return []interface{}{val}, true
+			}
+		case "object":
+			if s, ok := val.(string); ok {
+				var obj map[string]interface{}
+				if json.Unmarshal([]byte(s), &obj) == nil && obj != nil {
+					return obj, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// schemaTypes returns the "type" of a schema, which may be a name or a list
+func schemaTypes(schema map[string]interface{}) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	default:
+		return stringList(t)
+	}
+}
+
+func hasType(types []string, want string) bool {
+	for _, t := range types {
+		if t == want {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(types []string, val interface{}) bool {
+	got := jsonType(val)
+	for _, t := range types {
+		if t == got || (t == "number" && got == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON type of a decoded value; whole numbers are "integer"
+func jsonType(val interface{}) string {
+	switch x := val.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if x == math.Trunc(x) {
+			return "integer"
+		}
+		return "number"
+	case float32:
+		return "number"
+	case int, int32, int64:
+		return "integer"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", val)
+	}
+}
+
+// jsonValue normalizes a Go value (e.g. an int default) to what json.Unmarshal yields
+func jsonValue(val interface{}) interface{} {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return val
+	}
+	var out interface{}
+	if json.Unmarshal(data, &out) != nil {
+		return val
+	}
+	return out
+}
+
+func containsValue(list []interface{}, val interface{}) bool {
+	for _, item := range list {
+		if reflect.DeepEqual(item, val) {
+			return true
+		}
+	}
+	return false
+}
+
+func enumMatch(enum []interface{}, val interface{}) (interface{}, bool) {
+	s, ok := val.(string)
+	if !ok {
+		return nil, false
+	}
+	for _, item := range enum {
+		if is, ok := item.(string); ok && strings.EqualFold(strings.TrimSpace(s), is) {
+			return is, true
+		}
+	}
+	return nil, false
+}
+
+// valueList normalizes []string, []interface{} etc. to JSON-decoded values
+func valueList(v interface{}) []interface{} {
+	if v == nil {
+		return nil
+	}
+	list, _ := jsonValue(v).([]interface{})
+	return list
+}
+
+func stringList(v interface{}) []string {
+	var out []string
+	for _, item := range valueList(v) {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func joinPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
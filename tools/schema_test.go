@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var testSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"command": map[string]interface{}{"type": "string"},
+		"timeout": map[string]interface{}{"type": "integer", "default": 30},
+		"ratio":   map[string]interface{}{"type": "number"},
+		"force":   map[string]interface{}{"type": "boolean"},
+		"mode":    map[string]interface{}{"type": "string", "enum": []string{"fast", "safe"}},
+		"tags":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		"env": map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"port": map[string]interface{}{"type": "integer"}},
+			"required":   []string{"port"},
+		},
+	},
+	"required": []string{"command"},
+}
+
+func TestValidateArgsCoercion(t *testing.T) {
+	args := map[string]interface{}{
+		"command": "ls",
+		"ratio":   "0.5",
+		"force":   "TRUE",
+		"mode":    "Fast",
+		"tags":    "solo",
+		"env":     `{"port":"8080"}`,
+	}
+	coerced, errs := ValidateArgs(testSchema, args)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %+v", errs)
+	}
+	want := map[string]interface{}{
+		"command": "ls",
+		"timeout": float64(30),
+		"ratio":   0.5,
+		"force":   true,
+		"mode":    "fast",
+		"tags":    []interface{}{"solo"},
+		"env":     map[string]interface{}{"port": float64(8080)},
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %#v", args)
+	}
+	// ratio, force, mode, tags, env and env.port
+	if coerced != 6 {
+		t.Errorf("coerced = %d, want 6", coerced)
+	}
+}
+
+func TestValidateArgsErrors(t *testing.T) {
+	args := map[string]interface{}{
+		"command": nil,
+		"timeout": 2.5,
+		"mode":    "slow",
+		"tags":    []interface{}{"a", map[string]interface{}{}},
+		"env":     map[string]interface{}{},
+	}
+	_, errs := ValidateArgs(testSchema, args)
+	got := map[string]string{}
+	for _, e := range errs {
+		got[e.Path] = e.Problem
+	}
+	want := map[string]string{
+		"command":  "required",
+		"timeout":  "type",
+		"mode":     "enum",
+		"tags[1]":  "type",
+		"env.port": "required",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("errors = %+v", errs)
+	}
+}
+
+func TestValidateCallStats(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&ExecTool{})
+
+	if _, err := r.ValidateCall("exec", `{"command":"ls","timeout":"10"}`); err != nil {
+		t.Fatal(err)
+	}
+	_, err := r.ValidateCall("exec", `{"timeout":10}`)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Errors) != 1 || verr.Errors[0].Path != "command" {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := r.ValidateCall("exec", `{"command":`); !errors.As(err, &verr) || verr.Errors[0].Problem != "invalid_json" {
+		t.Fatalf("malformed JSON err = %v", err)
+	}
+	if args, err := r.ValidateCall("no_such_tool", `{"x":1}`); err != nil || args["x"] != float64(1) {
+		t.Errorf("unknown tool: args %v, err %v", args, err)
+	}
+	r.ValidateCall("made_up", `{"x":`)
+
+	stats := r.ValidationStats()
+	st := stats["exec"]
+	if st.Calls != 3 || st.Coerced != 1 || st.Failed != 2 || st.Problems["required"] != 1 || st.Problems["invalid_json"] != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+	if len(stats) != 1 {
+		t.Errorf("unknown tools were counted: %v", stats)
+	}
+}
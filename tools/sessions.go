@@ -2,11 +2,14 @@
 package tools
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -28,6 +31,10 @@ func (t *SessionsListTool) Name() string {
 	return "sessions_list"
 }
 
+func (t *SessionsListTool) Concurrency() ConcurrencyClass {
+	return ConcurrencySafe
+}
+
 func (t *SessionsListTool) Description() string {
 	return "List active sessions; supports optional kind filters."
 }
@@ -55,21 +62,7 @@ func (t *SessionsListTool) Parameters() map[string]interface{} {
 
 func (t *SessionsListTool) Execute(args map[string]interface{}) (interface{}, error) {
 	// Try to get real data from gateway
-	req, err := http.NewRequest("GET", gatewayURL+"/sessions/list", nil)
-	if err != nil {
-		return "Failed to fetch sessions: " + err.Error(), nil
-	}
-	// Add auth header - prefer UI_TOKEN as it's the correct one for local gateway
-	token := os.Getenv("OCG_UI_TOKEN")
-	if token == "" {
-		token = os.Getenv("OCG_GATEWAY_TOKEN")
-	}
-	if token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
-	}
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := gatewayRequest("GET", "/sessions/list", nil)
 	if err != nil {
 		return "Failed to fetch sessions: " + err.Error(), nil
 	}
@@ -85,6 +78,28 @@ func (t *SessionsListTool) Execute(args map[string]interface{}) (interface{}, er
 	return formatSessionsResult(result), nil
 }
 
+// gatewayRequest calls a protected gateway endpoint
+func gatewayRequest(method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, gatewayURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	// Add auth header - prefer UI_TOKEN as it's the correct one for local gateway
+	token := os.Getenv("OCG_UI_TOKEN")
+	if token == "" {
+		token = os.Getenv("OCG_GATEWAY_TOKEN")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	return client.Do(req)
+}
+
 func formatSessionsResult(data map[string]interface{}) string {
 	sessions, _ := data["sessions"].([]interface{})
 	count, _ := data["count"].(float64)
@@ -99,12 +114,88 @@ func formatSessionsResult(data map[string]interface{}) string {
 		key := ses["session_key"]
 		tokens := ses["total_tokens"]
 		updated := ses["updated_at"]
-		output += fmt.Sprintf("%d. Session: %v | Tokens: %v | Updated: %v\n", i+1, key, tokens, updated)
+		output += fmt.Sprintf("%d. Session: %v | Tokens: %v | Updated: %v", i+1, key, tokens, updated)
+		if parent, ok := ses["parent_session_key"]; ok {
+			output += fmt.Sprintf(" | Forked from: %v at message %v", parent, ses["fork_message_id"])
+		}
+		if branches, ok := ses["branches"].([]interface{}); ok && len(branches) > 0 {
+			output += fmt.Sprintf(" | Branches: %v", branches)
+		}
+		output += "\n"
 	}
 	output += fmt.Sprintf("\nTotal: %d sessions", int(count))
 	return output
 }
 
+// Sessions Fork Tool - branch a session into a new one
+type SessionsForkTool struct{}
+
+func NewSessionsForkTool() *SessionsForkTool {
+	return &SessionsForkTool{}
+}
+
+func (t *SessionsForkTool) Name() string {
+	return "sessions_fork"
+}
+
+func (t *SessionsForkTool) Description() string {
+	return "Fork a session into a new session to try an alternative direction; the original is kept as is."
+}
+
+func (t *SessionsForkTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"sessionKey": map[string]interface{}{
+				"type":        "string",
+				"description": "Session to fork (default: the current session)",
+			},
+			"messageId": map[string]interface{}{
+				"type":        "integer",
+				"description": "Last message to copy (default: the latest)",
+			},
+			"newSessionKey": map[string]interface{}{
+				"type":        "string",
+				"description": "Key of the new session (default: generated)",
+			},
+		},
+	}
+}
+
+func (t *SessionsForkTool) Execute(args map[string]interface{}) (interface{}, error) {
+	return t.ExecuteContext(context.Background(), args)
+}
+
+func (t *SessionsForkTool) ExecuteContext(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	sessionKey := GetString(args, "sessionKey")
+	if sessionKey == "" {
+		sessionKey = SessionKeyFrom(ctx)
+	}
+	if sessionKey == "" {
+		return nil, fmt.Errorf("sessionKey is required")
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"sessionKey":    sessionKey,
+		"messageId":     GetInt(args, "messageId"),
+		"newSessionKey": GetString(args, "newSessionKey"),
+	})
+
+	resp, err := gatewayRequest("POST", "/sessions/fork", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("fork failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fork failed: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("fork failed: %w", err)
+	}
+	return result, nil
+}
+
 // Sessions Send Tool - send message to another session
 type SessionsSendTool struct{}
 
@@ -241,6 +332,10 @@ func (t *SessionsHistoryTool) Name() string {
 	return "sessions_history"
 }
 
+func (t *SessionsHistoryTool) Concurrency() ConcurrencyClass {
+	return ConcurrencySafe
+}
+
 func (t *SessionsHistoryTool) Description() string {
 	return "Get message history of a session."
 }
@@ -298,6 +393,10 @@ func (t *SessionStatusTool) Name() string {
 	return "session_status"
 }
 
+func (t *SessionStatusTool) Concurrency() ConcurrencyClass {
+	return ConcurrencySafe
+}
+
 func (t *SessionStatusTool) Description() string {
 	return "Get current session status (usage, time, etc.)."
 }
@@ -347,6 +446,10 @@ func (t *AgentsListTool) Name() string {
 	return "agents_list"
 }
 
+func (t *AgentsListTool) Concurrency() ConcurrencyClass {
+	return ConcurrencySafe
+}
+
 func (t *AgentsListTool) Description() string {
 	return "List agent IDs available for sessions_spawn."
 }
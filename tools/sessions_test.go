@@ -1,6 +1,10 @@
 package tools
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -81,3 +85,35 @@ func TestAgentsListToolName(t *testing.T) {
 		t.Errorf("Expected 'agents_list', got '%s'", tool.Name())
 	}
 }
+
+func TestSessionsForkToolUsesCurrentSession(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/sessions/fork" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"session_key": "telegram:1-alt", "parent_session_key": "telegram:1", "fork_message_id": 7}`))
+	}))
+	defer srv.Close()
+	old := gatewayURL
+	gatewayURL = srv.URL
+	defer func() { gatewayURL = old }()
+
+	ctx := WithSessionKey(context.Background(), "telegram:1")
+	res, err := ExecuteContext(ctx, &SessionsForkTool{}, map[string]interface{}{"messageId": 7})
+	if err != nil {
+		t.Fatalf("fork: %v", err)
+	}
+	if got["sessionKey"] != "telegram:1" || got["messageId"] != float64(7) {
+		t.Errorf("request = %v", got)
+	}
+	if m, _ := res.(map[string]interface{}); m["session_key"] != "telegram:1-alt" {
+		t.Errorf("result = %v", res)
+	}
+
+	if _, err := (&SessionsForkTool{}).Execute(map[string]interface{}{}); err == nil {
+		t.Error("fork without a session should fail")
+	}
+}
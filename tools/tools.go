@@ -2,6 +2,7 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -21,26 +22,29 @@ type Tool interface {
 	Execute(args map[string]interface{}) (interface{}, error)
 }
 
-// ToolsPolicy holds tool allow/deny policy
+// ToolsPolicy holds tool allow/ask/deny policy
 type ToolsPolicy struct {
 	Profile       string   // "minimal", "coding", "messaging", "full"
 	Allow         []string // Tool names or groups to allow
 	Deny          []string // Tool names or groups to deny
+	Ask           []string // Tool names, groups or tool:action pairs that wait for human approval
+	AskPatterns   []string // Regexps; calls whose "tool {json args}" matches wait for approval
 	WorkspaceOnly bool     // Restrict file ops to workspace
 }
 
 // Registry holds registered tools
 type Registry struct {
-	tools  map[string]Tool
-	policy *ToolsPolicy
+	tools      map[string]Tool
+	policy     *ToolsPolicy
+	validation validationStats // Argument validation counters per tool
 }
 
 // Tool groups (matching official OCG)
 var ToolGroups = map[string][]string{
 	"group:runtime":    {"exec", "process"},
 	"group:fs":         {"read", "write", "edit", "apply_patch"},
-	"group:sessions":   {"sessions_list", "sessions_history", "sessions_send", "sessions_spawn", "session_status"},
-	"group:memory":     {"memory_search", "memory_get", "memory_store", "memory_graph"},
+	"group:sessions":   {"sessions_list", "sessions_history", "sessions_send", "sessions_spawn", "sessions_fork", "session_status"},
+	"group:memory":     {"memory_search", "memory_get", "memory_store", "memory_graph", "memory_ingest"},
 	"group:web":        {"web_search", "web_fetch"},
 	"group:ui":         {"browser", "canvas"},
 	"group:automation": {"cron", "gateway"},
@@ -131,6 +135,35 @@ func (r *Registry) IsToolAllowed(toolName string) bool {
 	return true
 }
 
+// Permits reports whether the allow and deny lists let a tool run. Entries
+// are tool names, groups or "*"; a tool on both lists is allowed, and an empty
+// allow list allows every tool not denied. A nil policy permits everything.
+func (p *ToolsPolicy) Permits(toolName string) bool {
+	if p == nil {
+		return true
+	}
+	allowed := policyMatch(p.Allow, toolName)
+	if policyMatch(p.Deny, toolName) && !allowed {
+		return false
+	}
+	return len(p.Allow) == 0 || allowed
+}
+
+// policyMatch reports whether a tool is named by a list of names, groups or "*"
+func policyMatch(entries []string, toolName string) bool {
+	for _, entry := range entries {
+		if entry == "*" || entry == toolName {
+			return true
+		}
+		for _, member := range ToolGroups[entry] {
+			if member == toolName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // GetAllowedTools returns list of tools filtered by policy
 func (r *Registry) GetAllowedTools() []string {
 	var allowed []string
@@ -165,6 +198,12 @@ func (r *Registry) List() []string {
 
 // CallTool and return its result
 func (r *Registry) CallTool(name string, args map[string]interface{}) (interface{}, error) {
+	return r.CallToolContext(context.Background(), name, args)
+}
+
+// CallToolContext calls a tool under ctx; cancelling ctx stops tools that
+// implement ContextTool and returns ctx.Err() for the rest
+func (r *Registry) CallToolContext(ctx context.Context, name string, args map[string]interface{}) (interface{}, error) {
 	// Check policy first
 	if !r.IsToolAllowed(name) {
 		return nil, fmt.Errorf("tool not allowed by policy: %s", name)
@@ -176,7 +215,7 @@ func (r *Registry) CallTool(name string, args map[string]interface{}) (interface
 	}
 
 	log.Printf("[TOOL] calling tool: %s, args: %v", name, args)
-	result, err := t.Execute(args)
+	result, err := ExecuteContext(ctx, t, args)
 	if err != nil {
 		log.Printf("[ERROR] tool failed: %s - %v", name, err)
 		return nil, err
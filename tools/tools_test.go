@@ -1,7 +1,11 @@
 package tools
 
 import (
+	"context"
+	"errors"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestToolRegistry(t *testing.T) {
@@ -58,6 +62,62 @@ func TestToolRegistryGetToolSpecs(t *testing.T) {
 	}
 }
 
+func TestToolRegistryConcurrency(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(&ExecTool{})
+	registry.Register(&ReadTool{})
+	registry.Register(&WebFetchTool{})
+
+	if class, _ := registry.Concurrency("exec", nil); class != ConcurrencyExclusive {
+		t.Errorf("exec: expected exclusive, got %s", class)
+	}
+	if class, _ := registry.Concurrency("web_fetch", nil); class != ConcurrencySafe {
+		t.Errorf("web_fetch: expected safe, got %s", class)
+	}
+	if class, _ := registry.Concurrency("nonexistent", nil); class != ConcurrencyExclusive {
+		t.Errorf("unknown tool: expected exclusive, got %s", class)
+	}
+
+	class, key := registry.Concurrency("read", map[string]interface{}{"path": "a/../b.txt"})
+	want, _ := filepath.Abs("b.txt")
+	if class != ConcurrencyPath || key != want {
+		t.Errorf("read: expected path lock on %s, got %s %q", want, class, key)
+	}
+}
+
+// blockingTool has no ExecuteContext and blocks until released
+type blockingTool struct{ release chan struct{} }
+
+func (b *blockingTool) Name() string                       { return "block" }
+func (b *blockingTool) Description() string                { return "blocks" }
+func (b *blockingTool) Parameters() map[string]interface{} { return nil }
+func (b *blockingTool) Execute(map[string]interface{}) (interface{}, error) {
+	<-b.release
+	return "released", nil
+}
+
+func TestCallToolContext(t *testing.T) {
+	tool := &blockingTool{release: make(chan struct{})}
+	defer close(tool.release)
+	registry := NewRegistry()
+	registry.Register(tool)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := registry.CallToolContext(ctx, "block", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancelled call returned after %v", elapsed)
+	}
+
+	// A context that is already done doesn't start the tool
+	if _, err := registry.CallToolContext(ctx, "block", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
 func TestGetString(t *testing.T) {
 	// Test with existing key
 	args := map[string]interface{}{"name": "test"}
@@ -160,3 +220,58 @@ func TestGetFloat64(t *testing.T) {
 		t.Errorf("Expected 10, got %f", result)
 	}
 }
+
+func TestNeedsApproval(t *testing.T) {
+	registry := NewRegistryWithPolicy(&ToolsPolicy{
+		Ask:         []string{"write", "process:start", "gateway:update.run", "group:web"},
+		AskPatterns: []string{`^exec .*\brm\b`},
+	})
+	cases := []struct {
+		name string
+		args map[string]interface{}
+		want bool
+	}{
+		{"write", map[string]interface{}{"path": "a.txt"}, true},
+		{"read", map[string]interface{}{"path": "a.txt"}, false},
+		{"process", map[string]interface{}{"action": "start", "command": "top"}, true},
+		{"process", map[string]interface{}{"action": "list"}, false},
+		{"gateway", map[string]interface{}{"action": "update.run"}, true},
+		{"gateway", map[string]interface{}{"action": "config.get"}, false},
+		{"web_fetch", map[string]interface{}{"url": "https://example.com"}, true},
+		{"exec", map[string]interface{}{"command": "rm -rf build"}, true},
+		{"exec", map[string]interface{}{"command": "ls"}, false},
+	}
+	for _, c := range cases {
+		if got, _ := registry.NeedsApproval(c.name, c.args); got != c.want {
+			t.Errorf("NeedsApproval(%s, %v) = %v, want %v", c.name, c.args, got, c.want)
+		}
+	}
+
+	if got, _ := NewRegistry().NeedsApproval("exec", nil); got {
+		t.Error("default policy should not ask")
+	}
+}
+
+func TestToolsPolicyPermits(t *testing.T) {
+	policy := &ToolsPolicy{Allow: []string{"group:fs", "exec"}, Deny: []string{"write", "exec", "cron"}}
+	cases := map[string]bool{
+		"read":    true,  // allowed through its group
+		"write":   true,  // denied, but allowed through its group
+		"exec":    true,  // named on both lists
+		"cron":    false, // denied
+		"process": false, // not on the allow list
+	}
+	for name, want := range cases {
+		if got := policy.Permits(name); got != want {
+			t.Errorf("Permits(%s) = %v, want %v", name, got, want)
+		}
+	}
+
+	denyOnly := &ToolsPolicy{Deny: []string{"group:runtime"}}
+	if denyOnly.Permits("process") || !denyOnly.Permits("read") {
+		t.Error("deny-only policy should deny its groups and allow the rest")
+	}
+	if !(*ToolsPolicy)(nil).Permits("exec") {
+		t.Error("nil policy should permit everything")
+	}
+}
@@ -2,6 +2,7 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -21,6 +22,10 @@ func (t *WebSearchTool) Name() string {
 	return "web_search"
 }
 
+func (t *WebSearchTool) Concurrency() ConcurrencyClass {
+	return ConcurrencySafe
+}
+
 func (t *WebSearchTool) Description() string {
 	return "Use Tavily API (or fallback) to search the web; returns title, URL, summary."
 }
@@ -44,6 +49,10 @@ func (t *WebSearchTool) Parameters() map[string]interface{} {
 }
 
 func (t *WebSearchTool) Execute(args map[string]interface{}) (interface{}, error) {
+	return t.ExecuteContext(context.Background(), args)
+}
+
+func (t *WebSearchTool) ExecuteContext(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	query := GetString(args, "query")
 	count := GetInt(args, "count")
 	if count <= 0 || count > 10 {
@@ -55,7 +64,7 @@ func (t *WebSearchTool) Execute(args map[string]interface{}) (interface{}, error
 	}
 
 	// Tavily API (simplified mock); in production, call the real API with key
-	results, err := tavilySearch(query, count)
+	results, err := tavilySearch(ctx, query, count)
 	if err != nil {
 		return nil, fmt.Errorf("search failed: %v", err)
 	}
@@ -75,7 +84,7 @@ type tavilyResult struct {
 }
 
 // tavilySearch simplified (should call Tavily API)
-func tavilySearch(query string, count int) ([]tavilyResult, error) {
+func tavilySearch(ctx context.Context, query string, count int) ([]tavilyResult, error) {
 	// Read API key from environment
 	apiKey := os.Getenv("TAVILY_API_KEY")
 	if apiKey == "" {
@@ -86,7 +95,7 @@ func tavilySearch(query string, count int) ([]tavilyResult, error) {
 	if apiKey != "" {
 		// Prefer Tavily API
 		if os.Getenv("TAVILY_API_KEY") != "" {
-			return tavilyAPISearch(query, count, apiKey)
+			return tavilyAPISearch(ctx, query, count, apiKey)
 		}
 		// Otherwise use Brave Search
 		return braveSearch(query, count)
@@ -101,12 +110,12 @@ func tavilySearch(query string, count int) ([]tavilyResult, error) {
 }
 
 // tavilyAPISearch calls Tavily API
-func tavilyAPISearch(query string, count int, apiKey string) ([]tavilyResult, error) {
+func tavilyAPISearch(ctx context.Context, query string, count int, apiKey string) ([]tavilyResult, error) {
 	// FIX: Add timeout to prevent hanging
 	client := &http.Client{
 		Timeout: 15 * time.Second,
 	}
-	req, err := http.NewRequest("POST", "https://api.tavily.com/search", strings.NewReader(
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.tavily.com/search", strings.NewReader(
 		fmt.Sprintf(`{"query":"%s","search_depth":"basic","max_results":%d}`, query, count),
 	))
 	if err != nil {
@@ -182,6 +191,10 @@ func (t *WebFetchTool) Name() string {
 	return "web_fetch"
 }
 
+func (t *WebFetchTool) Concurrency() ConcurrencyClass {
+	return ConcurrencySafe
+}
+
 func (t *WebFetchTool) Description() string {
 	return "Fetch a web page and extract readable content (HTML to markdown/text)."
 }
@@ -210,6 +223,10 @@ func (t *WebFetchTool) Parameters() map[string]interface{} {
 }
 
 func (t *WebFetchTool) Execute(args map[string]interface{}) (interface{}, error) {
+	return t.ExecuteContext(context.Background(), args)
+}
+
+func (t *WebFetchTool) ExecuteContext(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	url := GetString(args, "url")
 	extractMode := GetString(args, "extractMode")
 	if extractMode == "" {
@@ -229,7 +246,7 @@ func (t *WebFetchTool) Execute(args map[string]interface{}) (interface{}, error)
 		return nil, fmt.Errorf("invalid URL")
 	}
 
-	content, err := fetchURL(url, extractMode, maxChars)
+	content, err := fetchURL(ctx, url, extractMode, maxChars)
 	if err != nil {
 		return nil, fmt.Errorf("fetch failed: %v", err)
 	}
@@ -243,7 +260,7 @@ func (t *WebFetchTool) Execute(args map[string]interface{}) (interface{}, error)
 }
 
 // fetchURL retrieves content and applies extraction
-func fetchURL(url, extractMode string, maxChars int) (string, error) {
+func fetchURL(ctx context.Context, url, extractMode string, maxChars int) (string, error) {
 	// FIX: Add timeout to prevent hanging, allow redirects
 	client := &http.Client{
 		Timeout: 30 * time.Second,
@@ -256,7 +273,11 @@ func fetchURL(url, extractMode string, maxChars int) (string, error) {
 		},
 	}
 
-	resp, err := client.Get(url)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", err
 	}
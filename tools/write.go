@@ -12,6 +12,10 @@ func (t *WriteTool) Name() string {
 	return "write"
 }
 
+func (t *WriteTool) Concurrency() ConcurrencyClass {
+	return ConcurrencyPath
+}
+
 func (t *WriteTool) Description() string {
 	return "Create a new file or overwrite an existing file. Parent dirs auto-created."
 }